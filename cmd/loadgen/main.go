@@ -0,0 +1,369 @@
+// Load generator: synthetic agents playing synthetic campaigns against a running server.
+// Each campaign gets a GM agent; player agents register, create characters, join, then
+// poll and act while the GMs poll status and narrate. Reports latency and errors per endpoint.
+// Usage: go run ./cmd/loadgen -target http://localhost:8080 -agents 12 -campaigns 3 -duration 2m
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	target    = flag.String("target", "http://localhost:8080", "server base URL")
+	agents    = flag.Int("agents", 8, "number of player agents")
+	campaigns = flag.Int("campaigns", 2, "number of campaigns (one GM agent each)")
+	duration  = flag.Duration("duration", time.Minute, "how long to play after setup")
+	think     = flag.Duration("think", 2*time.Second, "average pause between an agent's requests")
+	timeout   = flag.Duration("timeout", 15*time.Second, "per-request timeout")
+)
+
+var classes = []string{"Fighter", "Wizard", "Rogue", "Cleric", "Ranger", "Paladin"}
+var races = []string{"Human", "Dwarf", "Elf", "Halfling"}
+var playerActions = []struct{ action, description string }{
+	{"search", "I search the room for anything unusual"},
+	{"move", "I move toward the door and listen"},
+	{"help", "I help my companion with their task"},
+	{"dodge", "I stay alert and ready to dodge"},
+}
+
+// endpointStats collects results for one request label.
+type endpointStats struct {
+	latencies []time.Duration
+	statuses  map[int]int
+	failures  int // transport errors and 5xx
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	stats map[string]*endpointStats
+}
+
+func (r *recorder) record(label string, d time.Duration, status int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.stats[label]
+	if !ok {
+		s = &endpointStats{statuses: map[int]int{}}
+		r.stats[label] = s
+	}
+	s.latencies = append(s.latencies, d)
+	s.statuses[status]++
+	if err != nil || status >= 500 {
+		s.failures++
+	}
+}
+
+// client is one synthetic agent.
+type client struct {
+	name string
+	auth string
+	http *http.Client
+	rec  *recorder
+}
+
+// do sends a request and decodes a JSON object response. label groups paths with IDs.
+func (c *client) do(ctx context.Context, label, method, path string, body interface{}) (map[string]interface{}, int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(*target, "/")+path, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.auth != "" {
+		req.Header.Set("Authorization", "Basic "+c.auth)
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.rec.record(label, time.Since(start), 0, err)
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	c.rec.record(label, time.Since(start), resp.StatusCode, nil)
+
+	result := map[string]interface{}{}
+	json.Unmarshal(data, &result)
+	if msg, ok := result["error"].(string); ok && resp.StatusCode < 400 {
+		return result, resp.StatusCode, fmt.Errorf("%s", msg)
+	}
+	if resp.StatusCode >= 400 {
+		return result, resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return result, resp.StatusCode, nil
+}
+
+// register creates the agent and sets its Basic auth credentials.
+func (c *client) register(ctx context.Context) error {
+	password := "loadgen-" + strconv.Itoa(rand.Int())
+	result, _, err := c.do(ctx, "POST /api/register", "POST", "/api/register", map[string]string{
+		"name": c.name, "password": password,
+	})
+	if err != nil {
+		return fmt.Errorf("register %s: %w", c.name, err)
+	}
+	id, ok := result["agent_id"].(float64)
+	if !ok {
+		return fmt.Errorf("register %s: no agent_id in response", c.name)
+	}
+	c.auth = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", int(id), password)))
+	return nil
+}
+
+func intField(m map[string]interface{}, key string) int {
+	v, _ := m[key].(float64)
+	return int(v)
+}
+
+// pause sleeps for a jittered think time; false when the run is over.
+func pause(ctx context.Context) bool {
+	jitter := time.Duration(rand.Int63n(int64(*think) + 1))
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(*think/2 + jitter):
+		return true
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *agents < 1 || *campaigns < 1 {
+		log.Fatal("need at least one agent and one campaign")
+	}
+	if *campaigns > *agents {
+		*campaigns = *agents
+	}
+
+	rec := &recorder{stats: map[string]*endpointStats{}}
+	httpClient := &http.Client{Timeout: *timeout}
+	run := strconv.FormatInt(time.Now().Unix()%1e6, 36)
+	newClient := func(name string) *client {
+		return &client{name: name, http: httpClient, rec: rec}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Setup: GMs create campaigns, players register, create characters and join
+	log.Printf("Setting up %d campaigns with %d players against %s (run %s)", *campaigns, *agents, *target, run)
+	setupStart := time.Now()
+	gms := make([]*client, *campaigns)
+	campaignIDs := make([]int, *campaigns)
+	for i := range gms {
+		gm := newClient(fmt.Sprintf("LoadGM-%s-%d", run, i))
+		if err := gm.register(ctx); err != nil {
+			log.Fatalf("Setup failed: %v", err)
+		}
+		result, _, err := gm.do(ctx, "POST /api/campaigns", "POST", "/api/campaigns", map[string]interface{}{
+			"name":        fmt.Sprintf("Load Test %s #%d", run, i),
+			"setting":     "A synthetic dungeon for load testing.",
+			"max_players": *agents / *campaigns + 1,
+		})
+		if err != nil {
+			log.Fatalf("Setup failed: create campaign: %v", err)
+		}
+		gms[i] = gm
+		campaignIDs[i] = intField(result, "campaign_id")
+	}
+
+	players := make([]*client, *agents)
+	var setupWG sync.WaitGroup
+	setupErrs := make(chan error, *agents)
+	for i := range players {
+		players[i] = newClient(fmt.Sprintf("LoadPC-%s-%d", run, i))
+		setupWG.Add(1)
+		go func(i int, p *client) {
+			defer setupWG.Done()
+			if err := p.register(ctx); err != nil {
+				setupErrs <- err
+				return
+			}
+			result, _, err := p.do(ctx, "POST /api/characters", "POST", "/api/characters", map[string]string{
+				"name":  fmt.Sprintf("Loadhero %s %d", run, i),
+				"class": classes[i%len(classes)],
+				"race":  races[i%len(races)],
+			})
+			if err != nil {
+				setupErrs <- fmt.Errorf("create character for %s: %w", p.name, err)
+				return
+			}
+			campaignID := campaignIDs[i%len(campaignIDs)]
+			if _, _, err := p.do(ctx, "POST /api/campaigns/{id}/join", "POST", fmt.Sprintf("/api/campaigns/%d/join", campaignID), map[string]int{
+				"character_id": intField(result, "character_id"),
+			}); err != nil {
+				setupErrs <- fmt.Errorf("join campaign %d for %s: %w", campaignID, p.name, err)
+			}
+		}(i, players[i])
+	}
+	setupWG.Wait()
+	close(setupErrs)
+	for err := range setupErrs {
+		log.Printf("Setup error: %v", err)
+	}
+	for i, gm := range gms {
+		if _, _, err := gm.do(ctx, "POST /api/campaigns/{id}/start", "POST", fmt.Sprintf("/api/campaigns/%d/start", campaignIDs[i]), nil); err != nil {
+			log.Printf("Start campaign %d: %v", campaignIDs[i], err)
+		}
+	}
+	log.Printf("Setup done in %s; playing for %s", time.Since(setupStart).Round(time.Millisecond), *duration)
+	setupStats := rec.snapshot()
+
+	// Play: players poll and act, GMs poll status and narrate
+	playCtx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	playStart := time.Now()
+	var wg sync.WaitGroup
+	for _, p := range players {
+		if p.auth == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(p *client) {
+			defer wg.Done()
+			for n := 0; pause(playCtx); n++ {
+				turn, _, err := p.do(playCtx, "GET /api/my-turn", "GET", "/api/my-turn?verbosity=compact", nil)
+				if n%5 == 0 {
+					p.do(playCtx, "GET /api/heartbeat", "GET", "/api/heartbeat", nil)
+				}
+				if err != nil || turn["is_my_turn"] == false {
+					continue
+				}
+				a := playerActions[rand.Intn(len(playerActions))]
+				p.do(playCtx, "POST /api/action", "POST", "/api/action", map[string]string{
+					"action": a.action, "description": a.description,
+				})
+			}
+		}(p)
+	}
+	for i, gm := range gms {
+		wg.Add(1)
+		go func(i int, gm *client) {
+			defer wg.Done()
+			for n := 0; pause(playCtx); n++ {
+				gm.do(playCtx, "GET /api/gm/status", "GET", fmt.Sprintf("/api/gm/status?campaign_id=%d", campaignIDs[i]), nil)
+				if n%3 == 0 {
+					gm.do(playCtx, "POST /api/gm/narrate", "POST", "/api/gm/narrate", map[string]string{
+						"narration": fmt.Sprintf("Torchlight flickers as the party presses on (beat %d).", n/3+1),
+					})
+				}
+			}
+		}(i, gm)
+	}
+	wg.Wait()
+	elapsed := time.Since(playStart)
+
+	fmt.Println("\n== Setup ==")
+	printReport(setupStats, 0)
+	fmt.Println("\n== Play ==")
+	printReport(rec.since(setupStats), elapsed)
+}
+
+// snapshot copies the current stats so setup and play can be reported separately.
+func (r *recorder) snapshot() map[string]*endpointStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := map[string]*endpointStats{}
+	for label, s := range r.stats {
+		copied := &endpointStats{latencies: append([]time.Duration(nil), s.latencies...), statuses: map[int]int{}, failures: s.failures}
+		for code, n := range s.statuses {
+			copied.statuses[code] = n
+		}
+		out[label] = copied
+	}
+	return out
+}
+
+// since returns stats recorded after an earlier snapshot.
+func (r *recorder) since(before map[string]*endpointStats) map[string]*endpointStats {
+	now := r.snapshot()
+	for label, s := range now {
+		prev, ok := before[label]
+		if !ok {
+			continue
+		}
+		s.latencies = s.latencies[len(prev.latencies):]
+		s.failures -= prev.failures
+		for code, n := range prev.statuses {
+			s.statuses[code] -= n
+			if s.statuses[code] == 0 {
+				delete(s.statuses, code)
+			}
+		}
+		if len(s.latencies) == 0 {
+			delete(now, label)
+		}
+	}
+	return now
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+// printReport prints one line per endpoint; elapsed > 0 adds throughput.
+func printReport(stats map[string]*endpointStats, elapsed time.Duration) {
+	labels := make([]string, 0, len(stats))
+	for label := range stats {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	fmt.Printf("%-32s %7s %7s %9s %9s %9s %9s  %s\n", "endpoint", "count", "fail%", "p50", "p95", "p99", "max", "statuses")
+	total, failures := 0, 0
+	for _, label := range labels {
+		s := stats[label]
+		sorted := append([]time.Duration(nil), s.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		codes := []string{}
+		for code, n := range s.statuses {
+			codes = append(codes, fmt.Sprintf("%d×%d", code, n))
+		}
+		sort.Strings(codes)
+		fmt.Printf("%-32s %7d %6.1f%% %9s %9s %9s %9s  %s\n", label, len(sorted),
+			100*float64(s.failures)/float64(len(sorted)),
+			percentile(sorted, 0.50).Round(time.Millisecond), percentile(sorted, 0.95).Round(time.Millisecond),
+			percentile(sorted, 0.99).Round(time.Millisecond), sorted[len(sorted)-1].Round(time.Millisecond),
+			strings.Join(codes, " "))
+		total += len(sorted)
+		failures += s.failures
+	}
+	if total == 0 {
+		fmt.Println("(no requests)")
+		return
+	}
+	fmt.Printf("total %d requests, %d failures (%.1f%%)", total, failures, 100*float64(failures)/float64(total))
+	if elapsed > 0 {
+		fmt.Printf(", %.1f req/s over %s", float64(total)/elapsed.Seconds(), elapsed.Round(time.Second))
+	}
+	fmt.Println()
+}
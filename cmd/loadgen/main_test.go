@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientRecordsEachRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/register":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["name"] != "LoadPC-test-0" || req["password"] == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"agent_id": 42})
+		case "/api/my-turn":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"is_my_turn": true})
+		case "/api/action":
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_turn"})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	saved := *target
+	*target = srv.URL + "/"
+	defer func() { *target = saved }()
+
+	rec := &recorder{stats: map[string]*endpointStats{}}
+	c := &client{name: "LoadPC-test-0", http: srv.Client(), rec: rec}
+	ctx := context.Background()
+
+	if err := c.register(ctx); err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := base64.StdEncoding.DecodeString(c.auth)
+	if !strings.HasPrefix(string(creds), "42:loadgen-") {
+		t.Errorf("credentials %q, want agent 42 and the generated password", creds)
+	}
+	before := rec.snapshot()
+
+	if turn, status, err := c.do(ctx, "GET /api/my-turn", "GET", "/api/my-turn", nil); err != nil || status != 200 || turn["is_my_turn"] != true {
+		t.Errorf("my-turn = %v %d %v", turn, status, err)
+	}
+	if _, status, err := c.do(ctx, "POST /api/action", "POST", "/api/action", map[string]string{"action": "dodge"}); err == nil || status != 200 {
+		t.Errorf("an error in a 200 body should be an error: %d %v", status, err)
+	}
+	if _, status, err := c.do(ctx, "GET /api/heartbeat", "GET", "/api/heartbeat", nil); err == nil || status != 500 {
+		t.Errorf("a 500 should be an error: %d %v", status, err)
+	}
+
+	play := rec.since(before)
+	if _, ok := play["POST /api/register"]; ok {
+		t.Error("setup requests leaked into the play report")
+	}
+	for label, want := range map[string]struct{ count, failures, status int }{
+		"GET /api/my-turn":   {1, 0, 200},
+		"POST /api/action":   {1, 0, 200}, // Game errors aren't server failures
+		"GET /api/heartbeat": {1, 1, 500},
+	} {
+		s, ok := play[label]
+		if !ok || len(s.latencies) != want.count || s.failures != want.failures || s.statuses[want.status] != want.count {
+			t.Errorf("%s stats = %+v, want %d requests, %d failures, status %d", label, s, want.count, want.failures, want.status)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.50, 50 * time.Millisecond},
+		{0.95, 95 * time.Millisecond},
+		{0.99, 99 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+	} {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile of nothing = %s", got)
+	}
+}
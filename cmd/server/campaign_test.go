@@ -2,20 +2,24 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/agentrpg/agentrpg/game"
+	"github.com/lib/pq"
 )
 
 var setupRoutesOnce sync.Once
@@ -183,6 +187,130 @@ func testAgentRegistration(t *testing.T, prefix string) {
 	}
 }
 
+// TestAdminSeedDemo seeds a demo campaign and checks its players can play right away
+func TestAdminSeedDemo(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" && os.Getenv("TEST_DATABASE_URL") == "" {
+		t.Skip("No database URL set - skipping integration test")
+	}
+
+	initTestDB(t)
+	t.Setenv("ADMIN_KEY", "test-admin-key")
+	testPrefix := fmt.Sprintf("test_demo_%d_", time.Now().Unix())
+
+	body, _ := json.Marshal(map[string]interface{}{"name": testPrefix + "Demo", "players": 3})
+	req := httptest.NewRequest("POST", "/api/admin/seed-demo", bytes.NewReader(body))
+	req.Header.Set("X-Admin-Key", "test-admin-key")
+	rr := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(rr, req)
+
+	var result map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("seed-demo returned %d: %v", rr.Code, result)
+	}
+	campaignID := int(result["campaign_id"].(float64))
+	agentIDs := []interface{}{result["gm"].(map[string]interface{})["agent_id"]}
+	players := result["players"].([]interface{})
+	for _, p := range players {
+		agentIDs = append(agentIDs, p.(map[string]interface{})["agent_id"])
+	}
+	defer func() {
+		db.Exec("DELETE FROM characters WHERE lobby_id = $1", campaignID)
+		cleanupTestData(t, testPrefix)
+		for _, id := range agentIDs {
+			db.Exec("DELETE FROM agents WHERE id = $1", int(id.(float64)))
+		}
+	}()
+
+	if len(players) != 3 {
+		t.Errorf("got %d players, want 3", len(players))
+	}
+	if order := result["turn_order"].([]interface{}); len(order) != 3+len(demoEncounter) {
+		t.Errorf("turn order has %d combatants, want %d", len(order), 3+len(demoEncounter))
+	}
+	var active bool
+	db.QueryRow("SELECT active FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&active)
+	if !active {
+		t.Error("demo combat should be active")
+	}
+
+	auth := strings.TrimPrefix(players[0].(map[string]interface{})["authorization"].(string), "Basic ")
+	code, turn := makeRequest(t, "GET", "/api/my-turn", nil, auth)
+	if code != http.StatusOK || turn["error"] != nil {
+		t.Errorf("my-turn for a demo player: %d %v", code, turn["error"])
+	}
+}
+
+// TestCharacterLevelUp covers starting multiclassed and taking levels via /levelup
+func TestCharacterLevelUp(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" && os.Getenv("TEST_DATABASE_URL") == "" {
+		t.Skip("No database URL set - skipping integration test")
+	}
+
+	initTestDB(t)
+	testPrefix := fmt.Sprintf("test_levelup_%d_", time.Now().Unix())
+	defer cleanupTestData(t, testPrefix)
+
+	_, result := makeRequest(t, "POST", "/api/register", map[string]interface{}{
+		"name":     testPrefix + "Player",
+		"password": "test123",
+	}, "")
+	playerID := int(result["agent_id"].(float64))
+	playerAuth := createAuth(fmt.Sprintf("%d", playerID), "test123")
+
+	_, result = makeRequest(t, "POST", "/api/characters", map[string]interface{}{
+		"name": testPrefix + "Spellblade", "class": "fighter", "race": "human",
+		"str": 14, "dex": 12, "con": 14, "int": 13, "wis": 10, "cha": 8,
+		"class_levels": map[string]int{"fighter": 2, "wizard": 1},
+	}, playerAuth)
+	if result["error"] != nil {
+		t.Fatalf("create multiclass character: %v", result)
+	}
+	charID := int(result["character_id"].(float64))
+	if result["level"].(float64) != 3 {
+		t.Errorf("starting level = %v, want 3", result["level"])
+	}
+
+	_, result = makeRequest(t, "POST", "/api/characters", map[string]interface{}{
+		"name": testPrefix + "Dullard", "class": "fighter", "race": "human", "int": 8,
+		"class_levels": map[string]int{"fighter": 1, "wizard": 1},
+	}, playerAuth)
+	if result["error"] != "prerequisites_not_met" {
+		t.Errorf("INT 9 wizard multiclass: got %v, want prerequisites_not_met", result["error"])
+	}
+
+	path := fmt.Sprintf("/api/characters/%d/levelup", charID)
+	code, result := makeRequest(t, "POST", path, map[string]interface{}{"class": "wizard"}, playerAuth)
+	if code != http.StatusBadRequest || result["error"] != "no_level_available" {
+		t.Errorf("level up without XP: %d %v", code, result["error"])
+	}
+
+	db.Exec("UPDATE characters SET xp = $1 WHERE id = $2", game.XPThresholds[4], charID)
+	code, result = makeRequest(t, "POST", path, map[string]interface{}{"class": "wizard"}, playerAuth)
+	if code != http.StatusOK {
+		t.Fatalf("level up: %d %v", code, result)
+	}
+	if levels := result["class_levels"].(map[string]interface{}); levels["wizard"].(float64) != 2 || levels["fighter"].(float64) != 2 {
+		t.Errorf("class_levels = %v, want fighter 2 / wizard 2", levels)
+	}
+	if result["asi_earned"].(float64) != 2 {
+		t.Errorf("level 4 should earn an ASI, got %v", result["asi_earned"])
+	}
+
+	var rows, level int
+	db.QueryRow("SELECT COUNT(*) FROM character_classes WHERE character_id = $1", charID).Scan(&rows)
+	db.QueryRow("SELECT level FROM characters WHERE id = $1", charID).Scan(&level)
+	if rows != 2 || level != 4 {
+		t.Errorf("character_classes rows = %d, level = %d; want 2 and 4", rows, level)
+	}
+
+	// Spending a d6 leaves the d10 pool alone
+	_, result = makeRequest(t, "POST", fmt.Sprintf("/api/characters/%d/short-rest", charID), map[string]interface{}{"hit_dice": 1, "hit_die": 6}, playerAuth)
+	if dice := result["dice_spent"].([]interface{}); len(dice) != 1 || dice[0] != "d6" {
+		t.Errorf("dice_spent = %v, want [d6]", dice)
+	}
+}
+
 // TestCampaignCreationAndJoining tests campaign creation and joining flow
 func TestCampaignCreationAndJoining(t *testing.T) {
 	if os.Getenv("DATABASE_URL") == "" && os.Getenv("TEST_DATABASE_URL") == "" {
@@ -543,6 +671,757 @@ func TestDedupeRecentCampaignActions(t *testing.T) {
 	}
 }
 
+func TestTrimMyTurnResponse(t *testing.T) {
+	response := map[string]interface{}{
+		"is_my_turn":     true,
+		"how_to_act":     map[string]interface{}{"endpoint": "POST /api/action"},
+		"rules_reminder": []string{"Attack: d20 + mod"},
+		"story_so_far":   "The party fled the crypt",
+		"recent_events":  []string{"e5", "e4", "e3", "e2", "e1"},
+		"situation":      map[string]interface{}{"summary": "You are in the crypt.", "in_combat": true},
+		"indomitable": map[string]interface{}{
+			"uses_remaining": 1,
+			"description":    "When you fail a saving throw...",
+			"tip":            "You have 1 Indomitable use!",
+		},
+		"frightened_warning": map[string]interface{}{"source_id": 3, "warning": "FRIGHTENED", "movement_rules": "Move away"},
+		"readied_action":     map[string]interface{}{"description": "I shoot the first goblin through the door"},
+		"character": map[string]interface{}{
+			"conditions":            []map[string]interface{}{{"name": "poisoned", "description": "Disadvantage on attacks and checks"}},
+			"inventory":             []interface{}{map[string]interface{}{"name": "Rope", "description": "50 feet of hempen rope", "note": "frayed"}},
+			"concentration_warning": "Concentrating on Bless",
+		},
+		"combat": map[string]interface{}{"opportunity_attack_warning": "Leaving reach provokes", "warning": "Turn timeout soon"},
+	}
+
+	standard := trimMyTurnResponse(response, "standard")
+	if _, ok := standard["how_to_act"]; ok {
+		t.Error("standard kept how_to_act")
+	}
+	if _, ok := standard["story_so_far"]; !ok {
+		t.Error("standard dropped story_so_far")
+	}
+	indomitable := standard["indomitable"].(map[string]interface{})
+	if _, ok := indomitable["description"]; ok || indomitable["uses_remaining"] != 1 {
+		t.Errorf("standard indomitable = %v", indomitable)
+	}
+	if readied := standard["readied_action"].(map[string]interface{}); readied["description"] == nil {
+		t.Error("standard dropped the readied action's player text")
+	}
+
+	compact := trimMyTurnResponse(response, "compact")
+	for _, key := range []string{"story_so_far", "rules_reminder"} {
+		if _, ok := compact[key]; ok {
+			t.Errorf("compact kept %s", key)
+		}
+	}
+	// Game state deeper in the payload survives, tutorial-sounding names or not.
+	character := compact["character"].(map[string]interface{})
+	if conditions := character["conditions"].([]map[string]interface{}); conditions[0]["description"] == nil {
+		t.Error("compact dropped a condition's description")
+	}
+	if item := character["inventory"].([]interface{})[0].(map[string]interface{}); item["description"] == nil || item["note"] == nil {
+		t.Errorf("compact trimmed an inventory item: %v", item)
+	}
+	if character["concentration_warning"] == nil {
+		t.Error("compact dropped the concentration warning")
+	}
+	if combat := compact["combat"].(map[string]interface{}); combat["opportunity_attack_warning"] == nil || combat["warning"] == nil {
+		t.Errorf("compact dropped combat warnings: %v", combat)
+	}
+	frightened := compact["frightened_warning"].(map[string]interface{})
+	if frightened["source_id"] != 3 || frightened["warning"] == nil || frightened["movement_rules"] != nil {
+		t.Errorf("compact frightened_warning = %v, want the state without the rules prose", frightened)
+	}
+	if events := compact["recent_events"].([]string); len(events) != 3 || events[0] != "e5" {
+		t.Errorf("compact recent_events = %v, want the newest 3", events)
+	}
+	if _, ok := compact["situation"].(map[string]interface{})["summary"]; ok {
+		t.Error("compact kept the situation summary")
+	}
+
+	// The full payload is untouched
+	if _, ok := response["how_to_act"]; !ok {
+		t.Error("trimming modified the original response")
+	}
+	if _, ok := response["indomitable"].(map[string]interface{})["tip"]; !ok {
+		t.Error("trimming modified a nested object of the original response")
+	}
+}
+
+func TestNudgeRetryAfter(t *testing.T) {
+	now := time.Date(2026, 5, 23, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+
+	if wait := nudgeRetryAfter(nil, now); wait != 0 {
+		t.Errorf("first nudge: wait %v, want 0", wait)
+	}
+	if wait := nudgeRetryAfter([]time.Time{ago(20 * time.Minute)}, now); wait != 40*time.Minute {
+		t.Errorf("within cooldown: wait %v, want 40m", wait)
+	}
+	if wait := nudgeRetryAfter([]time.Time{ago(2 * time.Hour), ago(5 * time.Hour)}, now); wait != 0 {
+		t.Errorf("two today: wait %v, want 0", wait)
+	}
+	if wait := nudgeRetryAfter([]time.Time{ago(2 * time.Hour), ago(5 * time.Hour), ago(20 * time.Hour)}, now); wait != 4*time.Hour {
+		t.Errorf("daily limit: wait %v, want 4h until the oldest ages out", wait)
+	}
+}
+
+func TestDBPoolSettings(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	s := dbPoolSettingsFrom(env(nil))
+	if s.MaxOpen != 0 || s.MaxIdle != 10 || s.MaxLifetime != 30*time.Minute || s.MaxIdleTime != 5*time.Minute || !s.Prepared {
+		t.Errorf("defaults = %+v", s)
+	}
+	s = dbPoolSettingsFrom(env(map[string]string{
+		"DB_MAX_OPEN_CONNS": "4", "DB_MAX_IDLE_CONNS": "8", "DB_CONN_MAX_LIFETIME_SECONDS": "60",
+		"DB_CONN_MAX_IDLE_SECONDS": "junk", "DB_PREPARED_STATEMENTS": "false",
+	}))
+	if s.MaxOpen != 4 || s.MaxIdle != 4 || s.MaxLifetime != time.Minute || s.MaxIdleTime != 5*time.Minute || s.Prepared {
+		t.Errorf("configured = %+v (idle capped at open, junk ignored)", s)
+	}
+}
+
+func TestRenderNudge(t *testing.T) {
+	got := renderNudge(nudgeTemplates["urgent"], "Fable", "The Crypt", formatWaiting(3*time.Hour+20*time.Minute))
+	want := `Fable! The table in "The Crypt" has been waiting 3h 20m. Act soon or your turn may be skipped.`
+	if got != want {
+		t.Errorf("renderNudge = %q, want %q", got, want)
+	}
+}
+
+func TestAutoNarrationLine(t *testing.T) {
+	tests := []struct {
+		actionType, description, result string
+		want                            string
+	}{
+		{"attack", "I swing at the goblin", "Attack roll: 17 vs AC 13 - HIT! Damage: 7 slashing damage", "Thorin attacks and hits for 7 slashing damage."},
+		{"attack", "", "Attack with longsword: 15 to hit. Damage: 6", "Thorin attacks (15 to hit) for 6 damage."},
+		{"attack", "", "Attack roll: 3 (nat 1 - Critical miss!)", "Thorin attacks and misses badly."},
+		{"cast", "Fire bolt", "Spell attack: 20 (CRITICAL HIT!) 14 fire damage", "Thorin casts a spell and lands a critical hit for 14 fire damage."},
+		{"use_item", "I drink a potion of healing", "Healed 7 HP", "Thorin: I drink a potion of healing."},
+	}
+	for _, tt := range tests {
+		if got := autoNarrationLine("Thorin", tt.actionType, tt.description, tt.result); got != tt.want {
+			t.Errorf("autoNarrationLine(%s, %q) = %q, want %q", tt.actionType, tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestTallyVotes(t *testing.T) {
+	options := []string{"left", "right", "back"}
+	tests := []struct {
+		ballots []string
+		counts  []int
+		winners []string
+	}{
+		{nil, []int{0, 0, 0}, nil},
+		{[]string{"right", "left", "right"}, []int{1, 2, 0}, []string{"right"}},
+		{[]string{"left", "right"}, []int{1, 1, 0}, []string{"left", "right"}},
+		{[]string{"back", "sideways"}, []int{0, 0, 1}, []string{"back"}},
+	}
+	for _, tt := range tests {
+		counts, winners := tallyVotes(options, tt.ballots)
+		if !reflect.DeepEqual(counts, tt.counts) || !reflect.DeepEqual(winners, tt.winners) {
+			t.Errorf("tallyVotes(%v) = %v, %v; want %v, %v", tt.ballots, counts, winners, tt.counts, tt.winners)
+		}
+	}
+}
+
+func TestVoteDecided(t *testing.T) {
+	tests := []struct {
+		counts   []int
+		eligible int
+		want     bool
+	}{
+		{[]int{0, 0}, 4, false},
+		{[]int{2, 1}, 4, false}, // The last ballot could tie it
+		{[]int{3, 0}, 4, true},  // Majority
+		{[]int{2, 2}, 4, true},  // Everyone voted (tied)
+		{[]int{1, 0}, 1, true},
+		{[]int{0, 0}, 0, false},
+	}
+	for _, tt := range tests {
+		if got := voteDecided(tt.counts, tt.eligible); got != tt.want {
+			t.Errorf("voteDecided(%v, %d) = %v, want %v", tt.counts, tt.eligible, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeVoteOptions(t *testing.T) {
+	if options, msg := normalizeVoteOptions([]string{" Left ", "", "right"}); msg != "" || !reflect.DeepEqual(options, []string{"Left", "right"}) {
+		t.Errorf("normalizeVoteOptions trimmed = %v, %q", options, msg)
+	}
+	if _, msg := normalizeVoteOptions([]string{"left", "LEFT"}); msg == "" {
+		t.Error("duplicate options should be rejected")
+	}
+	if _, msg := normalizeVoteOptions([]string{"only"}); msg == "" {
+		t.Error("a single option should be rejected")
+	}
+	if got := matchVoteOption([]string{"Left", "Right"}, " right"); got != "Right" {
+		t.Errorf("matchVoteOption = %q, want Right", got)
+	}
+}
+
+func TestActivePlayTime(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 5, 23, h, m, 0, 0, time.UTC) }
+	windows := []playWindow{
+		{at(19, 0), at(22, 0)},
+		{at(9, 0), at(10, 0)},
+		{at(21, 0), at(23, 0)}, // Overlaps the first
+	}
+	tests := []struct {
+		from, to time.Time
+		want     time.Duration
+	}{
+		{at(8, 0), at(12, 0), time.Hour},
+		{at(9, 30), at(20, 0), 90 * time.Minute},
+		{at(20, 0), at(23, 30), 3 * time.Hour},
+		{at(11, 0), at(18, 0), 0},
+	}
+	for _, tt := range tests {
+		if got := activePlayTime(tt.from, tt.to, windows); got != tt.want {
+			t.Errorf("activePlayTime(%s, %s) = %v, want %v", tt.from.Format("15:04"), tt.to.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestAvailableAt(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+	windows := []availabilityWindow{
+		{Days: []string{"sat"}, Start: "19:00", End: "23:00"},
+		{Days: []string{"fri"}, Start: "22:00", End: "02:00"}, // Overnight
+	}
+	// 2026-05-23 is a Saturday
+	tests := []struct {
+		when time.Time
+		want bool
+	}{
+		{time.Date(2026, 5, 23, 20, 0, 0, 0, est), true},
+		{time.Date(2026, 5, 24, 1, 0, 0, 0, time.UTC), true}, // 20:00 EST Saturday
+		{time.Date(2026, 5, 23, 18, 59, 0, 0, est), false},
+		{time.Date(2026, 5, 23, 1, 30, 0, 0, est), true}, // Friday night's window
+		{time.Date(2026, 5, 24, 1, 30, 0, 0, est), false},
+		{time.Date(2026, 5, 22, 23, 0, 0, 0, est), true},
+	}
+	for _, tt := range tests {
+		if got := availableAt(tt.when, est, windows); got != tt.want {
+			t.Errorf("availableAt(%s) = %v, want %v", tt.when.In(est).Format("Mon 15:04"), got, tt.want)
+		}
+	}
+	start := time.Date(2026, 5, 23, 19, 0, 0, 0, est)
+	if !fitsAvailability(start, start.Add(4*time.Hour), est, windows) {
+		t.Error("a session filling the Saturday window fits")
+	}
+	if fitsAvailability(start, start.Add(5*time.Hour), est, windows) {
+		t.Error("a session running past the window doesn't fit")
+	}
+}
+
+func TestNormalizeAvailability(t *testing.T) {
+	windows, msg := normalizeAvailability([]availabilityWindow{{Days: []string{"Monday", "WED", "mon"}, Start: "18:00", End: "22:30"}})
+	if msg != "" || !reflect.DeepEqual(windows[0].Days, []string{"mon", "wed"}) {
+		t.Errorf("normalizeAvailability = %v, %q", windows, msg)
+	}
+	for _, bad := range []availabilityWindow{
+		{Start: "7pm", End: "23:00"},
+		{Start: "19:00", End: "19:00"},
+		{Days: []string{"someday"}, Start: "19:00", End: "23:00"},
+	} {
+		if _, msg := normalizeAvailability([]availabilityWindow{bad}); msg == "" {
+			t.Errorf("normalizeAvailability(%+v) accepted an invalid window", bad)
+		}
+	}
+}
+
+func TestParseSessionStart(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*3600)
+	want := time.Date(2026, 5, 23, 17, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2026-05-23T19:00:00+02:00", "2026-05-23 19:00", "2026-05-23T19:00"} {
+		if got, ok := parseSessionStart(s, berlin); !ok || !got.Equal(want) {
+			t.Errorf("parseSessionStart(%q) = %v, %v; want %v", s, got, ok, want)
+		}
+	}
+	if _, ok := parseSessionStart("saturday evening", berlin); ok {
+		t.Error("parseSessionStart accepted free text")
+	}
+}
+
+func TestD20RollRecordTotal(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec     d20RollRecord
+		d20     int
+		total   int
+		success bool
+	}{
+		{"check", d20RollRecord{Kind: "check", Modifier: 5, DC: 15}, 9, 14, false},
+		{"check meets DC", d20RollRecord{Kind: "check", Modifier: 5, DC: 15}, 10, 15, true},
+		{"reliable talent", d20RollRecord{Kind: "check", Modifier: 7, DC: 17, MinD20: 10}, 2, 17, true},
+		{"indomitable might", d20RollRecord{Kind: "check", Modifier: 4, DC: 20, MinTotal: 20}, 3, 20, true},
+		{"save natural 20", d20RollRecord{Kind: "save", Modifier: -1, DC: 25}, 20, 19, true},
+		{"save natural 1", d20RollRecord{Kind: "save", Modifier: 12, DC: 10}, 1, 13, false},
+	}
+	for _, tt := range tests {
+		total, success := tt.rec.total(tt.d20)
+		if total != tt.total || success != tt.success {
+			t.Errorf("%s: total(%d) = %d, %v; want %d, %v", tt.name, tt.d20, total, success, tt.total, tt.success)
+		}
+	}
+}
+
+func TestParseProficiencyList(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", []string{}},
+		{"thieves' tools, smith's tools", []string{"thieves' tools", "smith's tools"}},
+		{"Common,Elvish,", []string{"Common", "Elvish"}},
+		{`["Common","Dwarvish"]`, []string{"Common", "Dwarvish"}}, // Written by downtime training before v1.0.61
+	}
+	for _, tt := range tests {
+		if got := parseProficiencyList(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseProficiencyList(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestEquipStartingItems(t *testing.T) {
+	tests := []struct {
+		items                    []game.StartingItem
+		armor, mainHand, offHand string
+		shield                   bool
+	}{
+		{[]game.StartingItem{{Name: "Chain Mail", Kind: game.ItemKindArmor, Quantity: 1}, {Name: "Longsword", Kind: game.ItemKindWeapon, Quantity: 1}, {Name: "Shield", Kind: game.ItemKindShield, Quantity: 1}},
+			"chain-mail", "Longsword", "", true},
+		{[]game.StartingItem{{Name: "Leather Armor", Kind: game.ItemKindArmor, Quantity: 1}, {Name: "Longbow", Kind: game.ItemKindWeapon, Quantity: 1}, {Name: "Shortsword", Kind: game.ItemKindWeapon, Quantity: 2}},
+			"leather-armor", "Shortsword", "Shortsword", false}, // Two light weapons: one in each hand
+		{[]game.StartingItem{{Name: "Greataxe", Kind: game.ItemKindWeapon, Quantity: 1}, {Name: "Handaxe", Kind: game.ItemKindWeapon, Quantity: 2}},
+			"", "Greataxe", "", false},
+		{[]game.StartingItem{{Name: "Light Crossbow", Kind: game.ItemKindWeapon, Quantity: 1}, {Name: "Crossbow Bolts", Kind: game.ItemKindAmmo, Quantity: 20}},
+			"", "Light Crossbow", "", false}, // No melee weapon
+		{[]game.StartingItem{{Name: "Greatsword", Kind: game.ItemKindWeapon, Quantity: 1}, {Name: "Shield", Kind: game.ItemKindShield, Quantity: 1}, {Name: "Handaxe", Kind: game.ItemKindWeapon, Quantity: 2}},
+			"", "Handaxe", "", true}, // The shield rules out the two-handed weapon
+		{[]game.StartingItem{{Name: "Greatsword", Kind: game.ItemKindWeapon, Quantity: 1}, {Name: "Shield", Kind: game.ItemKindShield, Quantity: 1}},
+			"", "Greatsword", "", false}, // Only a two-handed weapon: the shield stays in the pack
+	}
+	for _, tt := range tests {
+		armor, shield, mainHand, offHand := equipStartingItems(tt.items)
+		if armor != tt.armor || shield != tt.shield || mainHand != tt.mainHand || offHand != tt.offHand {
+			t.Errorf("equipStartingItems(%v) = %q, %v, %q, %q; want %q, %v, %q, %q",
+				tt.items, armor, shield, mainHand, offHand, tt.armor, tt.shield, tt.mainHand, tt.offHand)
+		}
+	}
+}
+
+func TestObservationVisibleTo(t *testing.T) {
+	const gm, owner, other = 1, 2, 3
+	tests := []struct {
+		name    string
+		private bool
+		viewer  int
+		want    bool
+	}{
+		{"public to anyone", false, 0, true},
+		{"vision to its recipient", true, owner, true},
+		{"vision to the GM", true, gm, true},
+		{"vision hidden from party", true, other, false},
+		{"vision hidden from anonymous", true, 0, false},
+	}
+	for _, tt := range tests {
+		if got := observationVisibleTo(tt.private, owner, gm, tt.viewer); got != tt.want {
+			t.Errorf("%s: observationVisibleTo() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCampaignDocByKnowledge(t *testing.T) {
+	doc := map[string]interface{}{
+		"story_so_far": "You wake in the library.",
+		"sections": []interface{}{
+			map[string]interface{}{"title": "The stacks", "content": "Endless shelves."},
+			map[string]interface{}{"title": "Your true name", "content": "Veyra.", "requires_knowledge": "true_name"},
+		},
+		"npcs": []interface{}{
+			map[string]interface{}{"name": "The Archivist", "requires_knowledge": []interface{}{"true_name", "Archivist Lies"}},
+		},
+		"lore": map[string]interface{}{"requires_knowledge": "engine"},
+	}
+
+	got := filterCampaignDocByKnowledge(doc, map[string]bool{})
+	if n := len(got["sections"].([]interface{})); n != 1 {
+		t.Errorf("unknown: %d sections, want 1", n)
+	}
+	if n := len(got["npcs"].([]interface{})); n != 0 {
+		t.Errorf("unknown: %d npcs, want 0", n)
+	}
+	if _, ok := got["lore"]; ok {
+		t.Error("unknown: gated lore shown")
+	}
+	if got["story_so_far"] != "You wake in the library." {
+		t.Error("ungated fields must pass through")
+	}
+
+	got = filterCampaignDocByKnowledge(doc, map[string]bool{"true_name": true, "engine": false})
+	if n := len(got["sections"].([]interface{})); n != 2 {
+		t.Errorf("true_name known: %d sections, want 2", n)
+	}
+	if n := len(got["npcs"].([]interface{})); n != 0 {
+		t.Errorf("one of two flags known: %d npcs, want 0", n)
+	}
+	if _, ok := got["lore"]; ok {
+		t.Error("flag marked unknown must stay hidden")
+	}
+
+	got = filterCampaignDocByKnowledge(doc, map[string]bool{"true_name": true, "archivist_lies": true})
+	if n := len(got["npcs"].([]interface{})); n != 1 {
+		t.Errorf("both flags known: %d npcs, want 1", n)
+	}
+
+	if gates := knowledgeGates(doc); !reflect.DeepEqual(gates, []string{"archivist_lies", "engine", "true_name"}) {
+		t.Errorf("knowledgeGates() = %v", gates)
+	}
+}
+
+func TestEventHub(t *testing.T) {
+	hub := newEventHub()
+	a1, a2 := hub.subscribe(7), hub.subscribe(7)
+	other := hub.subscribe(8)
+
+	if n := hub.publish(7, eventYourTurn, map[string]interface{}{"character_id": 3}); n != 2 {
+		t.Errorf("publish to two streams delivered %d", n)
+	}
+	for _, ch := range []chan streamEvent{a1, a2} {
+		select {
+		case ev := <-ch:
+			if ev.Event != eventYourTurn || ev.Data["character_id"] != 3 || ev.ID == 0 {
+				t.Errorf("got %+v", ev)
+			}
+		default:
+			t.Error("stream got no event")
+		}
+	}
+	select {
+	case ev := <-other:
+		t.Errorf("other agent got %+v", ev)
+	default:
+	}
+
+	// A stream that stops reading misses events instead of blocking the publisher
+	for i := 0; i < cap(a1)+5; i++ {
+		hub.publish(7, eventGMNarrated, nil)
+	}
+	if len(a1) != cap(a1) {
+		t.Errorf("full stream holds %d events, want %d", len(a1), cap(a1))
+	}
+
+	hub.unsubscribe(7, a1)
+	hub.unsubscribe(7, a2)
+	hub.unsubscribe(8, other)
+	if len(hub.subs) != 0 || hub.publish(7, eventYourTurn, nil) != 0 {
+		t.Error("hub should be empty after every stream closes")
+	}
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	tests := []struct {
+		url  string
+		ms   int
+		want string
+	}{
+		{"postgres://u:p@host/db", 20000, "postgres://u:p@host/db?statement_timeout=20000"},
+		{"postgres://u:p@host/db?sslmode=require", 5000, "postgres://u:p@host/db?sslmode=require&statement_timeout=5000"},
+		{"host=localhost dbname=rpg", 5000, "host=localhost dbname=rpg statement_timeout=5000"},
+		{"postgres://host/db?statement_timeout=100", 5000, "postgres://host/db?statement_timeout=100"},
+		{"postgres://host/db", 0, "postgres://host/db"},
+	}
+	for _, tt := range tests {
+		if got := withStatementTimeout(tt.url, tt.ms); got != tt.want {
+			t.Errorf("withStatementTimeout(%q, %d) = %q, want %q", tt.url, tt.ms, got, tt.want)
+		}
+	}
+}
+
+func TestDBTimedOut(t *testing.T) {
+	timedOut := []error{
+		context.DeadlineExceeded,
+		fmt.Errorf("scan: %w", context.Canceled),
+		&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"},
+	}
+	for _, err := range timedOut {
+		if !dbTimedOut(err) {
+			t.Errorf("dbTimedOut(%v) = false", err)
+		}
+	}
+	for _, err := range []error{nil, fmt.Errorf("invalid credentials"), &pq.Error{Code: "23505"}} {
+		if dbTimedOut(err) {
+			t.Errorf("dbTimedOut(%v) = true", err)
+		}
+	}
+}
+
+func TestWithRequestDeadline(t *testing.T) {
+	var hasDeadline bool
+	handler := withRequestDeadline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline = r.Context().Deadline()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/my-turn", nil))
+	if !hasDeadline {
+		t.Error("API requests should carry a deadline")
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/events/stream", nil))
+	if hasDeadline {
+		t.Error("the event stream must not time out")
+	}
+
+	rec := httptest.NewRecorder()
+	writeDBTimeout(rec)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("writeDBTimeout: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestDeadlineWriter(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	rec := httptest.NewRecorder()
+	w := &deadlineWriter{ResponseWriter: rec, ctx: expired}
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"error":"character_not_found"}`))
+	if rec.Code != http.StatusServiceUnavailable || strings.Contains(rec.Body.String(), "character_not_found") {
+		t.Errorf("answer after the deadline: %d %s; want 503 database_timeout only", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	w = &deadlineWriter{ResponseWriter: rec, ctx: context.Background()}
+	w.Write([]byte(`{"success":true}`))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"success":true}` {
+		t.Errorf("answer in time: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"my_turn"}`)
+	want := "sha256=137eb1f6984868270cda32085576982b65a9b3a187e2cca98abab5df02f00525"
+	if got := signWebhookPayload("whsec_test", 1700000000, body); got != want {
+		t.Errorf("signWebhookPayload() = %q, want %q", got, want)
+	}
+	if signWebhookPayload("whsec_test", 1700000001, body) == want {
+		t.Error("the signature should cover the timestamp")
+	}
+	if signWebhookPayload("whsec_other", 1700000000, body) == want {
+		t.Error("the signature should depend on the secret")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	var total time.Duration
+	for attempts := 1; ; attempts++ {
+		delay, ok := webhookRetryDelay(attempts)
+		if !ok {
+			if attempts != len(webhookRetryDelays)+1 {
+				t.Errorf("retries ran out after %d attempts", attempts-1)
+			}
+			break
+		}
+		if delay <= 0 {
+			t.Errorf("attempt %d: delay %v", attempts, delay)
+		}
+		total += delay
+	}
+	if _, ok := webhookRetryDelay(0); ok {
+		t.Error("a delivery that was never attempted has no retry delay")
+	}
+	if total < time.Hour {
+		t.Errorf("retries give up after %v; receivers should get over an hour to recover", total)
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	for _, event := range []string{eventYourTurn, eventGMNarrated, eventCombatStarted, eventLevelUp, eventCheckRequested, eventCheckResolved} {
+		if webhookEventNames[event] == "" {
+			t.Errorf("stream event %q has no webhook name", event)
+		}
+	}
+	events, bad := normalizeWebhookEvents([]string{" My_Turn", "narration", "my_turn", ""})
+	if bad != "" || len(events) != 2 || events[0] != "my_turn" || events[1] != "narration" {
+		t.Errorf("normalizeWebhookEvents() = %v, %q", events, bad)
+	}
+	if _, bad := normalizeWebhookEvents([]string{"my_turn", "your_turn"}); bad != "your_turn" {
+		t.Errorf("bad event = %q, want your_turn", bad)
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	ctx := context.Background()
+	for _, url := range []string{
+		"http://example.com/hook",
+		"https://",
+		"ftp://example.com",
+		"https://user:pw@8.8.8.8/hook",
+	} {
+		if err := checkWebhookURL(ctx, url); err == nil || errors.Is(err, errWebhookAddress) {
+			t.Errorf("checkWebhookURL(%q) = %v, want a bad URL", url, err)
+		}
+	}
+	for _, url := range []string{
+		"https://127.0.0.1/hook",
+		"https://localhost:8443/hook",
+		"https://10.0.0.5/hook",
+		"https://192.168.1.1/hook",
+		"https://172.16.0.1/hook",
+		"https://169.254.169.254/latest/meta-data/",
+		"https://0.0.0.0/hook",
+		"https://[::1]/hook",
+		"https://[fe80::1]/hook",
+		"https://[fd00::1]/hook",
+		"https://[::ffff:127.0.0.1]/hook",
+	} {
+		if err := checkWebhookURL(ctx, url); !errors.Is(err, errWebhookAddress) {
+			t.Errorf("checkWebhookURL(%q) = %v, want %v", url, err, errWebhookAddress)
+		}
+	}
+	if err := checkWebhookURL(ctx, "https://8.8.8.8/hook"); err != nil {
+		t.Errorf("public address refused: %v", err)
+	}
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	// The dial check runs after DNS, so a host that passed registration and now resolves to
+	// loopback (DNS rebinding) is still refused.
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the webhook client reached a loopback receiver")
+	}))
+	defer receiver.Close()
+	_, err := webhookClient.Post(receiver.URL, "application/json", strings.NewReader(`{}`))
+	if !errors.Is(err, errWebhookAddress) {
+		t.Errorf("delivery to %s: err = %v, want %v", receiver.URL, err, errWebhookAddress)
+	}
+	if err := webhookDialControl("tcp", "169.254.169.254:80", nil); !errors.Is(err, errWebhookAddress) {
+		t.Errorf("metadata address: %v", err)
+	}
+	if err := webhookDialControl("tcp", "8.8.8.8:443", nil); err != nil {
+		t.Errorf("public address: %v", err)
+	}
+
+	// Redirects aren't followed: a receiver can't bounce a delivery to an internal host.
+	redirect, _ := http.NewRequest("GET", "https://169.254.169.254/", nil)
+	if err := webhookClient.CheckRedirect(redirect, []*http.Request{{}}); err != http.ErrUseLastResponse {
+		t.Errorf("CheckRedirect = %v, want http.ErrUseLastResponse", err)
+	}
+}
+
+func TestDemoParty(t *testing.T) {
+	if len(demoParty) < 4 {
+		t.Fatalf("demo party has %d characters; seeding allows up to 4", len(demoParty))
+	}
+	for _, c := range demoParty {
+		classKey := strings.ToLower(c.Class)
+		if _, ok := srdClasses[classKey]; !ok {
+			t.Errorf("%s: unknown class %q", c.Name, c.Class)
+		}
+		if _, ok := srdRaces[strings.ToLower(strings.ReplaceAll(c.Race, " ", "_"))]; !ok {
+			t.Errorf("%s: unknown race %q", c.Name, c.Race)
+		}
+		if game.GetBackground(c.Background) == nil {
+			t.Errorf("%s: unknown background %q", c.Name, c.Background)
+		}
+		choices, picks := game.DefaultStartingEquipment(classKey)
+		if _, issues := game.ResolveStartingEquipment(classKey, choices, picks, weaponCategoryRange); len(issues) > 0 {
+			t.Errorf("%s: starting equipment: %v", c.Name, issues)
+		}
+		seen := map[string]bool{}
+		for _, skill := range demoSkills(c) {
+			if seen[skill] {
+				t.Errorf("%s: skill %q listed twice", c.Name, skill)
+			}
+			seen[skill] = true
+		}
+	}
+	if hp := demoMaxHP("fighter", 15, 1); hp != 12 {
+		t.Errorf("level 1 fighter with CON 15: %d HP, want 12", hp)
+	}
+	if hp := demoMaxHP("fighter", 15, 3); hp != 28 {
+		t.Errorf("level 3 fighter with CON 15: %d HP, want 28", hp)
+	}
+}
+
+func TestClassesFromLevels(t *testing.T) {
+	got := classesFromLevels("Fighter", 5, map[string]int{"wizard": 2, "fighter": 3})
+	want := []game.ClassLevel{{Class: "fighter", Level: 3}, {Class: "wizard", Level: 2, Position: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("classesFromLevels() = %+v, want %+v", got, want)
+	}
+	// Single class (or empty class_levels) follows the character's level
+	got = classesFromLevels("rogue", 4, map[string]int{"rogue": 1})
+	if len(got) != 1 || got[0].Level != 4 {
+		t.Errorf("single class = %+v, want rogue 4", got)
+	}
+}
+
+func TestMergeProficiencies(t *testing.T) {
+	list, added := mergeProficiencies("simple, Light", []string{"light", "medium", "shields", ""})
+	if list != "simple, Light, medium, shields" {
+		t.Errorf("list = %q", list)
+	}
+	if !reflect.DeepEqual(added, []string{"medium", "shields"}) {
+		t.Errorf("added = %v", added)
+	}
+	if list, added := mergeProficiencies("", []string{"thieves' tools"}); list != "thieves' tools" || len(added) != 1 {
+		t.Errorf("empty list: %q %v", list, added)
+	}
+}
+
+func TestCheckMulticlassPrereqs(t *testing.T) {
+	fighter := []game.ClassLevel{{Class: "fighter", Level: 3}}
+	// str, dex, int, wis, cha
+	if ok, _ := checkMulticlassPrereqs(fighter, "wizard", 14, 10, 13, 10, 10); !ok {
+		t.Error("STR 14 / INT 13 fighter should qualify for wizard")
+	}
+	if ok, reason := checkMulticlassPrereqs(fighter, "wizard", 14, 10, 12, 10, 10); ok || !strings.HasPrefix(reason, "wizard") {
+		t.Errorf("INT 12 shouldn't qualify for wizard, reason %q", reason)
+	}
+	// Every current class counts, not just the first
+	two := append(fighter, game.ClassLevel{Class: "cleric", Level: 1, Position: 1})
+	if ok, reason := checkMulticlassPrereqs(two, "wizard", 14, 10, 13, 12, 10); ok || !strings.HasPrefix(reason, "cleric") {
+		t.Errorf("WIS 12 should fail the cleric prerequisite, reason %q", reason)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.9", "1.0.10", -1},
+		{"1.0.10", "1.0.10", 0},
+		{"1.1", "1.0.45", 1},
+		{"0.9.19", "0.8.61", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestServerCapabilities(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range serverCapabilities {
+		if seen[c.Key] {
+			t.Errorf("duplicate capability %q", c.Key)
+		}
+		seen[c.Key] = true
+		if compareVersions(c.Since, version) > 0 {
+			t.Errorf("capability %q since %s is newer than server version %s", c.Key, c.Since, version)
+		}
+	}
+}
+
 // TestDeathSaves tests the death save mechanics
 func TestDeathSaves(t *testing.T) {
 	if os.Getenv("DATABASE_URL") == "" && os.Getenv("TEST_DATABASE_URL") == "" {
@@ -0,0 +1,294 @@
+package main
+
+// Contract tests for the JSON that deployed agents parse (v1.0.87).
+//
+// testdata/contracts/*.json holds the shape of each core response: objects keep their
+// keys, arrays their first element, and values become "string", "number", "bool" or
+// "null". A key ending in "?" is optional. A field in the contract that goes missing or
+// changes type fails the test; new fields are only logged.
+//
+// Changing a shape on purpose needs an explicit approval: rerun with
+//
+//	go test ./cmd/server -run Contract -update-contracts
+//
+// and commit the rewritten golden file, so the change shows up in review.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateContracts = flag.Bool("update-contracts", false, "rewrite testdata/contracts from live responses (approves shape changes)")
+
+// jsonShape reduces a decoded JSON value to its shape.
+func jsonShape(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		shape := map[string]interface{}{}
+		for k, child := range val {
+			shape[k] = jsonShape(child)
+		}
+		return shape
+	case []interface{}:
+		if len(val) == 0 {
+			return []interface{}{}
+		}
+		return []interface{}{jsonShape(val[0])}
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	}
+	return "null"
+}
+
+// compareShape lists where got breaks the contract in want. Fields got has and want
+// doesn't are returned separately as additions.
+func compareShape(path string, want, got interface{}) (breaks, additions []string) {
+	if want == "null" || got == "null" {
+		return nil, nil // Nullable either way
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: want object, got %v", path, describeShape(got))}, nil
+		}
+		seen := map[string]bool{}
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := strings.TrimSuffix(k, "?")
+			seen[name] = true
+			child, present := g[name]
+			if !present {
+				if name == k {
+					breaks = append(breaks, fmt.Sprintf("%s.%s: missing (renamed or removed?)", path, name))
+				}
+				continue
+			}
+			b, a := compareShape(path+"."+name, w[k], child)
+			breaks, additions = append(breaks, b...), append(additions, a...)
+		}
+		for k := range g {
+			if !seen[k] {
+				additions = append(additions, path+"."+k)
+			}
+		}
+		sort.Strings(additions)
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: want array, got %v", path, describeShape(got))}, nil
+		}
+		if len(w) > 0 && len(g) > 0 {
+			return compareShape(path+"[]", w[0], g[0])
+		}
+	default:
+		if want != got {
+			return []string{fmt.Sprintf("%s: want %v, got %v", path, want, describeShape(got))}, nil
+		}
+	}
+	return breaks, additions
+}
+
+func describeShape(shape interface{}) string {
+	switch shape.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprint(shape)
+}
+
+// mergeShape is got with want's optional keys kept when got lacks them, so approving a
+// change doesn't forget fields that only appear sometimes.
+func mergeShape(want, got interface{}) interface{} {
+	w, wok := want.(map[string]interface{})
+	g, gok := got.(map[string]interface{})
+	if !wok || !gok {
+		if wa, ok := want.([]interface{}); ok && len(wa) > 0 {
+			if ga, ok := got.([]interface{}); ok && len(ga) > 0 {
+				return []interface{}{mergeShape(wa[0], ga[0])}
+			}
+			return want
+		}
+		return got
+	}
+	merged := map[string]interface{}{}
+	for k, v := range g {
+		merged[k] = v
+	}
+	for k, v := range w {
+		name := strings.TrimSuffix(k, "?")
+		if gv, ok := g[name]; ok {
+			delete(merged, name)
+			merged[k] = mergeShape(v, gv)
+		} else if name != k {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// checkContract compares a response body with testdata/contracts/<name>.json.
+func checkContract(t *testing.T, name string, body map[string]interface{}) {
+	t.Helper()
+	path := filepath.Join("testdata", "contracts", name+".json")
+	got := jsonShape(body)
+
+	raw, err := os.ReadFile(path)
+	if err != nil && !*updateContracts {
+		t.Fatalf("read contract %s: %v (run with -update-contracts to create it)", path, err)
+	}
+	var want interface{}
+	if err == nil {
+		if err := json.Unmarshal(raw, &want); err != nil {
+			t.Fatalf("parse contract %s: %v", path, err)
+		}
+	}
+
+	if *updateContracts {
+		out, _ := json.MarshalIndent(mergeShape(want, got), "", "  ")
+		if err := os.WriteFile(path, append(out, '\n'), 0644); err != nil {
+			t.Fatalf("write contract %s: %v", path, err)
+		}
+		t.Logf("updated %s", path)
+		return
+	}
+
+	breaks, additions := compareShape(name, want, got)
+	for _, a := range additions {
+		t.Logf("new field %s is not in the contract yet", a)
+	}
+	if len(breaks) > 0 {
+		t.Errorf("%s breaks the agent contract:\n  %s\nIf this is intended, approve it with -update-contracts and commit %s",
+			name, strings.Join(breaks, "\n  "), path)
+	}
+}
+
+func TestContractShapeComparison(t *testing.T) {
+	var want interface{}
+	json.Unmarshal([]byte(`{"id":"number","name":"string","notes?":"string","tags":["string"],"ledger":{"total":"number"}}`), &want)
+
+	body := map[string]interface{}{}
+	json.Unmarshal([]byte(`{"id":3,"name":"Ariel","tags":[],"ledger":{"total":14},"extra":true}`), &body)
+	breaks, additions := compareShape("r", want, jsonShape(body))
+	if len(breaks) != 0 {
+		t.Errorf("compatible body broke the contract: %v", breaks)
+	}
+	if len(additions) != 1 || additions[0] != "r.extra" {
+		t.Errorf("additions = %v, want [r.extra]", additions)
+	}
+
+	renamed := map[string]interface{}{}
+	json.Unmarshal([]byte(`{"id":"3","character_name":"Ariel","tags":["a"],"ledger":null}`), &renamed)
+	breaks, _ = compareShape("r", want, jsonShape(renamed))
+	wantBreaks := []string{"r.id: want number, got string", "r.name: missing (renamed or removed?)"}
+	if strings.Join(breaks, "|") != strings.Join(wantBreaks, "|") {
+		t.Errorf("breaks = %v, want %v", breaks, wantBreaks)
+	}
+
+	merged := mergeShape(want, jsonShape(body)).(map[string]interface{})
+	if _, ok := merged["notes?"]; !ok {
+		t.Error("mergeShape dropped an optional field the response lacked")
+	}
+	if _, ok := merged["extra"]; !ok {
+		t.Error("mergeShape dropped a new field")
+	}
+}
+
+func TestContractFilesParse(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "contracts", "*.json"))
+	if len(files) == 0 {
+		t.Fatal("no contract files in testdata/contracts")
+	}
+	for _, f := range files {
+		raw, _ := os.ReadFile(f)
+		var shape map[string]interface{}
+		if err := json.Unmarshal(raw, &shape); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+	}
+}
+
+// TestAgentContracts plays a short scene and checks /api/my-turn, an /api/action attack
+// and /api/gm/status against their contracts.
+func TestAgentContracts(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" && os.Getenv("TEST_DATABASE_URL") == "" {
+		t.Skip("No database URL set - skipping integration test")
+	}
+
+	initTestDB(t)
+	testPrefix := fmt.Sprintf("test_contract_%d_", time.Now().Unix())
+	defer cleanupTestData(t, testPrefix)
+
+	_, result := makeRequest(t, "POST", "/api/register", map[string]interface{}{
+		"name":     testPrefix + "GM",
+		"email":    testPrefix + "gm@test.local",
+		"password": "contractgm",
+	}, "")
+	gmID := int(result["agent_id"].(float64))
+	gmAuth := createAuth(fmt.Sprintf("%d", gmID), "contractgm")
+
+	_, result = makeRequest(t, "POST", "/api/campaigns", map[string]interface{}{
+		"name":      testPrefix + "Contracts",
+		"min_level": 1,
+		"max_level": 5,
+	}, gmAuth)
+	campaignID := int(result["campaign_id"].(float64))
+
+	_, result = makeRequest(t, "POST", "/api/register", map[string]interface{}{
+		"name":     testPrefix + "Player",
+		"email":    testPrefix + "player@test.local",
+		"password": "contractplayer",
+	}, "")
+	playerID := int(result["agent_id"].(float64))
+	playerAuth := createAuth(fmt.Sprintf("%d", playerID), "contractplayer")
+
+	_, result = makeRequest(t, "POST", "/api/characters", map[string]interface{}{
+		"name":  testPrefix + "Thorne",
+		"class": "fighter",
+		"race":  "human",
+		"str":   16,
+		"dex":   12,
+		"con":   14,
+	}, playerAuth)
+	charID := int(result["character_id"].(float64))
+	makeRequest(t, "POST", fmt.Sprintf("/api/campaigns/%d/join", campaignID), map[string]interface{}{"character_id": charID}, playerAuth)
+	makeRequest(t, "POST", fmt.Sprintf("/api/campaigns/%d/start", campaignID), nil, gmAuth)
+
+	status, body := makeRequest(t, "GET", "/api/my-turn", nil, playerAuth)
+	if status != 200 {
+		t.Fatalf("my-turn returned %d: %v", status, body)
+	}
+	checkContract(t, "my_turn", body)
+
+	status, body = makeRequest(t, "POST", "/api/action", map[string]interface{}{
+		"action":      "attack",
+		"description": "I swing my longsword at the training dummy",
+	}, playerAuth)
+	if status != 200 {
+		t.Fatalf("action returned %d: %v", status, body)
+	}
+	checkContract(t, "action_attack", body)
+
+	status, body = makeRequest(t, "GET", fmt.Sprintf("/api/gm/status?campaign_id=%d", campaignID), nil, gmAuth)
+	if status != 200 {
+		t.Fatalf("gm/status returned %d: %v", status, body)
+	}
+	checkContract(t, "gm_status", body)
+}
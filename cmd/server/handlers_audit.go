@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+	"github.com/agentrpg/agentrpg/internal/audit"
+)
+
+// GM audit log (v1.0.119): withCharacterChanges records every /api/gm/ write that succeeds
+// in gm_audit: who really made it (a co-GM or assistant acting for the GM is named), the
+// endpoint, and field-by-field how it changed each character the request named
+// (internal/audit). The campaign's GM and moderators read it at GET /api/campaigns/{id}/audit.
+
+// auditRequestLimit is how much of a request body is kept with an audit entry.
+const auditRequestLimit = 4096
+
+// loadCharacterRow reads a character's whole row by column name.
+func loadCharacterRow(ctx context.Context, charID int) (map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM characters WHERE id = $1", charID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	vals := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]interface{}, len(cols))
+	for i, col := range cols {
+		if b, ok := vals[i].([]byte); ok {
+			vals[i] = string(b)
+		}
+		row[col] = vals[i]
+	}
+	return row, nil
+}
+
+// auditCampaign is the campaign a GM request worked on: the one a role acts for, the one it
+// names, the first target's, or else the GM's active campaign.
+func auditCampaign(r *http.Request, gmID int, targets []int) int {
+	if acting, ok := actingGMFrom(r); ok {
+		return acting.CampaignID
+	}
+	if id := requestedCampaignID(r); id != 0 {
+		return id
+	}
+	var id int
+	if len(targets) > 0 {
+		db.QueryRowContext(r.Context(), "SELECT COALESCE(lobby_id, 0) FROM characters WHERE id = $1", targets[0]).Scan(&id)
+	}
+	if id == 0 {
+		db.QueryRowContext(r.Context(), "SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' ORDER BY id LIMIT 1", gmID).Scan(&id)
+	}
+	return id
+}
+
+// tracksCharacterChanges reports whether a path's writes can change characters: GM tools,
+// character tools, actions and combat.
+func tracksCharacterChanges(path string) bool {
+	for _, prefix := range []string{"/api/gm/", "/api/characters/", "/api/action", "/api/combat/", "/api/campaigns/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// changeTargets returns the characters a request may change: the one in a
+// /api/characters/{id}/ path, those its body names, and those routeTargets finds for
+// endpoints that pick their own. Player requests that name none act for the agent's
+// characters in active campaigns.
+func changeTargets(r *http.Request, agentID int, body []byte) []int {
+	var targets []int
+	seen := map[int]bool{}
+	add := func(id int) {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			targets = append(targets, id)
+		}
+	}
+	if rest := strings.TrimPrefix(r.URL.Path, "/api/characters/"); rest != r.URL.Path {
+		if id, err := strconv.Atoi(strings.Split(rest, "/")[0]); err == nil {
+			add(id)
+		}
+	}
+	for _, id := range audit.Targets(body) {
+		add(id)
+	}
+	for _, id := range routeTargets(r, agentID, body) {
+		add(id)
+	}
+	if len(targets) > 0 || strings.HasPrefix(r.URL.Path, "/api/gm/") || strings.HasPrefix(r.URL.Path, "/api/campaigns/") {
+		return targets
+	}
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT c.id FROM characters c JOIN lobbies l ON l.id = c.lobby_id
+		WHERE c.agent_id = $1 AND l.status = 'active' ORDER BY c.id
+	`, agentID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			targets = append(targets, id)
+		}
+	}
+	return targets
+}
+
+// routeTargets returns the characters an endpoint changes without naming them in its body:
+// a party long rest rests the whole campaign, an area spell aimed at a point hits whoever
+// is inside it, and campaign combat endpoints (next turn's effects, damage, start and end)
+// reach every character in the campaign.
+func routeTargets(r *http.Request, agentID int, body []byte) []int {
+	var req struct {
+		CampaignID   int           `json:"campaign_id"`
+		CharacterIDs []int         `json:"character_ids"`
+		SpellSlug    string        `json:"spell_slug"`
+		CasterID     int           `json:"caster_id"`
+		TargetIDs    []int         `json:"target_ids"`
+		Point        *game.GridPos `json:"point"`
+	}
+	json.Unmarshal(body, &req)
+	switch path := r.URL.Path; {
+	case path == "/api/gm/long-rest":
+		if len(req.CharacterIDs) == 0 {
+			return campaignCharacterIDs(r.Context(), req.CampaignID)
+		}
+	case path == "/api/gm/aoe-cast":
+		if req.Point != nil && len(req.TargetIDs) == 0 {
+			return aoePointTargets(r, agentID, req.SpellSlug, req.CasterID, *req.Point)
+		}
+	case strings.HasPrefix(path, "/api/campaigns/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/api/campaigns/"), "/")
+		if id, err := strconv.Atoi(parts[0]); err == nil && len(parts) > 1 && parts[1] == "combat" {
+			return campaignCharacterIDs(r.Context(), id)
+		}
+	}
+	return nil
+}
+
+// campaignCharacterIDs returns the IDs of a campaign's characters.
+func campaignCharacterIDs(ctx context.Context, campaignID int) []int {
+	if campaignID == 0 {
+		return nil
+	}
+	rows, err := db.QueryContext(ctx, "SELECT id FROM characters WHERE lobby_id = $1 ORDER BY id", campaignID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// aoePointTargets returns the characters handleGMAoECast will hit when its spell is aimed
+// at point: those battleMapAreaTargets finds inside the area on the GM's battle map.
+func aoePointTargets(r *http.Request, gmID int, spellSlug string, casterID int, point game.GridPos) []int {
+	var campaignID, aoeSize int
+	var aoeShape, spellRange string
+	err := db.QueryRowContext(r.Context(), `SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1`, gmID, actingCampaignID(r)).Scan(&campaignID)
+	if err != nil {
+		return nil
+	}
+	err = db.QueryRowContext(r.Context(), `
+		SELECT COALESCE(aoe_shape, ''), COALESCE(aoe_size, 0), COALESCE(range, '') FROM spells WHERE slug = $1
+	`, spellSlug).Scan(&aoeShape, &aoeSize, &spellRange)
+	if err != nil {
+		return nil
+	}
+	area, areaErr := battleMapAreaTargets(campaignID, casterID, aoeShape, aoeSize, spellRange, point)
+	if areaErr != nil {
+		return nil
+	}
+	var ids []int
+	for _, id := range area["targets"].([]int) {
+		if id > 0 { // Monsters have negative IDs
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// withCharacterChanges reads the characters a write may change before and after it runs.
+// When it succeeds, each character it changed gets a snapshot of how it was (v1.0.120), and
+// /api/gm/ writes are recorded in the audit log.
+func withCharacterChanges(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if db == nil || r.Method == "GET" || !tracksCharacterChanges(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		agentID, err := getAgentFromAuth(r) // The GM, for someone acting under a campaign role
+		if err != nil {
+			next.ServeHTTP(w, r) // The handler reports it
+			return
+		}
+		actorID, role := agentID, "gm"
+		if acting, ok := actingGMFrom(r); ok {
+			actorID, role = acting.AgentID, acting.Role.Name
+		}
+
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		targets := changeTargets(r, agentID, body)
+		before := map[int]map[string]interface{}{}
+		for _, id := range targets {
+			if state, err := loadCharacterState(r.Context(), id); err == nil {
+				before[id] = state
+			}
+		}
+
+		capture := &responseCapture{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(capture, r)
+		if !audit.Succeeded(capture.statusCode, capture.body) {
+			return
+		}
+
+		// The request is done; record it even if the client has gone.
+		ctx := context.Background()
+		changes := map[int][]audit.Change{}
+		for _, id := range targets {
+			old, ok := before[id]
+			if !ok {
+				continue
+			}
+			after, err := loadCharacterState(ctx, id)
+			if err != nil {
+				after = map[string]interface{}{} // Deleted by the request
+			}
+			changes[id] = audit.Diff(old, after)
+			if len(changes[id]) > 0 && err == nil {
+				saveCharacterSnapshot(ctx, id, actorID, r.URL.Path, old, changes[id])
+			}
+		}
+		if !strings.HasPrefix(r.URL.Path, "/api/gm/") {
+			return
+		}
+
+		campaignID := auditCampaign(r, agentID, targets)
+		request := string(body)
+		if len(request) > auditRequestLimit {
+			request = request[:auditRequestLimit]
+		}
+		record := func(charID interface{}, changes []audit.Change) {
+			changesJSON, _ := json.Marshal(changes)
+			db.ExecContext(ctx, `
+				INSERT INTO gm_audit (lobby_id, actor_id, gm_id, role, endpoint, method, character_id, changes, request)
+				VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9)
+			`, campaignID, actorID, agentID, role, r.URL.Path, r.Method, charID, string(changesJSON), request)
+		}
+		if len(changes) == 0 {
+			record(nil, []audit.Change{})
+			return
+		}
+		for _, id := range targets {
+			if c, ok := changes[id]; ok {
+				record(id, c)
+			}
+		}
+	})
+}
+
+// handleCampaignAudit godoc
+// @Summary GM audit log
+// @Description Every successful GM tool call in the campaign, newest first: who made it (actor, and role when a co-GM or assistant acted for the GM), the endpoint, the character it named and each field it changed with the value before and after. Filter with character_id; page with limit (default 50, max 200) and before (an entry ID). Only the GM and moderators can read it. v1.0.119.
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param character_id query int false "Only entries for this character"
+// @Param limit query int false "Entries per page (default 50, max 200)"
+// @Param before query int false "Entries older than this ID"
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Audit entries"
+// @Failure 403 {object} map[string]interface{} "Not the GM or a moderator"
+// @Failure 404 {object} map[string]interface{} "Campaign not found"
+// @Router /campaigns/{id}/audit [get]
+func handleCampaignAudit(w http.ResponseWriter, r *http.Request, campaignID int) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var dmID int
+	if err := rdb.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", campaignID).Scan(&dmID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "campaign_not_found"})
+		return
+	}
+	if agentID != dmID && !isModerator(agentID) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the campaign's GM and moderators can read its audit log"})
+		return
+	}
+
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	characterID, _ := strconv.Atoi(q.Get("character_id"))
+	before, _ := strconv.Atoi(q.Get("before"))
+
+	rows, err := rdb.Query(`
+		SELECT g.id, COALESCE(g.actor_id, 0), COALESCE(a.name, ''), g.role, g.endpoint, g.method,
+			COALESCE(g.character_id, 0), COALESCE(c.name, ''), g.changes, g.created_at
+		FROM gm_audit g
+		LEFT JOIN agents a ON a.id = g.actor_id
+		LEFT JOIN characters c ON c.id = g.character_id
+		WHERE g.lobby_id = $1 AND ($2 = 0 OR g.character_id = $2) AND ($3 = 0 OR g.id < $3)
+		ORDER BY g.id DESC LIMIT $4
+	`, campaignID, characterID, before, limit)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+	entries := []map[string]interface{}{}
+	var last int
+	for rows.Next() {
+		var id, actorID, charID int
+		var actorName, role, endpoint, method, charName string
+		var changesJSON []byte
+		var created time.Time
+		if rows.Scan(&id, &actorID, &actorName, &role, &endpoint, &method, &charID, &charName, &changesJSON, &created) != nil {
+			continue
+		}
+		changes := []audit.Change{}
+		json.Unmarshal(changesJSON, &changes)
+		entry := map[string]interface{}{
+			"id": id, "actor_id": actorID, "actor_name": actorName, "role": role,
+			"endpoint": endpoint, "method": method, "changes": changes,
+			"summary": audit.Summary(changes), "created_at": created.Format(time.RFC3339),
+		}
+		if charID != 0 {
+			entry["character_id"], entry["character_name"] = charID, charName
+		}
+		entries = append(entries, entry)
+		last = id
+	}
+	resp := map[string]interface{}{"campaign_id": campaignID, "entries": entries, "count": len(entries)}
+	if len(entries) == limit {
+		resp["next_before"] = last
+	}
+	json.NewEncoder(w).Encode(resp)
+}
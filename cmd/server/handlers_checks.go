@@ -0,0 +1,398 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Requested checks (v1.0.102): instead of rolling for a player, the GM asks for a check
+// (skill, DC and why). The player sees it in GET /api/my-turn and answers with POST
+// /api/respond-check, choosing whether to spend inspiration or a luck point, and the GM is
+// sent the result.
+
+// Requested check statuses.
+const (
+	checkRequestPending   = "pending"
+	checkRequestResolved  = "resolved"
+	checkRequestCancelled = "cancelled"
+)
+
+// checkRequest is a check the GM asked a character to roll.
+type checkRequest struct {
+	ID          int                    `json:"id"`
+	CharacterID int                    `json:"character_id"`
+	Character   string                 `json:"character"`
+	Check       string                 `json:"check"`
+	DC          int                    `json:"dc"`
+	Reason      string                 `json:"reason,omitempty"`
+	Status      string                 `json:"status"`
+	Result      map[string]interface{} `json:"result,omitempty"`
+	RequestedAt time.Time              `json:"requested_at"`
+	ResolvedAt  *time.Time             `json:"resolved_at,omitempty"`
+	params      skillCheckRequest
+	campaignID  int
+}
+
+const checkRequestColumns = `r.id, r.lobby_id, r.character_id, c.name, r.params, COALESCE(r.reason, ''), r.status,
+	COALESCE(r.result, 'null'), r.created_at, r.resolved_at`
+
+func scanCheckRequest(row interface{ Scan(...interface{}) error }) (checkRequest, error) {
+	var cr checkRequest
+	var paramsJSON, resultJSON []byte
+	var resolvedAt sql.NullTime
+	err := row.Scan(&cr.ID, &cr.campaignID, &cr.CharacterID, &cr.Character, &paramsJSON, &cr.Reason, &cr.Status,
+		&resultJSON, &cr.RequestedAt, &resolvedAt)
+	if err != nil {
+		return cr, err
+	}
+	json.Unmarshal(paramsJSON, &cr.params)
+	json.Unmarshal(resultJSON, &cr.Result)
+	if resolvedAt.Valid {
+		cr.ResolvedAt = &resolvedAt.Time
+	}
+	cr.Check = cr.params.Skill
+	if cr.Check == "" {
+		cr.Check = game.AbilityFullName(cr.params.Ability)
+	}
+	cr.DC = cr.params.DC
+	return cr, nil
+}
+
+// listCheckRequests returns a campaign's requested checks with the given status, newest
+// first; charID 0 means the whole party.
+func listCheckRequests(campaignID, charID int, status string, limit int) []checkRequest {
+	rows, err := db.Query(`
+		SELECT `+checkRequestColumns+`
+		FROM check_requests r JOIN characters c ON c.id = r.character_id
+		WHERE r.lobby_id = $1 AND ($2 = 0 OR r.character_id = $2) AND r.status = $3
+		ORDER BY r.id DESC LIMIT $4
+	`, campaignID, charID, status, limit)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	requests := []checkRequest{}
+	for rows.Next() {
+		if cr, err := scanCheckRequest(rows); err == nil {
+			requests = append(requests, cr)
+		}
+	}
+	return requests
+}
+
+// luckPointsRemaining is how many Lucky feat luck points a character has left; 0 without
+// the feat. Spent points are kept with the class resources, which a long rest clears.
+func luckPointsRemaining(charID int) int {
+	if !hasSpecificFeat(charID, "lucky") {
+		return 0
+	}
+	var usedJSON []byte
+	db.QueryRow("SELECT COALESCE(class_resources_used, '{}') FROM characters WHERE id = $1", charID).Scan(&usedJSON)
+	used := map[string]int{}
+	json.Unmarshal(usedJSON, &used)
+	return max(game.LuckPoints-used["luck_points"], 0)
+}
+
+// spendLuckPoint spends one of a character's luck points and returns how many are left.
+func spendLuckPoint(charID int) int {
+	var usedJSON []byte
+	db.QueryRow("SELECT COALESCE(class_resources_used, '{}') FROM characters WHERE id = $1", charID).Scan(&usedJSON)
+	used := map[string]int{}
+	json.Unmarshal(usedJSON, &used)
+	used["luck_points"]++
+	updated, _ := json.Marshal(used)
+	db.Exec("UPDATE characters SET class_resources_used = $1 WHERE id = $2", updated, charID)
+	return max(game.LuckPoints-used["luck_points"], 0)
+}
+
+// pendingChecksForPlayer describes a character's requested checks for GET /api/my-turn.
+func pendingChecksForPlayer(campaignID, charID int) []map[string]interface{} {
+	pending := []map[string]interface{}{}
+	for _, cr := range listCheckRequests(campaignID, charID, checkRequestPending, 10) {
+		options := []string{}
+		var hasInspiration bool
+		db.QueryRow("SELECT COALESCE(inspiration, false) FROM characters WHERE id = $1", charID).Scan(&hasInspiration)
+		if hasInspiration && campaignInspirationMode(campaignID) != inspirationModeReroll {
+			options = append(options, "use_inspiration")
+		}
+		if luckPointsRemaining(charID) > 0 {
+			options = append(options, "use_lucky")
+		}
+		entry := map[string]interface{}{
+			"check_id": cr.ID,
+			"check":    cr.Check,
+			"dc":       cr.DC,
+			"respond":  fmt.Sprintf("POST /api/respond-check {\"check_id\":%d}", cr.ID),
+		}
+		if cr.Reason != "" {
+			entry["reason"] = cr.Reason
+		}
+		if len(options) > 0 {
+			entry["options"] = options
+		}
+		pending = append(pending, entry)
+	}
+	return pending
+}
+
+// handleGMRequestCheck godoc
+// @Summary Ask players to roll a check
+// @Description Instead of rolling for them, ask one or more characters for a skill or ability check. Takes the same parameters as POST /api/gm/skill-check plus reason and character_ids (a group asked together). Each player sees the request in GET /api/my-turn (pending_checks) and answers with POST /api/respond-check; the result is posted to the feed, listed in GET /api/gm/status and pushed to you as a check_resolved event. GET lists pending and resolved requests; DELETE ?id= cancels one. v1.0.102.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{character_id=integer,character_ids=[]integer,skill=string,ability=string,dc=integer,reason=string,advantage=boolean,disadvantage=boolean} true "The check to ask for"
+// @Success 200 {object} map[string]interface{} "Requests created"
+// @Failure 400 {object} map[string]interface{} "No check or characters given"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/request-check [post]
+func handleGMRequestCheck(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var campaignID int
+	if rdb.QueryRow("SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1", agentID, actingCampaignID(r)).Scan(&campaignID) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of any active campaign"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"pending":  listCheckRequests(campaignID, 0, checkRequestPending, 50),
+			"resolved": listCheckRequests(campaignID, 0, checkRequestResolved, 20),
+		})
+		return
+
+	case http.MethodDelete:
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		res, _ := rdb.Exec(`
+			UPDATE check_requests SET status = $3, resolved_at = NOW()
+			WHERE id = $1 AND lobby_id = $2 AND status = $4
+		`, id, campaignID, checkRequestCancelled, checkRequestPending)
+		if n, _ := res.RowsAffected(); n == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_found", "message": fmt.Sprintf("No pending check %d in your campaign", id)})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "cancelled": id})
+		return
+
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var req struct {
+		skillCheckRequest
+		CharacterIDs []int  `json:"character_ids"`
+		Reason       string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+	ids := req.CharacterIDs
+	if req.CharacterID != 0 {
+		ids = append([]int{req.CharacterID}, ids...)
+	}
+	if len(ids) == 0 || (req.Skill == "" && req.Ability == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_request",
+			"message": "character_id (or character_ids) and a skill or ability required, e.g. {\"character_ids\":[5,6],\"skill\":\"perception\",\"dc\":15,\"reason\":\"a faint scraping behind the wall\"}",
+		})
+		return
+	}
+	if req.Skill != "" {
+		if _, ok := skillAbilityMap[strings.ToLower(strings.ReplaceAll(req.Skill, " ", "_"))]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_skill", "message": fmt.Sprintf("Unknown skill '%s'", req.Skill)})
+			return
+		}
+	} else if game.NormalizeAbility(req.Ability) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_ability", "message": "ability must be str, dex, con, int, wis or cha"})
+		return
+	}
+	if req.DC == 0 {
+		req.DC = 10
+	}
+	if req.Reason == "" {
+		req.Reason = req.Description
+	}
+	// The player decides these when they answer
+	req.UseInspiration, req.UseLucky, req.UsePeerlessSkill = false, false, false
+
+	requested := []map[string]interface{}{}
+	notFound := []int{}
+	seen := map[int]bool{}
+	for _, charID := range ids {
+		if seen[charID] {
+			continue
+		}
+		seen[charID] = true
+		var name string
+		var ownerID int
+		if rdb.QueryRow("SELECT name, COALESCE(agent_id, 0) FROM characters WHERE id = $1 AND lobby_id = $2", charID, campaignID).Scan(&name, &ownerID) != nil {
+			notFound = append(notFound, charID)
+			continue
+		}
+		params := req.skillCheckRequest
+		params.CharacterID = charID
+		paramsJSON, _ := json.Marshal(params)
+		var id int
+		if rdb.QueryRow(`
+			INSERT INTO check_requests (lobby_id, character_id, params, reason, status)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id
+		`, campaignID, charID, paramsJSON, req.Reason, checkRequestPending).Scan(&id) != nil {
+			continue
+		}
+		check := req.Skill
+		if check == "" {
+			check = game.AbilityFullName(req.Ability)
+		}
+		notifyAgent(ownerID, eventCheckRequested, map[string]interface{}{
+			"campaign_id":    campaignID,
+			"character_id":   charID,
+			"character_name": name,
+			"check_id":       id,
+			"check":          check,
+			"dc":             req.DC,
+			"reason":         req.Reason,
+			"next":           fmt.Sprintf("POST /api/respond-check {\"check_id\":%d}", id),
+		})
+		requested = append(requested, map[string]interface{}{"check_id": id, "character_id": charID, "character": name})
+	}
+
+	response := map[string]interface{}{
+		"success":   len(requested) > 0,
+		"requested": requested,
+		"message":   fmt.Sprintf("Asked %d character(s) to roll. Results arrive as check_resolved events and in GET /api/gm/status.", len(requested)),
+	}
+	if len(notFound) > 0 {
+		response["not_in_campaign"] = notFound
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleRespondCheck godoc
+// @Summary Roll a check the GM asked for
+// @Description Answer a pending check from GET /api/my-turn (pending_checks). Optionally spend inspiration for advantage, a Lucky feat luck point to roll another d20 and keep the better, or Peerless Skill. The server rolls with your modifiers, posts the result to the feed and sends it to the GM. v1.0.102.
+// @Tags Actions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{check_id=integer,use_inspiration=boolean,use_lucky=boolean,use_peerless_skill=boolean} true "Which check and what to spend on it"
+// @Success 200 {object} map[string]interface{} "Check result"
+// @Failure 400 {object} map[string]interface{} "Already resolved or nothing to spend"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not your character"
+// @Failure 404 {object} map[string]interface{} "No such check"
+// @Router /respond-check [post]
+func handleRespondCheck(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CheckID          int  `json:"check_id"`
+		UseInspiration   bool `json:"use_inspiration"`
+		UseLucky         bool `json:"use_lucky"`
+		UsePeerlessSkill bool `json:"use_peerless_skill"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	cr, err := scanCheckRequest(rdb.QueryRow(`
+		SELECT `+checkRequestColumns+`
+		FROM check_requests r JOIN characters c ON c.id = r.character_id
+		WHERE r.id = $1
+	`, req.CheckID))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "check_not_found", "message": "No check with that check_id. See pending_checks in GET /api/my-turn."})
+		return
+	}
+	var ownerID, dmID int
+	rdb.QueryRow("SELECT COALESCE(agent_id, 0) FROM characters WHERE id = $1", cr.CharacterID).Scan(&ownerID)
+	rdb.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", cr.campaignID).Scan(&dmID)
+	if ownerID != agentID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_character", "message": fmt.Sprintf("This check is for %s", cr.Character)})
+		return
+	}
+	if cr.Status != checkRequestPending {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "check_" + cr.Status, "message": fmt.Sprintf("This check was already %s", cr.Status)})
+		return
+	}
+
+	// Claim the request first so two answers can't both roll
+	res, _ := rdb.Exec("UPDATE check_requests SET status = $2 WHERE id = $1 AND status = $3", cr.ID, checkRequestResolved, checkRequestPending)
+	if n, _ := res.RowsAffected(); n == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "check_resolved", "message": "This check was already answered"})
+		return
+	}
+
+	params := cr.params
+	params.UseInspiration, params.UseLucky, params.UsePeerlessSkill = req.UseInspiration, req.UseLucky, req.UsePeerlessSkill
+	if params.Description == "" {
+		params.Description = cr.Reason
+	}
+	status, result := resolveSkillCheck(cr.campaignID, params)
+	if _, failed := result["error"]; failed || status != http.StatusOK {
+		// Nothing was rolled: leave the request open to answer again
+		rdb.Exec("UPDATE check_requests SET status = $2 WHERE id = $1", cr.ID, checkRequestPending)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+		}
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	rdb.Exec("UPDATE check_requests SET result = $2, resolved_at = NOW() WHERE id = $1", cr.ID, resultJSON)
+	notifyAgent(dmID, eventCheckResolved, map[string]interface{}{
+		"campaign_id":  cr.campaignID,
+		"check_id":     cr.ID,
+		"character_id": cr.CharacterID,
+		"character":    cr.Character,
+		"check":        cr.Check,
+		"dc":           cr.DC,
+		"success":      result["success"],
+		"total":        result["total"],
+		"result":       result["result"],
+	})
+
+	result["check_id"] = cr.ID
+	json.NewEncoder(w).Encode(result)
+}
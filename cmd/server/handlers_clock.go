@@ -0,0 +1,204 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Campaign clock (v1.0.106): each campaign keeps its own in-game date and time in
+// lobbies.game_minutes, minutes since Day 1 00:00. Rests, travel and downtime move it on,
+// the GM can set or advance it, and long rests and spell durations are counted against it
+// rather than the real-world clock.
+
+// campaignClock returns the campaign's current clock minute.
+func campaignClock(campaignID int) int {
+	minute := game.ClockStart
+	db.QueryRow("SELECT COALESCE(game_minutes, $2) FROM lobbies WHERE id = $1", campaignID, game.ClockStart).Scan(&minute)
+	return minute
+}
+
+// campaignGameTime returns the campaign's current in-game date and time.
+func campaignGameTime(campaignID int) game.GameTime {
+	return game.NewGameTime(campaignClock(campaignID))
+}
+
+// setCampaignClock moves the campaign clock to minute. Downtime started after this point
+// begins at the new time (see downtimeClock).
+func setCampaignClock(campaignID, minute int) {
+	db.Exec("UPDATE lobbies SET game_minutes = $2, downtime_from = $2 WHERE id = $1", campaignID, minute)
+}
+
+// advanceCampaignClock moves the campaign clock forward and returns the new minute.
+func advanceCampaignClock(campaignID, minutes int) int {
+	minute := campaignClock(campaignID) + max(minutes, 0)
+	setCampaignClock(campaignID, minute)
+	return minute
+}
+
+// restEnd works out when a rest of the given length taken now ends on the campaign clock.
+// Characters rest together: if someone else in the campaign finished the same kind of rest
+// at the current minute, this one joins theirs and ends then too. Otherwise it ends
+// minutes from now. column is last_long_rest_game or last_short_rest_game.
+func restEnd(campaignID, charID int, column string, minutes int) (end int, joined bool) {
+	clock := campaignClock(campaignID)
+	var others int
+	db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM characters WHERE lobby_id = $1 AND id <> $2 AND %s = $3", column),
+		campaignID, charID, clock).Scan(&others)
+	if others > 0 {
+		return clock, true
+	}
+	return clock + minutes, false
+}
+
+// finishRest records a character's rest on the campaign clock and moves the clock to its end.
+func finishRest(campaignID, charID int, column string, end int) {
+	db.Exec(fmt.Sprintf("UPDATE characters SET %s = $2 WHERE id = $1", column), charID, end)
+	if end > campaignClock(campaignID) {
+		setCampaignClock(campaignID, end)
+	}
+}
+
+// downtimeClock spends days of a character's downtime on the campaign clock and returns
+// the minute it ends. Party members' downtime runs side by side: each character's starts
+// where their own last downtime ended, or where the clock was when downtime began, and
+// the clock follows whoever has spent the most days.
+func downtimeClock(campaignID, charID, days int) int {
+	var from, until sql.NullInt64
+	var clock int
+	db.QueryRow("SELECT COALESCE(game_minutes, $2), downtime_from FROM lobbies WHERE id = $1", campaignID, game.ClockStart).Scan(&clock, &from)
+	db.QueryRow("SELECT downtime_until_game FROM characters WHERE id = $1", charID).Scan(&until)
+	start := clock
+	if from.Valid {
+		start = int(from.Int64)
+	} else {
+		db.Exec("UPDATE lobbies SET downtime_from = $2 WHERE id = $1", campaignID, clock)
+	}
+	if until.Valid && int(until.Int64) > start {
+		start = int(until.Int64)
+	}
+	end := start + days*game.MinutesPerDay
+	db.Exec("UPDATE characters SET downtime_until_game = $2 WHERE id = $1", charID, end)
+	if end > clock {
+		db.Exec("UPDATE lobbies SET game_minutes = $2 WHERE id = $1", campaignID, end)
+	}
+	return end
+}
+
+// handleGMClock godoc
+// @Summary Campaign clock
+// @Description GET shows the in-game date and time. POST sets it with {day, time: "HH:MM"} or moves it on with {advance_minutes, advance_hours, advance_days}. Rests (8 hours long, 1 hour short; party members resting at the same time share one rest), travel days and downtime also move the clock; a long rest must end at least 24 in-game hours after the last one, and spell durations outside combat run on this clock. v1.0.106.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{day=integer,time=string,advance_minutes=integer,advance_hours=integer,advance_days=integer,reason=string} true "New time or how far to advance"
+// @Success 200 {object} map[string]interface{} "Game time"
+// @Failure 400 {object} map[string]interface{} "Invalid time"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/clock [post]
+func handleGMClock(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var campaignID int
+	if rdb.QueryRow("SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1", agentID, actingCampaignID(r)).Scan(&campaignID) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of any active campaign"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"game_time": campaignGameTime(campaignID)})
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var req struct {
+		Day            int    `json:"day"`
+		Time           string `json:"time"`
+		AdvanceMinutes int    `json:"advance_minutes"`
+		AdvanceHours   int    `json:"advance_hours"`
+		AdvanceDays    int    `json:"advance_days"`
+		Reason         string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+
+	var inCombat bool
+	rdb.QueryRow("SELECT COALESCE(active, false) FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&inCombat)
+	if inCombat {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "in_combat", "message": "Time moves in rounds during combat; end combat first"})
+		return
+	}
+
+	before := campaignGameTime(campaignID)
+	advance := req.AdvanceMinutes + req.AdvanceHours*game.MinutesPerHour + req.AdvanceDays*game.MinutesPerDay
+	var minute int
+	switch {
+	case req.Day > 0 || req.Time != "":
+		day, clock := req.Day, req.Time
+		if day == 0 {
+			day = before.Day
+		}
+		if clock == "" {
+			clock = before.Clock
+		}
+		var ok bool
+		if minute, ok = game.ClockMinute(day, clock); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_time", "message": "day must be 1 or more and time HH:MM (00:00-23:59)"})
+			return
+		}
+		setCampaignClock(campaignID, minute)
+	case advance > 0:
+		minute = advanceCampaignClock(campaignID, advance)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "nothing_to_do", "message": "Send day and/or time to set the clock, or advance_minutes, advance_hours or advance_days"})
+		return
+	}
+
+	after := game.NewGameTime(minute)
+	result := fmt.Sprintf("%s → %s", before.Display, after.Display)
+	if req.Reason != "" {
+		result += ": " + req.Reason
+	}
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result)
+		VALUES ($1, 'time_passes', $2, $3)
+	`, campaignID, fmt.Sprintf("It is now %s", after.Display), result)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"previous":  before,
+		"game_time": after,
+	})
+}
+
+// downtimeGameTime spends a character's downtime days on their campaign's clock and
+// returns when the downtime ends, or nil for a character outside a campaign.
+func downtimeGameTime(campaignID, charID, days int) interface{} {
+	if campaignID == 0 || days <= 0 {
+		return nil
+	}
+	return game.NewGameTime(downtimeClock(campaignID, charID, days))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentrpg/agentrpg/internal/combatlock"
+)
+
+// Combat concurrency (v1.0.115): combat writes read the turn order, change it and write it
+// back, so two at once could lose one of them. withCombatLock holds a per-campaign advisory
+// lock for every request that can change a campaign's combat (internal/combatlock: GM tools,
+// combat endpoints and every player write but a short list of account endpoints), and a
+// trigger bumps combat_state.version on every write. Clients that send If-Match (or
+// ?expected_version=) with the version they last read get a 409 if it has moved on.
+
+// combatLockNamespace is the first key of the per-campaign advisory lock held while a
+// combat change runs (the second key is the campaign ID).
+const combatLockNamespace = 1217
+
+// combatLockWait is how long a combat change waits for another to finish before giving up.
+const combatLockWait = 3 * time.Second
+
+// errCombatBusy means another change to the same combat held the lock for too long.
+var errCombatBusy = errors.New("combat_busy")
+
+// localCombatLocks are per-campaign locks (1-slot channels) for databases without advisory
+// locks, like SQLite in tests. They only serialize requests within this process.
+var localCombatLocks sync.Map
+
+// lockCampaignCombatLocally is lockCampaignCombat with an in-process lock.
+func lockCampaignCombatLocally(ctx context.Context, campaignID int) (release func(), err error) {
+	lock, _ := localCombatLocks.LoadOrStore(campaignID, make(chan struct{}, 1))
+	slot := lock.(chan struct{})
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return func() {}, errCombatBusy
+	case <-time.After(combatLockWait):
+		return func() {}, errCombatBusy
+	}
+}
+
+// lockCampaignCombat serializes combat changes for a campaign. It holds a Postgres advisory
+// lock on a dedicated connection until release is called, waiting up to combatLockWait for
+// it. Without advisory locks (SQLite tests) it falls back to an in-process lock. Any other
+// error means nothing is held and the change must not run.
+func lockCampaignCombat(ctx context.Context, campaignID int) (release func(), err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return func() {}, err
+	}
+	deadline := time.Now().Add(combatLockWait)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", combatLockNamespace, campaignID).Scan(&acquired); err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return func() {}, errCombatBusy
+			}
+			return lockCampaignCombatLocally(ctx, campaignID)
+		}
+		if acquired {
+			return func() {
+				conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, $2)", combatLockNamespace, campaignID)
+				conn.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			conn.Close()
+			return func() {}, errCombatBusy
+		}
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return func() {}, errCombatBusy
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// combatCampaigns returns the campaigns whose combat a request may change, in ID order. For
+// GM and player requests that's only campaigns with combat running.
+func combatCampaigns(r *http.Request) []int {
+	route, campaignID := combatlock.Classify(r.Method, r.URL.Path)
+	switch route {
+	case combatlock.Campaign:
+		return []int{campaignID}
+	case combatlock.GM:
+		if acting, ok := actingGMFrom(r); ok {
+			return []int{acting.CampaignID}
+		}
+		if id := requestedCampaignID(r); id != 0 {
+			return []int{id}
+		}
+		agentID, err := getAgentFromAuth(r)
+		if err != nil {
+			return nil
+		}
+		return queryCombatCampaigns(r.Context(), `
+			SELECT l.id FROM lobbies l JOIN combat_state cs ON cs.lobby_id = l.id
+			WHERE l.dm_id = $1 AND l.status = 'active' AND cs.active
+		`, agentID)
+	case combatlock.Player:
+		agentID, err := getAgentFromAuth(r)
+		if err != nil {
+			return nil
+		}
+		return queryCombatCampaigns(r.Context(), `
+			SELECT DISTINCT c.lobby_id FROM characters c
+			JOIN lobbies l ON l.id = c.lobby_id JOIN combat_state cs ON cs.lobby_id = l.id
+			WHERE c.agent_id = $1 AND l.status = 'active' AND cs.active
+		`, agentID)
+	}
+	return nil
+}
+
+// queryCombatCampaigns runs a query for campaign IDs and sorts them, so requests touching
+// several campaigns always lock them in the same order.
+func queryCombatCampaigns(ctx context.Context, query string, agentID int) []int {
+	rows, err := db.QueryContext(ctx, query, agentID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// combatVersion returns a campaign's combat_state version, or 0 before its first combat.
+func combatVersion(ctx context.Context, campaignID int) int {
+	var version int
+	db.QueryRowContext(ctx, "SELECT COALESCE(version, 0) FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&version)
+	return version
+}
+
+// withCombatLock runs requests that can change a campaign's combat one at a time per
+// campaign, and refuses those whose If-Match names an old combat version.
+func withCombatLock(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if db == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		campaigns := combatCampaigns(r)
+		if len(campaigns) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, campaignID := range campaigns {
+			release, err := lockCampaignCombat(r.Context(), campaignID)
+			defer release()
+			if err != nil && !errors.Is(err, errCombatBusy) {
+				writeDBTimeout(w)
+				return
+			}
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":       "combat_busy",
+					"message":     "Another change to this combat is still running. Check GET /api/campaigns/{id}/combat and try again.",
+					"campaign_id": campaignID,
+				})
+				return
+			}
+		}
+
+		ifMatch := r.Header.Get("If-Match")
+		if v := r.URL.Query().Get("expected_version"); v != "" && ifMatch == "" {
+			ifMatch = v
+		}
+		if ifMatch == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, campaignID := range campaigns {
+			version := combatVersion(r.Context(), campaignID)
+			ok, err := combatlock.Matches(ifMatch, version)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_combat_version", "message": err.Error()})
+				return
+			}
+			if !ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("ETag", combatlock.ETag(version))
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":           "combat_state_conflict",
+					"message":         "The combat changed since you read it (now version " + strconv.Itoa(version) + "). Re-read it and decide again.",
+					"campaign_id":     campaignID,
+					"current_version": version,
+				})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
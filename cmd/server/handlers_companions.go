@@ -0,0 +1,710 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Companions (v1.0.99): familiars from Find Familiar, Pact of the Chain familiars and
+// rangers' beast companions are creatures a character owns, with hit points, AC and attacks
+// copied from the bestiary. Familiars roll their own initiative; a beast companion follows
+// its ranger in the order. Players command them with POST /api/action and actor "companion".
+
+// companion is a row of the companions table.
+type companion struct {
+	ID           int             `json:"id"`
+	CharacterID  int             `json:"character_id"`
+	Kind         string          `json:"kind"`
+	Name         string          `json:"name"`
+	MonsterSlug  string          `json:"monster_slug"`
+	CreatureType string          `json:"creature_type"`
+	Size         string          `json:"size"`
+	Speed        int             `json:"speed"`
+	AC           int             `json:"ac"`
+	HP           int             `json:"hp"`
+	MaxHP        int             `json:"max_hp"`
+	Dex          int             `json:"dex"`
+	Actions      json.RawMessage `json:"actions"`
+	Dismissed    bool            `json:"dismissed"`
+	ActionUsed   bool            `json:"action_used"`
+	ReactionUsed bool            `json:"reaction_used"`
+	CombatID     int             `json:"combat_id"` // Its ID in the turn order
+}
+
+const companionColumns = `id, COALESCE(character_id, 0), kind, name, COALESCE(monster_slug, ''), COALESCE(creature_type, ''),
+	COALESCE(size, 'Tiny'), COALESCE(speed, 30), COALESCE(ac, 10), hp, max_hp, COALESCE(dex, 10), COALESCE(actions, '[]'),
+	COALESCE(dismissed, false), COALESCE(action_used, false), COALESCE(reaction_used, false)`
+
+func scanCompanion(row interface{ Scan(...interface{}) error }) (companion, error) {
+	var c companion
+	var actions []byte
+	err := row.Scan(&c.ID, &c.CharacterID, &c.Kind, &c.Name, &c.MonsterSlug, &c.CreatureType, &c.Size, &c.Speed,
+		&c.AC, &c.HP, &c.MaxHP, &c.Dex, &actions, &c.Dismissed, &c.ActionUsed, &c.ReactionUsed)
+	c.Actions = json.RawMessage(actions)
+	c.CombatID = game.CompanionCombatID(c.ID)
+	return c, err
+}
+
+func loadCompanion(companionID int) (companion, bool) {
+	c, err := scanCompanion(db.QueryRow("SELECT "+companionColumns+" FROM companions WHERE id = $1", companionID))
+	return c, err == nil
+}
+
+func characterCompanions(charID int) []companion {
+	companions := []companion{}
+	rows, err := db.Query("SELECT "+companionColumns+" FROM companions WHERE character_id = $1 ORDER BY id", charID)
+	if err != nil {
+		return companions
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if c, err := scanCompanion(rows); err == nil {
+			companions = append(companions, c)
+		}
+	}
+	return companions
+}
+
+// companionEligibility explains why a character can't call this kind of companion
+// themselves, or returns "" when they can.
+func companionEligibility(kind, class string, level int, pactBoon string, spells []string) string {
+	switch kind {
+	case game.CompanionFamiliar:
+		if !slices.Contains(spells, "find-familiar") && pactBoon != "chain" {
+			return "You need to know Find Familiar (or take the Pact of the Chain)"
+		}
+	case game.CompanionChain:
+		if pactBoon != "chain" {
+			return "Only a warlock with the Pact of the Chain has a chain familiar"
+		}
+	case game.CompanionBeast:
+		if !strings.EqualFold(class, "ranger") || level < 3 {
+			return "Only a ranger of 3rd level or higher gains a beast companion"
+		}
+	}
+	return ""
+}
+
+// companionBonus is what a ranger's companion adds to its attack and damage rolls:
+// the ranger's proficiency bonus (PHB p93). Familiars add nothing.
+func companionBonus(c companion, masterLevel int) int {
+	if c.Kind == game.CompanionBeast {
+		return game.ProficiencyBonus(masterLevel)
+	}
+	return 0
+}
+
+// handleCharacterCompanions godoc
+// @Summary List or call companions
+// @Description GET lists a character's companions (owner or GM). POST calls one from a monster slug: kind familiar (Find Familiar: bat, cat, owl, raven... costs 10 gp of incense), chain (Pact of the Chain: also imp, pseudodragon, quasit, sprite) or beast (ranger 3+: a Medium or smaller beast of CR 1/4 or lower, with HP of at least 4 x ranger level). A character has one familiar at a time; calling another changes its form. The GM can give any companion for free. Command it with POST /api/action and actor "companion". v1.0.99.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param character_id query int false "Character (GET)"
+// @Param request body object{character_id=integer,kind=string,creature=string,name=string} false "Companion to call (POST)"
+// @Success 200 {object} map[string]interface{} "Companions"
+// @Failure 400 {object} map[string]interface{} "Form not allowed, not eligible or not enough coin"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not your character"
+// @Router /characters/companions [get]
+// @Router /characters/companions [post]
+func handleCharacterCompanions(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CharacterID int    `json:"character_id"`
+		Kind        string `json:"kind"`
+		Creature    string `json:"creature"`
+		Name        string `json:"name"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		req.CharacterID, _ = strconv.Atoi(r.URL.Query().Get("character_id"))
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Creature == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "invalid_request",
+				"message": "kind (familiar, chain or beast) and creature required, e.g. {\"kind\":\"familiar\",\"creature\":\"owl\"}",
+			})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var charName, class string
+	var ownerID, campaignID, dmID, level int
+	var pactBoon string
+	var knownJSON, preparedJSON []byte
+	err = rdb.QueryRow(`
+		SELECT c.name, c.agent_id, COALESCE(c.lobby_id, 0), COALESCE(l.dm_id, 0), c.class, c.level,
+			COALESCE(c.pact_boon, ''), COALESCE(c.known_spells, '[]'), COALESCE(c.prepared_spells, '[]')
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id WHERE c.id = $1
+	`, req.CharacterID).Scan(&charName, &ownerID, &campaignID, &dmID, &class, &level, &pactBoon, &knownJSON, &preparedJSON)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_found"})
+		return
+	}
+	isGM := dmID == agentID && dmID != 0
+	if ownerID != agentID && !isGM {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_character"})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"character":  charName,
+			"companions": characterCompanions(req.CharacterID),
+			"commands":   game.CompanionCommands,
+		})
+		return
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	slug := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(req.Creature), " ", "-"))
+	c := companion{CharacterID: req.CharacterID, Kind: kind}
+	var cr string
+	var actions []byte
+	err = rdb.QueryRow(`
+		SELECT slug, name, COALESCE(type, ''), COALESCE(size, 'Tiny'), COALESCE(cr, '0'), COALESCE(speed, 30),
+			COALESCE(ac, 10), COALESCE(hp, 1), COALESCE(dex, 10), COALESCE(actions, '[]')
+		FROM monsters WHERE slug = $1 OR LOWER(name) = LOWER($2)
+	`, slug, req.Creature).Scan(&c.MonsterSlug, &c.Name, &c.CreatureType, &c.Size, &cr, &c.Speed, &c.AC, &c.MaxHP, &c.Dex, &actions)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "unknown_creature",
+			"message": fmt.Sprintf("No creature '%s' in the bestiary", req.Creature),
+		})
+		return
+	}
+	if ok, reason := game.CompanionFormAllowed(kind, c.MonsterSlug, strings.ToLower(c.CreatureType), c.Size, cr); !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "form_not_allowed", "message": reason})
+		return
+	}
+	c.Actions = json.RawMessage(actions)
+	if kind == game.CompanionBeast {
+		c.MaxHP = game.BeastCompanionMaxHP(c.MaxHP, level)
+	}
+	c.HP = c.MaxHP
+	form := c.Name
+	if req.Name != "" {
+		c.Name = req.Name
+	}
+
+	var inCombat bool
+	rdb.QueryRow("SELECT COALESCE(active, false) FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&inCombat)
+	if inCombat && !isGM {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "in_combat", "message": "Calling a companion takes an hour or more; not mid-fight"})
+		return
+	}
+
+	response := map[string]interface{}{"success": true}
+	if !isGM {
+		var known, prepared []string
+		json.Unmarshal(knownJSON, &known)
+		json.Unmarshal(preparedJSON, &prepared)
+		if reason := companionEligibility(kind, class, level, pactBoon, append(known, prepared...)); reason != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_eligible", "message": reason + ". Or ask the GM."})
+			return
+		}
+		if kind != game.CompanionBeast {
+			purse := characterPurse(req.CharacterID)
+			paid, ok := purse.Pay(game.FindFamiliarCostCP)
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   "insufficient_funds",
+					"message": fmt.Sprintf("Find Familiar burns %s of charcoal, incense and herbs; %s has %s", game.FormatCP(game.FindFamiliarCostCP), charName, game.FormatCP(purse.TotalCP())),
+				})
+				return
+			}
+			saveCharacterPurse(req.CharacterID, paid)
+			response["price"] = game.FormatCP(game.FindFamiliarCostCP)
+			response["purse"] = paid
+		}
+	}
+
+	// One familiar at a time: casting Find Familiar again gives the one you have a new form
+	// (PHB p240). A ranger replaces a companion only once it has died.
+	existing := 0
+	for _, other := range characterCompanions(req.CharacterID) {
+		switch {
+		case kind != game.CompanionBeast && other.Kind != game.CompanionBeast:
+			existing = other.ID
+		case kind == game.CompanionBeast && other.Kind == game.CompanionBeast && other.HP > 0:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "already_has_companion",
+				"message": fmt.Sprintf("%s already has %s", charName, other.Name),
+			})
+			return
+		case kind == game.CompanionBeast && other.Kind == game.CompanionBeast:
+			rdb.Exec("DELETE FROM companions WHERE id = $1", other.ID)
+		}
+	}
+
+	if existing != 0 {
+		c.ID = existing
+		_, err = rdb.Exec(`
+			UPDATE companions SET kind = $1, name = $2, monster_slug = $3, creature_type = $4, size = $5, speed = $6,
+				ac = $7, hp = $8, max_hp = $9, dex = $10, actions = $11, dismissed = false
+			WHERE id = $12
+		`, c.Kind, c.Name, c.MonsterSlug, c.CreatureType, c.Size, c.Speed, c.AC, c.HP, c.MaxHP, c.Dex, []byte(c.Actions), c.ID)
+	} else {
+		err = rdb.QueryRow(`
+			INSERT INTO companions (character_id, kind, name, monster_slug, creature_type, size, speed, ac, hp, max_hp, dex, actions)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id
+		`, c.CharacterID, c.Kind, c.Name, c.MonsterSlug, c.CreatureType, c.Size, c.Speed, c.AC, c.HP, c.MaxHP, c.Dex, []byte(c.Actions)).Scan(&c.ID)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error", "detail": err.Error()})
+		return
+	}
+	c.CombatID = game.CompanionCombatID(c.ID)
+
+	if campaignID != 0 {
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+			VALUES ($1, $2, 'companion_acquired', $3, $4)
+		`, campaignID, req.CharacterID, fmt.Sprintf("%s gains a %s companion", charName, strings.ToLower(form)),
+			fmt.Sprintf("%s: AC %d, %d HP, speed %d ft", c.Name, c.AC, c.MaxHP, c.Speed))
+	}
+	if existing != 0 {
+		response["replaced_form"] = true
+	}
+	response["companion"] = c
+	response["commands"] = game.CompanionCommands[kind]
+	response["message"] = fmt.Sprintf("%s is at %s's side. Command it with POST /api/action {\"actor\":\"companion\",\"action\":\"%s\"}", c.Name, charName, game.CompanionCommands[kind][0])
+	json.NewEncoder(w).Encode(response)
+}
+
+// resolveCompanionCommand carries out a command given to a character's companion through
+// POST /api/action. The master pays what the command costs them (game.CompanionCommandCost);
+// a familiar acting on its own turn spends its own action.
+func resolveCompanionCommand(charID, lobbyID, companionID int, command, description string, targetID int) map[string]interface{} {
+	var c companion
+	found := false
+	if companionID != 0 {
+		c, found = loadCompanion(companionID)
+		found = found && c.CharacterID == charID
+	} else {
+		for _, other := range characterCompanions(charID) {
+			if other.HP > 0 {
+				c, found = other, true
+				break
+			}
+		}
+	}
+	if !found {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "companion_not_found",
+			"message": "You have no such companion. GET /api/characters/companions lists them.",
+		}
+	}
+
+	command = strings.ToLower(command)
+	if !game.CompanionCommandAllowed(c.Kind, command) {
+		return map[string]interface{}{
+			"success":  false,
+			"error":    "invalid_command",
+			"message":  fmt.Sprintf("%s can't be told to %s", c.Name, command),
+			"commands": game.CompanionCommands[c.Kind],
+		}
+	}
+	switch {
+	case c.HP <= 0:
+		return map[string]interface{}{"success": false, "error": "companion_down", "message": fmt.Sprintf("%s is at 0 HP", c.Name)}
+	case c.Dismissed && command != "summon":
+		return map[string]interface{}{"success": false, "error": "companion_dismissed", "message": fmt.Sprintf("%s waits in its pocket dimension. Summon it first.", c.Name)}
+	case !c.Dismissed && command == "summon":
+		return map[string]interface{}{"success": false, "error": "companion_present", "message": fmt.Sprintf("%s is already here", c.Name)}
+	}
+
+	var charName string
+	var level int
+	db.QueryRow("SELECT name, level FROM characters WHERE id = $1", charID).Scan(&charName, &level)
+	inCombat := characterInCombat(charID)
+	cost := game.CompanionCommandCost(c.Kind, command, level)
+	ownTurn := cost == "" && command != "move" && game.CompanionOwnInitiative(c.Kind)
+
+	if inCombat {
+		if cost != "" && isIncapacitated(charID) {
+			return map[string]interface{}{"success": false, "error": "incapacitated", "message": "You can't command your companion while incapacitated"}
+		}
+		problem := ""
+		switch cost {
+		case "attack":
+			if ok, _, msg := checkActionEconomy(charID, "attack", 0); !ok {
+				problem = msg
+			} else if c.ReactionUsed {
+				problem = fmt.Sprintf("%s has already used its reaction", c.Name)
+			}
+		case "action", "bonus_action":
+			var actionUsed, bonusUsed bool
+			db.QueryRow("SELECT COALESCE(action_used, false), COALESCE(bonus_action_used, false) FROM characters WHERE id = $1", charID).Scan(&actionUsed, &bonusUsed)
+			if (cost == "action" && actionUsed) || (cost == "bonus_action" && bonusUsed) {
+				problem = fmt.Sprintf("Commanding %s to %s takes your %s, and you've used it this turn", c.Name, command, strings.ReplaceAll(cost, "_", " "))
+			}
+		}
+		if ownTurn && c.ActionUsed {
+			problem = fmt.Sprintf("%s has already taken its action this turn", c.Name)
+		}
+		if problem != "" {
+			return map[string]interface{}{
+				"success":       false,
+				"error":         "resource_exhausted",
+				"message":       problem,
+				"resource_type": cost,
+				"hint":          "Use GET /api/my-turn to see your available resources.",
+			}
+		}
+	}
+
+	response := map[string]interface{}{"success": true, "actor": "companion", "companion_id": c.ID, "command": command}
+	var result string
+	switch command {
+	case "attack":
+		attack := companionAttack(lobbyID, c, level, description, targetID)
+		for k, v := range attack {
+			response[k] = v
+		}
+		result, _ = attack["result"].(string)
+	case "share_senses":
+		result = fmt.Sprintf("%s sees through %s's eyes and hears what it hears, and is blind and deaf to their own senses until the start of their next turn (within %d ft)", charName, c.Name, game.CompanionSharedSensesFt)
+		if inCombat {
+			conditions := getCharConditions(charID)
+			for _, cond := range []string{"blinded", "deafened"} {
+				if !conditionListHas(conditions, cond) {
+					conditions = append(conditions, cond)
+				}
+				if timer, msg := buildConditionTimer(charID, cond, game.EndsStartOfNextTurn, charID, "", 0); msg == "" {
+					setConditionTimer(charID, timer)
+				}
+			}
+			updated, _ := json.Marshal(conditions)
+			db.Exec("UPDATE characters SET conditions = $1 WHERE id = $2", updated, charID)
+			response["conditions_applied"] = []string{"blinded", "deafened"}
+		}
+	case "dismiss":
+		db.Exec("UPDATE companions SET dismissed = true WHERE id = $1", c.ID)
+		if inCombat {
+			removeCompanionFromTurnOrder(lobbyID, c.CombatID)
+		}
+		result = fmt.Sprintf("%s vanishes into a pocket dimension", c.Name)
+	case "summon":
+		db.Exec("UPDATE companions SET dismissed = false WHERE id = $1", c.ID)
+		c.Dismissed = false
+		if inCombat {
+			addCompanionToTurnOrder(lobbyID, c, charName)
+		}
+		result = fmt.Sprintf("%s reappears within 30 feet of %s", c.Name, charName)
+	case "move":
+		result = fmt.Sprintf("%s moves (speed %d ft)", c.Name, c.Speed)
+		if description != "" {
+			result = fmt.Sprintf("%s moves %s (speed %d ft)", c.Name, description, c.Speed)
+		}
+	default:
+		result = fmt.Sprintf("%s takes the %s action", c.Name, command)
+		if description != "" {
+			result += ": " + description
+		}
+	}
+
+	if inCombat {
+		switch cost {
+		case "attack":
+			consumeActionResource(charID, "action", 0, "attack")
+			db.Exec("UPDATE companions SET reaction_used = true WHERE id = $1", c.ID)
+		case "action", "bonus_action":
+			consumeActionResource(charID, cost, 0)
+		}
+		if ownTurn {
+			db.Exec("UPDATE companions SET action_used = true WHERE id = $1", c.ID)
+		}
+		response["resource_consumed"] = cost
+	}
+
+	var actionID int
+	db.QueryRow(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id
+	`, lobbyID, charID, "companion_"+command, description, result).Scan(&actionID)
+	response["action_id"] = actionID
+	response["result"] = result
+	return response
+}
+
+// companionAttack rolls one of a companion's stat block attacks. description picks the
+// attack by name (the first attack otherwise); targetID is a monster's negative combat ID,
+// which takes the damage on a hit. Without a target the GM applies the rolls.
+func companionAttack(lobbyID int, c companion, masterLevel int, description string, targetID int) map[string]interface{} {
+	var actions []map[string]interface{}
+	json.Unmarshal(c.Actions, &actions)
+	name, bonus, dice, damageType := "", 0, "", "bludgeoning"
+	for _, a := range actions {
+		aName, _ := a["name"].(string)
+		aBonus, _ := a["attack_bonus"].(float64)
+		named := description != "" && strings.Contains(strings.ToLower(description), strings.ToLower(aName))
+		if aBonus == 0 || (name != "" && !named) {
+			continue
+		}
+		name, bonus, dice, damageType = aName, int(aBonus), "1d4", "bludgeoning"
+		if d, ok := a["damage_dice"].(string); ok && d != "" {
+			dice = d
+		}
+		if t, ok := a["damage_type"].(string); ok && t != "" {
+			damageType = strings.ToLower(t)
+		}
+		if named {
+			break
+		}
+	}
+	if name == "" {
+		return map[string]interface{}{"result": fmt.Sprintf("%s has no attack in its stat block", c.Name), "hit": false}
+	}
+
+	extra := companionBonus(c, masterLevel)
+	targetName, targetAC := "", 0
+	if targetID < 0 {
+		if entry, ok := turnOrderEntry(lobbyID, targetID); ok {
+			targetName, _ = entry["name"].(string)
+			targetAC = turnOrderInt(entry, "ac")
+		}
+	}
+
+	roll := game.RollDie(20)
+	total := roll + bonus + extra
+	crit := roll == 20
+	hit := crit || (roll != 1 && targetName != "" && total >= targetAC)
+	attack := map[string]interface{}{
+		"attack":      name,
+		"attack_roll": fmt.Sprintf("d20(%d)%+d = %d", roll, bonus+extra, total),
+		"crit":        crit,
+	}
+	if targetName == "" {
+		hit = roll != 1
+		damage := max(game.RollDamage(dice, crit)+game.DiceBonus(dice)+extra, 1)
+		attack["damage"] = damage
+		attack["damage_type"] = damageType
+		attack["hit"] = hit
+		attack["result"] = fmt.Sprintf("%s uses %s: %d to hit, %d %s damage if it hits", c.Name, name, total, damage, damageType)
+		return attack
+	}
+
+	attack["target"] = targetName
+	attack["hit"] = hit
+	if !hit {
+		attack["result"] = fmt.Sprintf("%s's %s misses %s (%d vs AC %d)", c.Name, name, targetName, total, targetAC)
+		return attack
+	}
+	damage := max(game.RollDamage(dice, crit)+game.DiceBonus(dice)+extra, 1)
+	attack["damage"] = damage
+	attack["damage_type"] = damageType
+	attack["damage_applied"] = damageCombatMonster(lobbyID, monsterDamageRequest{
+		CombatantID: targetID,
+		Damage:      damage,
+		DamageType:  damageType,
+	}, true)
+	attack["result"] = fmt.Sprintf("%s's %s hits %s (%d vs AC %d) for %d %s damage", c.Name, name, targetName, total, targetAC, damage, damageType)
+	return attack
+}
+
+// companionTurnEntry is a companion's turn order entry. A familiar rolls its own initiative
+// (d20 + DEX); a beast companion shares its ranger's.
+func companionTurnEntry(c companion, ownerName string, ownerInitiative int) map[string]interface{} {
+	initiative := ownerInitiative
+	if game.CompanionOwnInitiative(c.Kind) {
+		initiative = game.RollInitiative(game.Modifier(c.Dex), 0)
+	}
+	return map[string]interface{}{
+		"id":         c.CombatID,
+		"name":       fmt.Sprintf("%s (%s's %s)", c.Name, ownerName, c.Kind),
+		"initiative": initiative,
+		"dex_score":  c.Dex,
+	}
+}
+
+// campaignCompanions returns the companions of a campaign's characters who are present
+// and standing, keyed by their owner.
+func campaignCompanions(campaignID int) map[int][]companion {
+	byOwner := map[int][]companion{}
+	rows, err := db.Query(`
+		SELECT `+companionColumns+` FROM companions
+		WHERE character_id IN (SELECT id FROM characters WHERE lobby_id = $1) AND NOT COALESCE(dismissed, false) AND hp > 0
+		ORDER BY id
+	`, campaignID)
+	if err != nil {
+		return byOwner
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if c, err := scanCompanion(rows); err == nil {
+			byOwner[c.CharacterID] = append(byOwner[c.CharacterID], c)
+		}
+	}
+	return byOwner
+}
+
+// addCompanionToTurnOrder slots a companion arriving mid-combat into the initiative order.
+func addCompanionToTurnOrder(campaignID int, c companion, ownerName string) {
+	var turnIndex int
+	var turnOrderJSON []byte
+	if db.QueryRow("SELECT current_turn_index, turn_order FROM combat_state WHERE lobby_id = $1 AND active", campaignID).Scan(&turnIndex, &turnOrderJSON) != nil {
+		return
+	}
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	ownerInitiative := 0
+	for _, e := range entries {
+		if id := turnOrderInt(e, "id"); id == c.CombatID {
+			return
+		} else if id == c.CharacterID {
+			ownerInitiative = turnOrderInt(e, "initiative")
+		}
+	}
+	entries, _, turnIndex = insertCombatant(entries, companionTurnEntry(c, ownerName, ownerInitiative), turnIndex)
+	updated, _ := json.Marshal(entries)
+	db.Exec("UPDATE combat_state SET turn_order = $1, current_turn_index = $2 WHERE lobby_id = $3", updated, turnIndex, campaignID)
+}
+
+// removeCompanionFromTurnOrder takes a dismissed or fallen companion out of the initiative
+// order, keeping the turn with whoever holds it.
+func removeCompanionFromTurnOrder(campaignID, combatID int) {
+	var turnIndex, round int
+	var turnOrderJSON []byte
+	if db.QueryRow("SELECT current_turn_index, round_number, turn_order FROM combat_state WHERE lobby_id = $1 AND active", campaignID).Scan(&turnIndex, &round, &turnOrderJSON) != nil {
+		return
+	}
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	kept := []map[string]interface{}{}
+	for i, e := range entries {
+		if turnOrderInt(e, "id") != combatID {
+			kept = append(kept, e)
+		} else if i < turnIndex {
+			turnIndex--
+		}
+	}
+	if len(kept) == len(entries) || len(kept) == 0 {
+		return
+	}
+	if turnIndex >= len(kept) {
+		turnIndex = 0
+		round++
+	}
+	updated, _ := json.Marshal(kept)
+	db.Exec("UPDATE combat_state SET turn_order = $1, current_turn_index = $2, round_number = $3 WHERE lobby_id = $4", updated, turnIndex, round, campaignID)
+}
+
+// handleGMCompanionDamage godoc
+// @Summary Damage or heal a companion (GM only)
+// @Description Applies damage to a character's companion (negative damage heals). A familiar dropped to 0 HP disappears until its master casts Find Familiar again; a beast companion dies. Either leaves the initiative order. v1.0.99.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{companion_id=integer,damage=integer} true "Damage to apply"
+// @Success 200 {object} map[string]interface{} "Companion"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Failure 404 {object} map[string]interface{} "Companion not found"
+// @Router /gm/companion-damage [post]
+func handleGMCompanionDamage(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CompanionID int `json:"companion_id"`
+		Damage      int `json:"damage"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CompanionID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_request", "message": "companion_id required"})
+		return
+	}
+	if id, ok := game.CompanionFromCombatID(req.CompanionID); ok {
+		req.CompanionID = id // Its turn order ID works too
+	}
+
+	c, ok := loadCompanion(req.CompanionID)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "companion_not_found"})
+		return
+	}
+	var campaignID, dmID int
+	rdb.QueryRow(`
+		SELECT COALESCE(c.lobby_id, 0), COALESCE(l.dm_id, 0)
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id WHERE c.id = $1
+	`, c.CharacterID).Scan(&campaignID, &dmID)
+	if dmID != agentID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the owner's GM can harm their companion"})
+		return
+	}
+
+	wasUp := c.HP > 0
+	c.HP = min(max(c.HP-req.Damage, 0), c.MaxHP)
+	rdb.Exec("UPDATE companions SET hp = $1 WHERE id = $2", c.HP, c.ID)
+
+	response := map[string]interface{}{"success": true, "companion": c}
+	summary := fmt.Sprintf("%s: %d/%d HP", c.Name, c.HP, c.MaxHP)
+	if req.Damage > 0 {
+		summary = fmt.Sprintf("%s takes %d damage (%d/%d HP)", c.Name, req.Damage, c.HP, c.MaxHP)
+	} else if req.Damage < 0 {
+		summary = fmt.Sprintf("%s recovers %d HP (%d/%d HP)", c.Name, -req.Damage, c.HP, c.MaxHP)
+	}
+	if wasUp && c.HP == 0 {
+		removeCompanionFromTurnOrder(campaignID, c.CombatID)
+		response["companion_down"] = true
+		if c.Kind == game.CompanionBeast {
+			summary += " and dies"
+		} else {
+			summary += " and vanishes until Find Familiar is cast again"
+		}
+	}
+
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+		VALUES ($1, $2, 'companion_damage', $3, $4)
+	`, campaignID, c.CharacterID, fmt.Sprintf("%s is hit", c.Name), summary)
+	response["message"] = summary
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Connection pool and prepared statements (v1.0.117). The pool is sized from DB_* env
+// vars. The hot paths (auth, my-turn, GM status, feed) run the same few queries on every
+// request, so those are prepared once and reused; DB_PREPARED_STATEMENTS=false turns that
+// off behind a transaction-mode pooler such as PgBouncer, which can't keep them.
+
+// dbPoolSettings is how the connection pool is sized.
+type dbPoolSettings struct {
+	MaxOpen     int // 0 is unlimited
+	MaxIdle     int
+	MaxLifetime time.Duration
+	MaxIdleTime time.Duration
+	Prepared    bool
+}
+
+// Pool defaults. Open connections stay unlimited: advisory locks (actions, combat) pin a
+// connection while the handler needs another, so a low cap can starve itself.
+const (
+	dbDefaultMaxIdle         = 10
+	dbDefaultLifetimeSeconds = 1800
+	dbDefaultIdleSeconds     = 300
+)
+
+// dbPoolSettingsFrom reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS,
+// DB_CONN_MAX_LIFETIME_SECONDS, DB_CONN_MAX_IDLE_SECONDS and DB_PREPARED_STATEMENTS.
+// Missing or invalid values get the defaults.
+func dbPoolSettingsFrom(getenv func(string) string) dbPoolSettings {
+	num := func(key string, def int) int {
+		n, err := strconv.Atoi(getenv(key))
+		if err != nil || n < 0 {
+			return def
+		}
+		return n
+	}
+	s := dbPoolSettings{
+		MaxOpen:     num("DB_MAX_OPEN_CONNS", 0),
+		MaxIdle:     num("DB_MAX_IDLE_CONNS", dbDefaultMaxIdle),
+		MaxLifetime: time.Duration(num("DB_CONN_MAX_LIFETIME_SECONDS", dbDefaultLifetimeSeconds)) * time.Second,
+		MaxIdleTime: time.Duration(num("DB_CONN_MAX_IDLE_SECONDS", dbDefaultIdleSeconds)) * time.Second,
+		Prepared:    getenv("DB_PREPARED_STATEMENTS") != "false",
+	}
+	if s.MaxOpen > 0 && s.MaxIdle > s.MaxOpen {
+		s.MaxIdle = s.MaxOpen
+	}
+	return s
+}
+
+// usePreparedStatements is DB_PREPARED_STATEMENTS, set by configureDBPool.
+var usePreparedStatements = true
+
+// configureDBPool applies pool settings to the database.
+func configureDBPool(s dbPoolSettings) {
+	db.SetMaxOpenConns(s.MaxOpen)
+	db.SetMaxIdleConns(s.MaxIdle)
+	db.SetConnMaxLifetime(s.MaxLifetime)
+	db.SetConnMaxIdleTime(s.MaxIdleTime)
+	usePreparedStatements = s.Prepared
+	log.Printf("DB pool: max open %d (0 = unlimited), max idle %d, lifetime %s, idle %s, prepared statements %v",
+		s.MaxOpen, s.MaxIdle, s.MaxLifetime, s.MaxIdleTime, s.Prepared)
+}
+
+// preparedStmts holds the prepared hot-path queries by query text.
+var preparedStmts sync.Map
+
+// preparedStmt returns query prepared, preparing it on first use. It returns nil when
+// prepared statements are off or the query can't be prepared, and callers run it plainly.
+func preparedStmt(query string) *sql.Stmt {
+	if db == nil || !usePreparedStatements {
+		return nil
+	}
+	if s, ok := preparedStmts.Load(query); ok {
+		return s.(*sql.Stmt)
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil
+	}
+	if s, loaded := preparedStmts.LoadOrStore(query, stmt); loaded {
+		stmt.Close()
+		return s.(*sql.Stmt)
+	}
+	return stmt
+}
+
+// preparedQueryRow is QueryRowContext through a prepared statement.
+func preparedQueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt := preparedStmt(query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// preparedQuery is QueryContext through a prepared statement.
+func preparedQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := preparedStmt(query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowPrepared is QueryRow for a hot-path query, through a prepared statement.
+func (q requestDB) QueryRowPrepared(query string, args ...interface{}) *sql.Row {
+	return preparedQueryRow(q.ctx, query, args...)
+}
+
+// QueryPrepared is Query for a hot-path query, through a prepared statement.
+func (q requestDB) QueryPrepared(query string, args ...interface{}) (*sql.Rows, error) {
+	return preparedQuery(q.ctx, query, args...)
+}
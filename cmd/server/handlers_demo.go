@@ -0,0 +1,407 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+	"github.com/agentrpg/agentrpg/internal/auth"
+)
+
+// Demo campaigns (v1.0.74): one admin call builds a populated sandbox (GM, party, a fight in
+// progress and some history) for new agent developers and integration tests.
+
+// demoCharacter is one pregenerated party member. Ability scores include racial bonuses.
+type demoCharacter struct {
+	Name, Class, Race, Background string
+	Str, Dex, Con, Int, Wis, Cha  int
+	Skills                        []string
+	Expertise                     []string
+	Spells                        []string
+}
+
+// demoParty is the party a demo campaign gets, in the order players are added.
+var demoParty = []demoCharacter{
+	{Name: "Brannoc Vale", Class: "fighter", Race: "Human", Background: "soldier",
+		Str: 16, Dex: 12, Con: 15, Int: 10, Wis: 13, Cha: 9, Skills: []string{"perception", "survival"}},
+	{Name: "Ilsevel Moonwhisper", Class: "wizard", Race: "High Elf", Background: "sage",
+		Str: 8, Dex: 16, Con: 13, Int: 16, Wis: 12, Cha: 10, Skills: []string{"investigation", "insight"},
+		Spells: []string{"fire-bolt", "mage-hand", "light", "magic-missile", "shield", "sleep", "detect-magic", "mage-armor", "burning-hands"}},
+	{Name: "Dorra Flintmantle", Class: "cleric", Race: "Hill Dwarf", Background: "acolyte",
+		Str: 14, Dex: 10, Con: 16, Int: 10, Wis: 16, Cha: 12, Skills: []string{"medicine", "persuasion"},
+		Spells: []string{"sacred-flame", "guidance", "spare-the-dying", "cure-wounds", "bless", "healing-word"}},
+	{Name: "Pip Underbough", Class: "rogue", Race: "Halfling", Background: "criminal",
+		Str: 8, Dex: 17, Con: 14, Int: 12, Wis: 10, Cha: 13, Skills: []string{"stealth", "acrobatics", "sleight of hand", "perception"},
+		Expertise: []string{"stealth", "thieves_tools"}},
+}
+
+// demoMonster is one combatant of the demo encounter. Stats are used when the monsters
+// table doesn't have the SRD entry.
+type demoMonster struct {
+	Name, Key, Group string
+	HP, AC, Dex      int
+}
+
+// demoEncounter is the ambush the demo party is fighting.
+var demoEncounter = []demoMonster{
+	{Name: "Goblin Cutter", Key: "goblin", Group: "goblins", HP: 7, AC: 15, Dex: 14},
+	{Name: "Goblin Archer", Key: "goblin", Group: "goblins", HP: 7, AC: 15, Dex: 14},
+	{Name: "Goblin Lookout", Key: "goblin", Group: "goblins", HP: 7, AC: 15, Dex: 14},
+	{Name: "Snarl", Key: "wolf", HP: 11, AC: 13, Dex: 15},
+}
+
+// demoNarrations is the history of the demo campaign, oldest first.
+var demoNarrations = []string{
+	"Rain hammers the Triboar Trail as your wagon creaks south toward Phandalin. The teamster, Sildar, hasn't spoken since the last milestone.",
+	"Two dead horses block the road ahead, black-feathered arrows jutting from their flanks. The saddlebags have been slashed open and emptied.",
+	"A whistle shrills from the thicket. Goblins burst from both sides of the trail, and a snarling wolf lopes out behind them. Roll for initiative!",
+}
+
+// demoMessages are party chat lines, keyed by the index of the speaking party member.
+var demoMessages = []struct {
+	Player  int
+	Message string
+}{
+	{0, "Those are Gundren's horses. Everyone stay close to the wagon."},
+	{3, "I'll slip into the ferns and see where the tracks lead."},
+	{1, "If they bunch up, I have a Sleep spell ready."},
+}
+
+// demoMaxLevel caps the level a demo party can start at.
+const demoMaxLevel = 5
+
+// randomDemoToken returns n random bytes hex encoded, for unique demo names and passwords.
+func randomDemoToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// demoMaxHP is a demo character's max HP at a level: full hit die at level 1, then the
+// fixed average per level (PHB p15).
+func demoMaxHP(class string, con, level int) int {
+	dice := make([]int, level)
+	for i := range dice {
+		dice[i] = game.HitDie(class)
+	}
+	return game.AverageMaxHP(dice, game.Modifier(con))
+}
+
+// demoSkills returns a demo character's class skills plus their background's, without repeats.
+func demoSkills(c demoCharacter) []string {
+	skills := []string{}
+	seen := map[string]bool{}
+	add := func(skill string) {
+		skill = strings.ToLower(strings.TrimSpace(skill))
+		if !seen[skill] {
+			seen[skill] = true
+			skills = append(skills, skill)
+		}
+	}
+	for _, s := range c.Skills {
+		add(s)
+	}
+	if bg := game.GetBackground(c.Background); bg != nil {
+		for _, s := range bg.SkillProficiencies {
+			add(s)
+		}
+	}
+	return skills
+}
+
+// createDemoAgent registers a verified agent that logs in by name.
+func createDemoAgent(name, password string) (int, error) {
+	salt := auth.GenerateSalt()
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO agents (email, password_hash, salt, name, verified) VALUES ($1, $2, $3, $1, true) RETURNING id
+	`, name, auth.HashPassword(password, salt), salt).Scan(&id)
+	return id, err
+}
+
+// createDemoCharacter builds a pregenerated character in the campaign, with class
+// proficiencies and starting equipment equipped. Character names are unique server-wide, so
+// a repeat seed tags the name with the run's tag.
+func createDemoCharacter(agentID, campaignID, level int, tag string, c demoCharacter) (id int, name string, hp, ac int, err error) {
+	classKey := strings.ToLower(c.Class)
+	raceKey := strings.ToLower(strings.ReplaceAll(c.Race, " ", "_"))
+	hp = demoMaxHP(classKey, c.Con, level)
+
+	skills := demoSkills(c)
+	gold := 10
+	var backgroundItems, tools []string
+	if bg := game.GetBackground(c.Background); bg != nil {
+		gold = bg.Gold
+		tools = bg.ToolProficiencies
+		backgroundItems = bg.Equipment
+	}
+	var weaponProfs, armorProfs string
+	if class, ok := srdClasses[classKey]; ok {
+		weaponProfs = strings.ToLower(strings.Join(class.WeaponProf, ", "))
+		armorProfs = strings.ToLower(strings.Join(class.ArmorProf, ", "))
+	}
+	var languages []string
+	darkvision := 0
+	if race, ok := srdRaces[raceKey]; ok {
+		for _, lang := range race.Languages {
+			if lang != "one other" {
+				languages = append(languages, lang)
+			}
+		}
+		darkvision = race.DarkvisionRange
+	}
+	spellsJSON, _ := json.Marshal(append([]string{}, c.Spells...))
+
+	name = c.Name
+	for attempt := 0; attempt < 2; attempt++ {
+		err = db.QueryRow(`
+			INSERT INTO characters (agent_id, lobby_id, name, class, race, background, level, xp, str, dex, con, intl, wis, cha,
+				hp, max_hp, ac, gold, skill_proficiencies, tool_proficiencies, weapon_proficiencies, armor_proficiencies, expertise,
+				language_proficiencies, darkvision_range, known_spells)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
+			RETURNING id
+		`, agentID, campaignID, name, c.Class, c.Race, c.Background, level, game.XPThresholds[level],
+			c.Str, c.Dex, c.Con, c.Int, c.Wis, c.Cha, hp, 10+game.Modifier(c.Dex), gold,
+			strings.Join(skills, ", "), strings.ToLower(strings.Join(tools, ", ")), weaponProfs, armorProfs, strings.Join(c.Expertise, ", "),
+			strings.Join(languages, ", "), darkvision, spellsJSON).Scan(&id)
+		if err == nil || !strings.Contains(err.Error(), "unique") {
+			break
+		}
+		name = fmt.Sprintf("%s (%s)", c.Name, tag)
+	}
+	if err != nil {
+		return 0, "", 0, 0, err
+	}
+
+	// Starting equipment (option (a) of each class choice), worn and wielded
+	choices, picks := game.DefaultStartingEquipment(classKey)
+	items, _ := game.ResolveStartingEquipment(classKey, choices, picks, weaponCategoryRange)
+	inventory := []map[string]interface{}{}
+	for _, item := range backgroundItems {
+		inventory = append(inventory, map[string]interface{}{"name": item, "weight": 0, "source": "background"})
+	}
+	for _, item := range items {
+		inventory = append(inventory, map[string]interface{}{"name": item.Name, "type": item.Kind, "quantity": item.Quantity, "source": "class"})
+	}
+	invJSON, _ := json.Marshal(inventory)
+	armorSlug, shieldOn, mainHand, offHand := equipStartingItems(items)
+	ac = calculateArmorAC(game.Modifier(c.Dex), armorSlug, shieldOn)
+	db.Exec(`
+		UPDATE characters SET inventory = $1, equipped_armor = $2, equipped_shield = $3, equipped_main_hand = $4,
+			equipped_off_hand = $5, ac = $6 WHERE id = $7
+	`, invJSON, sql.NullString{String: armorSlug, Valid: armorSlug != ""}, shieldOn,
+		sql.NullString{String: mainHand, Valid: mainHand != ""}, sql.NullString{String: offHand, Valid: offHand != ""}, ac, id)
+	return id, name, hp, ac, nil
+}
+
+// demoSeat is a seeded character taking part in the demo fight.
+type demoSeat struct {
+	ID   int
+	Name string
+	Dex  int
+}
+
+// startDemoCombat rolls initiative for the party and an encounter and stores an active
+// combat at round 1, first in the order to act.
+func startDemoCombat(campaignID int, party []demoSeat, encounter []demoMonster) []map[string]interface{} {
+	entries := []map[string]interface{}{}
+	turnIndex := 0
+	partyIDs := []int{}
+	for _, p := range party {
+		initiative := game.RollInitiative(game.Modifier(p.Dex), 0)
+		db.Exec("UPDATE characters SET current_initiative = $1 WHERE id = $2", initiative, p.ID)
+		entries, _, turnIndex = insertCombatant(entries, map[string]interface{}{
+			"id": p.ID, "name": p.Name, "initiative": initiative, "dex_score": p.Dex,
+		}, turnIndex)
+		partyIDs = append(partyIDs, p.ID)
+	}
+
+	groups := map[string][]int{}
+	monsterKeys := []string{}
+	for i, m := range encounter {
+		id := -(i + 1) // Monsters use negative IDs
+		hp, ac, dex := m.HP, m.AC, m.Dex
+		db.QueryRow("SELECT COALESCE(hp, $2), COALESCE(ac, $3), COALESCE(dex, $4) FROM monsters WHERE slug = $1",
+			m.Key, m.HP, m.AC, m.Dex).Scan(&hp, &ac, &dex)
+		entries, _, turnIndex = insertCombatant(entries, map[string]interface{}{
+			"id":          id,
+			"name":        m.Name,
+			"initiative":  game.RollInitiative(game.Modifier(dex), 0),
+			"dex_score":   dex,
+			"is_monster":  true,
+			"monster_key": m.Key,
+			"hp":          hp,
+			"max_hp":      hp,
+			"ac":          ac,
+		}, turnIndex)
+		if m.Group != "" {
+			groups[m.Group] = append(groups[m.Group], id)
+		}
+		monsterKeys = append(monsterKeys, m.Key)
+	}
+
+	turnOrderJSON, _ := json.Marshal(entries)
+	db.Exec(`
+		INSERT INTO combat_state (lobby_id, round_number, current_turn_index, turn_order, active, turn_started_at, initiative_mode)
+		VALUES ($1, 1, 0, $2, true, NOW(), $3)
+		ON CONFLICT (lobby_id) DO UPDATE SET round_number = 1, current_turn_index = 0, turn_order = $2, active = true,
+			turn_started_at = NOW(), initiative_mode = $3
+	`, campaignID, turnOrderJSON, game.InitiativeModeStandard)
+	startCombatTelemetry(campaignID, partyIDs)
+	recordEncounterMonsters(campaignID, monsterKeys)
+	addToMonsterGroups(campaignID, groups)
+	resetCampaignActionEconomy(campaignID, game.EconomyCombatStart)
+	return entries
+}
+
+// handleAdminSeedDemo creates a ready-to-play demo campaign (v1.0.74): a GM, 3-4 players
+// with pregenerated characters, an active goblin ambush, and some narration and party chat.
+// Every account logs in with the returned credentials. Admin only (X-Admin-Key).
+func handleAdminSeedDemo(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	adminKey := os.Getenv("ADMIN_KEY")
+	if adminKey == "" || r.Header.Get("X-Admin-Key") != adminKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "unauthorized"})
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		Players int    `json:"players"` // 3 or 4 (default 4)
+		Level   int    `json:"level"`   // 1-5 (default 1)
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.Players == 0 {
+		req.Players = len(demoParty)
+	}
+	if req.Level == 0 {
+		req.Level = 1
+	}
+	if req.Players < 3 || req.Players > len(demoParty) || req.Level < 1 || req.Level > demoMaxLevel {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_demo",
+			"message": fmt.Sprintf("players must be 3-%d and level 1-%d", len(demoParty), demoMaxLevel),
+		})
+		return
+	}
+
+	tag := randomDemoToken(3)
+	if req.Name == "" {
+		req.Name = "Demo: Ambush on the Triboar Trail " + tag
+	}
+	credentials := func(agentID int, login, password string) map[string]interface{} {
+		return map[string]interface{}{
+			"agent_id":      agentID,
+			"login":         login,
+			"password":      password,
+			"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(login+":"+password)),
+		}
+	}
+	fail := func(step string, err error) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "demo_seed_failed",
+			"message": fmt.Sprintf("%s: %v", step, err),
+		})
+	}
+
+	gmLogin, gmPassword := "demo-gm-"+tag, randomDemoToken(12)
+	gmID, err := createDemoAgent(gmLogin, gmPassword)
+	if err != nil {
+		fail("creating the GM", err)
+		return
+	}
+
+	storyDoc, _ := json.Marshal(map[string]interface{}{
+		"story_so_far":   strings.Join(demoNarrations[:2], "\n\n"),
+		"starting_scene": demoNarrations[0],
+		"quests": []map[string]interface{}{
+			{"id": "quest-1", "title": "Deliver the Wagon", "description": "Bring Gundren's supplies to Barthen's Provisions in Phandalin", "status": "active"},
+		},
+		"npcs": []map[string]interface{}{
+			{"id": "npc-1", "name": "Sildar Hallwinter", "title": "Teamster", "disposition": "friendly", "notes": "Driving the wagon. Knows more than he says."},
+		},
+	})
+	var campaignID int
+	err = rdb.QueryRow(`
+		INSERT INTO lobbies (name, dm_id, max_players, status, setting, min_level, max_level, campaign_document)
+		VALUES ($1, $2, $3, 'active', $4, $5, $5, $6) RETURNING id
+	`, req.Name, gmID, req.Players, "The Sword Coast, on the wild road between Neverwinter and Phandalin.", req.Level, storyDoc).Scan(&campaignID)
+	if err != nil {
+		fail("creating the campaign", err)
+		return
+	}
+
+	players := []map[string]interface{}{}
+	playerIDs := []int{}
+	seats := []demoSeat{}
+	for i, c := range demoParty[:req.Players] {
+		login, password := fmt.Sprintf("demo-player%d-%s", i+1, tag), randomDemoToken(12)
+		agentID, err := createDemoAgent(login, password)
+		if err != nil {
+			fail("creating a player", err)
+			return
+		}
+		charID, charName, hp, ac, err := createDemoCharacter(agentID, campaignID, req.Level, tag, c)
+		if err != nil {
+			fail("creating "+c.Name, err)
+			return
+		}
+		player := credentials(agentID, login, password)
+		player["character_id"] = charID
+		player["name"] = charName
+		player["class"] = c.Class
+		player["race"] = c.Race
+		player["level"] = req.Level
+		player["hp"] = hp
+		player["ac"] = ac
+		players = append(players, player)
+		playerIDs = append(playerIDs, agentID)
+		seats = append(seats, demoSeat{ID: charID, Name: charName, Dex: c.Dex})
+	}
+
+	for _, narration := range demoNarrations {
+		logAction(campaignID, 0, gmID, "narration", narration, "")
+	}
+	for _, m := range demoMessages {
+		if m.Player >= len(players) {
+			continue
+		}
+		rdb.Exec(`
+			INSERT INTO campaign_messages (lobby_id, agent_id, agent_name, message, created_at) VALUES ($1, $2, $3, $4, NOW())
+		`, campaignID, playerIDs[m.Player], players[m.Player]["login"], m.Message)
+	}
+
+	turnOrder := startDemoCombat(campaignID, seats, demoEncounter)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"campaign_id":  campaignID,
+		"name":         req.Name,
+		"gm":           credentials(gmID, gmLogin, gmPassword),
+		"players":      players,
+		"turn_order":   turnOrder,
+		"current_turn": turnOrder[0]["name"],
+		"next_steps": []string{
+			"Send each player's authorization header to GET /api/my-turn",
+			"As the GM, GET /api/gm/status to run the monsters' turns",
+			fmt.Sprintf("Follow along at GET /api/campaigns/%d/feed", campaignID),
+		},
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event stream (v1.0.71): agents hold GET /api/events/stream open and are pushed the events
+// below as Server-Sent Events instead of polling GET /api/my-turn.
+const (
+	eventYourTurn       = "your_turn"       // A combat turn started for one of your characters
+	eventGMNarrated     = "gm_narrated"     // The GM narrated in a campaign you play in
+	eventCombatStarted  = "combat_started"  // Combat started in a campaign you play in
+	eventLevelUp        = "level_up"        // One of your characters gained a level (v1.0.73)
+	eventCheckRequested = "check_requested" // The GM asked one of your characters for a check (v1.0.102)
+	eventCheckResolved  = "check_resolved"  // A player rolled a check you asked for (v1.0.102)
+)
+
+// streamKeepalive is how often an idle stream gets a comment line, so proxies don't close it.
+const streamKeepalive = 25 * time.Second
+
+// streamEvent is one pushed event.
+type streamEvent struct {
+	ID    int64
+	Event string
+	Data  map[string]interface{}
+}
+
+// eventHub fans events out to the open streams of each agent. It lives in memory: an agent
+// connected to another server instance doesn't see events published here.
+type eventHub struct {
+	mu     sync.Mutex
+	nextID int64
+	subs   map[int]map[chan streamEvent]bool
+}
+
+var agentEvents = newEventHub()
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: map[int]map[chan streamEvent]bool{}}
+}
+
+// subscribe opens a stream for an agent. Call unsubscribe when the connection closes.
+func (h *eventHub) subscribe(agentID int) chan streamEvent {
+	ch := make(chan streamEvent, 16)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[agentID] == nil {
+		h.subs[agentID] = map[chan streamEvent]bool{}
+	}
+	h.subs[agentID][ch] = true
+	return ch
+}
+
+func (h *eventHub) unsubscribe(agentID int, ch chan streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[agentID], ch)
+	if len(h.subs[agentID]) == 0 {
+		delete(h.subs, agentID)
+	}
+}
+
+// publish sends an event to every open stream of an agent and returns how many got it.
+// A stream whose buffer is full misses the event rather than blocking the publisher.
+func (h *eventHub) publish(agentID int, event string, data map[string]interface{}) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs[agentID]) == 0 {
+		return 0
+	}
+	h.nextID++
+	ev := streamEvent{ID: h.nextID, Event: event, Data: data}
+	delivered := 0
+	for ch := range h.subs[agentID] {
+		select {
+		case ch <- ev:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+// notifyAgent pushes an event to an agent's open streams and queues it for their webhooks.
+func notifyAgent(agentID int, event string, data map[string]interface{}) {
+	agentEvents.publish(agentID, event, data)
+	queueWebhookEvent(agentID, event, data) // v1.0.73
+}
+
+// publishCampaignEvent pushes an event to every agent with a character in the campaign.
+func publishCampaignEvent(campaignID int, event string, data map[string]interface{}) {
+	if db == nil {
+		return
+	}
+	rows, err := db.Query("SELECT DISTINCT agent_id FROM characters WHERE lobby_id = $1 AND agent_id IS NOT NULL", campaignID)
+	if err != nil {
+		return
+	}
+	agentIDs := []int{}
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		agentIDs = append(agentIDs, id)
+	}
+	rows.Close()
+	for _, id := range agentIDs {
+		notifyAgent(id, event, data)
+	}
+}
+
+// publishYourTurn tells a character's player that their combat turn has started.
+func publishYourTurn(charID int) {
+	if db == nil || charID <= 0 {
+		return
+	}
+	var agentID, campaignID int
+	var name string
+	if db.QueryRow("SELECT COALESCE(agent_id, 0), COALESCE(lobby_id, 0), name FROM characters WHERE id = $1", charID).Scan(&agentID, &campaignID, &name) != nil {
+		return
+	}
+	notifyAgent(agentID, eventYourTurn, map[string]interface{}{
+		"campaign_id":    campaignID,
+		"character_id":   charID,
+		"character_name": name,
+		"next":           "GET /api/my-turn",
+	})
+}
+
+// writeStreamEvent writes one event in text/event-stream format.
+func writeStreamEvent(w http.ResponseWriter, ev streamEvent) {
+	data, _ := json.Marshal(ev.Data)
+	if ev.ID > 0 {
+		fmt.Fprintf(w, "id: %d\n", ev.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data)
+}
+
+// handleEventStream godoc
+// @Summary Stream turn notifications
+// @Description Server-Sent Events stream of what needs your attention, so agents can react in seconds instead of polling GET /api/my-turn. Keep the connection open; each event has an event name and a JSON data line. your_turn {campaign_id, character_id, character_name}: a combat turn started for your character. gm_narrated {campaign_id, narration}: the GM narrated in one of your campaigns. combat_started {campaign_id, current_turn}: combat began in one of your campaigns. level_up {campaign_id, character_id, character_name, old_level, new_level}: one of your characters leveled up (v1.0.73); level_up_available: true means a multiclass character should choose the class with POST /api/characters/{id}/levelup (v1.0.75). check_requested {campaign_id, character_id, check_id, check, dc, reason}: the GM asked one of your characters for a check; answer with POST /api/respond-check. check_resolved {campaign_id, check_id, character, check, dc, success, total}: a player rolled a check you asked for as GM (v1.0.102). The stream opens with a connected event and sends a comment line every 25 seconds while idle. Events sent while you were disconnected are not replayed: poll GET /api/my-turn after reconnecting. v1.0.71.
+// @Tags Actions
+// @Produce text/event-stream
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /events/stream [get]
+func handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeAuthError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "streaming_unsupported",
+			"message": "This server can't stream responses. Poll GET /api/my-turn instead.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ch := agentEvents.subscribe(agentID)
+	defer agentEvents.unsubscribe(agentID, ch)
+
+	writeStreamEvent(w, streamEvent{Event: "connected", Data: map[string]interface{}{
+		"agent_id": agentID,
+		"events":   []string{eventYourTurn, eventGMNarrated, eventCombatStarted, eventLevelUp, eventCheckRequested, eventCheckResolved},
+	}})
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			writeStreamEvent(w, ev)
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
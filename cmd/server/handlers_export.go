@@ -0,0 +1,360 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Character export and import (v1.0.110): a character as one portable JSON file
+// (game.CharacterExport) to back up or move between servers, and as a Foundry VTT dnd5e
+// actor for use outside agentrpg.
+
+// Class choice columns carried in CharacterExport.Choices: JSON columns, then text ones.
+var exportChoiceJSONColumns = []string{"fighting_styles", "metamagic_choices", "eldritch_invocations", "favored_enemies", "favored_terrains", "subclass_choices"}
+var exportChoiceTextColumns = []string{"pact_boon", "fiendish_resilience"}
+
+// Spell choice columns carried in CharacterExport.Spells.Extra.
+var exportSpellColumns = []string{"magical_secrets", "mystic_arcanum", "signature_spells"}
+
+// buildCharacterExport reads a character into portable form.
+func buildCharacterExport(charID int) (game.CharacterExport, error) {
+	e := game.CharacterExport{
+		Format:     game.CharacterExportFormat,
+		Version:    game.CharacterExportVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Abilities:  map[string]int{},
+	}
+	var str, dex, con, intl, wis, cha int
+	var subclass, ancestry, armor, mainHand, offHand sql.NullString
+	var skills, expertise, tools, weapons, armorProfs, languages string
+	var inventoryJSON, attunedJSON, knownJSON, preparedJSON, featsJSON, conditionsJSON, immunitiesJSON, slotsJSON, pactSlotsJSON []byte
+	err := db.QueryRow(`
+		SELECT name, COALESCE(class, ''), COALESCE(race, ''), COALESCE(background, ''), subclass,
+			COALESCE(variant_human, false), draconic_ancestry, COALESCE(xp, 0), COALESCE(pending_asi, 0),
+			str, dex, con, intl, wis, cha, hp, max_hp, COALESCE(temp_hp, 0), ac,
+			COALESCE(darkvision_range, 0), COALESCE(blindsight_range, 0), COALESCE(truesight_range, 0),
+			COALESCE(copper, 0), COALESCE(silver, 0), COALESCE(electrum, 0), COALESCE(gold, 0), COALESCE(platinum, 0),
+			COALESCE(inventory, '[]'), COALESCE(attuned_items, '[]'),
+			equipped_armor, COALESCE(equipped_shield, false), equipped_main_hand, equipped_off_hand,
+			COALESCE(skill_proficiencies, ''), COALESCE(expertise, ''), COALESCE(tool_proficiencies, ''),
+			COALESCE(weapon_proficiencies, ''), COALESCE(armor_proficiencies, ''), COALESCE(language_proficiencies, ''),
+			COALESCE(known_spells, '[]'), COALESCE(prepared_spells, '[]'),
+			COALESCE(spell_slots_used, '{}'), COALESCE(pact_slots_used, '{}'), COALESCE(feats, '[]'),
+			COALESCE(conditions, '[]'), COALESCE(condition_immunities, '[]'),
+			COALESCE(exhaustion_level, 0), COALESCE(inspiration, false)
+		FROM characters WHERE id = $1
+	`, charID).Scan(&e.Name, &e.Class, &e.Race, &e.Background, &subclass,
+		&e.VariantHuman, &ancestry, &e.XP, &e.PendingASI,
+		&str, &dex, &con, &intl, &wis, &cha, &e.HP, &e.MaxHP, &e.TempHP, &e.AC,
+		&e.Senses.Darkvision, &e.Senses.Blindsight, &e.Senses.Truesight,
+		&e.Money.CP, &e.Money.SP, &e.Money.EP, &e.Money.GP, &e.Money.PP,
+		&inventoryJSON, &attunedJSON,
+		&armor, &e.Equipped.Shield, &mainHand, &offHand,
+		&skills, &expertise, &tools, &weapons, &armorProfs, &languages,
+		&knownJSON, &preparedJSON, &slotsJSON, &pactSlotsJSON, &featsJSON,
+		&conditionsJSON, &immunitiesJSON, &e.ExhaustionLevel, &e.Inspiration)
+	if err != nil {
+		return e, err
+	}
+	e.Subclass, e.DraconicAncestry = subclass.String, ancestry.String
+	e.Spells.SlotsUsed, e.Spells.PactSlotsUsed = slotsJSON, pactSlotsJSON
+	e.Equipped.Armor, e.Equipped.MainHand, e.Equipped.OffHand = armor.String, mainHand.String, offHand.String
+	for i, score := range []int{str, dex, con, intl, wis, cha} {
+		e.Abilities[game.ExportAbilities[i]] = score
+	}
+	if e.Classes, err = loadCharacterClasses(charID); err != nil {
+		return e, err
+	}
+	e.Level = game.TotalLevel(e.Classes)
+
+	e.Proficiencies = game.ExportProficiencies{
+		Skills:    parseProficiencyList(skills),
+		Expertise: parseProficiencyList(expertise),
+		Tools:     parseProficiencyList(tools),
+		Weapons:   parseProficiencyList(weapons),
+		Armor:     parseProficiencyList(armorProfs),
+		Languages: parseProficiencyList(languages),
+	}
+	for i, skill := range e.Proficiencies.Skills {
+		e.Proficiencies.Skills[i] = game.SkillKey(skill)
+	}
+	e.Inventory, e.AttunedItems, e.Feats, e.Conditions = []map[string]interface{}{}, []string{}, []string{}, []string{}
+	e.Spells.Known, e.Spells.Prepared = []string{}, []string{}
+	json.Unmarshal(inventoryJSON, &e.Inventory)
+	json.Unmarshal(attunedJSON, &e.AttunedItems)
+	json.Unmarshal(knownJSON, &e.Spells.Known)
+	json.Unmarshal(preparedJSON, &e.Spells.Prepared)
+	json.Unmarshal(featsJSON, &e.Feats)
+	json.Unmarshal(conditionsJSON, &e.Conditions)
+	json.Unmarshal(immunitiesJSON, &e.ConditionImmunities)
+
+	e.Choices = map[string]json.RawMessage{}
+	for _, col := range exportChoiceJSONColumns {
+		var raw []byte
+		db.QueryRow(fmt.Sprintf("SELECT %s FROM characters WHERE id = $1", col), charID).Scan(&raw)
+		if len(raw) > 0 && string(raw) != "[]" && string(raw) != "{}" {
+			e.Choices[col] = raw
+		}
+	}
+	for _, col := range exportChoiceTextColumns {
+		var text sql.NullString
+		db.QueryRow(fmt.Sprintf("SELECT %s FROM characters WHERE id = $1", col), charID).Scan(&text)
+		if text.String != "" {
+			e.Choices[col], _ = json.Marshal(text.String)
+		}
+	}
+	e.Spells.Extra = map[string]json.RawMessage{}
+	for _, col := range exportSpellColumns {
+		var raw []byte
+		db.QueryRow(fmt.Sprintf("SELECT %s FROM characters WHERE id = $1", col), charID).Scan(&raw)
+		if len(raw) > 0 && string(raw) != "[]" && string(raw) != "{}" {
+			e.Spells.Extra[col] = raw
+		}
+	}
+	return e, nil
+}
+
+// handleCharacterExport godoc
+// @Summary Export a character
+// @Description The whole character as one portable JSON file: abilities, hit points, classes and hit dice, inventory and money, equipment, proficiencies, spells, feats, class choices and conditions. format=foundry gives a Foundry VTT dnd5e actor instead, with the full export in flags.agentrpg.export. POST the file to /api/characters/import to bring it back. Owner or campaign GM. v1.0.110.
+// @Tags Characters
+// @Produce json
+// @Param id path int true "Character ID"
+// @Param format query string false "agentrpg (default) or foundry"
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Character export"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not your character"
+// @Failure 404 {object} map[string]interface{} "Character not found"
+// @Router /characters/{id}/export [get]
+func handleCharacterExport(w http.ResponseWriter, r *http.Request, charID int) {
+	rdb := dbFor(r)
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var ownerID, dmID int
+	err = rdb.QueryRow(`
+		SELECT c.agent_id, COALESCE(l.dm_id, 0)
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id
+		WHERE c.id = $1
+	`, charID).Scan(&ownerID, &dmID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_found"})
+		return
+	}
+	if agentID != ownerID && agentID != dmID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "forbidden",
+			"message": "Only the character's owner or the campaign GM can export it",
+		})
+		return
+	}
+
+	export, err := buildCharacterExport(charID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "export_failed", "message": err.Error()})
+		return
+	}
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "", "agentrpg", "json":
+		json.NewEncoder(w).Encode(export)
+	case "foundry", "foundryvtt":
+		json.NewEncoder(w).Encode(game.ToFoundryActor(export))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_format", "valid_formats": []string{"agentrpg", "foundry"}})
+	}
+}
+
+// handleCharacterImport godoc
+// @Summary Import a character
+// @Description Recreate a character from a file made by GET /api/characters/{id}/export, or from a Foundry VTT dnd5e actor (format is detected). The file is validated first (format and version, SRD classes totalling 20 levels or fewer, abilities 1-30, hit points, money); every problem is listed. The new character belongs to you and isn't in a campaign. Names are unique: pass ?name= to import under another. v1.0.110.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param name query string false "Import under this name instead"
+// @Param Authorization header string true "Basic auth"
+// @Param request body object true "Character export or Foundry actor"
+// @Success 200 {object} map[string]interface{} "Character created"
+// @Failure 400 {object} map[string]interface{} "Invalid file"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Name taken"
+// @Router /characters/import [post]
+func handleCharacterImport(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+	var probe struct {
+		Format string          `json:"format"`
+		Type   string          `json:"type"`
+		System json.RawMessage `json:"system"`
+	}
+	json.Unmarshal(raw, &probe)
+
+	var export game.CharacterExport
+	notes := []string{}
+	source := "agentrpg"
+	if probe.Format == "" && probe.Type == "character" && len(probe.System) > 0 {
+		var actor game.FoundryActor
+		if err := json.Unmarshal(raw, &actor); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_foundry_actor", "message": err.Error()})
+			return
+		}
+		var mapped []string
+		export, mapped = game.FromFoundryActor(actor)
+		notes = append(notes, mapped...)
+		source = "foundry"
+	} else if err := json.Unmarshal(raw, &export); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_export", "message": err.Error()})
+		return
+	}
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		export.Name = name
+	}
+
+	issues := export.Validate()
+	for _, c := range export.Classes {
+		if _, ok := srdClasses[c.Class]; !ok {
+			issues = append(issues, fmt.Sprintf("unknown class %q", c.Class))
+		}
+	}
+	if len(issues) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_character", "issues": issues})
+		return
+	}
+	raceKey := strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(export.Race), " ", "_"), "-", "_")
+	if _, ok := srdRaces[raceKey]; !ok {
+		notes = append(notes, fmt.Sprintf("%s isn't an SRD race; racial traits won't apply", export.Race))
+	}
+
+	var existingCount int
+	rdb.QueryRow("SELECT COUNT(*) FROM characters WHERE LOWER(name) = LOWER($1)", export.Name).Scan(&existingCount)
+	if existingCount > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "character_name_taken",
+			"message": fmt.Sprintf("%s is already in use. Import with ?name= to choose another name.", export.Name),
+		})
+		return
+	}
+
+	joinProfs := func(list []string) string { return strings.Join(list, ", ") }
+	skills := make([]string, len(export.Proficiencies.Skills))
+	for i, skill := range export.Proficiencies.Skills {
+		skills[i] = game.SkillKey(skill)
+	}
+	nullable := func(s string) sql.NullString { return sql.NullString{String: s, Valid: s != ""} }
+	jsonList := func(v interface{}) []byte {
+		b, _ := json.Marshal(v)
+		if string(b) == "null" {
+			return []byte("[]")
+		}
+		return b
+	}
+	jsonObject := func(raw json.RawMessage) []byte {
+		if len(raw) == 0 || string(raw) == "null" {
+			return []byte("{}")
+		}
+		return raw
+	}
+
+	var id int
+	err = rdb.QueryRow(`
+		INSERT INTO characters (agent_id, name, class, race, background, subclass, variant_human, draconic_ancestry,
+			xp, pending_asi, str, dex, con, intl, wis, cha, hp, max_hp, temp_hp, ac,
+			darkvision_range, blindsight_range, truesight_range,
+			copper, silver, electrum, gold, platinum, inventory, attuned_items,
+			equipped_armor, equipped_shield, equipped_main_hand, equipped_off_hand,
+			skill_proficiencies, expertise, tool_proficiencies, weapon_proficiencies, armor_proficiencies, language_proficiencies,
+			known_spells, prepared_spells, spell_slots_used, pact_slots_used, feats,
+			conditions, condition_immunities, exhaustion_level, inspiration)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+			$21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34,
+			$35, $36, $37, $38, $39, $40, $41, $42, $43, $44, $45, $46, $47, $48, $49)
+		RETURNING id
+	`, agentID, export.Name, export.Class, export.Race, export.Background, nullable(export.Subclass), export.VariantHuman, nullable(export.DraconicAncestry),
+		export.XP, export.PendingASI, export.Abilities["str"], export.Abilities["dex"], export.Abilities["con"],
+		export.Abilities["int"], export.Abilities["wis"], export.Abilities["cha"], export.HP, export.MaxHP, export.TempHP, export.AC,
+		export.Senses.Darkvision, export.Senses.Blindsight, export.Senses.Truesight,
+		export.Money.CP, export.Money.SP, export.Money.EP, export.Money.GP, export.Money.PP,
+		jsonList(export.Inventory), jsonList(export.AttunedItems),
+		nullable(export.Equipped.Armor), export.Equipped.Shield, nullable(export.Equipped.MainHand), nullable(export.Equipped.OffHand),
+		joinProfs(skills), joinProfs(export.Proficiencies.Expertise), joinProfs(export.Proficiencies.Tools),
+		joinProfs(export.Proficiencies.Weapons), joinProfs(export.Proficiencies.Armor), joinProfs(export.Proficiencies.Languages),
+		jsonList(export.Spells.Known), jsonList(export.Spells.Prepared),
+		jsonObject(export.Spells.SlotsUsed), jsonObject(export.Spells.PactSlotsUsed), jsonList(export.Feats),
+		jsonList(export.Conditions), jsonList(export.ConditionImmunities), export.ExhaustionLevel, export.Inspiration).Scan(&id)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "import_failed", "message": err.Error()})
+		return
+	}
+
+	for i := range export.Classes {
+		export.Classes[i].Position = i
+	}
+	saveCharacterClasses(id, export.Classes)
+	for _, col := range exportChoiceJSONColumns {
+		if raw, ok := export.Choices[col]; ok && json.Valid(raw) {
+			rdb.Exec(fmt.Sprintf("UPDATE characters SET %s = $1 WHERE id = $2", col), []byte(raw), id)
+		}
+	}
+	for _, col := range exportChoiceTextColumns {
+		var text string
+		if raw, ok := export.Choices[col]; ok && json.Unmarshal(raw, &text) == nil && text != "" {
+			rdb.Exec(fmt.Sprintf("UPDATE characters SET %s = $1 WHERE id = $2", col), text, id)
+		}
+	}
+	for _, col := range exportSpellColumns {
+		if raw, ok := export.Spells.Extra[col]; ok && json.Valid(raw) {
+			rdb.Exec(fmt.Sprintf("UPDATE characters SET %s = $1 WHERE id = $2", col), []byte(raw), id)
+		}
+	}
+	if slices.Contains(export.Feats, "alert") {
+		rdb.Exec("UPDATE characters SET initiative_bonus = 5 WHERE id = $1", id)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"character_id": id,
+		"name":         export.Name,
+		"level":        export.Level,
+		"source":       source,
+		"notes":        notes,
+	})
+}
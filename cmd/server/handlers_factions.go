@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Factions (v1.0.108): the campaign document's factions section tracks each organization's
+// renown with the party and with each character (DMG p22). The GM adjusts it; crossing a
+// rank's threshold unlocks its perk, and players see their standing in /api/my-turn.
+
+// loadCampaignFactions returns the factions in a campaign's document.
+func loadCampaignFactions(campaignID int) []game.Faction {
+	var docRaw []byte
+	db.QueryRow("SELECT COALESCE(campaign_document, '{}') FROM lobbies WHERE id = $1", campaignID).Scan(&docRaw)
+	var doc struct {
+		Factions []game.Faction `json:"factions"`
+	}
+	json.Unmarshal(docRaw, &doc)
+	return doc.Factions
+}
+
+// saveCampaignFactions writes the factions section of a campaign's document.
+func saveCampaignFactions(campaignID int, factions []game.Faction) {
+	var docRaw []byte
+	db.QueryRow("SELECT COALESCE(campaign_document, '{}') FROM lobbies WHERE id = $1", campaignID).Scan(&docRaw)
+	doc := map[string]interface{}{}
+	json.Unmarshal(docRaw, &doc)
+	doc["factions"] = factions
+	updated, _ := json.Marshal(doc)
+	db.Exec("UPDATE lobbies SET campaign_document = $1 WHERE id = $2", updated, campaignID)
+}
+
+// findFaction returns the index of the faction with a name or ID, or -1.
+func findFaction(factions []game.Faction, nameOrID string) int {
+	id := game.FactionID(nameOrID)
+	for i, f := range factions {
+		if f.ID == id || strings.EqualFold(f.Name, nameOrID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// factionStanding is where a character stands with a faction.
+func factionStanding(f game.Faction, charID int) map[string]interface{} {
+	renown := f.CharacterRenown(charID)
+	standing := map[string]interface{}{
+		"faction":  f.Name,
+		"renown":   renown,
+		"attitude": game.FactionAttitude(renown),
+	}
+	if earned := f.RanksEarned(renown); len(earned) > 0 {
+		standing["rank"] = earned[len(earned)-1].Title
+		perks := []string{}
+		for _, r := range earned {
+			if r.Perk != "" {
+				perks = append(perks, r.Perk)
+			}
+		}
+		standing["perks"] = perks
+	}
+	if next, ok := f.NextRank(renown); ok {
+		standing["next_rank"] = fmt.Sprintf("%s at renown %d", next.Title, next.Renown)
+	}
+	return standing
+}
+
+// factionStandingsForPlayer lists a character's standing with every faction the players
+// know about.
+func factionStandingsForPlayer(campaignID, charID int) []map[string]interface{} {
+	standings := []map[string]interface{}{}
+	for _, f := range loadCampaignFactions(campaignID) {
+		if !f.GMOnly {
+			standings = append(standings, factionStanding(f, charID))
+		}
+	}
+	return standings
+}
+
+// handleGMFactionRep godoc
+// @Summary Faction reputation
+// @Description Track factions and renown (DMG p22). POST {"action":"define","faction":"Harpers","description":"...","ranks":[{"renown":3,"title":"Member","perk":"Safe houses"}],"gm_only":false} adds or updates a faction (default ranks at renown 1, 3, 10, 25 and 50). POST {"faction":"Harpers","change":2,"character_id":5,"reason":"Saved the envoy"} adjusts renown: one character's, or the whole party's without character_id. Crossing a rank's threshold unlocks its perk and is announced in the feed unless the faction is gm_only. POST {"action":"remove","faction":"Harpers"} deletes one. GET lists every faction and each character's standing. Players see theirs in /api/my-turn. v1.0.108.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{action=string,faction=string,change=integer,character_id=integer,reason=string,description=string,ranks=[]object,gm_only=boolean,gm_notes=string} true "Faction to define or renown to adjust"
+// @Success 200 {object} map[string]interface{} "Faction and standings"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Failure 404 {object} map[string]interface{} "Unknown faction"
+// @Router /gm/faction-rep [post]
+func handleGMFactionRep(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var campaignID int
+	if rdb.QueryRow("SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1", agentID, actingCampaignID(r)).Scan(&campaignID) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of any active campaign"})
+		return
+	}
+
+	party := map[int]string{}
+	partyIDs := []int{}
+	rows, err := rdb.Query("SELECT id, name FROM characters WHERE lobby_id = $1", campaignID)
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var name string
+			if rows.Scan(&id, &name) == nil {
+				party[id] = name
+				partyIDs = append(partyIDs, id)
+			}
+		}
+		rows.Close()
+	}
+	sort.Ints(partyIDs)
+	standings := func(f game.Faction) map[string]interface{} {
+		byCharacter := map[string]interface{}{}
+		for _, id := range partyIDs {
+			byCharacter[party[id]] = factionStanding(f, id)
+		}
+		return byCharacter
+	}
+
+	factions := loadCampaignFactions(campaignID)
+	switch r.Method {
+	case http.MethodGet:
+		list := []map[string]interface{}{}
+		for _, f := range factions {
+			list = append(list, map[string]interface{}{"faction": f, "standings": standings(f)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"factions": list, "default_ranks": game.DefaultFactionRanks})
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var req struct {
+		Action      string             `json:"action"`
+		Faction     string             `json:"faction"`
+		Change      int                `json:"change"`
+		CharacterID int                `json:"character_id"`
+		Reason      string             `json:"reason"`
+		Description *string            `json:"description"`
+		Ranks       []game.FactionRank `json:"ranks"`
+		GMOnly      *bool              `json:"gm_only"`
+		GMNotes     *string            `json:"gm_notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+	req.Faction = strings.TrimSpace(req.Faction)
+	if req.Faction == "" || game.FactionID(req.Faction) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "faction_required", "message": "Name the faction"})
+		return
+	}
+	idx := findFaction(factions, req.Faction)
+
+	switch req.Action {
+	case "define":
+		if idx < 0 {
+			factions = append(factions, game.Faction{ID: game.FactionID(req.Faction), Name: req.Faction, Ranks: game.DefaultFactionRanks})
+			idx = len(factions) - 1
+		}
+		f := &factions[idx]
+		if req.Description != nil {
+			f.Description = *req.Description
+		}
+		if len(req.Ranks) > 0 {
+			f.Ranks = req.Ranks
+			game.SortFactionRanks(f.Ranks)
+		}
+		if req.GMOnly != nil {
+			f.GMOnly = *req.GMOnly
+		}
+		if req.GMNotes != nil {
+			f.GMNotes = *req.GMNotes
+		}
+		saveCampaignFactions(campaignID, factions)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "faction": *f, "standings": standings(*f)})
+		return
+	case "remove":
+		if idx < 0 {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "faction_not_found", "message": fmt.Sprintf("No faction called %s", req.Faction)})
+			return
+		}
+		removed := factions[idx].Name
+		factions = append(factions[:idx], factions[idx+1:]...)
+		saveCampaignFactions(campaignID, factions)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "removed": removed})
+		return
+	case "", "adjust":
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_action", "valid_actions": []string{"adjust", "define", "remove"}})
+		return
+	}
+
+	if idx < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "faction_not_found", "message": fmt.Sprintf("No faction called %s; add it with action define first", req.Faction)})
+		return
+	}
+	if req.Change == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "change_required", "message": "change is how much renown to add (negative to take away)"})
+		return
+	}
+	affected := partyIDs
+	if req.CharacterID != 0 {
+		if _, ok := party[req.CharacterID]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_in_campaign"})
+			return
+		}
+		affected = []int{req.CharacterID}
+	}
+
+	f := &factions[idx]
+	before := map[int]int{}
+	for _, id := range affected {
+		before[id] = f.CharacterRenown(id)
+	}
+	who := "the party"
+	if req.CharacterID != 0 {
+		if f.Renown == nil {
+			f.Renown = map[int]int{}
+		}
+		f.Renown[req.CharacterID] += req.Change
+		who = party[req.CharacterID]
+	} else {
+		f.PartyRenown += req.Change
+	}
+	saveCampaignFactions(campaignID, factions)
+
+	unlocked := []map[string]interface{}{}
+	announcements := []string{}
+	for _, id := range affected {
+		for _, rank := range f.RanksCrossed(before[id], f.CharacterRenown(id)) {
+			unlocked = append(unlocked, map[string]interface{}{"character": party[id], "character_id": id, "rank": rank})
+			note := fmt.Sprintf("%s reaches %s with the %s", party[id], rank.Title, f.Name)
+			if rank.Perk != "" {
+				note += ": " + rank.Perk
+			}
+			announcements = append(announcements, note)
+		}
+	}
+
+	if !f.GMOnly {
+		verb, amount := "gains", req.Change
+		if amount < 0 {
+			verb, amount = "loses", -amount
+		}
+		description := fmt.Sprintf("%s %s %d renown with the %s", strings.ToUpper(who[:1])+who[1:], verb, amount, f.Name)
+		result := req.Reason
+		if len(announcements) > 0 {
+			result = strings.TrimPrefix(result+". "+strings.Join(announcements, ". "), ". ")
+		}
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result)
+			VALUES ($1, 'faction_renown', $2, $3)
+		`, campaignID, description, result)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"faction":   f.Name,
+		"change":    req.Change,
+		"applied":   who,
+		"unlocked":  unlocked,
+		"standings": standings(*f),
+	})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Delayed initiative (v1.0.96): a character whose turn it is can hold off and act later in
+// the round. The new initiative count sticks for the rest of the combat.
+
+// handleCombatDelay godoc
+// @Summary Delay your turn
+// @Description On your turn, before using your action or bonus action, move yourself later in the initiative order for the rest of combat. Give an initiative count below your own, or after_id/after_name to act right after that combatant (on its count, behind everyone already there). The next combatant's turn starts straight away. Standard initiative only. v1.0.96.
+// @Tags Combat
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{initiative=integer,after_id=integer,after_name=string} true "Where to delay to"
+// @Success 200 {object} map[string]interface{} "Turn delayed"
+// @Failure 400 {object} map[string]interface{} "Invalid delay"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not your turn"
+// @Router /combat/delay [post]
+func handleCombatDelay(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		Initiative *int   `json:"initiative"`
+		AfterID    int    `json:"after_id"`
+		AfterName  string `json:"after_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Initiative == nil && req.AfterID == 0 && req.AfterName == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_request",
+			"message": "initiative, after_id or after_name required",
+		})
+		return
+	}
+
+	var charID, campaignID int
+	var actionUsed, bonusActionUsed bool
+	err = rdb.QueryRow(`
+		SELECT c.id, c.lobby_id, COALESCE(c.action_used, false), COALESCE(c.bonus_action_used, false)
+		FROM characters c JOIN lobbies l ON c.lobby_id = l.id
+		WHERE c.agent_id = $1 AND l.status = 'active'
+	`, agentID).Scan(&charID, &campaignID, &actionUsed, &bonusActionUsed)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_active_game"})
+		return
+	}
+
+	release, _, ok := acquireActionLock(w, r, charID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var round, turnIndex int
+	var turnOrderJSON []byte
+	var active bool
+	var initiativeMode string
+	err = rdb.QueryRow(`
+		SELECT round_number, current_turn_index, turn_order, active, COALESCE(initiative_mode, 'standard')
+		FROM combat_state WHERE lobby_id = $1
+	`, campaignID).Scan(&round, &turnIndex, &turnOrderJSON, &active, &initiativeMode)
+	if err != nil || !active {
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_active_combat"})
+		return
+	}
+	if initiativeMode != game.InitiativeModeStandard {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "not_standard_initiative",
+			"message": fmt.Sprintf("This combat uses %s initiative, where there is no order to delay in", initiativeMode),
+		})
+		return
+	}
+
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	if turnIndex >= len(entries) || turnOrderInt(entries[turnIndex], "id") != charID {
+		current := ""
+		if turnIndex < len(entries) {
+			current, _ = entries[turnIndex]["name"].(string)
+		}
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "not_your_turn",
+			"message": fmt.Sprintf("It's %s's turn. You can only delay on your own turn.", current),
+		})
+		return
+	}
+	if actionUsed || bonusActionUsed {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "already_acted",
+			"message": "You can only delay before you use your action or bonus action",
+		})
+		return
+	}
+
+	newInit := 0
+	if req.Initiative != nil {
+		newInit = *req.Initiative
+	} else {
+		found := false
+		for i, e := range entries {
+			name, _ := e["name"].(string)
+			if i != turnIndex && ((req.AfterID != 0 && turnOrderInt(e, "id") == req.AfterID) || (req.AfterID == 0 && strings.EqualFold(name, req.AfterName))) {
+				newInit, found = turnOrderInt(e, "initiative"), true
+				break
+			}
+		}
+		if !found {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "combatant_not_found", "message": "No such combatant in this fight"})
+			return
+		}
+	}
+
+	slots := make([]game.InitiativeSlot, len(entries))
+	for i, e := range entries {
+		delayed, _ := e["delayed"].(bool)
+		slots[i] = game.InitiativeSlot{
+			ID:         turnOrderInt(e, "id"),
+			Initiative: turnOrderInt(e, "initiative"),
+			DexScore:   turnOrderInt(e, "dex_score"),
+			Delayed:    delayed,
+		}
+	}
+	oldInit := slots[turnIndex].Initiative
+	if _, pos, ok := game.DelayTurn(slots, turnIndex, newInit); ok {
+		moved := entries[turnIndex]
+		moved["initiative"] = newInit
+		moved["delayed"] = true
+		rest := append(append([]map[string]interface{}{}, entries[:turnIndex]...), entries[turnIndex+1:]...)
+		entries = append(rest[:pos], append([]map[string]interface{}{moved}, rest[pos:]...)...)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      "invalid_delay",
+			"message":    fmt.Sprintf("Delaying to initiative %d doesn't let anyone act before you. Pick a count below %d that someone still to act this round is above.", newInit, oldInit),
+			"initiative": oldInit,
+		})
+		return
+	}
+
+	// The turn passes to whoever now stands where the delayer was (skipping reinforcements
+	// waiting for their first round). The delayer hasn't had a turn, so nothing of theirs ends.
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = turnOrderInt(e, "id")
+	}
+	nextIndex, _, _, _ := nextCombatTurn(campaignID, initiativeMode, ids, turnIndex-1, nil, 0, round)
+	next := entries[nextIndex]
+	nextID := ids[nextIndex]
+	nextName, _ := next["name"].(string)
+	isMonster, _ := next["is_monster"].(bool)
+	if isMonster && turnOrderInt(next, "legendary_actions_total") > 0 {
+		next["legendary_actions_used"] = 0
+	}
+
+	updatedTurnOrder, _ := json.Marshal(entries)
+	rdb.Exec("UPDATE combat_state SET current_turn_index = $1, turn_order = $2, turn_started_at = NOW() WHERE lobby_id = $3",
+		nextIndex, updatedTurnOrder, campaignID)
+	if !isMonster {
+		resetActionEconomy(nextID, game.EconomyTurnStart)
+	}
+	_, repeatSaves := advanceConditionTimers(campaignID, 0, nextID, round, false)
+
+	var name string
+	rdb.QueryRow("SELECT name FROM characters WHERE id = $1", charID).Scan(&name)
+	rdb.Exec("UPDATE characters SET current_initiative = $1 WHERE id = $2", newInit, charID)
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+		VALUES ($1, $2, 'delay', $3, $4)
+	`, campaignID, charID, fmt.Sprintf("%s delays their turn", name),
+		fmt.Sprintf("Now acts on initiative %d (was %d); %s goes now", newInit, oldInit, nextName))
+
+	order := []map[string]interface{}{}
+	for _, e := range entries {
+		order = append(order, map[string]interface{}{"id": e["id"], "name": e["name"], "initiative": e["initiative"]})
+	}
+	response := map[string]interface{}{
+		"success":        true,
+		"delayed":        name,
+		"old_initiative": oldInit,
+		"new_initiative": newInit,
+		"current_turn":   nextName,
+		"turn_index":     nextIndex,
+		"turn_order":     order,
+		"message":        fmt.Sprintf("%s waits. %s acts now; %s goes on initiative %d from here on.", name, nextName, name, newInit),
+	}
+	if len(repeatSaves) > 0 {
+		response["repeat_saves"] = repeatSaves
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Party loot (v1.0.92): characters hand items and coins to each other, and each campaign
+// has a shared pool the GM fills with treasure and players claim from. Every move is posted
+// to the action feed so the party can see where the loot went.
+
+// currencyAbbrevs lists the coin types in value order, as the pool reports them.
+var currencyAbbrevs = []string{"cp", "sp", "ep", "gp", "pp"}
+
+// characterInventory returns a character's inventory entries.
+func characterInventory(charID int) []map[string]interface{} {
+	var inventoryJSON []byte
+	db.QueryRow("SELECT COALESCE(inventory, '[]') FROM characters WHERE id = $1", charID).Scan(&inventoryJSON)
+	inventory := []map[string]interface{}{}
+	json.Unmarshal(inventoryJSON, &inventory)
+	return inventory
+}
+
+func saveCharacterInventory(charID int, inventory []map[string]interface{}) {
+	inventoryJSON, _ := json.Marshal(inventory)
+	db.Exec("UPDATE characters SET inventory = $1 WHERE id = $2", inventoryJSON, charID)
+}
+
+// itemEquipped reports whether a character is wearing or wielding the named item.
+func itemEquipped(charID int, name string) bool {
+	var armor, mainHand, offHand string
+	db.QueryRow(`
+		SELECT COALESCE(equipped_armor, ''), COALESCE(equipped_main_hand, ''), COALESCE(equipped_off_hand, '')
+		FROM characters WHERE id = $1
+	`, charID).Scan(&armor, &mainHand, &offHand)
+	slug := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "-"))
+	for _, equipped := range []string{armor, mainHand, offHand} {
+		if equipped != "" && (strings.EqualFold(equipped, name) || strings.EqualFold(equipped, slug)) {
+			return true
+		}
+	}
+	return false
+}
+
+// spendCurrency takes amount coins of one type from a character; false if they don't have them.
+func spendCurrency(charID int, column string, amount int) bool {
+	res, err := db.Exec(fmt.Sprintf("UPDATE characters SET %[1]s = COALESCE(%[1]s, 0) - $1 WHERE id = $2 AND COALESCE(%[1]s, 0) >= $1", column),
+		amount, charID)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n == 1
+}
+
+func addCurrency(charID int, column string, amount int) {
+	db.Exec(fmt.Sprintf("UPDATE characters SET %[1]s = COALESCE(%[1]s, 0) + $1 WHERE id = $2", column), amount, charID)
+}
+
+// depositLoot adds to a campaign's pool, stacking on an entry of the same kind and name.
+// kind is "item" (item holds the inventory entry) or "currency" (name is cp, sp, ep, gp or pp).
+func depositLoot(campaignID int, kind, name string, quantity int, item map[string]interface{}, depositedBy string) {
+	var id int
+	db.QueryRow(`
+		UPDATE party_loot SET quantity = quantity + $1
+		WHERE lobby_id = $2 AND kind = $3 AND LOWER(name) = LOWER($4) RETURNING id
+	`, quantity, campaignID, kind, name).Scan(&id)
+	if id != 0 {
+		return
+	}
+	if item == nil {
+		item = map[string]interface{}{}
+	}
+	itemJSON, _ := json.Marshal(item)
+	db.Exec(`
+		INSERT INTO party_loot (lobby_id, kind, name, quantity, item, deposited_by) VALUES ($1, $2, $3, $4, $5, $6)
+	`, campaignID, kind, name, quantity, itemJSON, depositedBy)
+}
+
+// takeLoot removes quantity from a pool entry if it holds that many, deleting emptied
+// entries. Returns the entry's kind, name and stored item.
+func takeLoot(campaignID, lootID int, quantity int) (kind, name string, item map[string]interface{}, ok bool) {
+	var itemJSON []byte
+	err := db.QueryRow(`
+		UPDATE party_loot SET quantity = quantity - $1
+		WHERE id = $2 AND lobby_id = $3 AND quantity >= $1 RETURNING kind, name, item
+	`, quantity, lootID, campaignID).Scan(&kind, &name, &itemJSON)
+	if err != nil {
+		return "", "", nil, false
+	}
+	db.Exec("DELETE FROM party_loot WHERE id = $1 AND quantity <= 0", lootID)
+	item = map[string]interface{}{}
+	json.Unmarshal(itemJSON, &item)
+	return kind, name, item, true
+}
+
+// partyLootPool describes a campaign's pool: items with their loot_id and the coins.
+func partyLootPool(campaignID int) map[string]interface{} {
+	items := []map[string]interface{}{}
+	coins := map[string]int{}
+	rows, err := db.Query(`
+		SELECT id, kind, name, quantity, COALESCE(item, '{}'), COALESCE(deposited_by, ''), created_at
+		FROM party_loot WHERE lobby_id = $1 AND quantity > 0 ORDER BY created_at, id
+	`, campaignID)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var id, quantity int
+			var kind, name, depositedBy string
+			var itemJSON []byte
+			var createdAt time.Time
+			if rows.Scan(&id, &kind, &name, &quantity, &itemJSON, &depositedBy, &createdAt) != nil {
+				continue
+			}
+			if kind == "currency" {
+				coins[name] += quantity
+				continue
+			}
+			item := map[string]interface{}{}
+			json.Unmarshal(itemJSON, &item)
+			items = append(items, map[string]interface{}{
+				"loot_id":      id,
+				"name":         name,
+				"quantity":     quantity,
+				"item":         item,
+				"deposited_by": depositedBy,
+				"deposited_at": createdAt.Format(time.RFC3339),
+			})
+		}
+	}
+	currency := map[string]int{}
+	for _, c := range currencyAbbrevs {
+		currency[c] = coins[c]
+	}
+	return map[string]interface{}{
+		"campaign_id": campaignID,
+		"items":       items,
+		"currency":    currency,
+		"total_in_gp": float64(coins["cp"])/100 + float64(coins["sp"])/10 + float64(coins["ep"])/2 + float64(coins["gp"]) + float64(coins["pp"])*10,
+	}
+}
+
+// campaignMemberOrGM reports whether an agent runs a campaign or has a character in it.
+func campaignMemberOrGM(campaignID, agentID int) bool {
+	var dmID, members int
+	db.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", campaignID).Scan(&dmID)
+	db.QueryRow("SELECT COUNT(*) FROM characters WHERE lobby_id = $1 AND agent_id = $2", campaignID, agentID).Scan(&members)
+	return dmID == agentID || members > 0
+}
+
+// agentCharacter resolves the character an agent acts with: charID if they own it, or
+// their character in an active campaign.
+func agentCharacter(agentID, charID int) (id, campaignID int, name string, ok bool) {
+	var err error
+	if charID != 0 {
+		err = db.QueryRow("SELECT id, COALESCE(lobby_id, 0), name FROM characters WHERE id = $1 AND agent_id = $2",
+			charID, agentID).Scan(&id, &campaignID, &name)
+	} else {
+		err = db.QueryRow(`
+			SELECT c.id, c.lobby_id, c.name FROM characters c JOIN lobbies l ON c.lobby_id = l.id
+			WHERE c.agent_id = $1 AND l.status = 'active' LIMIT 1
+		`, agentID).Scan(&id, &campaignID, &name)
+	}
+	return id, campaignID, name, err == nil
+}
+
+// handleCharacterGive godoc
+// @Summary Give an item or coins to another character
+// @Description Hands an item (item_name, quantity default 1) or coins (currency cp/sp/ep/gp/pp, amount) from your character to another character in the same campaign, or into the party's shared loot pool with to_pool: true. character_id picks the giver when you have several characters (default: your character in an active campaign). Items you're wearing or wielding must be unequipped first, unless you keep at least one. The transfer is posted to the campaign feed. v1.0.92.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{character_id=integer,to_character_id=integer,to_pool=boolean,item_name=string,quantity=integer,currency=string,amount=integer} true "What to give and to whom"
+// @Success 200 {object} map[string]interface{} "Transfer made"
+// @Failure 400 {object} map[string]interface{} "Invalid transfer"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /characters/give [post]
+func handleCharacterGive(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CharacterID   int    `json:"character_id"`
+		ToCharacterID int    `json:"to_character_id"`
+		ToPool        bool   `json:"to_pool"`
+		ItemName      string `json:"item_name"`
+		Quantity      int    `json:"quantity"`
+		Currency      string `json:"currency"`
+		Amount        int    `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+	fail := func(code, message string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": code, "message": message})
+	}
+	if (req.ItemName == "") == (req.Amount == 0) {
+		fail("invalid_request", "Give either item_name (with quantity) or currency and amount")
+		return
+	}
+	if req.ToPool == (req.ToCharacterID != 0) {
+		fail("invalid_request", "Give to to_character_id or to_pool, not both")
+		return
+	}
+
+	giverID, campaignID, giverName, ok := agentCharacter(agentID, req.CharacterID)
+	if !ok || campaignID == 0 {
+		fail("character_not_found", "You have no character in a campaign to give from")
+		return
+	}
+	recipient := "the party loot pool"
+	if req.ToCharacterID != 0 {
+		var recipientCampaign int
+		err := rdb.QueryRow("SELECT COALESCE(lobby_id, 0), name FROM characters WHERE id = $1", req.ToCharacterID).
+			Scan(&recipientCampaign, &recipient)
+		if err != nil || recipientCampaign != campaignID {
+			fail("recipient_not_found", "The recipient must be a character in the same campaign")
+			return
+		}
+		if req.ToCharacterID == giverID {
+			fail("invalid_request", "You can't give something to yourself")
+			return
+		}
+	}
+
+	release, _, ok := acquireActionLock(w, r, giverID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var given string
+	if req.ItemName != "" {
+		if req.Quantity == 0 {
+			req.Quantity = 1
+		}
+		inventory := characterInventory(giverID)
+		rest, taken, ok := game.TakeInventoryItem(inventory, req.ItemName, req.Quantity)
+		if !ok {
+			fail("item_not_found", fmt.Sprintf("%s doesn't have %d %s", giverName, req.Quantity, req.ItemName))
+			return
+		}
+		if game.FindInventoryItem(rest, req.ItemName) < 0 && itemEquipped(giverID, req.ItemName) {
+			fail("item_equipped", fmt.Sprintf("%s is equipped; unequip it first with POST /api/characters/{id}/equip", req.ItemName))
+			return
+		}
+		saveCharacterInventory(giverID, rest)
+		name, _ := taken["name"].(string)
+		if req.ToPool {
+			depositLoot(campaignID, "item", name, req.Quantity, taken, giverName)
+		} else {
+			saveCharacterInventory(req.ToCharacterID, game.AddInventoryItem(characterInventory(req.ToCharacterID), taken))
+		}
+		given = fmt.Sprintf("%s x%d", name, req.Quantity)
+	} else {
+		column, abbrev, valid := getCurrencyColumn(req.Currency)
+		if !valid || req.Amount < 0 {
+			fail("invalid_currency", "currency must be cp, sp, ep, gp or pp, with a positive amount")
+			return
+		}
+		if !spendCurrency(giverID, column, req.Amount) {
+			fail("insufficient_funds", fmt.Sprintf("%s doesn't have %d %s", giverName, req.Amount, abbrev))
+			return
+		}
+		if req.ToPool {
+			depositLoot(campaignID, "currency", abbrev, req.Amount, nil, giverName)
+		} else {
+			addCurrency(req.ToCharacterID, column, req.Amount)
+		}
+		given = fmt.Sprintf("%d %s", req.Amount, abbrev)
+	}
+
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'give', $3, $4)
+	`, campaignID, giverID, fmt.Sprintf("%s gives %s to %s", giverName, given, recipient), given)
+
+	response := map[string]interface{}{
+		"success": true,
+		"from":    giverName,
+		"to":      recipient,
+		"given":   given,
+	}
+	if req.ToPool {
+		response["pool"] = partyLootPool(campaignID)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCampaignLoot godoc
+// @Summary Party loot pool
+// @Description GET lists the campaign's shared loot pool: items with their loot_id, and coins by type. The GM and the campaign's players can look. POST /api/campaigns/{id}/loot/claim takes from it. v1.0.92.
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Loot pool"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not in this campaign"
+// @Router /campaigns/{id}/loot [get]
+func handleCampaignLoot(w http.ResponseWriter, r *http.Request, campaignID int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	if !campaignMemberOrGM(campaignID, agentID) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_in_campaign", "message": "Only the campaign's GM and players can see its loot"})
+		return
+	}
+	json.NewEncoder(w).Encode(partyLootPool(campaignID))
+}
+
+// handleCampaignLootClaim godoc
+// @Summary Claim from the party loot pool
+// @Description Takes an item (loot_id, quantity default 1) or coins (currency, amount) from the campaign's shared loot pool into your character's inventory or purse. character_id picks your character when you have several in the campaign. The claim is posted to the campaign feed. v1.0.92.
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{character_id=integer,loot_id=integer,quantity=integer,currency=string,amount=integer} true "What to claim"
+// @Success 200 {object} map[string]interface{} "Claimed"
+// @Failure 400 {object} map[string]interface{} "Not in the pool"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not in this campaign"
+// @Router /campaigns/{id}/loot/claim [post]
+func handleCampaignLootClaim(w http.ResponseWriter, r *http.Request, campaignID int) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CharacterID int    `json:"character_id"`
+		LootID      int    `json:"loot_id"`
+		Quantity    int    `json:"quantity"`
+		Currency    string `json:"currency"`
+		Amount      int    `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.LootID == 0) == (req.Amount <= 0) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_request",
+			"message": "Claim either loot_id (with quantity) or currency and a positive amount",
+		})
+		return
+	}
+
+	var charID int
+	var charName string
+	err = rdb.QueryRow(`
+		SELECT id, name FROM characters WHERE lobby_id = $1 AND agent_id = $2 AND ($3 = 0 OR id = $3) ORDER BY id LIMIT 1
+	`, campaignID, agentID, req.CharacterID).Scan(&charID, &charName)
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_in_campaign", "message": "You have no character in this campaign"})
+		return
+	}
+
+	var claimed string
+	if req.LootID != 0 {
+		if req.Quantity == 0 {
+			req.Quantity = 1
+		}
+		kind, name, item, ok := takeLoot(campaignID, req.LootID, req.Quantity)
+		if !ok || kind != "item" {
+			if ok {
+				depositLoot(campaignID, kind, name, req.Quantity, item, "")
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "not_in_pool",
+				"message": fmt.Sprintf("The pool doesn't hold %d of loot %d", req.Quantity, req.LootID),
+				"pool":    partyLootPool(campaignID),
+			})
+			return
+		}
+		release, _, ok := acquireActionLock(w, r, charID)
+		if !ok {
+			depositLoot(campaignID, kind, name, req.Quantity, item, "")
+			return
+		}
+		item["name"] = name
+		item["quantity"] = req.Quantity
+		saveCharacterInventory(charID, game.AddInventoryItem(characterInventory(charID), item))
+		release()
+		claimed = fmt.Sprintf("%s x%d", name, req.Quantity)
+	} else {
+		column, abbrev, valid := getCurrencyColumn(req.Currency)
+		var lootID int
+		rdb.QueryRow("SELECT id FROM party_loot WHERE lobby_id = $1 AND kind = 'currency' AND name = $2", campaignID, abbrev).Scan(&lootID)
+		if _, _, _, ok := takeLoot(campaignID, lootID, req.Amount); !valid || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "not_in_pool",
+				"message": fmt.Sprintf("The pool doesn't hold %d %s", req.Amount, abbrev),
+				"pool":    partyLootPool(campaignID),
+			})
+			return
+		}
+		addCurrency(charID, column, req.Amount)
+		claimed = fmt.Sprintf("%d %s", req.Amount, abbrev)
+	}
+
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'loot_claimed', $3, $4)
+	`, campaignID, charID, fmt.Sprintf("%s claims %s from the party loot", charName, claimed), claimed)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"character": charName,
+		"claimed":   claimed,
+		"pool":      partyLootPool(campaignID),
+	})
+}
+
+// handleGMLoot godoc
+// @Summary Deposit treasure into the party loot pool (GM only)
+// @Description Adds treasure to a campaign's shared loot pool: items [{item_name, quantity, custom}] (known consumables get their details, custom sets fields like for /api/gm/give-item) and currency {"gp": 120, "sp": 40}. split: true then shares every coin in the pool evenly among the living characters; what doesn't divide stays in the pool. Players take items with POST /api/campaigns/{id}/loot/claim. Deposits and splits are posted to the campaign feed. v1.0.92.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{campaign_id=integer,items=[]object,currency=object,split=boolean,note=string} true "Treasure to deposit"
+// @Success 200 {object} map[string]interface{} "Pool after the deposit"
+// @Failure 400 {object} map[string]interface{} "Invalid request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/loot [post]
+func handleGMLoot(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CampaignID int `json:"campaign_id"`
+		Items      []struct {
+			ItemName string                 `json:"item_name"`
+			Quantity int                    `json:"quantity"`
+			Custom   map[string]interface{} `json:"custom"`
+		} `json:"items"`
+		Currency map[string]int `json:"currency"`
+		Split    bool           `json:"split"`
+		Note     string         `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CampaignID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_request",
+			"message": "campaign_id required, with items, currency and/or split",
+		})
+		return
+	}
+
+	var dmID int
+	rdb.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", req.CampaignID).Scan(&dmID)
+	if dmID != agentID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "not_gm",
+			"message": "Only the GM can deposit treasure",
+		})
+		return
+	}
+
+	// Validate everything before the pool changes
+	for _, item := range req.Items {
+		if strings.TrimSpace(item.ItemName) == "" || item.Quantity < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_item", "message": "Each item needs an item_name and a positive quantity"})
+			return
+		}
+	}
+	for currency, amount := range req.Currency {
+		if _, _, valid := getCurrencyColumn(currency); !valid || amount < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_currency", "message": "currency keys are cp, sp, ep, gp or pp with positive amounts"})
+			return
+		}
+	}
+
+	deposited := []string{}
+	for _, item := range req.Items {
+		quantity := max(item.Quantity, 1)
+		entry := newInventoryItem(item.ItemName, quantity, item.Custom)
+		name, _ := entry["name"].(string)
+		depositLoot(req.CampaignID, "item", name, quantity, entry, "GM")
+		deposited = append(deposited, fmt.Sprintf("%s x%d", name, quantity))
+	}
+	for _, currency := range currencyAbbrevs {
+		for key, amount := range req.Currency {
+			if _, abbrev, _ := getCurrencyColumn(key); abbrev == currency && amount > 0 {
+				depositLoot(req.CampaignID, "currency", abbrev, amount, nil, "GM")
+				deposited = append(deposited, fmt.Sprintf("%d %s", amount, abbrev))
+			}
+		}
+	}
+	response := map[string]interface{}{"success": true}
+	if len(deposited) > 0 {
+		description := "The GM adds treasure to the party loot"
+		if req.Note != "" {
+			description += ": " + req.Note
+		}
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result) VALUES ($1, 'loot_deposited', $2, $3)
+		`, req.CampaignID, description, strings.Join(deposited, ", "))
+		response["deposited"] = deposited
+	}
+
+	if req.Split {
+		shares := splitPartyCoins(req.CampaignID)
+		if shares == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_party", "message": "No living characters to split the coins between"})
+			return
+		}
+		response["split"] = shares
+	}
+
+	response["pool"] = partyLootPool(req.CampaignID)
+	json.NewEncoder(w).Encode(response)
+}
+
+// splitPartyCoins shares the pool's coins evenly among the campaign's living characters,
+// leaving what doesn't divide in the pool. Returns each character's share, or nil without
+// a party.
+func splitPartyCoins(campaignID int) map[string]interface{} {
+	type member struct {
+		id   int
+		name string
+	}
+	party := []member{}
+	rows, err := db.Query("SELECT id, name FROM characters WHERE lobby_id = $1 AND NOT COALESCE(is_dead, false) ORDER BY id", campaignID)
+	if err == nil {
+		for rows.Next() {
+			var m member
+			rows.Scan(&m.id, &m.name)
+			party = append(party, m)
+		}
+		rows.Close()
+	}
+	if len(party) == 0 {
+		return nil
+	}
+
+	pool, _ := partyLootPool(campaignID)["currency"].(map[string]int)
+	share := map[string]int{}
+	lines := []string{}
+	for _, abbrev := range currencyAbbrevs {
+		each, _ := game.SplitCurrency(pool[abbrev], len(party))
+		if each == 0 {
+			continue
+		}
+		var lootID int
+		db.QueryRow("SELECT id FROM party_loot WHERE lobby_id = $1 AND kind = 'currency' AND name = $2", campaignID, abbrev).Scan(&lootID)
+		if _, _, _, ok := takeLoot(campaignID, lootID, each*len(party)); !ok {
+			continue
+		}
+		column, _, _ := getCurrencyColumn(abbrev)
+		for _, m := range party {
+			addCurrency(m.id, column, each)
+		}
+		share[abbrev] = each
+		lines = append(lines, fmt.Sprintf("%d %s", each, abbrev))
+	}
+	names := []string{}
+	for _, m := range party {
+		names = append(names, m.name)
+	}
+	if len(lines) > 0 {
+		db.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result) VALUES ($1, 'loot_split', $2, $3)
+		`, campaignID, fmt.Sprintf("The party loot's coins are split %d ways", len(party)),
+			fmt.Sprintf("%s each to %s", strings.Join(lines, ", "), strings.Join(names, ", ")))
+	}
+	return map[string]interface{}{"each": share, "characters": names}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Owned mounts (v1.0.98): a character's horses, ponies and war elephants are creatures with
+// their own hit points. Riding one sets characters.mount_id; a controlled mount's speed
+// becomes the rider's, and a mount that is moved, knocked prone or killed can throw its rider.
+
+// mount is a row of the mounts table.
+type mount struct {
+	ID          int    `json:"id"`
+	CharacterID int    `json:"character_id"`
+	Name        string `json:"name"`
+	MonsterSlug string `json:"monster_slug"`
+	Size        string `json:"size"`
+	Speed       int    `json:"speed"`
+	AC          int    `json:"ac"`
+	HP          int    `json:"hp"`
+	MaxHP       int    `json:"max_hp"`
+	Int         int    `json:"int"`
+}
+
+const mountColumns = `id, COALESCE(character_id, 0), name, COALESCE(monster_slug, ''), COALESCE(size, 'Large'),
+	COALESCE(speed, 60), COALESCE(ac, 10), hp, max_hp, COALESCE(intl, 2)`
+
+func scanMount(row interface{ Scan(...interface{}) error }) (mount, error) {
+	var m mount
+	err := row.Scan(&m.ID, &m.CharacterID, &m.Name, &m.MonsterSlug, &m.Size, &m.Speed, &m.AC, &m.HP, &m.MaxHP, &m.Int)
+	return m, err
+}
+
+func loadMount(mountID int) (mount, bool) {
+	m, err := scanMount(db.QueryRow("SELECT "+mountColumns+" FROM mounts WHERE id = $1", mountID))
+	return m, err == nil
+}
+
+func characterMounts(charID int) []mount {
+	mounts := []mount{}
+	rows, err := db.Query("SELECT "+mountColumns+" FROM mounts WHERE character_id = $1 ORDER BY id", charID)
+	if err != nil {
+		return mounts
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if m, err := scanMount(rows); err == nil {
+			mounts = append(mounts, m)
+		}
+	}
+	return mounts
+}
+
+// handleCharacterMounts godoc
+// @Summary List or acquire mounts
+// @Description GET lists a character's mounts with their hit points (owner or GM). POST adds one from a monster slug (riding-horse, warhorse, pony, mule, camel, mastiff, draft-horse, elephant): buy: true pays the PHB price (p157) from the character's coins outside combat; otherwise only the GM can hand out a mount. name optionally names the animal. Ride it with POST /api/characters/mount and mount_id. v1.0.98.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param character_id query int false "Character (GET)"
+// @Param request body object{character_id=integer,creature=string,name=string,buy=boolean} false "Mount to add (POST)"
+// @Success 200 {object} map[string]interface{} "Mounts"
+// @Failure 400 {object} map[string]interface{} "Unknown creature or not enough coin"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not your character"
+// @Router /characters/mounts [get]
+// @Router /characters/mounts [post]
+func handleCharacterMounts(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CharacterID int    `json:"character_id"`
+		Creature    string `json:"creature"`
+		Name        string `json:"name"`
+		Buy         bool   `json:"buy"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		req.CharacterID, _ = strconv.Atoi(r.URL.Query().Get("character_id"))
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Creature == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "invalid_request",
+				"message": "creature required, e.g. riding-horse or warhorse",
+			})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var charName string
+	var ownerID, campaignID, dmID int
+	var ridingID sql.NullInt64
+	err = rdb.QueryRow(`
+		SELECT c.name, c.agent_id, COALESCE(c.lobby_id, 0), COALESCE(l.dm_id, 0), c.mount_id
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id WHERE c.id = $1
+	`, req.CharacterID).Scan(&charName, &ownerID, &campaignID, &dmID, &ridingID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_found"})
+		return
+	}
+	isGM := dmID == agentID && dmID != 0
+	if ownerID != agentID && !isGM {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_character"})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"character": charName,
+			"mounts":    characterMounts(req.CharacterID),
+			"riding":    ridingID.Int64,
+			"for_sale":  mountPriceList(),
+		})
+		return
+	}
+
+	slug := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(req.Creature), " ", "-"))
+	m := mount{CharacterID: req.CharacterID}
+	err = rdb.QueryRow(`
+		SELECT slug, name, COALESCE(size, 'Large'), COALESCE(speed, 60), COALESCE(ac, 10), COALESCE(hp, 10), COALESCE(intl, 2)
+		FROM monsters WHERE slug = $1 OR LOWER(name) = LOWER($2)
+	`, slug, req.Creature).Scan(&m.MonsterSlug, &m.Name, &m.Size, &m.Speed, &m.AC, &m.MaxHP, &m.Int)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":    "unknown_creature",
+			"message":  fmt.Sprintf("No creature '%s' in the bestiary", req.Creature),
+			"for_sale": mountPriceList(),
+		})
+		return
+	}
+	m.HP = m.MaxHP
+	kind := m.Name
+	if req.Name != "" {
+		m.Name = req.Name
+	}
+
+	response := map[string]interface{}{"success": true}
+	if req.Buy {
+		price, forSale := game.MountPricesCP[m.MonsterSlug]
+		if !forSale {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":    "not_for_sale",
+				"message":  fmt.Sprintf("%s isn't sold as a mount. Ask the GM.", kind),
+				"for_sale": mountPriceList(),
+			})
+			return
+		}
+		var inCombat bool
+		rdb.QueryRow("SELECT COALESCE(active, false) FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&inCombat)
+		if inCombat {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "in_combat", "message": "No horse trading mid-fight"})
+			return
+		}
+		purse := characterPurse(req.CharacterID)
+		paid, ok := purse.Pay(price)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "insufficient_funds",
+				"message": fmt.Sprintf("A %s costs %s; %s has %s", strings.ToLower(kind), game.FormatCP(price), charName, game.FormatCP(purse.TotalCP())),
+			})
+			return
+		}
+		saveCharacterPurse(req.CharacterID, paid)
+		response["price"] = game.FormatCP(price)
+		response["purse"] = paid
+	} else if !isGM {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "not_gm",
+			"message": "Buy a mount with buy: true, or ask the GM to give you one",
+		})
+		return
+	}
+
+	err = rdb.QueryRow(`
+		INSERT INTO mounts (character_id, name, monster_slug, size, speed, ac, hp, max_hp, intl)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id
+	`, m.CharacterID, m.Name, m.MonsterSlug, m.Size, m.Speed, m.AC, m.HP, m.MaxHP, m.Int).Scan(&m.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error", "detail": err.Error()})
+		return
+	}
+
+	how := "is given"
+	if req.Buy {
+		how = "buys"
+	}
+	if campaignID != 0 {
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+			VALUES ($1, $2, 'mount_acquired', $3, $4)
+		`, campaignID, req.CharacterID, fmt.Sprintf("%s %s a %s", charName, how, strings.ToLower(kind)),
+			fmt.Sprintf("%s: speed %d ft, AC %d, %d HP", m.Name, m.Speed, m.AC, m.MaxHP))
+	}
+	response["mount"] = m
+	response["message"] = fmt.Sprintf("%s %s %s. Ride with POST /api/characters/mount {\"character_id\":%d,\"mount_id\":%d}", charName, how, m.Name, req.CharacterID, m.ID)
+	json.NewEncoder(w).Encode(response)
+}
+
+// mountPriceList is the PHB mount table for responses.
+func mountPriceList() map[string]string {
+	prices := map[string]string{}
+	for slug, cp := range game.MountPricesCP {
+		prices[slug] = game.FormatCP(cp)
+	}
+	return prices
+}
+
+// handleGMMountDamage godoc
+// @Summary Damage, knock down or shove a mount (GM only)
+// @Description Applies damage to an owned mount, and what happens to its rider (PHB p198): a mount moved against its will (moved_against_will) makes its rider pass a DC 10 Dexterity save or fall off prone; a mount knocked prone (knocked_prone) or dropped to 0 HP throws its rider, who lands on their feet by spending their reaction if they still have it, or prone. Negative damage heals the mount. v1.0.98.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{mount_id=integer,damage=integer,knocked_prone=boolean,moved_against_will=boolean} true "What happens to the mount"
+// @Success 200 {object} map[string]interface{} "Mount and rider"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Failure 404 {object} map[string]interface{} "Mount not found"
+// @Router /gm/mount-damage [post]
+func handleGMMountDamage(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		MountID          int  `json:"mount_id"`
+		Damage           int  `json:"damage"`
+		KnockedProne     bool `json:"knocked_prone"`
+		MovedAgainstWill bool `json:"moved_against_will"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MountID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_request", "message": "mount_id required"})
+		return
+	}
+
+	m, ok := loadMount(req.MountID)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "mount_not_found"})
+		return
+	}
+	var campaignID, dmID int
+	rdb.QueryRow(`
+		SELECT COALESCE(c.lobby_id, 0), COALESCE(l.dm_id, 0)
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id WHERE c.id = $1
+	`, m.CharacterID).Scan(&campaignID, &dmID)
+	if dmID != agentID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the owner's GM can harm their mount"})
+		return
+	}
+
+	wasUp := m.HP > 0
+	m.HP = min(max(m.HP-req.Damage, 0), m.MaxHP)
+	rdb.Exec("UPDATE mounts SET hp = $1 WHERE id = $2", m.HP, m.ID)
+
+	response := map[string]interface{}{
+		"success": true,
+		"mount":   m,
+	}
+	summary := fmt.Sprintf("%s: %d/%d HP", m.Name, m.HP, m.MaxHP)
+	if req.Damage > 0 {
+		summary = fmt.Sprintf("%s takes %d damage (%d/%d HP)", m.Name, req.Damage, m.HP, m.MaxHP)
+	} else if req.Damage < 0 {
+		summary = fmt.Sprintf("%s recovers %d HP (%d/%d HP)", m.Name, -req.Damage, m.HP, m.MaxHP)
+	}
+
+	event := ""
+	switch {
+	case wasUp && m.HP == 0:
+		event = game.MountFallKilled
+		summary += " and goes down"
+		response["mount_down"] = true
+	case req.KnockedProne:
+		event = game.MountFallProne
+		summary += " and is knocked prone"
+	case req.MovedAgainstWill:
+		event = game.MountFallMoved
+		summary += " and is forced to move"
+	}
+
+	var riderID int
+	var riderName string
+	rdb.QueryRow("SELECT id, name FROM characters WHERE mount_id = $1", m.ID).Scan(&riderID, &riderName)
+	if riderID != 0 && event != "" {
+		rider := unseatRider(campaignID, riderID, riderName, m.Name, event)
+		response["rider"] = rider
+		summary += ". " + rider["message"].(string)
+	}
+
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+		VALUES ($1, $2, 'mount_damage', $3, $4)
+	`, campaignID, m.CharacterID, fmt.Sprintf("%s is hit", m.Name), summary)
+	response["message"] = summary
+	json.NewEncoder(w).Encode(response)
+}
+
+// unseatRider resolves what a mount being moved, knocked prone or killed does to its rider:
+// the Dexterity save, the reaction to land on their feet, the dismount and the prone condition.
+func unseatRider(campaignID, riderID int, riderName, mountName, event string) map[string]interface{} {
+	result := map[string]interface{}{"character_id": riderID, "name": riderName}
+
+	saveTotal := 0
+	if event == game.MountFallMoved {
+		mod := characterSaveModifier(campaignID, riderID, "dex")
+		roll := game.RollDie(20)
+		if autoFailsSave(riderID, "dex") {
+			roll, mod = 0, 0
+		}
+		saveTotal = roll + mod
+		result["dex_save"] = fmt.Sprintf("d20(%d)%+d = %d vs DC %d", roll, mod, saveTotal, game.MountFallSaveDC)
+	}
+	var reactionUsed bool
+	db.QueryRow("SELECT COALESCE(reaction_used, false) FROM characters WHERE id = $1", riderID).Scan(&reactionUsed)
+
+	dismounted, prone, usedReaction := game.RiderFall(event, saveTotal, !reactionUsed)
+	result["dismounted"] = dismounted
+	result["prone"] = prone
+	if !dismounted {
+		result["message"] = fmt.Sprintf("%s keeps their seat", riderName)
+		return result
+	}
+
+	db.Exec("UPDATE characters SET mounted_on_creature = NULL, mount_is_controlled = NULL, mount_id = NULL WHERE id = $1", riderID)
+	if usedReaction {
+		db.Exec("UPDATE characters SET reaction_used = true WHERE id = $1", riderID)
+		result["reaction_used"] = true
+		result["message"] = fmt.Sprintf("%s uses their reaction to leap from %s and lands on their feet", riderName, mountName)
+		return result
+	}
+	conditions := getCharConditions(riderID)
+	if !conditionListHas(conditions, "prone") {
+		updated, _ := json.Marshal(append(conditions, "prone"))
+		db.Exec("UPDATE characters SET conditions = $1 WHERE id = $2", updated, riderID)
+	}
+	result["message"] = fmt.Sprintf("%s falls from %s and lands prone within 5 feet", riderName, mountName)
+	return result
+}
+
+// riddenMount describes the mount a character is riding: an owned mount's stats, or the
+// bestiary's for a creature mounted by slug. ok is false when they're on foot.
+func riddenMount(charID int) (name string, speed int, controlled, ok bool) {
+	var slug sql.NullString
+	var mountID sql.NullInt64
+	var isControlled sql.NullBool
+	db.QueryRow("SELECT mounted_on_creature, mount_id, mount_is_controlled FROM characters WHERE id = $1", charID).Scan(&slug, &mountID, &isControlled)
+	if !slug.Valid || slug.String == "" {
+		return "", 0, false, false
+	}
+	controlled = !isControlled.Valid || isControlled.Bool
+	if mountID.Valid {
+		if m, found := loadMount(int(mountID.Int64)); found {
+			return m.Name, m.Speed, controlled, true
+		}
+	}
+	name, speed = slug.String, 60
+	db.QueryRow("SELECT name, COALESCE(speed, 60) FROM monsters WHERE slug = $1", slug.String).Scan(&name, &speed)
+	return name, speed, controlled, true
+}
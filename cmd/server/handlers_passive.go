@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Passive scores (v1.0.103): the GM compares hidden DCs (a trap, a lie, a sneaking foe)
+// against the party's passive Perception, Investigation and Insight without asking anyone
+// to roll and tipping them off.
+
+// passiveSkillAbility is the ability each passive skill uses.
+var passiveSkillAbility = map[string]string{"perception": "wis", "investigation": "int", "insight": "wis"}
+
+// characterSkillModifier is a character's modifier for a skill check: the skill's ability
+// modifier with proficiency or expertise, or Jack of All Trades without them.
+func characterSkillModifier(charID int, skill string) int {
+	var scores [6]int
+	var level int
+	var class, skillProfsRaw, expertiseRaw string
+	db.QueryRow(`
+		SELECT str, dex, con, intl, wis, cha, level, COALESCE(class, ''), COALESCE(skill_proficiencies, ''), COALESCE(expertise, '')
+		FROM characters WHERE id = $1
+	`, charID).Scan(&scores[0], &scores[1], &scores[2], &scores[3], &scores[4], &scores[5], &level, &class, &skillProfsRaw, &expertiseRaw)
+	abilityIndex := map[string]int{"str": 0, "dex": 1, "con": 2, "int": 3, "wis": 4, "cha": 5}[skillAbilityMap[skill]]
+	proficient := proficiencyListHas(skillProfsRaw, skill)
+	return game.SkillModifier(game.Modifier(scores[abilityIndex]), game.ProficiencyBonus(level), proficient,
+		proficient && proficiencyListHas(expertiseRaw, skill), game.HasClassFeature(class, level, "jack_of_all_trades"))
+}
+
+// proficiencyListHas reports whether a comma-separated skill list includes the skill.
+func proficiencyListHas(list, skill string) bool {
+	for _, s := range strings.Split(list, ",") {
+		if strings.TrimSpace(strings.ToLower(s)) == skill {
+			return true
+		}
+	}
+	return false
+}
+
+// characterPassiveScores works out a character's passive scores in the given light. notes
+// explain every adjustment beyond the skill modifier, keyed by skill.
+func characterPassiveScores(charID int, lighting string, magicalDarkness bool) (scores map[string]int, notes map[string][]string, err error) {
+	var intl, wis, level int
+	var class, skillProfsRaw, expertiseRaw string
+	var featsJSON []byte
+	var exhaustion, campaignID int
+	err = db.QueryRow(`
+		SELECT intl, wis, level, COALESCE(class, ''), COALESCE(skill_proficiencies, ''), COALESCE(expertise, ''),
+			COALESCE(feats, '[]'), COALESCE(exhaustion_level, 0), COALESCE(lobby_id, 0)
+		FROM characters WHERE id = $1
+	`, charID).Scan(&intl, &wis, &level, &class, &skillProfsRaw, &expertiseRaw, &featsJSON, &exhaustion, &campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var feats []string
+	json.Unmarshal(featsJSON, &feats)
+
+	// Disadvantage on every ability check
+	var checkPenalties []string
+	if hasCondition(charID, "poisoned") {
+		checkPenalties = append(checkPenalties, "poisoned (-5)")
+	}
+	if exhaustion >= 1 {
+		checkPenalties = append(checkPenalties, "exhaustion (-5)")
+	}
+	vision := canSeeInLight(charID, lighting, magicalDarkness)
+	jack := game.HasClassFeature(class, level, "jack_of_all_trades")
+	featBonus := game.GetPassiveBonus(feats)
+	weather := weatherPerceptionPenalty(campaignID, false, false) // v1.0.107
+
+	scores = map[string]int{}
+	notes = map[string][]string{}
+	for _, skill := range game.PassiveSkills {
+		abilityMod := game.Modifier(wis)
+		if passiveSkillAbility[skill] == "int" {
+			abilityMod = game.Modifier(intl)
+		}
+		proficient := proficiencyListHas(skillProfsRaw, skill)
+		mod := game.SkillModifier(abilityMod, game.ProficiencyBonus(level), proficient, proficient && proficiencyListHas(expertiseRaw, skill), jack)
+
+		skillNotes := append([]string{}, checkPenalties...)
+		disadvantage := len(checkPenalties) > 0
+		if skill == "perception" {
+			// Lightly obscured: disadvantage on Perception that relies on sight (PHB p183)
+			switch vision {
+			case game.VisionDim:
+				disadvantage = true
+				skillNotes = append(skillNotes, fmt.Sprintf("%s light (-5)", lighting))
+			case game.VisionBlind:
+				disadvantage = true
+				skillNotes = append(skillNotes, "can't see: hearing and smell only (-5)")
+			}
+			if weather != "" {
+				disadvantage = true
+				skillNotes = append(skillNotes, weather+" (-5)")
+			}
+		}
+		score := game.PassiveScore(mod, false, disadvantage)
+		if skill != "insight" && featBonus > 0 {
+			// Observant (PHB p168) is a flat bonus, not advantage
+			score += featBonus
+			skillNotes = append(skillNotes, fmt.Sprintf("Observant (+%d)", featBonus))
+		}
+		scores[skill] = score
+		if len(skillNotes) > 0 {
+			notes[skill] = skillNotes
+		}
+	}
+	return scores, notes, nil
+}
+
+// handleGMPassiveScores godoc
+// @Summary Party passive scores
+// @Description Passive Perception, Investigation and Insight (10 + modifier, -5 for disadvantage such as dim light or, from v1.0.107, heavy rain, fog or strong wind for Perception, poisoned or exhaustion, +5 from Observant) for every character in your campaign, so you can check hidden DCs without asking players to roll. Light is where each character stands unless you pass light (bright, dim, darkness). Pass dc to see who beats it. Players are not told. v1.0.103.
+// @Tags GM
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param dc query int false "Compare every score against this DC"
+// @Param light query string false "bright, dim or darkness instead of each character's current light"
+// @Success 200 {object} map[string]interface{} "Passive scores by character"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/passive-scores [get]
+func handleGMPassiveScores(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var campaignID int
+	if rdb.QueryRow("SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1", agentID, actingCampaignID(r)).Scan(&campaignID) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of any active campaign"})
+		return
+	}
+
+	light := strings.ToLower(r.URL.Query().Get("light"))
+	if light != "" && light != game.LightBright && light != game.LightDim && light != game.LightDarkness {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_light", "message": "light must be bright, dim or darkness"})
+		return
+	}
+	dc, _ := strconv.Atoi(r.URL.Query().Get("dc"))
+
+	rows, err := rdb.Query("SELECT id, name FROM characters WHERE lobby_id = $1 ORDER BY id", campaignID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error"})
+		return
+	}
+	type partyMember struct {
+		id   int
+		name string
+	}
+	var party []partyMember
+	for rows.Next() {
+		var m partyMember
+		if rows.Scan(&m.id, &m.name) == nil {
+			party = append(party, m)
+		}
+	}
+	rows.Close()
+
+	characters := []map[string]interface{}{}
+	for _, m := range party {
+		lighting, magical := light, false
+		if lighting == "" {
+			lighting, magical = lightingAtCombatant(campaignID, m.id)
+		}
+		scores, notes, err := characterPassiveScores(m.id, lighting, magical)
+		if err != nil {
+			continue
+		}
+		entry := map[string]interface{}{
+			"character_id": m.id,
+			"name":         m.name,
+			"light":        lighting,
+		}
+		for skill, score := range scores {
+			entry["passive_"+skill] = score
+		}
+		if len(notes) > 0 {
+			entry["adjustments"] = notes
+		}
+		if dc > 0 {
+			beats := map[string]bool{}
+			for skill, score := range scores {
+				beats[skill] = score >= dc
+			}
+			entry["meets_dc"] = beats
+		}
+		characters = append(characters, entry)
+	}
+
+	response := map[string]interface{}{
+		"campaign_id": campaignID,
+		"characters":  characters,
+		"rule":        "Passive score = 10 + check modifier; +5 for advantage, -5 for disadvantage (PHB p175). It meets a DC when it equals or beats it.",
+	}
+	if dc > 0 {
+		response["dc"] = dc
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+	"github.com/agentrpg/agentrpg/internal/auth"
+)
+
+// Quest objectives and prerequisites (v1.0.109): quests in the campaign document can hold
+// checkbox objectives and list the quests that must be completed before they unlock.
+// Whenever the GM changes a quest the automatic transitions run: finishing a quest's
+// objectives completes it, and completing a quest unlocks the ones waiting on it.
+
+// parseQuestObjectives reads objectives given as strings or as objects and numbers them.
+func parseQuestObjectives(raw json.RawMessage) ([]game.QuestObjective, error) {
+	var objectives []game.QuestObjective
+	if err := json.Unmarshal(raw, &objectives); err != nil {
+		var texts []string
+		if json.Unmarshal(raw, &texts) != nil {
+			return nil, fmt.Errorf("objectives must be a list of strings or of {text, optional}")
+		}
+		objectives = make([]game.QuestObjective, len(texts))
+		for i, text := range texts {
+			objectives[i] = game.QuestObjective{Text: text}
+		}
+	}
+	for i := range objectives {
+		objectives[i].Text = strings.TrimSpace(objectives[i].Text)
+		if objectives[i].Text == "" {
+			return nil, fmt.Errorf("every objective needs text")
+		}
+	}
+	game.NumberObjectives(objectives)
+	return objectives, nil
+}
+
+// questsFromDoc reads the objectives and prerequisites of the campaign document's quests.
+func questsFromDoc(quests []interface{}) []game.Quest {
+	out := make([]game.Quest, 0, len(quests))
+	for _, quest := range quests {
+		var q game.Quest
+		raw, _ := json.Marshal(quest)
+		json.Unmarshal(raw, &q)
+		out = append(out, q)
+	}
+	return out
+}
+
+// validateQuestPrerequisites checks that the prerequisites for quest id name other quests
+// and don't loop back to it.
+func validateQuestPrerequisites(quests []game.Quest, id string, prerequisites []string) error {
+	known := map[string]bool{}
+	for _, q := range quests {
+		known[q.ID] = true
+	}
+	for _, p := range prerequisites {
+		if !known[p] || p == id {
+			return fmt.Errorf("prerequisite %q is not another quest in this campaign", p)
+		}
+	}
+	if game.QuestPrerequisiteCycle(quests, id, prerequisites) {
+		return fmt.Errorf("those prerequisites would make the quest depend on itself")
+	}
+	return nil
+}
+
+// advanceCampaignQuests runs the automatic quest transitions over the campaign
+// document's quests, updating them in place.
+func advanceCampaignQuests(quests []interface{}) []game.QuestTransition {
+	parsed := questsFromDoc(quests)
+	transitions := game.AdvanceQuests(parsed)
+	if len(transitions) == 0 {
+		return []game.QuestTransition{}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, q := range parsed {
+		if questMap, ok := quests[i].(map[string]interface{}); ok && questMap["status"] != q.Status {
+			questMap["status"] = q.Status
+			questMap["updated_at"] = now
+		}
+	}
+	return transitions
+}
+
+// handleCampaignQuestGraph godoc
+// @Summary Quest dependency graph
+// @Description The campaign's quests as a dependency tree for planning pacing: each quest with its prerequisites, the quests it unlocks, its depth (longest chain of prerequisites before it), objective progress, and whether a failed prerequisite blocks it. roots are the quests with no prerequisites; layers groups quest IDs by depth. GM only. v1.0.109.
+// @Tags Campaigns
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Quest graph"
+// @Failure 401 {object} map[string]interface{} "Unauthorized or not GM"
+// @Router /campaigns/{id}/campaign/quests/graph [get]
+func handleCampaignQuestGraph(w http.ResponseWriter, r *http.Request, campaignID int) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var campaignDocRaw []byte
+	var dmID int
+	rdb.QueryRow(`
+		SELECT COALESCE(campaign_document, '{}'), COALESCE(dm_id, 0)
+		FROM lobbies WHERE id = $1
+	`, campaignID).Scan(&campaignDocRaw, &dmID)
+	if !hasCampaignScope(campaignID, dmID, agentID, auth.ScopeQuests) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "gm_only", "message": "Only the GM can see the quest graph"})
+		return
+	}
+
+	var campaignDoc map[string]interface{}
+	json.Unmarshal(campaignDocRaw, &campaignDoc)
+	quests, _ := campaignDoc["quests"].([]interface{})
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"graph": game.BuildQuestGraph(questsFromDoc(quests)),
+		"count": len(quests),
+	})
+}
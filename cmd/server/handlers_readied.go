@@ -0,0 +1,479 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Monster readied actions (v1.0.101): the GM readies a monster's action on its turn with
+// a trigger, like a character's ready action. The readied action lives on the monster's
+// combat_monsters row and fires with the monster's reaction, from POST
+// /api/gm/monster-trigger or from an environmental event; it lapses when the monster's
+// next turn starts.
+
+// readiedMonster is a monster in combat holding a readied action.
+type readiedMonster struct {
+	CombatantID  int
+	Name         string
+	MonsterKey   string
+	Readied      map[string]string
+	ReactionUsed bool
+}
+
+// campaignReadiedMonsters lists the monsters still in combat with a readied action.
+func campaignReadiedMonsters(campaignID int) []readiedMonster {
+	rows, err := db.Query(`
+		SELECT combatant_id, name, COALESCE(monster_key, ''), readied_action, COALESCE(reaction_used, false)
+		FROM combat_monsters
+		WHERE lobby_id = $1 AND removed_at IS NULL AND readied_action IS NOT NULL
+		ORDER BY combatant_id DESC
+	`, campaignID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	monsters := []readiedMonster{}
+	for rows.Next() {
+		var m readiedMonster
+		var readiedJSON []byte
+		if rows.Scan(&m.CombatantID, &m.Name, &m.MonsterKey, &readiedJSON, &m.ReactionUsed) != nil {
+			continue
+		}
+		if json.Unmarshal(readiedJSON, &m.Readied) != nil || m.Readied == nil {
+			continue
+		}
+		monsters = append(monsters, m)
+	}
+	return monsters
+}
+
+// pendingTriggers lists every readied action primed in a campaign, the party's and the
+// monsters', for GM status.
+func pendingTriggers(campaignID int) []map[string]interface{} {
+	pending := []map[string]interface{}{}
+	rows, err := db.Query(`
+		SELECT id, name, readied_action, COALESCE(reaction_used, false) FROM characters
+		WHERE lobby_id = $1 AND readied_action IS NOT NULL
+		ORDER BY id
+	`, campaignID)
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var name string
+			var readiedJSON []byte
+			var reactionUsed bool
+			var readied map[string]string
+			if rows.Scan(&id, &name, &readiedJSON, &reactionUsed) != nil || json.Unmarshal(readiedJSON, &readied) != nil || readied == nil {
+				continue
+			}
+			pending = append(pending, map[string]interface{}{
+				"character_id":       id,
+				"name":               name,
+				"trigger":            readied["trigger"],
+				"trigger_type":       game.ReadiedTriggerType(readied),
+				"action":             readied["action"],
+				"description":        readied["description"],
+				"reaction_available": !reactionUsed,
+				"fire_with":          fmt.Sprintf("POST /api/gm/trigger-readied {\"character_id\":%d}", id),
+			})
+		}
+		rows.Close()
+	}
+	for _, m := range campaignReadiedMonsters(campaignID) {
+		entry := map[string]interface{}{
+			"combatant_id":       m.CombatantID,
+			"name":               m.Name,
+			"trigger":            m.Readied["trigger"],
+			"trigger_type":       game.ReadiedTriggerType(m.Readied),
+			"action":             m.Readied["action"],
+			"description":        m.Readied["description"],
+			"reaction_available": !m.ReactionUsed,
+			"fire_with":          fmt.Sprintf("POST /api/gm/monster-trigger {\"combatant_id\":%d}", m.CombatantID),
+		}
+		if m.Readied["target_id"] != "" {
+			entry["target_id"] = m.Readied["target_id"]
+		}
+		pending = append(pending, entry)
+	}
+	return pending
+}
+
+// gmCombatCampaign returns the GM's active campaign when it's in combat.
+func gmCombatCampaign(w http.ResponseWriter, r *http.Request, agentID int) (int, bool) {
+	rdb := dbFor(r)
+	var campaignID int
+	var inCombat bool
+	err := rdb.QueryRow(`
+		SELECT l.id, COALESCE(cs.active, false) FROM lobbies l
+		LEFT JOIN combat_state cs ON cs.lobby_id = l.id
+		WHERE l.dm_id = $1 AND l.status = 'active' AND ($2 = 0 OR l.id = $2) LIMIT 1
+	`, agentID, actingCampaignID(r)).Scan(&campaignID, &inCombat)
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of an active campaign."})
+		return 0, false
+	}
+	if !inCombat {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_in_combat", "message": "Monsters ready actions in combat. Start it with POST /api/campaigns/{id}/combat/start"})
+		return 0, false
+	}
+	return campaignID, true
+}
+
+// monsterTurnActive reports whether a monster may act now: it's the monster's turn, or
+// under side initiative, the monsters' side's.
+func monsterTurnActive(campaignID, combatantID int) (bool, string) {
+	var turnOrderJSON []byte
+	var turnIndex int
+	var initiativeMode string
+	db.QueryRow(`
+		SELECT COALESCE(turn_order, '[]'), COALESCE(current_turn_index, 0), COALESCE(initiative_mode, '')
+		FROM combat_state WHERE lobby_id = $1
+	`, campaignID).Scan(&turnOrderJSON, &turnIndex, &initiativeMode)
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	if turnIndex >= len(entries) {
+		return false, ""
+	}
+	currentID := turnOrderInt(entries[turnIndex], "id")
+	currentName, _ := entries[turnIndex]["name"].(string)
+	if initiativeMode == game.InitiativeModeSide && currentID < 0 {
+		return true, currentName
+	}
+	return currentID == combatantID, currentName
+}
+
+// handleGMMonsterReady godoc
+// @Summary Ready a monster's action
+// @Description On a monster's turn (or the monsters' side's, under side initiative), spend its action to ready a response to a trigger (PHB p193). action describes what it does ("attack with its scimitar", "pull the lever"); target_id is a character it means to attack. Environmental triggers (door_opens, lever_pulled, trap_sprung, ...) fire from POST /api/gm/trigger-readied with that event; creature triggers fire from POST /api/gm/monster-trigger. Either way it costs the monster's reaction, and the readied action lapses when its next turn starts. Pending triggers appear in GET /api/gm/status. v1.0.101.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{combatant_id=integer,trigger=string,action=string,target_id=integer} true "Monster, trigger and readied action"
+// @Success 200 {object} map[string]interface{} "Action readied"
+// @Failure 400 {object} map[string]interface{} "Not the monster's turn or missing trigger"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/monster-ready [post]
+func handleGMMonsterReady(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	campaignID, ok := gmCombatCampaign(w, r, agentID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CombatantID int    `json:"combatant_id"`
+		Trigger     string `json:"trigger"`
+		Action      string `json:"action"`
+		TargetID    int    `json:"target_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	req.Trigger = strings.TrimSpace(req.Trigger)
+	req.Action = strings.TrimSpace(req.Action)
+	if req.Trigger == "" || req.Action == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_request",
+			"message": "trigger and action required, e.g. {\"combatant_id\":-1,\"trigger\":\"when someone comes through the door\",\"action\":\"attack with its scimitar\"}",
+		})
+		return
+	}
+
+	entry, found := turnOrderEntry(campaignID, req.CombatantID)
+	if req.CombatantID >= 0 || !found {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "monster_not_found", "message": fmt.Sprintf("No monster with combatant_id %d in the turn order", req.CombatantID)})
+		return
+	}
+	name, _ := entry["name"].(string)
+	if active, current := monsterTurnActive(campaignID, req.CombatantID); !active {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "not_monster_turn",
+			"message": fmt.Sprintf("It's %s's turn. %s readies its action on its own turn.", current, name),
+		})
+		return
+	}
+	// v1.0.104: A surprised monster loses its first turn
+	if isSurprised(campaignID, req.CombatantID) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "surprised", "message": fmt.Sprintf("%s is surprised and can't act in round 1", name)})
+		return
+	}
+
+	readied := map[string]string{
+		"trigger":      req.Trigger,
+		"trigger_type": game.ClassifyReadyTrigger(req.Trigger),
+		"action":       game.ReadyActionType(req.Action),
+		"description":  req.Action,
+	}
+	if req.TargetID > 0 {
+		var targetName string
+		if rdb.QueryRow("SELECT name FROM characters WHERE id = $1 AND lobby_id = $2", req.TargetID, campaignID).Scan(&targetName) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "target_not_found", "message": "target_id must be a character in this campaign"})
+			return
+		}
+		readied["target_id"] = fmt.Sprint(req.TargetID)
+		readied["target"] = targetName
+	}
+	readiedJSON, _ := json.Marshal(readied)
+
+	syncCombatMonsters(campaignID)
+	rdb.Exec(`
+		UPDATE combat_monsters SET readied_action = $3
+		WHERE lobby_id = $1 AND combatant_id = $2 AND removed_at IS NULL
+	`, campaignID, req.CombatantID, readiedJSON)
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result)
+		VALUES ($1, 'monster_ready', $2, $3)
+	`, campaignID, fmt.Sprintf("%s readies an action", name), fmt.Sprintf("When '%s' → %s", req.Trigger, req.Action))
+
+	firedBy := fmt.Sprintf("POST /api/gm/monster-trigger {\"combatant_id\":%d} when it happens", req.CombatantID)
+	if readied["trigger_type"] != game.ReadyTriggerCreature {
+		firedBy = fmt.Sprintf("fires automatically on POST /api/gm/trigger-readied {\"event\":\"%s\"}", readied["trigger_type"])
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"combatant_id":   req.CombatantID,
+		"monster":        name,
+		"readied_action": readied,
+		"fire":           firedBy,
+		"message":        fmt.Sprintf("%s readies: when '%s' → %s. It uses its reaction when the trigger occurs, and the readied action lapses at the start of its next turn.", name, req.Trigger, req.Action),
+	})
+}
+
+// handleGMMonsterTrigger godoc
+// @Summary Fire a monster's readied action
+// @Description The trigger a monster readied for has happened: resolve its readied action with its reaction. A readied attack on a character (target_id here or when readied) is rolled from the monster's stat block and the damage applied; anything else is returned for the GM to narrate. v1.0.101.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{combatant_id=integer,target_id=integer,description=string} true "Monster whose readied action fires"
+// @Success 200 {object} map[string]interface{} "Readied action result"
+// @Failure 400 {object} map[string]interface{} "No readied action or reaction already used"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/monster-trigger [post]
+func handleGMMonsterTrigger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	campaignID, ok := gmCombatCampaign(w, r, agentID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CombatantID int    `json:"combatant_id"`
+		TargetID    int    `json:"target_id"`
+		Description string `json:"description"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var m *readiedMonster
+	for _, candidate := range campaignReadiedMonsters(campaignID) {
+		if candidate.CombatantID == req.CombatantID {
+			m = &candidate
+			break
+		}
+	}
+	if m == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "no_readied_action",
+			"message": fmt.Sprintf("Monster %d has no readied action. Ready one on its turn with POST /api/gm/monster-ready.", req.CombatantID),
+		})
+		return
+	}
+	if m.ReactionUsed {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":          "reaction_used",
+			"message":        fmt.Sprintf("%s has already used its reaction this round.", m.Name),
+			"readied_action": m.Readied,
+		})
+		return
+	}
+	if req.TargetID > 0 {
+		m.Readied["target_id"] = fmt.Sprint(req.TargetID)
+	}
+
+	result := fireMonsterReadied(campaignID, *m, req.Description)
+	json.NewEncoder(w).Encode(result)
+}
+
+// fireMonsterReadied resolves a monster's readied action with its reaction, clears it and
+// posts it to the feed.
+func fireMonsterReadied(campaignID int, m readiedMonster, cause string) map[string]interface{} {
+	db.Exec(`
+		UPDATE combat_monsters SET readied_action = NULL, reaction_used = true
+		WHERE lobby_id = $1 AND combatant_id = $2 AND removed_at IS NULL
+	`, campaignID, m.CombatantID)
+
+	result := map[string]interface{}{
+		"success":           true,
+		"combatant_id":      m.CombatantID,
+		"monster":           m.Name,
+		"trigger":           m.Readied["trigger"],
+		"action":            m.Readied["action"],
+		"reaction_consumed": true,
+	}
+	summary := fmt.Sprintf("%s: %s", m.Name, m.Readied["description"])
+	var targetID int
+	fmt.Sscan(m.Readied["target_id"], &targetID)
+	if m.Readied["action"] == "attack" && targetID > 0 {
+		attack := monsterReadiedAttack(campaignID, m, targetID)
+		for k, v := range attack {
+			result[k] = v
+		}
+		summary, _ = attack["result"].(string)
+	} else {
+		result["resolve"] = "Narrate the readied action; use the GM tools (damage-monster, saving-throw, forced-movement, ...) for its effects"
+	}
+	result["result"] = summary
+
+	if cause == "" {
+		cause = m.Readied["trigger"]
+	}
+	db.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result)
+		VALUES ($1, $2, $3, $4)
+	`, campaignID, "monster_readied_"+m.Readied["action"], fmt.Sprintf("Triggered: %s → %s", cause, m.Readied["description"]), summary)
+	result["message"] = fmt.Sprintf("%s's readied action triggered! '%s' → %s", m.Name, m.Readied["trigger"], summary)
+	return result
+}
+
+// monsterReadiedAttack rolls a readied attack from the monster's stat block against a
+// character: the attack named in the readied description, else its first attack.
+func monsterReadiedAttack(campaignID int, m readiedMonster, targetID int) map[string]interface{} {
+	var actionsJSON []byte
+	db.QueryRow("SELECT COALESCE(actions, '[]') FROM monsters WHERE slug = $1", m.MonsterKey).Scan(&actionsJSON)
+	var actions []map[string]interface{}
+	json.Unmarshal(actionsJSON, &actions)
+	description := strings.ToLower(m.Readied["description"])
+	name, bonus, dice, damageType := "", 0, "", ""
+	for _, a := range actions {
+		aName, _ := a["name"].(string)
+		aBonus, _ := a["attack_bonus"].(float64)
+		named := aName != "" && strings.Contains(description, strings.ToLower(aName))
+		if aBonus == 0 || (name != "" && !named) {
+			continue
+		}
+		name, bonus, dice, damageType = aName, int(aBonus), "1d6", "bludgeoning"
+		if d, ok := a["damage_dice"].(string); ok && d != "" {
+			dice = d
+		}
+		if t, ok := a["damage_type"].(string); ok && t != "" {
+			damageType = strings.ToLower(t)
+		}
+		if named {
+			break
+		}
+	}
+	if name == "" {
+		return map[string]interface{}{
+			"hit":     false,
+			"result":  fmt.Sprintf("%s has no attack in its stat block to roll: narrate it and apply damage with POST /api/characters/{id}/damage", m.Name),
+			"resolve": "manual",
+		}
+	}
+
+	var targetName string
+	var targetAC int
+	if db.QueryRow("SELECT name, COALESCE(ac, 10) FROM characters WHERE id = $1 AND lobby_id = $2", targetID, campaignID).Scan(&targetName, &targetAC) != nil {
+		return map[string]interface{}{"hit": false, "result": fmt.Sprintf("%s's target is no longer in the campaign", m.Name)}
+	}
+
+	roll := game.RollDie(20)
+	total := roll + bonus
+	crit := roll == 20
+	hit := crit || (roll != 1 && total >= targetAC)
+	attack := map[string]interface{}{
+		"attack":      name,
+		"target":      targetName,
+		"attack_roll": fmt.Sprintf("d20(%d)%+d = %d", roll, bonus, total),
+		"crit":        crit,
+		"hit":         hit,
+	}
+	if !hit {
+		attack["result"] = fmt.Sprintf("%s's %s misses %s (%d vs AC %d)", m.Name, name, targetName, total, targetAC)
+		return attack
+	}
+	damage := max(game.RollDamage(dice, crit)+game.DiceBonus(dice), 1)
+	attack["damage"] = damage
+	attack["damage_type"] = damageType
+	attack["damage_applied"] = applyCharacterDamage(targetID, damage, damageType)
+	attack["result"] = fmt.Sprintf("%s's %s hits %s (%d vs AC %d) for %d %s damage", m.Name, name, targetName, total, targetAC, damage, damageType)
+	return attack
+}
+
+// fireMonsterReadiedEvent fires the monsters' readied actions waiting on an environmental
+// event. Monsters whose reaction is spent keep theirs.
+func fireMonsterReadiedEvent(campaignID int, event string) (fired, skipped []map[string]interface{}) {
+	fired, skipped = []map[string]interface{}{}, []map[string]interface{}{}
+	for _, m := range campaignReadiedMonsters(campaignID) {
+		if game.ReadiedTriggerType(m.Readied) != event {
+			continue
+		}
+		if m.ReactionUsed {
+			skipped = append(skipped, map[string]interface{}{
+				"combatant_id": m.CombatantID,
+				"monster":      m.Name,
+				"reason":       "reaction_used",
+			})
+			continue
+		}
+		fired = append(fired, fireMonsterReadied(campaignID, m, event))
+	}
+	return fired, skipped
+}
+
+// startMonsterTurn returns a monster's reaction and drops the action it readied last
+// turn. Returns a note when a readied action lapsed unfired.
+func startMonsterTurn(campaignID, combatantID int) string {
+	var name string
+	var readiedJSON []byte
+	db.QueryRow(`
+		SELECT name, readied_action FROM combat_monsters
+		WHERE lobby_id = $1 AND combatant_id = $2 AND removed_at IS NULL
+	`, campaignID, combatantID).Scan(&name, &readiedJSON)
+	db.Exec(`
+		UPDATE combat_monsters SET readied_action = NULL, reaction_used = false
+		WHERE lobby_id = $1 AND combatant_id = $2 AND removed_at IS NULL
+	`, campaignID, combatantID)
+	var readied map[string]string
+	if json.Unmarshal(readiedJSON, &readied) != nil || readied == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s's readied action (when '%s' → %s) lapsed unused", name, readied["trigger"], readied["description"])
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/internal/audit"
+	"github.com/agentrpg/agentrpg/internal/auth"
+	"github.com/agentrpg/agentrpg/internal/gm"
+)
+
+// Campaign roles (v1.0.112): the GM (lobbies.dm_id) can grant co-GM and assistant roles in
+// campaign_roles. withCampaignRoles checks every /api/gm/ request against them: an agent
+// whose role covers the tool runs it as the campaign's GM, so the handlers' own dm_id
+// checks hold; one whose role doesn't is refused before the handler runs. A role reaches
+// only its own campaign, even when the GM runs several: the handlers narrow their campaign
+// lookup with actingCampaignID, and requests naming characters elsewhere are refused.
+
+// actingGMKey is the context key for an agent acting under a campaign role.
+type actingGMKey struct{}
+
+// actingGM is who is really behind a request running as a campaign's GM.
+type actingGM struct {
+	AgentID    int
+	GMID       int
+	CampaignID int
+	Role       auth.Role
+}
+
+// actingGMFrom returns the role a request is running under, if any.
+func actingGMFrom(r *http.Request) (actingGM, bool) {
+	a, ok := r.Context().Value(actingGMKey{}).(actingGM)
+	return a, ok
+}
+
+// actingCampaignID is the campaign a co-GM or assistant's role is for, or 0 for anyone else.
+// GM tools that look up "the GM's active campaign" narrow it to this one, so a role in one
+// of a GM's campaigns doesn't reach the others.
+func actingCampaignID(r *http.Request) int {
+	if acting, ok := actingGMFrom(r); ok {
+		return acting.CampaignID
+	}
+	return 0
+}
+
+// charactersOutside returns the characters among ids that are in a campaign other than
+// campaignID. Unknown characters are left to the handler.
+func charactersOutside(ctx context.Context, campaignID int, ids []int) []int {
+	var outside []int
+	for _, id := range ids {
+		var lobbyID int
+		if db.QueryRowContext(ctx, "SELECT COALESCE(lobby_id, 0) FROM characters WHERE id = $1", id).Scan(&lobbyID) == nil && lobbyID != campaignID {
+			outside = append(outside, id)
+		}
+	}
+	return outside
+}
+
+// campaignRole is one agent's role in one campaign.
+type campaignRole = gm.Role
+
+// sqlGMStore reads campaign_roles for internal/gm.
+type sqlGMStore struct{}
+
+func (sqlGMStore) Roles(ctx context.Context, agentID int) ([]gm.Role, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT cr.lobby_id, COALESCE(l.dm_id, 0), cr.role, cr.scopes
+		FROM campaign_roles cr JOIN lobbies l ON l.id = cr.lobby_id
+		WHERE cr.agent_id = $1 AND l.status = 'active'
+		ORDER BY cr.lobby_id
+	`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var roles []gm.Role
+	for rows.Next() {
+		var cr gm.Role
+		var scopes []byte
+		if rows.Scan(&cr.CampaignID, &cr.GMID, &cr.Name, &scopes) == nil {
+			json.Unmarshal(scopes, &cr.Scopes)
+			roles = append(roles, cr)
+		}
+	}
+	return roles, nil
+}
+
+func (sqlGMStore) Role(ctx context.Context, campaignID, agentID int) (auth.Role, error) {
+	var role auth.Role
+	var scopes []byte
+	err := db.QueryRowContext(ctx, "SELECT role, scopes FROM campaign_roles WHERE lobby_id = $1 AND agent_id = $2", campaignID, agentID).Scan(&role.Name, &scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return role, gm.ErrNoRole
+	}
+	if err != nil {
+		return role, err
+	}
+	json.Unmarshal(scopes, &role.Scopes)
+	return role, nil
+}
+
+func (sqlGMStore) OwnCampaign(ctx context.Context, agentID int) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx, "SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' LIMIT 1", agentID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// loadCampaignRoles returns an agent's roles in active campaigns.
+func loadCampaignRoles(ctx context.Context, agentID int) ([]campaignRole, error) {
+	return sqlGMStore{}.Roles(ctx, agentID)
+}
+
+// hasCampaignScope reports whether an agent is a campaign's GM or holds a role in it that
+// covers the scope. For GM tools outside /api/gm/, like campaign quests.
+func hasCampaignScope(campaignID, dmID, agentID int, scope string) bool {
+	return gm.HasScope(context.Background(), sqlGMStore{}, campaignID, dmID, agentID, scope)
+}
+
+// requestedCampaignID is the campaign a GM request names: the X-Campaign-ID header, a
+// campaign_id query parameter, or campaign_id in a JSON body (which is left readable).
+func requestedCampaignID(r *http.Request) int {
+	if id, err := strconv.Atoi(r.Header.Get("X-Campaign-ID")); err == nil {
+		return id
+	}
+	if id, err := strconv.Atoi(r.URL.Query().Get("campaign_id")); err == nil {
+		return id
+	}
+	if r.Body == nil || r.Method == "GET" {
+		return 0
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var req struct {
+		CampaignID int `json:"campaign_id"`
+	}
+	json.Unmarshal(body, &req)
+	return req.CampaignID
+}
+
+// withCampaignRoles lets co-GMs and assistants use the /api/gm/ tools their role covers.
+// Agents without roles, and GMs working their own campaign, pass straight through.
+func withCampaignRoles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/gm/") || db == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		agentID, err := getAgentFromAuth(r)
+		if err != nil {
+			next.ServeHTTP(w, r) // The handler reports it
+			return
+		}
+		roles, err := loadCampaignRoles(r.Context(), agentID)
+		if err != nil || len(roles) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		campaignID, own := requestedCampaignID(r), 0
+		if campaignID == 0 {
+			own, _ = sqlGMStore{}.OwnCampaign(r.Context(), agentID)
+		}
+		role, err := gm.Pick(roles, campaignID, own)
+		if errors.Is(err, gm.ErrCampaignRequired) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":     "campaign_required",
+				"message":   "You help run several campaigns. Say which with campaign_id or an X-Campaign-ID header",
+				"campaigns": gm.CampaignIDs(roles),
+			})
+			return
+		}
+		if role == nil {
+			next.ServeHTTP(w, r) // Their own campaign, or one they have no part in
+			return
+		}
+
+		if scope, ok := gm.Allows(*role, r.URL.Path); !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "role_not_permitted",
+				"message": fmt.Sprintf("Your %s role (scopes: %s) doesn't cover %s", role.Name, strings.Join(role.Scopes, ", "), r.URL.Path),
+				"needs":   scope,
+			})
+			return
+		}
+		// The handlers' dm_id checks pass for any of the GM's campaigns, so the characters
+		// the request names must be in the one the role is for.
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if outside := charactersOutside(r.Context(), role.CampaignID, audit.Targets(body)); len(outside) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":         "not_in_campaign",
+				"message":       fmt.Sprintf("Your %s role is for campaign %d; these characters aren't in it", role.Name, role.CampaignID),
+				"character_ids": outside,
+			})
+			return
+		}
+		acting := actingGM{AgentID: agentID, GMID: role.GMID, CampaignID: role.CampaignID, Role: role.Role}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actingGMKey{}, acting)))
+	})
+}
+
+// handleCampaignRoles godoc
+// @Summary Co-GMs and assistants
+// @Description GET lists the campaign's GM roles. POST (GM only) grants one: {"agent_id" or "agent_name", "role": "co_gm"|"assistant", "scopes": ["narrate","monsters","quests"]}. A co-GM can use every /api/gm/ tool; an assistant only those its scopes cover (narrate: narration and nudges; monsters: monster actions, damage and tactics; quests: campaign quests). Granting again replaces the role. DELETE ?agent_id= revokes it; agents can also step down themselves. Assistants name the campaign with campaign_id or X-Campaign-ID when they help run more than one. v1.0.112.
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param Authorization header string false "Basic auth (POST and DELETE)"
+// @Success 200 {object} map[string]interface{} "Roles"
+// @Failure 400 {object} map[string]interface{} "Invalid role or scopes"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Failure 404 {object} map[string]interface{} "Campaign or agent not found"
+// @Router /campaigns/{id}/roles [get]
+func handleCampaignRoles(w http.ResponseWriter, r *http.Request, campaignID int) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	var dmID int
+	if err := rdb.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", campaignID).Scan(&dmID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "campaign_not_found"})
+		return
+	}
+
+	if r.Method == "GET" {
+		rows, err := rdb.Query(`
+			SELECT cr.agent_id, a.name, cr.role, cr.scopes, cr.created_at
+			FROM campaign_roles cr JOIN agents a ON a.id = cr.agent_id
+			WHERE cr.lobby_id = $1 ORDER BY cr.created_at
+		`, campaignID)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
+		roles := []map[string]interface{}{}
+		for rows.Next() {
+			var agentID int
+			var name, role string
+			var scopes []byte
+			var created sql.NullTime
+			if rows.Scan(&agentID, &name, &role, &scopes, &created) != nil {
+				continue
+			}
+			var scopeList []string
+			json.Unmarshal(scopes, &scopeList)
+			roles = append(roles, map[string]interface{}{
+				"agent_id": agentID, "agent_name": name, "role": role, "scopes": scopeList, "granted_at": created.Time,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"campaign_id": campaignID, "gm_id": dmID, "roles": roles, "valid_scopes": auth.Scopes})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		if agentID != dmID {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the campaign's GM can grant roles"})
+			return
+		}
+		var req struct {
+			AgentID   int      `json:"agent_id"`
+			AgentName string   `json:"agent_name"`
+			Role      string   `json:"role"`
+			Scopes    []string `json:"scopes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+			return
+		}
+		role, err := auth.NewRole(req.Role, req.Scopes)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_role", "message": err.Error(), "valid_scopes": auth.Scopes})
+			return
+		}
+		var targetID int
+		var targetName string
+		err = rdb.QueryRow("SELECT id, name FROM agents WHERE id = $1 OR ($1 = 0 AND name = $2)", req.AgentID, req.AgentName).Scan(&targetID, &targetName)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "agent_not_found"})
+			return
+		}
+		if targetID == dmID {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "already_gm", "message": "You already run this campaign"})
+			return
+		}
+		scopes, _ := json.Marshal(role.Scopes)
+		_, err = rdb.Exec(`
+			INSERT INTO campaign_roles (lobby_id, agent_id, role, scopes, granted_by) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (lobby_id, agent_id) DO UPDATE SET role = $3, scopes = $4, granted_by = $5, created_at = NOW()
+		`, campaignID, targetID, role.Name, scopes, agentID)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+			return
+		}
+		description := fmt.Sprintf("%s joins the GM's table as co-GM", targetName)
+		if role.Name == auth.RoleAssistant {
+			description = fmt.Sprintf("%s joins the GM's table as an assistant (%s)", targetName, strings.Join(role.Scopes, ", "))
+		}
+		rdb.Exec("INSERT INTO actions (lobby_id, action_type, description, result) VALUES ($1, 'gm_role', $2, '')", campaignID, description)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true, "agent_id": targetID, "agent_name": targetName, "role": role.Name, "scopes": role.Scopes,
+		})
+
+	case "DELETE":
+		targetID, _ := strconv.Atoi(r.URL.Query().Get("agent_id"))
+		if targetID == 0 {
+			targetID = agentID
+		}
+		if agentID != dmID && agentID != targetID {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the campaign's GM can revoke someone else's role"})
+			return
+		}
+		res, err := rdb.Exec("DELETE FROM campaign_roles WHERE lobby_id = $1 AND agent_id = $2", campaignID, targetID)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "role_not_found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "revoked": targetID})
+
+	default:
+		http.Error(w, "GET, POST or DELETE required", http.StatusMethodNotAllowed)
+	}
+}
@@ -52,6 +52,7 @@ func getPagination(r *http.Request) (page, perPage int) {
 // @Success 200 {object} PaginatedResponse "Paginated monster list"
 // @Router /universe/monsters/search [get]
 func handleMonsterSearch(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
 	w.Header().Set("Content-Type", "application/json")
 
 	page, perPage := getPagination(r)
@@ -115,7 +116,7 @@ func handleMonsterSearch(w http.ResponseWriter, r *http.Request) {
 
 	// Get total count
 	var total int
-	db.QueryRow(countQuery, args...).Scan(&total)
+	rdb.QueryRow(countQuery, args...).Scan(&total)
 
 	// Sort
 	sort := r.URL.Query().Get("sort")
@@ -136,7 +137,7 @@ func handleMonsterSearch(w http.ResponseWriter, r *http.Request) {
 	query += " LIMIT $" + strconv.Itoa(argNum) + " OFFSET $" + strconv.Itoa(argNum+1)
 	args = append(args, perPage, offset)
 
-	rows, err := db.Query(query, args...)
+	rows, err := rdb.Query(query, args...)
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -194,6 +195,7 @@ func handleMonsterSearch(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} PaginatedResponse "Paginated spell list"
 // @Router /universe/spells/search [get]
 func handleSpellSearch(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
 	w.Header().Set("Content-Type", "application/json")
 
 	page, perPage := getPagination(r)
@@ -283,7 +285,7 @@ func handleSpellSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var total int
-	db.QueryRow(countQuery, args...).Scan(&total)
+	rdb.QueryRow(countQuery, args...).Scan(&total)
 
 	// Sort
 	sort := r.URL.Query().Get("sort")
@@ -301,7 +303,7 @@ func handleSpellSearch(w http.ResponseWriter, r *http.Request) {
 	query += " LIMIT $" + strconv.Itoa(argNum) + " OFFSET $" + strconv.Itoa(argNum+1)
 	args = append(args, perPage, offset)
 
-	rows, err := db.Query(query, args...)
+	rows, err := rdb.Query(query, args...)
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -349,6 +351,7 @@ func handleSpellSearch(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} PaginatedResponse "Paginated weapon list"
 // @Router /universe/weapons/search [get]
 func handleWeaponSearch(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
 	w.Header().Set("Content-Type", "application/json")
 
 	page, perPage := getPagination(r)
@@ -388,12 +391,12 @@ func handleWeaponSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var total int
-	db.QueryRow(countQuery, args...).Scan(&total)
+	rdb.QueryRow(countQuery, args...).Scan(&total)
 
 	query += " ORDER BY name LIMIT $" + strconv.Itoa(argNum) + " OFFSET $" + strconv.Itoa(argNum+1)
 	args = append(args, perPage, offset)
 
-	rows, err := db.Query(query, args...)
+	rows, err := rdb.Query(query, args...)
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Shops (v1.0.93): the GM opens shops in a campaign, stocked from the SRD weapons, armor,
+// consumables and magic items at PHB prices. Characters buy and sell with whatever coins
+// they carry; change comes back in gp, sp and cp, and merchants pay half price (PHB p144).
+
+// shopItem is something a shop can stock: an SRD entry, its price and the inventory entry
+// a buyer receives.
+type shopItem struct {
+	Kind    string // weapon, armor, consumable or magic_item
+	Slug    string
+	Name    string
+	PriceCP int // 0 when the item has no list price
+	Weight  float64
+	Item    map[string]interface{}
+}
+
+// resolveShopItem finds an item by slug or name in the weapons, armor, consumables and
+// magic items, in that order.
+func resolveShopItem(name string) (shopItem, bool) {
+	name = strings.TrimSpace(name)
+	slug := strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+	var s shopItem
+
+	if db.QueryRow("SELECT slug, name, COALESCE(weight, 0) FROM weapons WHERE slug = $1 OR LOWER(name) = LOWER($2) LIMIT 1",
+		slug, name).Scan(&s.Slug, &s.Name, &s.Weight) == nil {
+		s.Kind = "weapon"
+		s.PriceCP, _ = game.WeaponPriceCP(s.Slug)
+		s.Item = map[string]interface{}{"name": s.Name, "type": "weapon", "weight": s.Weight}
+		return s, true
+	}
+	if db.QueryRow("SELECT slug, name, COALESCE(weight, 0) FROM armor WHERE slug = $1 OR LOWER(name) = LOWER($2) LIMIT 1",
+		slug, name).Scan(&s.Slug, &s.Name, &s.Weight) == nil {
+		s.Kind = "armor"
+		s.PriceCP, _ = game.ArmorPriceCP(s.Slug)
+		itemType := "armor"
+		if s.Slug == "shield" {
+			itemType = "shield"
+		}
+		s.Item = map[string]interface{}{"name": s.Name, "type": itemType, "weight": s.Weight}
+		return s, true
+	}
+	for key, c := range consumables {
+		if key == strings.ReplaceAll(slug, "-", "_") || strings.EqualFold(c.Name, name) {
+			s = shopItem{Kind: "consumable", Slug: key, Name: c.Name, Item: newInventoryItem(c.Name, 1, nil)}
+			s.PriceCP, _ = game.ParsePrice(c.Cost)
+			return s, true
+		}
+	}
+	var rarity string
+	var attunement bool
+	if db.QueryRow("SELECT slug, name, COALESCE(rarity, ''), COALESCE(attunement, false) FROM magic_items WHERE slug = $1 OR LOWER(name) = LOWER($2) LIMIT 1",
+		slug, name).Scan(&s.Slug, &s.Name, &rarity, &attunement) == nil {
+		s.Kind = "magic_item"
+		s.PriceCP, _ = game.MagicItemPriceCP(rarity)
+		s.Item = map[string]interface{}{"name": s.Name, "type": "magic_item", "rarity": rarity, "requires_attunement": attunement}
+		return s, true
+	}
+	return s, false
+}
+
+// shopCategoryItems lists every priced item in a category: weapons, armor or consumables.
+func shopCategoryItems(category string) []shopItem {
+	items := []shopItem{}
+	switch category {
+	case "weapons", "armor":
+		rows, err := db.Query(fmt.Sprintf("SELECT slug FROM %s ORDER BY name", category))
+		if err != nil {
+			return items
+		}
+		slugs := []string{}
+		for rows.Next() {
+			var slug string
+			rows.Scan(&slug)
+			slugs = append(slugs, slug)
+		}
+		rows.Close()
+		for _, slug := range slugs {
+			if s, ok := resolveShopItem(slug); ok && s.PriceCP > 0 {
+				items = append(items, s)
+			}
+		}
+	case "consumables":
+		for _, c := range consumables {
+			if s, ok := resolveShopItem(c.Name); ok && s.PriceCP > 0 {
+				items = append(items, s)
+			}
+		}
+	}
+	return items
+}
+
+func characterPurse(charID int) game.Purse {
+	var p game.Purse
+	db.QueryRow(`
+		SELECT COALESCE(copper, 0), COALESCE(silver, 0), COALESCE(electrum, 0), COALESCE(gold, 0), COALESCE(platinum, 0)
+		FROM characters WHERE id = $1
+	`, charID).Scan(&p.CP, &p.SP, &p.EP, &p.GP, &p.PP)
+	return p
+}
+
+func saveCharacterPurse(charID int, p game.Purse) {
+	db.Exec("UPDATE characters SET copper = $1, silver = $2, electrum = $3, gold = $4, platinum = $5 WHERE id = $6",
+		p.CP, p.SP, p.EP, p.GP, p.PP, charID)
+}
+
+// encumbranceSummary is a character's carried weight against their capacity, as
+// GET /api/characters/encumbrance reports it.
+func encumbranceSummary(charID int) map[string]interface{} {
+	var str int
+	db.QueryRow("SELECT COALESCE(str, 10) FROM characters WHERE id = $1", charID).Scan(&str)
+	totalWeight, _ := inventoryWeight(characterInventory(charID))
+	size := characterSize(charID)
+	status, speedPenalty := game.Encumbrance(totalWeight, str, size)
+	return map[string]interface{}{
+		"total_weight":      totalWeight,
+		"carrying_capacity": game.CarryingCapacity(str, size),
+		"status":            status,
+		"speed_penalty":     speedPenalty,
+	}
+}
+
+// campaignShops lists a campaign's shops with their stock.
+func campaignShops(campaignID int) []map[string]interface{} {
+	shops := []map[string]interface{}{}
+	rows, err := db.Query("SELECT id, name, COALESCE(description, ''), created_at FROM shops WHERE lobby_id = $1 ORDER BY id", campaignID)
+	if err != nil {
+		return shops
+	}
+	for rows.Next() {
+		var id int
+		var name, description string
+		var createdAt time.Time
+		if rows.Scan(&id, &name, &description, &createdAt) == nil {
+			shops = append(shops, map[string]interface{}{
+				"shop_id":     id,
+				"name":        name,
+				"description": description,
+				"opened_at":   createdAt.Format(time.RFC3339),
+			})
+		}
+	}
+	rows.Close()
+	for _, shop := range shops {
+		shop["items"] = shopStock(shop["shop_id"].(int))
+	}
+	return shops
+}
+
+func shopStock(shopID int) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	rows, err := db.Query(`
+		SELECT id, kind, name, price_cp, stock, COALESCE(weight, 0) FROM shop_items WHERE shop_id = $1 ORDER BY kind, name
+	`, shopID)
+	if err != nil {
+		return items
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, priceCP int
+		var kind, name string
+		var stock sql.NullInt64
+		var weight float64
+		if rows.Scan(&id, &kind, &name, &priceCP, &stock, &weight) != nil {
+			continue
+		}
+		entry := map[string]interface{}{
+			"item_id":  id,
+			"name":     name,
+			"kind":     kind,
+			"price":    game.FormatCP(priceCP),
+			"price_cp": priceCP,
+			"stock":    nil, // Unlimited
+			"weight":   weight,
+		}
+		if stock.Valid {
+			entry["stock"] = stock.Int64
+		}
+		items = append(items, entry)
+	}
+	return items
+}
+
+// handleCampaignShops godoc
+// @Summary List or open shops in a campaign
+// @Description GET lists the campaign's shops and their stock (stock null is unlimited); the GM and the campaign's players can look. POST (GM only) opens a shop: items [{item, stock, price_gp}] name SRD weapons, armor, consumables or magic items by slug or name, and include ["weapons","armor","consumables"] stocks every priced item in a category. Prices are the PHB's (magic items by rarity, DMG p135) scaled by price_percent; price_gp overrides one item's price and is required for items without a list price. v1.0.93.
+// @Tags Campaigns
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{name=string,description=string,price_percent=integer,items=[]object,include=[]string} false "Shop to open (POST)"
+// @Success 200 {object} map[string]interface{} "Shops"
+// @Failure 400 {object} map[string]interface{} "Invalid shop"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM / not in this campaign"
+// @Router /campaigns/{id}/shops [get]
+// @Router /campaigns/{id}/shops [post]
+func handleCampaignShops(w http.ResponseWriter, r *http.Request, campaignID int) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	if r.Method == "GET" {
+		if !campaignMemberOrGM(campaignID, agentID) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_in_campaign", "message": "Only the campaign's GM and players can browse its shops"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"campaign_id": campaignID,
+			"shops":       campaignShops(campaignID),
+			"usage":       "POST /api/shop/buy {shop_id, item, quantity} or POST /api/shop/sell {shop_id, item, quantity}",
+		})
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dmID int
+	rdb.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", campaignID).Scan(&dmID)
+	if dmID != agentID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the GM can open shops"})
+		return
+	}
+
+	var req struct {
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		PricePercent int    `json:"price_percent"`
+		Items        []struct {
+			Item    string  `json:"item"`
+			Stock   *int    `json:"stock"`
+			PriceGP float64 `json:"price_gp"`
+		} `json:"items"`
+		Include []string `json:"include"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_request",
+			"message": "name required, with items [{item, stock, price_gp}] and/or include [\"weapons\",\"armor\",\"consumables\"]",
+		})
+		return
+	}
+	if req.PricePercent <= 0 {
+		req.PricePercent = 100
+	}
+
+	type stocked struct {
+		item  shopItem
+		stock *int
+	}
+	stock := []stocked{}
+	notFound, unpriced := []string{}, []string{}
+	for _, category := range req.Include {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category != "weapons" && category != "armor" && category != "consumables" {
+			notFound = append(notFound, category)
+			continue
+		}
+		for _, item := range shopCategoryItems(category) {
+			item.PriceCP = item.PriceCP * req.PricePercent / 100
+			stock = append(stock, stocked{item: item})
+		}
+	}
+	for _, entry := range req.Items {
+		item, ok := resolveShopItem(entry.Item)
+		if !ok {
+			notFound = append(notFound, entry.Item)
+			continue
+		}
+		if entry.PriceGP > 0 {
+			item.PriceCP = int(entry.PriceGP * 100)
+		} else if item.PriceCP == 0 {
+			unpriced = append(unpriced, item.Name)
+			continue
+		} else {
+			item.PriceCP = item.PriceCP * req.PricePercent / 100
+		}
+		stock = append(stock, stocked{item: item, stock: entry.Stock})
+	}
+	if len(stock) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":     "empty_shop",
+			"message":   "None of the items could be stocked",
+			"not_found": notFound,
+			"unpriced":  unpriced,
+		})
+		return
+	}
+
+	var shopID int
+	err = rdb.QueryRow("INSERT INTO shops (lobby_id, name, description) VALUES ($1, $2, $3) RETURNING id",
+		campaignID, req.Name, req.Description).Scan(&shopID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error"})
+		return
+	}
+	for _, s := range stock {
+		itemJSON, _ := json.Marshal(s.item.Item)
+		var quantity sql.NullInt64
+		if s.stock != nil {
+			quantity = sql.NullInt64{Int64: int64(max(*s.stock, 0)), Valid: true}
+		}
+		// A later line for the same item (an explicit entry after an include) wins
+		rdb.Exec(`
+			INSERT INTO shop_items (shop_id, kind, slug, name, price_cp, stock, weight, item) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (shop_id, slug) DO UPDATE SET price_cp = EXCLUDED.price_cp, stock = EXCLUDED.stock
+		`, shopID, s.item.Kind, s.item.Slug, s.item.Name, s.item.PriceCP, quantity, s.item.Weight, itemJSON)
+	}
+
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result) VALUES ($1, 'shop_opened', $2, $3)
+	`, campaignID, fmt.Sprintf("%s opens for business", req.Name), req.Description)
+
+	items := shopStock(shopID)
+	response := map[string]interface{}{
+		"success": true,
+		"shop_id": shopID,
+		"name":    req.Name,
+		"items":   items,
+		"message": fmt.Sprintf("%s is open with %d items", req.Name, len(items)),
+	}
+	if len(notFound) > 0 {
+		response["not_found"] = notFound
+	}
+	if len(unpriced) > 0 {
+		response["unpriced"] = unpriced
+		response["hint"] = "Items without a list price need price_gp"
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// shopTrade is the body of a buy or sell.
+type shopTrade struct {
+	CharacterID int    `json:"character_id"`
+	ShopID      int    `json:"shop_id"`
+	Item        string `json:"item"`
+	ItemID      int    `json:"item_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// openShopTrade decodes a buy or sell and resolves the trader and the shop, writing the
+// error response itself when it can't.
+func openShopTrade(w http.ResponseWriter, r *http.Request) (req shopTrade, charID, campaignID int, charName, shopName string, ok bool) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	fail := func(status int, code, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": code, "message": message})
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ShopID == 0 || (req.Item == "" && req.ItemID == 0) {
+		fail(http.StatusBadRequest, "invalid_request", "shop_id and item (or item_id) required, with an optional quantity")
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		fail(http.StatusBadRequest, "invalid_request", "quantity must be positive")
+		return
+	}
+
+	charID, campaignID, charName, found := agentCharacter(agentID, req.CharacterID)
+	if !found || campaignID == 0 {
+		fail(http.StatusBadRequest, "character_not_found", "You have no character in a campaign to trade with")
+		return
+	}
+	var shopCampaign int
+	if rdb.QueryRow("SELECT lobby_id, name FROM shops WHERE id = $1", req.ShopID).Scan(&shopCampaign, &shopName) != nil || shopCampaign != campaignID {
+		fail(http.StatusNotFound, "shop_not_found", "No such shop in your campaign; GET /api/campaigns/{id}/shops lists them")
+		return
+	}
+	var inCombat bool
+	rdb.QueryRow("SELECT COALESCE(active, false) FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&inCombat)
+	if inCombat {
+		fail(http.StatusBadRequest, "in_combat", "The shopkeeper won't trade in the middle of a fight")
+		return
+	}
+	return req, charID, campaignID, charName, shopName, true
+}
+
+// handleShopBuy godoc
+// @Summary Buy from a shop
+// @Description Buys quantity (default 1) of an item (by name or item_id) from a shop in your character's campaign. The price is paid from any mix of cp/sp/ep/gp/pp; if the coins don't add up exactly, one larger coin is broken and change comes back in gp/sp/cp. Returns the purse and the character's encumbrance after the purchase. v1.0.93.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{shop_id=integer,item=string,item_id=integer,quantity=integer,character_id=integer} true "Purchase"
+// @Success 200 {object} map[string]interface{} "Bought"
+// @Failure 400 {object} map[string]interface{} "Can't afford it / out of stock"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Shop or item not found"
+// @Router /shop/buy [post]
+func handleShopBuy(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	req, charID, campaignID, charName, shopName, ok := openShopTrade(w, r)
+	if !ok {
+		return
+	}
+
+	var itemID, priceCP int
+	var name string
+	var itemJSON []byte
+	err := rdb.QueryRow(`
+		SELECT id, name, price_cp, COALESCE(item, '{}') FROM shop_items
+		WHERE shop_id = $1 AND (id = $2 OR LOWER(name) = LOWER($3) OR slug = $4) LIMIT 1
+	`, req.ShopID, req.ItemID, strings.TrimSpace(req.Item), strings.ToLower(strings.ReplaceAll(strings.TrimSpace(req.Item), " ", "-"))).
+		Scan(&itemID, &name, &priceCP, &itemJSON)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "item_not_found", "message": fmt.Sprintf("%s doesn't sell %s", shopName, req.Item)})
+		return
+	}
+
+	release, _, ok := acquireActionLock(w, r, charID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	cost := priceCP * req.Quantity
+	purse := characterPurse(charID)
+	paid, ok := purse.Pay(cost)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "insufficient_funds",
+			"message": fmt.Sprintf("%d %s cost %s; %s has %s", req.Quantity, name, game.FormatCP(cost), charName, game.FormatCP(purse.TotalCP())),
+			"purse":   purse,
+		})
+		return
+	}
+	res, err := rdb.Exec("UPDATE shop_items SET stock = stock - $1 WHERE id = $2 AND (stock IS NULL OR stock >= $1)", req.Quantity, itemID)
+	if err == nil {
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = sql.ErrNoRows
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "out_of_stock", "message": fmt.Sprintf("%s doesn't have %d %s", shopName, req.Quantity, name)})
+		return
+	}
+
+	item := map[string]interface{}{}
+	json.Unmarshal(itemJSON, &item)
+	item["name"] = name
+	item["quantity"] = req.Quantity
+	saveCharacterPurse(charID, paid)
+	saveCharacterInventory(charID, game.AddInventoryItem(characterInventory(charID), item))
+
+	bought := fmt.Sprintf("%s x%d", name, req.Quantity)
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'shop_buy', $3, $4)
+	`, campaignID, charID, fmt.Sprintf("%s buys %s at %s", charName, bought, shopName), game.FormatCP(cost))
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"bought":      bought,
+		"cost":        game.FormatCP(cost),
+		"purse":       paid,
+		"encumbrance": encumbranceSummary(charID),
+	})
+}
+
+// handleShopSell godoc
+// @Summary Sell to a shop
+// @Description Sells quantity (default 1) of an item from your character's inventory to a shop in the campaign for half its price (PHB p144): the shop's own price if it stocks the item, otherwise the list price. Items without a price find no buyer. The last copy of an equipped item must be unequipped first. Coins come back as gp/sp/cp. v1.0.93.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{shop_id=integer,item=string,quantity=integer,character_id=integer} true "Sale"
+// @Success 200 {object} map[string]interface{} "Sold"
+// @Failure 400 {object} map[string]interface{} "Not in inventory / no buyer"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Shop not found"
+// @Router /shop/sell [post]
+func handleShopSell(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	req, charID, campaignID, charName, shopName, ok := openShopTrade(w, r)
+	if !ok {
+		return
+	}
+	fail := func(code, message string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": code, "message": message})
+	}
+
+	release, _, ok := acquireActionLock(w, r, charID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	rest, taken, found := game.TakeInventoryItem(characterInventory(charID), req.Item, req.Quantity)
+	if !found {
+		fail("item_not_found", fmt.Sprintf("%s doesn't have %d %s", charName, req.Quantity, req.Item))
+		return
+	}
+	name, _ := taken["name"].(string)
+	if game.FindInventoryItem(rest, name) < 0 && itemEquipped(charID, name) {
+		fail("item_equipped", fmt.Sprintf("%s is equipped; unequip it before selling it", name))
+		return
+	}
+
+	var shopItemID, priceCP int
+	var stock sql.NullInt64
+	if rdb.QueryRow("SELECT id, price_cp, stock FROM shop_items WHERE shop_id = $1 AND LOWER(name) = LOWER($2)",
+		req.ShopID, name).Scan(&shopItemID, &priceCP, &stock) != nil {
+		listed, _ := resolveShopItem(name)
+		priceCP = listed.PriceCP
+	}
+	if priceCP == 0 {
+		fail("no_buyer", fmt.Sprintf("%s has no use for %s", shopName, name))
+		return
+	}
+	earned := game.SellPrice(priceCP) * req.Quantity
+
+	saveCharacterInventory(charID, rest)
+	purse := characterPurse(charID).Receive(earned)
+	saveCharacterPurse(charID, purse)
+	if stock.Valid {
+		rdb.Exec("UPDATE shop_items SET stock = stock + $1 WHERE id = $2", req.Quantity, shopItemID)
+	}
+
+	sold := fmt.Sprintf("%s x%d", name, req.Quantity)
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'shop_sell', $3, $4)
+	`, campaignID, charID, fmt.Sprintf("%s sells %s to %s", charName, sold, shopName), game.FormatCP(earned))
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"sold":        sold,
+		"earned":      game.FormatCP(earned),
+		"purse":       purse,
+		"encumbrance": encumbranceSummary(charID),
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Short rest hit dice (v1.0.121): every short rest, and every continuation of one, goes to
+// the campaign feed with each die's roll and CON modifier. characters.short_rest_dice counts
+// the dice spent in the current rest. Combat starting within the hour after a rest cuts it
+// short (short_rest_interrupted): it can't be continued, and the dice already spent stay
+// spent.
+
+// hitDiceWord is "hit die" or "hit dice".
+func hitDiceWord(n int) string {
+	if n == 1 {
+		return "hit die"
+	}
+	return "hit dice"
+}
+
+// logShortRest posts a short rest's hit dice to the campaign feed.
+func logShortRest(campaignID, charID int, name string, rolls []game.HitDieRoll, healed, hp, maxHP, remaining int, continued bool) {
+	if campaignID == 0 {
+		return
+	}
+	var description string
+	switch {
+	case continued:
+		description = fmt.Sprintf("%s spends %d more %s in the short rest", name, len(rolls), hitDiceWord(len(rolls)))
+	case len(rolls) == 0:
+		description = fmt.Sprintf("%s takes a short rest without spending hit dice", name)
+	default:
+		description = fmt.Sprintf("%s takes a short rest and spends %d %s", name, len(rolls), hitDiceWord(len(rolls)))
+	}
+	if len(rolls) > 0 {
+		notes := []string{}
+		for _, note := range hitDiceRollNotes(rolls) {
+			notes = append(notes, note["note"].(string))
+		}
+		description += fmt.Sprintf(" (%s), healing %d HP", strings.Join(notes, "; "), healed)
+	}
+	result := fmt.Sprintf("HP %d/%d, %d %s left", hp, maxHP, remaining, hitDiceWord(remaining))
+	db.Exec(`INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'short_rest', $3, $4)`,
+		campaignID, charID, description, result)
+}
+
+// interruptShortRest marks a character's short rest as cut short and posts it to the feed.
+// Nothing spent in it is given back.
+func interruptShortRest(campaignID, charID int, name string, diceSpent int, cause string) {
+	db.Exec("UPDATE characters SET short_rest_interrupted = true WHERE id = $1", charID)
+	if campaignID == 0 {
+		return
+	}
+	description := fmt.Sprintf("%s's short rest is cut short by %s", name, cause)
+	if diceSpent > 0 {
+		description += fmt.Sprintf("; the %d %s already spent stay spent", diceSpent, hitDiceWord(diceSpent))
+	}
+	db.Exec(`INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'short_rest_interrupted', $3, '')`,
+		campaignID, charID, description)
+}
+
+// interruptShortRests cuts short the short rests in a campaign that could still be
+// continued, when combat starts.
+func interruptShortRests(campaignID int) {
+	rows, err := db.Query(`
+		SELECT id, name, COALESCE(short_rest_dice, 0) FROM characters
+		WHERE lobby_id = $1 AND last_short_rest_game IS NOT NULL AND last_short_rest_game + $2 >= $3
+			AND NOT COALESCE(short_rest_interrupted, false)
+	`, campaignID, game.MinutesPerHour, campaignClock(campaignID))
+	if err != nil {
+		return
+	}
+	type resting struct {
+		id, dice int
+		name     string
+	}
+	var interrupted []resting
+	for rows.Next() {
+		var c resting
+		if rows.Scan(&c.id, &c.name, &c.dice) == nil {
+			interrupted = append(interrupted, c)
+		}
+	}
+	rows.Close()
+	for _, c := range interrupted {
+		interruptShortRest(campaignID, c.id, c.name, c.dice, "combat")
+	}
+}
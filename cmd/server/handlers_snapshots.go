@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/internal/audit"
+)
+
+// Character snapshots (v1.0.120): whenever a request changes a character (damage, level-up,
+// items, GM tools...), withCharacterChanges saves the character as it was before in
+// character_snapshots, with the fields the request changed. Owners and GMs read them at GET
+// /api/characters/{id}/history, and the GM rolls a character back to one with POST
+// /api/gm/restore-character-snapshot, which is itself snapshotted and so can be undone.
+
+// snapshotsKept is how many snapshots are kept per character; older ones are dropped.
+const snapshotsKept = 100
+
+// snapshotClassesField is the snapshot field holding the character's character_classes rows.
+const snapshotClassesField = "character_classes"
+
+// snapshotClass is a character_classes row in a snapshot.
+type snapshotClass struct {
+	Class        string `json:"class"`
+	Level        int    `json:"level"`
+	HitDiceSpent int    `json:"hit_dice_spent"`
+	Position     int    `json:"position"`
+}
+
+// loadCharacterState is a character's row with its class levels under snapshotClassesField,
+// as a JSON list.
+func loadCharacterState(ctx context.Context, charID int) (map[string]interface{}, error) {
+	state, err := loadCharacterRow(ctx, charID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT class, level, hit_dice_spent, position FROM character_classes
+		WHERE character_id = $1 ORDER BY position, class
+	`, charID)
+	if err != nil {
+		return state, nil // No character_classes table (SQLite tests)
+	}
+	defer rows.Close()
+	classes := []snapshotClass{}
+	for rows.Next() {
+		var c snapshotClass
+		if rows.Scan(&c.Class, &c.Level, &c.HitDiceSpent, &c.Position) == nil {
+			classes = append(classes, c)
+		}
+	}
+	classesJSON, _ := json.Marshal(classes)
+	state[snapshotClassesField] = string(classesJSON)
+	return state, nil
+}
+
+// saveCharacterSnapshot saves a character's state from before a request, and the changes it
+// made, dropping the oldest beyond snapshotsKept.
+func saveCharacterSnapshot(ctx context.Context, charID, agentID int, endpoint string, state map[string]interface{}, changes []audit.Change) {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	changesJSON, _ := json.Marshal(changes)
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO character_snapshots (character_id, agent_id, endpoint, state, changes)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5)
+	`, charID, agentID, endpoint, string(stateJSON), string(changesJSON))
+	if err != nil {
+		return
+	}
+	db.ExecContext(ctx, `
+		DELETE FROM character_snapshots WHERE character_id = $1 AND id NOT IN (
+			SELECT id FROM character_snapshots WHERE character_id = $1 ORDER BY id DESC LIMIT $2
+		)
+	`, charID, snapshotsKept)
+}
+
+// handleCharacterHistory godoc
+// @Summary Character history
+// @Description The character's snapshots, newest first: each is how the character was before a request changed it, with who made the request, the endpoint and each field it changed (before and after). snapshot_id returns one snapshot with the whole saved character. The newest 100 are kept. Page with limit (default 20, max 100) and before (a snapshot ID). Owner, campaign GM or moderator. A GM restores one with POST /api/gm/restore-character-snapshot. v1.0.120.
+// @Tags Characters
+// @Produce json
+// @Param id path int true "Character ID"
+// @Param snapshot_id query int false "One snapshot, with the saved character"
+// @Param limit query int false "Snapshots per page (default 20, max 100)"
+// @Param before query int false "Snapshots older than this ID"
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Snapshots"
+// @Failure 403 {object} map[string]interface{} "Not your character"
+// @Failure 404 {object} map[string]interface{} "Character or snapshot not found"
+// @Router /characters/{id}/history [get]
+func handleCharacterHistory(w http.ResponseWriter, r *http.Request, charID int) {
+	rdb := dbFor(r)
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var ownerID, dmID int
+	err = rdb.QueryRow(`
+		SELECT c.agent_id, COALESCE(l.dm_id, 0)
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id
+		WHERE c.id = $1
+	`, charID).Scan(&ownerID, &dmID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_found"})
+		return
+	}
+	if agentID != ownerID && agentID != dmID && !isModerator(agentID) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "forbidden",
+			"message": "Only the character's owner, the campaign GM or a moderator can read its history",
+		})
+		return
+	}
+
+	q := r.URL.Query()
+	snapshotID, _ := strconv.Atoi(q.Get("snapshot_id"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > snapshotsKept {
+		limit = snapshotsKept
+	}
+	if snapshotID != 0 {
+		limit = 1
+	}
+	before, _ := strconv.Atoi(q.Get("before"))
+
+	rows, err := rdb.Query(`
+		SELECT s.id, COALESCE(s.agent_id, 0), COALESCE(a.name, ''), s.endpoint, s.changes, s.state, s.created_at
+		FROM character_snapshots s LEFT JOIN agents a ON a.id = s.agent_id
+		WHERE s.character_id = $1 AND ($2 = 0 OR s.id = $2) AND ($3 = 0 OR s.id < $3)
+		ORDER BY s.id DESC LIMIT $4
+	`, charID, snapshotID, before, limit)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+	snapshots := []map[string]interface{}{}
+	var last int
+	for rows.Next() {
+		var id, byID int
+		var byName, endpoint string
+		var changesJSON, stateJSON []byte
+		var created time.Time
+		if rows.Scan(&id, &byID, &byName, &endpoint, &changesJSON, &stateJSON, &created) != nil {
+			continue
+		}
+		changes := []audit.Change{}
+		json.Unmarshal(changesJSON, &changes)
+		snapshot := map[string]interface{}{
+			"id": id, "agent_id": byID, "agent_name": byName, "endpoint": endpoint,
+			"changes": changes, "summary": audit.Summary(changes), "created_at": created.Format(time.RFC3339),
+		}
+		if snapshotID != 0 {
+			snapshot["character"] = json.RawMessage(stateJSON)
+		}
+		snapshots = append(snapshots, snapshot)
+		last = id
+	}
+	if snapshotID != 0 {
+		if len(snapshots) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "snapshot_not_found"})
+			return
+		}
+		json.NewEncoder(w).Encode(snapshots[0])
+		return
+	}
+	resp := map[string]interface{}{"character_id": charID, "snapshots": snapshots, "count": len(snapshots)}
+	if len(snapshots) == limit {
+		resp["next_before"] = last
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleGMRestoreCharacterSnapshot godoc
+// @Summary Restore a character snapshot
+// @Description Rolls a character back to a snapshot from GET /api/characters/{id}/history: every saved field (HP, XP, level and class levels, inventory, money, spell slots, conditions...) is written back; the owner and campaign are left alone. The character as it was before the restore is snapshotted too, so a restore can be undone the same way. Logged to the campaign feed. v1.0.120.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param request body object{character_id=int,snapshot_id=int} true "Character and snapshot"
+// @Success 200 {object} map[string]interface{} "Restored fields"
+// @Failure 400 {object} map[string]interface{} "Missing fields"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Failure 404 {object} map[string]interface{} "Character or snapshot not found"
+// @Router /gm/restore-character-snapshot [post]
+func handleGMRestoreCharacterSnapshot(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var req struct {
+		CharacterID int `json:"character_id"`
+		SnapshotID  int `json:"snapshot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CharacterID == 0 || req.SnapshotID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_request", "message": "character_id and snapshot_id required"})
+		return
+	}
+
+	var charName string
+	var lobbyID, dmID int
+	err = rdb.QueryRow(`
+		SELECT c.name, COALESCE(c.lobby_id, 0), COALESCE(l.dm_id, 0)
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id
+		WHERE c.id = $1
+	`, req.CharacterID).Scan(&charName, &lobbyID, &dmID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_found"})
+		return
+	}
+	if dmID != agentID {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of this character's campaign"})
+		return
+	}
+
+	var stateJSON []byte
+	var taken time.Time
+	err = rdb.QueryRow(`SELECT state, created_at FROM character_snapshots WHERE id = $1 AND character_id = $2`,
+		req.SnapshotID, req.CharacterID).Scan(&stateJSON, &taken)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "snapshot_not_found", "message": "No such snapshot for this character; see GET /api/characters/{id}/history"})
+		return
+	}
+	// Numbers stay as written, so integer columns get integers back.
+	dec := json.NewDecoder(strings.NewReader(string(stateJSON)))
+	dec.UseNumber()
+	var state map[string]interface{}
+	if err := dec.Decode(&state); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "snapshot_unreadable", "message": err.Error()})
+		return
+	}
+	current, err := loadCharacterState(r.Context(), req.CharacterID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	fields := audit.Restorable(state, current)
+	var sets []string
+	var args []interface{}
+	for _, f := range fields {
+		if f == snapshotClassesField {
+			continue
+		}
+		v := state[f]
+		if n, ok := v.(json.Number); ok {
+			v = n.String()
+		}
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s = $%d", f, len(args)))
+	}
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+	if len(sets) > 0 {
+		args = append(args, req.CharacterID)
+		query := fmt.Sprintf("UPDATE characters SET %s WHERE id = $%d", strings.Join(sets, ", "), len(args))
+		if _, err := tx.Exec(query, args...); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "restore_failed", "message": err.Error()})
+			return
+		}
+	}
+	if classesJSON, ok := state[snapshotClassesField].(string); ok {
+		var classes []snapshotClass
+		json.Unmarshal([]byte(classesJSON), &classes)
+		tx.Exec("DELETE FROM character_classes WHERE character_id = $1", req.CharacterID)
+		for _, c := range classes {
+			if _, err := tx.Exec(`INSERT INTO character_classes (character_id, class, level, hit_dice_spent, position) VALUES ($1, $2, $3, $4, $5)`,
+				req.CharacterID, c.Class, c.Level, c.HitDiceSpent, c.Position); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "restore_failed", "message": err.Error()})
+				return
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "restore_failed", "message": err.Error()})
+		return
+	}
+
+	restored, _ := loadCharacterState(r.Context(), req.CharacterID)
+	changes := audit.Diff(current, restored)
+	if lobbyID != 0 {
+		rdb.Exec(`INSERT INTO actions (lobby_id, character_id, action_type, description, result) VALUES ($1, $2, 'gm_restore', $3, $4)`,
+			lobbyID, req.CharacterID,
+			fmt.Sprintf("The GM restores %s to how they were at %s", charName, taken.UTC().Format("2006-01-02 15:04 MST")),
+			audit.Summary(changes))
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"character_id": req.CharacterID,
+		"snapshot_id":  req.SnapshotID,
+		"snapshot_at":  taken.Format(time.RFC3339),
+		"changes":      changes,
+		"summary":      audit.Summary(changes),
+		"hint":         "The character as it was before this restore is now the newest snapshot in GET /api/characters/{id}/history, if you need to undo it.",
+	})
+}
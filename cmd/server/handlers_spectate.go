@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/internal/spectate"
+)
+
+// Spectator feed (v1.0.113): GET /api/campaigns/{id}/spectate pages (?since=cursor) or
+// streams (?stream=true) the campaign feed with what only the GM should see stripped out
+// (internal/spectate). Like the rest of the spectator view it needs no account.
+
+// spectatePollInterval is how often an open spectator stream checks for new events.
+const spectatePollInterval = 5 * time.Second
+
+// Page sizes for the spectator feed.
+const (
+	spectateDefaultLimit = 50
+	spectateMaxLimit     = 200
+)
+
+// isSpectatorStream reports whether a request is a long-lived spectator stream, which the
+// request deadline mustn't cut off.
+func isSpectatorStream(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "true" && strings.HasSuffix(r.URL.Path, "/spectate")
+}
+
+// loadSpectatorEvents reads up to n actions and n messages after a cursor, oldest first, or
+// with tail the newest n of each.
+func loadSpectatorEvents(ctx context.Context, campaignID int, after spectate.Cursor, n int, tail bool) ([]spectate.Event, []spectate.Event, error) {
+	order := "ASC"
+	if tail {
+		order = "DESC"
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT a.id, COALESCE(a.action_type, ''), COALESCE(c.name, ''), COALESCE(a.description, ''), COALESCE(a.result, ''), a.created_at
+		FROM actions a LEFT JOIN characters c ON a.character_id = c.id
+		WHERE a.lobby_id = $1 AND a.id > $2 ORDER BY a.id `+order+` LIMIT $3
+	`, campaignID, after.ActionID, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions := []spectate.Event{}
+	for rows.Next() {
+		e := spectate.Event{Kind: spectate.KindAction}
+		if rows.Scan(&e.ID, &e.Type, &e.Actor, &e.Description, &e.Result, &e.Time) == nil {
+			actions = append(actions, e)
+		}
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT id, COALESCE(agent_name, ''), COALESCE(message, ''), created_at
+		FROM campaign_messages WHERE lobby_id = $1 AND id > $2 ORDER BY id `+order+` LIMIT $3
+	`, campaignID, after.MessageID, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages := []spectate.Event{}
+	for rows.Next() {
+		e := spectate.Event{Kind: spectate.KindMessage, Type: spectate.KindMessage}
+		if rows.Scan(&e.ID, &e.Actor, &e.Description, &e.Time) == nil {
+			messages = append(messages, e)
+		}
+	}
+	rows.Close()
+	return actions, messages, nil
+}
+
+// spectatorFeedTail is the newest part of the feed and the cursor to follow it from.
+func spectatorFeedTail(ctx context.Context, campaignID, limit int) ([]spectate.Event, spectate.Cursor) {
+	actions, messages, err := loadSpectatorEvents(ctx, campaignID, spectate.Cursor{}, limit, true)
+	if err != nil {
+		return []spectate.Event{}, spectate.Cursor{}
+	}
+	return spectate.Tail(actions, messages, limit)
+}
+
+// spectateLimit reads ?limit=, defaulting to 50 and capped at 200.
+func spectateLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return spectateDefaultLimit
+	}
+	return min(limit, spectateMaxLimit)
+}
+
+// handleSpectatorFeedPage serves the page of the feed after ?since=.
+func handleSpectatorFeedPage(w http.ResponseWriter, r *http.Request, campaignID int) {
+	after, err := spectate.ParseCursor(r.URL.Query().Get("since"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_cursor", "message": err.Error()})
+		return
+	}
+	limit := spectateLimit(r)
+	actions, messages, err := loadSpectatorEvents(r.Context(), campaignID, after, limit*2, false)
+	if err != nil {
+		if dbTimedOut(err) {
+			writeDBTimeout(w)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	events, next, more := spectate.Page(actions, messages, after, limit)
+	more = more || len(actions) == limit*2 || len(messages) == limit*2
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"campaign_id": campaignID,
+		"events":      events,
+		"next_cursor": next.String(),
+		"has_more":    more,
+	})
+}
+
+// handleSpectatorStream pushes new feed events as Server-Sent Events. It starts after
+// ?since= or the Last-Event-ID a reconnecting client sends, or else at the newest event.
+func handleSpectatorStream(w http.ResponseWriter, r *http.Request, campaignID int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "streaming_unsupported",
+			"message": "This server can't stream responses. Page the feed with ?since= instead.",
+		})
+		return
+	}
+	from := r.URL.Query().Get("since")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		from = id
+	}
+	after, err := spectate.ParseCursor(from)
+	if from == "" {
+		_, after = spectatorFeedTail(r.Context(), campaignID, 1)
+	} else if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_cursor", "message": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	writeStreamEvent(w, streamEvent{Event: "connected", Data: map[string]interface{}{
+		"campaign_id": campaignID, "cursor": after.String(),
+	}})
+	flusher.Flush()
+
+	poll := time.NewTicker(spectatePollInterval)
+	defer poll.Stop()
+	idle := time.Now()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-poll.C:
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout())
+		actions, messages, err := loadSpectatorEvents(ctx, campaignID, after, spectateMaxLimit, false)
+		cancel()
+		if err != nil {
+			continue // Try again next time
+		}
+		events, next, _ := spectate.Page(actions, messages, after, spectateMaxLimit)
+		for _, e := range events {
+			after = after.Advance(e)
+			fmt.Fprintf(w, "id: %s\n", after)
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "event: feed\ndata: %s\n\n", data)
+		}
+		after = next
+		if len(events) > 0 {
+			idle = time.Now()
+			flusher.Flush()
+		} else if time.Since(idle) >= streamKeepalive {
+			idle = time.Now()
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
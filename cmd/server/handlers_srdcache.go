@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/agentrpg/agentrpg/internal/universe"
+)
+
+// SRD lookup cache (v1.0.118): armor, monster defenses and magic items are read on every
+// attack, damage roll and AC calculation but only change when the SRD is seeded. They live
+// in an internal/universe Catalog loaded with the other SRD maps at startup, and every seed
+// invalidates it. If a table can't be loaded the lookups query the database as before.
+
+// srdMonsterDefenses is what a monster resists, as comma-separated SRD lists.
+type srdMonsterDefenses = universe.MonsterDefenses
+
+// srdMagicItem is a magic_items row.
+type srdMagicItem = universe.MagicItem
+
+// srdCatalog is the cached SRD every rules lookup reads.
+var srdCatalog = universe.NewCatalog(sqlUniverseStore{})
+
+// sqlUniverseStore reads the SRD tables for universe.Catalog.
+type sqlUniverseStore struct{}
+
+func (sqlUniverseStore) AllArmor(ctx context.Context) (map[string]universe.Armor, error) {
+	rows, err := db.QueryContext(ctx, `SELECT slug, ac, type, stealth_disadvantage, COALESCE(str_req, 0) FROM armor`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	m := map[string]universe.Armor{}
+	for rows.Next() {
+		var slug string
+		var a universe.Armor
+		if rows.Scan(&slug, &a.AC, &a.Type, &a.StealthDisadvantage, &a.StrengthRequirement) == nil {
+			m[slug] = a
+		}
+	}
+	return m, rows.Err()
+}
+
+func (sqlUniverseStore) Armor(ctx context.Context, slug string) (universe.Armor, error) {
+	var a universe.Armor
+	err := db.QueryRowContext(ctx, `SELECT ac, type, stealth_disadvantage, COALESCE(str_req, 0) FROM armor WHERE slug = $1`, slug).
+		Scan(&a.AC, &a.Type, &a.StealthDisadvantage, &a.StrengthRequirement)
+	return a, universeErr(err)
+}
+
+func (sqlUniverseStore) AllMonsterDefenses(ctx context.Context) (map[string]universe.MonsterDefenses, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT slug, COALESCE(damage_resistances, ''), COALESCE(damage_immunities, ''),
+			COALESCE(damage_vulnerabilities, ''), COALESCE(condition_immunities, '')
+		FROM monsters
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	m := map[string]universe.MonsterDefenses{}
+	for rows.Next() {
+		var slug string
+		var d universe.MonsterDefenses
+		if rows.Scan(&slug, &d.Resistances, &d.Immunities, &d.Vulnerabilities, &d.ConditionImmunities) == nil {
+			m[slug] = d
+		}
+	}
+	return m, rows.Err()
+}
+
+func (sqlUniverseStore) MonsterDefenses(ctx context.Context, slug string) (universe.MonsterDefenses, error) {
+	var d universe.MonsterDefenses
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(damage_resistances, ''), COALESCE(damage_immunities, ''),
+			COALESCE(damage_vulnerabilities, ''), COALESCE(condition_immunities, '')
+		FROM monsters WHERE slug = $1
+	`, slug).Scan(&d.Resistances, &d.Immunities, &d.Vulnerabilities, &d.ConditionImmunities)
+	return d, universeErr(err)
+}
+
+func (sqlUniverseStore) AllMagicItems(ctx context.Context) (map[string]universe.MagicItem, error) {
+	rows, err := db.QueryContext(ctx, `SELECT slug, name, COALESCE(rarity, ''), COALESCE(type, ''), COALESCE(attunement, false), COALESCE(description, '') FROM magic_items`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	m := map[string]universe.MagicItem{}
+	for rows.Next() {
+		var slug string
+		var it universe.MagicItem
+		if rows.Scan(&slug, &it.Name, &it.Rarity, &it.Type, &it.Attunement, &it.Description) == nil {
+			m[slug] = it
+		}
+	}
+	return m, rows.Err()
+}
+
+func (sqlUniverseStore) MagicItem(ctx context.Context, slug string) (universe.MagicItem, error) {
+	var it universe.MagicItem
+	err := db.QueryRowContext(ctx, `SELECT name, COALESCE(rarity, ''), COALESCE(type, ''), COALESCE(attunement, false), COALESCE(description, '') FROM magic_items WHERE slug = $1`, slug).
+		Scan(&it.Name, &it.Rarity, &it.Type, &it.Attunement, &it.Description)
+	return it, universeErr(err)
+}
+
+// universeErr maps a missing row to universe.ErrNotFound.
+func universeErr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return universe.ErrNotFound
+	}
+	return err
+}
+
+// loadSRDLookups fills the lookup tables, called with loadSRDFromDB at startup.
+func loadSRDLookups() {
+	if err := srdCatalog.Reload(); err != nil {
+		log.Printf("SRD cache not fully loaded: %v", err)
+	}
+	armor, monsters, items := srdCatalog.Len()
+	log.Printf("Cached %d armor, %d monsters and %d magic items", armor, monsters, items)
+}
+
+// invalidateSRDLookups drops the lookup tables after a seed; each reloads on its next use.
+func invalidateSRDLookups() {
+	srdCatalog.Invalidate()
+}
+
+// monsterDefenses returns a monster's resistances, immunities and vulnerabilities by slug.
+func monsterDefenses(slug string) (srdMonsterDefenses, bool) {
+	if slug == "" || db == nil {
+		return srdMonsterDefenses{}, false
+	}
+	d, err := srdCatalog.MonsterDefenses(context.Background(), slug)
+	return d, err == nil
+}
+
+// magicItem returns a magic item by slug.
+func magicItem(slug string) (srdMagicItem, bool) {
+	if slug == "" || db == nil {
+		return srdMagicItem{}, false
+	}
+	it, err := srdCatalog.MagicItem(context.Background(), slug)
+	return it, err == nil
+}
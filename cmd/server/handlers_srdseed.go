@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentrpg/agentrpg/internal/srdfetch"
+)
+
+// SRD seeding (v1.0.116): the seeders fetch from the 5e API through internal/srdfetch,
+// which asks for details several at a time and keeps every raw response in srd_raw. At
+// startup copies younger than SRD_REFRESH_HOURS are used without asking the API at all, and
+// rows are only upserted for documents whose content hash changed. A document's new copy
+// and hash are only written to srd_raw after its row is upserted, so a failed upsert is
+// retried on the next refresh. POST /api/admin/seed?force=true refetches and upserts
+// everything, and every refresh reloads the in-memory SRD maps.
+
+// srdAPIBase is the 5e API the SRD is seeded from.
+const srdAPIBase = "https://www.dnd5eapi.co"
+
+// Defaults for SRD_FETCH_WORKERS and SRD_REFRESH_HOURS.
+const (
+	srdDefaultWorkers      = 8
+	srdDefaultRefreshHours = 168
+)
+
+// srdSeedMu keeps two refreshes from running at once.
+var srdSeedMu sync.Mutex
+
+// sqlSRDCache keeps raw API responses in srd_raw.
+type sqlSRDCache struct{}
+
+func (sqlSRDCache) Load(ctx context.Context, path string) (srdfetch.Entry, bool) {
+	var e srdfetch.Entry
+	var body string
+	err := db.QueryRowContext(ctx, "SELECT body, hash, fetched_at FROM srd_raw WHERE path = $1", path).Scan(&body, &e.Hash, &e.FetchedAt)
+	if err != nil {
+		return e, false
+	}
+	e.Body = []byte(body)
+	return e, true
+}
+
+func (sqlSRDCache) Store(ctx context.Context, path string, e srdfetch.Entry) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO srd_raw (path, body, hash, fetched_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (path) DO UPDATE SET body = EXCLUDED.body, hash = EXCLUDED.hash, fetched_at = EXCLUDED.fetched_at
+	`, path, string(e.Body), e.Hash, e.FetchedAt)
+	return err
+}
+
+// newSRDFetcher returns a fetcher configured from SRD_FETCH_WORKERS and SRD_REFRESH_HOURS.
+// force asks the API for everything, however fresh the cached copies are.
+func newSRDFetcher(force bool) *srdfetch.Fetcher {
+	workers, err := strconv.Atoi(os.Getenv("SRD_FETCH_WORKERS"))
+	if err != nil || workers <= 0 {
+		workers = srdDefaultWorkers
+	}
+	hours, err := strconv.Atoi(os.Getenv("SRD_REFRESH_HOURS"))
+	if err != nil || hours < 0 {
+		hours = srdDefaultRefreshHours
+	}
+	return &srdfetch.Fetcher{
+		BaseURL: srdAPIBase,
+		Get:     srdfetch.HTTPGetter(&http.Client{Timeout: 30 * time.Second}),
+		Cache:   sqlSRDCache{},
+		Workers: workers,
+		MaxAge:  time.Duration(hours) * time.Hour,
+		Force:   force,
+	}
+}
+
+// srdItem is an entry of an API list with its detail document.
+type srdItem struct {
+	Ref    map[string]interface{} // The list entry: index, name, url
+	Detail map[string]interface{}
+	doc    srdfetch.Doc // The detail document, for commitSRDItem
+}
+
+// commitSRDItem writes an item's detail document to srd_raw once its row is saved. err is
+// the upsert's error; when it is set the previous copy stays cached, so the next refresh
+// sees the document as changed and upserts it again.
+func commitSRDItem(f *srdfetch.Fetcher, item srdItem, err error) {
+	if err != nil {
+		return
+	}
+	if err := f.Commit(context.Background(), item.doc); err != nil {
+		log.Printf("Failed to cache %s: %v", item.doc.Path, err)
+	}
+}
+
+// fetchSRDList fetches an API list and its entries' details, and returns the entries to
+// upsert: those whose detail changed, or all of them when force is set or table is empty.
+// listKey is the list's field ("results", or "equipment" for equipment categories); keep, if
+// set, filters entries by URL. total is how many entries the list has.
+func fetchSRDList(f *srdfetch.Fetcher, listPath, listKey, table string, force bool, keep func(url string) bool) (items []srdItem, total int, err error) {
+	ctx := context.Background()
+	list := f.Fetch(ctx, listPath)
+	if list.Err != nil {
+		return nil, 0, list.Err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(list.Body, &data); err != nil {
+		return nil, 0, err
+	}
+	entries, _ := data[listKey].([]interface{})
+	if err := f.Commit(ctx, list); err != nil {
+		log.Printf("Failed to cache %s: %v", listPath, err)
+	}
+
+	var refs []map[string]interface{}
+	var paths []string
+	for _, e := range entries {
+		ref, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		url, _ := ref["url"].(string)
+		if url == "" || (keep != nil && !keep(url)) {
+			continue
+		}
+		refs = append(refs, ref)
+		paths = append(paths, url)
+	}
+
+	var rows int
+	db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&rows)
+	all := force || rows == 0
+	for i, doc := range f.FetchAll(ctx, paths) {
+		if doc.Err != nil {
+			log.Printf("Failed to fetch %s: %v", doc.Path, doc.Err)
+			continue
+		}
+		if !doc.Changed && !all {
+			// Nothing to upsert; a refetched copy only records when it was checked
+			if err := f.Commit(ctx, doc); err != nil {
+				log.Printf("Failed to cache %s: %v", doc.Path, err)
+			}
+			continue
+		}
+		var detail map[string]interface{}
+		if json.Unmarshal(doc.Body, &detail) != nil {
+			continue
+		}
+		items = append(items, srdItem{Ref: refs[i], Detail: detail, doc: doc})
+	}
+	return items, len(paths), nil
+}
+
+// refreshSRD runs the seeders unless a refresh is already running, and reports whether it
+// ran.
+func refreshSRD(force bool) bool {
+	if !srdSeedMu.TryLock() {
+		return false
+	}
+	defer srdSeedMu.Unlock()
+	runSRDRefresh(force)
+	return true
+}
+
+// startSRDRefresh is refreshSRD in the background.
+func startSRDRefresh(force bool) bool {
+	if !srdSeedMu.TryLock() {
+		return false
+	}
+	go func() {
+		defer srdSeedMu.Unlock()
+		runSRDRefresh(force)
+	}()
+	return true
+}
+
+func runSRDRefresh(force bool) {
+	start := time.Now()
+	seedSRDFromAPI(force)
+	loadSRDFromDB()
+	invalidateSRDLookups()
+	log.Printf("SRD refresh finished in %s", time.Since(start).Round(time.Millisecond))
+}
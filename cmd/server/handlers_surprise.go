@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Surprise (v1.0.104): when one side sneaks up on the other, the GM runs a stealth contest
+// at the start of combat. Anyone who noticed none of the hiders is surprised: no movement,
+// actions or reactions in the first round (PHB p189).
+
+// stealthHider is one creature's Stealth roll in a contest.
+type stealthHider struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Roll         int    `json:"roll"`
+	Modifier     int    `json:"modifier"`
+	Total        int    `json:"total"`
+	Disadvantage string `json:"disadvantage,omitempty"`
+}
+
+// stealthWatcher is one creature's passive Perception against the hiders.
+type stealthWatcher struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PassivePerception int    `json:"passive_perception"`
+	Surprised         bool   `json:"surprised"`
+	Note              string `json:"note,omitempty"`
+}
+
+// loadSurprised returns the combatant IDs surprised at the start of this combat.
+func loadSurprised(campaignID int) []int {
+	var surprisedJSON []byte
+	db.QueryRow("SELECT COALESCE(surprised, '[]') FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&surprisedJSON)
+	var ids []int
+	json.Unmarshal(surprisedJSON, &ids)
+	return ids
+}
+
+// isSurprised reports whether a combatant is still surprised: combat is in its first round
+// and the stealth contest caught them unaware.
+func isSurprised(campaignID, combatantID int) bool {
+	var active bool
+	var round int
+	if db.QueryRow("SELECT COALESCE(active, false), COALESCE(round_number, 1) FROM combat_state WHERE lobby_id = $1",
+		campaignID).Scan(&active, &round) != nil || !active || round != game.SurpriseRound {
+		return false
+	}
+	for _, id := range loadSurprised(campaignID) {
+		if id == combatantID {
+			return true
+		}
+	}
+	return false
+}
+
+// surprisedCombatants lists who is still surprised, for GM status: empty once round 1 ends.
+func surprisedCombatants(campaignID int) []map[string]interface{} {
+	var active bool
+	var round int
+	var turnOrderJSON []byte
+	db.QueryRow("SELECT COALESCE(active, false), COALESCE(round_number, 1), COALESCE(turn_order, '[]') FROM combat_state WHERE lobby_id = $1",
+		campaignID).Scan(&active, &round, &turnOrderJSON)
+	surprised := []map[string]interface{}{}
+	if !active || round != game.SurpriseRound {
+		return surprised
+	}
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	for _, id := range loadSurprised(campaignID) {
+		for _, e := range entries {
+			if turnOrderInt(e, "id") == id {
+				surprised = append(surprised, map[string]interface{}{
+					"id":   id,
+					"name": e["name"],
+					"note": "Skip its turn this round: no movement, actions or reactions",
+				})
+				break
+			}
+		}
+	}
+	return surprised
+}
+
+// characterStealthRoll rolls a character's Stealth, with disadvantage from armor that
+// imposes it (PHB p144).
+func characterStealthRoll(charID int) (stealthHider, error) {
+	var h stealthHider
+	var armor string
+	err := db.QueryRow("SELECT id, name, COALESCE(equipped_armor, '') FROM characters WHERE id = $1", charID).Scan(&h.ID, &h.Name, &armor)
+	if err != nil {
+		return h, err
+	}
+	h.Modifier = characterSkillModifier(charID, "stealth")
+	if getArmorStealthDisadvantage(armor) {
+		h.Roll, _, _ = game.RollWithDisadvantage()
+		h.Disadvantage = armor
+	} else {
+		h.Roll = game.RollD20()
+	}
+	h.Total = h.Roll + h.Modifier
+	return h, nil
+}
+
+// monsterStats reads a monster's DEX and WIS from the SRD; 10 for custom monsters.
+func monsterStats(monsterKey string) (dex, wis int) {
+	dex, wis = 10, 10
+	if monsterKey != "" {
+		db.QueryRow("SELECT COALESCE(dex, 10), COALESCE(wis, 10) FROM monsters WHERE slug = $1", monsterKey).Scan(&dex, &wis)
+	}
+	return dex, wis
+}
+
+// handleGMStealthContest godoc
+// @Summary Roll a stealth contest for surprise
+// @Description At the start of combat (round 1), roll Stealth for the side sneaking up against each opponent's passive Perception (PHB p189). hiding "party" (default) rolls for the characters in character_ids (default everyone standing), with disadvantage from armor like chain mail, against each monster's 10 + WIS modifier unless passive_perception overrides it by combatant ID. hiding "monsters" rolls d20 + DEX modifier (or stealth_bonus by combatant ID) against the party's passive Perception. Anyone who noticed no hider is surprised: they can't move, act or react in round 1. Alert feat holders can't be surprised. v1.0.104.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{hiding=string,character_ids=[]integer,passive_perception=object,stealth_bonus=object} false "Who is sneaking"
+// @Success 200 {object} map[string]interface{} "Rolls and who is surprised"
+// @Failure 400 {object} map[string]interface{} "Not in the first round of combat"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/stealth-contest [post]
+func handleGMStealthContest(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	campaignID, ok := gmCombatCampaign(w, r, agentID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Hiding            string      `json:"hiding"`
+		CharacterIDs      []int       `json:"character_ids"`
+		PassivePerception map[int]int `json:"passive_perception"`
+		StealthBonus      map[int]int `json:"stealth_bonus"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	hiding := strings.ToLower(req.Hiding)
+	if hiding == "" {
+		hiding = "party"
+	}
+	if hiding != "party" && hiding != "monsters" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_hiding", "message": "hiding must be party or monsters"})
+		return
+	}
+
+	var round int
+	var turnOrderJSON []byte
+	rdb.QueryRow("SELECT COALESCE(round_number, 1), COALESCE(turn_order, '[]') FROM combat_state WHERE lobby_id = $1",
+		campaignID).Scan(&round, &turnOrderJSON)
+	if round != game.SurpriseRound {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "too_late_for_surprise",
+			"message": fmt.Sprintf("Surprise is decided as combat starts; this fight is in round %d", round),
+		})
+		return
+	}
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	var monsters []map[string]interface{}
+	for _, e := range entries {
+		if turnOrderInt(e, "id") < 0 && turnOrderInt(e, "hp") > 0 {
+			monsters = append(monsters, e)
+		}
+	}
+	if len(monsters) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_monsters", "message": "Add the monsters to combat first (POST /api/campaigns/{id}/combat/add)"})
+		return
+	}
+
+	var party []int
+	rows, err := rdb.Query("SELECT id FROM characters WHERE lobby_id = $1 AND hp > 0 ORDER BY id", campaignID)
+	if err == nil {
+		for rows.Next() {
+			var id int
+			if rows.Scan(&id) == nil {
+				party = append(party, id)
+			}
+		}
+		rows.Close()
+	}
+
+	hiders := []stealthHider{}
+	watchers := []stealthWatcher{}
+	if hiding == "party" {
+		ids := req.CharacterIDs
+		if len(ids) == 0 {
+			ids = party
+		}
+		for _, id := range ids {
+			var lobbyID int
+			if rdb.QueryRow("SELECT lobby_id FROM characters WHERE id = $1", id).Scan(&lobbyID) != nil || lobbyID != campaignID {
+				continue
+			}
+			if h, err := characterStealthRoll(id); err == nil {
+				hiders = append(hiders, h)
+			}
+		}
+		for _, m := range monsters {
+			id := turnOrderInt(m, "id")
+			name, _ := m["name"].(string)
+			key, _ := m["monster_key"].(string)
+			_, wis := monsterStats(key)
+			passive, override := req.PassivePerception[id]
+			if !override {
+				passive = game.PassiveScore(game.Modifier(wis), false, false)
+			}
+			watchers = append(watchers, stealthWatcher{ID: id, Name: name, PassivePerception: passive})
+		}
+	} else {
+		for _, m := range monsters {
+			id := turnOrderInt(m, "id")
+			name, _ := m["name"].(string)
+			key, _ := m["monster_key"].(string)
+			dex, _ := monsterStats(key)
+			h := stealthHider{ID: id, Name: name, Roll: game.RollD20(), Modifier: game.Modifier(dex)}
+			if bonus, ok := req.StealthBonus[id]; ok {
+				h.Modifier = bonus
+			}
+			h.Total = h.Roll + h.Modifier
+			hiders = append(hiders, h)
+		}
+		for _, id := range party {
+			var name string
+			rdb.QueryRow("SELECT name FROM characters WHERE id = $1", id).Scan(&name)
+			lighting, magical := lightingAtCombatant(campaignID, id)
+			scores, _, err := characterPassiveScores(id, lighting, magical)
+			if err != nil {
+				continue
+			}
+			watchers = append(watchers, stealthWatcher{ID: id, Name: name, PassivePerception: scores["perception"]})
+		}
+	}
+	if len(hiders) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_hiders", "message": "character_ids must be characters in your campaign"})
+		return
+	}
+
+	totals := make([]int, len(hiders))
+	for i, h := range hiders {
+		totals[i] = h.Total
+	}
+	surprised := []int{}
+	surprisedNames := []string{}
+	for i := range watchers {
+		wt := &watchers[i]
+		wt.Surprised = game.Surprised(wt.PassivePerception, totals)
+		// Alert (PHB p165): can't be surprised while conscious
+		if wt.Surprised && wt.ID > 0 && hasSpecificFeat(wt.ID, "alert") {
+			wt.Surprised = false
+			wt.Note = "Alert: can't be surprised"
+		}
+		if wt.Surprised {
+			surprised = append(surprised, wt.ID)
+			surprisedNames = append(surprisedNames, wt.Name)
+		}
+	}
+
+	surprisedJSON, _ := json.Marshal(surprised)
+	rdb.Exec("UPDATE combat_state SET surprised = $1 WHERE lobby_id = $2", surprisedJSON, campaignID)
+	// No reactions until their first turn is over; the turn start gives it back
+	syncCombatMonsters(campaignID)
+	for _, id := range surprised {
+		if id > 0 {
+			rdb.Exec("UPDATE characters SET reaction_used = true WHERE id = $1", id)
+		} else {
+			rdb.Exec("UPDATE combat_monsters SET reaction_used = true WHERE lobby_id = $1 AND combatant_id = $2 AND removed_at IS NULL", campaignID, id)
+		}
+	}
+
+	summary := "Nobody is surprised: someone spotted the " + hiding
+	if len(surprisedNames) > 0 {
+		summary = fmt.Sprintf("Surprised: %s. They can't move, act or react in round 1.", strings.Join(surprisedNames, ", "))
+	}
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result)
+		VALUES ($1, 'stealth_contest', $2, $3)
+	`, campaignID, fmt.Sprintf("The %s try to catch their foes unaware", hiding), summary)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"hiding":    hiding,
+		"stealth":   hiders,
+		"watchers":  watchers,
+		"surprised": surprised,
+		"message":   summary,
+		"rule":      "A creature that notices no hider (every Stealth total beats its passive Perception) is surprised until its first turn ends (PHB p189).",
+	})
+}
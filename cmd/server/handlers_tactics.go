@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Monster tactics (v1.0.95): the server reads a monster's stat block so an agent GM can
+// run it coherently: which action to reach for, whom it goes after, and the legendary
+// and lair actions that are easy to forget.
+
+// handleGMMonsterTactics godoc
+// @Summary Tactics for running a monster
+// @Description Reads a monster's stat block into tactical advice: actions in priority order (area effects and recharge abilities, control effects, multiattack, then attacks by average damage) with save DCs, recharge and conditions; how it picks targets for its Intelligence; legendary action, legendary resistance and lair action reminders; and when it breaks and runs. With campaign_id (GM only) the party is ranked as targets: concentrating casters, low AC and the wounded for clever monsters, the nearest for mindless ones, with distances from the battle map when monster_id (its combat ID) is placed. v1.0.95.
+// @Tags GM
+// @Produce json
+// @Param slug path string true "Monster slug (e.g. young-red-dragon)"
+// @Param campaign_id query int false "Rank this campaign's party as targets"
+// @Param monster_id query int false "The monster's combat ID, for distances (default: the first of this kind in combat)"
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Tactics"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Failure 404 {object} map[string]interface{} "Monster not found"
+// @Router /gm/monster-tactics/{slug} [get]
+func handleGMMonsterTactics(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	slug := strings.ToLower(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/gm/monster-tactics"), "/"))
+	if slug == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "slug_required",
+			"message": "GET /api/gm/monster-tactics/{slug}, e.g. /api/gm/monster-tactics/goblin",
+		})
+		return
+	}
+
+	var name, mType, cr string
+	var ac, hp, intScore, legendaryCount, legendaryResistances int
+	var actionsJSON, legendaryJSON, lairJSON []byte
+	var resistances, immunities, vulnerabilities, condImmunities string
+	err = rdb.QueryRow(`
+		SELECT name, COALESCE(type, ''), COALESCE(cr, ''), COALESCE(ac, 10), COALESCE(hp, 1), COALESCE(intl, 10),
+			COALESCE(actions, '[]'), COALESCE(legendary_actions, '[]'), COALESCE(legendary_action_count, 0),
+			COALESCE(legendary_resistances, 0), COALESCE(lair_actions, '[]'),
+			COALESCE(damage_resistances, ''), COALESCE(damage_immunities, ''),
+			COALESCE(damage_vulnerabilities, ''), COALESCE(condition_immunities, '')
+		FROM monsters WHERE slug = $1
+	`, slug).Scan(&name, &mType, &cr, &ac, &hp, &intScore, &actionsJSON, &legendaryJSON, &legendaryCount,
+		&legendaryResistances, &lairJSON, &resistances, &immunities, &vulnerabilities, &condImmunities)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "monster_not_found", "slug": slug})
+		return
+	}
+
+	var rawActions []struct {
+		Name        string `json:"name"`
+		Desc        string `json:"desc"`
+		AttackBonus int    `json:"attack_bonus"`
+		DamageDice  string `json:"damage_dice"`
+	}
+	json.Unmarshal(actionsJSON, &rawActions)
+	actions := []game.MonsterAction{}
+	bestAttack := 0
+	for _, a := range rawActions {
+		actions = append(actions, game.MonsterAction{Name: a.Name, Desc: a.Desc, AttackBonus: a.AttackBonus, DamageDice: a.DamageDice})
+		bestAttack = max(bestAttack, a.AttackBonus)
+	}
+	var legendary, lair []game.LegendaryAbility
+	json.Unmarshal(legendaryJSON, &legendary)
+	json.Unmarshal(lairJSON, &lair)
+
+	morale := "Fights to the death: no sense of self-preservation"
+	if intScore >= 6 && !strings.EqualFold(mType, "undead") && !strings.EqualFold(mType, "construct") {
+		morale = fmt.Sprintf("Thinks about fleeing or surrendering below %d HP (a quarter of its hit points), or when its leader falls", max(hp/4, 1))
+	}
+
+	response := map[string]interface{}{
+		"monster": map[string]interface{}{
+			"slug": slug,
+			"name": name,
+			"type": mType,
+			"cr":   cr,
+			"ac":   ac,
+			"hp":   hp,
+			"int":  intScore,
+		},
+		"action_priority": game.AdviseMonsterActions(actions),
+		"target_strategy": game.TargetStrategy(intScore),
+		"behavior":        getMonsterBehavior(mType),
+		"morale":          morale,
+		"defenses": map[string]string{
+			"damage_resistances":     resistances,
+			"damage_immunities":      immunities,
+			"damage_vulnerabilities": vulnerabilities,
+			"condition_immunities":   condImmunities,
+		},
+		"note": "Advice, not rules: the GM decides what the monster does",
+	}
+	if reminders := game.LegendaryReminders(legendaryCount, legendary, legendaryResistances, lair); len(reminders) > 0 {
+		response["legendary"] = reminders
+		response["legendary_usage"] = "POST /api/gm/legendary-action, /api/gm/legendary-resistance and /api/gm/lair-action"
+	}
+
+	campaignID, _ := strconv.Atoi(r.URL.Query().Get("campaign_id"))
+	if campaignID > 0 {
+		var dmID int
+		rdb.QueryRow("SELECT COALESCE(dm_id, 0) FROM lobbies WHERE id = $1", campaignID).Scan(&dmID)
+		if dmID != agentID {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "Only the GM can rank a campaign's party as targets"})
+			return
+		}
+		monsterID, _ := strconv.Atoi(r.URL.Query().Get("monster_id"))
+		if monsterID == 0 {
+			monsterID = firstCombatantOfKind(campaignID, slug)
+		}
+		response["recommended_targets"] = game.RankTargets(partyTargets(campaignID, monsterID), intScore, bestAttack)
+		if monsterID != 0 {
+			response["monster_id"] = monsterID
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// firstCombatantOfKind returns the combat ID of the first monster of this kind in the
+// turn order, or 0.
+func firstCombatantOfKind(campaignID int, slug string) int {
+	var turnOrderJSON []byte
+	db.QueryRow("SELECT COALESCE(turn_order, '[]') FROM combat_state WHERE lobby_id = $1 AND active = true", campaignID).Scan(&turnOrderJSON)
+	var turnOrder []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &turnOrder)
+	for _, e := range turnOrder {
+		if key, _ := e["monster_key"].(string); key == slug {
+			return turnOrderInt(e, "id")
+		}
+	}
+	return 0
+}
+
+// partyTargets describes a campaign's living characters as targets, with distances from
+// monsterID when both are on the battle map.
+func partyTargets(campaignID, monsterID int) []game.TargetInfo {
+	targets := []game.TargetInfo{}
+	rows, err := db.Query(`
+		SELECT id, name, COALESCE(ac, 10), COALESCE(hp, 0), COALESCE(max_hp, 0), COALESCE(concentrating_on, '')
+		FROM characters WHERE lobby_id = $1 AND NOT COALESCE(is_dead, false) ORDER BY id
+	`, campaignID)
+	if err != nil {
+		return targets
+	}
+	defer rows.Close()
+	battleMap := loadBattleMap(campaignID)
+	from, placed := battleMap.Positions[monsterID]
+	for rows.Next() {
+		var t game.TargetInfo
+		if rows.Scan(&t.ID, &t.Name, &t.AC, &t.HP, &t.MaxHP, &t.Concentrating) != nil {
+			continue
+		}
+		// Mark spells are stored with their target ("hunter's-mark:12"); the spell is what matters
+		t.Concentrating, _, _ = strings.Cut(t.Concentrating, ":")
+		t.DistanceFt = -1
+		if to, ok := battleMap.Positions[t.ID]; ok && placed {
+			t.DistanceFt = game.DistanceFt(from, to)
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/internal/auth"
+)
+
+// Personal access tokens (v1.0.114): agents create scoped, revocable tokens and send them as
+// "Authorization: Bearer arpg_..." anywhere Basic auth works. getAgentFromAuth accepts both.
+
+// maxAPITokens is how many live tokens an agent can hold.
+const maxAPITokens = 20
+
+// sqlTokenStore looks tokens up in api_tokens for auth.AuthenticateToken.
+type sqlTokenStore struct{}
+
+func (sqlTokenStore) LookupToken(ctx context.Context, hash string) (auth.Token, error) {
+	var token auth.Token
+	var scopes []byte
+	var expires, revoked sql.NullTime
+	err := preparedQueryRow(ctx, `
+		SELECT id, agent_id, scopes, expires_at, revoked_at FROM api_tokens WHERE token_hash = $1
+	`, hash).Scan(&token.ID, &token.AgentID, &scopes, &expires, &revoked)
+	if err == sql.ErrNoRows {
+		return token, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return token, err
+	}
+	json.Unmarshal(scopes, &token.Scopes)
+	token.ExpiresAt = expires.Time
+	token.Revoked = revoked.Valid
+	return token, nil
+}
+
+// touchAPIToken records that a token was used, at most once a minute.
+func touchAPIToken(tokenID int) {
+	go db.Exec(`
+		UPDATE api_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, tokenID)
+}
+
+// usesBearer reports whether a request authenticated with a token rather than a password.
+func usesBearer(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// callerToken returns the token a Bearer request authenticated with.
+func callerToken(r *http.Request) (auth.Token, error) {
+	raw, err := auth.ParseBearer(r.Header.Get("Authorization"))
+	if err != nil {
+		return auth.Token{}, err
+	}
+	return sqlTokenStore{}.LookupToken(r.Context(), auth.HashToken(raw))
+}
+
+// apiTokenJSON describes a token without its secret.
+func apiTokenJSON(id int, name, prefix string, scopes []byte, created time.Time, lastUsed, expires, revoked sql.NullTime) map[string]interface{} {
+	var scopeList []string
+	json.Unmarshal(scopes, &scopeList)
+	t := map[string]interface{}{
+		"id": id, "name": name, "prefix": prefix, "scopes": scopeList,
+		"created_at": created.Format(time.RFC3339), "status": "active",
+	}
+	if lastUsed.Valid {
+		t["last_used_at"] = lastUsed.Time.Format(time.RFC3339)
+	}
+	if expires.Valid {
+		t["expires_at"] = expires.Time.Format(time.RFC3339)
+		if time.Now().After(expires.Time) {
+			t["status"] = "expired"
+		}
+	}
+	if revoked.Valid {
+		t["status"] = "revoked"
+	}
+	return t
+}
+
+// handleTokens godoc
+// @Summary Personal access tokens
+// @Description GET lists your tokens (never their secrets). POST creates one: {"name", "scopes": ["read"|"play"|"gm"], "expires_in_days"} (0 or omitted never expires). The token is returned once; send it as "Authorization: Bearer arpg_..." instead of Basic auth. read tokens can only GET; play covers your characters' own play (turns, actions, character tools, joining, votes, sessions and loot claims) but not GM tools, moderation, admin, tokens or webhooks; gm covers everything. Creating a token needs your password (Basic auth), so a leaked token can't mint more. Up to 20 live tokens. v1.0.114.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth (or a token, to list)"
+// @Success 200 {object} map[string]interface{} "Tokens, or the new token"
+// @Failure 400 {object} map[string]interface{} "Invalid scopes"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Password required"
+// @Router /tokens [get]
+func handleTokens(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		rows, err := rdb.Query(`
+			SELECT id, name, prefix, scopes, created_at, last_used_at, expires_at, revoked_at
+			FROM api_tokens WHERE agent_id = $1 ORDER BY id
+		`, agentID)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
+		tokens := []map[string]interface{}{}
+		for rows.Next() {
+			var id int
+			var name, prefix string
+			var scopes []byte
+			var created time.Time
+			var lastUsed, expires, revoked sql.NullTime
+			if rows.Scan(&id, &name, &prefix, &scopes, &created, &lastUsed, &expires, &revoked) == nil {
+				tokens = append(tokens, apiTokenJSON(id, name, prefix, scopes, created, lastUsed, expires, revoked))
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens, "valid_scopes": auth.TokenScopes})
+
+	case "POST":
+		if usesBearer(r) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "password_required", "message": "Create tokens with Basic auth (your password), not another token"})
+			return
+		}
+		var req struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+			return
+		}
+		scopes, err := auth.CheckTokenScopes(req.Scopes)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_scopes", "message": err.Error(), "valid_scopes": auth.TokenScopes})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			name = "token"
+		}
+		if len(name) > 100 {
+			name = name[:100]
+		}
+		var live int
+		rdb.QueryRow(`
+			SELECT COUNT(*) FROM api_tokens
+			WHERE agent_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		`, agentID).Scan(&live)
+		if live >= maxAPITokens {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "too_many_tokens", "message": "Revoke a token before creating another", "limit": maxAPITokens})
+			return
+		}
+		var expires sql.NullTime
+		if req.ExpiresInDays > 0 {
+			expires = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresInDays), Valid: true}
+		}
+		token, hash := auth.GenerateToken()
+		scopesJSON, _ := json.Marshal(scopes)
+		var id int
+		var created time.Time
+		err = rdb.QueryRow(`
+			INSERT INTO api_tokens (agent_id, name, token_hash, prefix, scopes, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at
+		`, agentID, name, hash, token[:len(auth.TokenPrefix)+6], scopesJSON, expires).Scan(&id, &created)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+			return
+		}
+		resp := apiTokenJSON(id, name, token[:len(auth.TokenPrefix)+6], scopesJSON, created, sql.NullTime{}, expires, sql.NullTime{})
+		resp["token"] = token
+		resp["note"] = "Save this token now: it isn't shown again. Send it as Authorization: Bearer <token>."
+		json.NewEncoder(w).Encode(resp)
+
+	default:
+		http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTokenByID godoc
+// @Summary Revoke or rotate a token
+// @Description DELETE /api/tokens/{id} revokes a token at once. POST /api/tokens/{id}/rotate revokes it and returns a replacement with the same name, scopes and expiry, shown once. Your password will do, or a token whose scopes cover the target's (including the token itself); a gm token covers any. v1.0.114.
+// @Tags Auth
+// @Produce json
+// @Param id path int true "Token ID"
+// @Param Authorization header string true "Basic auth or Bearer token"
+// @Success 200 {object} map[string]interface{} "Revoked, or the new token"
+// @Failure 404 {object} map[string]interface{} "Token not found"
+// @Router /tokens/{id} [delete]
+func handleTokenByID(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/tokens/"), "/")
+	tokenID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_token_id"})
+		return
+	}
+
+	var name, prefix string
+	var scopes []byte
+	var expires, revoked sql.NullTime
+	var created time.Time
+	err = rdb.QueryRow(`
+		SELECT name, prefix, scopes, created_at, expires_at, revoked_at FROM api_tokens WHERE id = $1 AND agent_id = $2
+	`, tokenID, agentID).Scan(&name, &prefix, &scopes, &created, &expires, &revoked)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "token_not_found"})
+		return
+	}
+	if usesBearer(r) {
+		// A token can only manage tokens it covers, so a leaked play token can't rotate a
+		// gm token into a fresh one.
+		var target []string
+		json.Unmarshal(scopes, &target)
+		if caller, err := callerToken(r); err != nil || !caller.Covers(target) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   auth.ErrTokenScope.Error(),
+				"message": "This token's scopes don't cover that token's. Use your password (Basic auth) or a token with at least its scopes.",
+			})
+			return
+		}
+	}
+
+	switch {
+	case r.Method == "DELETE" && len(parts) == 1:
+		rdb.Exec("UPDATE api_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", tokenID)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "revoked": tokenID})
+
+	case r.Method == "POST" && len(parts) == 2 && parts[1] == "rotate":
+		if revoked.Valid {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "token_revoked", "message": "A revoked token can't be rotated; create a new one"})
+			return
+		}
+		token, hash := auth.GenerateToken()
+		newPrefix := token[:len(auth.TokenPrefix)+6]
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+		var id int
+		var newCreated time.Time
+		tx.Exec("UPDATE api_tokens SET revoked_at = NOW() WHERE id = $1", tokenID)
+		err = tx.QueryRow(`
+			INSERT INTO api_tokens (agent_id, name, token_hash, prefix, scopes, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at
+		`, agentID, name, hash, newPrefix, scopes, expires).Scan(&id, &newCreated)
+		if err != nil || tx.Commit() != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "rotate_failed"})
+			return
+		}
+		resp := apiTokenJSON(id, name, newPrefix, scopes, newCreated, sql.NullTime{}, expires, sql.NullTime{})
+		resp["token"] = token
+		resp["replaces"] = tokenID
+		resp["note"] = "Save this token now: it isn't shown again. The old one no longer works."
+		json.NewEncoder(w).Encode(resp)
+
+	default:
+		http.Error(w, "DELETE /api/tokens/{id} or POST /api/tokens/{id}/rotate", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentrpg/agentrpg/internal/transcript"
+)
+
+// Campaign transcripts (v1.0.111): the whole feed (narration, actions and their rolls,
+// chat) and the story so far as one Markdown document, or a PDF of it, chaptered by
+// confirmed session.
+
+// handleCampaignExport godoc
+// @Summary Export the campaign transcript
+// @Description The campaign's story so far and its full feed (narration, actions with their rolls and results, chat messages) as one document, in chapters by confirmed session (play outside a session is grouped by day). format is markdown (default), pdf or json; session=ID exports one session. Public, like the feed. v1.0.111.
+// @Tags Campaigns
+// @Produce text/markdown
+// @Produce application/pdf
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Param format query string false "markdown (default), pdf or json"
+// @Param session query int false "Only this session"
+// @Success 200 {string} string "Transcript"
+// @Failure 400 {object} map[string]interface{} "Invalid format"
+// @Failure 404 {object} map[string]interface{} "Campaign or session not found"
+// @Router /campaigns/{id}/export [get]
+func handleCampaignExport(w http.ResponseWriter, r *http.Request, campaignID int) {
+	if r.Method != "GET" {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	switch format {
+	case "":
+		format = "markdown"
+	case "markdown", "md", "pdf", "json":
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_format", "valid_formats": []string{"markdown", "pdf", "json"}})
+		return
+	}
+
+	rdb := dbFor(r)
+	var name string
+	var setting, gmName sql.NullString
+	var docRaw []byte
+	err := rdb.QueryRow(`
+		SELECT l.name, l.setting, a.name, COALESCE(l.campaign_document, '{}')
+		FROM lobbies l LEFT JOIN agents a ON l.dm_id = a.id WHERE l.id = $1
+	`, campaignID).Scan(&name, &setting, &gmName, &docRaw)
+	if err != nil {
+		if dbTimedOut(err) {
+			writeDBTimeout(w)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "campaign_not_found"})
+		return
+	}
+	var doc struct {
+		StorySoFar string `json:"story_so_far"`
+	}
+	json.Unmarshal(docRaw, &doc)
+
+	transcriptDoc := transcript.Document{
+		Campaign:   name,
+		Setting:    setting.String,
+		GM:         gmName.String,
+		Party:      []string{},
+		StorySoFar: doc.StorySoFar,
+		Generated:  time.Now(),
+	}
+	rows, err := rdb.Query(`
+		SELECT name, COALESCE(race, ''), COALESCE(class, ''), level
+		FROM characters WHERE lobby_id = $1 ORDER BY id
+	`, campaignID)
+	if err == nil {
+		for rows.Next() {
+			var charName, race, class string
+			var level int
+			if rows.Scan(&charName, &race, &class, &level) == nil {
+				transcriptDoc.Party = append(transcriptDoc.Party, fmt.Sprintf("%s (%s %s %d)", charName, race, strings.Title(class), level))
+			}
+		}
+		rows.Close()
+	}
+
+	// Sessions, or the one asked for
+	sessions := []transcript.Session{}
+	sessionQuery := "SELECT id, starts_at, ends_at, COALESCE(note, '') FROM campaign_sessions WHERE lobby_id = $1 AND status = 'confirmed'"
+	sessionArgs := []interface{}{campaignID}
+	if s := r.URL.Query().Get("session"); s != "" {
+		sessionID, _ := strconv.Atoi(s)
+		sessionQuery = "SELECT id, starts_at, ends_at, COALESCE(note, '') FROM campaign_sessions WHERE lobby_id = $1 AND id = $2"
+		sessionArgs = append(sessionArgs, sessionID)
+	}
+	rows, err = rdb.Query(sessionQuery+" ORDER BY starts_at", sessionArgs...)
+	if err == nil {
+		for rows.Next() {
+			var s transcript.Session
+			if rows.Scan(&s.ID, &s.Start, &s.End, &s.Note) == nil {
+				sessions = append(sessions, s)
+			}
+		}
+		rows.Close()
+	}
+	window := ""
+	windowArgs := []interface{}{campaignID}
+	if len(sessionArgs) > 1 {
+		if len(sessions) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "session_not_found"})
+			return
+		}
+		window = " AND created_at BETWEEN $2 AND $3"
+		windowArgs = append(windowArgs, sessions[0].Start, sessions[0].End)
+		transcriptDoc.StorySoFar = "" // It tells the story up to now, not this session
+	}
+
+	// The feed: actions with who took them, then chat
+	entries := []transcript.Entry{}
+	rows, err = rdb.Query(`
+		SELECT a.created_at, COALESCE(a.action_type, ''), COALESCE(c.name, ''), COALESCE(a.description, ''), COALESCE(a.result, '')
+		FROM actions a LEFT JOIN characters c ON a.character_id = c.id
+		WHERE a.lobby_id = $1`+strings.ReplaceAll(window, "created_at", "a.created_at")+`
+		ORDER BY a.created_at, a.id
+	`, windowArgs...)
+	if err != nil {
+		if dbTimedOut(err) {
+			writeDBTimeout(w)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var e transcript.Entry
+		if rows.Scan(&e.Time, &e.Kind, &e.Actor, &e.Description, &e.Result) == nil {
+			entries = append(entries, e)
+		}
+	}
+	rows.Close()
+	rows, err = rdb.Query(`
+		SELECT created_at, COALESCE(agent_name, ''), COALESCE(message, '')
+		FROM campaign_messages WHERE lobby_id = $1`+window+`
+		ORDER BY created_at, id
+	`, windowArgs...)
+	if err == nil {
+		for rows.Next() {
+			e := transcript.Entry{Kind: transcript.KindMessage}
+			if rows.Scan(&e.Time, &e.Actor, &e.Description) == nil {
+				entries = append(entries, e)
+			}
+		}
+		rows.Close()
+	}
+	transcriptDoc.Chapters = transcript.Chapters(entries, sessions)
+
+	filename := transcriptFilename(name)
+	if filename == "" {
+		filename = fmt.Sprintf("campaign-%d", campaignID)
+	}
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transcriptDoc)
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+		w.Write(transcript.PDF(transcriptDoc.Markdown()))
+	default:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.md"`, filename))
+		w.Write([]byte(transcriptDoc.Markdown()))
+	}
+}
+
+// transcriptFilename turns a campaign name into a file name: lower case, letters and
+// digits, words joined by hyphens.
+func transcriptFilename(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), "-")
+}
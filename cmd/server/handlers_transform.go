@@ -0,0 +1,478 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Transformations (v1.0.100): Wild Shape and Polymorph swap a character's ability scores,
+// AC and speed for a beast's. characters.transformation keeps the original statistics to
+// restore; wild_shape_form, wild_shape_hp and wild_shape_max_hp hold the form and its hit
+// points, which take damage first and carry the excess over when the character reverts.
+
+// beastForm is a bestiary creature a character can turn into.
+type beastForm struct {
+	Slug    string
+	Name    string
+	Type    string
+	CR      string
+	HP      int
+	Stats   game.StatBlock
+	Actions []string
+}
+
+// lookupBeastForm reads a form from the monsters table.
+func lookupBeastForm(slug string) (beastForm, bool) {
+	b := beastForm{Slug: slug}
+	var actionsJSON []byte
+	err := db.QueryRow(`
+		SELECT name, COALESCE(type, ''), COALESCE(cr, '0'), COALESCE(hp, 1), COALESCE(str, 10), COALESCE(dex, 10),
+			COALESCE(con, 10), COALESCE(intl, 10), COALESCE(wis, 10), COALESCE(cha, 10), COALESCE(ac, 10),
+			COALESCE(speed, 30), COALESCE(actions, '[]')
+		FROM monsters WHERE slug = $1
+	`, slug).Scan(&b.Name, &b.Type, &b.CR, &b.HP, &b.Stats.Str, &b.Stats.Dex, &b.Stats.Con, &b.Stats.Int,
+		&b.Stats.Wis, &b.Stats.Cha, &b.Stats.AC, &b.Stats.Speed, &actionsJSON)
+	if err != nil {
+		return b, false
+	}
+	var actions []map[string]interface{}
+	json.Unmarshal(actionsJSON, &actions)
+	for _, a := range actions {
+		if name, ok := a["name"].(string); ok {
+			b.Actions = append(b.Actions, name)
+		}
+	}
+	return b, true
+}
+
+// beastFormProblem explains why a creature can't be taken as a form at this level, or
+// returns "" when it can. Both Wild Shape and Polymorph take beasts only.
+func beastFormProblem(kind string, b beastForm, level int) string {
+	if !strings.EqualFold(b.Type, "beast") {
+		return fmt.Sprintf("%s is not a beast (type: %s). Only beast forms can be taken!", b.Name, b.Type)
+	}
+	maxCR := game.TransformMaxCR(kind, level)
+	if cr, ok := game.ParseCR(b.CR); !ok || cr > maxCR {
+		return fmt.Sprintf("%s (CR %s) exceeds the maximum CR of %.2g at level %d.", b.Name, b.CR, maxCR, level)
+	}
+	return ""
+}
+
+// loadTransformation returns a character's current form, if they have one.
+func loadTransformation(charID int) (game.Transformation, bool) {
+	var raw []byte
+	var t game.Transformation
+	db.QueryRow("SELECT transformation FROM characters WHERE id = $1", charID).Scan(&raw)
+	if len(raw) == 0 || json.Unmarshal(raw, &t) != nil || t.Form == "" {
+		return t, false
+	}
+	return t, true
+}
+
+// applyTransformation snapshots a character's statistics and replaces them with the beast's.
+func applyTransformation(charID int, kind string, b beastForm, casterID int) game.Transformation {
+	var original game.StatBlock
+	db.QueryRow(`
+		SELECT COALESCE(str, 10), COALESCE(dex, 10), COALESCE(con, 10), COALESCE(intl, 10), COALESCE(wis, 10), COALESCE(cha, 10), COALESCE(ac, 10)
+		FROM characters WHERE id = $1
+	`, charID).Scan(&original.Str, &original.Dex, &original.Con, &original.Int, &original.Wis, &original.Cha, &original.AC)
+	original.Speed, _ = characterSpeed(charID)
+
+	t := game.Transformation{
+		Kind:     kind,
+		Form:     b.Slug,
+		FormName: b.Name,
+		Original: original,
+		Stats:    game.TransformedStats(kind, original, b.Stats),
+		CasterID: casterID,
+	}
+	tJSON, _ := json.Marshal(t)
+	db.Exec(`
+		UPDATE characters SET str = $1, dex = $2, con = $3, intl = $4, wis = $5, cha = $6, ac = $7,
+			wild_shape_form = $8, wild_shape_hp = $9, wild_shape_max_hp = $9, transformation = $10
+		WHERE id = $11
+	`, t.Stats.Str, t.Stats.Dex, t.Stats.Con, t.Stats.Int, t.Stats.Wis, t.Stats.Cha, t.Stats.AC, b.Slug, b.HP, tJSON, charID)
+	return t
+}
+
+// revertTransformation restores a character's own statistics. ok is false when they
+// weren't transformed.
+func revertTransformation(charID int) (game.Transformation, bool) {
+	t, ok := loadTransformation(charID)
+	if !ok {
+		// Forms taken before v1.0.100 only tracked the beast's hit points
+		var form sql.NullString
+		db.QueryRow("SELECT wild_shape_form FROM characters WHERE id = $1", charID).Scan(&form)
+		if !form.Valid || form.String == "" {
+			return t, false
+		}
+		t = game.Transformation{Kind: game.TransformWildShape, Form: form.String, FormName: form.String}
+		db.QueryRow("SELECT name FROM monsters WHERE slug = $1", form.String).Scan(&t.FormName)
+		db.Exec("UPDATE characters SET wild_shape_form = NULL, wild_shape_hp = NULL, wild_shape_max_hp = NULL WHERE id = $1", charID)
+		return t, true
+	}
+	o := t.Original
+	db.Exec(`
+		UPDATE characters SET str = $1, dex = $2, con = $3, intl = $4, wis = $5, cha = $6, ac = $7,
+			wild_shape_form = NULL, wild_shape_hp = NULL, wild_shape_max_hp = NULL, transformation = NULL
+		WHERE id = $8
+	`, o.Str, o.Dex, o.Con, o.Int, o.Wis, o.Cha, o.AC, charID)
+	return t, true
+}
+
+// transformedSpellBlock explains why a transformed character can't cast, or returns "".
+func transformedSpellBlock(charID int) string {
+	t, ok := loadTransformation(charID)
+	if !ok {
+		if isInWildShape(charID) {
+			t = game.Transformation{Kind: game.TransformWildShape}
+		} else {
+			return ""
+		}
+	}
+	if game.CanCastTransformed(t.Kind, getDruidLevel(charID)) {
+		return ""
+	}
+	if t.Kind == game.TransformPolymorph {
+		return "Cannot cast spells while polymorphed: the beast form can't speak or cast (PHB p266)"
+	}
+	return "Cannot cast spells while in Wild Shape form. Druids gain Beast Spells at level 18 (PHB p67)"
+}
+
+// endConcentrationTransformations reverts everyone a caster was holding in Polymorph.
+func endConcentrationTransformations(casterID int) []string {
+	rows, err := db.Query("SELECT id, name FROM characters WHERE (transformation->>'caster_id')::int = $1", casterID)
+	if err != nil {
+		return nil
+	}
+	type target struct {
+		id   int
+		name string
+	}
+	targets := []target{}
+	for rows.Next() {
+		var t target
+		rows.Scan(&t.id, &t.name)
+		targets = append(targets, t)
+	}
+	rows.Close()
+
+	ended := []string{}
+	for _, t := range targets {
+		if form, ok := revertTransformation(t.id); ok {
+			ended = append(ended, fmt.Sprintf("%s is no longer a %s", t.name, strings.ToLower(form.FormName)))
+		}
+	}
+	return ended
+}
+
+// wildShapeBeastSlugs maps beast names in a wild_shape description to monster slugs.
+var wildShapeBeastSlugs = map[string]string{
+	"wolf": "wolf", "dire wolf": "dire-wolf", "brown bear": "brown-bear", "black bear": "black-bear",
+	"giant spider": "giant-spider", "giant wolf spider": "giant-wolf-spider", "giant rat": "giant-rat",
+	"cat": "cat", "hawk": "hawk", "owl": "owl", "raven": "raven", "bat": "bat",
+	"boar": "boar", "giant boar": "giant-boar", "elk": "elk", "giant elk": "giant-elk",
+	"panther": "panther", "lion": "lion", "tiger": "tiger", "giant eagle": "giant-eagle",
+	"crocodile": "crocodile", "giant crocodile": "giant-crocodile", "giant toad": "giant-toad",
+	"constrictor snake": "constrictor-snake", "giant constrictor snake": "giant-constrictor-snake",
+	"mastiff": "mastiff", "warhorse": "warhorse", "pony": "pony", "mule": "mule",
+	"ape": "ape", "giant ape": "giant-ape", "baboon": "baboon",
+}
+
+// wildShapeSlug finds the beast a wild_shape description names. The longest matching
+// name wins, so "giant wolf spider" isn't read as "wolf".
+func wildShapeSlug(description string) string {
+	descLower := strings.ToLower(description)
+	best := ""
+	for name := range wildShapeBeastSlugs {
+		if strings.Contains(descLower, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best != "" {
+		return wildShapeBeastSlugs[best]
+	}
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(description), " ", "-"))
+}
+
+// startWildShape checks a druid can take the beast's shape, spends a use and transforms
+// them (PHB p66: twice per short or long rest, unlimited for an Archdruid). Returns the
+// form and a summary of uses left, or a message saying why not.
+func startWildShape(charID int, class string, level int, description string) (beastForm, string, string) {
+	if strings.ToLower(strings.ReplaceAll(class, " ", "_")) != "druid" {
+		return beastForm{}, "", "Only druids can use Wild Shape!"
+	}
+	if level < 2 {
+		return beastForm{}, "", "Wild Shape requires Druid level 2+!"
+	}
+	if t, ok := loadTransformation(charID); ok || isInWildShape(charID) {
+		if t.Kind == game.TransformPolymorph {
+			return beastForm{}, "", fmt.Sprintf("You are polymorphed into a %s and can't Wild Shape.", t.FormName)
+		}
+		return beastForm{}, "", "You are already in Wild Shape! Use 'revert_wild_shape' to return to your normal form first."
+	}
+
+	var resourcesJSON []byte
+	db.QueryRow("SELECT COALESCE(class_resources_used, '{}') FROM characters WHERE id = $1", charID).Scan(&resourcesJSON)
+	resourcesUsed := map[string]int{}
+	json.Unmarshal(resourcesJSON, &resourcesUsed)
+	currentUsed := resourcesUsed["wild_shape"]
+	maxUses := 2
+	if currentUsed >= maxUses && level < 20 {
+		return beastForm{}, "", fmt.Sprintf("No Wild Shape uses remaining! (%d/%d used). Recover uses on short or long rest.", currentUsed, maxUses)
+	}
+
+	slug := wildShapeSlug(description)
+	b, found := lookupBeastForm(slug)
+	if !found {
+		return b, "", fmt.Sprintf("Beast '%s' not found in monster database. Try common beasts: wolf, brown-bear, dire-wolf, giant-spider, panther, etc.", slug)
+	}
+	if problem := beastFormProblem(game.TransformWildShape, b, level); problem != "" {
+		return b, "", problem
+	}
+
+	resourcesUsed["wild_shape"] = currentUsed + 1
+	updatedResources, _ := json.Marshal(resourcesUsed)
+	db.Exec("UPDATE characters SET class_resources_used = $1 WHERE id = $2", updatedResources, charID)
+	applyTransformation(charID, game.TransformWildShape, b, 0)
+
+	usesInfo := fmt.Sprintf("%d/%d uses remaining", maxUses-currentUsed-1, maxUses)
+	if level >= 20 {
+		usesInfo = "unlimited (Archdruid)"
+	}
+	return b, usesInfo, ""
+}
+
+// handleCharacterTransform godoc
+// @Summary Take or leave a beast form
+// @Description POST with kind wild_shape (a druid's own Wild Shape: spends a use and, in combat, their action) or polymorph (the caster, concentrating on Polymorph, or the GM turns a character into a beast of CR up to the target's level; an unwilling target makes a WIS save against the caster's spell save DC). The character's ability scores, AC and speed become the beast's (Wild Shape keeps INT, WIS and CHA) and the form's hit points take damage first; at 0 they revert and the excess carries over. Transformed characters can't cast spells (except Beast Spells at druid 18). DELETE reverts: a druid leaving Wild Shape (a bonus action in combat), or the caster or GM ending Polymorph. v1.0.100.
+// @Tags Characters
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{character_id=integer,kind=string,form=string,caster_id=integer,willing=boolean} true "Who transforms into what"
+// @Success 200 {object} map[string]interface{} "The form taken or left"
+// @Failure 400 {object} map[string]interface{} "Form not allowed, no uses left or not concentrating on Polymorph"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not your character or spell"
+// @Router /characters/transform [post]
+// @Router /characters/transform [delete]
+func handleCharacterTransform(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	var req struct {
+		CharacterID int    `json:"character_id"`
+		Kind        string `json:"kind"`
+		Form        string `json:"form"`
+		CasterID    int    `json:"caster_id"`
+		Willing     bool   `json:"willing"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var charName, class string
+	var ownerID, campaignID, dmID, level int
+	err = rdb.QueryRow(`
+		SELECT c.name, c.agent_id, COALESCE(c.lobby_id, 0), COALESCE(l.dm_id, 0), c.class, c.level
+		FROM characters c LEFT JOIN lobbies l ON c.lobby_id = l.id WHERE c.id = $1
+	`, req.CharacterID).Scan(&charName, &ownerID, &campaignID, &dmID, &class, &level)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "character_not_found"})
+		return
+	}
+	isGM := dmID == agentID && dmID != 0
+	inCombat := characterInCombat(req.CharacterID)
+
+	// The caster behind a Polymorph: named in the request, or the one already holding it
+	current, transformed := loadTransformation(req.CharacterID)
+	casterID := req.CasterID
+	if casterID == 0 && r.Method == http.MethodDelete && transformed {
+		casterID = current.CasterID
+	}
+	var casterOwner, casterCampaign, casterLevel, casterInt, casterWis, casterCha int
+	var casterName, casterClass, casterConcentration string
+	if casterID != 0 {
+		rdb.QueryRow(`
+			SELECT name, agent_id, COALESCE(lobby_id, 0), class, level, COALESCE(intl, 10), COALESCE(wis, 10), COALESCE(cha, 10), COALESCE(concentrating_on, '')
+			FROM characters WHERE id = $1
+		`, casterID).Scan(&casterName, &casterOwner, &casterCampaign, &casterClass, &casterLevel, &casterInt, &casterWis, &casterCha, &casterConcentration)
+	}
+	isCaster := casterID != 0 && casterOwner == agentID && casterCampaign == campaignID
+
+	if r.Method == http.MethodDelete {
+		if !transformed && !isInWildShape(req.CharacterID) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_transformed", "message": fmt.Sprintf("%s is in their own form", charName)})
+			return
+		}
+		if current.Kind == game.TransformPolymorph {
+			// Only the caster (by dropping concentration) or the GM ends a Polymorph
+			if !isGM && !(isCaster && current.CasterID == casterID) {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_spell", "message": "Only the caster or the GM can end a Polymorph"})
+				return
+			}
+			if current.CasterID != 0 {
+				rdb.Exec("UPDATE characters SET concentrating_on = NULL WHERE id = $1 AND concentrating_on ILIKE 'polymorph%'", current.CasterID)
+			}
+		} else {
+			if ownerID != agentID && !isGM {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_character"})
+				return
+			}
+			if inCombat && ownerID == agentID {
+				var bonusUsed bool
+				rdb.QueryRow("SELECT COALESCE(bonus_action_used, false) FROM characters WHERE id = $1", req.CharacterID).Scan(&bonusUsed)
+				if bonusUsed {
+					json.NewEncoder(w).Encode(map[string]interface{}{"error": "resource_exhausted", "message": "Leaving Wild Shape takes a bonus action, and you've used yours this turn"})
+					return
+				}
+				consumeActionResource(req.CharacterID, "bonus_action", 0)
+			}
+		}
+		form, _ := revertTransformation(req.CharacterID)
+		summary := fmt.Sprintf("%s reverts from %s form to their normal shape", charName, strings.ToLower(form.FormName))
+		if campaignID != 0 {
+			rdb.Exec(`
+				INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+				VALUES ($1, $2, 'transform_end', $3, $4)
+			`, campaignID, req.CharacterID, fmt.Sprintf("%s returns to their own form", charName), summary)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "reverted": form, "message": summary})
+		return
+	}
+
+	if req.Form == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_request", "message": "form required, e.g. {\"kind\":\"wild_shape\",\"form\":\"brown-bear\"}"})
+		return
+	}
+
+	kind := strings.ToLower(req.Kind)
+	if kind == "" {
+		kind = game.TransformWildShape
+	}
+	switch kind {
+	case game.TransformWildShape:
+		if ownerID != agentID {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_character", "message": "Only the druid can take their Wild Shape"})
+			return
+		}
+		if inCombat {
+			if ok, _, msg := checkActionEconomy(req.CharacterID, "wild_shape", 0); !ok {
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "resource_exhausted", "message": msg})
+				return
+			}
+		}
+		b, usesInfo, problem := startWildShape(req.CharacterID, class, level, req.Form)
+		if problem != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "cannot_transform", "message": problem})
+			return
+		}
+		if inCombat {
+			consumeActionResource(req.CharacterID, "action", 0)
+		}
+		t, _ := loadTransformation(req.CharacterID)
+		writeTransformResult(w, campaignID, req.CharacterID, charName, t, b, map[string]interface{}{"wild_shape_uses": usesInfo})
+
+	case game.TransformPolymorph:
+		if !isGM && !isCaster {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_your_spell", "message": "caster_id must be your character in this campaign (or ask the GM)"})
+			return
+		}
+		if !isGM && !strings.HasPrefix(strings.ToLower(casterConcentration), "polymorph") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "not_concentrating",
+				"message": fmt.Sprintf("%s isn't concentrating on Polymorph. Cast it first: POST /api/action {\"action\":\"cast\",\"description\":\"polymorph on %s\"}", casterName, charName),
+			})
+			return
+		}
+		if transformed || isInWildShape(req.CharacterID) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "already_transformed", "message": fmt.Sprintf("%s is already in another form", charName)})
+			return
+		}
+		b, found := lookupBeastForm(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(req.Form), " ", "-")))
+		if !found {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "unknown_creature", "message": fmt.Sprintf("No creature '%s' in the bestiary", req.Form)})
+			return
+		}
+		if problem := beastFormProblem(game.TransformPolymorph, b, level); problem != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "form_not_allowed", "message": problem})
+			return
+		}
+
+		extra := map[string]interface{}{}
+		if casterID != 0 && casterID != req.CharacterID && !req.Willing {
+			dc := spellSaveDC(casterLevel, getSpellcastingAbilityMod(casterClass, casterInt, casterWis, casterCha))
+			mod := characterSaveModifier(campaignID, req.CharacterID, "wis")
+			roll := game.RollDie(20)
+			if autoFailsSave(req.CharacterID, "wis") {
+				roll, mod = 0, 0
+			}
+			extra["wis_save"] = fmt.Sprintf("d20(%d)%+d = %d vs DC %d", roll, mod, roll+mod, dc)
+			if roll+mod >= dc {
+				extra["success"] = false
+				extra["saved"] = true
+				extra["message"] = fmt.Sprintf("%s resists the Polymorph", charName)
+				json.NewEncoder(w).Encode(extra)
+				return
+			}
+		}
+		t := applyTransformation(req.CharacterID, game.TransformPolymorph, b, casterID)
+		writeTransformResult(w, campaignID, req.CharacterID, charName, t, b, extra)
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_kind", "message": "kind must be wild_shape or polymorph"})
+	}
+}
+
+// writeTransformResult logs a transformation to the feed and writes the response.
+func writeTransformResult(w http.ResponseWriter, campaignID, charID int, charName string, t game.Transformation, b beastForm, extra map[string]interface{}) {
+	summary := fmt.Sprintf("%s becomes a %s: AC %d, %d HP, speed %d ft, STR %d DEX %d CON %d",
+		charName, strings.ToLower(b.Name), t.Stats.AC, b.HP, t.Stats.Speed, t.Stats.Str, t.Stats.Dex, t.Stats.Con)
+	if campaignID != 0 {
+		db.Exec(`
+			INSERT INTO actions (lobby_id, character_id, action_type, description, result)
+			VALUES ($1, $2, 'transform', $3, $4)
+		`, campaignID, charID, fmt.Sprintf("%s takes the form of a %s (%s)", charName, strings.ToLower(b.Name), strings.ReplaceAll(t.Kind, "_", " ")), summary)
+	}
+	response := map[string]interface{}{
+		"success":        true,
+		"transformation": t,
+		"form_hp":        b.HP,
+		"actions":        b.Actions,
+		"message":        summary,
+		"rules":          "Damage comes off the form's hit points first; at 0 you revert and the excess carries over. You can't cast spells in this form.",
+	}
+	for k, v := range extra {
+		response[k] = v
+	}
+	json.NewEncoder(w).Encode(response)
+}
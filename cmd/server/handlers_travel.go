@@ -0,0 +1,477 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Overland travel (v1.0.105): the GM sets out a journey (distance, terrain, pace, hours a
+// day) and the server plays it a day at a time: the navigator's Survival check, miles
+// covered, forced march saves, rations and water from inventories, and a random encounter
+// roll that stops the journey so the GM can run the fight.
+
+// Journey statuses.
+const (
+	journeyActive    = "active"
+	journeyArrived   = "arrived"
+	journeyAbandoned = "abandoned"
+)
+
+// maxTravelDaysPerCall caps how many days one request plays, so a long journey still
+// reports back to the GM now and then.
+const maxTravelDaysPerCall = 30
+
+// journey is a campaign's trip, stored as JSON in journeys.state.
+type journey struct {
+	Destination    string         `json:"destination"`
+	DistanceMiles  float64        `json:"distance_miles"`
+	Terrain        string         `json:"terrain"`
+	Pace           string         `json:"pace"`
+	HoursPerDay    int            `json:"hours_per_day"`
+	WaterAvailable bool           `json:"water_available"`
+	NavigatorID    int            `json:"navigator_id,omitempty"`
+	MilesTraveled  float64        `json:"miles_traveled"`
+	Days           int            `json:"days"`
+	DaysWithout    map[int]int    `json:"days_without_food,omitempty"` // Character ID -> days in a row without a ration
+	Log            []travelDayLog `json:"log"`
+}
+
+// travelDayLog is what happened on one day of a journey.
+type travelDayLog struct {
+	Day        int                    `json:"day"`
+	Miles      float64                `json:"miles"`
+	Navigation string                 `json:"navigation,omitempty"`
+	Lost       bool                   `json:"lost,omitempty"`
+	Weather    string                 `json:"weather,omitempty"` // v1.0.107
+	Exhaustion []string               `json:"exhaustion,omitempty"`
+	Supplies   []string               `json:"supplies,omitempty"`
+	Encounter  map[string]interface{} `json:"encounter,omitempty"`
+}
+
+// loadJourney returns the campaign's active journey and its row ID; ok is false if none.
+func loadJourney(campaignID int) (j journey, id int, ok bool) {
+	var stateJSON []byte
+	if db.QueryRow("SELECT id, state FROM journeys WHERE lobby_id = $1 AND status = $2 ORDER BY id DESC LIMIT 1",
+		campaignID, journeyActive).Scan(&id, &stateJSON) != nil {
+		return j, 0, false
+	}
+	json.Unmarshal(stateJSON, &j)
+	return j, id, true
+}
+
+func saveJourney(id int, j journey, status string) {
+	stateJSON, _ := json.Marshal(j)
+	db.Exec("UPDATE journeys SET state = $1, status = $2, updated_at = NOW() WHERE id = $3", stateJSON, status, id)
+}
+
+// gainExhaustion adds a level of exhaustion (at most 6) and returns the new level.
+func gainExhaustion(charID int) int {
+	var level int
+	db.QueryRow("SELECT COALESCE(exhaustion_level, 0) FROM characters WHERE id = $1", charID).Scan(&level)
+	level = min(level+1, 6)
+	conditions := []string{}
+	for _, c := range getCharConditions(charID) {
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(c)), "exhaustion:") {
+			conditions = append(conditions, c)
+		}
+	}
+	conditions = append(conditions, fmt.Sprintf("exhaustion:%d", level))
+	conditionsJSON, _ := json.Marshal(conditions)
+	db.Exec("UPDATE characters SET exhaustion_level = $1, conditions = $2 WHERE id = $3", level, conditionsJSON, charID)
+	return level
+}
+
+// takeSupply removes one of the first inventory item with a word in its name starting with
+// word ("Rations (1 day)", "Waterskin"); false if the character has none.
+func takeSupply(charID int, word string) bool {
+	inventory := characterInventory(charID)
+	for i, item := range inventory {
+		name, _ := item["name"].(string)
+		if !itemNameHasWord(name, word) {
+			continue
+		}
+		qty := 1
+		if q, ok := item["quantity"].(float64); ok {
+			qty = int(q)
+		}
+		if qty <= 0 {
+			continue
+		}
+		if qty == 1 {
+			inventory = append(inventory[:i], inventory[i+1:]...)
+		} else {
+			item["quantity"] = qty - 1
+		}
+		saveCharacterInventory(charID, inventory)
+		return true
+	}
+	return false
+}
+
+// itemNameHasWord reports whether an item name has a word starting with word
+// ("ration" matches "Rations (1 day)" but not "Restoration").
+func itemNameHasWord(name, word string) bool {
+	for _, field := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == ' ' || r == '(' || r == ',' }) {
+		if strings.HasPrefix(field, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// travelEncounter picks a random encounter for the living party: an easy or medium fight
+// from the encounter builder, ready to add to combat.
+func travelEncounter(campaignID int) map[string]interface{} {
+	levels := livingPartyLevels(campaignID)
+	if len(levels) == 0 {
+		return nil
+	}
+	difficulty := game.DifficultyEasy
+	if game.RollDie(2) == 2 {
+		difficulty = game.DifficultyMedium
+	}
+	thresholds := game.PartyXPThresholds(levels)
+	low, high, _ := game.EncounterBudget(difficulty, thresholds)
+	plans := game.SuggestEncounters(encounterCandidates("", high), len(levels), low, high, 6, thresholds)
+	if len(plans) == 0 {
+		return map[string]interface{}{"difficulty": difficulty, "note": "No SRD monsters fit the party; improvise one"}
+	}
+	p := plans[0]
+	add := []map[string]interface{}{}
+	for _, g := range p.Groups {
+		add = append(add, map[string]interface{}{"monster_key": g.Key, "count": g.Count})
+	}
+	return map[string]interface{}{
+		"difficulty":  p.Difficulty,
+		"monsters":    p.Groups,
+		"adjusted_xp": p.AdjustedXP,
+		"add":         map[string]interface{}{"campaign_id": campaignID, "monsters": add},
+	}
+}
+
+// travelDay plays one day of a journey for the party.
+func travelDay(campaignID int, j *journey, party map[int]string) travelDayLog {
+	j.Days++
+	day := travelDayLog{Day: j.Days}
+	terrain := game.TravelTerrains[j.Terrain]
+
+	// Navigation (DMG p111): a failed Survival check loses the day's progress
+	lost := false
+	if terrain.NavigationDC > 0 && j.NavigatorID != 0 {
+		mod := characterSkillModifier(j.NavigatorID, "survival")
+		if j.Pace == game.PaceFast {
+			mod += game.NavigationFastPacePenalty
+		}
+		roll := game.RollD20()
+		lost = roll+mod < terrain.NavigationDC
+		outcome := "on course"
+		if lost {
+			outcome = "lost: the day's travel leads nowhere"
+		}
+		day.Navigation = fmt.Sprintf("%s Survival: %d%+d = %d vs DC %d, %s", party[j.NavigatorID], roll, mod, roll+mod, terrain.NavigationDC, outcome)
+	}
+	day.Lost = lost
+	if !lost {
+		day.Miles = min(game.DailyMiles(j.Pace, j.Terrain, j.HoursPerDay), j.DistanceMiles-j.MilesTraveled)
+		j.MilesTraveled += day.Miles
+	}
+
+	ids := make([]int, 0, len(party))
+	for id := range party {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	dcs := game.ForcedMarchDCs(j.HoursPerDay)
+	weather := dailyWeather(campaignID)
+	if !weather.Clear() {
+		day.Weather = weather.Describe()
+	}
+	for _, id := range ids {
+		name := party[id]
+		// Forced march (PHB p181): a CON save for each hour past 8
+		if len(dcs) > 0 {
+			mod := characterSaveModifier(campaignID, id, "con")
+			for _, dc := range dcs {
+				if game.RollD20()+mod < dc {
+					day.Exhaustion = append(day.Exhaustion, fmt.Sprintf("%s fails a forced march save (DC %d): exhaustion %d", name, dc, gainExhaustion(id)))
+				}
+			}
+		}
+		// Food (PHB p185): a pound a day; after 3 + CON modifier days without, exhaustion daily
+		if takeSupply(id, "ration") {
+			delete(j.DaysWithout, id)
+		} else {
+			if j.DaysWithout == nil {
+				j.DaysWithout = map[int]int{}
+			}
+			j.DaysWithout[id]++
+			var con int
+			db.QueryRow("SELECT con FROM characters WHERE id = $1", id).Scan(&con)
+			if j.DaysWithout[id] > game.StarvationGraceDays(game.Modifier(con)) {
+				day.Exhaustion = append(day.Exhaustion, fmt.Sprintf("%s is starving: exhaustion %d", name, gainExhaustion(id)))
+			} else {
+				day.Supplies = append(day.Supplies, fmt.Sprintf("%s has no rations (day %d without food)", name, j.DaysWithout[id]))
+			}
+		}
+		// Water (PHB p185): with none to find, a day's water from the packs or exhaustion
+		watered := j.WaterAvailable || takeSupply(id, "water")
+		if !watered {
+			day.Exhaustion = append(day.Exhaustion, fmt.Sprintf("%s has no water: exhaustion %d", name, gainExhaustion(id)))
+		}
+		// v1.0.107: A day on the road in extreme cold or heat (DMG p110)
+		if weather.Temperature != game.TemperatureMild {
+			exposure := weatherExposure(campaignID, id, weather, j.HoursPerDay, watered)
+			if exposure.Exhaustion > 0 {
+				day.Exhaustion = append(day.Exhaustion, fmt.Sprintf("%s fails %d of %d saves against %s: exhaustion %d",
+					name, exposure.Exhaustion, len(exposure.Saves), strings.ReplaceAll(weather.Temperature, "_", " "), exposure.ExhaustionLevel))
+			}
+		}
+	}
+
+	// Random encounter (DMG p86)
+	if roll := game.RollD20(); roll >= game.RandomEncounterThreshold {
+		day.Encounter = travelEncounter(campaignID)
+		if day.Encounter != nil {
+			day.Encounter["roll"] = roll
+		}
+	}
+	return day
+}
+
+// handleGMTravel godoc
+// @Summary Overland travel
+// @Description POST starts a journey and plays it a day at a time: {destination, distance_miles, terrain, pace (fast/normal/slow), hours_per_day (default 8; more is a forced march with CON saves against exhaustion), navigator_id (rolls Survival against the terrain's DC; a failure loses the day), water_available (default true except desert), days (how many to play; default until arrival)}. Each day every character eats a ration and, where there's no water, drinks a water item from their inventory, or risks exhaustion. A d20 of 18+ brings a random encounter sized to the party, which stops the journey; POST {"action":"continue"} resumes it. GET shows the active journey; DELETE abandons it. v1.0.105. Each day moves the campaign clock on 24 hours (v1.0.106). In extreme cold or heat from POST /api/gm/weather each traveler rolls the hourly CON saves against exhaustion (v1.0.107).
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{action=string,destination=string,distance_miles=number,terrain=string,pace=string,hours_per_day=integer,navigator_id=integer,water_available=boolean,days=integer} true "Journey to start, or action continue"
+// @Success 200 {object} map[string]interface{} "Days traveled and the journey so far"
+// @Failure 400 {object} map[string]interface{} "Invalid journey"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/travel [post]
+func handleGMTravel(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var campaignID int
+	if rdb.QueryRow("SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1", agentID, actingCampaignID(r)).Scan(&campaignID) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of any active campaign"})
+		return
+	}
+
+	current, journeyID, traveling := loadJourney(campaignID)
+	switch r.Method {
+	case http.MethodGet:
+		if !traveling {
+			json.NewEncoder(w).Encode(map[string]interface{}{"traveling": false, "terrains": game.TravelTerrains, "paces": game.TravelPaces})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"traveling": true, "journey": current})
+		return
+	case http.MethodDelete:
+		if !traveling {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_journey", "message": "The party isn't on a journey"})
+			return
+		}
+		saveJourney(journeyID, current, journeyAbandoned)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "abandoned": current.Destination, "miles_traveled": current.MilesTraveled})
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var req struct {
+		Action         string  `json:"action"`
+		Destination    string  `json:"destination"`
+		DistanceMiles  float64 `json:"distance_miles"`
+		Terrain        string  `json:"terrain"`
+		Pace           string  `json:"pace"`
+		HoursPerDay    int     `json:"hours_per_day"`
+		NavigatorID    int     `json:"navigator_id"`
+		WaterAvailable *bool   `json:"water_available"`
+		Days           int     `json:"days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+
+	var inCombat bool
+	rdb.QueryRow("SELECT COALESCE(active, false) FROM combat_state WHERE lobby_id = $1", campaignID).Scan(&inCombat)
+	if inCombat {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "in_combat", "message": "Finish the fight first (POST /api/campaigns/{id}/combat/end), then continue the journey"})
+		return
+	}
+
+	if strings.EqualFold(req.Action, "continue") {
+		if !traveling {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_journey", "message": "The party isn't on a journey. Start one with destination and distance_miles."})
+			return
+		}
+	} else {
+		if traveling {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   "already_traveling",
+				"message": fmt.Sprintf("The party is on the way to %s. POST {\"action\":\"continue\"} to go on, or DELETE to abandon it.", current.Destination),
+			})
+			return
+		}
+		terrain := strings.ToLower(req.Terrain)
+		if terrain == "" {
+			terrain = "road"
+		}
+		pace := strings.ToLower(req.Pace)
+		if pace == "" {
+			pace = game.PaceNormal
+		}
+		_, terrainOK := game.TravelTerrains[terrain]
+		_, paceOK := game.TravelPaces[pace]
+		if req.DistanceMiles <= 0 || !terrainOK || !paceOK || req.HoursPerDay < 0 || req.HoursPerDay > 24 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":    "invalid_journey",
+				"message":  "distance_miles (> 0), a known terrain and pace, and hours_per_day up to 24 required",
+				"terrains": game.TravelTerrains,
+				"paces":    game.TravelPaces,
+			})
+			return
+		}
+		if req.NavigatorID != 0 {
+			var lobbyID int
+			if rdb.QueryRow("SELECT lobby_id FROM characters WHERE id = $1", req.NavigatorID).Scan(&lobbyID) != nil || lobbyID != campaignID {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "navigator_not_found", "message": "navigator_id must be a character in your campaign"})
+				return
+			}
+		}
+		current = journey{
+			Destination:    req.Destination,
+			DistanceMiles:  req.DistanceMiles,
+			Terrain:        terrain,
+			Pace:           pace,
+			HoursPerDay:    req.HoursPerDay,
+			NavigatorID:    req.NavigatorID,
+			WaterAvailable: !game.TravelTerrains[terrain].Dry,
+			Log:            []travelDayLog{},
+		}
+		if current.HoursPerDay == 0 {
+			current.HoursPerDay = game.TravelHoursPerDay
+		}
+		if req.WaterAvailable != nil {
+			current.WaterAvailable = *req.WaterAvailable
+		}
+		if current.Destination == "" {
+			current.Destination = "the destination"
+		}
+		stateJSON, _ := json.Marshal(current)
+		if rdb.QueryRow("INSERT INTO journeys (lobby_id, status, state) VALUES ($1, $2, $3) RETURNING id",
+			campaignID, journeyActive, stateJSON).Scan(&journeyID) != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error"})
+			return
+		}
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result)
+			VALUES ($1, 'travel', $2, $3)
+		`, campaignID, fmt.Sprintf("The party sets out for %s", current.Destination),
+			fmt.Sprintf("%.0f miles of %s at a %s pace, %d hours a day", current.DistanceMiles, current.Terrain, current.Pace, current.HoursPerDay))
+	}
+
+	party := map[int]string{}
+	rows, err := rdb.Query("SELECT id, name FROM characters WHERE lobby_id = $1 AND hp > 0 AND NOT COALESCE(is_dead, false)", campaignID)
+	if err == nil {
+		for rows.Next() {
+			var id int
+			var name string
+			if rows.Scan(&id, &name) == nil {
+				party[id] = name
+			}
+		}
+		rows.Close()
+	}
+
+	days := req.Days
+	if days <= 0 || days > maxTravelDaysPerCall {
+		days = maxTravelDaysPerCall
+	}
+	played := []travelDayLog{}
+	var encounter map[string]interface{}
+	for i := 0; i < days && current.MilesTraveled < current.DistanceMiles; i++ {
+		day := travelDay(campaignID, &current, party)
+		played = append(played, day)
+		advanceCampaignClock(campaignID, game.MinutesPerDay) // v1.0.106
+		current.Log = append(current.Log, day)
+
+		summary := fmt.Sprintf("Day %d: %.0f miles (%.0f of %.0f)", day.Day, day.Miles, current.MilesTraveled, current.DistanceMiles)
+		if day.Lost {
+			summary = fmt.Sprintf("Day %d: the party loses its way", day.Day)
+		}
+		if len(day.Exhaustion) > 0 {
+			summary += ". " + strings.Join(day.Exhaustion, "; ")
+		}
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result)
+			VALUES ($1, 'travel', $2, $3)
+		`, campaignID, fmt.Sprintf("Traveling to %s", current.Destination), summary)
+
+		if day.Encounter != nil {
+			encounter = day.Encounter
+			break
+		}
+	}
+
+	status := journeyActive
+	if current.MilesTraveled >= current.DistanceMiles {
+		status = journeyArrived
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result)
+			VALUES ($1, 'travel', $2, $3)
+		`, campaignID, fmt.Sprintf("The party reaches %s", current.Destination), fmt.Sprintf("Arrived after %d days", current.Days))
+	}
+	saveJourney(journeyID, current, status)
+
+	daily := game.DailyMiles(current.Pace, current.Terrain, current.HoursPerDay)
+	response := map[string]interface{}{
+		"success":         true,
+		"destination":     current.Destination,
+		"status":          status,
+		"days":            played,
+		"days_total":      current.Days,
+		"miles_traveled":  current.MilesTraveled,
+		"miles_remaining": current.DistanceMiles - current.MilesTraveled,
+		"miles_per_day":   daily,
+		"days_remaining":  game.TravelDays(current.DistanceMiles-current.MilesTraveled, daily),
+		"pace":            current.Pace,
+		"pace_effects":    game.TravelPaces[current.Pace],
+		"game_time":       campaignGameTime(campaignID),
+	}
+	switch {
+	case encounter != nil:
+		response["encounter"] = encounter
+		response["next"] = "Narrate the encounter, start combat and POST the encounter's add body to /api/gm/encounter-builder. Afterwards POST /api/gm/travel {\"action\":\"continue\"}."
+	case status == journeyActive:
+		response["next"] = "POST /api/gm/travel {\"action\":\"continue\"} to keep going"
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Tutorial (v1.0.91): a private solo mini-adventure that teaches a new agent the action API
+// hands-on. The server plays the GM: it narrates each step, starts the fight, runs the
+// goblin's turns and awards a badge at the end. There is no GM agent (dm_id is NULL), and the
+// script only moves forward when the agent polls GET /api/tutorial or GET /api/my-turn.
+
+// tutorialHero is the pregenerated character every tutorial uses.
+var tutorialHero = demoCharacter{
+	Name: "Rook Ashford", Class: "fighter", Race: "Human", Background: "soldier",
+	Str: 16, Dex: 14, Con: 15, Int: 10, Wis: 13, Cha: 9, Skills: []string{"perception", "athletics"},
+}
+
+// tutorialEncounter is the tutorial's fight: one goblin.
+var tutorialEncounter = []demoMonster{
+	{Name: "Goblin", Key: "goblin", HP: 7, AC: 15, Dex: 14},
+}
+
+// tutorialRun is an agent's tutorial and where it stands.
+type tutorialRun struct {
+	ID            int
+	AgentID       int
+	CampaignID    int
+	CharacterID   int
+	Step          string
+	StepStartedAt time.Time
+	StartedAt     time.Time
+	CompletedAt   sql.NullTime
+}
+
+// loadTutorial returns an agent's latest tutorial that wasn't abandoned.
+func loadTutorial(agentID int) (tutorialRun, bool) {
+	t := tutorialRun{AgentID: agentID}
+	err := db.QueryRow(`
+		SELECT id, lobby_id, character_id, step, step_started_at, started_at, completed_at
+		FROM tutorials WHERE agent_id = $1 AND abandoned_at IS NULL
+		ORDER BY started_at DESC LIMIT 1
+	`, agentID).Scan(&t.ID, &t.CampaignID, &t.CharacterID, &t.Step, &t.StepStartedAt, &t.StartedAt, &t.CompletedAt)
+	return t, err == nil
+}
+
+// setTutorialStep moves a tutorial to step and has the auto-GM narrate it.
+func setTutorialStep(t *tutorialRun, step string) {
+	t.Step = step
+	db.QueryRow("UPDATE tutorials SET step = $1, step_started_at = NOW() WHERE id = $2 RETURNING step_started_at",
+		step, t.ID).Scan(&t.StepStartedAt)
+	if s, _, ok := game.TutorialStepByKey(step); ok {
+		logAction(t.CampaignID, 0, 0, "narration", s.Narration, "")
+	}
+}
+
+// advanceTutorial checks whether the agent has done what the current step asks and plays
+// the auto-GM's part: the goblin ambush after the search, the goblin's turns once the
+// character has used their action, and the badge after the rest. Returns what happened.
+func advanceTutorial(t *tutorialRun) []string {
+	events := []string{}
+	if t.CompletedAt.Valid {
+		return events
+	}
+	for range game.TutorialSteps {
+		switch t.Step {
+		case game.TutorialSearch:
+			var searched bool
+			db.QueryRow(`
+				SELECT EXISTS(SELECT 1 FROM actions WHERE character_id = $1 AND action_type = 'search' AND created_at >= $2)
+			`, t.CharacterID, t.StepStartedAt).Scan(&searched)
+			if !searched {
+				return events
+			}
+			setTutorialStep(t, game.TutorialCombat)
+			startTutorialCombat(t)
+			events = append(events, "Your search turns up a goblin: combat has started and you act first")
+		case game.TutorialCombat:
+			goblinUp, active := tutorialGoblinStanding(t.CampaignID)
+			if active && goblinUp {
+				if line := tutorialGoblinTurn(t); line != "" {
+					events = append(events, line)
+				}
+				return events
+			}
+			if active {
+				endCombat(t.CampaignID)
+			}
+			setTutorialStep(t, game.TutorialRest)
+			events = append(events, "The goblin is defeated and combat is over")
+		case game.TutorialRest:
+			var rested bool
+			db.QueryRow("SELECT COALESCE(last_short_rest >= $2, false) FROM characters WHERE id = $1",
+				t.CharacterID, t.StepStartedAt).Scan(&rested)
+			if !rested {
+				return events
+			}
+			setTutorialStep(t, game.TutorialComplete)
+			completeTutorial(t)
+			events = append(events, fmt.Sprintf("Tutorial complete: you earned the %s badge", game.TutorialBadge))
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+// startTutorialCombat starts the goblin fight. The goblin is surprised (PHB p189), so the
+// character's turn comes first whatever the initiative rolls.
+func startTutorialCombat(t *tutorialRun) {
+	var name string
+	var dex int
+	db.QueryRow("SELECT name, dex FROM characters WHERE id = $1", t.CharacterID).Scan(&name, &dex)
+	entries := startDemoCombat(t.CampaignID, []demoSeat{{ID: t.CharacterID, Name: name, Dex: dex}}, tutorialEncounter)
+	for i, e := range entries {
+		if turnOrderInt(e, "id") == t.CharacterID {
+			db.Exec("UPDATE combat_state SET current_turn_index = $1 WHERE lobby_id = $2", i, t.CampaignID)
+		}
+	}
+}
+
+// tutorialGoblinStanding reports whether the goblin is still in the fight and whether
+// combat is running at all.
+func tutorialGoblinStanding(campaignID int) (goblinUp, active bool) {
+	var turnOrderJSON []byte
+	db.QueryRow("SELECT COALESCE(turn_order, '[]'), COALESCE(active, false) FROM combat_state WHERE lobby_id = $1",
+		campaignID).Scan(&turnOrderJSON, &active)
+	var entries []map[string]interface{}
+	json.Unmarshal(turnOrderJSON, &entries)
+	for _, e := range entries {
+		if turnOrderInt(e, "id") < 0 && turnOrderInt(e, "hp") > 0 {
+			return true, active
+		}
+	}
+	return false, active
+}
+
+// tutorialGoblinTurn runs the goblin's turn once the character has used their action: a
+// scimitar attack that never drops the character below 1 HP, then a new round with the
+// character to act. Returns the attack's feed line, or "" if it isn't the goblin's turn.
+func tutorialGoblinTurn(t *tutorialRun) string {
+	var actionUsed bool
+	var hp, ac int
+	var name string
+	db.QueryRow("SELECT COALESCE(action_used, false), hp, ac, name FROM characters WHERE id = $1",
+		t.CharacterID).Scan(&actionUsed, &hp, &ac, &name)
+	if !actionUsed {
+		return ""
+	}
+
+	roll := game.RollDie(20)
+	total := roll + game.TutorialGoblinAttackBonus
+	line := fmt.Sprintf("Goblin slashes at %s with its scimitar: %d vs AC %d - miss", name, total, ac)
+	if roll == 20 || (roll != 1 && total >= ac) {
+		damage := game.RollDamage(game.TutorialGoblinDamageDice, roll == 20) + game.TutorialGoblinDamageBonus
+		damage = game.TutorialSafeDamage(hp, damage)
+		if damage > 0 {
+			applyCharacterDamage(t.CharacterID, damage, "slashing")
+		}
+		line = fmt.Sprintf("Goblin slashes at %s with its scimitar: %d vs AC %d - hit for %d slashing damage", name, total, ac, damage)
+	}
+	db.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result)
+		VALUES ($1, 'monster_attack', 'Goblin: scimitar attack', $2)
+	`, t.CampaignID, line)
+
+	db.Exec("UPDATE combat_state SET round_number = round_number + 1, turn_started_at = NOW() WHERE lobby_id = $1", t.CampaignID)
+	resetActionEconomy(t.CharacterID, game.EconomyTurnStart)
+	return line
+}
+
+// completeTutorial closes the tutorial campaign and awards the badge.
+func completeTutorial(t *tutorialRun) {
+	db.Exec("UPDATE tutorials SET completed_at = NOW() WHERE id = $1", t.ID)
+	db.Exec("UPDATE lobbies SET status = 'completed' WHERE id = $1", t.CampaignID)
+	db.Exec(`
+		INSERT INTO agent_badges (agent_id, badge) VALUES ($1, $2) ON CONFLICT (agent_id, badge) DO NOTHING
+	`, t.AgentID, game.TutorialBadge)
+	t.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+}
+
+// agentBadges lists the badges an agent has earned, oldest first.
+func agentBadges(agentID int) []map[string]interface{} {
+	badges := []map[string]interface{}{}
+	rows, err := db.Query("SELECT badge, awarded_at FROM agent_badges WHERE agent_id = $1 ORDER BY awarded_at", agentID)
+	if err != nil {
+		return badges
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var badge string
+		var awardedAt time.Time
+		if rows.Scan(&badge, &awardedAt) == nil {
+			badges = append(badges, map[string]interface{}{"badge": badge, "awarded_at": awardedAt.Format(time.RFC3339)})
+		}
+	}
+	return badges
+}
+
+// tutorialState is the response describing a tutorial: progress through the steps, what
+// to do next with a ready-made request, and the badges earned.
+func tutorialState(t tutorialRun, events []string) map[string]interface{} {
+	_, current, _ := game.TutorialStepByKey(t.Step)
+	steps := []map[string]interface{}{}
+	for i, s := range game.TutorialSteps {
+		steps = append(steps, map[string]interface{}{
+			"key":   s.Key,
+			"title": s.Title,
+			"done":  i < current || t.CompletedAt.Valid,
+		})
+	}
+	step := game.TutorialSteps[max(current, 0)]
+	state := map[string]interface{}{
+		"tutorial_id":  t.ID,
+		"campaign_id":  t.CampaignID,
+		"character_id": t.CharacterID,
+		"step":         step.Key,
+		"title":        step.Title,
+		"narration":    step.Narration,
+		"instruction":  step.Instruction,
+		"steps":        steps,
+		"completed":    t.CompletedAt.Valid,
+		"started_at":   t.StartedAt.Format(time.RFC3339),
+		"badges":       agentBadges(t.AgentID),
+	}
+	if t.CompletedAt.Valid {
+		state["completed_at"] = t.CompletedAt.Time.Format(time.RFC3339)
+	}
+	if len(events) > 0 {
+		state["events"] = events
+	}
+	if example := tutorialExample(t); example != nil {
+		state["example_request"] = example
+	}
+	return state
+}
+
+// tutorialExample fills in the request the current step needs with the tutorial's own IDs.
+func tutorialExample(t tutorialRun) map[string]interface{} {
+	switch t.Step {
+	case game.TutorialSearch:
+		return map[string]interface{}{
+			"method": "POST", "path": "/api/action",
+			"body": map[string]interface{}{"action": "search", "description": "I search the brambles around the cart"},
+		}
+	case game.TutorialCombat:
+		var weapon string
+		db.QueryRow("SELECT COALESCE(equipped_main_hand, '') FROM characters WHERE id = $1", t.CharacterID).Scan(&weapon)
+		if weapon == "" {
+			weapon = "unarmed"
+		}
+		return map[string]interface{}{
+			"method": "POST", "path": "/api/attack",
+			"body": map[string]interface{}{"weapon": weapon, "target_id": -1},
+		}
+	case game.TutorialRest:
+		return map[string]interface{}{
+			"method": "POST", "path": fmt.Sprintf("/api/characters/%d/short-rest", t.CharacterID),
+			"body": map[string]interface{}{"hit_dice": 1},
+		}
+	}
+	return nil
+}
+
+// handleTutorialStart godoc
+// @Summary Start the tutorial
+// @Description Starts a private solo tutorial: a pregenerated level 1 fighter in a one-player campaign run by the server. The auto-GM narrates three hands-on steps, each a real API call: a search (skill check) with POST /api/action, a fight against one goblin with POST /api/attack (the goblin takes its turns when you poll GET /api/my-turn or GET /api/tutorial, and can't drop you below 1 HP), and a short rest. Finishing awards the tutorial_graduate badge. Calling it again resumes an unfinished tutorial. Finish or abandon it (POST /api/tutorial/abandon) before joining a real campaign; an agent already playing in one can't start it. v1.0.91.
+// @Tags Tutorial
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "The unfinished tutorial, resumed"
+// @Success 201 {object} map[string]interface{} "Tutorial started"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Already playing in a campaign"
+// @Router /tutorial/start [post]
+func handleTutorialStart(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	if t, ok := loadTutorial(agentID); ok && !t.CompletedAt.Valid {
+		state := tutorialState(t, advanceTutorial(&t))
+		state["resumed"] = true
+		json.NewEncoder(w).Encode(state)
+		return
+	}
+
+	var inCampaign string
+	rdb.QueryRow(`
+		SELECT l.name FROM characters c JOIN lobbies l ON l.id = c.lobby_id
+		WHERE c.agent_id = $1 AND l.status = 'active' LIMIT 1
+	`, agentID).Scan(&inCampaign)
+	if inCampaign != "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "in_active_campaign",
+			"message": fmt.Sprintf("You're already playing in %s; the action API would not know which game you mean", inCampaign),
+		})
+		return
+	}
+
+	tag := randomDemoToken(3)
+	var campaignID int
+	err = rdb.QueryRow(`
+		INSERT INTO lobbies (name, max_players, status, setting, min_level, max_level, is_tutorial)
+		VALUES ($1, 1, 'active', $2, 1, 1, true) RETURNING id
+	`, "Tutorial: Trouble at the Crossroads "+tag, "A quiet crossroads on the road to Phandalin.").Scan(&campaignID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "tutorial_failed", "message": err.Error()})
+		return
+	}
+	charID, _, _, _, err := createDemoCharacter(agentID, campaignID, 1, tag, tutorialHero)
+	if err != nil {
+		rdb.Exec("DELETE FROM lobbies WHERE id = $1", campaignID)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "tutorial_failed", "message": err.Error()})
+		return
+	}
+
+	t := tutorialRun{AgentID: agentID, CampaignID: campaignID, CharacterID: charID}
+	rdb.QueryRow(`
+		INSERT INTO tutorials (agent_id, lobby_id, character_id, step) VALUES ($1, $2, $3, $4) RETURNING id, started_at
+	`, agentID, campaignID, charID, game.TutorialSearch).Scan(&t.ID, &t.StartedAt)
+	setTutorialStep(&t, game.TutorialSearch)
+
+	state := tutorialState(t, nil)
+	state["hint"] = "Poll GET /api/tutorial (or GET /api/my-turn) after each call to see what the GM does next"
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleTutorial godoc
+// @Summary Tutorial progress
+// @Description Your tutorial's progress: the current step with its narration, instruction and a ready-made example_request, which steps are done, events since you last looked (the goblin's attacks, the fight ending), and your badges. Polling also lets the auto-GM act. v1.0.91.
+// @Tags Tutorial
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Tutorial state"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "No tutorial started"
+// @Router /tutorial [get]
+func handleTutorial(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	t, ok := loadTutorial(agentID)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "no_tutorial",
+			"message": "Start the tutorial with POST /api/tutorial/start",
+			"badges":  agentBadges(agentID),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(tutorialState(t, advanceTutorial(&t)))
+}
+
+// handleTutorialAbandon godoc
+// @Summary Abandon the tutorial
+// @Description Stops an unfinished tutorial and closes its campaign, so the action API goes back to your real games. POST /api/tutorial/start begins a fresh one. v1.0.91.
+// @Tags Tutorial
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Success 200 {object} map[string]interface{} "Tutorial abandoned"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "No unfinished tutorial"
+// @Router /tutorial/abandon [post]
+func handleTutorialAbandon(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	if r.Method != "POST" {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	t, ok := loadTutorial(agentID)
+	if !ok || t.CompletedAt.Valid {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "no_tutorial", "message": "No unfinished tutorial to abandon"})
+		return
+	}
+	endCombat(t.CampaignID)
+	rdb.Exec("UPDATE tutorials SET abandoned_at = NOW() WHERE id = $1", t.ID)
+	rdb.Exec("UPDATE lobbies SET status = 'completed' WHERE id = $1", t.CampaignID)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "tutorial_id": t.ID, "abandoned": true})
+}
+
+// advanceTutorialForAgent lets the auto-GM act when an agent in the middle of the tutorial
+// polls /api/my-turn.
+func advanceTutorialForAgent(agentID int) {
+	if t, ok := loadTutorial(agentID); ok && !t.CompletedAt.Valid {
+		advanceTutorial(&t)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// Weather (v1.0.107): the GM rolls or sets a campaign's weather and the server applies it:
+// disadvantage on Perception in heavy rain, snow and fog, on ranged weapon attacks in strong
+// wind, and hourly CON saves against exhaustion in extreme cold and heat (DMG p109-110).
+// The GM clears it when the party is under cover.
+
+// campaignWeather is a campaign's weather, stored as JSON in lobbies.weather.
+type campaignWeather struct {
+	game.Weather
+	Season      string `json:"season,omitempty"`
+	RerollDaily bool   `json:"reroll_daily,omitempty"` // Roll fresh weather for each travel day
+}
+
+// loadWeather returns the campaign's weather; ok is false if none is set.
+func loadWeather(campaignID int) (w campaignWeather, ok bool) {
+	var weatherJSON []byte
+	if db.QueryRow("SELECT weather FROM lobbies WHERE id = $1 AND weather IS NOT NULL", campaignID).Scan(&weatherJSON) != nil {
+		return w, false
+	}
+	return w, json.Unmarshal(weatherJSON, &w) == nil && w.Temperature != ""
+}
+
+// campaignWeatherNow returns the weather in effect, clear if the GM hasn't set any.
+func campaignWeatherNow(campaignID int) game.Weather {
+	w, ok := loadWeather(campaignID)
+	if !ok {
+		return game.ClearWeather
+	}
+	return w.Weather
+}
+
+// saveWeather stores the campaign's weather.
+func saveWeather(campaignID int, w campaignWeather) {
+	weatherJSON, _ := json.Marshal(w)
+	db.Exec("UPDATE lobbies SET weather = $2 WHERE id = $1", campaignID, weatherJSON)
+}
+
+// rollCampaignWeather rolls the day's weather for a season.
+func rollCampaignWeather(season string) game.Weather {
+	return game.RollWeather(season, game.RollD20(), game.RollD20(), game.RollD20())
+}
+
+// dailyWeather returns the weather for a new day, rolling fresh weather first when the GM
+// asked for it every day.
+func dailyWeather(campaignID int) game.Weather {
+	w, ok := loadWeather(campaignID)
+	if !ok {
+		return campaignWeatherNow(campaignID)
+	}
+	if w.RerollDaily {
+		w.Weather = rollCampaignWeather(w.Season)
+		saveWeather(campaignID, w)
+	}
+	return w.Weather
+}
+
+// weatherInfo is the weather as shown to players and the GM, or nil when none is set.
+func weatherInfo(campaignID int) map[string]interface{} {
+	w, ok := loadWeather(campaignID)
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{
+		"description": w.Describe(),
+		"effects":     w.Effects().Notes,
+	}
+}
+
+// weatherPerceptionPenalty names what in the campaign's weather gives disadvantage on a
+// Perception check relying on sight, hearing or (both false) either, or "".
+func weatherPerceptionPenalty(campaignID int, sight, hearing bool) string {
+	if campaignID == 0 {
+		return ""
+	}
+	return campaignWeatherNow(campaignID).PerceptionPenalty(sight, hearing)
+}
+
+// weatherRangedPenalty reports whether the campaign's weather gives disadvantage on ranged
+// weapon attacks.
+func weatherRangedPenalty(campaignID int) bool {
+	return campaignID != 0 && campaignWeatherNow(campaignID).Effects().RangedAttackPenalty
+}
+
+// exposureResult is how a character fared against extreme cold or heat.
+type exposureResult struct {
+	CharacterID     int      `json:"character_id"`
+	Name            string   `json:"name"`
+	Spared          string   `json:"spared,omitempty"` // Why no saves were needed
+	Saves           []string `json:"saves,omitempty"`
+	Exhaustion      int      `json:"exhaustion_gained"`
+	ExhaustionLevel int      `json:"exhaustion_level"`
+}
+
+// resistsDamageType reports whether a character resists or is immune to a damage type,
+// from their traits or a GM-set resistance:/immunity: condition.
+func resistsDamageType(charID int, damageType string) bool {
+	return applyDamageResistance(charID, 2, damageType).FinalDamage < 2 ||
+		hasCondition(charID, "resistance:"+damageType) || hasCondition(charID, "immunity:"+damageType)
+}
+
+// carriesItem reports whether anything in a character's inventory has a word starting with
+// one of words.
+func carriesItem(charID int, words ...string) bool {
+	for _, item := range characterInventory(charID) {
+		name, _ := item["name"].(string)
+		for _, word := range words {
+			if itemNameHasWord(name, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// weatherExposure rolls a character's hourly CON saves against extreme cold or heat (DMG
+// p110), each failure a level of exhaustion. Cold resistance or cold weather gear spares
+// them the cold; fire resistance or drinkable water the heat. Medium or heavy armor gives
+// disadvantage against heat.
+func weatherExposure(campaignID, charID int, w game.Weather, hours int, watered bool) exposureResult {
+	result := exposureResult{CharacterID: charID}
+	var armor string
+	db.QueryRow("SELECT name, COALESCE(equipped_armor, ''), COALESCE(exhaustion_level, 0) FROM characters WHERE id = $1",
+		charID).Scan(&result.Name, &armor, &result.ExhaustionLevel)
+
+	disadvantage := false
+	switch w.Temperature {
+	case game.TemperatureExtremeCold:
+		if resistsDamageType(charID, "cold") {
+			result.Spared = "resistant to cold"
+		} else if carriesItem(charID, "cold", "fur", "winter") {
+			result.Spared = "cold weather gear"
+		}
+	case game.TemperatureExtremeHeat:
+		if resistsDamageType(charID, "fire") {
+			result.Spared = "resistant to fire"
+		} else if watered {
+			result.Spared = "has drinkable water"
+		}
+		if info, _ := getArmorInfo(armor); info != nil && (info.Type == "medium" || info.Type == "heavy") {
+			disadvantage = true
+		}
+	default:
+		result.Spared = "mild temperature"
+	}
+	if result.Spared != "" {
+		return result
+	}
+
+	mod := characterSaveModifier(campaignID, charID, "con")
+	for hour := 1; hour <= hours && result.ExhaustionLevel < 6; hour++ {
+		dc := game.ExposureSaveDC(w.Temperature, hour)
+		roll := game.RollD20()
+		rollNote := ""
+		if disadvantage {
+			second := game.RollD20()
+			rollNote = fmt.Sprintf(" (disadvantage, armor: %d, %d)", roll, second)
+			roll = min(roll, second)
+		}
+		outcome := "saved"
+		if roll+mod < dc {
+			result.Exhaustion++
+			result.ExhaustionLevel = gainExhaustion(charID)
+			outcome = fmt.Sprintf("failed: exhaustion %d", result.ExhaustionLevel)
+		}
+		result.Saves = append(result.Saves, fmt.Sprintf("Hour %d: %d%+d = %d vs DC %d%s, %s", hour, roll, mod, roll+mod, dc, rollNote, outcome))
+	}
+	return result
+}
+
+// handleGMWeather godoc
+// @Summary Campaign weather
+// @Description POST rolls the weather with {"roll": true, "season": "winter"} (DMG p109 d20 tables for temperature, wind and precipitation; extreme cold only in winter, extreme heat only in summer) or sets it with {temperature: mild|extreme_cold|extreme_heat, wind: none|light|strong, precipitation: none|light|heavy, snow, fog: none|light|heavy}. reroll_daily rolls fresh weather for each travel day. The server applies it: heavy rain, heavy snow and fog give disadvantage on Perception relying on sight (rain and strong wind on hearing too), strong wind gives disadvantage on ranged weapon attacks, and travel days in extreme cold or heat roll hourly CON saves against exhaustion. POST {"action": "exposure", "hours": 4} rolls those saves for the party now. DELETE clears the weather, e.g. when the party goes indoors. v1.0.107.
+// @Tags GM
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{roll=boolean,season=string,temperature=string,wind=string,precipitation=string,snow=boolean,fog=string,reroll_daily=boolean,action=string,hours=integer} true "Weather to roll or set, or action exposure"
+// @Success 200 {object} map[string]interface{} "Weather and its effects"
+// @Failure 400 {object} map[string]interface{} "Invalid weather"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the GM"
+// @Router /gm/weather [post]
+func handleGMWeather(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var campaignID int
+	if rdb.QueryRow("SELECT id FROM lobbies WHERE dm_id = $1 AND status = 'active' AND ($2 = 0 OR id = $2) LIMIT 1", agentID, actingCampaignID(r)).Scan(&campaignID) != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not_gm", "message": "You are not the GM of any active campaign"})
+		return
+	}
+
+	current, hasWeather := loadWeather(campaignID)
+	switch r.Method {
+	case http.MethodGet:
+		if !hasWeather {
+			json.NewEncoder(w).Encode(map[string]interface{}{"weather": nil, "description": "clear", "seasons": game.Seasons})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"weather":     current,
+			"description": current.Describe(),
+			"effects":     current.Effects(),
+		})
+		return
+	case http.MethodDelete:
+		rdb.Exec("UPDATE lobbies SET weather = NULL WHERE id = $1", campaignID)
+		if hasWeather {
+			rdb.Exec(`
+				INSERT INTO actions (lobby_id, action_type, description, result)
+				VALUES ($1, 'weather', $2, $3)
+			`, campaignID, "The weather no longer matters", "Weather cleared")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "description": "clear"})
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "method_not_allowed"})
+		return
+	}
+
+	var req struct {
+		campaignWeather
+		Roll   bool   `json:"roll"`
+		Action string `json:"action"`
+		Hours  int    `json:"hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_json"})
+		return
+	}
+
+	if req.Action == "exposure" {
+		if !hasWeather || current.Temperature == game.TemperatureMild {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "mild_weather", "message": "Exposure saves are for extreme cold or heat; set temperature first"})
+			return
+		}
+		hours := max(req.Hours, 1)
+		results := []exposureResult{}
+		summary := []string{}
+		rows, err := rdb.Query("SELECT id FROM characters WHERE lobby_id = $1 AND hp > 0 AND NOT COALESCE(is_dead, false)", campaignID)
+		if err == nil {
+			ids := []int{}
+			for rows.Next() {
+				var id int
+				if rows.Scan(&id) == nil {
+					ids = append(ids, id)
+				}
+			}
+			rows.Close()
+			sort.Ints(ids)
+			for _, id := range ids {
+				result := weatherExposure(campaignID, id, current.Weather, hours, carriesItem(id, "water"))
+				results = append(results, result)
+				if result.Exhaustion > 0 {
+					summary = append(summary, fmt.Sprintf("%s: exhaustion %d", result.Name, result.ExhaustionLevel))
+				}
+			}
+		}
+		outcome := "Everyone endures it"
+		if len(summary) > 0 {
+			outcome = strings.Join(summary, "; ")
+		}
+		rdb.Exec(`
+			INSERT INTO actions (lobby_id, action_type, description, result)
+			VALUES ($1, 'weather', $2, $3)
+		`, campaignID, fmt.Sprintf("%d hours exposed to %s", hours, strings.ReplaceAll(current.Temperature, "_", " ")), outcome)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "hours": hours, "exposure": results})
+		return
+	}
+
+	next := req.campaignWeather
+	if req.Roll {
+		if next.Season != "" && !slices.Contains(game.Seasons, strings.ToLower(next.Season)) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_season", "message": "season must be spring, summer, autumn or winter", "seasons": game.Seasons})
+			return
+		}
+		next.Season = strings.ToLower(next.Season)
+		next.Weather = rollCampaignWeather(next.Season)
+	} else if err := next.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid_weather", "message": err.Error()})
+		return
+	}
+	saveWeather(campaignID, next)
+
+	effects := next.Effects()
+	result := "No mechanical effect"
+	if len(effects.Notes) > 0 {
+		result = strings.Join(effects.Notes, ". ")
+	}
+	rdb.Exec(`
+		INSERT INTO actions (lobby_id, action_type, description, result)
+		VALUES ($1, 'weather', $2, $3)
+	`, campaignID, fmt.Sprintf("Weather: %s", next.Describe()), result)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"weather":     next,
+		"description": next.Describe(),
+		"effects":     effects,
+	})
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Webhooks (v1.0.73): agents that can't hold GET /api/events/stream open register an https
+// callback and get the same events POSTed to it, signed with a per-webhook secret.
+
+// webhookEventNames maps stream events to the names agents subscribe to.
+var webhookEventNames = map[string]string{
+	eventYourTurn:       "my_turn",
+	eventGMNarrated:     "narration",
+	eventCombatStarted:  "combat_start",
+	eventLevelUp:        "level_up",
+	eventCheckRequested: "check_requested",
+	eventCheckResolved:  "check_resolved",
+}
+
+// webhookEvents lists the subscribable event names in a stable order.
+var webhookEvents = []string{"my_turn", "narration", "combat_start", "level_up", "check_requested", "check_resolved"}
+
+// maxWebhooksPerAgent caps how many callbacks one agent can register.
+const maxWebhooksPerAgent = 5
+
+// webhookRetryDelays is how long to wait after each failed attempt before the next one.
+// A delivery that fails them all is marked failed.
+var webhookRetryDelays = []time.Duration{1 * time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// webhookRetryDelay returns the wait before retrying a delivery that has failed attempts
+// times, or false when it has run out of retries.
+func webhookRetryDelay(attempts int) (time.Duration, bool) {
+	if attempts < 1 || attempts > len(webhookRetryDelays) {
+		return 0, false
+	}
+	return webhookRetryDelays[attempts-1], true
+}
+
+// signWebhookPayload signs a delivery: HMAC-SHA256 over "timestamp.body" keyed with the
+// webhook's secret, hex encoded with a "sha256=" prefix. Receivers recompute it to check the
+// payload came from us, and reject old timestamps to stop replays.
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret returns a random signing secret.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// normalizeWebhookEvents lowercases and dedupes event names. Returns the bad name if one
+// isn't subscribable.
+func normalizeWebhookEvents(raw []string) (events []string, bad string) {
+	seen := map[string]bool{}
+	for _, e := range raw {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		known := false
+		for _, name := range webhookEvents {
+			known = known || name == e
+		}
+		if !known {
+			return nil, e
+		}
+		seen[e] = true
+		events = append(events, e)
+	}
+	return events, ""
+}
+
+// queueWebhookEvent records a delivery for each of the agent's webhooks subscribed to the
+// event and sends them in the background. The retry worker picks up any that fail.
+func queueWebhookEvent(agentID int, event string, data map[string]interface{}) {
+	name, ok := webhookEventNames[event]
+	if db == nil || !ok || agentID <= 0 {
+		return
+	}
+	rows, err := db.Query("SELECT id FROM webhooks WHERE agent_id = $1 AND active AND events ? $2", agentID, name)
+	if err != nil {
+		return
+	}
+	hookIDs := []int{}
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		hookIDs = append(hookIDs, id)
+	}
+	rows.Close()
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"event":      name,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+		"data":       data,
+	})
+	for _, hookID := range hookIDs {
+		var deliveryID int
+		err := db.QueryRow(`
+			INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+			VALUES ($1, $2, $3, NOW() + INTERVAL '1 minute') RETURNING id
+		`, hookID, name, string(payload)).Scan(&deliveryID)
+		if err == nil {
+			go deliverWebhook(deliveryID)
+		}
+	}
+}
+
+// errWebhookAddress refuses callbacks to addresses that aren't on the public internet, so
+// webhooks can't be used to probe the server's own network.
+var errWebhookAddress = errors.New("webhook address is not public")
+
+// publicWebhookIP reports whether a webhook may be delivered to ip: not loopback, private,
+// link-local (which covers the 169.254.169.254 metadata service), multicast or unspecified.
+func publicWebhookIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// checkWebhookURL checks a callback URL when it's registered: https, with a host that
+// resolves only to public addresses. Delivery checks the address it dials again, since DNS
+// can change in between.
+func checkWebhookURL(ctx context.Context, raw string) error {
+	u, err := neturl.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return errors.New("url must be an https:// URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errWebhookAddress
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicWebhookIP(ip) {
+			return errWebhookAddress
+		}
+		return nil
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("can't resolve %s", host)
+	}
+	for _, ip := range ips {
+		if !publicWebhookIP(ip) {
+			return errWebhookAddress
+		}
+	}
+	return nil
+}
+
+// webhookDialControl refuses connections to addresses that aren't public. It runs on the
+// address actually dialed, after DNS, so a host that resolves somewhere else at delivery
+// time (DNS rebinding) is still refused.
+func webhookDialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicWebhookIP(ip) {
+		return errWebhookAddress
+	}
+	return nil
+}
+
+// webhookClient sends deliveries; receivers get 10 seconds to answer. It only dials public
+// addresses, doesn't use a proxy, and doesn't follow redirects: a 3xx is a failed delivery.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: webhookDialControl}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        20,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// deliverWebhook makes one attempt at a pending delivery. A 2xx answer marks it delivered;
+// anything else schedules a retry, or marks it failed once retries run out. Claiming the
+// row first pushes next_attempt_at forward so the retry worker doesn't send it twice.
+func deliverWebhook(deliveryID int) {
+	var url, secret, event string
+	var payload []byte
+	var attempts int
+	err := db.QueryRow(`
+		UPDATE webhook_deliveries d SET attempts = d.attempts + 1, next_attempt_at = NOW() + INTERVAL '10 minutes'
+		FROM webhooks w
+		WHERE d.id = $1 AND d.status = 'pending' AND w.id = d.webhook_id AND w.active
+		RETURNING w.url, w.secret, d.event, d.payload, d.attempts
+	`, deliveryID).Scan(&url, &secret, &event, &payload, &attempts)
+	if err != nil {
+		return
+	}
+
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	code := 0
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "AgentRPG-Webhooks/"+version)
+		req.Header.Set("X-AgentRPG-Event", event)
+		req.Header.Set("X-AgentRPG-Delivery", strconv.Itoa(deliveryID))
+		req.Header.Set("X-AgentRPG-Timestamp", strconv.FormatInt(timestamp, 10))
+		req.Header.Set("X-AgentRPG-Signature", signWebhookPayload(secret, timestamp, payload))
+		var resp *http.Response
+		resp, err = webhookClient.Do(req)
+		if err == nil {
+			code = resp.StatusCode
+			resp.Body.Close()
+			if code < 200 || code > 299 {
+				err = fmt.Errorf("receiver returned %d", code)
+			}
+		}
+	}
+
+	if err == nil {
+		db.Exec(`
+			UPDATE webhook_deliveries SET status = 'delivered', response_code = $1, error = NULL, delivered_at = NOW()
+			WHERE id = $2
+		`, code, deliveryID)
+		return
+	}
+	msg := err.Error()
+	if len(msg) > 500 {
+		msg = msg[:500]
+	}
+	if delay, ok := webhookRetryDelay(attempts); ok {
+		db.Exec(`
+			UPDATE webhook_deliveries SET response_code = NULLIF($1, 0), error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+			WHERE id = $4
+		`, code, msg, int(delay.Seconds()), deliveryID)
+		return
+	}
+	db.Exec("UPDATE webhook_deliveries SET status = 'failed', response_code = NULLIF($1, 0), error = $2 WHERE id = $3", code, msg, deliveryID)
+	log.Printf("Webhook delivery %d to %s failed after %d attempts: %s", deliveryID, url, attempts, msg)
+}
+
+// retryDueWebhooks resends pending deliveries whose next attempt is due.
+func retryDueWebhooks() {
+	rows, err := db.Query(`
+		SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at LIMIT 100
+	`)
+	if err != nil {
+		return
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	rows.Close()
+	for _, id := range ids {
+		deliverWebhook(id)
+	}
+}
+
+// startWebhookRetryWorker retries failed webhook deliveries every minute.
+func startWebhookRetryWorker() {
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		for {
+			<-ticker.C
+			retryDueWebhooks()
+		}
+	}()
+	log.Println("Webhook retry worker started (runs every 1min)")
+}
+
+// webhookJSON is how a webhook is shown to its owner. The secret is only shown at creation.
+func webhookJSON(id int, url string, eventsJSON []byte, active bool, createdAt time.Time) map[string]interface{} {
+	events := []string{}
+	json.Unmarshal(eventsJSON, &events)
+	return map[string]interface{}{
+		"id":         id,
+		"url":        url,
+		"events":     events,
+		"active":     active,
+		"created_at": createdAt.Format(time.RFC3339),
+	}
+}
+
+// handleWebhooks godoc
+// @Summary Register webhook callbacks
+// @Description For agents that can't hold GET /api/events/stream open. POST {url, events} registers an https callback for any of my_turn, narration, combat_start, level_up, check_requested and check_resolved (default all); the response includes the signing secret, which is never shown again. Each event is POSTed as JSON {event, created_at, data} with headers X-AgentRPG-Event, X-AgentRPG-Delivery, X-AgentRPG-Timestamp and X-AgentRPG-Signature: "sha256=" + hex HMAC-SHA256 of "<timestamp>.<raw body>" keyed with the secret. Answer 2xx within 10 seconds; otherwise the delivery is retried after 1 minute, 5 minutes, 30 minutes and 2 hours, then marked failed. Up to 5 webhooks per agent. GET lists yours. v1.0.73.
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param request body object{url=string,events=[]string} false "Callback to register"
+// @Success 200 {object} map[string]interface{} "Your webhooks"
+// @Success 201 {object} map[string]interface{} "Webhook registered, with its secret"
+// @Failure 400 {object} map[string]interface{} "Invalid URL or event"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Too many webhooks"
+// @Router /webhooks [get]
+// @Router /webhooks [post]
+func handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	if r.Method != "POST" && r.Method != "GET" {
+		http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	if r.Method == "GET" {
+		rows, err := rdb.Query(`
+			SELECT id, url, events, COALESCE(active, true), created_at FROM webhooks
+			WHERE agent_id = $1 ORDER BY id
+		`, agentID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error", "message": err.Error()})
+			return
+		}
+		defer rows.Close()
+		hooks := []map[string]interface{}{}
+		for rows.Next() {
+			var id int
+			var url string
+			var events []byte
+			var active bool
+			var createdAt time.Time
+			rows.Scan(&id, &url, &events, &active, &createdAt)
+			hooks = append(hooks, webhookJSON(id, url, events, active, createdAt))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"webhooks":         hooks,
+			"available_events": webhookEvents,
+		})
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	url := strings.TrimSpace(req.URL)
+	if err := checkWebhookURL(r.Context(), url); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "invalid_webhook_url",
+			"message": err.Error(),
+		})
+		return
+	}
+	events, bad := normalizeWebhookEvents(req.Events)
+	if bad != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":            "invalid_event",
+			"message":          fmt.Sprintf("Unknown event '%s'", bad),
+			"available_events": webhookEvents,
+		})
+		return
+	}
+	if len(events) == 0 {
+		events = webhookEvents
+	}
+
+	var count int
+	rdb.QueryRow("SELECT COUNT(*) FROM webhooks WHERE agent_id = $1", agentID).Scan(&count)
+	if count >= maxWebhooksPerAgent {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "too_many_webhooks",
+			"message": fmt.Sprintf("You already have %d webhooks. DELETE /api/webhooks/{id} one first.", count),
+		})
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "secret_failed", "message": err.Error()})
+		return
+	}
+	eventsJSON, _ := json.Marshal(events)
+	var id int
+	var createdAt time.Time
+	err = rdb.QueryRow(`
+		INSERT INTO webhooks (agent_id, url, events, secret) VALUES ($1, $2, $3, $4) RETURNING id, created_at
+	`, agentID, url, string(eventsJSON), secret).Scan(&id, &createdAt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error", "message": err.Error()})
+		return
+	}
+
+	resp := webhookJSON(id, url, eventsJSON, true, createdAt)
+	resp["success"] = true
+	resp["secret"] = secret
+	resp["message"] = "Store the secret now: it isn't shown again. Verify each delivery's X-AgentRPG-Signature against it."
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleWebhookByID godoc
+// @Summary Remove a webhook or read its delivery log
+// @Description DELETE /api/webhooks/{id} removes one of your webhooks. GET /api/webhooks/{id}/deliveries lists its last 50 deliveries: event, status (pending, delivered, failed), attempts, response_code, error and timing. v1.0.73.
+// @Tags Agent
+// @Produce json
+// @Param Authorization header string true "Basic auth"
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]interface{} "Deleted, or the delivery log"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Webhook not found"
+// @Router /webhooks/{id} [delete]
+// @Router /webhooks/{id}/deliveries [get]
+func handleWebhookByID(w http.ResponseWriter, r *http.Request) {
+	rdb := dbFor(r)
+	w.Header().Set("Content-Type", "application/json")
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks/"), "/"), "/")
+	hookID, err := strconv.Atoi(parts[0])
+	deliveries := len(parts) == 2 && parts[1] == "deliveries"
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && !deliveries) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "not_found",
+			"message": "Use DELETE /api/webhooks/{id} or GET /api/webhooks/{id}/deliveries",
+		})
+		return
+	}
+	if (deliveries && r.Method != "GET") || (!deliveries && r.Method != "DELETE") {
+		http.Error(w, "DELETE /api/webhooks/{id} or GET /api/webhooks/{id}/deliveries", http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentID, err := getAgentFromAuth(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	var url string
+	if rdb.QueryRow("SELECT url FROM webhooks WHERE id = $1 AND agent_id = $2", hookID, agentID).Scan(&url) != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   "webhook_not_found",
+			"message": "You have no webhook with that id",
+		})
+		return
+	}
+
+	if !deliveries {
+		rdb.Exec("DELETE FROM webhooks WHERE id = $1", hookID)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": fmt.Sprintf("Webhook %d (%s) removed", hookID, url),
+		})
+		return
+	}
+
+	rows, err := rdb.Query(`
+		SELECT id, event, status, attempts, response_code, COALESCE(error, ''), created_at, next_attempt_at, delivered_at
+		FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT 50
+	`, hookID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "database_error", "message": err.Error()})
+		return
+	}
+	defer rows.Close()
+	entries := []map[string]interface{}{}
+	for rows.Next() {
+		var id, attempts int
+		var event, status, errMsg string
+		var code sql.NullInt64
+		var createdAt, nextAttempt time.Time
+		var deliveredAt sql.NullTime
+		rows.Scan(&id, &event, &status, &attempts, &code, &errMsg, &createdAt, &nextAttempt, &deliveredAt)
+		entry := map[string]interface{}{
+			"id":         id,
+			"event":      event,
+			"status":     status,
+			"attempts":   attempts,
+			"created_at": createdAt.Format(time.RFC3339),
+		}
+		if code.Valid {
+			entry["response_code"] = code.Int64
+		}
+		if errMsg != "" {
+			entry["error"] = errMsg
+		}
+		if status == "pending" {
+			entry["next_attempt_at"] = nextAttempt.Format(time.RFC3339)
+		}
+		if deliveredAt.Valid {
+			entry["delivered_at"] = deliveredAt.Time.Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhook_id": hookID,
+		"url":        url,
+		"deliveries": entries,
+	})
+}
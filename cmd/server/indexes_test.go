@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var placeholderPattern = regexp.MustCompile(`\$\d+`)
+
+// queryPlan returns SQLite's EXPLAIN QUERY PLAN details for a query, one line per step.
+// Placeholders are bound to dummy values; they don't affect index choice.
+func queryPlan(t *testing.T, testDB *sql.DB, query string) string {
+	t.Helper()
+	args := []interface{}{}
+	seen := map[string]bool{}
+	for _, p := range placeholderPattern.FindAllString(query, -1) {
+		if !seen[p] {
+			seen[p] = true
+			args = append(args, 1)
+		}
+	}
+	rows, err := testDB.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		t.Fatalf("explain %q: %v", query, err)
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		lines = append(lines, detail)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestHotPathIndexes(t *testing.T) {
+	testDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite db: %v", err)
+	}
+	defer testDB.Close()
+
+	schema := `
+CREATE TABLE characters (
+	id INTEGER PRIMARY KEY, agent_id INTEGER, lobby_id INTEGER, name TEXT, class TEXT, race TEXT,
+	level INTEGER, hp INTEGER, max_hp INTEGER, ac INTEGER, conditions TEXT, concentrating_on TEXT
+);
+CREATE TABLE actions (
+	id INTEGER PRIMARY KEY, lobby_id INTEGER, character_id INTEGER, action_type TEXT,
+	description TEXT, result TEXT, created_at TIMESTAMP
+);
+CREATE TABLE campaign_messages (
+	id INTEGER PRIMARY KEY, lobby_id INTEGER, agent_id INTEGER, agent_name TEXT, message TEXT, created_at TIMESTAMP
+);
+CREATE TABLE observations (id INTEGER PRIMARY KEY, lobby_id INTEGER, content TEXT, created_at TIMESTAMP);
+CREATE TABLE api_logs (id INTEGER PRIMARY KEY, agent_id INTEGER, endpoint TEXT, created_at TIMESTAMP);`
+	if _, err := testDB.Exec(schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	// Without the indexes the feed falls back to a full scan
+	if plan := queryPlan(t, testDB, feedQuery(feedActionsQuery, false)); !strings.Contains(plan, "SCAN actions") {
+		t.Fatalf("expected a table scan before indexing, got:\n%s", plan)
+	}
+
+	for _, stmt := range hotPathIndexes {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		index string
+	}{
+		{"feed actions", feedQuery(feedActionsQuery, false), "idx_actions_lobby_created"},
+		{"feed actions since", feedQuery(feedActionsQuery, true), "idx_actions_lobby_created"},
+		{"feed messages", feedQuery(feedMessagesQuery, true), "idx_campaign_messages_lobby_created"},
+		{"my-turn party", myTurnPartyQuery, "idx_characters_lobby"},
+		{"my-turn events", myTurnEventsQuery, "idx_actions_lobby_created"},
+		{"GM status last action", gmStatusLastActionQuery, "idx_actions_lobby_created"},
+		{"GM status party", gmStatusPartyQuery, "idx_characters_lobby"},
+		{"GM status last action per character", gmStatusPartyQuery, "idx_actions_character_created"},
+		{"character by agent", "SELECT id FROM characters WHERE agent_id = $1", "idx_characters_agent"},
+		{"observations by campaign", "SELECT content FROM observations WHERE lobby_id = $1", "idx_observations_lobby"},
+		{"api log cleanup", "SELECT id FROM api_logs WHERE created_at < $1", "idx_api_logs_created"},
+	}
+	for _, tt := range tests {
+		plan := queryPlan(t, testDB, tt.query)
+		if !strings.Contains(plan, tt.index) {
+			t.Errorf("%s: plan does not use %s:\n%s", tt.name, tt.index, plan)
+		}
+	}
+}
@@ -1,19 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/agentrpg/agentrpg/game"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/agentrpg/agentrpg/internal/audit"
+	"github.com/agentrpg/agentrpg/internal/auth"
+	"github.com/mattn/go-sqlite3"
 )
 
+// The tests' SQLite driver adds Postgres's NOW(), so timestamp writes like revoking a token
+// land instead of failing quietly.
+func init() {
+	sql.Register("sqlite3_now", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("now", func() string {
+				return time.Now().UTC().Format("2006-01-02 15:04:05")
+			}, false)
+		},
+	})
+}
+
 func setupSQLiteTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 
 	originalDB := db
-	testDB, err := sql.Open("sqlite3", ":memory:")
+	testDB, err := sql.Open("sqlite3_now", ":memory:")
 	if err != nil {
 		t.Fatalf("open sqlite db: %v", err)
 	}
@@ -101,6 +123,274 @@ func TestSQLiteIncapacitationAndMovement(t *testing.T) {
 	}
 }
 
+func TestSQLiteCharacterRowDiff(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	seedCharacter(t, testDB, 5, "Edda", `[]`, 0)
+
+	before, err := loadCharacterRow(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("loadCharacterRow: %v", err)
+	}
+	if before["name"] != "Edda" {
+		t.Fatalf("name = %#v, want Edda", before["name"])
+	}
+	testDB.Exec(`UPDATE characters SET conditions = '["poisoned"]', exhaustion_level = 2 WHERE id = 5`)
+	after, _ := loadCharacterRow(context.Background(), 5)
+
+	changes := audit.Diff(before, after)
+	if got := audit.Summary(changes); got != `conditions [] → ["poisoned"], exhaustion_level 0 → 2` {
+		t.Errorf("changes = %q", got)
+	}
+	if state, err := loadCharacterState(context.Background(), 5); err != nil || state["exhaustion_level"] != int64(2) {
+		t.Errorf("loadCharacterState without a character_classes table = %v, %v", state, err)
+	}
+	if _, err := loadCharacterRow(context.Background(), 99); err != sql.ErrNoRows {
+		t.Errorf("missing character: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// seedSQLiteToken stores a personal access token for an agent and returns it, so tests can
+// authenticate with Bearer auth.
+func seedSQLiteToken(t *testing.T, testDB *sql.DB, id, agentID int, scopes string) string {
+	t.Helper()
+	usePreparedStatements = false
+	t.Cleanup(func() { usePreparedStatements = true })
+	if _, err := testDB.Exec(`CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY, agent_id INTEGER, name TEXT, token_hash TEXT, prefix TEXT, scopes TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, last_used_at TIMESTAMP, expires_at TIMESTAMP, revoked_at TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("create api_tokens: %v", err)
+	}
+	token, hash := auth.GenerateToken()
+	if _, err := testDB.Exec(`INSERT INTO api_tokens (id, agent_id, name, token_hash, prefix, scopes) VALUES (?, ?, 'bot', ?, ?, ?)`,
+		id, agentID, hash, token[:len(auth.TokenPrefix)+6], scopes); err != nil {
+		t.Fatalf("insert token: %v", err)
+	}
+	return token
+}
+
+func TestSQLiteTokenRotationNeedsCoveringScopes(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	play := seedSQLiteToken(t, testDB, 1, 7, `["play"]`)
+	gm := seedSQLiteToken(t, testDB, 2, 7, `["gm"]`)
+
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handleTokenByID(rr, req)
+		return rr
+	}
+
+	// The play token gets past the path check, so it's Covers that refuses it.
+	if rr := call("POST", "/api/tokens/2/rotate", play); rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "that token's") {
+		t.Fatalf("play token rotating a gm token: %d %s", rr.Code, rr.Body)
+	}
+	if rr := call("DELETE", "/api/tokens/2", play); rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "that token's") {
+		t.Fatalf("play token revoking a gm token: %d %s", rr.Code, rr.Body)
+	}
+	var tokens int
+	testDB.QueryRow(`SELECT COUNT(*) FROM api_tokens WHERE scopes = '["gm"]'`).Scan(&tokens)
+	if tokens != 1 {
+		t.Fatalf("gm tokens = %d, want 1: a refused rotation must not mint one", tokens)
+	}
+	if rr := call("POST", "/api/tokens/1/rotate", gm); rr.Code != http.StatusOK {
+		t.Fatalf("gm token rotating a play token: %d %s", rr.Code, rr.Body)
+	}
+}
+
+func TestSQLiteTokenRevokesItself(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	play := seedSQLiteToken(t, testDB, 1, 7, `["play"]`)
+	seedSQLiteToken(t, testDB, 2, 7, `["gm"]`)
+
+	call := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+play)
+		rr := httptest.NewRecorder()
+		handleTokenByID(rr, req)
+		return rr
+	}
+
+	if rr := call("DELETE", "/api/tokens/2"); rr.Code != http.StatusForbidden {
+		t.Errorf("play token revoking a gm token: %d %s", rr.Code, rr.Body)
+	}
+	if rr := call("DELETE", "/api/tokens/1"); rr.Code != http.StatusOK {
+		t.Fatalf("play token revoking itself: %d %s", rr.Code, rr.Body)
+	}
+	var revoked int
+	testDB.QueryRow(`SELECT COUNT(*) FROM api_tokens WHERE revoked_at IS NOT NULL`).Scan(&revoked)
+	if revoked != 1 {
+		t.Errorf("%d tokens revoked, want only the play token", revoked)
+	}
+	if rr := call("DELETE", "/api/tokens/1"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token still authenticates: %d %s", rr.Code, rr.Body)
+	}
+}
+
+func TestSQLiteCampaignRoleStaysInItsCampaign(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`ALTER TABLE characters ADD COLUMN lobby_id INTEGER`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT)`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, active BOOLEAN)`,
+		`CREATE TABLE campaign_roles (lobby_id INTEGER, agent_id INTEGER, role TEXT, scopes TEXT)`,
+		// The GM (agent 1) runs two campaigns; agent 2 is co-GM of the second only.
+		`INSERT INTO lobbies VALUES (10, 'Other Table', 1, 'active'), (20, 'Shared Table', 1, 'active')`,
+		`INSERT INTO combat_state VALUES (10, 1), (20, 1)`,
+		`INSERT INTO campaign_roles VALUES (20, 2, 'co_gm', '["narrate","monsters","quests"]')`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	seedCharacter(t, testDB, 100, "Outsider", `[]`, 0)
+	seedCharacter(t, testDB, 200, "Insider", `[]`, 0)
+	testDB.Exec(`UPDATE characters SET lobby_id = 10 WHERE id = 100`)
+	testDB.Exec(`UPDATE characters SET lobby_id = 20 WHERE id = 200`)
+	coGM := seedSQLiteToken(t, testDB, 1, 2, `["gm"]`)
+
+	var reached int
+	handler := withCampaignRoles(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agentID, err := getAgentFromAuth(r)
+		if err != nil || agentID != 1 {
+			t.Errorf("acting agent = %d, %v; want the GM", agentID, err)
+		}
+		reached, _ = gmCombatCampaign(w, r, agentID)
+	}))
+	call := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/gm/award-xp", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+coGM)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := call(`{"xp": 50}`); rr.Code != http.StatusOK || reached != 20 {
+		t.Fatalf("co-GM reached campaign %d (%d %s), want 20", reached, rr.Code, rr.Body)
+	}
+	reached = 0
+	if rr := call(`{"character_id": 100, "xp": 50}`); rr.Code != http.StatusForbidden || reached != 0 {
+		t.Fatalf("co-GM naming a character in the GM's other campaign: %d %s", rr.Code, rr.Body)
+	}
+	if rr := call(`{"character_ids": [200], "xp": 50}`); rr.Code != http.StatusOK || reached != 20 {
+		t.Fatalf("co-GM naming a character in their campaign: %d %s", rr.Code, rr.Body)
+	}
+}
+
+func TestSQLiteAttackAndNextTurnDontLoseUpdates(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	testDB.SetMaxOpenConns(1) // Every connection to :memory: is its own database
+	for _, stmt := range []string{
+		`ALTER TABLE characters ADD COLUMN lobby_id INTEGER`,
+		`ALTER TABLE characters ADD COLUMN agent_id INTEGER`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT)`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, active BOOLEAN, version INTEGER DEFAULT 0, turns INTEGER DEFAULT 0)`,
+		`INSERT INTO lobbies VALUES (20, 'Table', 1, 'active')`,
+		`INSERT INTO combat_state (lobby_id, active) VALUES (20, 1)`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	seedCharacter(t, testDB, 200, "Brask", `[]`, 0)
+	testDB.Exec(`UPDATE characters SET lobby_id = 20, agent_id = 5 WHERE id = 200`)
+	player := seedSQLiteToken(t, testDB, 1, 5, `["gm"]`)
+	gm := seedSQLiteToken(t, testDB, 2, 1, `["gm"]`)
+
+	// Both endpoints read the combat, think, and write it back, like damageCombatMonster
+	// and the turn advance do with the turn order.
+	readModifyWrite := func(w http.ResponseWriter, r *http.Request) {
+		var turns int
+		db.QueryRow("SELECT turns FROM combat_state WHERE lobby_id = 20").Scan(&turns)
+		time.Sleep(5 * time.Millisecond)
+		db.Exec("UPDATE combat_state SET turns = $1 WHERE lobby_id = 20", turns+1)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/attack", readModifyWrite)
+	mux.HandleFunc("/api/campaigns/", readModifyWrite)
+	handler := withCombatLock(mux)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		for _, call := range []struct{ path, token string }{
+			{"/api/attack", player},
+			{"/api/campaigns/20/combat/next", gm},
+		} {
+			wg.Add(1)
+			go func(path, token string) {
+				defer wg.Done()
+				req := httptest.NewRequest("POST", path, strings.NewReader(`{}`))
+				req.Header.Set("Authorization", "Bearer "+token)
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				if rr.Code != http.StatusOK {
+					t.Errorf("%s: %d %s", path, rr.Code, rr.Body)
+				}
+			}(call.path, call.token)
+		}
+	}
+	wg.Wait()
+	var turns int
+	testDB.QueryRow("SELECT turns FROM combat_state WHERE lobby_id = 20").Scan(&turns)
+	if turns != 10 {
+		t.Errorf("turns = %d after 10 serialized changes; /api/attack raced combat/next", turns)
+	}
+}
+
+func TestSQLiteCombatLockRefusesWithoutAConnection(t *testing.T) {
+	setupSQLiteTestDB(t)
+	db.Close()
+	reached := false
+	handler := withCombatLock(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/campaigns/20/combat/next", nil))
+	if reached || rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("combat change without a lock: reached %v, %d %s; want 503 and no change", reached, rr.Code, rr.Body)
+	}
+}
+
+func TestSQLiteChangeTargetsByRoute(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`ALTER TABLE characters ADD COLUMN lobby_id INTEGER`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT)`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, battle_map TEXT)`,
+		`CREATE TABLE spells (slug TEXT, aoe_shape TEXT, aoe_size INTEGER, range TEXT)`,
+		`INSERT INTO lobbies VALUES (10, 'Other Table', 2, 'active'), (20, 'Table', 1, 'active')`,
+		`INSERT INTO spells VALUES ('fireball', 'sphere', 20, '150 feet')`,
+		// Brask and a goblin stand in the blast; Cora is well clear of it.
+		`INSERT INTO combat_state VALUES (20, '{"positions": {"200": {"x": 5, "y": 5}, "201": {"x": 20, "y": 20}, "-1": {"x": 5, "y": 6}}}')`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	seedCharacter(t, testDB, 100, "Outsider", `[]`, 0)
+	seedCharacter(t, testDB, 200, "Brask", `[]`, 0)
+	seedCharacter(t, testDB, 201, "Cora", `[]`, 0)
+	testDB.Exec(`UPDATE characters SET lobby_id = 10 WHERE id = 100`)
+	testDB.Exec(`UPDATE characters SET lobby_id = 20 WHERE id IN (200, 201)`)
+
+	for _, tt := range []struct {
+		path, body string
+		want       []int
+	}{
+		{"/api/gm/long-rest", `{"campaign_id": 20}`, []int{200, 201}},
+		{"/api/gm/long-rest", `{"campaign_id": 20, "character_ids": [201]}`, []int{201}},
+		{"/api/gm/aoe-cast", `{"spell_slug": "fireball", "point": {"x": 5, "y": 5}}`, []int{200}},
+		{"/api/gm/aoe-cast", `{"spell_slug": "fireball", "point": {"x": 5, "y": 5}, "target_ids": [201]}`, []int{201}},
+		{"/api/campaigns/20/combat/next", `{}`, []int{200, 201}},
+		{"/api/campaigns/20/combat/damage", `{"target_id": 201}`, []int{201, 200}},
+		{"/api/campaigns/20/messages", `{}`, nil},
+	} {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		if got := changeTargets(req, 1, []byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s: targets %v, want %v", tt.path, tt.body, got, tt.want)
+		}
+	}
+}
+
 func TestSQLiteSaveDisadvantageAndNames(t *testing.T) {
 	testDB := setupSQLiteTestDB(t)
 	seedCharacter(t, testDB, 5, "Eris", `["restrained"]`, 2)
@@ -545,3 +835,453 @@ func TestSQLiteHunterDefensiveTactics(t *testing.T) {
 		}
 	})
 }
+
+func TestSQLiteContextBundle(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`DROP TABLE characters`,
+		`CREATE TABLE characters (
+			id INTEGER PRIMARY KEY, agent_id INTEGER, lobby_id INTEGER, name TEXT, class TEXT, race TEXT,
+			subclass TEXT, level INTEGER, hp INTEGER, max_hp INTEGER, temp_hp INTEGER, ac INTEGER,
+			str INTEGER, dex INTEGER, con INTEGER, intl INTEGER, wis INTEGER, cha INTEGER, gold INTEGER,
+			xp INTEGER, exhaustion_level INTEGER, concentrating_on TEXT, conditions TEXT,
+			spell_slots_used TEXT, inventory TEXT
+		)`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT, campaign_document TEXT)`,
+		`CREATE TABLE actions (id INTEGER PRIMARY KEY, lobby_id INTEGER, character_id INTEGER, action_type TEXT, description TEXT, result TEXT, created_at TIMESTAMP)`,
+		`INSERT INTO lobbies VALUES (20, 'Table', 1, 'active', '{"setting": "Saltmarsh", "gm_notes": "the mayor did it", "quests": [{"title": "Find the smugglers"}]}')`,
+		`INSERT INTO characters VALUES (200, 5, 20, 'Brask', 'fighter', 'dwarf', NULL, 3, 20, 28, 0, 16,
+			16, 12, 14, 8, 10, 10, 40, 900, 0, NULL, '[]', '{}', '[{"name": "Rope", "quantity": 2}]')`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	for i := 1; i <= 60; i++ {
+		testDB.Exec(`INSERT INTO actions (lobby_id, character_id, action_type, description, result, created_at) VALUES (20, 200, 'attack', ?, '', ?)`,
+			fmt.Sprintf("Brask swings, round %d: %s", i, strings.Repeat("clang ", 10)), time.Date(2026, 10, 16, 12, i, 0, 0, time.UTC))
+	}
+	player := seedSQLiteToken(t, testDB, 1, 5, `["read"]`)
+	stranger := seedSQLiteToken(t, testDB, 2, 6, `["read"]`)
+
+	get := func(token, query string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("GET", "/api/context?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handleContext(rr, req)
+		var bundle map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &bundle)
+		return rr.Code, bundle
+	}
+
+	code, bundle := get(player, "include=character,campaign,quests,events&events=5")
+	if code != http.StatusOK || bundle["your_role"] != "player" || bundle["character_id"] != float64(200) {
+		t.Fatalf("player bundle: %d %v", code, bundle)
+	}
+	if sheet, _ := bundle["character"].(map[string]interface{}); sheet["name"] != "Brask" || fmt.Sprint(sheet["inventory"]) != "[Rope x2]" {
+		t.Errorf("character = %v", bundle["character"])
+	}
+	doc, _ := bundle["campaign"].(map[string]interface{})
+	if doc["setting"] != "Saltmarsh" || doc["gm_notes"] != nil || doc["quests"] != nil {
+		t.Errorf("campaign = %v; want the setting without GM notes, quests in their own section", doc)
+	}
+	if quests, _ := bundle["quests"].([]interface{}); len(quests) != 1 {
+		t.Errorf("quests = %v", bundle["quests"])
+	}
+	events, _ := bundle["events"].([]interface{})
+	if len(events) != 5 || !strings.Contains(events[0].(map[string]interface{})["description"].(string), "round 60") {
+		t.Errorf("events = %v; want the newest 5, newest first", events)
+	}
+	if _, ok := bundle["party"]; ok {
+		t.Error("party was not included")
+	}
+
+	_, bundle = get(player, "include=campaign&doc_fields=gm_notes,setting")
+	if doc, _ := bundle["campaign"].(map[string]interface{}); len(doc) != 1 || doc["setting"] != "Saltmarsh" {
+		t.Errorf("doc_fields campaign = %v; want only the setting", doc)
+	}
+
+	code, bundle = get(player, "include=events&events=60&max_bytes=2000")
+	body, _ := json.Marshal(bundle)
+	events, _ = bundle["events"].([]interface{})
+	if code != http.StatusOK || len(body) > 2000 || len(events) == 0 || len(events) >= 60 || fmt.Sprint(bundle["truncated"]) != "[events]" {
+		t.Errorf("bounded bundle: %d bytes, %d events, truncated %v", len(body), len(events), bundle["truncated"])
+	}
+
+	if code, _ := get(stranger, "campaign_id=20"); code != http.StatusForbidden {
+		t.Errorf("stranger naming the campaign: %d, want 403", code)
+	}
+}
+
+func TestSQLiteActionSubmissionsTakeTurns(t *testing.T) {
+	setupSQLiteTestDB(t)
+	ctx := context.Background()
+
+	release, contended, err := lockCharacterAction(ctx, 200)
+	if contended || err != nil {
+		t.Fatalf("first submission: contended %v, %v", contended, err)
+	}
+	other, contended, _ := lockCharacterAction(ctx, 201)
+	if contended {
+		t.Error("another character's submission waited on this one")
+	}
+	other()
+
+	// A second submission for the same character waits for the first, then learns it was
+	// a concurrent duplicate.
+	done := make(chan bool)
+	go func() {
+		release, contended, err := lockCharacterAction(ctx, 200)
+		release()
+		done <- contended && err == nil
+	}()
+	select {
+	case <-done:
+		t.Fatal("second submission resolved while the first still held the character")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	select {
+	case contended := <-done:
+		if !contended {
+			t.Error("second submission was not told another one went first, or didn't get the lock")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second submission never ran after the first finished")
+	}
+
+	// A cancelled request stops waiting.
+	release, _, _ = lockCharacterAction(ctx, 200)
+	defer release()
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := lockCharacterAction(cancelled, 200); err == nil {
+		t.Error("a request cancelled while waiting got the lock")
+	}
+}
+
+func TestSQLiteActionLockFailureResolvesNothing(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`ALTER TABLE characters ADD COLUMN agent_id INTEGER`,
+		`ALTER TABLE characters ADD COLUMN lobby_id INTEGER`,
+		`ALTER TABLE characters ADD COLUMN race TEXT`,
+		`ALTER TABLE characters ADD COLUMN action_used BOOLEAN DEFAULT 0`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT)`,
+		`CREATE TABLE actions (id INTEGER PRIMARY KEY, lobby_id INTEGER, character_id INTEGER, action_type TEXT, description TEXT, result TEXT, created_at TIMESTAMP)`,
+		`INSERT INTO lobbies VALUES (20, 'Table', 1, 'active')`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	seedCharacter(t, testDB, 200, "Brask", `[]`, 0)
+	testDB.Exec(`UPDATE characters SET agent_id = 5, lobby_id = 20, race = 'human' WHERE id = 200`)
+	player := seedSQLiteToken(t, testDB, 1, 5, `["play"]`)
+	saved := actionLockWait
+	t.Cleanup(func() { actionLockWait = saved })
+
+	// Another submission for Brask is still resolving.
+	release, _, err := lockCharacterAction(context.Background(), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	post := func(ctx context.Context) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/api/action", strings.NewReader(`{"action": "dodge"}`)).WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer "+player)
+		rr := httptest.NewRecorder()
+		handleAction(rr, req)
+		var resp map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp
+	}
+
+	actionLockWait = 20 * time.Millisecond
+	if status, resp := post(context.Background()); status != http.StatusConflict || resp["error"] != "action_busy" {
+		t.Errorf("lock held past the wait: %d %v; want 409 action_busy", status, resp)
+	}
+	actionLockWait = time.Second
+	expiring, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if status, resp := post(expiring); status != http.StatusServiceUnavailable || resp["error"] != "database_timeout" {
+		t.Errorf("request deadline while waiting: %d %v; want 503 database_timeout", status, resp)
+	}
+	var used bool
+	var actions int
+	testDB.QueryRow("SELECT action_used FROM characters WHERE id = 200").Scan(&used)
+	testDB.QueryRow("SELECT COUNT(*) FROM actions").Scan(&actions)
+	if used || actions != 0 {
+		t.Errorf("refused submissions resolved anyway: action_used %v, %d actions", used, actions)
+	}
+
+	// Without a connection the lock can't be taken, so nothing resolves either.
+	db.Close()
+	rr := httptest.NewRecorder()
+	if _, _, ok := acquireActionLock(rr, httptest.NewRequest("POST", "/api/action", nil), 200); ok || rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("closed database: ok %v, %d; want 503", ok, rr.Code)
+	}
+}
+
+func TestSQLiteCheckAssist(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`ALTER TABLE characters ADD COLUMN lobby_id INTEGER`,
+		`ALTER TABLE characters ADD COLUMN hp INTEGER DEFAULT 10`,
+		`ALTER TABLE characters ADD COLUMN is_dead BOOLEAN DEFAULT 0`,
+		`ALTER TABLE characters ADD COLUMN tool_proficiencies TEXT`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, active BOOLEAN)`,
+		`CREATE TABLE actions (id INTEGER PRIMARY KEY, lobby_id INTEGER, character_id INTEGER, action_type TEXT, description TEXT, result TEXT, created_at TIMESTAMP)`,
+		`INSERT INTO combat_state VALUES (20, 0), (30, 1)`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	seedCharacter(t, testDB, 200, "Brask", `[]`, 0)
+	seedCharacter(t, testDB, 201, "Cora", `[]`, 0)
+	seedCharacter(t, testDB, 202, "Dell", `[]`, 0)
+	seedCharacter(t, testDB, 203, "Eris", `["stunned"]`, 0)
+	seedCharacter(t, testDB, 204, "Finn", `[]`, 0)
+	seedCharacter(t, testDB, 205, "Gale", `[]`, 0)
+	seedCharacter(t, testDB, 300, "Hale", `[]`, 0)
+	seedCharacter(t, testDB, 301, "Ivo", `[]`, 0)
+	testDB.Exec(`UPDATE characters SET lobby_id = 20 WHERE id BETWEEN 200 AND 299`)
+	testDB.Exec(`UPDATE characters SET lobby_id = 30 WHERE id >= 300`)
+	testDB.Exec(`UPDATE characters SET tool_proficiencies = 'Thieves'' Tools, Herbalism Kit' WHERE id = 201`)
+	testDB.Exec(`UPDATE characters SET hp = 0 WHERE id = 202`)
+	testDB.Exec(`UPDATE characters SET is_dead = 1 WHERE id = 205`)
+
+	for _, tt := range []struct {
+		name                string
+		campaign, checker   int
+		helper              int
+		tool, wantErr, want string
+	}{
+		{"helper in the party", 20, 200, 201, "", "", "Cora"},
+		{"helper proficient with the tool", 20, 200, 201, "thieves' tools", "", "Cora"},
+		{"helper without the tool", 20, 200, 204, "thieves' tools", "helper_not_proficient", ""},
+		{"helping yourself", 20, 200, 200, "", "invalid_assist", ""},
+		{"helper at 0 HP", 20, 200, 202, "", "helper_cannot_act", ""},
+		{"helper stunned", 20, 200, 203, "", "helper_cannot_act", ""},
+		{"helper dead", 20, 200, 205, "", "helper_cannot_act", ""},
+		{"helper in another campaign", 20, 200, 300, "", "helper_not_found", ""},
+		{"in combat", 30, 300, 301, "", "assist_in_combat", ""},
+	} {
+		name, errResp := resolveCheckAssist(tt.campaign, tt.checker, &checkAssist{CharacterID: tt.helper}, tt.tool)
+		if errResp["error"] != nil && errResp["error"] != tt.wantErr || errResp == nil && tt.wantErr != "" || name != tt.want {
+			t.Errorf("%s: helper %q, error %v; want %q, %q", tt.name, name, errResp["error"], tt.want, tt.wantErr)
+		}
+	}
+
+	assist := recordCheckAssist(20, &checkAssist{CharacterID: 201}, "Cora", "Brask", "Stealth")
+	if assist["helper_id"] != 201 || assist["minutes_spent"] != 1 {
+		t.Errorf("assist = %v; want Cora credited with the default minute", assist)
+	}
+}
+
+func TestSQLiteCapabilitiesListEveryCampaignSetting(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, ability_score_method TEXT, inspiration_mode TEXT, inspiration_session_cap INTEGER, auto_narration_minutes INTEGER)`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, morale TEXT)`,
+		`INSERT INTO lobbies VALUES (20, 'point_buy', 'reroll', 3, 30)`,
+		`INSERT INTO combat_state VALUES (20, '{"enabled": true, "dc": 12, "triggers": ["bloodied"]}')`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	rr := httptest.NewRecorder()
+	handleCapabilities(rr, httptest.NewRequest("GET", "/api/capabilities?campaign_id=20", nil))
+	var resp struct {
+		HouseRules    []houseRule            `json:"house_rules"`
+		CampaignRules map[string]interface{} `json:"campaign_rules"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"inspiration_mode":        "reroll",
+		"inspiration_session_cap": float64(3),
+		"auto_narration_minutes":  float64(30),
+		"morale":                  true,
+		"morale_dc":               float64(12),
+		"morale_triggers":         []interface{}{"bloodied"},
+	} {
+		listed := false
+		for _, rule := range resp.HouseRules {
+			listed = listed || rule.Key == key
+		}
+		if !listed {
+			t.Errorf("house_rules doesn't list %s", key)
+		}
+		if got := resp.CampaignRules[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("campaign_rules[%s] = %v, want %v", key, got, want)
+		}
+	}
+	// Every house rule has the campaign's value next to it.
+	for _, rule := range resp.HouseRules {
+		if _, ok := resp.CampaignRules[rule.Key]; !ok {
+			t.Errorf("campaign_rules has no value for house rule %s", rule.Key)
+		}
+	}
+}
+
+func TestSQLiteGMLongRest(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`DROP TABLE characters`,
+		`CREATE TABLE characters (
+			id INTEGER PRIMARY KEY, agent_id INTEGER, lobby_id INTEGER, name TEXT, class TEXT, subclass TEXT, race TEXT,
+			level INTEGER, con INTEGER, wis INTEGER, cha INTEGER, hp INTEGER, max_hp INTEGER, is_dead BOOLEAN DEFAULT 0,
+			hit_dice_spent INTEGER DEFAULT 0, class_levels TEXT, exhaustion_level INTEGER DEFAULT 0, equipped_armor TEXT,
+			last_long_rest TIMESTAMP, last_long_rest_game INTEGER, last_short_rest TIMESTAMP, last_short_rest_game INTEGER,
+			conditions TEXT, spell_slots_used TEXT, pact_slots_used TEXT, death_save_successes INTEGER, death_save_failures INTEGER,
+			is_stable BOOLEAN, concentrating_on TEXT, short_rest_dice INTEGER, short_rest_interrupted BOOLEAN,
+			action_used BOOLEAN, bonus_action_used BOOLEAN, reaction_used BOOLEAN, movement_remaining INTEGER,
+			ammo_used_since_rest INTEGER, class_resources_used TEXT, breath_weapon_used BOOLEAN,
+			relentless_endurance_used BOOLEAN, relentless_rage_uses INTEGER, hellish_rebuke_used BOOLEAN,
+			darkness_racial_used BOOLEAN, wholeness_of_body_used BOOLEAN, divine_intervention_failed BOOLEAN,
+			dark_ones_luck_used BOOLEAN, hurl_through_hell_used BOOLEAN, invocation_spells_used TEXT, indomitable_used INTEGER,
+			mystic_arcanum_used TEXT, stroke_of_luck_used BOOLEAN, eldritch_master_used BOOLEAN, signature_spells_used TEXT,
+			overchannel_used BOOLEAN, holy_nimbus_used BOOLEAN, revival_penalty INTEGER DEFAULT 0
+		)`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT, game_minutes INTEGER, downtime_from INTEGER)`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, active BOOLEAN)`,
+		`CREATE TABLE actions (id INTEGER PRIMARY KEY, lobby_id INTEGER, character_id INTEGER, action_type TEXT, description TEXT, result TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO lobbies (id, name, dm_id, status) VALUES (20, 'Table', 1, 'active')`,
+		`INSERT INTO characters (id, agent_id, lobby_id, name, class, race, level, con, wis, cha, hp, max_hp, hit_dice_spent, conditions)
+			VALUES (200, 5, 20, 'Brask', 'fighter', 'human', 4, 14, 10, 10, 9, 36, 4, '[]'),
+				(201, 6, 20, 'Cora', 'rogue', 'halfling', 4, 12, 12, 10, 3, 27, 2, '[]')`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	gm := seedSQLiteToken(t, testDB, 1, 1, `["gm"]`)
+	player := seedSQLiteToken(t, testDB, 2, 5, `["gm"]`)
+
+	post := func(token, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/api/gm/long-rest", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handleGMLongRest(rr, req)
+		var resp map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp
+	}
+
+	if status, resp := post(player, `{"campaign_id": 20}`); status != http.StatusForbidden || resp["error"] != "not_gm" {
+		t.Fatalf("a player resting the party: %d %v; want 403 not_gm", status, resp)
+	}
+	var hp int
+	testDB.QueryRow("SELECT hp FROM characters WHERE id = 200").Scan(&hp)
+	if hp != 9 {
+		t.Fatalf("a refused rest healed Brask to %d HP", hp)
+	}
+
+	status, resp := post(gm, `{"campaign_id": 20, "narration": "Camp by the river"}`)
+	if status != http.StatusOK {
+		t.Fatalf("party long rest: %d %v", status, resp)
+	}
+	if rested, _ := resp["rested"].([]interface{}); len(rested) != 2 {
+		t.Fatalf("rested = %v, want both characters", resp["rested"])
+	}
+	for _, want := range []struct {
+		id, hp, hitDiceSpent int
+	}{
+		{200, 36, 2}, // Half of 4 dice come back
+		{201, 27, 0}, // Both spent dice come back
+	} {
+		var hp, spent int
+		testDB.QueryRow("SELECT hp, hit_dice_spent FROM characters WHERE id = $1", want.id).Scan(&hp, &spent)
+		if hp != want.hp || spent != want.hitDiceSpent {
+			t.Errorf("character %d after the rest: %d HP, %d hit dice spent; want %d and %d", want.id, hp, spent, want.hp, want.hitDiceSpent)
+		}
+	}
+	var posts int
+	testDB.QueryRow("SELECT COUNT(*) FROM actions WHERE lobby_id = 20 AND action_type = 'party_rest'").Scan(&posts)
+	if posts != 1 {
+		t.Errorf("%d party rest posts, want one for the whole party", posts)
+	}
+}
+
+func TestSQLiteGMResurrect(t *testing.T) {
+	testDB := setupSQLiteTestDB(t)
+	for _, stmt := range []string{
+		`DROP TABLE characters`,
+		`CREATE TABLE characters (
+			id INTEGER PRIMARY KEY, agent_id INTEGER, lobby_id INTEGER, name TEXT, class TEXT, level INTEGER,
+			hp INTEGER, max_hp INTEGER, gold INTEGER DEFAULT 0, inventory TEXT, spell_slots_used TEXT,
+			conditions TEXT, is_dead BOOLEAN DEFAULT 0, is_stable BOOLEAN DEFAULT 0,
+			death_save_successes INTEGER DEFAULT 0, death_save_failures INTEGER DEFAULT 0,
+			died_at TIMESTAMP, died_round INTEGER, revival_penalty INTEGER DEFAULT 0
+		)`,
+		`CREATE TABLE lobbies (id INTEGER PRIMARY KEY, name TEXT, dm_id INTEGER, status TEXT)`,
+		`CREATE TABLE combat_state (lobby_id INTEGER, active BOOLEAN, round_number INTEGER)`,
+		`CREATE TABLE actions (id INTEGER PRIMARY KEY, lobby_id INTEGER, character_id INTEGER, action_type TEXT, description TEXT, result TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO lobbies VALUES (20, 'Table', 1, 'active')`,
+		`INSERT INTO characters (id, agent_id, lobby_id, name, class, level, hp, max_hp, gold, inventory, is_dead)
+			VALUES (200, 5, 20, 'Brask', 'fighter', 5, 0, 44, 600, '[]', 1), (201, 6, 20, 'Cora', 'rogue', 5, 0, 33, 0, '[]', 1)`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	gm := seedSQLiteToken(t, testDB, 1, 1, `["gm"]`)
+	player := seedSQLiteToken(t, testDB, 2, 5, `["gm"]`)
+
+	post := func(token, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/api/gm/resurrect", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handleGMResurrect(rr, req)
+		var resp map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp
+	}
+
+	for _, tt := range []struct {
+		name, token, body string
+		status            int
+		wantErr           string
+	}{
+		{"a player", player, `{"character_id": 200, "spell": "raise_dead", "minutes_since_death": 60}`, http.StatusForbidden, "not_gm"},
+		{"unknown spell", gm, `{"character_id": 200, "spell": "wish"}`, http.StatusBadRequest, "invalid_spell"},
+		{"too late for Revivify", gm, `{"character_id": 200, "spell": "revivify", "minutes_since_death": 5}`, http.StatusBadRequest, "too_late"},
+		{"time since death unknown", gm, `{"character_id": 200, "spell": "revivify"}`, http.StatusBadRequest, "time_unknown"},
+		{"no diamond", gm, `{"character_id": 201, "spell": "raise_dead", "minutes_since_death": 60}`, http.StatusBadRequest, "missing_material"},
+		{"not enough gold", gm, `{"character_id": 201, "spell": "raise_dead", "minutes_since_death": 60, "pay_with": "gold"}`, http.StatusBadRequest, "insufficient_gold"},
+	} {
+		if status, resp := post(tt.token, tt.body); status != tt.status || resp["error"] != tt.wantErr {
+			t.Errorf("%s: %d %v; want %d %s", tt.name, status, resp, tt.status, tt.wantErr)
+		}
+	}
+
+	// Brask pays for Raise Dead from his own purse; Cora's return is waived for the story.
+	status, resp := post(gm, `{"character_id": 200, "spell": "Raise Dead", "minutes_since_death": 60, "pay_with": "gold"}`)
+	if status != http.StatusOK || resp["hp"] != float64(1) || resp["revival_penalty"] != float64(-4) || resp["material"] != "500 gp" {
+		t.Fatalf("raise dead: %d %v", status, resp)
+	}
+	var hp, gold, penalty int
+	var dead bool
+	testDB.QueryRow("SELECT hp, gold, revival_penalty, is_dead FROM characters WHERE id = 200").Scan(&hp, &gold, &penalty, &dead)
+	if hp != 1 || gold != 100 || penalty != 4 || dead {
+		t.Errorf("Brask after Raise Dead: hp %d, gold %d, penalty %d, dead %v", hp, gold, penalty, dead)
+	}
+
+	status, resp = post(gm, `{"character_id": 201, "spell": "resurrection", "minutes_since_death": 60, "waive_cost": true, "narration": "The goddess answers."}`)
+	if status != http.StatusOK || resp["hp"] != float64(33) || resp["cost_waived"] != true || resp["paid_by"] != "the GM" || resp["narration_recorded"] != true {
+		t.Fatalf("waived resurrection: %d %v", status, resp)
+	}
+	var narrations int
+	testDB.QueryRow("SELECT COUNT(*) FROM actions WHERE character_id = 201 AND action_type IN ('revival', 'narration')").Scan(&narrations)
+	if narrations != 2 {
+		t.Errorf("%d feed entries for Cora's resurrection, want the revival and the narration", narrations)
+	}
+
+	if status, resp := post(gm, `{"character_id": 201, "spell": "resurrection", "minutes_since_death": 60, "waive_cost": true}`); resp["error"] != "not_dead" {
+		t.Errorf("resurrecting the living: %d %v", status, resp)
+	}
+}
@@ -0,0 +1,88 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// ability_drain.go - temporary ability score reduction (shadow Strength Drain, etc.)
+package game
+
+import "strings"
+
+// Drain recovery conditions: what ends an ability score reduction.
+const (
+	DrainRecoveryShortRest          = "short_rest"          // Ends on a short or long rest (shadow, MM p269)
+	DrainRecoveryLongRest           = "long_rest"           // Ends on a long rest
+	DrainRecoveryGreaterRestoration = "greater_restoration" // Only greater restoration (or similar magic) ends it
+)
+
+// DrainRecoveries describes each supported recovery condition.
+var DrainRecoveries = map[string]string{
+	DrainRecoveryShortRest:          "Reduction lasts until the creature finishes a short or long rest",
+	DrainRecoveryLongRest:           "Reduction lasts until the creature finishes a long rest",
+	DrainRecoveryGreaterRestoration: "Reduction lasts until removed by greater restoration or similar magic",
+}
+
+// AbilityDrain is one temporary reduction to an ability score.
+type AbilityDrain struct {
+	Ability  string `json:"ability"` // str, dex, con, int, wis, cha
+	Amount   int    `json:"amount"`
+	Source   string `json:"source"`
+	Recovery string `json:"recovery"`
+	Lethal   bool   `json:"lethal,omitempty"` // Creature dies if the score reaches 0 (shadow Strength Drain)
+}
+
+// NormalizeAbility maps an ability name or abbreviation to its short form
+// (str, dex, con, int, wis, cha). Returns "" if the ability is unknown.
+func NormalizeAbility(ability string) string {
+	switch strings.ToLower(strings.TrimSpace(ability)) {
+	case "str", "strength":
+		return "str"
+	case "dex", "dexterity":
+		return "dex"
+	case "con", "constitution":
+		return "con"
+	case "int", "intl", "intelligence":
+		return "int"
+	case "wis", "wisdom":
+		return "wis"
+	case "cha", "charisma":
+		return "cha"
+	}
+	return ""
+}
+
+// IsValidDrainRecovery reports whether recovery is a known recovery condition.
+func IsValidDrainRecovery(recovery string) bool {
+	_, ok := DrainRecoveries[recovery]
+	return ok
+}
+
+// DrainRecovered reports whether a drain with the given recovery condition ends on event
+// (short_rest, long_rest, or greater_restoration). A long rest also ends short-rest
+// drains, and greater restoration ends every drain.
+func DrainRecovered(recovery, event string) bool {
+	switch event {
+	case DrainRecoveryGreaterRestoration:
+		return true
+	case DrainRecoveryLongRest:
+		return recovery == DrainRecoveryShortRest || recovery == DrainRecoveryLongRest
+	case DrainRecoveryShortRest:
+		return recovery == DrainRecoveryShortRest
+	}
+	return false
+}
+
+// ApplyDrain reduces an ability score by amount, never below 0.
+// Returns the new score and how much was actually drained.
+func ApplyDrain(score, amount int) (int, int) {
+	if amount <= 0 {
+		return score, 0
+	}
+	if amount > score {
+		amount = score
+	}
+	return score - amount, amount
+}
+
+// IsShadowDrain reports whether a drain source is a shadow's Strength Drain, which kills
+// the target if its Strength reaches 0 (MM p269).
+func IsShadowDrain(source, ability string) bool {
+	return NormalizeAbility(ability) == "str" && strings.Contains(strings.ToLower(source), "shadow")
+}
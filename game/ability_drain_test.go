@@ -0,0 +1,72 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestNormalizeAbility(t *testing.T) {
+	tests := map[string]string{
+		"STR":          "str",
+		"strength":     "str",
+		"intl":         "int",
+		"Intelligence": "int",
+		" wis ":        "wis",
+		"luck":         "",
+	}
+	for in, want := range tests {
+		if got := NormalizeAbility(in); got != want {
+			t.Errorf("NormalizeAbility(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDrainRecovered(t *testing.T) {
+	tests := []struct {
+		recovery, event string
+		expected        bool
+	}{
+		{DrainRecoveryShortRest, DrainRecoveryShortRest, true},
+		{DrainRecoveryShortRest, DrainRecoveryLongRest, true},
+		{DrainRecoveryLongRest, DrainRecoveryShortRest, false},
+		{DrainRecoveryLongRest, DrainRecoveryLongRest, true},
+		{DrainRecoveryGreaterRestoration, DrainRecoveryLongRest, false},
+		{DrainRecoveryGreaterRestoration, DrainRecoveryGreaterRestoration, true},
+		{DrainRecoveryShortRest, DrainRecoveryGreaterRestoration, true},
+		{DrainRecoveryShortRest, "lesser_restoration", false},
+	}
+	for _, tt := range tests {
+		if got := DrainRecovered(tt.recovery, tt.event); got != tt.expected {
+			t.Errorf("DrainRecovered(%q, %q) = %v, want %v", tt.recovery, tt.event, got, tt.expected)
+		}
+	}
+	if !IsValidDrainRecovery("long_rest") || IsValidDrainRecovery("never") {
+		t.Error("IsValidDrainRecovery returned the wrong answer")
+	}
+}
+
+func TestApplyDrain(t *testing.T) {
+	tests := []struct {
+		score, amount       int
+		wantScore, wantUsed int
+	}{
+		{14, 3, 11, 3},
+		{2, 4, 0, 2},
+		{10, 0, 10, 0},
+		{10, -2, 10, 0},
+	}
+	for _, tt := range tests {
+		score, used := ApplyDrain(tt.score, tt.amount)
+		if score != tt.wantScore || used != tt.wantUsed {
+			t.Errorf("ApplyDrain(%d, %d) = (%d, %d), want (%d, %d)", tt.score, tt.amount, score, used, tt.wantScore, tt.wantUsed)
+		}
+	}
+}
+
+func TestIsShadowDrain(t *testing.T) {
+	if !IsShadowDrain("Shadow touch", "strength") {
+		t.Error("IsShadowDrain(\"Shadow touch\", \"strength\") = false, want true")
+	}
+	if IsShadowDrain("Shadow touch", "dex") || IsShadowDrain("Intellect devourer", "str") {
+		t.Error("IsShadowDrain matched a non-shadow STR drain")
+	}
+}
@@ -0,0 +1,140 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// ability_scores.go - ability score generation methods (PHB p12-13) for build validation
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ability score generation methods a campaign can require.
+const (
+	AbilityMethodFreeform      = ""               // Any scores; only the 20 cap applies
+	AbilityMethodPointBuy      = "point_buy"      // 27 points, scores 8-15 before racial bonuses
+	AbilityMethodStandardArray = "standard_array" // 15, 14, 13, 12, 10, 8
+	AbilityMethodRolled        = "rolled"         // 4d6 drop lowest: 3-18
+)
+
+// PointBuyBudget is the number of points available with the point-buy method.
+const PointBuyBudget = 27
+
+// StandardArray is the PHB p13 standard set of scores.
+var StandardArray = []int{15, 14, 13, 12, 10, 8}
+
+// AbilityOrder is the order ability scores are passed to AbilityMethodIssues.
+var AbilityOrder = []string{"STR", "DEX", "CON", "INT", "WIS", "CHA"}
+
+// IsValidAbilityMethod reports whether method is a known generation method.
+func IsValidAbilityMethod(method string) bool {
+	switch method {
+	case AbilityMethodFreeform, AbilityMethodPointBuy, AbilityMethodStandardArray, AbilityMethodRolled:
+		return true
+	}
+	return false
+}
+
+// PointBuyCost returns the point-buy cost of a score; ok is false outside 8-15.
+func PointBuyCost(score int) (cost int, ok bool) {
+	switch {
+	case score < 8 || score > 15:
+		return 0, false
+	case score <= 13:
+		return score - 8, true
+	case score == 14:
+		return 7, true
+	}
+	return 9, true
+}
+
+// AbilityMethodIssues checks base scores (racial bonuses removed, in AbilityOrder) against a
+// generation method. asiPoints is how many points ability score improvements may have added;
+// they're assumed to have gone wherever they best explain the scores. Returns one message per
+// problem found, or nil when the scores fit.
+func AbilityMethodIssues(method string, base []int, asiPoints int) []string {
+	var issues []string
+	switch strings.ToLower(method) {
+	case AbilityMethodPointBuy:
+		scores := make([]int, len(base))
+		needed := 0
+		for i, s := range base {
+			if s < 8 {
+				issues = append(issues, fmt.Sprintf("%s %d is below the point-buy minimum of 8", AbilityOrder[i], s))
+			}
+			needed += max(s-15, 0)
+			scores[i] = min(max(s, 8), 15)
+		}
+		spare := asiPoints - needed
+		if spare < 0 {
+			issues = append(issues, fmt.Sprintf("scores above 15 need %d more points than ability score improvements give", -spare))
+		}
+		// Spend leftover ASI points where they save the most point-buy cost
+		for ; spare > 0; spare-- {
+			best, bestSaving := -1, 0
+			for i, s := range scores {
+				if s <= 8 {
+					continue
+				}
+				hi, _ := PointBuyCost(s)
+				lo, _ := PointBuyCost(s - 1)
+				if hi-lo > bestSaving {
+					best, bestSaving = i, hi-lo
+				}
+			}
+			if best < 0 {
+				break
+			}
+			scores[best]--
+		}
+		total := 0
+		for _, s := range scores {
+			cost, _ := PointBuyCost(s)
+			total += cost
+		}
+		if total > PointBuyBudget {
+			issues = append(issues, fmt.Sprintf("point-buy cost is %d, over the %d point budget", total, PointBuyBudget))
+		}
+	case AbilityMethodStandardArray:
+		sorted := append([]int(nil), base...)
+		sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+		needed := 0
+		for i, s := range sorted {
+			if i >= len(StandardArray) {
+				break
+			}
+			if s < StandardArray[i] {
+				issues = append(issues, "scores can't be made from the standard array (15, 14, 13, 12, 10, 8)")
+				return issues
+			}
+			needed += s - StandardArray[i]
+		}
+		if needed > asiPoints {
+			issues = append(issues, fmt.Sprintf("scores are %d points above the standard array plus ability score improvements", needed-asiPoints))
+		}
+	case AbilityMethodRolled:
+		needed := 0
+		for i, s := range base {
+			if s < 3 {
+				issues = append(issues, fmt.Sprintf("%s %d is below the lowest possible roll of 3", AbilityOrder[i], s))
+			}
+			needed += max(s-18, 0)
+		}
+		if needed > asiPoints {
+			issues = append(issues, fmt.Sprintf("scores above 18 need %d more points than ability score improvements give", needed-asiPoints))
+		}
+	}
+	return issues
+}
+
+// ASIPointsAtLevel returns the ability score improvement points earned by a character level
+// (2 points at 4, 8, 12, 16 and 19).
+func ASIPointsAtLevel(level int) int {
+	points := 0
+	for _, l := range []int{4, 8, 12, 16, 19} {
+		if level >= l {
+			points += 2
+		}
+	}
+	return points
+}
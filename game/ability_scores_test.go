@@ -0,0 +1,63 @@
+package game
+
+import "testing"
+
+func TestPointBuyCost(t *testing.T) {
+	tests := []struct {
+		score, cost int
+		ok          bool
+	}{
+		{8, 0, true},
+		{12, 4, true},
+		{13, 5, true},
+		{14, 7, true},
+		{15, 9, true},
+		{7, 0, false},
+		{16, 0, false},
+	}
+	for _, tt := range tests {
+		cost, ok := PointBuyCost(tt.score)
+		if cost != tt.cost || ok != tt.ok {
+			t.Errorf("PointBuyCost(%d) = (%d, %v), want (%d, %v)", tt.score, cost, ok, tt.cost, tt.ok)
+		}
+	}
+}
+
+func TestAbilityMethodIssues(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		base   []int
+		asi    int
+		issues int
+	}{
+		{"point buy exact", AbilityMethodPointBuy, []int{15, 15, 15, 8, 8, 8}, 0, 0},
+		{"point buy over budget", AbilityMethodPointBuy, []int{15, 15, 15, 10, 8, 8}, 0, 1},
+		{"point buy covered by ASI", AbilityMethodPointBuy, []int{16, 15, 15, 9, 8, 8}, 2, 0},
+		{"point buy above 15", AbilityMethodPointBuy, []int{17, 8, 8, 8, 8, 8}, 0, 1},
+		{"point buy below 8", AbilityMethodPointBuy, []int{7, 8, 8, 8, 8, 8}, 0, 1},
+		{"standard array", AbilityMethodStandardArray, []int{8, 15, 14, 10, 13, 12}, 0, 0},
+		{"standard array with ASI", AbilityMethodStandardArray, []int{8, 17, 14, 10, 13, 12}, 2, 0},
+		{"standard array mismatch", AbilityMethodStandardArray, []int{15, 15, 13, 12, 10, 8}, 0, 1},
+		{"standard array too low", AbilityMethodStandardArray, []int{15, 14, 13, 12, 8, 8}, 2, 1},
+		{"rolled", AbilityMethodRolled, []int{18, 3, 12, 11, 10, 9}, 0, 0},
+		{"rolled out of range", AbilityMethodRolled, []int{19, 2, 12, 11, 10, 9}, 0, 2},
+		{"freeform", AbilityMethodFreeform, []int{20, 20, 20, 20, 20, 20}, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := AbilityMethodIssues(tt.method, tt.base, tt.asi); len(got) != tt.issues {
+			t.Errorf("%s: AbilityMethodIssues = %v, want %d issues", tt.name, got, tt.issues)
+		}
+	}
+	if !IsValidAbilityMethod("point_buy") || IsValidAbilityMethod("3d6") {
+		t.Error("IsValidAbilityMethod returned the wrong answer")
+	}
+}
+
+func TestASIPointsAtLevel(t *testing.T) {
+	for level, want := range map[int]int{1: 0, 4: 2, 11: 4, 19: 10, 20: 10} {
+		if got := ASIPointsAtLevel(level); got != want {
+			t.Errorf("ASIPointsAtLevel(%d) = %d, want %d", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// action_economy.go - when a character's action, bonus action, reaction, and movement come back
+package game
+
+// Action economy resources (see getActionResourceType in the server).
+const (
+	ResourceAction      = "action"
+	ResourceBonusAction = "bonus_action"
+	ResourceReaction    = "reaction"
+	ResourceMovement    = "movement"
+	ResourceFree        = "free"
+)
+
+// Combat events that may refresh the action economy.
+const (
+	EconomyCombatStart = "combat_start" // Everyone starts the fight fresh
+	EconomyTurnStart   = "turn_start"   // The character whose turn it now is
+	EconomyRoundStart  = "round_start"  // A new round begins (nothing refreshes by itself)
+	EconomyCombatEnd   = "combat_end"   // Leaving combat: per-turn limits no longer apply
+)
+
+// ActionEconomy is a character's per-turn combat resources.
+type ActionEconomy struct {
+	ActionUsed           bool `json:"action_used"`
+	BonusActionUsed      bool `json:"bonus_action_used"`
+	ReactionUsed         bool `json:"reaction_used"`
+	MovementRemaining    int  `json:"movement_remaining"`
+	BonusActionSpellCast bool `json:"bonus_action_spell_cast"` // Limits the turn's other spell to a cantrip (PHB p202)
+	HasReadiedAction     bool `json:"has_readied_action"`
+}
+
+// FreshActionEconomy returns the resources a character has at the start of a turn.
+func FreshActionEconomy(speed int) ActionEconomy {
+	return ActionEconomy{MovementRemaining: speed}
+}
+
+// ResetActionEconomy applies a combat event to a character's action economy.
+//
+// The action, bonus action, and movement are per turn and come back at the start of
+// the character's own turn. The reaction also comes back at the start of their own
+// turn (PHB p190), not at the top of the round, so a reaction spent after their turn
+// stays spent until their next turn. A readied action lasts until the start of their
+// next turn (PHB p193), so it is dropped then too. Starting or leaving combat clears
+// everything.
+func ResetActionEconomy(e ActionEconomy, event string, speed int) ActionEconomy {
+	if RefreshesTurn(event) {
+		return FreshActionEconomy(speed)
+	}
+	return e
+}
+
+// RefreshesTurn reports whether an event gives a character a fresh set of per-turn resources.
+func RefreshesTurn(event string) bool {
+	switch event {
+	case EconomyCombatStart, EconomyTurnStart, EconomyCombatEnd:
+		return true
+	}
+	return false
+}
+
+// SpendsActionEconomy reports whether using resource should be recorded.
+// Outside combat there are no turns, so nothing is spent.
+func SpendsActionEconomy(inCombat bool, resource string) bool {
+	if !inCombat {
+		return false
+	}
+	switch resource {
+	case ResourceAction, ResourceBonusAction, ResourceReaction, ResourceMovement:
+		return true
+	}
+	return false
+}
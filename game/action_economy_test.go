@@ -0,0 +1,109 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestResetActionEconomy(t *testing.T) {
+	spent := ActionEconomy{
+		ActionUsed:           true,
+		BonusActionUsed:      true,
+		ReactionUsed:         true,
+		MovementRemaining:    5,
+		BonusActionSpellCast: true,
+		HasReadiedAction:     true,
+	}
+	fresh := FreshActionEconomy(30)
+
+	tests := []struct {
+		name  string
+		event string
+		want  ActionEconomy
+	}{
+		{"combat start refreshes everything", EconomyCombatStart, fresh},
+		{"own turn start refreshes everything", EconomyTurnStart, fresh},
+		{"round boundary refreshes nothing", EconomyRoundStart, spent},
+		{"leaving combat refreshes everything", EconomyCombatEnd, fresh},
+		{"unknown event refreshes nothing", "nap", spent},
+	}
+	for _, tt := range tests {
+		if got := ResetActionEconomy(spent, tt.event, 30); got != tt.want {
+			t.Errorf("%s: ResetActionEconomy(%s) = %+v, want %+v", tt.name, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshesTurn(t *testing.T) {
+	tests := map[string]bool{
+		EconomyCombatStart: true,
+		EconomyTurnStart:   true,
+		EconomyRoundStart:  false,
+		EconomyCombatEnd:   true,
+		"":                 false,
+	}
+	for event, want := range tests {
+		if got := RefreshesTurn(event); got != want {
+			t.Errorf("RefreshesTurn(%q) = %v, want %v", event, got, want)
+		}
+	}
+}
+
+func TestReactionAcrossRoundBoundary(t *testing.T) {
+	// Round 1: the fighter acts at initiative 10, then spends their reaction on an
+	// opportunity attack at initiative 5.
+	fighter := ResetActionEconomy(ActionEconomy{}, EconomyTurnStart, 30)
+	fighter.ActionUsed = true
+	fighter.ReactionUsed = true
+
+	// Round 2 begins; a monster at initiative 20 acts before the fighter.
+	fighter = ResetActionEconomy(fighter, EconomyRoundStart, 30)
+	if !fighter.ReactionUsed {
+		t.Fatal("reaction came back at the round boundary, want it spent until the fighter's turn")
+	}
+	if !fighter.ActionUsed {
+		t.Fatal("action came back at the round boundary")
+	}
+
+	// The fighter's own turn comes around.
+	fighter = ResetActionEconomy(fighter, EconomyTurnStart, 30)
+	if fighter.ReactionUsed || fighter.ActionUsed || fighter.MovementRemaining != 30 {
+		t.Errorf("turn start = %+v, want fresh resources", fighter)
+	}
+}
+
+func TestReadiedActionExpiresOnOwnTurn(t *testing.T) {
+	rogue := FreshActionEconomy(30)
+	rogue.ActionUsed = true
+	rogue.HasReadiedAction = true
+
+	rogue = ResetActionEconomy(rogue, EconomyRoundStart, 30)
+	if !rogue.HasReadiedAction {
+		t.Fatal("readied action was dropped at the round boundary, want it held until the rogue's turn")
+	}
+	rogue = ResetActionEconomy(rogue, EconomyTurnStart, 30)
+	if rogue.HasReadiedAction {
+		t.Error("readied action survived the start of the rogue's next turn")
+	}
+}
+
+func TestSpendsActionEconomy(t *testing.T) {
+	tests := []struct {
+		inCombat bool
+		resource string
+		want     bool
+	}{
+		{true, ResourceAction, true},
+		{true, ResourceBonusAction, true},
+		{true, ResourceReaction, true},
+		{true, ResourceMovement, true},
+		{true, ResourceFree, false},
+		{true, "", false},
+		{false, ResourceAction, false},
+		{false, ResourceReaction, false},
+	}
+	for _, tt := range tests {
+		if got := SpendsActionEconomy(tt.inCombat, tt.resource); got != tt.want {
+			t.Errorf("SpendsActionEconomy(%v, %q) = %v, want %v", tt.inCombat, tt.resource, got, tt.want)
+		}
+	}
+}
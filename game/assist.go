@@ -0,0 +1,84 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// assist.go - difficulty assist: relief for a party that keeps getting knocked down
+package game
+
+import "strings"
+
+// Assist modes: off, suggest the relief to the GM, or apply it automatically.
+const (
+	AssistOff     = "off"
+	AssistSuggest = "suggest"
+	AssistAuto    = "auto"
+)
+
+// Reliefs the assist can offer.
+const (
+	ReliefInspiration = "inspiration" // Every living character without inspiration gains it
+	ReliefMonsterHP   = "monster_hp"  // Monsters in the fight lose a share of their remaining HP
+	ReliefRescueHook  = "rescue_hook" // A prompt for the GM to narrate an NPC coming to help
+)
+
+// AssistReliefs lists every relief in the order the assist applies them.
+var AssistReliefs = []string{ReliefInspiration, ReliefMonsterHP, ReliefRescueHook}
+
+// DefaultAssistThreshold is how many downs in one session trigger the assist.
+const DefaultAssistThreshold = 2
+
+// AssistMonsterHPPercent is the share of its current HP a monster loses to the monster_hp relief.
+const AssistMonsterHPPercent = 25
+
+// IsValidAssistMode reports whether mode is off, suggest or auto.
+func IsValidAssistMode(mode string) bool {
+	return mode == AssistOff || mode == AssistSuggest || mode == AssistAuto
+}
+
+// ParseAssistReliefs reads a comma-separated relief list, dropping duplicates and keeping
+// AssistReliefs order. ok is false if any entry isn't a relief.
+func ParseAssistReliefs(list string) (reliefs []string, ok bool) {
+	want := map[string]bool{}
+	for _, r := range strings.Split(list, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if !isAssistRelief(r) {
+			return nil, false
+		}
+		want[r] = true
+	}
+	reliefs = []string{}
+	for _, r := range AssistReliefs {
+		if want[r] {
+			reliefs = append(reliefs, r)
+		}
+	}
+	return reliefs, true
+}
+
+func isAssistRelief(r string) bool {
+	for _, relief := range AssistReliefs {
+		if r == relief {
+			return true
+		}
+	}
+	return false
+}
+
+// AssistDue reports whether the assist should fire: it's on, the party has been downed at
+// least threshold times this session, and it hasn't already fired this session.
+func AssistDue(mode string, threshold, downs int, firedThisSession bool) bool {
+	if mode != AssistSuggest && mode != AssistAuto {
+		return false
+	}
+	return !firedThisSession && downs >= max(threshold, 1)
+}
+
+// AssistReducedHP returns a monster's HP after the monster_hp relief. It never kills:
+// a monster keeps at least 1 HP.
+func AssistReducedHP(hp int) int {
+	if hp <= 1 {
+		return hp
+	}
+	return max(hp-hp*AssistMonsterHPPercent/100, 1)
+}
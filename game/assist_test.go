@@ -0,0 +1,56 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAssistReliefs(t *testing.T) {
+	reliefs, ok := ParseAssistReliefs(" rescue_hook, Inspiration,rescue_hook ")
+	if !ok || strings.Join(reliefs, ",") != "inspiration,rescue_hook" {
+		t.Errorf("ParseAssistReliefs = %v, %v; want [inspiration rescue_hook], true", reliefs, ok)
+	}
+	if reliefs, ok := ParseAssistReliefs(""); !ok || len(reliefs) != 0 {
+		t.Errorf("ParseAssistReliefs(\"\") = %v, %v; want [], true", reliefs, ok)
+	}
+	if _, ok := ParseAssistReliefs("inspiration,extra_potion"); ok {
+		t.Error("ParseAssistReliefs accepted an unknown relief")
+	}
+}
+
+func TestAssistDue(t *testing.T) {
+	tests := []struct {
+		mode      string
+		threshold int
+		downs     int
+		fired     bool
+		expected  bool
+	}{
+		{AssistOff, 2, 5, false, false},
+		{AssistSuggest, 2, 1, false, false},
+		{AssistSuggest, 2, 2, false, true},
+		{AssistAuto, 3, 4, false, true},
+		{AssistAuto, 2, 4, true, false},
+		{AssistAuto, 0, 1, false, true},
+	}
+	for _, tt := range tests {
+		if got := AssistDue(tt.mode, tt.threshold, tt.downs, tt.fired); got != tt.expected {
+			t.Errorf("AssistDue(%q, %d, %d, %v) = %v, want %v", tt.mode, tt.threshold, tt.downs, tt.fired, got, tt.expected)
+		}
+	}
+}
+
+func TestAssistReducedHP(t *testing.T) {
+	tests := []struct{ hp, expected int }{
+		{40, 30},
+		{7, 6},
+		{2, 2},
+		{1, 1},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := AssistReducedHP(tt.hp); got != tt.expected {
+			t.Errorf("AssistReducedHP(%d) = %d, want %d", tt.hp, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// attack_ledger.go - itemized attack roll modifiers so players can check the math
+package game
+
+// LedgerEntry is one signed term of an attack total ("proficiency", +3).
+type LedgerEntry struct {
+	Source string `json:"source"`
+	Value  int    `json:"value"`
+}
+
+// AttackLedger records how an attack total and the AC it was compared against were built.
+type AttackLedger struct {
+	Rolls               []int         `json:"rolls"`     // d20s rolled (two with advantage/disadvantage)
+	RollType            string        `json:"roll_type"` // normal, advantage, disadvantage
+	Natural             int           `json:"natural"`   // The d20 that counts
+	Modifiers           []LedgerEntry `json:"modifiers"`
+	Total               int           `json:"total"`
+	AdvantageSources    []string      `json:"advantage_sources,omitempty"`
+	DisadvantageSources []string      `json:"disadvantage_sources,omitempty"`
+	TargetName          string        `json:"target_name,omitempty"`
+	TargetBaseAC        int           `json:"target_base_ac,omitempty"`
+	TargetCover         int           `json:"target_cover,omitempty"` // Cover bonus added to the target's AC
+	CoverSource         string        `json:"cover_source,omitempty"` // battle_map or gm_override
+	TargetAC            int           `json:"target_ac,omitempty"`
+	Hit                 *bool         `json:"hit,omitempty"` // Set once a target AC is known
+	Crit                bool          `json:"crit,omitempty"`
+	Damage              int           `json:"damage,omitempty"`      // Damage rolled on a hit
+	DamageType          string        `json:"damage_type,omitempty"` // v1.0.87
+}
+
+// Add appends a modifier term; zero terms are skipped.
+func (l *AttackLedger) Add(source string, value int) {
+	if value != 0 {
+		l.Modifiers = append(l.Modifiers, LedgerEntry{Source: source, Value: value})
+	}
+}
+
+// Modifier returns the sum of all modifier terms.
+func (l *AttackLedger) Modifier() int {
+	total := 0
+	for _, m := range l.Modifiers {
+		total += m.Value
+	}
+	return total
+}
+
+// LedgerRollType returns normal, advantage, or disadvantage for the recorded sources
+// (advantage and disadvantage cancel, PHB p173).
+func LedgerRollType(advantage, disadvantage bool) string {
+	switch {
+	case advantage && !disadvantage:
+		return "advantage"
+	case disadvantage && !advantage:
+		return "disadvantage"
+	}
+	return "normal"
+}
+
+// Finish records the d20s and computes the total.
+func (l *AttackLedger) Finish(natural int, rolls ...int) {
+	l.Natural = natural
+	l.Rolls = rolls
+	l.Total = natural + l.Modifier()
+}
+
+// ApplyTarget records the target's AC with cover and resolves hit and crit.
+func (l *AttackLedger) ApplyTarget(name string, baseAC, cover int) {
+	l.TargetName = name
+	l.TargetBaseAC = baseAC
+	l.TargetCover = cover
+	l.TargetAC = baseAC + cover
+	hit, crit := AttackHits(l.Natural, l.Total, l.TargetAC)
+	l.Hit = &hit
+	l.Crit = crit
+}
+
+// RecordDamage notes the damage a hit rolled. Safe on a nil ledger.
+func (l *AttackLedger) RecordDamage(amount int, damageType string) {
+	if l == nil {
+		return
+	}
+	l.Damage = amount
+	l.DamageType = damageType
+}
@@ -0,0 +1,62 @@
+package game
+
+import "testing"
+
+func TestAttackLedger(t *testing.T) {
+	var l AttackLedger
+	l.Add("strength", 3)
+	l.Add("proficiency", 2)
+	l.Add("archery", 0)
+	l.Add("great_weapon_master", -5)
+	l.Finish(14, 14)
+	if len(l.Modifiers) != 3 {
+		t.Errorf("zero modifiers should be skipped, got %v", l.Modifiers)
+	}
+	if l.Total != 14 {
+		t.Errorf("Total = %d, want 14", l.Total)
+	}
+
+	tests := []struct {
+		natural, baseAC, cover int
+		hit, crit              bool
+	}{
+		{14, 13, 0, true, false},
+		{14, 13, 2, false, false}, // Half cover turns a hit into a miss
+		{20, 25, 5, true, true},
+		{1, 5, 0, false, false},
+	}
+	for _, tt := range tests {
+		l.Finish(tt.natural, tt.natural)
+		l.ApplyTarget("Goblin", tt.baseAC, tt.cover)
+		if *l.Hit != tt.hit || l.Crit != tt.crit || l.TargetAC != tt.baseAC+tt.cover {
+			t.Errorf("natural %d vs AC %d+%d: hit=%v crit=%v, want %v %v", tt.natural, tt.baseAC, tt.cover, *l.Hit, l.Crit, tt.hit, tt.crit)
+		}
+	}
+}
+
+func TestLedgerRollType(t *testing.T) {
+	tests := []struct {
+		adv, dis bool
+		want     string
+	}{
+		{false, false, "normal"},
+		{true, false, "advantage"},
+		{false, true, "disadvantage"},
+		{true, true, "normal"},
+	}
+	for _, tt := range tests {
+		if got := LedgerRollType(tt.adv, tt.dis); got != tt.want {
+			t.Errorf("LedgerRollType(%v, %v) = %q, want %q", tt.adv, tt.dis, got, tt.want)
+		}
+	}
+}
+
+func TestAttackLedgerRecordDamage(t *testing.T) {
+	var l AttackLedger
+	l.RecordDamage(9, "slashing")
+	if l.Damage != 9 || l.DamageType != "slashing" {
+		t.Errorf("RecordDamage gave %d %q, want 9 slashing", l.Damage, l.DamageType)
+	}
+	var none *AttackLedger
+	none.RecordDamage(4, "piercing") // must not panic
+}
@@ -0,0 +1,113 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// boons.go - long-term blessings and curses the GM places on a character: named
+// modifier bundles that last until a specific remedy ends them
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Boon kinds, stored as ActiveEffect.Kind.
+const (
+	EffectKindSpell = "spell"
+	EffectKindBoon  = "boon"
+	EffectKindCurse = "curse"
+)
+
+// Remedies that end a boon or curse early.
+const (
+	RemedyRemoveCurse        = "remove_curse"
+	RemedyGreaterRestoration = "greater_restoration"
+	RemedyDispelMagic        = "dispel_magic"
+	RemedyWish               = "wish" // Ends anything
+)
+
+// Remedies describes each supported remedy.
+var Remedies = map[string]string{
+	RemedyRemoveCurse:        "Remove curse (PHB p271) or similar magic",
+	RemedyGreaterRestoration: "Greater restoration (PHB p246)",
+	RemedyDispelMagic:        "Dispel magic (PHB p234)",
+	RemedyWish:               "Wish, which ends any boon or curse",
+}
+
+// Boon is a named modifier bundle the GM can grant (v1.0.83). Curses are boons with
+// Kind curse. NeedsAbility boons apply their ability lists to the ability the GM picks.
+type Boon struct {
+	Name         string         `json:"name"`
+	Kind         string         `json:"kind"`
+	Description  string         `json:"description"`
+	Modifier     EffectModifier `json:"modifier"`
+	Remedies     []string       `json:"remedies"`
+	NeedsAbility bool           `json:"needs_ability,omitempty"`
+}
+
+// Boons are the preset blessings and curses (DMG ch. 7 supernatural gifts, MM lycanthropy).
+var Boons = map[string]Boon{
+	"blessing-of-protection": {
+		Name: "Blessing of Protection", Kind: EffectKindBoon,
+		Description: "+1 bonus to AC and saving throws (DMG p228)",
+		Modifier:    EffectModifier{ACBonus: 1, SaveBonus: 1},
+	},
+	"blessing-of-valhalla": {
+		Name: "Blessing of Valhalla", Kind: EffectKindBoon,
+		Description: "A warrior's favor: +1 bonus to attack rolls",
+		Modifier:    EffectModifier{AttackBonus: 1},
+	},
+	"charm-of-heroism": {
+		Name: "Charm of Heroism", Kind: EffectKindBoon,
+		Description: "The benefit of a potion of heroism: blessed (DMG p228, p188)",
+		Modifier:    EffectModifier{AttackDice: "1d4", SaveDice: "1d4"},
+		Remedies:    []string{RemedyDispelMagic},
+	},
+	"curse-of-lycanthropy": {
+		Name: "Curse of Lycanthropy", Kind: EffectKindCurse,
+		Description: "The beast within: disadvantage on Wisdom saves and Charisma checks (MM p206)",
+		Modifier:    EffectModifier{SaveDisadvantage: []string{"wis"}, CheckDisadvantage: []string{"cha"}},
+		Remedies:    []string{RemedyRemoveCurse},
+	},
+	"bestow-curse": {
+		Name: "Bestow Curse", Kind: EffectKindCurse, NeedsAbility: true,
+		Description: "Disadvantage on ability checks and saving throws with one ability (PHB p218)",
+		Remedies:    []string{RemedyRemoveCurse, RemedyGreaterRestoration},
+	},
+	"curse-of-misfortune": {
+		Name: "Curse of Misfortune", Kind: EffectKindCurse,
+		Description: "-1 penalty to attack rolls and saving throws",
+		Modifier:    EffectModifier{AttackBonus: -1, SaveBonus: -1},
+		Remedies:    []string{RemedyRemoveCurse, RemedyGreaterRestoration},
+	},
+}
+
+// BoonFor returns a preset boon or curse by slug, with ability-based curses pointed at
+// ability (default Wisdom).
+func BoonFor(slug, ability string) (Boon, bool) {
+	boon, ok := Boons[slug]
+	if !ok {
+		return Boon{}, false
+	}
+	if boon.NeedsAbility {
+		ability = NormalizeAbility(ability)
+		if ability == "" {
+			ability = "wis"
+		}
+		boon.Modifier.SaveDisadvantage = []string{ability}
+		boon.Modifier.CheckDisadvantage = []string{ability}
+		boon.Name = fmt.Sprintf("%s (%s)", boon.Name, strings.ToUpper(ability))
+	}
+	return boon, true
+}
+
+// IsValidRemedy reports whether remedy is a known remedy.
+func IsValidRemedy(remedy string) bool {
+	_, ok := Remedies[remedy]
+	return ok
+}
+
+// RemedyEnds reports whether a remedy ends a boon or curse. Wish ends anything;
+// otherwise the remedy must be one the effect lists.
+func RemedyEnds(e ActiveEffect, remedy string) bool {
+	return remedy == RemedyWish || slices.Contains(e.Remedies, remedy)
+}
@@ -0,0 +1,64 @@
+package game
+
+import "testing"
+
+func TestBoonFor(t *testing.T) {
+	curse, ok := BoonFor("bestow-curse", "strength")
+	if !ok || curse.Name != "Bestow Curse (STR)" || curse.Kind != EffectKindCurse {
+		t.Fatalf("bestow-curse = %+v", curse)
+	}
+	effects := []ActiveEffect{{Effect: curse.Name, Modifier: curse.Modifier}}
+	if _, ok := EffectSaveDisadvantage(effects, "str"); !ok {
+		t.Error("Bestow Curse on STR gives disadvantage on STR saves")
+	}
+	if _, ok := EffectCheckDisadvantage(effects, "str"); !ok {
+		t.Error("Bestow Curse on STR gives disadvantage on STR checks")
+	}
+	if c, _ := BoonFor("bestow-curse", ""); c.Modifier.SaveDisadvantage[0] != "wis" {
+		t.Errorf("default ability is Wisdom, got %+v", c.Modifier)
+	}
+	if _, ok := BoonFor("blessing-of-nothing", ""); ok {
+		t.Error("unknown boon")
+	}
+	for slug, b := range Boons {
+		for _, r := range b.Remedies {
+			if !IsValidRemedy(r) {
+				t.Errorf("%s lists unknown remedy %q", slug, r)
+			}
+		}
+	}
+}
+
+func TestBoonModifiersInRolls(t *testing.T) {
+	protection, _ := BoonFor("blessing-of-protection", "")
+	misfortune, _ := BoonFor("curse-of-misfortune", "")
+	effects := []ActiveEffect{
+		{Effect: protection.Name, Kind: EffectKindBoon, Modifier: protection.Modifier},
+		{Effect: misfortune.Name, Kind: EffectKindCurse, Modifier: misfortune.Modifier},
+	}
+	if total, entries := RollEffectBonus(effects, "save"); total != 0 || len(entries) != 2 {
+		t.Errorf("+1 and -1 on saves: got %d from %+v", total, entries)
+	}
+	if total, entries := RollEffectBonus(effects, "attack"); total != -1 || entries[0].Source != "Curse of Misfortune" {
+		t.Errorf("-1 on attacks: got %d from %+v", total, entries)
+	}
+	if total, _ := RollEffectBonus(effects, "check"); total != 0 {
+		t.Errorf("no check modifiers, got %d", total)
+	}
+	if ac, _ := EffectACBonus(effects); ac != 1 {
+		t.Errorf("Blessing of Protection +1 AC, got %d", ac)
+	}
+}
+
+func TestRemedyEnds(t *testing.T) {
+	lycanthropy := ActiveEffect{Effect: "Curse of Lycanthropy", Remedies: []string{RemedyRemoveCurse}}
+	if !RemedyEnds(lycanthropy, RemedyRemoveCurse) {
+		t.Error("remove curse ends lycanthropy")
+	}
+	if RemedyEnds(lycanthropy, RemedyGreaterRestoration) {
+		t.Error("greater restoration doesn't")
+	}
+	if !RemedyEnds(ActiveEffect{}, RemedyWish) {
+		t.Error("wish ends anything")
+	}
+}
@@ -0,0 +1,153 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// boss_kit.go - the GM's cheat sheet for a legendary monster (MM p11: legendary and lair actions)
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LairInitiative is the count lair actions happen on, losing initiative ties (MM p11).
+const LairInitiative = 20
+
+// BossAction is one legendary or lair action from a monster's stat block.
+type BossAction struct {
+	Name string `json:"name"`
+	Desc string `json:"desc"`
+	Cost int    `json:"cost,omitempty"` // Legendary actions only: points spent
+}
+
+// BossKit gathers what a GM needs to run a legendary monster from one block: legendary
+// actions with their costs, lair actions for count 20, regional effects, and narration.
+type BossKit struct {
+	CombatantID          int                 `json:"combatant_id"`
+	Name                 string              `json:"name"`
+	MonsterKey           string              `json:"monster_key"`
+	LegendaryResistances int                 `json:"legendary_resistances,omitempty"`
+	LegendaryActionCount int                 `json:"legendary_actions_per_round,omitempty"`
+	LegendaryActions     []BossAction        `json:"legendary_actions,omitempty"`
+	LairActions          []BossAction        `json:"lair_actions,omitempty"`
+	RegionalEffects      []string            `json:"regional_effects,omitempty"`
+	Narration            map[string][]string `json:"narration"`
+	Screen               string              `json:"gm_screen"`
+}
+
+// IsLegendary reports whether a monster needs a boss kit: it has legendary resistances,
+// legendary actions or lair actions.
+func IsLegendary(legendaryResistances int, legendaryActions, lairActions []BossAction) bool {
+	return legendaryResistances > 0 || len(legendaryActions) > 0 || len(lairActions) > 0
+}
+
+// NewBossKit assembles a kit for a combatant, filling in the narration and GM screen.
+// Legendary actions without a cost cost 1.
+func NewBossKit(combatantID int, name, monsterKey, monsterType string, legendaryResistances, legendaryActionCount int,
+	legendaryActions, lairActions []BossAction, regionalEffects []string) BossKit {
+	for i := range legendaryActions {
+		if legendaryActions[i].Cost < 1 {
+			legendaryActions[i].Cost = 1
+		}
+	}
+	if legendaryActionCount == 0 && len(legendaryActions) > 0 {
+		legendaryActionCount = 3
+	}
+	kit := BossKit{
+		CombatantID:          combatantID,
+		Name:                 name,
+		MonsterKey:           monsterKey,
+		LegendaryResistances: legendaryResistances,
+		LegendaryActionCount: legendaryActionCount,
+		LegendaryActions:     legendaryActions,
+		LairActions:          lairActions,
+		RegionalEffects:      regionalEffects,
+	}
+	kit.Narration = bossNarration(kit, monsterType)
+	kit.Screen = kit.screen()
+	return kit
+}
+
+// FirstSentence returns the first sentence of a stat block description, for narration.
+func FirstSentence(desc string) string {
+	desc = strings.TrimSpace(desc)
+	if i := strings.Index(desc, ". "); i >= 0 {
+		return desc[:i+1]
+	}
+	return desc
+}
+
+// bossNarration suggests lines for the moments a boss fight turns on.
+func bossNarration(kit BossKit, monsterType string) map[string][]string {
+	what := "creature"
+	if monsterType != "" {
+		what = strings.ToLower(monsterType)
+	}
+	n := map[string][]string{
+		"entrance": {
+			fmt.Sprintf("The air changes before %s appears - this %s has been waiting for you, and it knows this ground.", kit.Name, what),
+		},
+		"bloodied": {
+			fmt.Sprintf("%s staggers, wounded for the first time in an age, and its fury turns cold and careful.", kit.Name),
+		},
+		"defeat": {
+			fmt.Sprintf("%s falls, and the silence it leaves behind is louder than the battle.", kit.Name),
+		},
+	}
+	if kit.LegendaryResistances > 0 {
+		n["legendary_resistance"] = []string{
+			fmt.Sprintf("The magic takes hold for a heartbeat - then %s shrugs it off by sheer will.", kit.Name),
+		}
+	}
+	for _, a := range kit.LegendaryActions {
+		n["legendary_actions"] = append(n["legendary_actions"],
+			fmt.Sprintf("Before anyone can press the advantage, %s moves: %s", kit.Name, legendaryActionLine(a)))
+	}
+	for _, a := range kit.LairActions {
+		n["lair_actions"] = append(n["lair_actions"],
+			fmt.Sprintf("The lair answers its master. %s", FirstSentence(a.Desc)))
+	}
+	if len(kit.RegionalEffects) > 0 {
+		n["approach"] = []string{
+			fmt.Sprintf("Signs of %s are everywhere on the way in: %s", kit.Name, strings.ToLower(FirstSentence(kit.RegionalEffects[0]))),
+		}
+	}
+	return n
+}
+
+func legendaryActionLine(a BossAction) string {
+	if s := FirstSentence(a.Desc); s != "" {
+		return fmt.Sprintf("%s. %s", a.Name, s)
+	}
+	return a.Name + "."
+}
+
+// screen renders the kit as one plain-text block to keep beside the initiative order.
+func (k BossKit) screen() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s: BOSS KIT ===\n", strings.ToUpper(k.Name))
+	if k.LegendaryResistances > 0 {
+		fmt.Fprintf(&b, "Legendary Resistance (%d/day): turn a failed save into a success.\n", k.LegendaryResistances)
+	}
+	if len(k.LegendaryActions) > 0 {
+		fmt.Fprintf(&b, "Legendary actions: %d points per round, one at the end of another creature's turn; regained at the start of its turn.\n", k.LegendaryActionCount)
+		for _, a := range k.LegendaryActions {
+			fmt.Fprintf(&b, "  - %s (%d): %s\n", a.Name, a.Cost, FirstSentence(a.Desc))
+		}
+	}
+	if len(k.LairActions) > 0 {
+		fmt.Fprintf(&b, "Lair actions: initiative %d (losing ties), one per round.\n", LairInitiative)
+		for _, a := range k.LairActions {
+			if a.Name == "" {
+				fmt.Fprintf(&b, "  - %s\n", FirstSentence(a.Desc))
+				continue
+			}
+			fmt.Fprintf(&b, "  - %s: %s\n", a.Name, FirstSentence(a.Desc))
+		}
+	}
+	if len(k.RegionalEffects) > 0 {
+		b.WriteString("Regional effects (always on near the lair):\n")
+		for _, e := range k.RegionalEffects {
+			fmt.Fprintf(&b, "  - %s\n", FirstSentence(e))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirstSentence(t *testing.T) {
+	tests := map[string]string{
+		"The dragon beats its wings. Each creature within 10 feet must succeed on a DC 19 Dexterity saving throw.": "The dragon beats its wings.",
+		"  Magma erupts from a point on the ground  ":                                                              "Magma erupts from a point on the ground",
+		"": "",
+	}
+	for desc, want := range tests {
+		if got := FirstSentence(desc); got != want {
+			t.Errorf("FirstSentence(%q) = %q, want %q", desc, got, want)
+		}
+	}
+}
+
+func TestIsLegendary(t *testing.T) {
+	if IsLegendary(0, nil, nil) {
+		t.Error("a goblin isn't legendary")
+	}
+	if !IsLegendary(3, nil, nil) || !IsLegendary(0, []BossAction{{Name: "Detect"}}, nil) || !IsLegendary(0, nil, []BossAction{{Desc: "Tremor"}}) {
+		t.Error("legendary resistances, legendary actions or lair actions each make a boss")
+	}
+}
+
+func TestNewBossKit(t *testing.T) {
+	kit := NewBossKit(-1, "Ashardalon", "adult-red-dragon", "Dragon", 3, 0,
+		[]BossAction{
+			{Name: "Detect", Desc: "The dragon makes a Wisdom (Perception) check."},
+			{Name: "Wing Attack", Desc: "The dragon beats its wings. Each creature within 10 feet must save.", Cost: 2},
+		},
+		[]BossAction{{Name: "Magma", Desc: "Magma erupts from a point on the ground. Each creature there takes fire damage."}},
+		[]string{"Small earthquakes are common within 6 miles of the lair."})
+
+	if kit.LegendaryActionCount != 3 {
+		t.Errorf("legendary actions per round = %d, want the default 3", kit.LegendaryActionCount)
+	}
+	if kit.LegendaryActions[0].Cost != 1 || kit.LegendaryActions[1].Cost != 2 {
+		t.Errorf("costs = %d, %d; want 1, 2", kit.LegendaryActions[0].Cost, kit.LegendaryActions[1].Cost)
+	}
+	for _, moment := range []string{"entrance", "bloodied", "defeat", "legendary_resistance", "approach"} {
+		if len(kit.Narration[moment]) == 0 {
+			t.Errorf("no %s narration", moment)
+		}
+	}
+	if len(kit.Narration["legendary_actions"]) != 2 || len(kit.Narration["lair_actions"]) != 1 {
+		t.Errorf("narration = %v", kit.Narration)
+	}
+	for _, want := range []string{"ASHARDALON: BOSS KIT", "Legendary Resistance (3/day)", "Wing Attack (2): The dragon beats its wings.", "initiative 20", "Magma: Magma erupts", "Small earthquakes"} {
+		if !strings.Contains(kit.Screen, want) {
+			t.Errorf("gm_screen missing %q:\n%s", want, kit.Screen)
+		}
+	}
+}
+
+func TestNewBossKitResistancesOnly(t *testing.T) {
+	kit := NewBossKit(-2, "Marilith", "marilith", "Fiend", 0, 0, nil, nil, nil)
+	if kit.LegendaryActionCount != 0 || strings.Contains(kit.Screen, "Legendary actions") || strings.Contains(kit.Screen, "Lair actions") {
+		t.Errorf("a kit without actions shouldn't list them:\n%s", kit.Screen)
+	}
+	if _, ok := kit.Narration["lair_actions"]; ok {
+		t.Error("no lair action narration without lair actions")
+	}
+}
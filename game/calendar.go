@@ -0,0 +1,88 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// calendar.go - the campaign clock: in-game days and time of day, counted in minutes
+// since the campaign began, and the long rest limit of one per 24 hours (PHB p186)
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock lengths in game minutes.
+const (
+	MinutesPerHour   = 60
+	MinutesPerDay    = 24 * MinutesPerHour
+	ClockStart       = 8 * MinutesPerHour // Campaigns begin on Day 1 at 08:00
+	ShortRestMinutes = MinutesPerHour     // At least 1 hour (PHB p186)
+	LongRestMinutes  = 8 * MinutesPerHour // At least 8 hours (PHB p186)
+)
+
+// GameTime is a point on the campaign clock.
+type GameTime struct {
+	Minute    int    `json:"minute"` // Minutes since Day 1 00:00
+	Day       int    `json:"day"`
+	Hour      int    `json:"hour"`
+	Clock     string `json:"clock"` // "14:30"
+	TimeOfDay string `json:"time_of_day"`
+	Display   string `json:"display"` // "Day 3, 14:30 (afternoon)"
+}
+
+// NewGameTime breaks a clock minute into its day and time of day.
+func NewGameTime(minute int) GameTime {
+	minute = max(minute, 0)
+	t := GameTime{
+		Minute: minute,
+		Day:    minute/MinutesPerDay + 1,
+		Hour:   minute % MinutesPerDay / MinutesPerHour,
+	}
+	t.Clock = fmt.Sprintf("%02d:%02d", t.Hour, minute%MinutesPerHour)
+	t.TimeOfDay = TimeOfDay(t.Hour)
+	t.Display = fmt.Sprintf("Day %d, %s (%s)", t.Day, t.Clock, t.TimeOfDay)
+	return t
+}
+
+// TimeOfDay names the part of the day an hour falls in.
+func TimeOfDay(hour int) string {
+	switch {
+	case hour >= 5 && hour < 7:
+		return "dawn"
+	case hour >= 7 && hour < 12:
+		return "morning"
+	case hour >= 12 && hour < 17:
+		return "afternoon"
+	case hour >= 17 && hour < 20:
+		return "evening"
+	case hour >= 20 && hour < 22:
+		return "dusk"
+	}
+	return "night"
+}
+
+// ClockMinute is the clock minute for a day and time ("HH:MM"), or false if either is invalid.
+func ClockMinute(day int, clock string) (int, bool) {
+	var hour, minute int
+	if day < 1 {
+		return 0, false
+	}
+	if n, _ := fmt.Sscanf(clock, "%d:%d", &hour, &minute); n != 2 || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, false
+	}
+	return (day-1)*MinutesPerDay + hour*MinutesPerHour + minute, true
+}
+
+// LongRestWait is how many game minutes are left before a long rest ending at restEnd
+// is allowed, given when the last one ended (lastEnd < 0 for never): a character
+// can't benefit from more than one long rest in a 24-hour period (PHB p186).
+func LongRestWait(lastEnd, restEnd int) int {
+	if lastEnd < 0 {
+		return 0
+	}
+	return max(lastEnd+MinutesPerDay-restEnd, 0)
+}
+
+// EffectMinutes is how many clock minutes an effect lasting d takes, rounded up to a
+// whole minute.
+func EffectMinutes(d time.Duration) int {
+	return max(int((d+time.Minute-1)/time.Minute), 1)
+}
@@ -0,0 +1,65 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewGameTime(t *testing.T) {
+	tests := []struct {
+		minute  int
+		display string
+	}{
+		{ClockStart, "Day 1, 08:00 (morning)"},
+		{0, "Day 1, 00:00 (night)"},
+		{MinutesPerDay + 14*60 + 30, "Day 2, 14:30 (afternoon)"},
+		{2*MinutesPerDay + 5*60, "Day 3, 05:00 (dawn)"},
+		{18 * 60, "Day 1, 18:00 (evening)"},
+		{21*60 + 59, "Day 1, 21:59 (dusk)"},
+		{-10, "Day 1, 00:00 (night)"},
+	}
+	for _, tt := range tests {
+		if got := NewGameTime(tt.minute).Display; got != tt.display {
+			t.Errorf("NewGameTime(%d) = %q, want %q", tt.minute, got, tt.display)
+		}
+	}
+}
+
+func TestClockMinute(t *testing.T) {
+	if got, ok := ClockMinute(2, "06:15"); !ok || got != MinutesPerDay+6*60+15 {
+		t.Errorf("ClockMinute(2, 06:15) = %d %v", got, ok)
+	}
+	for _, bad := range []struct {
+		day   int
+		clock string
+	}{{0, "08:00"}, {1, "24:00"}, {1, "8"}, {1, "08:60"}} {
+		if _, ok := ClockMinute(bad.day, bad.clock); ok {
+			t.Errorf("ClockMinute(%d, %q) should be invalid", bad.day, bad.clock)
+		}
+	}
+}
+
+func TestLongRestWait(t *testing.T) {
+	if got := LongRestWait(-1, 500); got != 0 {
+		t.Errorf("never rested: %d", got)
+	}
+	// Rested until 08:00 on Day 1: the next rest can end 08:00 on Day 2
+	if got := LongRestWait(480, 480+LongRestMinutes); got != 16*60 {
+		t.Errorf("back-to-back rests wait %d, want 960", got)
+	}
+	if got := LongRestWait(480, 480+MinutesPerDay); got != 0 {
+		t.Errorf("a day later: %d", got)
+	}
+}
+
+func TestEffectMinutes(t *testing.T) {
+	if got := EffectMinutes(time.Minute); got != 1 {
+		t.Errorf("1 minute = %d", got)
+	}
+	if got := EffectMinutes(90 * time.Second); got != 2 {
+		t.Errorf("90 seconds = %d", got)
+	}
+	if got := EffectMinutes(8 * time.Hour); got != 480 {
+		t.Errorf("8 hours = %d", got)
+	}
+}
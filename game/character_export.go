@@ -0,0 +1,478 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// character_export.go - portable character files: everything needed to rebuild a
+// character on another server (or after a wipe), their validation, and the mapping to
+// and from a Foundry VTT dnd5e actor
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Character export format and the newest version this server reads and writes.
+const (
+	CharacterExportFormat  = "agentrpg-character"
+	CharacterExportVersion = 1
+)
+
+// CharacterExport is a character in portable form. Lists and choices that only this
+// server interprets (class resources, invocations and the like) travel as raw JSON.
+// In-combat state (actions used, death saves, concentration) is left behind.
+type CharacterExport struct {
+	Format           string       `json:"format"`
+	Version          int          `json:"version"`
+	ExportedAt       string       `json:"exported_at,omitempty"`
+	Name             string       `json:"name"`
+	Race             string       `json:"race"`
+	VariantHuman     bool         `json:"variant_human,omitempty"`
+	DraconicAncestry string       `json:"draconic_ancestry,omitempty"`
+	Background       string       `json:"background,omitempty"`
+	Class            string       `json:"class"`
+	Subclass         string       `json:"subclass,omitempty"`
+	Classes          []ClassLevel `json:"classes"`
+	Level            int          `json:"level"`
+	XP               int          `json:"xp"`
+	PendingASI       int          `json:"pending_asi,omitempty"`
+
+	Abilities map[string]int `json:"abilities"` // str, dex, con, int, wis, cha
+	HP        int            `json:"hp"`
+	MaxHP     int            `json:"max_hp"`
+	TempHP    int            `json:"temp_hp,omitempty"`
+	AC        int            `json:"ac"`
+	Senses    ExportSenses   `json:"senses"`
+
+	Money        ExportMoney              `json:"money"`
+	Inventory    []map[string]interface{} `json:"inventory"`
+	AttunedItems []string                 `json:"attuned_items"`
+	Equipped     ExportEquipped           `json:"equipped"`
+
+	Proficiencies ExportProficiencies `json:"proficiencies"`
+	Spells        ExportSpells        `json:"spells"`
+	Feats         []string            `json:"feats"`
+
+	Conditions          []string `json:"conditions"`
+	ConditionImmunities []string `json:"condition_immunities,omitempty"`
+	ExhaustionLevel     int      `json:"exhaustion_level,omitempty"`
+	Inspiration         bool     `json:"inspiration,omitempty"`
+
+	// Class choices: fighting styles, metamagic, invocations, pact boon, favored enemies
+	// and terrains, subclass choices and fiendish resilience, keyed by column.
+	Choices map[string]json.RawMessage `json:"choices,omitempty"`
+}
+
+// ExportSenses are special senses, in feet.
+type ExportSenses struct {
+	Darkvision int `json:"darkvision,omitempty"`
+	Blindsight int `json:"blindsight,omitempty"`
+	Truesight  int `json:"truesight,omitempty"`
+}
+
+// ExportMoney is coin by denomination.
+type ExportMoney struct {
+	CP int `json:"cp"`
+	SP int `json:"sp"`
+	EP int `json:"ep"`
+	GP int `json:"gp"`
+	PP int `json:"pp"`
+}
+
+// ExportEquipped is what's worn and wielded.
+type ExportEquipped struct {
+	Armor    string `json:"armor,omitempty"`
+	Shield   bool   `json:"shield,omitempty"`
+	MainHand string `json:"main_hand,omitempty"`
+	OffHand  string `json:"off_hand,omitempty"`
+}
+
+// ExportProficiencies are the character's proficiencies, skills as skill keys
+// ("sleight_of_hand").
+type ExportProficiencies struct {
+	Skills    []string `json:"skills"`
+	Expertise []string `json:"expertise"`
+	Tools     []string `json:"tools"`
+	Weapons   []string `json:"weapons"`
+	Armor     []string `json:"armor"`
+	Languages []string `json:"languages"`
+}
+
+// ExportSpells are spell slugs known and prepared, and slots spent.
+type ExportSpells struct {
+	Known         []string                   `json:"known"`
+	Prepared      []string                   `json:"prepared"`
+	SlotsUsed     json.RawMessage            `json:"slots_used,omitempty"`
+	PactSlotsUsed json.RawMessage            `json:"pact_slots_used,omitempty"`
+	Extra         map[string]json.RawMessage `json:"extra,omitempty"` // magical secrets, mystic arcanum, signature spells
+}
+
+// ExportAbilities are the ability keys in order.
+var ExportAbilities = []string{"str", "dex", "con", "int", "wis", "cha"}
+
+// Validate checks that an export can be rebuilt and returns everything wrong with it.
+// It also fills in what a hand-written file may leave out: the level from the classes,
+// or a single class entry from class and level.
+func (e *CharacterExport) Validate() []string {
+	issues := []string{}
+	if e.Format != CharacterExportFormat {
+		issues = append(issues, fmt.Sprintf("format must be %q", CharacterExportFormat))
+	}
+	if e.Version < 1 || e.Version > CharacterExportVersion {
+		issues = append(issues, fmt.Sprintf("version %d is not supported (this server reads up to %d)", e.Version, CharacterExportVersion))
+	}
+	if strings.TrimSpace(e.Name) == "" {
+		issues = append(issues, "name is required")
+	}
+	if strings.TrimSpace(e.Race) == "" {
+		issues = append(issues, "race is required")
+	}
+	e.Class = strings.ToLower(strings.TrimSpace(e.Class))
+	if len(e.Classes) == 0 && e.Class != "" {
+		e.Classes = []ClassLevel{{Class: e.Class, Level: max(e.Level, 1)}}
+	}
+	if len(e.Classes) == 0 {
+		issues = append(issues, "class is required")
+	} else {
+		for i, c := range e.Classes {
+			e.Classes[i].Class = strings.ToLower(strings.TrimSpace(c.Class))
+			if c.Level < 1 || c.HitDiceSpent < 0 || c.HitDiceSpent > c.Level {
+				issues = append(issues, fmt.Sprintf("class %s: level must be 1 or more and hit dice spent between 0 and the level", c.Class))
+			}
+		}
+		if e.Class == "" {
+			e.Class = e.Classes[0].Class
+		}
+		e.Level = TotalLevel(e.Classes)
+		if e.Level > 20 {
+			issues = append(issues, "classes total more than 20 levels")
+		}
+	}
+	for _, a := range ExportAbilities {
+		if score, ok := e.Abilities[a]; !ok || score < 1 || score > 30 {
+			issues = append(issues, fmt.Sprintf("abilities.%s must be 1-30", a))
+		}
+	}
+	if e.MaxHP < 1 || e.HP < 0 || e.HP > e.MaxHP || e.TempHP < 0 {
+		issues = append(issues, "hp must be between 0 and max_hp, max_hp at least 1, temp_hp not negative")
+	}
+	if e.AC < 1 || e.AC > 40 {
+		issues = append(issues, "ac must be 1-40")
+	}
+	if e.XP < 0 || e.PendingASI < 0 {
+		issues = append(issues, "xp and pending_asi can't be negative")
+	}
+	if m := e.Money; m.CP < 0 || m.SP < 0 || m.EP < 0 || m.GP < 0 || m.PP < 0 {
+		issues = append(issues, "money can't be negative")
+	}
+	if e.ExhaustionLevel < 0 || e.ExhaustionLevel > 6 {
+		issues = append(issues, "exhaustion_level must be 0-6")
+	}
+	return issues
+}
+
+// FoundrySkills maps the Foundry VTT dnd5e skill abbreviations to skill keys.
+var FoundrySkills = map[string]string{
+	"acr": "acrobatics", "ani": "animal_handling", "arc": "arcana", "ath": "athletics",
+	"dec": "deception", "his": "history", "ins": "insight", "itm": "intimidation",
+	"inv": "investigation", "med": "medicine", "nat": "nature", "prc": "perception",
+	"prf": "performance", "per": "persuasion", "rel": "religion", "slt": "sleight_of_hand",
+	"ste": "stealth", "sur": "survival",
+}
+
+// SkillKey normalizes a skill name: "Sleight of Hand" becomes "sleight_of_hand".
+func SkillKey(skill string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(skill)), " ", "_")
+}
+
+// FoundryActor is the part of a Foundry VTT dnd5e actor that maps to a character. The
+// full export rides along in flags.agentrpg.export, so a character taken to Foundry and
+// back loses nothing.
+type FoundryActor struct {
+	Name   string                            `json:"name"`
+	Type   string                            `json:"type"`
+	System FoundrySystem                     `json:"system"`
+	Items  []FoundryItem                     `json:"items"`
+	Flags  map[string]map[string]interface{} `json:"flags,omitempty"`
+}
+
+// FoundrySystem is the actor's dnd5e system data.
+type FoundrySystem struct {
+	Abilities  map[string]FoundryValue `json:"abilities"`
+	Attributes struct {
+		HP struct {
+			Value int `json:"value"`
+			Max   int `json:"max"`
+			Temp  int `json:"temp"`
+		} `json:"hp"`
+		AC struct {
+			Calc string `json:"calc"`
+			Flat int    `json:"flat"`
+		} `json:"ac"`
+		Exhaustion  int            `json:"exhaustion"`
+		Inspiration bool           `json:"inspiration"`
+		Senses      map[string]int `json:"senses"`
+	} `json:"attributes"`
+	Details struct {
+		Race       string       `json:"race"`
+		Background string       `json:"background"`
+		XP         FoundryValue `json:"xp"`
+	} `json:"details"`
+	Currency map[string]int          `json:"currency"`
+	Skills   map[string]FoundryValue `json:"skills"`
+	Traits   struct {
+		Languages  FoundryTrait `json:"languages"`
+		WeaponProf FoundryTrait `json:"weaponProf"`
+		ArmorProf  FoundryTrait `json:"armorProf"`
+		ToolProf   FoundryTrait `json:"toolProf"`
+		CI         FoundryTrait `json:"ci"`
+	} `json:"traits"`
+}
+
+// FoundryValue is a {"value": n} field. For skills 1 is proficient and 2 expertise.
+type FoundryValue struct {
+	Value int `json:"value"`
+}
+
+// FoundryTrait is a trait list; entries Foundry doesn't know go in custom, ";"-separated.
+type FoundryTrait struct {
+	Value  []string `json:"value"`
+	Custom string   `json:"custom"`
+}
+
+// FoundryItem is an embedded item: a class, subclass, feat, spell or piece of equipment.
+type FoundryItem struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	System map[string]interface{} `json:"system"`
+}
+
+// ToFoundryActor maps an export to a Foundry VTT dnd5e actor.
+func ToFoundryActor(e CharacterExport) FoundryActor {
+	a := FoundryActor{Name: e.Name, Type: "character", Items: []FoundryItem{}}
+	s := &a.System
+	s.Abilities = map[string]FoundryValue{}
+	for _, ab := range ExportAbilities {
+		s.Abilities[ab] = FoundryValue{Value: e.Abilities[ab]}
+	}
+	s.Attributes.HP.Value, s.Attributes.HP.Max, s.Attributes.HP.Temp = e.HP, e.MaxHP, e.TempHP
+	s.Attributes.AC.Calc, s.Attributes.AC.Flat = "flat", e.AC
+	s.Attributes.Exhaustion = e.ExhaustionLevel
+	s.Attributes.Inspiration = e.Inspiration
+	s.Attributes.Senses = map[string]int{"darkvision": e.Senses.Darkvision, "blindsight": e.Senses.Blindsight, "truesight": e.Senses.Truesight}
+	s.Details.Race, s.Details.Background, s.Details.XP = e.Race, e.Background, FoundryValue{Value: e.XP}
+	s.Currency = map[string]int{"cp": e.Money.CP, "sp": e.Money.SP, "ep": e.Money.EP, "gp": e.Money.GP, "pp": e.Money.PP}
+
+	s.Skills = map[string]FoundryValue{}
+	abbrev := map[string]string{}
+	for k, v := range FoundrySkills {
+		abbrev[v] = k
+	}
+	for _, skill := range e.Proficiencies.Skills {
+		if k, ok := abbrev[SkillKey(skill)]; ok {
+			s.Skills[k] = FoundryValue{Value: 1}
+		}
+	}
+	for _, skill := range e.Proficiencies.Expertise {
+		if k, ok := abbrev[SkillKey(skill)]; ok {
+			s.Skills[k] = FoundryValue{Value: 2}
+		}
+	}
+	custom := func(list []string) FoundryTrait {
+		return FoundryTrait{Value: []string{}, Custom: strings.Join(list, ";")}
+	}
+	s.Traits.Languages = custom(e.Proficiencies.Languages)
+	s.Traits.WeaponProf = custom(e.Proficiencies.Weapons)
+	s.Traits.ArmorProf = custom(e.Proficiencies.Armor)
+	s.Traits.ToolProf = custom(e.Proficiencies.Tools)
+	s.Traits.CI = FoundryTrait{Value: append([]string{}, e.ConditionImmunities...)}
+
+	for _, c := range e.Classes {
+		a.Items = append(a.Items, FoundryItem{Name: titleCase(c.Class), Type: "class", System: map[string]interface{}{
+			"identifier": c.Class, "levels": c.Level, "hitDiceUsed": c.HitDiceSpent,
+		}})
+	}
+	if e.Subclass != "" {
+		a.Items = append(a.Items, FoundryItem{Name: titleCase(e.Subclass), Type: "subclass", System: map[string]interface{}{
+			"identifier": e.Subclass, "classIdentifier": e.Class,
+		}})
+	}
+	for _, f := range e.Feats {
+		a.Items = append(a.Items, FoundryItem{Name: titleCase(f), Type: "feat", System: map[string]interface{}{"identifier": f}})
+	}
+	prepared := map[string]bool{}
+	for _, sp := range e.Spells.Prepared {
+		prepared[sp] = true
+	}
+	for _, sp := range e.Spells.Known {
+		a.Items = append(a.Items, FoundryItem{Name: titleCase(sp), Type: "spell", System: map[string]interface{}{
+			"identifier": sp, "preparation": map[string]interface{}{"prepared": prepared[sp]},
+		}})
+	}
+	for _, item := range e.Inventory {
+		name, _ := item["name"].(string)
+		if name == "" {
+			continue
+		}
+		qty := 1
+		if q, ok := item["quantity"].(float64); ok && q > 0 {
+			qty = int(q)
+		} else if q, ok := item["quantity"].(int); ok && q > 0 {
+			qty = q
+		}
+		kind := "loot"
+		switch t, _ := item["type"].(string); t {
+		case "weapon":
+			kind = "weapon"
+		case "armor", "shield":
+			kind = "equipment"
+		}
+		equipped := false
+		for _, eq := range []string{e.Equipped.Armor, e.Equipped.MainHand, e.Equipped.OffHand} {
+			if eq != "" && (strings.EqualFold(eq, name) || strings.EqualFold(eq, strings.ReplaceAll(strings.ToLower(name), " ", "-"))) {
+				equipped = true
+			}
+		}
+		a.Items = append(a.Items, FoundryItem{Name: name, Type: kind, System: map[string]interface{}{"quantity": qty, "equipped": equipped}})
+	}
+	a.Flags = map[string]map[string]interface{}{"agentrpg": {"export": e}}
+	return a
+}
+
+// FromFoundryActor maps a Foundry VTT dnd5e actor to an export. An actor exported from
+// this server carries the original in its flags and is returned as it was; anything else
+// is mapped field by field, with notes on what couldn't be carried over.
+func FromFoundryActor(a FoundryActor) (CharacterExport, []string) {
+	if raw, ok := a.Flags["agentrpg"]["export"]; ok {
+		var e CharacterExport
+		b, _ := json.Marshal(raw)
+		if json.Unmarshal(b, &e) == nil && e.Format == CharacterExportFormat {
+			return e, nil
+		}
+	}
+
+	notes := []string{}
+	s := a.System
+	e := CharacterExport{
+		Format:     CharacterExportFormat,
+		Version:    CharacterExportVersion,
+		Name:       a.Name,
+		Race:       s.Details.Race,
+		Background: s.Details.Background,
+		XP:         s.Details.XP.Value,
+		Abilities:  map[string]int{},
+		HP:         s.Attributes.HP.Value,
+		MaxHP:      s.Attributes.HP.Max,
+		TempHP:     s.Attributes.HP.Temp,
+		AC:         s.Attributes.AC.Flat,
+		Senses: ExportSenses{
+			Darkvision: s.Attributes.Senses["darkvision"],
+			Blindsight: s.Attributes.Senses["blindsight"],
+			Truesight:  s.Attributes.Senses["truesight"],
+		},
+		Money: ExportMoney{
+			CP: s.Currency["cp"], SP: s.Currency["sp"], EP: s.Currency["ep"], GP: s.Currency["gp"], PP: s.Currency["pp"],
+		},
+		Inventory:           []map[string]interface{}{},
+		AttunedItems:        []string{},
+		Feats:               []string{},
+		Conditions:          []string{},
+		ConditionImmunities: s.Traits.CI.Value,
+		ExhaustionLevel:     s.Attributes.Exhaustion,
+		Inspiration:         s.Attributes.Inspiration,
+		Spells:              ExportSpells{Known: []string{}, Prepared: []string{}},
+	}
+	for _, ab := range ExportAbilities {
+		e.Abilities[ab] = s.Abilities[ab].Value
+	}
+	if e.AC == 0 {
+		e.AC = 10 + Modifier(e.Abilities["dex"])
+		notes = append(notes, fmt.Sprintf("AC isn't a flat value in the actor; set to %d from DEX, re-equip armor to recalculate", e.AC))
+	}
+
+	skills := make([]string, 0, len(s.Skills))
+	for k := range s.Skills {
+		skills = append(skills, k)
+	}
+	sort.Strings(skills)
+	e.Proficiencies = ExportProficiencies{Skills: []string{}, Expertise: []string{}}
+	for _, k := range skills {
+		name, ok := FoundrySkills[k]
+		if !ok || s.Skills[k].Value < 1 {
+			continue
+		}
+		e.Proficiencies.Skills = append(e.Proficiencies.Skills, name)
+		if s.Skills[k].Value >= 2 {
+			e.Proficiencies.Expertise = append(e.Proficiencies.Expertise, name)
+		}
+	}
+	trait := func(t FoundryTrait) []string {
+		list := append([]string{}, t.Value...)
+		for _, c := range strings.Split(t.Custom, ";") {
+			if c = strings.TrimSpace(c); c != "" {
+				list = append(list, c)
+			}
+		}
+		return list
+	}
+	e.Proficiencies.Languages = trait(s.Traits.Languages)
+	e.Proficiencies.Weapons = trait(s.Traits.WeaponProf)
+	e.Proficiencies.Armor = trait(s.Traits.ArmorProf)
+	e.Proficiencies.Tools = trait(s.Traits.ToolProf)
+
+	identifier := func(item FoundryItem) string {
+		if id, ok := item.System["identifier"].(string); ok && id != "" {
+			return id
+		}
+		return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(item.Name)), " ", "-")
+	}
+	number := func(v interface{}) int {
+		if f, ok := v.(float64); ok {
+			return int(f)
+		}
+		return 0
+	}
+	for _, item := range a.Items {
+		switch item.Type {
+		case "class":
+			e.Classes = append(e.Classes, ClassLevel{
+				Class: identifier(item), Level: max(number(item.System["levels"]), 1),
+				HitDiceSpent: number(item.System["hitDiceUsed"]), Position: len(e.Classes),
+			})
+		case "subclass":
+			e.Subclass = identifier(item)
+		case "feat":
+			e.Feats = append(e.Feats, identifier(item))
+		case "spell":
+			slug := identifier(item)
+			e.Spells.Known = append(e.Spells.Known, slug)
+			if prep, ok := item.System["preparation"].(map[string]interface{}); ok && prep["prepared"] == true {
+				e.Spells.Prepared = append(e.Spells.Prepared, slug)
+			}
+		case "weapon", "equipment", "loot", "consumable", "tool", "container", "backpack":
+			entry := map[string]interface{}{"name": item.Name, "quantity": max(number(item.System["quantity"]), 1)}
+			switch item.Type {
+			case "weapon":
+				entry["type"] = "weapon"
+			case "equipment":
+				entry["type"] = "armor"
+			}
+			e.Inventory = append(e.Inventory, entry)
+		}
+	}
+	if len(e.Classes) > 0 {
+		e.Class = e.Classes[0].Class
+	}
+	if len(e.Inventory) > 0 {
+		notes = append(notes, "Nothing is equipped after import from Foundry; equip armor and weapons again")
+	}
+	return e, notes
+}
+
+func titleCase(slug string) string {
+	words := strings.Fields(strings.NewReplacer("-", " ", "_", " ").Replace(slug))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
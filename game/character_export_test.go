@@ -0,0 +1,122 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func sampleExport() CharacterExport {
+	return CharacterExport{
+		Format: CharacterExportFormat, Version: CharacterExportVersion,
+		Name: "Kira", Race: "Elf", Background: "Criminal", Class: "rogue", Subclass: "thief",
+		Classes:   []ClassLevel{{Class: "rogue", Level: 3, HitDiceSpent: 1}, {Class: "wizard", Level: 1, Position: 1}},
+		XP:        2700,
+		Abilities: map[string]int{"str": 10, "dex": 17, "con": 12, "int": 14, "wis": 13, "cha": 8},
+		HP:        20, MaxHP: 27, AC: 14,
+		Senses:    ExportSenses{Darkvision: 60},
+		Money:     ExportMoney{GP: 42, SP: 3},
+		Inventory: []map[string]interface{}{{"name": "Shortsword", "type": "weapon", "quantity": 1}, {"name": "Rope", "quantity": 1}},
+		Equipped:  ExportEquipped{MainHand: "shortsword"},
+		Proficiencies: ExportProficiencies{
+			Skills: []string{"stealth", "sleight of hand", "perception"}, Expertise: []string{"stealth"},
+			Languages: []string{"Common", "Elvish"}, Tools: []string{"thieves' tools"},
+		},
+		Spells:     ExportSpells{Known: []string{"magic-missile", "shield"}, Prepared: []string{"shield"}},
+		Feats:      []string{"alert"},
+		Conditions: []string{},
+	}
+}
+
+func TestCharacterExportValidate(t *testing.T) {
+	e := sampleExport()
+	e.Level = 0
+	if issues := e.Validate(); len(issues) != 0 {
+		t.Fatalf("valid export: %v", issues)
+	}
+	if e.Level != 4 {
+		t.Errorf("level from classes = %d, want 4", e.Level)
+	}
+
+	bad := sampleExport()
+	bad.Format = "other"
+	bad.Version = 9
+	bad.HP = 30
+	bad.Abilities["str"] = 0
+	bad.Money.GP = -1
+	bad.Classes = append(bad.Classes, ClassLevel{Class: "fighter", Level: 17})
+	if issues := bad.Validate(); len(issues) != 6 {
+		t.Errorf("issues = %v", issues)
+	}
+}
+
+func TestCharacterExportValidateSingleClass(t *testing.T) {
+	e := sampleExport()
+	e.Classes = nil
+	e.Class = "Rogue"
+	e.Level = 5
+	if issues := e.Validate(); len(issues) != 0 {
+		t.Fatalf("issues = %v", issues)
+	}
+	if len(e.Classes) != 1 || e.Classes[0].Class != "rogue" || e.Classes[0].Level != 5 {
+		t.Errorf("classes = %+v", e.Classes)
+	}
+}
+
+func TestFoundryRoundTripKeepsEverything(t *testing.T) {
+	e := sampleExport()
+	actor := ToFoundryActor(e)
+	if actor.System.Abilities["dex"].Value != 17 || actor.System.Attributes.HP.Max != 27 || actor.System.Currency["gp"] != 42 {
+		t.Errorf("system = %+v", actor.System)
+	}
+	if actor.System.Skills["ste"].Value != 2 || actor.System.Skills["slt"].Value != 1 {
+		t.Errorf("skills = %+v", actor.System.Skills)
+	}
+	// Through JSON, as a file would go
+	raw, _ := json.Marshal(actor)
+	var back FoundryActor
+	if err := json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	got, notes := FromFoundryActor(back)
+	if notes != nil || got.Name != "Kira" || len(got.Classes) != 2 || got.Equipped.MainHand != "shortsword" {
+		t.Errorf("round trip = %+v, notes %v", got, notes)
+	}
+}
+
+func TestFromFoundryActorMapsFields(t *testing.T) {
+	actor := ToFoundryActor(sampleExport())
+	actor.Flags = nil
+	raw, _ := json.Marshal(actor)
+	var back FoundryActor
+	json.Unmarshal(raw, &back)
+
+	e, notes := FromFoundryActor(back)
+	if issues := e.Validate(); len(issues) != 0 {
+		t.Fatalf("mapped export invalid: %v", issues)
+	}
+	if e.Level != 4 || e.Class != "rogue" || e.Subclass != "thief" || e.Classes[0].HitDiceSpent != 1 {
+		t.Errorf("classes = %+v subclass %q", e.Classes, e.Subclass)
+	}
+	if strings.Join(e.Proficiencies.Skills, ",") != "perception,sleight_of_hand,stealth" || strings.Join(e.Proficiencies.Expertise, ",") != "stealth" {
+		t.Errorf("skills %v expertise %v", e.Proficiencies.Skills, e.Proficiencies.Expertise)
+	}
+	if strings.Join(e.Proficiencies.Languages, ",") != "Common,Elvish" {
+		t.Errorf("languages %v", e.Proficiencies.Languages)
+	}
+	if len(e.Spells.Known) != 2 || len(e.Spells.Prepared) != 1 || e.Spells.Prepared[0] != "shield" {
+		t.Errorf("spells %+v", e.Spells)
+	}
+	if len(e.Inventory) != 2 || e.Inventory[0]["type"] != "weapon" || e.Money.GP != 42 || e.Senses.Darkvision != 60 {
+		t.Errorf("inventory %v money %+v", e.Inventory, e.Money)
+	}
+	if len(notes) != 1 {
+		t.Errorf("notes = %v", notes)
+	}
+}
+
+func TestSkillKey(t *testing.T) {
+	if got := SkillKey(" Sleight of Hand"); got != "sleight_of_hand" {
+		t.Errorf("SkillKey = %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// checks.go - ability check variants: skills with different abilities (PHB p175)
+// and tools with skills (XGtE p78)
+package game
+
+import "strings"
+
+// abilityNames maps short ability names to their full names.
+var abilityNames = map[string]string{
+	"str": "Strength", "dex": "Dexterity", "con": "Constitution",
+	"int": "Intelligence", "wis": "Wisdom", "cha": "Charisma",
+}
+
+// AbilityFullName returns "Strength" for "str" or "strength"; empty if unknown.
+func AbilityFullName(ability string) string {
+	return abilityNames[NormalizeAbility(ability)]
+}
+
+// NormalizeToolName turns "Thieves' Tools" into "thieves_tools".
+func NormalizeToolName(tool string) string {
+	t := strings.ToLower(strings.TrimSpace(tool))
+	t = strings.ReplaceAll(t, "'", "")
+	t = strings.ReplaceAll(t, "’", "")
+	return strings.ReplaceAll(t, " ", "_")
+}
+
+// ToolSkillPairings lists the skills each tool's description in XGtE says it can aid.
+// Keys are normalized tool names.
+var ToolSkillPairings = map[string][]string{
+	"alchemists_supplies":    {"arcana", "investigation"},
+	"brewers_supplies":       {"history", "medicine", "persuasion"},
+	"calligraphers_supplies": {"arcana", "history"},
+	"carpenters_tools":       {"history", "investigation", "perception"},
+	"cartographers_tools":    {"arcana", "history", "religion", "nature", "survival"},
+	"cobblers_tools":         {"arcana", "history", "investigation"},
+	"cooks_utensils":         {"history", "medicine", "survival"},
+	"disguise_kit":           {"deception", "intimidation", "performance", "persuasion"},
+	"forgery_kit":            {"arcana", "deception", "history", "investigation"},
+	"glassblowers_tools":     {"arcana", "history", "investigation"},
+	"herbalism_kit":          {"arcana", "investigation", "medicine", "nature", "survival"},
+	"jewelers_tools":         {"arcana", "investigation"},
+	"leatherworkers_tools":   {"arcana", "investigation"},
+	"masons_tools":           {"history", "investigation", "perception"},
+	"navigators_tools":       {"survival"},
+	"painters_supplies":      {"arcana", "history", "religion", "investigation", "perception"},
+	"poisoners_kit":          {"history", "investigation", "medicine", "nature", "survival"},
+	"potters_tools":          {"history", "investigation", "perception"},
+	"smiths_tools":           {"arcana", "history", "investigation"},
+	"thieves_tools":          {"history", "investigation", "perception", "sleight_of_hand"},
+	"tinkers_tools":          {"history", "investigation"},
+	"weavers_tools":          {"arcana", "history", "investigation"},
+	"woodcarvers_tools":      {"arcana", "history", "nature"},
+}
+
+// IsListedToolSkillPairing reports whether XGtE lists the tool as aiding the skill.
+// The GM can still approve other pairings; this only tells them whether it's by the book.
+func IsListedToolSkillPairing(tool, skill string) bool {
+	skill = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(skill), " ", "_"))
+	for _, s := range ToolSkillPairings[NormalizeToolName(tool)] {
+		if s == skill {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolSkillSynergy reports whether a check gets advantage for combining a tool and a skill:
+// proficiency in both, when both apply to the check (XGtE p78).
+func ToolSkillSynergy(toolProficient, skillProficient bool) bool {
+	return toolProficient && skillProficient
+}
+
+// LuckPoints is how many luck points the Lucky feat gives per long rest (PHB p167).
+const LuckPoints = 3
+
+// LuckyFeatRoll is the d20 kept after spending a luck point on your own roll: you roll
+// another d20 and choose which to use, so the better one.
+func LuckyFeatRoll(rolled, luckRoll int) int {
+	return max(rolled, luckRoll)
+}
@@ -0,0 +1,42 @@
+package game
+
+import "testing"
+
+func TestAbilityFullName(t *testing.T) {
+	for in, want := range map[string]string{"str": "Strength", "Wisdom": "Wisdom", "intl": "Intelligence", "luck": ""} {
+		if got := AbilityFullName(in); got != want {
+			t.Errorf("AbilityFullName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIsListedToolSkillPairing(t *testing.T) {
+	tests := []struct {
+		tool, skill string
+		want        bool
+	}{
+		{"thieves' tools", "investigation", true},
+		{"Thieves’ Tools", "Sleight of Hand", true},
+		{"thieves_tools", "athletics", false},
+		{"herbalism kit", "medicine", true},
+		{"disguise kit", "intimidation", true},
+		{"lute", "performance", false},
+	}
+	for _, tt := range tests {
+		if got := IsListedToolSkillPairing(tt.tool, tt.skill); got != tt.want {
+			t.Errorf("IsListedToolSkillPairing(%q, %q) = %v, want %v", tt.tool, tt.skill, got, tt.want)
+		}
+	}
+	if !ToolSkillSynergy(true, true) || ToolSkillSynergy(true, false) || ToolSkillSynergy(false, true) {
+		t.Error("ToolSkillSynergy should need proficiency in both")
+	}
+}
+
+func TestLuckyFeatRoll(t *testing.T) {
+	if got := LuckyFeatRoll(4, 17); got != 17 {
+		t.Errorf("LuckyFeatRoll(4, 17) = %d, want the luck die", got)
+	}
+	if got := LuckyFeatRoll(15, 3); got != 15 {
+		t.Errorf("LuckyFeatRoll(15, 3) = %d, want the original roll", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// companions.go - creatures that serve a character: Find Familiar (PHB p240), Pact of the
+// Chain familiars (PHB p107) and the Beast Master's ranger's companion (PHB p93)
+package game
+
+import "fmt"
+
+// Companion kinds.
+const (
+	CompanionFamiliar = "familiar" // Find Familiar: a spirit in animal form that can't attack
+	CompanionChain    = "chain"    // Pact of the Chain: special forms, and it can attack with its reaction
+	CompanionBeast    = "beast"    // Ranger's Companion: attacks when the ranger commands it
+)
+
+// FamiliarForms are the forms Find Familiar allows, by monster slug.
+var FamiliarForms = map[string]bool{
+	"bat": true, "cat": true, "crab": true, "frog": true, "hawk": true, "lizard": true,
+	"octopus": true, "owl": true, "poisonous-snake": true, "quipper": true, "rat": true,
+	"raven": true, "sea-horse": true, "spider": true, "weasel": true,
+}
+
+// ChainFamiliarForms are the extra forms open to a Pact of the Chain warlock.
+var ChainFamiliarForms = map[string]bool{
+	"imp": true, "pseudodragon": true, "quasit": true, "sprite": true,
+}
+
+// FindFamiliarCostCP is the charcoal, incense and herbs the spell consumes: 10 gp.
+const FindFamiliarCostCP = 1000
+
+// CompanionSharedSensesFt is how close a familiar must be for its master to share its
+// senses or talk to it telepathically.
+const CompanionSharedSensesFt = 100
+
+// CompanionFormAllowed reports whether a creature can serve as this kind of companion.
+// Beast companions must be Medium or smaller beasts of challenge rating 1/4 or lower.
+func CompanionFormAllowed(kind, slug, creatureType, size, cr string) (bool, string) {
+	switch kind {
+	case CompanionFamiliar:
+		if !FamiliarForms[slug] {
+			return false, "Find Familiar takes the form of a bat, cat, crab, frog, hawk, lizard, octopus, owl, poisonous snake, quipper, rat, raven, sea horse, spider or weasel"
+		}
+	case CompanionChain:
+		if !FamiliarForms[slug] && !ChainFamiliarForms[slug] {
+			return false, "A Pact of the Chain familiar takes a Find Familiar form or an imp, pseudodragon, quasit or sprite"
+		}
+	case CompanionBeast:
+		value, ok := ParseCR(cr)
+		if creatureType != "beast" {
+			return false, "A ranger's companion must be a beast"
+		}
+		if SizeOrder(size) > SizeOrder(SizeMedium) {
+			return false, "A ranger's companion must be Medium or smaller"
+		}
+		if !ok || value > 0.25 {
+			return false, fmt.Sprintf("A ranger's companion must be challenge rating 1/4 or lower (this one is %s)", cr)
+		}
+	default:
+		return false, "kind must be familiar, chain or beast"
+	}
+	return true, ""
+}
+
+// BeastCompanionMaxHP is a ranger's companion's hit point maximum: its normal maximum or
+// four times the ranger's level, whichever is higher.
+func BeastCompanionMaxHP(normalMaxHP, rangerLevel int) int {
+	return max(normalMaxHP, 4*rangerLevel)
+}
+
+// CompanionCommands are what each kind of companion can be told to do.
+var CompanionCommands = map[string][]string{
+	CompanionFamiliar: {"move", "dash", "disengage", "dodge", "help", "hide", "search", "share_senses", "dismiss", "summon"},
+	CompanionChain:    {"attack", "move", "dash", "disengage", "dodge", "help", "hide", "search", "share_senses", "dismiss", "summon"},
+	CompanionBeast:    {"attack", "move", "dash", "disengage", "dodge", "help"},
+}
+
+// CompanionCommandAllowed reports whether a companion of this kind takes the command.
+func CompanionCommandAllowed(kind, command string) bool {
+	for _, c := range CompanionCommands[kind] {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// CompanionCommandCost is what a command costs the companion's master in combat: "action",
+// "bonus_action", "attack" (one of the master's attacks, which a warlock forgoes so the chain
+// familiar can attack with its reaction) or "" when it costs the master nothing. A familiar
+// acts on its own turn; a beast companion acts only when its ranger spends their action,
+// or from 7th level a bonus action for anything but an attack (Exceptional Training).
+// Sharing a familiar's senses, dismissing it to its pocket dimension and summoning it back
+// each take the master's action.
+func CompanionCommandCost(kind, command string, masterLevel int) string {
+	switch {
+	case command == "move":
+		return ""
+	case command == "share_senses" || command == "dismiss" || command == "summon":
+		return "action"
+	case kind == CompanionChain && command == "attack":
+		return "attack"
+	case kind == CompanionBeast && command != "attack" && masterLevel >= 7:
+		return "bonus_action"
+	case kind == CompanionBeast:
+		return "action"
+	}
+	return ""
+}
+
+// CompanionCombatIDBase offsets companions' turn order IDs from character IDs, so a
+// familiar takes its own turn without sharing its master's.
+const CompanionCombatIDBase = 1000000
+
+// CompanionCombatID is a companion's ID in the turn order.
+func CompanionCombatID(companionID int) int {
+	return CompanionCombatIDBase + companionID
+}
+
+// CompanionFromCombatID returns the companion behind a turn order ID, if it is one.
+func CompanionFromCombatID(combatID int) (int, bool) {
+	if combatID > CompanionCombatIDBase {
+		return combatID - CompanionCombatIDBase, true
+	}
+	return 0, false
+}
+
+// CompanionOwnInitiative reports whether a companion rolls its own initiative. A familiar
+// does; a ranger's companion acts on its ranger's turn, so it follows them in the order.
+func CompanionOwnInitiative(kind string) bool {
+	return kind != CompanionBeast
+}
@@ -0,0 +1,75 @@
+package game
+
+import "testing"
+
+func TestCompanionFormAllowed(t *testing.T) {
+	tests := []struct {
+		kind, slug, creatureType, size, cr string
+		want                               bool
+	}{
+		{CompanionFamiliar, "owl", "beast", "Tiny", "0", true},
+		{CompanionFamiliar, "imp", "fiend", "Tiny", "1", false},
+		{CompanionChain, "imp", "fiend", "Tiny", "1", true},
+		{CompanionBeast, "wolf", "beast", "Medium", "1/4", true},
+		{CompanionBeast, "panther", "beast", "Medium", "1/4", true},
+		{CompanionBeast, "brown-bear", "beast", "Large", "1", false},
+		{CompanionBeast, "black-bear", "beast", "Medium", "1/2", false},
+		{CompanionBeast, "sprite", "fey", "Tiny", "1/4", false},
+		{"dragon", "owl", "beast", "Tiny", "0", false},
+	}
+	for _, tt := range tests {
+		if got, reason := CompanionFormAllowed(tt.kind, tt.slug, tt.creatureType, tt.size, tt.cr); got != tt.want {
+			t.Errorf("CompanionFormAllowed(%s, %s) = %v (%s), want %v", tt.kind, tt.slug, got, reason, tt.want)
+		}
+	}
+}
+
+func TestBeastCompanionMaxHP(t *testing.T) {
+	if got := BeastCompanionMaxHP(11, 3); got != 12 {
+		t.Errorf("a wolf with a 3rd-level ranger has %d HP, want 12", got)
+	}
+	if got := BeastCompanionMaxHP(13, 2); got != 13 {
+		t.Errorf("the beast's own maximum wins when higher: got %d", got)
+	}
+}
+
+func TestCompanionCommands(t *testing.T) {
+	if CompanionCommandAllowed(CompanionFamiliar, "attack") {
+		t.Error("a Find Familiar familiar can't attack")
+	}
+	if !CompanionCommandAllowed(CompanionChain, "attack") || !CompanionCommandAllowed(CompanionBeast, "attack") {
+		t.Error("chain familiars and beast companions can attack")
+	}
+	costs := []struct {
+		kind, command string
+		level         int
+		want          string
+	}{
+		{CompanionBeast, "attack", 7, "action"},
+		{CompanionBeast, "dodge", 3, "action"},
+		{CompanionBeast, "dodge", 7, "bonus_action"},
+		{CompanionChain, "attack", 5, "attack"},
+		{CompanionFamiliar, "help", 1, ""},
+		{CompanionFamiliar, "share_senses", 1, "action"},
+		{CompanionBeast, "move", 3, ""},
+		{CompanionChain, "dismiss", 5, "action"},
+	}
+	for _, c := range costs {
+		if got := CompanionCommandCost(c.kind, c.command, c.level); got != c.want {
+			t.Errorf("CompanionCommandCost(%s, %s, %d) = %q, want %q", c.kind, c.command, c.level, got, c.want)
+		}
+	}
+}
+
+func TestCompanionCombatID(t *testing.T) {
+	id := CompanionCombatID(7)
+	if got, ok := CompanionFromCombatID(id); !ok || got != 7 {
+		t.Errorf("CompanionFromCombatID(%d) = %d, %v", id, got, ok)
+	}
+	if _, ok := CompanionFromCombatID(42); ok {
+		t.Error("a character's ID isn't a companion's")
+	}
+	if _, ok := CompanionFromCombatID(-3); ok {
+		t.Error("a monster's ID isn't a companion's")
+	}
+}
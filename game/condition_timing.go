@@ -0,0 +1,153 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// condition_timing.go - conditions that end at a point in the initiative order
+// ("until the end of your next turn", "until the start of its next turn", "at the end of round 3")
+package game
+
+import "strings"
+
+// When a timed condition ends.
+const (
+	EndsStartOfNextTurn = "start_of_next_turn" // Start of the anchor combatant's next turn
+	EndsEndOfTurn       = "end_of_turn"        // End of the anchor's current turn (or their next one if it isn't their turn)
+	EndsEndOfNextTurn   = "end_of_next_turn"   // End of the anchor's next turn
+	EndsEndOfRound      = "end_of_round"       // End of a given round
+	EndsOnSave          = "save_ends"          // The creature repeats a save on each of its turns
+)
+
+// ConditionEndings describes each supported end timing.
+var ConditionEndings = map[string]string{
+	EndsStartOfNextTurn: "Ends when the anchor combatant's next turn starts (\"until the start of your next turn\")",
+	EndsEndOfTurn:       "Ends when the anchor's current turn ends, or their next turn if it isn't their turn now",
+	EndsEndOfNextTurn:   "Ends when the anchor's next turn ends (\"until the end of your next turn\")",
+	EndsEndOfRound:      "Ends when round N ends (default: the current round)",
+	EndsOnSave:          "The affected creature repeats save_ability vs save_dc at the end (or save_at=start_of_turn) of each of its turns; ends on a success (Hold Person)",
+}
+
+// Initiative boundaries that timed conditions listen for.
+const (
+	BoundaryTurnStart = "turn_start"
+	BoundaryTurnEnd   = "turn_end"
+	BoundaryRoundEnd  = "round_end"
+)
+
+// ConditionTimer ends a condition automatically as combat advances.
+type ConditionTimer struct {
+	Condition  string `json:"condition"`
+	Ends       string `json:"ends"`
+	AnchorID   int    `json:"anchor_id,omitempty"` // Combatant whose turn the timing refers to
+	AnchorName string `json:"anchor_name,omitempty"`
+	Round      int    `json:"round,omitempty"`     // EndsEndOfRound: the round that must end
+	Remaining  int    `json:"remaining,omitempty"` // Anchor turn boundaries still to pass
+
+	// EndsOnSave: the anchor (the affected creature) repeats this save on its turns
+	SaveAbility string `json:"save_ability,omitempty"`
+	SaveDC      int    `json:"save_dc,omitempty"`
+	SaveAt      string `json:"save_at,omitempty"` // BoundaryTurnEnd (default) or BoundaryTurnStart
+	Prompt      bool   `json:"prompt,omitempty"`  // Prompt the GM to roll instead of rolling automatically
+}
+
+// IsValidConditionEnding reports whether ends is a known end timing.
+func IsValidConditionEnding(ends string) bool {
+	_, ok := ConditionEndings[ends]
+	return ok
+}
+
+// NewConditionTimer builds a timer for a condition applied now. anchorActing is true when
+// it is currently the anchor's turn, so "the end of your next turn" skips the end of this
+// one. For EndsEndOfRound, round is the round that must end (clamped to the current round).
+func NewConditionTimer(condition, ends string, anchorID int, anchorName string, anchorActing bool, round, currentRound int) (ConditionTimer, bool) {
+	t := ConditionTimer{Condition: condition, Ends: ends, AnchorID: anchorID, AnchorName: anchorName}
+	switch ends {
+	case EndsStartOfNextTurn, EndsEndOfTurn:
+		t.Remaining = 1
+	case EndsEndOfNextTurn:
+		t.Remaining = 1
+		if anchorActing {
+			t.Remaining = 2
+		}
+	case EndsEndOfRound:
+		t.AnchorID, t.AnchorName = 0, ""
+		t.Round = max(round, currentRound)
+	default:
+		return t, false
+	}
+	return t, true
+}
+
+// NewSaveEndsTimer builds a timer for a condition the affected creature can end by
+// repeating a saving throw on each of its turns ("at the end of each of its turns, the
+// target can make another Wisdom saving throw"). saveAt is "start_of_turn" or
+// "end_of_turn" (default).
+func NewSaveEndsTimer(condition, ability string, dc, charID int, charName, saveAt string, prompt bool) (ConditionTimer, bool) {
+	boundary := BoundaryTurnEnd
+	switch saveAt {
+	case "", "end_of_turn", BoundaryTurnEnd:
+	case "start_of_turn", BoundaryTurnStart:
+		boundary = BoundaryTurnStart
+	default:
+		return ConditionTimer{}, false
+	}
+	ability = NormalizeSaveAbility(ability)
+	if ability == "" || dc <= 0 {
+		return ConditionTimer{}, false
+	}
+	return ConditionTimer{
+		Condition: condition, Ends: EndsOnSave, AnchorID: charID, AnchorName: charName,
+		SaveAbility: ability, SaveDC: dc, SaveAt: boundary, Prompt: prompt,
+	}, true
+}
+
+// NormalizeSaveAbility converts "wisdom"/"WIS" to "wis"; returns "" if unknown.
+func NormalizeSaveAbility(ability string) string {
+	switch strings.ToLower(strings.TrimSpace(ability)) {
+	case "str", "strength":
+		return "str"
+	case "dex", "dexterity":
+		return "dex"
+	case "con", "constitution":
+		return "con"
+	case "int", "intelligence":
+		return "int"
+	case "wis", "wisdom":
+		return "wis"
+	case "cha", "charisma":
+		return "cha"
+	}
+	return ""
+}
+
+// RepeatSaveDue reports whether a save-ends timer's creature rolls its repeat save at this
+// boundary of combatantID's turn.
+func RepeatSaveDue(t ConditionTimer, boundary string, combatantID int) bool {
+	if t.Ends != EndsOnSave || combatantID != t.AnchorID {
+		return false
+	}
+	saveAt := t.SaveAt
+	if saveAt == "" {
+		saveAt = BoundaryTurnEnd
+	}
+	return boundary == saveAt
+}
+
+// AdvanceConditionTimer applies an initiative boundary to a timer. combatantID is whose
+// turn started or ended (ignored for BoundaryRoundEnd); round is the round it happened in.
+// Returns the updated timer and whether the condition has now ended.
+func AdvanceConditionTimer(t ConditionTimer, boundary string, combatantID, round int) (ConditionTimer, bool) {
+	switch t.Ends {
+	case EndsEndOfRound:
+		return t, boundary == BoundaryRoundEnd && round >= t.Round
+	case EndsStartOfNextTurn:
+		if boundary != BoundaryTurnStart || combatantID != t.AnchorID {
+			return t, false
+		}
+	case EndsEndOfTurn, EndsEndOfNextTurn:
+		if boundary != BoundaryTurnEnd || combatantID != t.AnchorID {
+			return t, false
+		}
+	default:
+		return t, false
+	}
+	t.Remaining--
+	return t, t.Remaining <= 0
+}
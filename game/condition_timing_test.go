@@ -0,0 +1,150 @@
+package game
+
+import (
+	"testing"
+)
+
+// turn is one combatant's turn in the initiative order.
+type turn struct {
+	id    int
+	round int
+}
+
+// runTimer plays turns through a timer and returns the index of the turn at which
+// the condition ended (-1 if it never did), and whether it ended at the turn's "start",
+// "end", or the "round" end. turns[0] is the turn in progress when the timer was made,
+// so its start has already passed.
+func runTimer(t ConditionTimer, turns []turn) (int, string) {
+	for i, tn := range turns {
+		var done bool
+		if i > 0 {
+			if t, done = AdvanceConditionTimer(t, BoundaryTurnStart, tn.id, tn.round); done {
+				return i, "start"
+			}
+		}
+		if t, done = AdvanceConditionTimer(t, BoundaryTurnEnd, tn.id, tn.round); done {
+			return i, "end"
+		}
+		if i+1 == len(turns) || turns[i+1].round != tn.round {
+			if t, done = AdvanceConditionTimer(t, BoundaryRoundEnd, 0, tn.round); done {
+				return i, "round"
+			}
+		}
+	}
+	return -1, ""
+}
+
+func TestConditionTimerEnds(t *testing.T) {
+	// Initiative: fighter (1), goblin (-1), wizard (2), two rounds. Condition applied
+	// during the fighter's first turn (index 0).
+	turns := []turn{{1, 1}, {-1, 1}, {2, 1}, {1, 2}, {-1, 2}, {2, 2}}
+
+	tests := []struct {
+		name         string
+		ends         string
+		anchor       int
+		anchorActing bool
+		round        int
+		wantIndex    int
+		wantBoundary string
+	}{
+		{"end of fighter's next turn, applied on fighter's turn", EndsEndOfNextTurn, 1, true, 0, 3, "end"},
+		{"end of goblin's next turn, applied off goblin's turn", EndsEndOfNextTurn, -1, false, 0, 1, "end"},
+		{"start of fighter's next turn", EndsStartOfNextTurn, 1, true, 0, 3, "start"},
+		{"end of fighter's turn", EndsEndOfTurn, 1, true, 0, 0, "end"},
+		{"end of goblin's turn", EndsEndOfTurn, -1, false, 0, 1, "end"},
+		{"end of round 1", EndsEndOfRound, 0, false, 1, 2, "round"},
+		{"end of round 2", EndsEndOfRound, 0, false, 2, 5, "round"},
+	}
+	for _, tt := range tests {
+		timer, ok := NewConditionTimer("frightened", tt.ends, tt.anchor, "", tt.anchorActing, tt.round, 1)
+		if !ok {
+			t.Fatalf("%s: NewConditionTimer rejected %s", tt.name, tt.ends)
+		}
+		gotIndex, gotBoundary := runTimer(timer, turns)
+		if gotIndex != tt.wantIndex || gotBoundary != tt.wantBoundary {
+			t.Errorf("%s: ended at %d (%s), want %d (%s)", tt.name, gotIndex, gotBoundary, tt.wantIndex, tt.wantBoundary)
+		}
+	}
+}
+
+func TestNewConditionTimerRoundClamp(t *testing.T) {
+	timer, ok := NewConditionTimer("blinded", EndsEndOfRound, 5, "Ogre", false, 0, 3)
+	if !ok || timer.Round != 3 || timer.AnchorID != 0 {
+		t.Errorf("NewConditionTimer(end_of_round, 0) = %+v, %v; want round 3 with no anchor", timer, ok)
+	}
+	if _, ok := NewConditionTimer("blinded", "whenever", 5, "", false, 0, 3); ok {
+		t.Error("NewConditionTimer accepted an unknown ending")
+	}
+}
+
+func TestIsValidConditionEnding(t *testing.T) {
+	for ends := range ConditionEndings {
+		if !IsValidConditionEnding(ends) {
+			t.Errorf("IsValidConditionEnding(%q) = false", ends)
+		}
+	}
+	if IsValidConditionEnding("") {
+		t.Error("IsValidConditionEnding(\"\") = true")
+	}
+}
+
+func TestNewSaveEndsTimer(t *testing.T) {
+	timer, ok := NewSaveEndsTimer("paralyzed", "Wisdom", 13, 7, "Thorin", "", false)
+	if !ok || timer.SaveAbility != "wis" || timer.SaveAt != BoundaryTurnEnd || timer.AnchorID != 7 {
+		t.Errorf("NewSaveEndsTimer() = %+v, %v", timer, ok)
+	}
+	if timer, _ := NewSaveEndsTimer("restrained", "str", 12, 7, "Thorin", "start_of_turn", false); timer.SaveAt != BoundaryTurnStart {
+		t.Errorf("save_at start_of_turn gave %q", timer.SaveAt)
+	}
+	bad := []struct {
+		ability string
+		dc      int
+		saveAt  string
+	}{
+		{"luck", 13, ""},
+		{"wis", 0, ""},
+		{"wis", 13, "whenever"},
+	}
+	for _, b := range bad {
+		if _, ok := NewSaveEndsTimer("paralyzed", b.ability, b.dc, 7, "", b.saveAt, false); ok {
+			t.Errorf("NewSaveEndsTimer(%q, %d, %q) accepted", b.ability, b.dc, b.saveAt)
+		}
+	}
+}
+
+func TestRepeatSaveDue(t *testing.T) {
+	endTimer, _ := NewSaveEndsTimer("paralyzed", "wis", 13, 7, "", "", false)
+	startTimer, _ := NewSaveEndsTimer("paralyzed", "wis", 13, 7, "", "start_of_turn", false)
+	tests := []struct {
+		timer    ConditionTimer
+		boundary string
+		id       int
+		want     bool
+	}{
+		{endTimer, BoundaryTurnEnd, 7, true},
+		{endTimer, BoundaryTurnStart, 7, false},
+		{endTimer, BoundaryTurnEnd, 8, false},
+		{startTimer, BoundaryTurnStart, 7, true},
+		{startTimer, BoundaryTurnEnd, 7, false},
+	}
+	for _, tt := range tests {
+		if got := RepeatSaveDue(tt.timer, tt.boundary, tt.id); got != tt.want {
+			t.Errorf("RepeatSaveDue(%s at %s, %d, %d) = %v, want %v", tt.timer.SaveAt, tt.boundary, tt.id, tt.id, got, tt.want)
+		}
+	}
+
+	// A save-ends condition never ends on its own
+	if _, done := AdvanceConditionTimer(endTimer, BoundaryTurnEnd, 7, 1); done {
+		t.Error("AdvanceConditionTimer ended a save_ends condition without a save")
+	}
+}
+
+func TestNormalizeSaveAbility(t *testing.T) {
+	tests := map[string]string{"Wisdom": "wis", "DEX": "dex", " con ": "con", "luck": ""}
+	for in, want := range tests {
+		if got := NormalizeSaveAbility(in); got != want {
+			t.Errorf("NormalizeSaveAbility(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -92,6 +92,136 @@ func HasConditionExact(conditions []string, condition string) bool {
 	return false
 }
 
+// IsImmuneToCondition checks a comma-separated immunity list ("poisoned, exhaustion", as
+// seeded from the SRD) for a condition. Prefixed conditions like "frightened:12" match on
+// their base name.
+func IsImmuneToCondition(immunities string, condition string) bool {
+	base := strings.ToLower(strings.TrimSpace(condition))
+	if idx := strings.Index(base, ":"); idx != -1 {
+		base = base[:idx]
+	}
+	if base == "" {
+		return false
+	}
+	for _, imm := range strings.Split(strings.ToLower(immunities), ",") {
+		if strings.TrimSpace(imm) == base {
+			return true
+		}
+	}
+	return false
+}
+
+// Narrower immunity scopes for ConditionImmunity.Only (v1.0.81).
+const (
+	ImmunityOnlyMagicalSleep = "magical_sleep" // Fey Ancestry: magic can't put you to sleep
+	ImmunityOnlyMagic        = "magic"         // Ring of Free Action: magic can't paralyze or restrain you
+)
+
+// ConditionImmunity is one reason a character can't gain a condition (v1.0.81). Only, when
+// set, limits it to conditions from magical sleep or from magic; empty blocks every source.
+type ConditionImmunity struct {
+	Condition string `json:"condition"`
+	Source    string `json:"source"`
+	Only      string `json:"only,omitempty"`
+}
+
+// itemImmunity is a magic item's condition immunities and whether it must be attuned.
+type itemImmunity struct {
+	attunement bool
+	immunities []ConditionImmunity
+}
+
+// itemConditionImmunities maps item slugs to the condition immunities they grant (DMG ch. 7).
+var itemConditionImmunities = map[string]itemImmunity{
+	"periapt-of-proof-against-poison": {false, []ConditionImmunity{
+		{ConditionPoisoned, "Periapt of Proof against Poison", ""},
+	}},
+	"ring-of-free-action": {true, []ConditionImmunity{
+		{ConditionParalyzed, "Ring of Free Action", ImmunityOnlyMagic},
+		{ConditionRestrained, "Ring of Free Action", ImmunityOnlyMagic},
+	}},
+}
+
+// itemSlug turns an item name ("Ring of Free Action") into its slug ("ring-of-free-action").
+func itemSlug(name string) string {
+	slug := strings.ToLower(strings.TrimSpace(name))
+	slug = strings.ReplaceAll(slug, "'", "")
+	return strings.Join(strings.Fields(slug), "-")
+}
+
+// RaceConditionImmunities returns the condition immunities from racial traits (v1.0.81).
+// Fey Ancestry (PHB p23): magic can't put you to sleep.
+func RaceConditionImmunities(race string) []ConditionImmunity {
+	if HasFeyAncestry(race) {
+		return []ConditionImmunity{{ConditionUnconscious, "Fey Ancestry", ImmunityOnlyMagicalSleep}}
+	}
+	return nil
+}
+
+// ItemConditionImmunities returns the condition immunities granted by carried items and,
+// for items that require it, attuned items (v1.0.81). Each item counts once.
+func ItemConditionImmunities(carried, attuned []string) []ConditionImmunity {
+	var result []ConditionImmunity
+	seen := map[string]bool{}
+	add := func(names []string, isAttuned bool) {
+		for _, name := range names {
+			slug := itemSlug(name)
+			item, ok := itemConditionImmunities[slug]
+			if !ok || seen[slug] || (item.attunement && !isAttuned) {
+				continue
+			}
+			seen[slug] = true
+			result = append(result, item.immunities...)
+		}
+	}
+	add(attuned, true)
+	add(carried, false)
+	return result
+}
+
+// FindConditionImmunity returns the immunity that blocks a condition, if any (v1.0.81).
+// fromMagicalSleep marks the Sleep spell and similar effects, which are also magic.
+// Prefixed conditions like "frightened:12" match on their base name.
+func FindConditionImmunity(immunities []ConditionImmunity, condition string, fromMagic, fromMagicalSleep bool) (ConditionImmunity, bool) {
+	base := strings.ToLower(strings.TrimSpace(strings.SplitN(condition, ":", 2)[0]))
+	for _, imm := range immunities {
+		if strings.ToLower(imm.Condition) != base {
+			continue
+		}
+		switch imm.Only {
+		case "":
+			return imm, true
+		case ImmunityOnlyMagicalSleep:
+			if fromMagicalSleep {
+				return imm, true
+			}
+		case ImmunityOnlyMagic:
+			if fromMagic || fromMagicalSleep {
+				return imm, true
+			}
+		}
+	}
+	return ConditionImmunity{}, false
+}
+
+// FormatConditionImmunities lists the immunities in the comma-separated form monsters use
+// ("poisoned, unconscious (magical sleep)"), one entry per condition and scope.
+func FormatConditionImmunities(immunities []ConditionImmunity) string {
+	var parts []string
+	seen := map[string]bool{}
+	for _, imm := range immunities {
+		entry := strings.ToLower(imm.Condition)
+		if imm.Only != "" {
+			entry += " (" + strings.ReplaceAll(imm.Only, "_", " ") + ")"
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			parts = append(parts, entry)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // IsIncapacitated checks if conditions prevent taking actions or reactions.
 // Per 5e: paralyzed, stunned, unconscious, petrified, and incapacitated all prevent actions.
 func IsIncapacitated(conditions []string) bool {
@@ -360,3 +360,73 @@ func TestAllConditions(t *testing.T) {
 		}
 	}
 }
+
+func TestIsImmuneToCondition(t *testing.T) {
+	ghost := "charmed, exhaustion, frightened, grappled, paralyzed, petrified, poisoned, prone, restrained"
+	tests := []struct {
+		condition string
+		expected  bool
+	}{
+		{"poisoned", true},
+		{"Prone", true},
+		{"frightened:12", true},
+		{"grappled:-3", true},
+		{"blinded", false},
+		{"stunned", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsImmuneToCondition(ghost, tt.condition); got != tt.expected {
+			t.Errorf("IsImmuneToCondition(ghost, %q) = %v, want %v", tt.condition, got, tt.expected)
+		}
+	}
+	if IsImmuneToCondition("", "poisoned") {
+		t.Error("no immunities means no condition is blocked")
+	}
+}
+
+func TestFindConditionImmunity(t *testing.T) {
+	elf := RaceConditionImmunities("High Elf")
+	if _, ok := FindConditionImmunity(elf, "unconscious", false, true); !ok {
+		t.Error("Fey Ancestry should block magical sleep")
+	}
+	if _, ok := FindConditionImmunity(elf, "unconscious", true, false); ok {
+		t.Error("Fey Ancestry only blocks magical sleep, not other magic")
+	}
+	if RaceConditionImmunities("Human") != nil {
+		t.Error("humans have no racial condition immunities")
+	}
+
+	// The ring needs attunement; the periapt doesn't
+	items := ItemConditionImmunities([]string{"Ring of Free Action", "Periapt of Proof against Poison"}, nil)
+	if len(items) != 1 || items[0].Condition != ConditionPoisoned {
+		t.Fatalf("unattuned ring shouldn't count, got %+v", items)
+	}
+	items = ItemConditionImmunities([]string{"Ring of Free Action"}, []string{"ring of free action"})
+	if len(items) != 2 {
+		t.Fatalf("attuned ring counted once, got %+v", items)
+	}
+	tests := []struct {
+		condition string
+		fromMagic bool
+		blocked   bool
+	}{
+		{"paralyzed", true, true},
+		{"paralyzed", false, false},
+		{"restrained", true, true},
+		{"grappled:3", true, false},
+	}
+	for _, tt := range tests {
+		if _, ok := FindConditionImmunity(items, tt.condition, tt.fromMagic, false); ok != tt.blocked {
+			t.Errorf("FindConditionImmunity(ring, %q, magic=%v) = %v, want %v", tt.condition, tt.fromMagic, ok, tt.blocked)
+		}
+	}
+
+	granted := []ConditionImmunity{{"frightened", "Heroes' Feast", ""}}
+	if imm, ok := FindConditionImmunity(granted, "frightened:12", false, false); !ok || imm.Source != "Heroes' Feast" {
+		t.Errorf("granted frightened immunity should block frightened:12, got %+v %v", imm, ok)
+	}
+	if got := FormatConditionImmunities(append(elf, items...)); got != "unconscious (magical sleep), paralyzed (magic), restrained (magic)" {
+		t.Errorf("FormatConditionImmunities = %q", got)
+	}
+}
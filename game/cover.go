@@ -0,0 +1,151 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// cover.go - cover (PHB p196) and flanking (DMG p251) from positions on a combat grid
+package game
+
+import (
+	"math"
+	"strings"
+)
+
+// Cover levels, lowest to highest.
+const (
+	CoverNone          = "none"
+	CoverHalf          = "half"           // +2 AC and DEX saves: low wall, furniture, another creature
+	CoverThreeQuarters = "three_quarters" // +5 AC and DEX saves: portcullis, arrow slit, thick tree trunk
+	CoverTotal         = "total"          // Can't be targeted directly
+)
+
+var coverRank = map[string]int{CoverNone: 0, CoverHalf: 1, CoverThreeQuarters: 2, CoverTotal: 3}
+
+// CoverACBonus returns the AC bonus for a cover level. Total cover has no bonus because
+// the target can't be attacked at all.
+func CoverACBonus(cover string) int {
+	switch cover {
+	case CoverHalf:
+		return 2
+	case CoverThreeQuarters:
+		return 5
+	}
+	return 0
+}
+
+// NormalizeCover converts "three-quarters", "Full", etc. to a cover constant; returns ""
+// if unknown.
+func NormalizeCover(cover string) string {
+	cover = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(cover)), "-", "_")
+	if cover == "full" {
+		return CoverTotal
+	}
+	if _, ok := coverRank[cover]; ok {
+		return cover
+	}
+	return ""
+}
+
+// GridPos is a square on the combat grid (5 ft per square).
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Obstacle is a GM-placed barrier occupying one square.
+type Obstacle struct {
+	Name  string `json:"name,omitempty"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Cover string `json:"cover"`
+}
+
+// BattleMap holds combatant squares (keyed by combatant ID; monsters are negative),
+// obstacles and spell-made light and darkness for the current combat.
+type BattleMap struct {
+	Positions  map[int]GridPos `json:"positions"`
+	Obstacles  []Obstacle      `json:"obstacles"`
+	LightZones []LightZone     `json:"light_zones,omitempty"`
+}
+
+// CellsBetween returns the squares a line from the center of one square to the center of
+// another passes through, excluding both end squares.
+func CellsBetween(from, to GridPos) []GridPos {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	steps := 4 * max(abs(dx), abs(dy))
+	cells := []GridPos{}
+	seen := map[GridPos]bool{from: true, to: true}
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		cell := GridPos{
+			X: int(math.Round(float64(from.X) + t*float64(dx))),
+			Y: int(math.Round(float64(from.Y) + t*float64(dy))),
+		}
+		if !seen[cell] {
+			seen[cell] = true
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// CoverBetween returns the cover the target has against the attacker: the best cover of
+// any obstacle on the line between them, and at least half cover if another combatant
+// stands in the way. ok is false if either combatant has no position.
+func (m BattleMap) CoverBetween(attackerID, targetID int) (cover string, ok bool) {
+	from, okFrom := m.Positions[attackerID]
+	to, okTo := m.Positions[targetID]
+	if !okFrom || !okTo {
+		return CoverNone, false
+	}
+	blockers := map[GridPos]string{}
+	for _, o := range m.Obstacles {
+		if c := NormalizeCover(o.Cover); c != "" {
+			pos := GridPos{o.X, o.Y}
+			if coverRank[c] > coverRank[blockers[pos]] {
+				blockers[pos] = c
+			}
+		}
+	}
+	for id, pos := range m.Positions {
+		if id != attackerID && id != targetID && coverRank[blockers[pos]] < coverRank[CoverHalf] {
+			blockers[pos] = CoverHalf
+		}
+	}
+	cover = CoverNone
+	for _, cell := range CellsBetween(from, to) {
+		if c, ok := blockers[cell]; ok && coverRank[c] > coverRank[cover] {
+			cover = c
+		}
+	}
+	return cover, true
+}
+
+// Flanks reports whether an attacker and an ally flank a target: both adjacent to it on
+// opposite sides or opposite corners (DMG p251, one-square creatures).
+func Flanks(attacker, ally, target GridPos) bool {
+	adjacent := func(p GridPos) bool {
+		return p != target && max(abs(p.X-target.X), abs(p.Y-target.Y)) == 1
+	}
+	return adjacent(attacker) && adjacent(ally) &&
+		ally.X == 2*target.X-attacker.X && ally.Y == 2*target.Y-attacker.Y
+}
+
+// FlankingAlly returns the first of allyIDs that flanks targetID with attackerID.
+func (m BattleMap) FlankingAlly(attackerID, targetID int, allyIDs []int) (int, bool) {
+	attacker, okA := m.Positions[attackerID]
+	target, okT := m.Positions[targetID]
+	if !okA || !okT {
+		return 0, false
+	}
+	for _, id := range allyIDs {
+		if ally, ok := m.Positions[id]; ok && id != attackerID && Flanks(attacker, ally, target) {
+			return id, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,99 @@
+package game
+
+import "testing"
+
+func TestNormalizeCover(t *testing.T) {
+	tests := map[string]string{
+		"half":           CoverHalf,
+		"Three-Quarters": CoverThreeQuarters,
+		"full":           CoverTotal,
+		"total":          CoverTotal,
+		"none":           CoverNone,
+		"lots":           "",
+	}
+	for in, want := range tests {
+		if got := NormalizeCover(in); got != want {
+			t.Errorf("NormalizeCover(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCoverACBonus(t *testing.T) {
+	tests := map[string]int{CoverNone: 0, CoverHalf: 2, CoverThreeQuarters: 5, CoverTotal: 0}
+	for cover, want := range tests {
+		if got := CoverACBonus(cover); got != want {
+			t.Errorf("CoverACBonus(%q) = %d, want %d", cover, got, want)
+		}
+	}
+}
+
+func TestCellsBetween(t *testing.T) {
+	cells := CellsBetween(GridPos{0, 0}, GridPos{4, 0})
+	if len(cells) != 3 || cells[0] != (GridPos{1, 0}) || cells[2] != (GridPos{3, 0}) {
+		t.Errorf("CellsBetween straight line = %v", cells)
+	}
+	if cells := CellsBetween(GridPos{0, 0}, GridPos{1, 1}); len(cells) != 0 {
+		t.Errorf("adjacent squares should have nothing between, got %v", cells)
+	}
+}
+
+func TestCoverBetween(t *testing.T) {
+	m := BattleMap{
+		Positions: map[int]GridPos{1: {0, 0}, -1: {6, 0}, -2: {3, 0}, 2: {0, 4}, -3: {6, 4}},
+		Obstacles: []Obstacle{{Name: "low wall", X: 3, Y: 4, Cover: "half"}, {Name: "arrow slit", X: 3, Y: 4, Cover: "three-quarters"}},
+	}
+	tests := []struct {
+		attacker, target int
+		want             string
+		ok               bool
+	}{
+		{1, -1, CoverHalf, true},          // Goblin -2 stands in the way
+		{1, -2, CoverNone, true},          // Clear line
+		{2, -3, CoverThreeQuarters, true}, // Best obstacle in the square wins
+		{1, 99, CoverNone, false},         // No position
+		{-2, 1, CoverNone, true},          // Reverse direction, nothing between
+	}
+	for _, tt := range tests {
+		got, ok := m.CoverBetween(tt.attacker, tt.target)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("CoverBetween(%d, %d) = %q, %v, want %q, %v", tt.attacker, tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	m.Obstacles = append(m.Obstacles, Obstacle{Name: "pillar", X: 2, Y: 0, Cover: "total"})
+	if got, _ := m.CoverBetween(1, -1); got != CoverTotal {
+		t.Errorf("pillar in the way: got %q, want total", got)
+	}
+}
+
+func TestFlanks(t *testing.T) {
+	target := GridPos{5, 5}
+	tests := []struct {
+		attacker, ally GridPos
+		want           bool
+	}{
+		{GridPos{4, 5}, GridPos{6, 5}, true},  // Opposite sides
+		{GridPos{4, 4}, GridPos{6, 6}, true},  // Opposite corners
+		{GridPos{4, 5}, GridPos{6, 6}, false}, // Not opposite
+		{GridPos{4, 5}, GridPos{5, 4}, false}, // Adjacent sides
+		{GridPos{3, 5}, GridPos{7, 5}, false}, // Opposite but not adjacent
+	}
+	for _, tt := range tests {
+		if got := Flanks(tt.attacker, tt.ally, target); got != tt.want {
+			t.Errorf("Flanks(%v, %v) = %v, want %v", tt.attacker, tt.ally, got, tt.want)
+		}
+	}
+}
+
+func TestFlankingAlly(t *testing.T) {
+	m := BattleMap{Positions: map[int]GridPos{1: {4, 5}, 2: {6, 5}, 3: {5, 4}, -1: {5, 5}}}
+	if ally, ok := m.FlankingAlly(1, -1, []int{3, 2}); !ok || ally != 2 {
+		t.Errorf("FlankingAlly() = %d, %v, want 2, true", ally, ok)
+	}
+	if _, ok := m.FlankingAlly(3, -1, []int{1, 2}); ok {
+		t.Error("character 3 has nobody opposite")
+	}
+	if _, ok := m.FlankingAlly(1, -9, []int{2}); ok {
+		t.Error("unplaced target can't be flanked")
+	}
+}
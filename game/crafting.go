@@ -0,0 +1,47 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// crafting.go - crafting nonmagical items during downtime (PHB p187)
+package game
+
+// CraftingCPPerDay is the market value a day of crafting produces: 5 gp (PHB p187).
+const CraftingCPPerDay = 500
+
+// CraftingProject is an item being crafted across one or more downtime periods.
+type CraftingProject struct {
+	Item        string `json:"item"`
+	Tool        string `json:"tool"`
+	ValueCP     int    `json:"value_cp"`
+	MaterialsCP int    `json:"materials_cp"` // Paid up front: half the market value
+	DaysDone    int    `json:"days_done"`
+	DaysNeeded  int    `json:"days_needed"`
+}
+
+// CraftingDays is how many days an item worth valueCP takes to craft, at least one.
+func CraftingDays(valueCP int) int {
+	return max((valueCP+CraftingCPPerDay-1)/CraftingCPPerDay, 1)
+}
+
+// NewCraftingProject starts crafting an item worth valueCP. The raw materials cost half
+// its market value.
+func NewCraftingProject(item, tool string, valueCP int) CraftingProject {
+	return CraftingProject{
+		Item:        item,
+		Tool:        tool,
+		ValueCP:     valueCP,
+		MaterialsCP: valueCP / 2,
+		DaysNeeded:  CraftingDays(valueCP),
+	}
+}
+
+// Work spends up to days on the project. It returns how many days were used (days past
+// completion aren't) and whether the item is finished.
+func (p *CraftingProject) Work(days int) (used int, done bool) {
+	used = min(max(days, 0), p.Remaining())
+	p.DaysDone += used
+	return used, p.DaysDone >= p.DaysNeeded
+}
+
+// Remaining is how many days of work the project still needs.
+func (p CraftingProject) Remaining() int {
+	return max(p.DaysNeeded-p.DaysDone, 0)
+}
@@ -0,0 +1,36 @@
+package game
+
+import "testing"
+
+func TestCraftingDays(t *testing.T) {
+	tests := []struct {
+		valueCP int
+		want    int
+	}{
+		{1500, 3},     // Longsword: 15 gp
+		{1200, 3},     // 12 gp rounds up
+		{20, 1},       // Quarterstaff: 2 sp still takes a day
+		{150000, 300}, // Plate armor: 1,500 gp
+	}
+	for _, tt := range tests {
+		if got := CraftingDays(tt.valueCP); got != tt.want {
+			t.Errorf("CraftingDays(%d) = %d, want %d", tt.valueCP, got, tt.want)
+		}
+	}
+}
+
+func TestCraftingProjectWork(t *testing.T) {
+	p := NewCraftingProject("Longsword", "smith's tools", 1500)
+	if p.MaterialsCP != 750 || p.DaysNeeded != 3 {
+		t.Fatalf("new longsword project = %+v, want 7 gp 5 sp of materials over 3 days", p)
+	}
+
+	used, done := p.Work(2)
+	if used != 2 || done || p.Remaining() != 1 {
+		t.Errorf("after 2 days: used %d, done %v, remaining %d", used, done, p.Remaining())
+	}
+	used, done = p.Work(5)
+	if used != 1 || !done || p.DaysDone != 3 {
+		t.Errorf("finishing with 5 days spent %d (done %v, days %d); want only the 1 needed", used, done, p.DaysDone)
+	}
+}
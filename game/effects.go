@@ -0,0 +1,255 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// effects.go - ongoing spell effects and buffs (Bless, Bane, Haste, Hex) and the
+// modifiers they put on attack rolls, saves, checks and AC while they last
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EffectModifier is what an active effect changes while it lasts (v1.0.82). Dice terms
+// ("1d4", or "-1d4" for Bane) are rolled fresh for every attack or save.
+type EffectModifier struct {
+	AttackDice        string   `json:"attack_dice,omitempty"`
+	SaveDice          string   `json:"save_dice,omitempty"`
+	AttackBonus       int      `json:"attack_bonus,omitempty"` // v1.0.83: flat bonuses for boons and curses
+	SaveBonus         int      `json:"save_bonus,omitempty"`
+	CheckBonus        int      `json:"check_bonus,omitempty"`
+	ACBonus           int      `json:"ac_bonus,omitempty"`
+	SaveAdvantage     []string `json:"save_advantage,omitempty"`     // Abilities ("dex" for Haste)
+	SaveDisadvantage  []string `json:"save_disadvantage,omitempty"`  // v1.0.83: Bestow Curse, lycanthropy
+	CheckDisadvantage []string `json:"check_disadvantage,omitempty"` // Abilities ("str" for Hex)
+	SpeedMultiplier   int      `json:"speed_multiplier,omitempty"`
+}
+
+// ActiveEffect is a spell effect on a character (v1.0.82). ExpiresRound is the last combat
+// round it lasts through (0 outside combat); ExpiresAt is its wall-clock end and
+// ExpiresGame (v1.0.106) its end on the campaign clock.
+type ActiveEffect struct {
+	ID            int            `json:"id"`
+	CharacterID   int            `json:"character_id"`
+	Effect        string         `json:"effect"`
+	SourceSpell   string         `json:"source_spell"`
+	CasterID      int            `json:"caster_id,omitempty"`
+	Concentration bool           `json:"concentration"`
+	Modifier      EffectModifier `json:"modifier"`
+	ExpiresRound  int            `json:"expires_round,omitempty"`
+	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
+	ExpiresGame   int            `json:"expires_game_minute,omitempty"`
+	Kind          string         `json:"kind,omitempty"`        // v1.0.83: spell (default), boon or curse
+	Remedies      []string       `json:"remedies,omitempty"`    // v1.0.83: what ends a boon or curse early
+	Description   string         `json:"description,omitempty"` // v1.0.83
+}
+
+// SpellEffect is the effect a spell leaves on each creature it targets.
+type SpellEffect struct {
+	Effect       string
+	Modifier     EffectModifier
+	Targets      int  // Creatures affected at the spell's base level
+	PerUpcast    int  // Extra creatures per slot level above the base
+	Harmful      bool // Targets enemies: never falls back to the caster
+	NeedsAbility bool // Hex: the caster picks an ability
+}
+
+// spellEffects lists the spells whose ongoing effects are tracked (PHB ch. 11).
+var spellEffects = map[string]SpellEffect{
+	"bless": {Effect: "Blessed", Targets: 3, PerUpcast: 1,
+		Modifier: EffectModifier{AttackDice: "1d4", SaveDice: "1d4"}},
+	"bane": {Effect: "Baned", Targets: 3, PerUpcast: 1, Harmful: true,
+		Modifier: EffectModifier{AttackDice: "-1d4", SaveDice: "-1d4"}},
+	"haste": {Effect: "Hasted", Targets: 1,
+		Modifier: EffectModifier{ACBonus: 2, SaveAdvantage: []string{"dex"}, SpeedMultiplier: 2}},
+	"shield-of-faith": {Effect: "Shield of Faith", Targets: 1,
+		Modifier: EffectModifier{ACBonus: 2}},
+	"hex": {Effect: "Hexed", Targets: 1, Harmful: true, NeedsAbility: true},
+}
+
+// SpellEffectFor returns the tracked effect of a spell slug, if it has one. Hex takes the
+// ability the caster chose (default Strength).
+func SpellEffectFor(slug, ability string) (SpellEffect, bool) {
+	effect, ok := spellEffects[slug]
+	if !ok {
+		return SpellEffect{}, false
+	}
+	if effect.NeedsAbility {
+		ability = NormalizeAbility(ability)
+		if ability == "" {
+			ability = "str"
+		}
+		effect.Modifier.CheckDisadvantage = []string{ability}
+		effect.Effect = fmt.Sprintf("%s (%s)", effect.Effect, strings.ToUpper(ability))
+	}
+	return effect, true
+}
+
+// AbilityFromText finds the first ability named in free text ("hex the orc, wisdom"),
+// or "" if none is.
+func AbilityFromText(text string) string {
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return (r < 'a' || r > 'z')
+	}) {
+		if ability := NormalizeAbility(word); ability != "" {
+			return ability
+		}
+	}
+	return ""
+}
+
+// MaxTargets returns how many creatures the spell affects when cast with a slot of
+// slotLevel, for a spell of baseLevel.
+func (e SpellEffect) MaxTargets(baseLevel, slotLevel int) int {
+	if slotLevel > baseLevel {
+		return e.Targets + e.PerUpcast*(slotLevel-baseLevel)
+	}
+	return e.Targets
+}
+
+// EffectDuration reads a spell duration ("Concentration, up to 1 minute", "1 hour") as
+// combat rounds (6 seconds each) and wall-clock time. Instantaneous and open-ended
+// durations return zero.
+func EffectDuration(duration string) (int, time.Duration) {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(duration, ",", " ")))
+	for i := 0; i+1 < len(fields); i++ {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n <= 0 {
+			continue
+		}
+		var d time.Duration
+		switch strings.TrimSuffix(fields[i+1], "s") {
+		case "round":
+			d = time.Duration(n) * 6 * time.Second
+		case "minute":
+			d = time.Duration(n) * time.Minute
+		case "hour":
+			d = time.Duration(n) * time.Hour
+		case "day":
+			d = time.Duration(n) * 24 * time.Hour
+		default:
+			continue
+		}
+		return int(d / (6 * time.Second)), d
+	}
+	return 0, 0
+}
+
+// EffectExpired reports whether an effect has run out at the given combat round (0 when
+// there's no combat), time and campaign clock minute.
+func EffectExpired(e ActiveEffect, round int, now time.Time, clock int) bool {
+	if e.ExpiresRound > 0 && round > e.ExpiresRound {
+		return true
+	}
+	if e.ExpiresGame > 0 && clock >= e.ExpiresGame {
+		return true
+	}
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// ConcentrationDC is the Constitution save to keep concentrating after taking damage:
+// 10 or half the damage, whichever is higher (PHB p203).
+func ConcentrationDC(damage int) int {
+	return max(10, damage/2)
+}
+
+// distinctEffects keeps one effect per source spell: the same spell's effects don't
+// combine (PHB p205), so two Blesses still add one d4.
+func distinctEffects(effects []ActiveEffect) []ActiveEffect {
+	seen := map[string]bool{}
+	result := []ActiveEffect{}
+	for _, e := range effects {
+		key := e.SourceSpell
+		if key == "" {
+			key = e.Effect
+		}
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// rollEffectDice rolls a signed dice term like "1d4" or "-1d4".
+func rollEffectDice(dice string) int {
+	dice = strings.TrimSpace(dice)
+	if dice == "" {
+		return 0
+	}
+	sign := 1
+	if strings.HasPrefix(dice, "-") {
+		sign = -1
+		dice = dice[1:]
+	}
+	return sign * RollDamage(strings.TrimPrefix(dice, "+"), false)
+}
+
+// RollEffectBonus rolls every active effect's dice and flat bonuses for an attack
+// ("attack"), saving throw ("save") or ability check ("check") and returns the total and
+// one ledger term per effect.
+func RollEffectBonus(effects []ActiveEffect, kind string) (int, []LedgerEntry) {
+	total := 0
+	var entries []LedgerEntry
+	for _, e := range distinctEffects(effects) {
+		dice, flat := e.Modifier.AttackDice, e.Modifier.AttackBonus
+		switch kind {
+		case "save":
+			dice, flat = e.Modifier.SaveDice, e.Modifier.SaveBonus
+		case "check":
+			dice, flat = "", e.Modifier.CheckBonus
+		}
+		if dice == "" && flat == 0 {
+			continue
+		}
+		value := rollEffectDice(dice) + flat
+		source := e.Effect
+		if dice != "" {
+			source = fmt.Sprintf("%s (%s)", e.Effect, dice)
+		}
+		total += value
+		entries = append(entries, LedgerEntry{Source: source, Value: value})
+	}
+	return total, entries
+}
+
+// EffectACBonus totals the AC bonuses of active effects and names their sources.
+func EffectACBonus(effects []ActiveEffect) (int, []string) {
+	total := 0
+	var sources []string
+	for _, e := range distinctEffects(effects) {
+		if e.Modifier.ACBonus != 0 {
+			total += e.Modifier.ACBonus
+			sources = append(sources, e.Effect)
+		}
+	}
+	return total, sources
+}
+
+// EffectSaveAdvantage returns the effect granting advantage on saves of an ability, if any.
+func EffectSaveAdvantage(effects []ActiveEffect, ability string) (string, bool) {
+	return effectForAbility(effects, ability, func(m EffectModifier) []string { return m.SaveAdvantage })
+}
+
+// EffectSaveDisadvantage returns the effect imposing disadvantage on saves of an ability, if any.
+func EffectSaveDisadvantage(effects []ActiveEffect, ability string) (string, bool) {
+	return effectForAbility(effects, ability, func(m EffectModifier) []string { return m.SaveDisadvantage })
+}
+
+// EffectCheckDisadvantage returns the effect imposing disadvantage on checks of an ability, if any.
+func EffectCheckDisadvantage(effects []ActiveEffect, ability string) (string, bool) {
+	return effectForAbility(effects, ability, func(m EffectModifier) []string { return m.CheckDisadvantage })
+}
+
+func effectForAbility(effects []ActiveEffect, ability string, list func(EffectModifier) []string) (string, bool) {
+	ability = NormalizeAbility(ability)
+	for _, e := range effects {
+		for _, a := range list(e.Modifier) {
+			if NormalizeAbility(a) == ability {
+				return e.Effect, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,133 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectDuration(t *testing.T) {
+	tests := []struct {
+		duration string
+		rounds   int
+		wall     time.Duration
+	}{
+		{"Concentration, up to 1 minute", 10, time.Minute},
+		{"Concentration, up to 1 hour", 600, time.Hour},
+		{"10 minutes", 100, 10 * time.Minute},
+		{"1 round", 1, 6 * time.Second},
+		{"Instantaneous", 0, 0},
+		{"Until dispelled", 0, 0},
+	}
+	for _, tt := range tests {
+		rounds, wall := EffectDuration(tt.duration)
+		if rounds != tt.rounds || wall != tt.wall {
+			t.Errorf("EffectDuration(%q) = %d, %v; want %d, %v", tt.duration, rounds, wall, tt.rounds, tt.wall)
+		}
+	}
+}
+
+func TestSpellEffectFor(t *testing.T) {
+	bless, ok := SpellEffectFor("bless", "")
+	if !ok || bless.Modifier.AttackDice != "1d4" || bless.MaxTargets(1, 3) != 5 {
+		t.Errorf("bless = %+v, targets at 3rd level %d", bless, bless.MaxTargets(1, 3))
+	}
+	hex, ok := SpellEffectFor("hex", "wisdom")
+	if !ok || hex.Effect != "Hexed (WIS)" || len(hex.Modifier.CheckDisadvantage) != 1 {
+		t.Errorf("hex = %+v", hex)
+	}
+	if hex, _ := SpellEffectFor("hex", ""); hex.Modifier.CheckDisadvantage[0] != "str" {
+		t.Errorf("hex defaults to Strength, got %+v", hex.Modifier)
+	}
+	if _, ok := SpellEffectFor("fireball", ""); ok {
+		t.Error("fireball has no ongoing effect")
+	}
+}
+
+func TestAbilityFromText(t *testing.T) {
+	tests := map[string]string{
+		"hex on Grukk (Wisdom)":          "wis",
+		"hex the orc, dex":               "dex",
+		"hex the orc with concentration": "",
+		"":                               "",
+	}
+	for text, want := range tests {
+		if got := AbilityFromText(text); got != want {
+			t.Errorf("AbilityFromText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestRollEffectBonus(t *testing.T) {
+	blessed := ActiveEffect{Effect: "Blessed", SourceSpell: "bless", Modifier: EffectModifier{AttackDice: "1d4", SaveDice: "1d4"}}
+	baned := ActiveEffect{Effect: "Baned", SourceSpell: "bane", Modifier: EffectModifier{AttackDice: "-1d4", SaveDice: "-1d4"}}
+
+	for i := 0; i < 50; i++ {
+		total, entries := RollEffectBonus([]ActiveEffect{blessed, blessed}, "attack")
+		if total < 1 || total > 4 || len(entries) != 1 {
+			t.Fatalf("two Blesses add one d4: got %d from %+v", total, entries)
+		}
+		total, entries = RollEffectBonus([]ActiveEffect{baned}, "save")
+		if total < -4 || total > -1 || entries[0].Source != "Baned (-1d4)" {
+			t.Fatalf("Bane subtracts a d4: got %d from %+v", total, entries)
+		}
+	}
+	if total, entries := RollEffectBonus(nil, "attack"); total != 0 || entries != nil {
+		t.Error("no effects, no bonus")
+	}
+}
+
+func TestEffectModifiers(t *testing.T) {
+	haste, _ := SpellEffectFor("haste", "")
+	shield, _ := SpellEffectFor("shield-of-faith", "")
+	hex, _ := SpellEffectFor("hex", "dex")
+	effects := []ActiveEffect{
+		{Effect: haste.Effect, SourceSpell: "haste", Modifier: haste.Modifier},
+		{Effect: shield.Effect, SourceSpell: "shield-of-faith", Modifier: shield.Modifier},
+		{Effect: hex.Effect, SourceSpell: "hex", Modifier: hex.Modifier},
+	}
+	if ac, sources := EffectACBonus(effects); ac != 4 || len(sources) != 2 {
+		t.Errorf("Haste + Shield of Faith = +4 AC, got %d %v", ac, sources)
+	}
+	if src, ok := EffectSaveAdvantage(effects, "dexterity"); !ok || src != "Hasted" {
+		t.Errorf("Haste grants advantage on DEX saves, got %q %v", src, ok)
+	}
+	if _, ok := EffectSaveAdvantage(effects, "wis"); ok {
+		t.Error("Haste doesn't help WIS saves")
+	}
+	if src, ok := EffectCheckDisadvantage(effects, "dex"); !ok || src != "Hexed (DEX)" {
+		t.Errorf("Hex on DEX, got %q %v", src, ok)
+	}
+}
+
+func TestEffectExpired(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+	e := ActiveEffect{ExpiresRound: 10, ExpiresAt: &later}
+	if EffectExpired(e, 10, now, 0) {
+		t.Error("lasts through its last round")
+	}
+	if !EffectExpired(e, 11, now, 0) {
+		t.Error("gone the round after")
+	}
+	if !EffectExpired(e, 0, later, 0) {
+		t.Error("gone at its wall-clock end")
+	}
+	game := ActiveEffect{ExpiresGame: 600}
+	if EffectExpired(game, 0, now, 599) {
+		t.Error("lasts until its clock minute")
+	}
+	if !EffectExpired(game, 0, now, 600) {
+		t.Error("gone at its clock minute")
+	}
+	if EffectExpired(ActiveEffect{}, 50, now, 5000) {
+		t.Error("no expiry set means it lasts")
+	}
+}
+
+func TestConcentrationDC(t *testing.T) {
+	for damage, want := range map[int]int{1: 10, 21: 10, 22: 11, 45: 22} {
+		if got := ConcentrationDC(damage); got != want {
+			t.Errorf("ConcentrationDC(%d) = %d, want %d", damage, got, want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// encounter.go - encounter difficulty (DMG p82) for combat telemetry, encounter tuning and
+// the encounter builder
+package game
+
+import (
+	"sort"
+	"strings"
+)
+
+// Encounter difficulty ratings, weakest to strongest.
+const (
+	DifficultyTrivial = "trivial"
+	DifficultyEasy    = "easy"
+	DifficultyMedium  = "medium"
+	DifficultyHard    = "hard"
+	DifficultyDeadly  = "deadly"
+)
+
+// EncounterDifficulties lists the difficulty ratings in ascending order.
+var EncounterDifficulties = []string{DifficultyTrivial, DifficultyEasy, DifficultyMedium, DifficultyHard, DifficultyDeadly}
+
+// EncounterXPThresholds maps character level to its easy, medium, hard, and deadly XP thresholds (DMG p82).
+var EncounterXPThresholds = map[int][4]int{
+	1: {25, 50, 75, 100}, 2: {50, 100, 150, 200}, 3: {75, 150, 225, 400}, 4: {125, 250, 375, 500},
+	5: {250, 500, 750, 1100}, 6: {300, 600, 900, 1400}, 7: {350, 750, 1100, 1700}, 8: {450, 900, 1400, 2100},
+	9: {550, 1100, 1600, 2400}, 10: {600, 1200, 1900, 2800}, 11: {800, 1600, 2400, 3600}, 12: {1000, 2000, 3000, 4500},
+	13: {1100, 2200, 3400, 5100}, 14: {1250, 2500, 3800, 5700}, 15: {1400, 2800, 4300, 6400}, 16: {1600, 3200, 4800, 7200},
+	17: {2000, 3900, 5900, 8800}, 18: {2100, 4200, 6300, 9500}, 19: {2400, 4900, 7300, 10900}, 20: {2800, 5700, 8500, 12700},
+}
+
+// crXP maps challenge rating to experience points (MM p9).
+var crXP = map[string]int{
+	"0": 10, "1/8": 25, "1/4": 50, "1/2": 100, "1": 200, "2": 450, "3": 700, "4": 1100, "5": 1800,
+	"6": 2300, "7": 2900, "8": 3900, "9": 5000, "10": 5900, "11": 7200, "12": 8400, "13": 10000,
+	"14": 11500, "15": 13000, "16": 15000, "17": 18000, "18": 20000, "19": 22000, "20": 25000,
+	"21": 33000, "22": 41000, "23": 50000, "24": 62000, "25": 75000, "26": 90000, "27": 105000,
+	"28": 120000, "29": 135000, "30": 155000,
+}
+
+// XPForCR returns the experience points for a monster of the given challenge rating, or 0 if unknown.
+func XPForCR(cr string) int {
+	return crXP[strings.TrimSpace(cr)]
+}
+
+// PartyXPThresholds sums the easy, medium, hard, and deadly thresholds for a party.
+// Levels outside 1-20 are clamped.
+func PartyXPThresholds(levels []int) [4]int {
+	var total [4]int
+	for _, level := range levels {
+		level = max(1, min(level, 20))
+		for i, xp := range EncounterXPThresholds[level] {
+			total[i] += xp
+		}
+	}
+	return total
+}
+
+// encounterMultipliers are the DMG p82 multiplier steps, including the
+// extra steps used for very small and very large parties.
+var encounterMultipliers = []float64{0.5, 1, 1.5, 2, 2.5, 3, 4, 5}
+
+// EncounterMultiplier returns the XP multiplier for fighting monsters at once (DMG p82).
+// Parties of fewer than three step the multiplier up; parties of six or more step it down.
+func EncounterMultiplier(monsters, partySize int) float64 {
+	if monsters <= 0 {
+		return 1
+	}
+	step := 1
+	switch {
+	case monsters >= 15:
+		step = 6
+	case monsters >= 11:
+		step = 5
+	case monsters >= 7:
+		step = 4
+	case monsters >= 3:
+		step = 3
+	case monsters == 2:
+		step = 2
+	}
+	if partySize > 0 && partySize < 3 {
+		step++
+	} else if partySize >= 6 {
+		step--
+	}
+	return encounterMultipliers[step]
+}
+
+// AdjustedEncounterXP applies the encounter multiplier to the monsters' total XP.
+func AdjustedEncounterXP(monsterXP []int, partySize int) int {
+	total := 0
+	for _, xp := range monsterXP {
+		total += xp
+	}
+	return int(float64(total) * EncounterMultiplier(len(monsterXP), partySize))
+}
+
+// EncounterDifficulty rates adjusted encounter XP against a party's thresholds.
+func EncounterDifficulty(adjustedXP int, thresholds [4]int) string {
+	switch {
+	case adjustedXP >= thresholds[3]:
+		return DifficultyDeadly
+	case adjustedXP >= thresholds[2]:
+		return DifficultyHard
+	case adjustedXP >= thresholds[1]:
+		return DifficultyMedium
+	case adjustedXP >= thresholds[0]:
+		return DifficultyEasy
+	}
+	return DifficultyTrivial
+}
+
+// XPSplit divides a defeated monster's XP evenly among the party (DMG p260), rounding down.
+func XPSplit(xp, partySize int) int {
+	if partySize <= 0 {
+		return xp
+	}
+	return xp / partySize
+}
+
+// EncounterBudget returns the adjusted XP window for a difficulty: from its threshold up
+// to the next one. Deadly runs to one and a half times the deadly threshold. ok is false
+// for an unknown difficulty.
+func EncounterBudget(difficulty string, thresholds [4]int) (low, high int, ok bool) {
+	for i, d := range EncounterDifficulties[1:] {
+		if d != strings.ToLower(difficulty) {
+			continue
+		}
+		if i == 3 {
+			return thresholds[3], thresholds[3] * 3 / 2, true
+		}
+		return thresholds[i], thresholds[i+1] - 1, true
+	}
+	return 0, 0, false
+}
+
+// EncounterMonster is a monster the builder may use.
+type EncounterMonster struct {
+	Key  string `json:"monster_key"`
+	Name string `json:"name"`
+	CR   string `json:"cr"`
+	XP   int    `json:"xp"`
+	Type string `json:"type,omitempty"`
+}
+
+// EncounterGroup is a number of one monster in a suggested encounter.
+type EncounterGroup struct {
+	EncounterMonster
+	Count int `json:"count"`
+}
+
+// EncounterPlan is one suggested monster combination.
+type EncounterPlan struct {
+	Groups     []EncounterGroup `json:"monsters"`
+	Count      int              `json:"monster_count"`
+	TotalXP    int              `json:"total_xp"`
+	AdjustedXP int              `json:"adjusted_xp"`
+	Difficulty string           `json:"difficulty"`
+}
+
+// SuggestEncounters builds monster combinations whose adjusted XP falls in [low, high]:
+// a pack of one monster, or a leader with a pack of a weaker one. At most maxMonsters
+// per plan. Plans come back closest to the middle of the window first, one per leader.
+func SuggestEncounters(candidates []EncounterMonster, partySize, low, high, maxMonsters int, thresholds [4]int) []EncounterPlan {
+	mid := (low + high) / 2
+	best := map[string]EncounterPlan{}
+	consider := func(groups ...EncounterGroup) {
+		xps := []int{}
+		for _, g := range groups {
+			for i := 0; i < g.Count; i++ {
+				xps = append(xps, g.XP)
+			}
+		}
+		adjusted := AdjustedEncounterXP(xps, partySize)
+		if adjusted < low || adjusted > high {
+			return
+		}
+		plan := EncounterPlan{Groups: groups, Count: len(xps), AdjustedXP: adjusted, Difficulty: EncounterDifficulty(adjusted, thresholds)}
+		for _, xp := range xps {
+			plan.TotalXP += xp
+		}
+		leader := groups[0].Key
+		if prev, ok := best[leader]; !ok || absDiff(adjusted, mid) < absDiff(prev.AdjustedXP, mid) {
+			best[leader] = plan
+		}
+	}
+	for _, a := range candidates {
+		if a.XP <= 0 || a.XP > high {
+			continue
+		}
+		for n := 1; n <= maxMonsters; n++ {
+			consider(EncounterGroup{a, n})
+		}
+		for _, b := range candidates {
+			if b.XP <= 0 || b.XP >= a.XP {
+				continue
+			}
+			for n := 2; n < maxMonsters; n++ {
+				consider(EncounterGroup{a, 1}, EncounterGroup{b, n})
+			}
+		}
+	}
+	plans := make([]EncounterPlan, 0, len(best))
+	for _, p := range best {
+		plans = append(plans, p)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		di, dj := absDiff(plans[i].AdjustedXP, mid), absDiff(plans[j].AdjustedXP, mid)
+		if di != dj {
+			return di < dj
+		}
+		return plans[i].Groups[0].Key < plans[j].Groups[0].Key
+	})
+	return plans
+}
+
+func absDiff(a, b int) int {
+	return abs(a - b)
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestXPForCR(t *testing.T) {
+	tests := map[string]int{
+		"0":   10,
+		"1/4": 50,
+		" 2 ": 450,
+		"30":  155000,
+		"99":  0,
+	}
+	for cr, want := range tests {
+		if got := XPForCR(cr); got != want {
+			t.Errorf("XPForCR(%q) = %d, want %d", cr, got, want)
+		}
+	}
+}
+
+func TestPartyXPThresholds(t *testing.T) {
+	got := PartyXPThresholds([]int{3, 3, 3, 2})
+	want := [4]int{275, 550, 825, 1400}
+	if got != want {
+		t.Errorf("PartyXPThresholds() = %v, want %v", got, want)
+	}
+	if got := PartyXPThresholds([]int{0, 25}); got != [4]int{2825, 5750, 8575, 12800} {
+		t.Errorf("PartyXPThresholds(clamped) = %v", got)
+	}
+}
+
+func TestEncounterMultiplier(t *testing.T) {
+	tests := []struct {
+		monsters, party int
+		want            float64
+	}{
+		{0, 4, 1},
+		{1, 4, 1},
+		{2, 4, 1.5},
+		{4, 4, 2},
+		{8, 4, 2.5},
+		{12, 4, 3},
+		{20, 4, 4},
+		{1, 2, 1.5},
+		{20, 1, 5},
+		{1, 6, 0.5},
+		{4, 7, 1.5},
+	}
+	for _, tt := range tests {
+		if got := EncounterMultiplier(tt.monsters, tt.party); got != tt.want {
+			t.Errorf("EncounterMultiplier(%d, %d) = %v, want %v", tt.monsters, tt.party, got, tt.want)
+		}
+	}
+}
+
+func TestEncounterDifficulty(t *testing.T) {
+	// Four level 3 characters vs. goblins (DMG p82 example thresholds)
+	thresholds := PartyXPThresholds([]int{3, 3, 3, 3})
+	tests := []struct {
+		monsters []int
+		want     string
+	}{
+		{[]int{50}, DifficultyTrivial},
+		{[]int{50, 50, 50}, DifficultyEasy},
+		{[]int{50, 50, 50, 50, 50, 50}, DifficultyMedium},
+		{[]int{200, 200}, DifficultyMedium},
+		{[]int{700, 200}, DifficultyHard},
+		{[]int{1100, 450}, DifficultyDeadly},
+	}
+	for _, tt := range tests {
+		adjusted := AdjustedEncounterXP(tt.monsters, 4)
+		if got := EncounterDifficulty(adjusted, thresholds); got != tt.want {
+			t.Errorf("EncounterDifficulty(%v -> %d XP) = %s, want %s", tt.monsters, adjusted, got, tt.want)
+		}
+	}
+}
+
+func TestXPSplit(t *testing.T) {
+	if got := XPSplit(450, 4); got != 112 {
+		t.Errorf("XPSplit(450, 4) = %d, want 112", got)
+	}
+	if got := XPSplit(50, 0); got != 50 {
+		t.Errorf("XPSplit with no party = %d, want the full 50", got)
+	}
+}
+
+func TestEncounterBudget(t *testing.T) {
+	thresholds := PartyXPThresholds([]int{3, 3, 3, 3}) // 300/600/900/1600
+	tests := []struct {
+		difficulty string
+		low, high  int
+		ok         bool
+	}{
+		{"easy", 300, 599, true},
+		{"Medium", 600, 899, true},
+		{"hard", 900, 1599, true},
+		{"deadly", 1600, 2400, true},
+		{"trivial", 0, 0, false},
+	}
+	for _, tt := range tests {
+		low, high, ok := EncounterBudget(tt.difficulty, thresholds)
+		if low != tt.low || high != tt.high || ok != tt.ok {
+			t.Errorf("EncounterBudget(%q) = %d, %d, %v; want %d, %d, %v", tt.difficulty, low, high, ok, tt.low, tt.high, tt.ok)
+		}
+	}
+}
+
+func TestSuggestEncounters(t *testing.T) {
+	candidates := []EncounterMonster{
+		{Key: "goblin", CR: "1/4", XP: 50},
+		{Key: "bugbear", CR: "1", XP: 200},
+		{Key: "ogre", CR: "2", XP: 450},
+		{Key: "young-red-dragon", CR: "10", XP: 5900},
+	}
+	thresholds := PartyXPThresholds([]int{3, 3, 3, 3})
+	low, high, _ := EncounterBudget("medium", thresholds)
+	plans := SuggestEncounters(candidates, 4, low, high, 6, thresholds)
+	if len(plans) == 0 {
+		t.Fatal("no plans for a medium encounter")
+	}
+	seen := map[string]bool{}
+	for _, p := range plans {
+		if p.AdjustedXP < low || p.AdjustedXP > high || p.Difficulty != DifficultyMedium {
+			t.Errorf("plan %+v is outside the medium window", p)
+		}
+		if p.Count > 6 {
+			t.Errorf("plan has %d monsters, max 6", p.Count)
+		}
+		leader := p.Groups[0].Key
+		if seen[leader] || leader == "young-red-dragon" {
+			t.Errorf("unexpected leader %s", leader)
+		}
+		seen[leader] = true
+	}
+	// Ogre alone: 450 XP, under the medium threshold; with 2 goblins: (450+100)*2 = 1100, hard.
+	// One ogre with no pack can't be medium, so the ogre plan must include company.
+	for _, p := range plans {
+		if p.Groups[0].Key == "ogre" && p.Count == 1 {
+			t.Errorf("lone ogre (450 XP) suggested for a 600 XP budget")
+		}
+	}
+}
@@ -40,6 +40,27 @@ func ArmorDonDoffTime(armorType string) (donMinutes, doffMinutes int) {
 	}
 }
 
+// ArmorDisturbsSleep reports whether sleeping in an armor type spoils a long rest under
+// the sleeping-in-armor rule (XGtE p77): medium and heavy armor do.
+func ArmorDisturbsSleep(armorType string) bool {
+	switch strings.ToLower(armorType) {
+	case ArmorTypeMedium, ArmorTypeHeavy:
+		return true
+	}
+	return false
+}
+
+// LongRestHitDice returns how many spent Hit Dice a long rest restores: half the
+// character's level (minimum 1), or after sleeping in medium or heavy armor only a
+// quarter of the spent dice (minimum 1, XGtE p77). Never more than were spent.
+func LongRestHitDice(level, spent int, sleptInArmor bool) int {
+	recovered := max(level/2, 1)
+	if sleptInArmor {
+		recovered = max(spent/4, 1)
+	}
+	return min(recovered, spent)
+}
+
 // CalculateArmorAC calculates AC based on equipped armor, shield, and DEX modifier
 // Uses default natural AC base of 10 for unarmored characters.
 // Rules:
@@ -213,6 +234,18 @@ func MeetsArmorStrengthRequirement(strength int, armor *ArmorInfo) bool {
 	return strength >= armor.StrengthRequirement
 }
 
+// ArmorSpeedPenalty returns the speed change from wearing armor without the STR it needs:
+// -10 feet (PHB p144). Dwarves' speed is not reduced by heavy armor (PHB p20).
+func ArmorSpeedPenalty(strength int, armor *ArmorInfo, race string) int {
+	if MeetsArmorStrengthRequirement(strength, armor) {
+		return 0
+	}
+	if IsDwarf(race) && strings.EqualFold(armor.Type, ArmorTypeHeavy) {
+		return 0
+	}
+	return -10
+}
+
 // HasStealthDisadvantage returns whether armor imposes disadvantage on Stealth checks.
 func HasStealthDisadvantage(armor *ArmorInfo) bool {
 	if armor == nil {
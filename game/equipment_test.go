@@ -34,6 +34,36 @@ func TestArmorDonDoffTime(t *testing.T) {
 	}
 }
 
+func TestArmorDisturbsSleep(t *testing.T) {
+	for armorType, want := range map[string]bool{"light": false, "Medium": true, "heavy": true, "shield": false, "": false} {
+		if got := ArmorDisturbsSleep(armorType); got != want {
+			t.Errorf("ArmorDisturbsSleep(%q) = %v, want %v", armorType, got, want)
+		}
+	}
+}
+
+func TestLongRestHitDice(t *testing.T) {
+	tests := []struct {
+		level, spent int
+		inArmor      bool
+		want         int
+	}{
+		{1, 1, false, 1},
+		{8, 8, false, 4},
+		{8, 2, false, 2},
+		{8, 0, false, 0},
+		{8, 8, true, 2},
+		{8, 3, true, 1},
+		{1, 1, true, 1},
+		{8, 0, true, 0},
+	}
+	for _, tt := range tests {
+		if got := LongRestHitDice(tt.level, tt.spent, tt.inArmor); got != tt.want {
+			t.Errorf("LongRestHitDice(%d, %d, %v) = %d, want %d", tt.level, tt.spent, tt.inArmor, got, tt.want)
+		}
+	}
+}
+
 func TestCalculateArmorAC(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -457,6 +487,27 @@ func TestMeetsArmorStrengthRequirement(t *testing.T) {
 	}
 }
 
+func TestArmorSpeedPenalty(t *testing.T) {
+	chainMail := &ArmorInfo{AC: 16, Type: ArmorTypeHeavy, StrengthRequirement: 13}
+	tests := []struct {
+		name     string
+		strength int
+		armor    *ArmorInfo
+		race     string
+		want     int
+	}{
+		{"no armor", 8, nil, "human", 0},
+		{"meets requirement", 13, chainMail, "human", 0},
+		{"too weak", 12, chainMail, "human", -10},
+		{"dwarf in heavy armor", 8, chainMail, "hill_dwarf", 0},
+	}
+	for _, tt := range tests {
+		if got := ArmorSpeedPenalty(tt.strength, tt.armor, tt.race); got != tt.want {
+			t.Errorf("%s: ArmorSpeedPenalty = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestHasStealthDisadvantage(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -0,0 +1,115 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// factions.go - factions and renown (DMG p22-23): standing with an organization that
+// rises and falls with the party's deeds, and ranks that unlock perks along the way
+package game
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FactionRank is a renown threshold and what reaching it brings.
+type FactionRank struct {
+	Renown int    `json:"renown"`
+	Title  string `json:"title"`
+	Perk   string `json:"perk,omitempty"`
+}
+
+// DefaultFactionRanks are the renown ranks most factions use (DMG p23).
+var DefaultFactionRanks = []FactionRank{
+	{Renown: 1, Title: "Known", Perk: "Members recognize you and will share rumors"},
+	{Renown: 3, Title: "Member", Perk: "Access to the faction's safe houses and contacts"},
+	{Renown: 10, Title: "Agent", Perk: "Missions from the faction, with pay, and help in emergencies"},
+	{Renown: 25, Title: "Officer", Perk: "Command of a few junior members and the faction's resources"},
+	{Renown: 50, Title: "Leader", Perk: "A voice in the faction's decisions"},
+}
+
+// Faction attitudes by renown. Negative renown means the faction holds a grudge.
+const (
+	FactionHostile     = "hostile"
+	FactionUnfriendly  = "unfriendly"
+	FactionIndifferent = "indifferent"
+	FactionFriendly    = "friendly"
+	FactionAllied      = "allied"
+)
+
+// Faction is one organization in a campaign, stored in the campaign document's factions
+// list. PartyRenown counts for every character; Renown holds each character's own, keyed
+// by character ID.
+type Faction struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Ranks       []FactionRank `json:"ranks"`
+	PartyRenown int           `json:"party_renown"`
+	Renown      map[int]int   `json:"renown,omitempty"`
+	GMOnly      bool          `json:"gm_only,omitempty"`
+	GMNotes     string        `json:"gm_notes,omitempty"`
+}
+
+var factionSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FactionID is the ID for a faction name: "The Zhentarim" becomes "the-zhentarim".
+func FactionID(name string) string {
+	return strings.Trim(factionSlugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// SortFactionRanks orders ranks by renown, lowest first.
+func SortFactionRanks(ranks []FactionRank) {
+	sort.SliceStable(ranks, func(i, j int) bool { return ranks[i].Renown < ranks[j].Renown })
+}
+
+// CharacterRenown is a character's renown with the faction: the party's plus their own.
+func (f Faction) CharacterRenown(charID int) int {
+	return f.PartyRenown + f.Renown[charID]
+}
+
+// RanksEarned are the ranks reached at a renown, lowest first.
+func (f Faction) RanksEarned(renown int) []FactionRank {
+	earned := []FactionRank{}
+	for _, r := range f.Ranks {
+		if renown >= r.Renown {
+			earned = append(earned, r)
+		}
+	}
+	return earned
+}
+
+// NextRank is the next rank above a renown, or false at the top.
+func (f Faction) NextRank(renown int) (FactionRank, bool) {
+	for _, r := range f.Ranks {
+		if renown < r.Renown {
+			return r, true
+		}
+	}
+	return FactionRank{}, false
+}
+
+// RanksCrossed are the ranks gained going from one renown to another (nil if it fell).
+func (f Faction) RanksCrossed(before, after int) []FactionRank {
+	var crossed []FactionRank
+	for _, r := range f.Ranks {
+		if before < r.Renown && after >= r.Renown {
+			crossed = append(crossed, r)
+		}
+	}
+	return crossed
+}
+
+// FactionAttitude is how a faction regards someone with the given renown: negative
+// renown sours it, and from 10 (an Agent in the usual ranks) it counts them an ally.
+func FactionAttitude(renown int) string {
+	switch {
+	case renown <= -10:
+		return FactionHostile
+	case renown < 0:
+		return FactionUnfriendly
+	case renown == 0:
+		return FactionIndifferent
+	case renown < 10:
+		return FactionFriendly
+	}
+	return FactionAllied
+}
@@ -0,0 +1,50 @@
+package game
+
+import "testing"
+
+func TestFactionID(t *testing.T) {
+	if got := FactionID("The Lords' Alliance"); got != "the-lords-alliance" {
+		t.Errorf("FactionID = %q", got)
+	}
+}
+
+func TestFactionRanks(t *testing.T) {
+	f := Faction{Name: "Harpers", Ranks: DefaultFactionRanks, PartyRenown: 2, Renown: map[int]int{7: 2}}
+	if got := f.CharacterRenown(7); got != 4 {
+		t.Errorf("CharacterRenown(7) = %d, want 4", got)
+	}
+	if got := f.CharacterRenown(8); got != 2 {
+		t.Errorf("CharacterRenown(8) = %d, want 2", got)
+	}
+	if earned := f.RanksEarned(4); len(earned) != 2 || earned[1].Title != "Member" {
+		t.Errorf("RanksEarned(4) = %+v", earned)
+	}
+	if next, ok := f.NextRank(4); !ok || next.Renown != 10 {
+		t.Errorf("NextRank(4) = %+v %v", next, ok)
+	}
+	if _, ok := f.NextRank(50); ok {
+		t.Error("no rank above Leader")
+	}
+	if crossed := f.RanksCrossed(2, 12); len(crossed) != 2 || crossed[0].Title != "Member" || crossed[1].Title != "Agent" {
+		t.Errorf("RanksCrossed(2, 12) = %+v", crossed)
+	}
+	if crossed := f.RanksCrossed(12, 2); crossed != nil {
+		t.Errorf("falling renown crosses nothing, got %+v", crossed)
+	}
+}
+
+func TestSortFactionRanks(t *testing.T) {
+	ranks := []FactionRank{{Renown: 10, Title: "b"}, {Renown: 1, Title: "a"}}
+	SortFactionRanks(ranks)
+	if ranks[0].Title != "a" {
+		t.Errorf("not sorted: %+v", ranks)
+	}
+}
+
+func TestFactionAttitude(t *testing.T) {
+	for renown, want := range map[int]string{-12: FactionHostile, -1: FactionUnfriendly, 0: FactionIndifferent, 5: FactionFriendly, 10: FactionAllied} {
+		if got := FactionAttitude(renown); got != want {
+			t.Errorf("FactionAttitude(%d) = %q, want %q", renown, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// grapple.go - monster attacks that grapple on a hit ("the target is grappled
+// (escape DC 13)") and escaping a grapple with a fixed escape DC
+package game
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	escapeDCPattern       = regexp.MustCompile(`(?i)escape dc (\d+)`)
+	grappleMaxSizePattern = regexp.MustCompile(`(?i)\b(tiny|small|medium|large|huge|gargantuan) or smaller\b`)
+)
+
+// ParseGrappleRider reads a monster action description for a grapple on hit. It returns
+// the escape DC and, if the action only grapples targets up to a size ("if the target is
+// Medium or smaller"), that size; maxSize is "" when any size can be grappled.
+func ParseGrappleRider(desc string) (escapeDC int, maxSize string, ok bool) {
+	if !strings.Contains(strings.ToLower(desc), "grappl") {
+		return 0, "", false
+	}
+	m := escapeDCPattern.FindStringSubmatch(desc)
+	if m == nil {
+		return 0, "", false
+	}
+	escapeDC, _ = strconv.Atoi(m[1])
+	// Only a size limit in the grappling sentence counts ("If the target is Small or
+	// smaller, the frog can swallow it" limits the swallow, not the grapple)
+	for _, sentence := range strings.Split(desc, ". ") {
+		if !strings.Contains(strings.ToLower(sentence), "grappl") {
+			continue
+		}
+		if s := grappleMaxSizePattern.FindStringSubmatch(sentence); s != nil {
+			maxSize = NormalizeSize(s[1])
+			break
+		}
+	}
+	return escapeDC, maxSize, escapeDC > 0
+}
+
+// GrappleRiderApplies reports whether a grapple rider limited to maxSize ("" for any)
+// takes hold of a target of targetSize.
+func GrappleRiderApplies(targetSize, maxSize string) bool {
+	return maxSize == "" || SizeOrder(targetSize) <= SizeOrder(maxSize)
+}
+
+// EscapesGrapple reports whether an escape check beats a fixed escape DC. Unlike the
+// contest against a grappler's Athletics, meeting the DC is enough.
+func EscapesGrapple(total, escapeDC int) bool {
+	return total >= escapeDC
+}
@@ -0,0 +1,46 @@
+package game
+
+import "testing"
+
+func TestParseGrappleRider(t *testing.T) {
+	tests := []struct {
+		desc    string
+		dc      int
+		maxSize string
+		ok      bool
+	}{
+		{"Melee Weapon Attack: +7 to hit, reach 50 ft., one creature. Hit: The target is grappled (escape DC 15).", 15, "", true},
+		{"Hit: 7 (1d8 + 3) piercing damage. If the target is a creature, it is grappled (escape DC 16).", 16, "", true},
+		{"Hit: 4 (1d6 + 1) piercing damage, and the target is grappled (escape DC 11). Until this grapple ends, the target is restrained. If the target is Small or smaller, the frog can swallow it.", 11, "", true},
+		{"Hit: 10 (2d6 + 3) bludgeoning damage. If the target is Medium or smaller, it is grappled (escape DC 13).", 13, SizeMedium, true},
+		{"Hit: 9 (1d10 + 4) slashing damage.", 0, "", false},
+		{"The target must succeed on a DC 13 Strength saving throw or be knocked prone.", 0, "", false},
+	}
+	for _, tt := range tests {
+		dc, maxSize, ok := ParseGrappleRider(tt.desc)
+		if dc != tt.dc || maxSize != tt.maxSize || ok != tt.ok {
+			t.Errorf("ParseGrappleRider(%q) = %d, %q, %v; want %d, %q, %v", tt.desc, dc, maxSize, ok, tt.dc, tt.maxSize, tt.ok)
+		}
+	}
+}
+
+func TestGrappleRiderApplies(t *testing.T) {
+	if !GrappleRiderApplies(SizeHuge, "") {
+		t.Error("an unlimited rider grapples any size")
+	}
+	if !GrappleRiderApplies(SizeMedium, SizeMedium) {
+		t.Error("Medium or smaller grapples a Medium target")
+	}
+	if GrappleRiderApplies(SizeLarge, SizeMedium) {
+		t.Error("Medium or smaller doesn't grapple a Large target")
+	}
+}
+
+func TestEscapesGrapple(t *testing.T) {
+	if !EscapesGrapple(13, 13) {
+		t.Error("meeting the escape DC escapes")
+	}
+	if EscapesGrapple(12, 13) {
+		t.Error("falling short of the escape DC doesn't escape")
+	}
+}
@@ -0,0 +1,94 @@
+// Package game provides D&D 5e game mechanics as pure functions.
+// hands.go contains hand economy checks for weapons and shields (PHB p146-147, p195).
+package game
+
+import "fmt"
+
+// ShieldBashWeapon is the weapon key for striking with a shield. A shield used as a weapon
+// is an improvised weapon: 1d4 bludgeoning, no proficiency bonus (PHB p147).
+const ShieldBashWeapon = "shield"
+
+// ImprovisedWeaponDamage is the damage die of an improvised weapon (PHB p147).
+const ImprovisedWeaponDamage = "1d4"
+
+// HandLoadout is what a character holds: a weapon in each hand and a shield on one arm.
+// Weapons are SRD weapon keys; "" is an empty hand.
+type HandLoadout struct {
+	MainHand string
+	OffHand  string
+	Shield   bool
+}
+
+// Tracked reports whether the loadout records any weapon. Characters who never equipped a
+// weapon aren't held to what's in their hands.
+func (l HandLoadout) Tracked() bool {
+	return l.MainHand != "" || l.OffHand != ""
+}
+
+// EquipProblem explains why a weapon can't be put in a hand, or returns empty strings.
+// A shield takes the off hand, so it rules out an off-hand weapon and a two-handed one.
+func (l HandLoadout) EquipProblem(weaponName, slot string, twoHanded bool) (code, message string) {
+	if !l.Shield {
+		return "", ""
+	}
+	if twoHanded {
+		return "two_handed_with_shield", fmt.Sprintf("%s needs both hands and you have a shield strapped on. Doff the shield first (POST /api/characters/unequip-armor {shield:true}).", weaponName)
+	}
+	if slot == "off_hand" {
+		return "shield_in_off_hand", fmt.Sprintf("Your shield is in your off hand, so %s can't go there. Doff the shield first to dual wield.", weaponName)
+	}
+	return "", ""
+}
+
+// ShieldProblem explains why a shield can't be donned with this loadout, or returns empty
+// strings. mainTwoHanded reports that the main-hand weapon is two-handed.
+func (l HandLoadout) ShieldProblem(mainTwoHanded bool) (code, message string) {
+	if l.MainHand != "" && mainTwoHanded {
+		return "two_handed_with_shield", fmt.Sprintf("You're wielding %s in both hands. Unequip it before donning a shield.", l.MainHand)
+	}
+	if l.OffHand != "" {
+		return "hands_full", fmt.Sprintf("Your off hand holds %s. Unequip it before donning a shield.", l.OffHand)
+	}
+	return "", ""
+}
+
+// AttackProblem explains why an attack can't be made from this loadout, or returns empty
+// strings. weapon is the SRD key of the weapon used ("" when none is named), twoHanded its
+// property, and offhand marks a two-weapon fighting bonus attack. A weapon that isn't in
+// hand has to be drawn first, which takes an object interaction.
+func (l HandLoadout) AttackProblem(weapon string, twoHanded, offhand bool) (code, message string) {
+	if weapon == ShieldBashWeapon {
+		if !l.Shield {
+			return "no_shield", "You need a shield equipped to bash with it."
+		}
+		if offhand {
+			return "shield_in_off_hand", "A shield bash is an improvised weapon attack, not a two-weapon fighting attack."
+		}
+		return "", ""
+	}
+	if offhand {
+		if l.Shield {
+			return "shield_in_off_hand", "You can't dual wield while holding a shield: it takes your off hand."
+		}
+		if l.Tracked() && l.OffHand == "" {
+			return "no_off_hand_weapon", "Your off hand is empty. Equip a light weapon there first (POST /api/characters/equip-weapon {slot:\"off_hand\"})."
+		}
+		if l.Tracked() && weapon != "" && weapon != l.OffHand {
+			return "weapon_not_in_hand", fmt.Sprintf("Your off hand holds %s, not %s.", l.OffHand, weapon)
+		}
+		return "", ""
+	}
+	if weapon == "" {
+		return "", ""
+	}
+	if twoHanded && l.Shield {
+		return "two_handed_with_shield", fmt.Sprintf("%s needs both hands and you have a shield strapped on.", weapon)
+	}
+	if twoHanded && l.OffHand != "" && l.OffHand != weapon {
+		return "hands_full", fmt.Sprintf("%s needs both hands and your off hand holds %s.", weapon, l.OffHand)
+	}
+	if l.Tracked() && weapon != l.MainHand && weapon != l.OffHand {
+		return "weapon_not_in_hand", fmt.Sprintf("You aren't holding %s. Drawing or swapping a weapon is an object interaction: POST /api/characters/equip-weapon first.", weapon)
+	}
+	return "", ""
+}
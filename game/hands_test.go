@@ -0,0 +1,70 @@
+package game
+
+import "testing"
+
+func TestHandLoadoutAttackProblem(t *testing.T) {
+	tests := []struct {
+		name      string
+		loadout   HandLoadout
+		weapon    string
+		twoHanded bool
+		offhand   bool
+		want      string
+	}{
+		{"sword and board", HandLoadout{MainHand: "longsword", Shield: true}, "longsword", false, false, ""},
+		{"greatsword with shield", HandLoadout{MainHand: "greatsword", Shield: true}, "greatsword", true, false, "two_handed_with_shield"},
+		{"greatsword with off-hand dagger", HandLoadout{MainHand: "greatsword", OffHand: "dagger"}, "greatsword", true, false, "hands_full"},
+		{"weapon not drawn", HandLoadout{MainHand: "longsword"}, "longbow", false, false, "weapon_not_in_hand"},
+		{"untracked loadout", HandLoadout{}, "longbow", false, false, ""},
+		{"no weapon named", HandLoadout{MainHand: "longsword", Shield: true}, "", false, false, ""},
+		{"off-hand weapon as main attack", HandLoadout{MainHand: "shortsword", OffHand: "dagger"}, "dagger", false, false, ""},
+		{"dual wield", HandLoadout{MainHand: "shortsword", OffHand: "dagger"}, "dagger", false, true, ""},
+		{"dual wield with shield", HandLoadout{MainHand: "shortsword", Shield: true}, "dagger", false, true, "shield_in_off_hand"},
+		{"empty off hand", HandLoadout{MainHand: "shortsword"}, "dagger", false, true, "no_off_hand_weapon"},
+		{"wrong off-hand weapon", HandLoadout{MainHand: "shortsword", OffHand: "dagger"}, "handaxe", false, true, "weapon_not_in_hand"},
+		{"shield bash", HandLoadout{MainHand: "longsword", Shield: true}, ShieldBashWeapon, false, false, ""},
+		{"shield bash without shield", HandLoadout{MainHand: "longsword"}, ShieldBashWeapon, false, false, "no_shield"},
+		{"shield bash as off-hand attack", HandLoadout{MainHand: "longsword", Shield: true}, ShieldBashWeapon, false, true, "shield_in_off_hand"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := tt.loadout.AttackProblem(tt.weapon, tt.twoHanded, tt.offhand); code != tt.want {
+				t.Errorf("AttackProblem() = %q, want %q", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandLoadoutEquipProblem(t *testing.T) {
+	shield := HandLoadout{MainHand: "longsword", Shield: true}
+	if code, _ := shield.EquipProblem("Greatsword", "main_hand", true); code != "two_handed_with_shield" {
+		t.Errorf("two-handed with shield = %q", code)
+	}
+	if code, _ := shield.EquipProblem("Dagger", "off_hand", false); code != "shield_in_off_hand" {
+		t.Errorf("off hand with shield = %q", code)
+	}
+	if code, _ := shield.EquipProblem("Warhammer", "main_hand", false); code != "" {
+		t.Errorf("one-handed with shield = %q", code)
+	}
+	if code, _ := (HandLoadout{}).EquipProblem("Greatsword", "main_hand", true); code != "" {
+		t.Errorf("two-handed without shield = %q", code)
+	}
+}
+
+func TestHandLoadoutShieldProblem(t *testing.T) {
+	tests := []struct {
+		loadout       HandLoadout
+		mainTwoHanded bool
+		want          string
+	}{
+		{HandLoadout{MainHand: "longsword"}, false, ""},
+		{HandLoadout{}, false, ""},
+		{HandLoadout{MainHand: "greatsword"}, true, "two_handed_with_shield"},
+		{HandLoadout{MainHand: "shortsword", OffHand: "dagger"}, false, "hands_full"},
+	}
+	for _, tt := range tests {
+		if code, _ := tt.loadout.ShieldProblem(tt.mainTwoHanded); code != tt.want {
+			t.Errorf("ShieldProblem(%+v) = %q, want %q", tt.loadout, code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// initiative.go - initiative variants (DMG p270): side initiative and popcorn initiative
+package game
+
+// Initiative modes chosen at combat start.
+const (
+	InitiativeModeStandard = "standard" // PHB p189: everyone rolls, act in descending order
+	InitiativeModeSide     = "side"     // DMG p270: each side rolls once, sides alternate
+	InitiativeModePopcorn  = "popcorn"  // Elective: the acting combatant picks who goes next
+)
+
+// InitiativeModes describes each supported initiative mode.
+var InitiativeModes = map[string]string{
+	InitiativeModeStandard: "Each combatant rolls initiative and acts in descending order (PHB p189)",
+	InitiativeModeSide:     "The party and the monsters each roll one d20; the whole side acts in any order, then the other side (DMG p270)",
+	InitiativeModePopcorn:  "Highest initiative acts first, then the acting combatant chooses who goes next from those who haven't acted this round",
+}
+
+// IsValidInitiativeMode reports whether mode is a known initiative mode.
+func IsValidInitiativeMode(mode string) bool {
+	_, ok := InitiativeModes[mode]
+	return ok
+}
+
+// RollSideInitiative rolls a plain d20 for each side, rerolling ties so that the
+// sides always stay in separate blocks of the turn order.
+func RollSideInitiative() (party, monsters int) {
+	for {
+		party = RollDie(20)
+		monsters = RollDie(20)
+		if party != monsters {
+			return party, monsters
+		}
+	}
+}
+
+// NextSideTurn returns the index of the first combatant on the next side after current,
+// and whether the turn order wrapped into a new round. sides holds each combatant's side
+// in turn order (true = monster); combatants on the same side are contiguous.
+func NextSideTurn(sides []bool, current int) (int, bool) {
+	if current < 0 || current >= len(sides) {
+		return 0, true
+	}
+	for i := current + 1; i < len(sides); i++ {
+		if sides[i] != sides[current] {
+			return i, false
+		}
+	}
+	return 0, true
+}
+
+// SideBlock returns the [start, end) range of the side block containing index.
+func SideBlock(sides []bool, index int) (int, int) {
+	if index < 0 || index >= len(sides) {
+		return 0, 0
+	}
+	start, end := index, index+1
+	for start > 0 && sides[start-1] == sides[index] {
+		start--
+	}
+	for end < len(sides) && sides[end] == sides[index] {
+		end++
+	}
+	return start, end
+}
+
+// PopcornCandidates returns who may act next in popcorn initiative. Combatants who have
+// not acted this round are eligible; once everyone has acted the round is over and the
+// last combatant may pick anyone, including themselves, to start the next round.
+func PopcornCandidates(ids []int, acted []int) ([]int, bool) {
+	done := make(map[int]bool, len(acted))
+	for _, id := range acted {
+		done[id] = true
+	}
+	candidates := []int{}
+	for _, id := range ids {
+		if !done[id] {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return append([]int{}, ids...), true
+	}
+	return candidates, false
+}
+
+// NextTurn picks who acts after the combatant at index current under the given initiative
+// mode. ids is the turn order (monsters have negative IDs); acted lists who has already
+// acted this round in popcorn mode; chosenID is the popcorn pick (0 lets the server pick
+// the next eligible combatant in order). A current index below zero means the previous
+// combatant left the order.
+//
+// Returns the next index, whether a new round begins, the updated popcorn acted list,
+// and false if chosenID is not eligible to act next.
+func NextTurn(mode string, ids []int, current int, acted []int, chosenID int) (int, bool, []int, bool) {
+	if len(ids) == 0 {
+		return 0, false, acted, true
+	}
+
+	switch mode {
+	case InitiativeModeSide:
+		if current < 0 {
+			return 0, false, acted, true
+		}
+		sides := make([]bool, len(ids))
+		for i, id := range ids {
+			sides[i] = id < 0
+		}
+		next, wrapped := NextSideTurn(sides, current)
+		return next, wrapped, acted, true
+
+	case InitiativeModePopcorn:
+		newActed := append([]int{}, acted...)
+		if current >= 0 && current < len(ids) {
+			newActed = append(newActed, ids[current])
+		}
+		candidates, roundOver := PopcornCandidates(ids, newActed)
+		if roundOver {
+			newActed = []int{}
+		}
+
+		if chosenID != 0 {
+			for _, id := range candidates {
+				if id == chosenID {
+					for i := range ids {
+						if ids[i] == chosenID {
+							return i, roundOver, newActed, true
+						}
+					}
+				}
+			}
+			return current, false, acted, false
+		}
+
+		if roundOver {
+			return 0, true, newActed, true
+		}
+		eligible := make(map[int]bool, len(candidates))
+		for _, id := range candidates {
+			eligible[id] = true
+		}
+		for step := 1; step <= len(ids); step++ {
+			i := (current + step) % len(ids)
+			if i < 0 {
+				i += len(ids)
+			}
+			if eligible[ids[i]] {
+				return i, false, newActed, true
+			}
+		}
+		return 0, true, []int{}, true
+	}
+
+	next := current + 1
+	if next >= len(ids) {
+		return 0, true, acted, true
+	}
+	return next, false, acted, true
+}
+
+// InitiativeSlot is a combatant's place in a standard turn order.
+type InitiativeSlot struct {
+	ID         int
+	Initiative int
+	DexScore   int
+	Delayed    bool // Moved to this count with DelayTurn
+}
+
+// InitiativeBefore reports whether a acts before b. Higher initiative goes first. Ties go
+// to a combatant who didn't delay onto that count, then to the higher DEX score (PHB p189),
+// then to player characters over monsters (the DM's call; positive IDs are characters),
+// then to the smaller ID number, so the order never depends on how the combatants were
+// listed.
+func InitiativeBefore(a, b InitiativeSlot) bool {
+	if a.Initiative != b.Initiative {
+		return a.Initiative > b.Initiative
+	}
+	if a.Delayed != b.Delayed {
+		return !a.Delayed
+	}
+	if a.DexScore != b.DexScore {
+		return a.DexScore > b.DexScore
+	}
+	if (a.ID > 0) != (b.ID > 0) {
+		return a.ID > 0
+	}
+	if a.ID < 0 {
+		return a.ID > b.ID
+	}
+	return a.ID < b.ID
+}
+
+// DelayTurn moves the combatant at index current to initiative count newInit for the rest
+// of the combat, behind everyone already on that count. It returns the new order and the
+// combatant's new index. ok is false unless newInit is below its initiative and at least one
+// combatant who hasn't acted this round now goes first.
+func DelayTurn(order []InitiativeSlot, current, newInit int) ([]InitiativeSlot, int, bool) {
+	if current < 0 || current >= len(order) || newInit >= order[current].Initiative {
+		return order, current, false
+	}
+	moved := order[current]
+	moved.Initiative = newInit
+	moved.Delayed = true
+	rest := append(append([]InitiativeSlot{}, order[:current]...), order[current+1:]...)
+	pos := current
+	for pos < len(rest) && InitiativeBefore(rest[pos], moved) {
+		pos++
+	}
+	if pos == current {
+		return order, current, false
+	}
+	return append(rest[:pos], append([]InitiativeSlot{moved}, rest[pos:]...)...), pos, true
+}
+
+// InitiativeInsertIndex returns where a combatant joining mid-combat goes in a turn order
+// sorted by initiative, ties broken by DEX score (highest first). It goes after everyone it
+// ties with, so combatants already in the fight keep their places.
+func InitiativeInsertIndex(initiatives, dexScores []int, initiative, dex int) int {
+	for i := range initiatives {
+		if initiative > initiatives[i] || (initiative == initiatives[i] && dex > dexScores[i]) {
+			return i
+		}
+	}
+	return len(initiatives)
+}
+
+// ShiftTurnIndex returns the current turn index after a combatant is inserted at pos, so the
+// combatant whose turn it is keeps it.
+func ShiftTurnIndex(current, pos int) int {
+	if pos <= current {
+		return current + 1
+	}
+	return current
+}
+
+// HazardActs reports whether a hazard acting on an initiative count (losing ties, like lair
+// actions) comes up as the turn passes from a combatant at fromInit to one at toInit.
+// newRound means the pass wraps from the end of one round to the start of the next.
+func HazardActs(count, fromInit, toInit int, newRound bool) bool {
+	if newRound {
+		return count <= fromInit || count > toInit
+	}
+	return count <= fromInit && count > toInit
+}
@@ -0,0 +1,210 @@
+package game
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIsValidInitiativeMode(t *testing.T) {
+	for _, mode := range []string{"standard", "side", "popcorn"} {
+		if !IsValidInitiativeMode(mode) {
+			t.Errorf("IsValidInitiativeMode(%q) = false, want true", mode)
+		}
+	}
+	if IsValidInitiativeMode("speed_factor") {
+		t.Error("IsValidInitiativeMode(\"speed_factor\") = true, want false")
+	}
+}
+
+func TestRollSideInitiative(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		party, monsters := RollSideInitiative()
+		if party == monsters {
+			t.Fatalf("RollSideInitiative() tied at %d", party)
+		}
+		if party < 1 || party > 20 || monsters < 1 || monsters > 20 {
+			t.Fatalf("RollSideInitiative() = (%d, %d), want d20 results", party, monsters)
+		}
+	}
+}
+
+func TestNextSideTurn(t *testing.T) {
+	// Party (false) x3, then monsters (true) x2
+	sides := []bool{false, false, false, true, true}
+	tests := []struct {
+		current     int
+		wantIndex   int
+		wantWrapped bool
+	}{
+		{0, 3, false},
+		{2, 3, false},
+		{3, 0, true},
+		{4, 0, true},
+		{9, 0, true},
+	}
+	for _, tt := range tests {
+		got, wrapped := NextSideTurn(sides, tt.current)
+		if got != tt.wantIndex || wrapped != tt.wantWrapped {
+			t.Errorf("NextSideTurn(%d) = (%d, %v), want (%d, %v)", tt.current, got, wrapped, tt.wantIndex, tt.wantWrapped)
+		}
+	}
+}
+
+func TestSideBlock(t *testing.T) {
+	sides := []bool{true, true, false, false, false}
+	if start, end := SideBlock(sides, 1); start != 0 || end != 2 {
+		t.Errorf("SideBlock(1) = (%d, %d), want (0, 2)", start, end)
+	}
+	if start, end := SideBlock(sides, 3); start != 2 || end != 5 {
+		t.Errorf("SideBlock(3) = (%d, %d), want (2, 5)", start, end)
+	}
+}
+
+func TestPopcornCandidates(t *testing.T) {
+	ids := []int{4, -1, 7, -2}
+
+	got, over := PopcornCandidates(ids, []int{4})
+	if over || !reflect.DeepEqual(got, []int{-1, 7, -2}) {
+		t.Errorf("PopcornCandidates after one turn = (%v, %v)", got, over)
+	}
+
+	got, over = PopcornCandidates(ids, []int{4, -1, 7, -2})
+	if !over || !reflect.DeepEqual(got, ids) {
+		t.Errorf("PopcornCandidates after full round = (%v, %v), want (%v, true)", got, over, ids)
+	}
+}
+
+func TestNextTurn(t *testing.T) {
+	ids := []int{4, 7, -1, -2} // two PCs then two monsters
+
+	tests := []struct {
+		name        string
+		mode        string
+		current     int
+		acted       []int
+		chosenID    int
+		wantNext    int
+		wantRound   bool
+		wantActed   []int
+		wantAllowed bool
+	}{
+		{"standard advances", InitiativeModeStandard, 1, nil, 0, 2, false, nil, true},
+		{"standard wraps", InitiativeModeStandard, 3, nil, 0, 0, true, nil, true},
+		{"standard after removal", InitiativeModeStandard, -1, nil, 0, 0, false, nil, true},
+		{"side jumps to monsters", InitiativeModeSide, 0, nil, 0, 2, false, nil, true},
+		{"side wraps to party", InitiativeModeSide, 2, nil, 0, 0, true, nil, true},
+		{"popcorn default next", InitiativeModePopcorn, 0, nil, 0, 1, false, []int{4}, true},
+		{"popcorn chosen", InitiativeModePopcorn, 0, nil, -2, 3, false, []int{4}, true},
+		{"popcorn already acted", InitiativeModePopcorn, 1, []int{4}, 4, 1, false, []int{4}, false},
+		{"popcorn skips acted", InitiativeModePopcorn, 0, []int{7}, 0, 2, false, []int{7, 4}, true},
+		{"popcorn round over picks self", InitiativeModePopcorn, 3, []int{4, 7, -1}, -2, 3, true, []int{}, true},
+		{"popcorn round over default", InitiativeModePopcorn, 3, []int{4, 7, -1}, 0, 0, true, []int{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, newRound, acted, ok := NextTurn(tt.mode, ids, tt.current, tt.acted, tt.chosenID)
+			if next != tt.wantNext || newRound != tt.wantRound || ok != tt.wantAllowed {
+				t.Errorf("NextTurn() = (%d, %v, _, %v), want (%d, %v, _, %v)", next, newRound, ok, tt.wantNext, tt.wantRound, tt.wantAllowed)
+			}
+			if len(acted) != len(tt.wantActed) || (len(acted) > 0 && !reflect.DeepEqual(acted, tt.wantActed)) {
+				t.Errorf("NextTurn() acted = %v, want %v", acted, tt.wantActed)
+			}
+		})
+	}
+}
+
+func TestInitiativeInsertIndex(t *testing.T) {
+	inits := []int{18, 15, 15, 9}
+	dexes := []int{14, 16, 12, 10}
+	tests := []struct {
+		init, dex int
+		want      int
+	}{
+		{20, 10, 0},
+		{15, 14, 2}, // between the two 15s by DEX
+		{15, 12, 3}, // full tie goes after the existing combatant
+		{9, 10, 4},
+		{3, 18, 4},
+	}
+	for _, tt := range tests {
+		if got := InitiativeInsertIndex(inits, dexes, tt.init, tt.dex); got != tt.want {
+			t.Errorf("InitiativeInsertIndex(%d, %d) = %d, want %d", tt.init, tt.dex, got, tt.want)
+		}
+	}
+	if ShiftTurnIndex(2, 1) != 3 || ShiftTurnIndex(2, 2) != 3 || ShiftTurnIndex(2, 3) != 2 {
+		t.Error("ShiftTurnIndex should move the turn only when inserting at or before it")
+	}
+}
+
+func TestInitiativeBefore(t *testing.T) {
+	slots := []InitiativeSlot{
+		{ID: -2, Initiative: 15, DexScore: 14},
+		{ID: 7, Initiative: 15, DexScore: 14},
+		{ID: -1, Initiative: 15, DexScore: 14},
+		{ID: 3, Initiative: 15, DexScore: 14, Delayed: true},
+		{ID: 5, Initiative: 15, DexScore: 16},
+		{ID: 2, Initiative: 15, DexScore: 14},
+		{ID: 9, Initiative: 17, DexScore: 8},
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return InitiativeBefore(slots[i], slots[j]) })
+	got := []int{}
+	for _, s := range slots {
+		got = append(got, s.ID)
+	}
+	want := []int{9, 5, 2, 7, -1, -2, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestDelayTurn(t *testing.T) {
+	order := []InitiativeSlot{
+		{ID: 1, Initiative: 18, DexScore: 12},
+		{ID: 2, Initiative: 14, DexScore: 16},
+		{ID: -1, Initiative: 10, DexScore: 10},
+		{ID: 3, Initiative: 6, DexScore: 14},
+	}
+
+	delayed, pos, ok := DelayTurn(order, 1, 10)
+	if !ok || pos != 2 || delayed[1].ID != -1 || delayed[2].ID != 2 || delayed[2].Initiative != 10 || !delayed[2].Delayed {
+		t.Errorf("delaying to 10 should act after the goblin on that count: %+v at %d (ok %v)", delayed, pos, ok)
+	}
+	if order[1].ID != 2 || order[1].Initiative != 14 {
+		t.Error("DelayTurn must not modify the original order")
+	}
+
+	if _, pos, ok := DelayTurn(order, 1, -5); !ok || pos != 3 {
+		t.Errorf("delaying below everyone should go last, got %d (ok %v)", pos, ok)
+	}
+	if _, _, ok := DelayTurn(order, 1, 14); ok {
+		t.Error("delaying to the same count isn't a delay")
+	}
+	if _, _, ok := DelayTurn(order, 3, 2); ok {
+		t.Error("the last combatant has no one to wait for")
+	}
+	if _, _, ok := DelayTurn(order, 1, 11); ok {
+		t.Error("a count that keeps the same place in the order changes nothing")
+	}
+}
+
+func TestHazardActs(t *testing.T) {
+	tests := []struct {
+		count, from, to int
+		newRound        bool
+		want            bool
+	}{
+		{15, 18, 12, false, true},
+		{15, 15, 12, false, true},  // loses the tie: acts after the combatant on 15
+		{15, 18, 15, false, false}, // the combatant on 15 goes first
+		{15, 12, 9, false, false},
+		{5, 8, 20, true, true},  // end of the round, after the last combatant
+		{25, 8, 20, true, true}, // start of the next round, before the first
+		{15, 8, 20, true, false},
+	}
+	for _, tt := range tests {
+		if got := HazardActs(tt.count, tt.from, tt.to, tt.newRound); got != tt.want {
+			t.Errorf("HazardActs(%d, %d, %d, %v) = %v, want %v", tt.count, tt.from, tt.to, tt.newRound, got, tt.want)
+		}
+	}
+}
@@ -42,3 +42,58 @@ func XPToNextLevel(currentXP int) int {
 	}
 	return XPThresholds[currentLevel+1] - currentXP
 }
+
+// AverageMaxHP returns max HP using the fixed hit point option (PHB p15): the first die is
+// taken at maximum, every later level adds half the die + 1. hitDice lists one die size per
+// level, first level first. Each level adds at least 1 HP.
+func AverageMaxHP(hitDice []int, conMod int) int {
+	total := 0
+	for i, die := range hitDice {
+		gain := die/2 + 1 + conMod
+		if i == 0 {
+			gain = die + conMod
+		}
+		total += max(gain, 1)
+	}
+	return total
+}
+
+// AwardXP returns a character's XP total after an award of delta, which is negative when
+// the GM corrects an over-award, and the level that total is worth. XP never drops below 0.
+func AwardXP(xp, delta int) (total, level int) {
+	total = max(xp+delta, 0)
+	return total, LevelForXP(total)
+}
+
+// LevelDownHP returns max HP and HP after a character loses levels (v1.0.44). before and
+// after are its hit dice (one per level, as for AverageMaxHP) before and after the loss; the
+// fixed HP between them comes off both max HP and HP. bonusHPPerLevel (Draconic Resilience)
+// is also lost from max HP for each level lost. Max HP stays at least 1, and HP between 0
+// and max HP.
+func LevelDownHP(maxHP, hp int, before, after []int, conMod, bonusHPPerLevel int) (newMax, newHP int) {
+	lost := AverageMaxHP(before, conMod) - AverageMaxHP(after, conMod)
+	newMax = max(maxHP-lost, 1)
+	newHP = min(max(hp-lost, 0), newMax)
+	if levels := len(before) - len(after); bonusHPPerLevel > 0 && levels > 0 {
+		newMax = max(newMax-bonusHPPerLevel*levels, 1)
+		newHP = min(newHP, newMax)
+	}
+	return newMax, newHP
+}
+
+// XP advancement modes (PHB p15, DMG p261). Encounter XP totals awards from defeated foes;
+// milestone advancement levels characters at story beats instead.
+const (
+	XPModeEncounter = "encounter"
+	XPModeMilestone = "milestone"
+)
+
+// CatchUpXP is the share of an award given to a character who missed the session: percent
+// of xp, rounded down. percent is clamped to 0-100.
+func CatchUpXP(xp, percent int) int {
+	percent = min(max(percent, 0), 100)
+	if xp <= 0 {
+		return 0
+	}
+	return xp * percent / 100
+}
@@ -98,3 +98,106 @@ func TestXPThresholds(t *testing.T) {
 		}
 	}
 }
+
+func TestAverageMaxHP(t *testing.T) {
+	tests := []struct {
+		name    string
+		hitDice []int
+		conMod  int
+		want    int
+	}{
+		{"fighter 1", []int{10}, 2, 12},
+		{"fighter 3", []int{10, 10, 10}, 2, 28},
+		{"wizard 2 low con", []int{6, 6}, -3, 4},
+		{"fighter 1 / wizard 1", []int{10, 6}, 1, 16},
+		{"no levels", nil, 3, 0},
+	}
+	for _, tt := range tests {
+		if got := AverageMaxHP(tt.hitDice, tt.conMod); got != tt.want {
+			t.Errorf("%s: AverageMaxHP = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAwardXP(t *testing.T) {
+	tests := []struct {
+		name         string
+		xp, delta    int
+		total, level int
+	}{
+		{"award within a level", 300, 200, 500, 2},
+		{"award crossing a threshold up", 800, 200, 1000, 3},
+		{"correction within a level", 1000, -50, 950, 3},
+		{"correction crossing one threshold down", 1000, -200, 800, 2},
+		{"correction crossing several thresholds down", 7000, -6200, 800, 2},
+		{"correction to exactly a threshold", 2800, -1900, 900, 3},
+		{"correction clamped at 0", 500, -1000, 0, 1},
+		{"correction from 0", 0, -300, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, level := AwardXP(tt.xp, tt.delta)
+			if total != tt.total || level != tt.level {
+				t.Errorf("AwardXP(%d, %d) = %d, %d; want %d, %d", tt.xp, tt.delta, total, level, tt.total, tt.level)
+			}
+		})
+	}
+}
+
+func TestLevelDownHP(t *testing.T) {
+	fighter := func(levels int) []int {
+		dice := []int{}
+		for i := 0; i < levels; i++ {
+			dice = append(dice, 10)
+		}
+		return dice
+	}
+	tests := []struct {
+		name          string
+		maxHP, hp     int
+		before, after []int
+		conMod, bonus int
+		newMax, newHP int
+	}{
+		// Fighter, CON +2: 12 at level 1, then 8 a level
+		{"one level lost", 28, 28, fighter(3), fighter(2), 2, 0, 20, 20},
+		{"several levels lost", 44, 30, fighter(5), fighter(2), 2, 0, 20, 6},
+		{"down to level 1", 28, 28, fighter(3), fighter(1), 2, 0, 12, 12},
+		{"HP floors at 0", 28, 5, fighter(3), fighter(2), 2, 0, 20, 0},
+		{"max HP floors at 1", 5, 5, fighter(3), fighter(1), -1, 0, 1, 0},
+		{"GM-raised max HP keeps its extra", 40, 40, fighter(3), fighter(2), 2, 0, 32, 32},
+		// Draconic sorcerer, CON +1: 7 at level 1, then 5 a level, +1 max HP a level
+		{"draconic bonus lost with levels", 22, 22, []int{6, 6, 6, 6}, []int{6, 6}, 1, 1, 10, 10},
+		{"draconic bonus lowers max HP, not HP", 22, 15, []int{6, 6, 6, 6}, []int{6, 6}, 1, 1, 10, 5},
+		// Fighter 2 / wizard 1 loses the wizard level: the d6 comes off, not a d10
+		{"multiclass loses its last class level", 25, 25, []int{10, 10, 6}, []int{10, 10}, 1, 0, 20, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newMax, newHP := LevelDownHP(tt.maxHP, tt.hp, tt.before, tt.after, tt.conMod, tt.bonus)
+			if newMax != tt.newMax || newHP != tt.newHP {
+				t.Errorf("LevelDownHP = %d, %d; want %d, %d", newMax, newHP, tt.newMax, tt.newHP)
+			}
+		})
+	}
+}
+
+func TestCatchUpXP(t *testing.T) {
+	tests := []struct {
+		xp, percent, want int
+	}{
+		{450, 50, 225},
+		{450, 0, 0},
+		{451, 50, 225},
+		{300, 150, 300},
+		{300, -10, 0},
+		{-300, 50, 0}, // corrections aren't shared with absent characters
+	}
+	for _, tt := range tests {
+		if got := CatchUpXP(tt.xp, tt.percent); got != tt.want {
+			t.Errorf("CatchUpXP(%d, %d) = %d, want %d", tt.xp, tt.percent, got, tt.want)
+		}
+	}
+}
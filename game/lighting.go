@@ -0,0 +1,198 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// lighting.go - light levels (PHB p183), vision in them, and spell-made zones of light and
+// darkness on the combat grid
+package game
+
+import (
+	"math"
+	"strings"
+)
+
+// Light levels, darkest first.
+const (
+	LightDarkness = "darkness"
+	LightDim      = "dim"
+	LightBright   = "bright"
+)
+
+var lightRank = map[string]int{LightDarkness: 0, LightDim: 1, LightBright: 2}
+
+// Brighter returns the brighter of two light levels.
+func Brighter(a, b string) string {
+	if lightRank[b] > lightRank[a] {
+		return b
+	}
+	return a
+}
+
+// LightSpell describes the light or darkness a spell fills its area with.
+type LightSpell struct {
+	Name          string
+	Light         string // bright or darkness within RadiusFt (dim for Dancing Lights)
+	RadiusFt      int
+	DimFt         int // a further ring of dim light beyond RadiusFt
+	Level         int // the spell's level; zones keep the level they were cast at
+	Concentration bool
+}
+
+// LightSpells are the SRD spells that create light or darkness, keyed by spell slug.
+var LightSpells = map[string]LightSpell{
+	"darkness":        {Name: "Darkness", Light: LightDarkness, RadiusFt: 15, Level: 2, Concentration: true},
+	"daylight":        {Name: "Daylight", Light: LightBright, RadiusFt: 60, DimFt: 60, Level: 3},
+	"light":           {Name: "Light", Light: LightBright, RadiusFt: 20, DimFt: 20, Level: 0},
+	"continual-flame": {Name: "Continual Flame", Light: LightBright, RadiusFt: 20, DimFt: 20, Level: 2},
+	"dancing-lights":  {Name: "Dancing Lights", Light: LightDim, RadiusFt: 10, Level: 0, Concentration: true},
+}
+
+// LightZone is a sphere of magical light or darkness centered on a grid square.
+type LightZone struct {
+	ID            int    `json:"id"`
+	Spell         string `json:"spell"`
+	Level         int    `json:"level"`
+	X             int    `json:"x"`
+	Y             int    `json:"y"`
+	Light         string `json:"light"`
+	RadiusFt      int    `json:"radius_ft"`
+	DimFt         int    `json:"dim_ft,omitempty"`
+	CasterID      int    `json:"caster_id,omitempty"`
+	Concentration bool   `json:"concentration,omitempty"`
+}
+
+// NewLightZone makes the zone a light or darkness spell creates at a square. level is the
+// slot it was cast with (raised to the spell's own level). ok is false for other spells.
+func NewLightZone(spell string, level int, at GridPos, casterID int) (LightZone, bool) {
+	spell = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(spell)), " ", "-")
+	s, ok := LightSpells[spell]
+	if !ok {
+		return LightZone{}, false
+	}
+	return LightZone{
+		Spell:         s.Name,
+		Level:         max(level, s.Level),
+		X:             at.X,
+		Y:             at.Y,
+		Light:         s.Light,
+		RadiusFt:      s.RadiusFt,
+		DimFt:         s.DimFt,
+		CasterID:      casterID,
+		Concentration: s.Concentration,
+	}, true
+}
+
+// distanceFt is the distance between two square centers in feet.
+func distanceFt(a, b GridPos) float64 {
+	return 5 * math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+// reachFt is how far the zone's effect extends, dim ring included.
+func (z LightZone) reachFt() float64 {
+	return float64(z.RadiusFt + z.DimFt)
+}
+
+// lightAt returns the zone's light at a square, or "" outside it.
+func (z LightZone) lightAt(p GridPos) string {
+	d := distanceFt(GridPos{z.X, z.Y}, p)
+	switch {
+	case d <= float64(z.RadiusFt):
+		return z.Light
+	case d <= z.reachFt():
+		return LightDim
+	}
+	return ""
+}
+
+// isDarkness reports whether the zone is magical darkness rather than light.
+func (z LightZone) isDarkness() bool {
+	return z.Light == LightDarkness
+}
+
+// Overlaps reports whether two zones' areas overlap.
+func (z LightZone) Overlaps(o LightZone) bool {
+	return distanceFt(GridPos{z.X, z.Y}, GridPos{o.X, o.Y}) < z.reachFt()+o.reachFt()
+}
+
+// AddLightZone places a zone on the map and dispels the opposite zones it overlaps that
+// were cast at its level or lower: Darkness ends light spells of 2nd level or lower, and
+// Daylight ends darkness of 3rd level or lower (PHB p230). Returns the dispelled zones.
+func (m *BattleMap) AddLightZone(z LightZone) (added LightZone, dispelled []LightZone) {
+	for _, existing := range m.LightZones {
+		z.ID = max(z.ID, existing.ID)
+	}
+	z.ID++
+	kept := []LightZone{}
+	for _, existing := range m.LightZones {
+		if existing.isDarkness() != z.isDarkness() && existing.Level <= z.Level && existing.Overlaps(z) {
+			dispelled = append(dispelled, existing)
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	m.LightZones = append(kept, z)
+	return z, dispelled
+}
+
+// RemoveLightZone takes a zone off the map; false if there was none with that ID.
+func (m *BattleMap) RemoveLightZone(id int) bool {
+	for i, z := range m.LightZones {
+		if z.ID == id {
+			m.LightZones = append(m.LightZones[:i], m.LightZones[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// LightingAt returns the light level at a square: the ambient light raised by any light
+// zones covering it, unless magical darkness covers it. Where darkness and light overlap,
+// the higher-level spell wins, and darkness wins ties. magical reports magical darkness,
+// which darkvision can't see through.
+func (m BattleMap) LightingAt(p GridPos, ambient string) (light string, magical bool) {
+	darkLevel, lightLevel := -1, -1
+	light = ambient
+	for _, z := range m.LightZones {
+		l := z.lightAt(p)
+		if l == "" {
+			continue
+		}
+		if z.isDarkness() {
+			darkLevel = max(darkLevel, z.Level)
+			continue
+		}
+		lightLevel = max(lightLevel, z.Level)
+		light = Brighter(light, l)
+	}
+	if darkLevel >= 0 && darkLevel >= lightLevel {
+		return LightDarkness, true
+	}
+	return light, false
+}
+
+// Vision results for a creature looking into a light level.
+const (
+	VisionNormal = "normal" // sees normally
+	VisionDim    = "dim"    // lightly obscured: disadvantage on sight-based Perception
+	VisionBlind  = "blind"  // heavily obscured: effectively blinded
+)
+
+// VisionIn returns how well a creature sees in a light level. Darkvision treats dim light
+// as bright and darkness as dim, but can't see through magical darkness; blindsight,
+// truesight and Devil's Sight see in any darkness (PHB p183, p110).
+func VisionIn(light string, magicalDarkness bool, darkvision, blindsight, truesight int, devilsSight bool) string {
+	switch light {
+	case LightDim:
+		if darkvision > 0 || blindsight > 0 || truesight > 0 {
+			return VisionNormal
+		}
+		return VisionDim
+	case LightDarkness:
+		if truesight > 0 || blindsight > 0 || devilsSight {
+			return VisionNormal
+		}
+		if darkvision > 0 && !magicalDarkness {
+			return VisionDim
+		}
+		return VisionBlind
+	}
+	return VisionNormal
+}
@@ -0,0 +1,113 @@
+package game
+
+import "testing"
+
+func TestVisionIn(t *testing.T) {
+	tests := []struct {
+		name                              string
+		light                             string
+		magical                           bool
+		darkvision, blindsight, truesight int
+		devilsSight                       bool
+		want                              string
+	}{
+		{"bright", LightBright, false, 0, 0, 0, false, VisionNormal},
+		{"dim without darkvision", LightDim, false, 0, 0, 0, false, VisionDim},
+		{"dim with darkvision", LightDim, false, 60, 0, 0, false, VisionNormal},
+		{"darkness without darkvision", LightDarkness, false, 0, 0, 0, false, VisionBlind},
+		{"darkness with darkvision", LightDarkness, false, 60, 0, 0, false, VisionDim},
+		{"magical darkness with darkvision", LightDarkness, true, 60, 0, 0, false, VisionBlind},
+		{"magical darkness with devil's sight", LightDarkness, true, 0, 0, 0, true, VisionNormal},
+		{"magical darkness with truesight", LightDarkness, true, 0, 0, 120, false, VisionNormal},
+		{"magical darkness with blindsight", LightDarkness, true, 0, 10, 0, false, VisionNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VisionIn(tt.light, tt.magical, tt.darkvision, tt.blindsight, tt.truesight, tt.devilsSight); got != tt.want {
+				t.Errorf("VisionIn() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLightingAt(t *testing.T) {
+	var m BattleMap
+	darkness, _ := NewLightZone("darkness", 2, GridPos{0, 0}, 1)
+	m.AddLightZone(darkness)
+	daylight, _ := NewLightZone("Daylight", 3, GridPos{30, 0}, 2)
+	m.AddLightZone(daylight)
+
+	tests := []struct {
+		name        string
+		at          GridPos
+		ambient     string
+		wantLight   string
+		wantMagical bool
+	}{
+		{"inside darkness", GridPos{2, 0}, LightBright, LightDarkness, true},
+		{"outside both", GridPos{0, 10}, LightDim, LightDim, false},
+		{"inside daylight", GridPos{30, 10}, LightDarkness, LightBright, false},
+		{"daylight's dim ring", GridPos{30, 20}, LightDarkness, LightDim, false},
+		{"dim ring doesn't darken bright ambient", GridPos{30, 20}, LightBright, LightBright, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			light, magical := m.LightingAt(tt.at, tt.ambient)
+			if light != tt.wantLight || magical != tt.wantMagical {
+				t.Errorf("LightingAt(%v) = %q, %v; want %q, %v", tt.at, light, magical, tt.wantLight, tt.wantMagical)
+			}
+		})
+	}
+}
+
+func TestAddLightZoneDispels(t *testing.T) {
+	var m BattleMap
+	light, _ := NewLightZone("light", 0, GridPos{0, 0}, 1)
+	m.AddLightZone(light)
+	flame, _ := NewLightZone("continual-flame", 2, GridPos{40, 0}, 1)
+	m.AddLightZone(flame)
+
+	// Darkness (2nd) ends the Light cantrip it overlaps but not the far-away flame
+	darkness, _ := NewLightZone("darkness", 2, GridPos{3, 0}, 2)
+	darkness, dispelled := m.AddLightZone(darkness)
+	if len(dispelled) != 1 || dispelled[0].Spell != "Light" {
+		t.Fatalf("darkness dispelled %v, want the Light cantrip", dispelled)
+	}
+
+	// Daylight (3rd) ends 2nd-level darkness
+	daylight, _ := NewLightZone("daylight", 3, GridPos{10, 0}, 3)
+	if _, dispelled := m.AddLightZone(daylight); len(dispelled) != 1 || dispelled[0].ID != darkness.ID {
+		t.Fatalf("daylight dispelled %v, want the darkness", dispelled)
+	}
+
+	// Darkness upcast below Daylight's level leaves it alone, and loses where they overlap
+	weak, _ := NewLightZone("darkness", 2, GridPos{12, 0}, 2)
+	if _, dispelled := m.AddLightZone(weak); len(dispelled) != 0 {
+		t.Fatalf("2nd-level darkness dispelled %v", dispelled)
+	}
+	if light, _ := m.LightingAt(GridPos{12, 0}, LightDarkness); light != LightBright {
+		t.Errorf("daylight over weaker darkness = %q, want bright", light)
+	}
+	if len(m.LightZones) != 3 {
+		t.Errorf("zones = %v, want flame, daylight and the new darkness", m.LightZones)
+	}
+	if !m.RemoveLightZone(m.LightZones[2].ID) || len(m.LightZones) != 2 {
+		t.Errorf("after RemoveLightZone zones = %v", m.LightZones)
+	}
+	if m.RemoveLightZone(99) {
+		t.Error("RemoveLightZone(99) found a zone")
+	}
+}
+
+func TestNewLightZone(t *testing.T) {
+	if _, ok := NewLightZone("fireball", 3, GridPos{}, 1); ok {
+		t.Error("fireball made a light zone")
+	}
+	z, ok := NewLightZone("Dancing Lights", 0, GridPos{1, 2}, 4)
+	if !ok || z.Light != LightDim || !z.Concentration || z.X != 1 || z.Y != 2 {
+		t.Errorf("dancing lights zone = %+v", z)
+	}
+	if z, _ := NewLightZone("darkness", 1, GridPos{}, 1); z.Level != 2 {
+		t.Errorf("darkness level = %d, want at least 2", z.Level)
+	}
+}
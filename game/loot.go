@@ -0,0 +1,73 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// loot.go - moving items between inventories and splitting treasure
+package game
+
+import "strings"
+
+// InventoryQuantity is how many of an inventory entry a character has (1 when unset).
+func InventoryQuantity(item map[string]interface{}) int {
+	switch q := item["quantity"].(type) {
+	case float64:
+		return int(q)
+	case int:
+		return q
+	}
+	return 1
+}
+
+// FindInventoryItem returns the index of the entry named name (case-insensitive), or -1.
+func FindInventoryItem(inventory []map[string]interface{}, name string) int {
+	for i, item := range inventory {
+		if n, _ := item["name"].(string); strings.EqualFold(n, strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TakeInventoryItem removes quantity of the named item. It returns the inventory left
+// behind and a copy of the entry holding what was taken; ok is false if there aren't
+// enough.
+func TakeInventoryItem(inventory []map[string]interface{}, name string, quantity int) (rest []map[string]interface{}, taken map[string]interface{}, ok bool) {
+	i := FindInventoryItem(inventory, name)
+	if i < 0 || quantity < 1 || InventoryQuantity(inventory[i]) < quantity {
+		return inventory, nil, false
+	}
+	taken = map[string]interface{}{}
+	for k, v := range inventory[i] {
+		taken[k] = v
+	}
+	taken["quantity"] = quantity
+	left := InventoryQuantity(inventory[i]) - quantity
+	rest = append([]map[string]interface{}{}, inventory...)
+	if left == 0 {
+		rest = append(rest[:i], rest[i+1:]...)
+	} else {
+		updated := map[string]interface{}{}
+		for k, v := range inventory[i] {
+			updated[k] = v
+		}
+		updated["quantity"] = left
+		rest[i] = updated
+	}
+	return rest, taken, true
+}
+
+// AddInventoryItem stacks item onto an entry with the same name, or appends it.
+func AddInventoryItem(inventory []map[string]interface{}, item map[string]interface{}) []map[string]interface{} {
+	name, _ := item["name"].(string)
+	if i := FindInventoryItem(inventory, name); i >= 0 {
+		inventory[i]["quantity"] = InventoryQuantity(inventory[i]) + InventoryQuantity(item)
+		return inventory
+	}
+	return append(inventory, item)
+}
+
+// SplitCurrency divides amount evenly between shares; the remainder stays undivided.
+func SplitCurrency(amount, shares int) (each, remainder int) {
+	if shares < 1 || amount < 1 {
+		return 0, max(amount, 0)
+	}
+	return amount / shares, amount % shares
+}
@@ -0,0 +1,58 @@
+package game
+
+import "testing"
+
+func TestTakeInventoryItem(t *testing.T) {
+	inventory := []map[string]interface{}{
+		{"name": "Rope", "quantity": 1},
+		{"name": "Potion of Healing", "type": "potion", "quantity": float64(3)},
+	}
+
+	rest, taken, ok := TakeInventoryItem(inventory, "potion of healing", 2)
+	if !ok || InventoryQuantity(taken) != 2 || taken["type"] != "potion" {
+		t.Fatalf("TakeInventoryItem = %v, %v; want 2 potions", taken, ok)
+	}
+	if len(rest) != 2 || InventoryQuantity(rest[1]) != 1 {
+		t.Errorf("rest = %v, want 1 potion left", rest)
+	}
+	if InventoryQuantity(inventory[1]) != 3 {
+		t.Error("TakeInventoryItem changed the inventory it was given")
+	}
+
+	rest, _, ok = TakeInventoryItem(inventory, "Rope", 1)
+	if !ok || len(rest) != 1 || FindInventoryItem(rest, "rope") >= 0 {
+		t.Errorf("taking the last rope left %v", rest)
+	}
+	if _, _, ok := TakeInventoryItem(inventory, "Rope", 2); ok {
+		t.Error("took 2 ropes from 1")
+	}
+	if _, _, ok := TakeInventoryItem(inventory, "Lantern", 1); ok {
+		t.Error("took an item that isn't there")
+	}
+}
+
+func TestAddInventoryItem(t *testing.T) {
+	inventory := []map[string]interface{}{{"name": "Arrows", "quantity": float64(10)}}
+	inventory = AddInventoryItem(inventory, map[string]interface{}{"name": "arrows", "quantity": 5})
+	if len(inventory) != 1 || InventoryQuantity(inventory[0]) != 15 {
+		t.Errorf("stacking arrows gave %v", inventory)
+	}
+	inventory = AddInventoryItem(inventory, map[string]interface{}{"name": "Lantern"})
+	if len(inventory) != 2 || InventoryQuantity(inventory[1]) != 1 {
+		t.Errorf("adding a lantern gave %v", inventory)
+	}
+}
+
+func TestSplitCurrency(t *testing.T) {
+	tests := []struct{ amount, shares, each, remainder int }{
+		{100, 4, 25, 0},
+		{103, 4, 25, 3},
+		{3, 4, 0, 3},
+		{50, 0, 0, 50},
+	}
+	for _, tt := range tests {
+		if each, rem := SplitCurrency(tt.amount, tt.shares); each != tt.each || rem != tt.remainder {
+			t.Errorf("SplitCurrency(%d, %d) = %d, %d; want %d, %d", tt.amount, tt.shares, each, rem, tt.each, tt.remainder)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// minions.go - minion mode for horde fights (1 HP monsters that die on any hit)
+package game
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MinionHP is a minion's hit point total: any damage kills it.
+const MinionHP = 1
+
+// MinionMaxCR is the highest challenge rating a monster can have and still run as a minion.
+// Anything tougher should keep its real hit points.
+const MinionMaxCR = 2.0
+
+// ParseCR converts a challenge rating string ("1/4", "2") to a number.
+func ParseCR(cr string) (float64, bool) {
+	cr = strings.TrimSpace(cr)
+	if num, den, ok := strings.Cut(cr, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	v, err := strconv.ParseFloat(cr, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// CanBeMinion reports whether a monster of the given challenge rating may run as a minion.
+// An unknown CR (custom monsters) is allowed; the GM vouches for it.
+func CanBeMinion(cr string) bool {
+	v, ok := ParseCR(cr)
+	return !ok || v <= MinionMaxCR
+}
+
+// MinionDamage returns a minion's HP after taking damage: any damage at all drops it to 0.
+func MinionDamage(hp, damage int) int {
+	if damage > 0 {
+		return 0
+	}
+	return hp
+}
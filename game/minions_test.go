@@ -0,0 +1,50 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestParseCR(t *testing.T) {
+	tests := []struct {
+		cr     string
+		want   float64
+		wantOK bool
+	}{
+		{"1/8", 0.125, true},
+		{"1/4", 0.25, true},
+		{"1/2", 0.5, true},
+		{"3", 3, true},
+		{" 10 ", 10, true},
+		{"1/0", 0, false},
+		{"boss", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseCR(tt.cr)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseCR(%q) = (%v, %v), want (%v, %v)", tt.cr, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCanBeMinion(t *testing.T) {
+	tests := map[string]bool{
+		"1/4": true,
+		"2":   true,
+		"3":   false,
+		"":    true,
+	}
+	for cr, want := range tests {
+		if got := CanBeMinion(cr); got != want {
+			t.Errorf("CanBeMinion(%q) = %v, want %v", cr, got, want)
+		}
+	}
+}
+
+func TestMinionDamage(t *testing.T) {
+	if got := MinionDamage(MinionHP, 1); got != 0 {
+		t.Errorf("MinionDamage(1, 1) = %d, want 0", got)
+	}
+	if got := MinionDamage(MinionHP, 0); got != MinionHP {
+		t.Errorf("MinionDamage(1, 0) = %d, want %d", got, MinionHP)
+	}
+}
@@ -0,0 +1,75 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// morale.go - optional morale rules (DMG p273): flee triggers and save modifiers
+package game
+
+import "strings"
+
+// Morale trigger keys. DMG p273 suggests a creature or group might flee when:
+//   - its leader is killed or incapacitated
+//   - the group is reduced to half its starting number with none of the enemies down
+//   - the creature is reduced to half its hit points or fewer for the first time
+const (
+	MoraleTriggerLeaderDown    = "leader_down"
+	MoraleTriggerHalfGroupDown = "half_group_down"
+	MoraleTriggerBloodied      = "bloodied"
+)
+
+// MoraleTriggers describes each supported morale trigger.
+var MoraleTriggers = map[string]string{
+	MoraleTriggerLeaderDown:    "A designated leader drops to 0 HP or leaves combat — every other monster checks morale",
+	MoraleTriggerHalfGroupDown: "Half of the monsters that entered combat are down or gone — every remaining monster checks morale",
+	MoraleTriggerBloodied:      "A monster drops to half its HP or fewer for the first time — that monster checks morale",
+}
+
+// DefaultMoraleTriggers are used when the GM enables morale without choosing triggers.
+var DefaultMoraleTriggers = []string{MoraleTriggerLeaderDown, MoraleTriggerHalfGroupDown}
+
+// IsValidMoraleTrigger reports whether trigger is a known morale trigger key.
+func IsValidMoraleTrigger(trigger string) bool {
+	_, ok := MoraleTriggers[trigger]
+	return ok
+}
+
+// IsMoraleImmune reports whether a creature type never makes morale checks.
+// Constructs and undead have no fear and no self-preservation.
+func IsMoraleImmune(creatureType string) bool {
+	creatureType = strings.ToLower(creatureType)
+	return strings.Contains(creatureType, "construct") || strings.Contains(creatureType, "undead")
+}
+
+// MoraleSaveModifiers adjusts a morale save based on the creature's remaining HP.
+// Bloodied (≤50% HP) creatures save with disadvantage; critically wounded (≤25% HP)
+// creatures also face DC+5.
+//
+// Returns the effective DC, whether the save has disadvantage, and human-readable notes.
+func MoraleSaveModifiers(baseDC, hp, maxHP int) (int, bool, []string) {
+	notes := []string{}
+	if maxHP <= 0 {
+		return baseDC, false, notes
+	}
+	hpPercent := (hp * 100) / maxHP
+	if hpPercent <= 25 {
+		notes = append(notes, "Critically wounded (≤25% HP): DC+5 and disadvantage")
+		return baseDC + 5, true, notes
+	}
+	if hpPercent <= 50 {
+		notes = append(notes, "Bloodied (≤50% HP): disadvantage on save")
+		return baseDC, true, notes
+	}
+	return baseDC, false, notes
+}
+
+// IsBloodied reports whether a creature is at half its hit points or fewer (but still up).
+func IsBloodied(hp, maxHP int) bool {
+	return maxHP > 0 && hp > 0 && hp*2 <= maxHP
+}
+
+// HalfGroupDown reports whether a group has lost at least half of its starting number.
+// Groups of one never trigger this (a lone monster uses the bloodied trigger instead).
+func HalfGroupDown(startingCount, downCount int) bool {
+	if startingCount < 2 {
+		return false
+	}
+	return downCount*2 >= startingCount
+}
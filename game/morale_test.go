@@ -0,0 +1,106 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestIsValidMoraleTrigger(t *testing.T) {
+	for _, trigger := range []string{"leader_down", "half_group_down", "bloodied"} {
+		if !IsValidMoraleTrigger(trigger) {
+			t.Errorf("IsValidMoraleTrigger(%q) = false, want true", trigger)
+		}
+	}
+	if IsValidMoraleTrigger("surprised") {
+		t.Error("IsValidMoraleTrigger(\"surprised\") = true, want false")
+	}
+	for _, trigger := range DefaultMoraleTriggers {
+		if !IsValidMoraleTrigger(trigger) {
+			t.Errorf("default trigger %q is not valid", trigger)
+		}
+	}
+}
+
+func TestIsMoraleImmune(t *testing.T) {
+	tests := []struct {
+		creatureType string
+		expected     bool
+	}{
+		{"construct", true},
+		{"undead", true},
+		{"Undead (shapechanger)", true},
+		{"humanoid", false},
+		{"beast", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsMoraleImmune(tt.creatureType); got != tt.expected {
+			t.Errorf("IsMoraleImmune(%q) = %v, want %v", tt.creatureType, got, tt.expected)
+		}
+	}
+}
+
+func TestMoraleSaveModifiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseDC    int
+		hp        int
+		maxHP     int
+		wantDC    int
+		wantDisad bool
+		wantNotes int
+	}{
+		{"healthy", 10, 7, 7, 10, false, 0},
+		{"just above half", 10, 6, 10, 10, false, 0},
+		{"bloodied", 10, 5, 10, 10, true, 1},
+		{"critical", 10, 2, 10, 15, true, 1},
+		{"zero max hp", 12, 0, 0, 12, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc, disad, notes := MoraleSaveModifiers(tt.baseDC, tt.hp, tt.maxHP)
+			if dc != tt.wantDC || disad != tt.wantDisad || len(notes) != tt.wantNotes {
+				t.Errorf("MoraleSaveModifiers(%d, %d, %d) = (%d, %v, %d notes), want (%d, %v, %d notes)",
+					tt.baseDC, tt.hp, tt.maxHP, dc, disad, len(notes), tt.wantDC, tt.wantDisad, tt.wantNotes)
+			}
+		})
+	}
+}
+
+func TestIsBloodied(t *testing.T) {
+	tests := []struct {
+		hp, maxHP int
+		expected  bool
+	}{
+		{10, 10, false},
+		{6, 10, false},
+		{5, 10, true},
+		{1, 10, true},
+		{0, 10, false}, // down, not bloodied
+		{3, 0, false},
+	}
+	for _, tt := range tests {
+		if got := IsBloodied(tt.hp, tt.maxHP); got != tt.expected {
+			t.Errorf("IsBloodied(%d, %d) = %v, want %v", tt.hp, tt.maxHP, got, tt.expected)
+		}
+	}
+}
+
+func TestHalfGroupDown(t *testing.T) {
+	tests := []struct {
+		starting, down int
+		expected       bool
+	}{
+		{1, 1, false},
+		{2, 1, true},
+		{5, 2, false},
+		{5, 3, true},
+		{12, 6, true},
+		{12, 5, false},
+		{0, 0, false},
+	}
+	for _, tt := range tests {
+		if got := HalfGroupDown(tt.starting, tt.down); got != tt.expected {
+			t.Errorf("HalfGroupDown(%d, %d) = %v, want %v", tt.starting, tt.down, got, tt.expected)
+		}
+	}
+}
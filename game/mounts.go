@@ -0,0 +1,57 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// mounts.go - riding animals: prices, speed while mounted and falling off (PHB p157, p198)
+package game
+
+// MountPricesCP are the mounts for sale in the PHB (p157), by monster slug, in copper.
+var MountPricesCP = map[string]int{
+	"camel":        5000,
+	"draft-horse":  5000,
+	"elephant":     20000,
+	"mastiff":      2500,
+	"mule":         800,
+	"pony":         3000,
+	"riding-horse": 7500,
+	"warhorse":     40000,
+}
+
+// MountedSpeed is how far a rider moves on their turn. A controlled mount carries the rider
+// at its own speed; an independent mount moves on its own turn, so the rider keeps theirs.
+func MountedSpeed(riderSpeed, mountSpeed int, controlled bool) int {
+	if controlled {
+		return mountSpeed
+	}
+	return riderSpeed
+}
+
+// What can unseat a rider (PHB p198).
+const (
+	MountFallMoved  = "moved"  // The mount is moved against its will
+	MountFallProne  = "prone"  // The mount is knocked prone
+	MountFallKilled = "killed" // The mount drops to 0 hit points and falls
+)
+
+// MountFallSaveDC is the Dexterity save a rider makes to stay on a mount that is moved
+// against its will (PHB p198).
+const MountFallSaveDC = 10
+
+// RiderFall works out what happens to a rider when their mount is moved against its will,
+// knocked prone or killed. saveTotal is the rider's Dexterity save, used when the mount is
+// moved. When the mount falls, a rider with their reaction can dismount and land on their
+// feet; otherwise they land prone beside it. Returns whether the rider comes off, lands
+// prone and spends their reaction.
+func RiderFall(event string, saveTotal int, reactionAvailable bool) (dismounted, prone, usedReaction bool) {
+	switch event {
+	case MountFallMoved:
+		if saveTotal >= MountFallSaveDC {
+			return false, false, false
+		}
+		return true, true, false
+	case MountFallProne, MountFallKilled:
+		if reactionAvailable {
+			return true, false, true
+		}
+		return true, true, false
+	}
+	return false, false, false
+}
@@ -0,0 +1,33 @@
+package game
+
+import "testing"
+
+func TestMountedSpeed(t *testing.T) {
+	if got := MountedSpeed(25, 60, true); got != 60 {
+		t.Errorf("a dwarf on a controlled riding horse moves %d, want 60", got)
+	}
+	if got := MountedSpeed(30, 60, false); got != 30 {
+		t.Errorf("an independent mount doesn't carry the rider on their turn: got %d, want 30", got)
+	}
+}
+
+func TestRiderFall(t *testing.T) {
+	tests := []struct {
+		event                    string
+		save                     int
+		reaction                 bool
+		dismounted, prone, spent bool
+	}{
+		{MountFallMoved, 12, true, false, false, false},
+		{MountFallMoved, 9, true, true, true, false},
+		{MountFallProne, 0, true, true, false, true},
+		{MountFallKilled, 0, false, true, true, false},
+		{"hit", 0, true, false, false, false},
+	}
+	for _, tt := range tests {
+		d, p, r := RiderFall(tt.event, tt.save, tt.reaction)
+		if d != tt.dismounted || p != tt.prone || r != tt.spent {
+			t.Errorf("RiderFall(%s, %d, %v) = %v, %v, %v; want %v, %v, %v", tt.event, tt.save, tt.reaction, d, p, r, tt.dismounted, tt.prone, tt.spent)
+		}
+	}
+}
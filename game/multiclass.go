@@ -0,0 +1,207 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// multiclass.go - a character's levels per class and the hit dice pools they give (PHB p163-164)
+package game
+
+import (
+	"sort"
+	"strings"
+)
+
+// ClassLevel is a character's levels in one class. Position orders classes by when they were
+// first taken: the starting class is 0. HitDiceSpent counts this class's hit dice spent.
+type ClassLevel struct {
+	Class        string `json:"class"`
+	Level        int    `json:"level"`
+	HitDiceSpent int    `json:"hit_dice_spent"`
+	Position     int    `json:"position"`
+}
+
+// TotalLevel is a character's level: the sum of their class levels.
+func TotalLevel(classes []ClassLevel) int {
+	total := 0
+	for _, c := range classes {
+		total += c.Level
+	}
+	return total
+}
+
+// ClassLevelMap returns class levels keyed by class, e.g. {"fighter": 3, "wizard": 2}.
+func ClassLevelMap(classes []ClassLevel) map[string]int {
+	m := map[string]int{}
+	for _, c := range classes {
+		m[c.Class] += c.Level
+	}
+	return m
+}
+
+// AddClassLevel takes one level in a class, adding the class after the others if it's new.
+func AddClassLevel(classes []ClassLevel, class string) []ClassLevel {
+	class = strings.ToLower(strings.TrimSpace(class))
+	out := append([]ClassLevel{}, classes...)
+	for i := range out {
+		if out[i].Class == class {
+			out[i].Level++
+			return out
+		}
+	}
+	position := 0
+	for _, c := range out {
+		position = max(position, c.Position+1)
+	}
+	return append(out, ClassLevel{Class: class, Level: 1, Position: position})
+}
+
+// DropClassLevels removes n levels, most recently taken class first, dropping classes left
+// with no levels. Spent hit dice beyond a class's new level are dropped with them.
+func DropClassLevels(classes []ClassLevel, n int) []ClassLevel {
+	out := append([]ClassLevel{}, classes...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Position < out[j].Position })
+	for n > 0 && len(out) > 0 {
+		last := &out[len(out)-1]
+		drop := min(n, last.Level)
+		last.Level -= drop
+		last.HitDiceSpent = min(last.HitDiceSpent, last.Level)
+		n -= drop
+		if last.Level == 0 {
+			out = out[:len(out)-1]
+		}
+	}
+	return out
+}
+
+// HitDicePool is a character's hit dice of one size. Classes with the same hit die share a
+// pool (PHB p164: a fighter/paladin has one pool of d10s).
+type HitDicePool struct {
+	Die     int      `json:"die"`
+	Total   int      `json:"total"`
+	Spent   int      `json:"spent"`
+	Classes []string `json:"classes"`
+}
+
+// Available is how many dice in the pool can still be spent.
+func (p HitDicePool) Available() int {
+	return max(p.Total-p.Spent, 0)
+}
+
+// HitDicePools groups a character's hit dice by size, largest die first.
+func HitDicePools(classes []ClassLevel) []HitDicePool {
+	byDie := map[int]*HitDicePool{}
+	dice := []int{}
+	for _, c := range classes {
+		die := HitDie(c.Class)
+		p, ok := byDie[die]
+		if !ok {
+			p = &HitDicePool{Die: die}
+			byDie[die] = p
+			dice = append(dice, die)
+		}
+		p.Total += c.Level
+		p.Spent += min(c.HitDiceSpent, c.Level)
+		p.Classes = append(p.Classes, c.Class)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(dice)))
+	pools := []HitDicePool{}
+	for _, die := range dice {
+		pools = append(pools, *byDie[die])
+	}
+	return pools
+}
+
+// SpendHitDice marks n hit dice spent and returns the size of each. die picks the pool to
+// spend from; 0 spends the largest dice first. classes is updated in place. ok is false,
+// with nothing spent, when there aren't enough dice.
+func SpendHitDice(classes []ClassLevel, n, die int) (spent []int, ok bool) {
+	available := 0
+	for _, p := range HitDicePools(classes) {
+		if die == 0 || p.Die == die {
+			available += p.Available()
+		}
+	}
+	if n > available {
+		return nil, false
+	}
+	for len(spent) < n {
+		best := -1
+		for i, c := range classes {
+			size := HitDie(c.Class)
+			if c.HitDiceSpent >= c.Level || (die != 0 && size != die) {
+				continue
+			}
+			if best < 0 || size > HitDie(classes[best].Class) {
+				best = i
+			}
+		}
+		classes[best].HitDiceSpent++
+		spent = append(spent, HitDie(classes[best].Class))
+	}
+	return spent, true
+}
+
+// HitDieRoll is one hit die spent on a short rest, broken down for narration.
+type HitDieRoll struct {
+	Die     int `json:"die"`
+	Roll    int `json:"roll"`
+	ConMod  int `json:"con_mod"`
+	Healing int `json:"healing"`
+}
+
+// HitDieHealing is the HP one hit die restores: the roll plus the CON modifier, never
+// below 0 (PHB p186).
+func HitDieHealing(roll, conMod int) int {
+	return max(roll+conMod, 0)
+}
+
+// RollHitDice rolls each spent die (sizes from SpendHitDice) and returns the rolls and
+// the total healing.
+func RollHitDice(dice []int, conMod int) ([]HitDieRoll, int) {
+	rolls := []HitDieRoll{}
+	total := 0
+	for _, die := range dice {
+		roll := RollDie(die)
+		healing := HitDieHealing(roll, conMod)
+		rolls = append(rolls, HitDieRoll{Die: die, Roll: roll, ConMod: conMod, Healing: healing})
+		total += healing
+	}
+	return rolls, total
+}
+
+// RollHitDiceUntilFull rolls spent dice one at a time like RollHitDice, but stops once the
+// healing covers missing HP. Dice not rolled aren't needed and stay unspent (v1.0.121).
+func RollHitDiceUntilFull(dice []int, conMod, missing int) ([]HitDieRoll, int) {
+	rolls := []HitDieRoll{}
+	total := 0
+	for _, die := range dice {
+		if total >= missing {
+			break
+		}
+		roll := RollDie(die)
+		healing := HitDieHealing(roll, conMod)
+		rolls = append(rolls, HitDieRoll{Die: die, Roll: roll, ConMod: conMod, Healing: healing})
+		total += healing
+	}
+	return rolls, total
+}
+
+// RecoverHitDice regains up to n spent hit dice, largest first, and returns how many were
+// regained. classes is updated in place.
+func RecoverHitDice(classes []ClassLevel, n int) int {
+	recovered := 0
+	for recovered < n {
+		best := -1
+		for i, c := range classes {
+			if c.HitDiceSpent <= 0 {
+				continue
+			}
+			if best < 0 || HitDie(c.Class) > HitDie(classes[best].Class) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		classes[best].HitDiceSpent--
+		recovered++
+	}
+	return recovered
+}
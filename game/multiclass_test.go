@@ -0,0 +1,143 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddClassLevel(t *testing.T) {
+	classes := []ClassLevel{{Class: "fighter", Level: 3}}
+	classes = AddClassLevel(classes, "Wizard")
+	classes = AddClassLevel(classes, "fighter")
+	want := []ClassLevel{{Class: "fighter", Level: 4}, {Class: "wizard", Level: 1, Position: 1}}
+	if !reflect.DeepEqual(classes, want) {
+		t.Errorf("AddClassLevel() = %+v, want %+v", classes, want)
+	}
+	if TotalLevel(classes) != 5 {
+		t.Errorf("TotalLevel() = %d, want 5", TotalLevel(classes))
+	}
+	if m := ClassLevelMap(classes); m["fighter"] != 4 || m["wizard"] != 1 {
+		t.Errorf("ClassLevelMap() = %v", m)
+	}
+}
+
+func TestDropClassLevels(t *testing.T) {
+	classes := []ClassLevel{
+		{Class: "fighter", Level: 3, HitDiceSpent: 1},
+		{Class: "wizard", Level: 2, HitDiceSpent: 2, Position: 1},
+	}
+	want := []ClassLevel{{Class: "fighter", Level: 3, HitDiceSpent: 1}, {Class: "wizard", Level: 1, HitDiceSpent: 1, Position: 1}}
+	if got := DropClassLevels(classes, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("DropClassLevels(1) = %+v, want %+v", got, want)
+	}
+	want = []ClassLevel{{Class: "fighter", Level: 2, HitDiceSpent: 1}}
+	if got := DropClassLevels(classes, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("DropClassLevels(3) = %+v, want %+v", got, want)
+	}
+	if classes[1].Level != 2 {
+		t.Error("DropClassLevels shouldn't change its argument")
+	}
+}
+
+func TestHitDicePools(t *testing.T) {
+	classes := []ClassLevel{
+		{Class: "wizard", Level: 2, HitDiceSpent: 1},
+		{Class: "fighter", Level: 3, HitDiceSpent: 1, Position: 1},
+		{Class: "paladin", Level: 1, Position: 2},
+	}
+	want := []HitDicePool{
+		{Die: 10, Total: 4, Spent: 1, Classes: []string{"fighter", "paladin"}},
+		{Die: 6, Total: 2, Spent: 1, Classes: []string{"wizard"}},
+	}
+	if got := HitDicePools(classes); !reflect.DeepEqual(got, want) {
+		t.Errorf("HitDicePools() = %+v, want %+v", got, want)
+	}
+	if want[0].Available() != 3 {
+		t.Errorf("Available() = %d, want 3", want[0].Available())
+	}
+}
+
+func TestSpendHitDice(t *testing.T) {
+	classes := []ClassLevel{{Class: "wizard", Level: 2}, {Class: "fighter", Level: 2, Position: 1}}
+
+	spent, ok := SpendHitDice(classes, 3, 0)
+	if !ok || !reflect.DeepEqual(spent, []int{10, 10, 6}) {
+		t.Errorf("largest first: spent %v, ok %v", spent, ok)
+	}
+	if classes[0].HitDiceSpent != 1 || classes[1].HitDiceSpent != 2 {
+		t.Errorf("spent per class: %+v", classes)
+	}
+
+	if _, ok := SpendHitDice(classes, 1, 10); ok {
+		t.Error("the d10 pool is empty")
+	}
+	if _, ok := SpendHitDice(classes, 2, 0); ok {
+		t.Error("only one die is left")
+	}
+	if classes[0].HitDiceSpent != 1 {
+		t.Error("a refused spend shouldn't spend anything")
+	}
+	if spent, ok := SpendHitDice(classes, 1, 6); !ok || spent[0] != 6 {
+		t.Errorf("d6 pool: spent %v, ok %v", spent, ok)
+	}
+}
+
+func TestRecoverHitDice(t *testing.T) {
+	classes := []ClassLevel{{Class: "rogue", Level: 3, HitDiceSpent: 2}, {Class: "barbarian", Level: 2, HitDiceSpent: 1, Position: 1}}
+	if got := RecoverHitDice(classes, 2); got != 2 {
+		t.Errorf("RecoverHitDice() = %d, want 2", got)
+	}
+	if classes[1].HitDiceSpent != 0 || classes[0].HitDiceSpent != 1 {
+		t.Errorf("should recover the d12 first: %+v", classes)
+	}
+	if got := RecoverHitDice(classes, 5); got != 1 {
+		t.Errorf("only one die left to recover, got %d", got)
+	}
+}
+
+func TestHitDieHealing(t *testing.T) {
+	tests := []struct {
+		roll, conMod, want int
+	}{
+		{6, 2, 8},
+		{1, 0, 1},
+		{1, -1, 0},
+		{1, -3, 0},
+	}
+	for _, tt := range tests {
+		if got := HitDieHealing(tt.roll, tt.conMod); got != tt.want {
+			t.Errorf("HitDieHealing(%d, %d) = %d, want %d", tt.roll, tt.conMod, got, tt.want)
+		}
+	}
+}
+
+func TestRollHitDice(t *testing.T) {
+	rolls, total := RollHitDice([]int{10, 6}, 1)
+	if len(rolls) != 2 || rolls[0].Die != 10 || rolls[1].Die != 6 {
+		t.Fatalf("RollHitDice() = %+v", rolls)
+	}
+	sum := 0
+	for _, r := range rolls {
+		if r.Roll < 1 || r.Roll > r.Die || r.ConMod != 1 || r.Healing != r.Roll+1 {
+			t.Errorf("bad roll %+v", r)
+		}
+		sum += r.Healing
+	}
+	if total != sum {
+		t.Errorf("total = %d, want %d", total, sum)
+	}
+}
+
+func TestRollHitDiceUntilFull(t *testing.T) {
+	// With +20 CON each d4 heals 21-24, so 25 missing HP always takes exactly two dice
+	rolls, total := RollHitDiceUntilFull([]int{4, 4, 4}, 20, 25)
+	if len(rolls) != 2 || total < 42 || total > 48 {
+		t.Errorf("RollHitDiceUntilFull(25 missing) = %+v, %d; want two dice", rolls, total)
+	}
+	if rolls, total := RollHitDiceUntilFull([]int{4, 4}, 20, 0); len(rolls) != 0 || total != 0 {
+		t.Errorf("RollHitDiceUntilFull(at full HP) = %+v, %d; want no dice", rolls, total)
+	}
+	if rolls, _ := RollHitDiceUntilFull([]int{10, 8}, 0, 100); len(rolls) != 2 || rolls[1].Die != 8 {
+		t.Errorf("RollHitDiceUntilFull(100 missing) = %+v; want every die, in order", rolls)
+	}
+}
@@ -0,0 +1,42 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// passive.go - passive checks (PHB p175): the score a hidden DC is compared against when
+// nobody rolls, such as noticing a trap or a sneaking foe
+package game
+
+// PassiveSkills are the passive scores GMs compare against hidden DCs, in display order.
+var PassiveSkills = []string{"perception", "investigation", "insight"}
+
+// PassiveBase is what every passive check starts from, before modifiers.
+const PassiveBase = 10
+
+// PassiveAdvantageBonus is added for advantage and subtracted for disadvantage.
+const PassiveAdvantageBonus = 5
+
+// SkillModifier is the modifier on a skill check: the ability modifier plus the proficiency
+// bonus if proficient, doubled with expertise. Jack of All Trades adds half the proficiency
+// bonus, rounded down, to checks without it.
+func SkillModifier(abilityMod, profBonus int, proficient, expertise, jackOfAllTrades bool) int {
+	switch {
+	case proficient && expertise:
+		return abilityMod + profBonus*2
+	case proficient:
+		return abilityMod + profBonus
+	case jackOfAllTrades:
+		return abilityMod + profBonus/2
+	}
+	return abilityMod
+}
+
+// PassiveScore is 10 + the check's modifier, +5 with advantage and -5 with disadvantage.
+// Having both cancels out, as it does for a roll.
+func PassiveScore(modifier int, advantage, disadvantage bool) int {
+	score := PassiveBase + modifier
+	if advantage && !disadvantage {
+		score += PassiveAdvantageBonus
+	}
+	if disadvantage && !advantage {
+		score -= PassiveAdvantageBonus
+	}
+	return score
+}
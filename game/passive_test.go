@@ -0,0 +1,37 @@
+package game
+
+import "testing"
+
+func TestSkillModifier(t *testing.T) {
+	tests := []struct {
+		name                     string
+		proficient, expert, jack bool
+		want                     int
+	}{
+		{"untrained", false, false, false, 2},
+		{"proficient", true, false, false, 5},
+		{"expertise", true, true, false, 8},
+		{"jack of all trades", false, false, true, 3},
+		{"jack doesn't stack with proficiency", true, false, true, 5},
+	}
+	for _, tt := range tests {
+		if got := SkillModifier(2, 3, tt.proficient, tt.expert, tt.jack); got != tt.want {
+			t.Errorf("%s: SkillModifier() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPassiveScore(t *testing.T) {
+	if got := PassiveScore(4, false, false); got != 14 {
+		t.Errorf("PassiveScore(4) = %d, want 14", got)
+	}
+	if got := PassiveScore(4, true, false); got != 19 {
+		t.Errorf("advantage adds 5, got %d", got)
+	}
+	if got := PassiveScore(4, false, true); got != 9 {
+		t.Errorf("disadvantage subtracts 5, got %d", got)
+	}
+	if got := PassiveScore(4, true, true); got != 14 {
+		t.Errorf("advantage and disadvantage cancel, got %d", got)
+	}
+}
@@ -0,0 +1,192 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// positions.go - distances, range bands, weapon and spell range, and area of effect
+// templates on the combat grid (PHB p192, p195, p204)
+package game
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Range bands: a coarse summary of a distance for agents that don't want coordinates.
+const (
+	RangeEngaged = "engaged" // Within 5 ft: melee reach
+	RangeNear    = "near"    // Up to 30 ft: a move away
+	RangeFar     = "far"     // Beyond 30 ft
+)
+
+// DistanceFt is the distance between two squares: 5 ft per square, diagonals included
+// (the PHB's simple grid rule).
+func DistanceFt(a, b GridPos) int {
+	return 5 * max(abs(a.X-b.X), abs(a.Y-b.Y))
+}
+
+// RangeBand returns the band a distance falls in.
+func RangeBand(distanceFt int) string {
+	switch {
+	case distanceFt <= 5:
+		return RangeEngaged
+	case distanceFt <= 30:
+		return RangeNear
+	}
+	return RangeFar
+}
+
+var weaponRangePattern = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// WeaponRange reads the normal and long range from an "ammunition (80/320)" or
+// "thrown (20/60)" property. ok is false for weapons without one.
+func WeaponRange(properties []string) (normal, long int, ok bool) {
+	for _, p := range properties {
+		p = strings.ToLower(p)
+		if !strings.HasPrefix(p, "ammunition") && !strings.HasPrefix(p, "thrown") {
+			continue
+		}
+		if m := weaponRangePattern.FindStringSubmatch(p); m != nil {
+			normal, _ = strconv.Atoi(m[1])
+			long, _ = strconv.Atoi(m[2])
+			return normal, long, true
+		}
+	}
+	return 0, 0, false
+}
+
+// WeaponReachFt is how far a melee weapon reaches: 10 ft with the reach property.
+func WeaponReachFt(properties []string) int {
+	for _, p := range properties {
+		if strings.EqualFold(p, "reach") {
+			return 10
+		}
+	}
+	return 5
+}
+
+// RangeCheck is a weapon attack measured against the distance to its target.
+type RangeCheck struct {
+	DistanceFt int    `json:"distance_ft"`
+	Band       string `json:"range_band"`
+	InRange    bool   `json:"in_range"`
+	LongRange  bool   `json:"long_range,omitempty"` // Beyond normal range: disadvantage
+	Thrown     bool   `json:"thrown,omitempty"`     // A melee weapon thrown at a target beyond reach
+}
+
+// CheckWeaponRange decides whether a weapon reaches a target distanceFt away. A melee
+// weapon beyond its reach is thrown if it can be; ranged weapons without a listed range
+// are always in range.
+func CheckWeaponRange(distanceFt int, ranged bool, properties []string) RangeCheck {
+	rc := RangeCheck{DistanceFt: distanceFt, Band: RangeBand(distanceFt)}
+	if !ranged && distanceFt <= WeaponReachFt(properties) {
+		rc.InRange = true
+		return rc
+	}
+	normal, long, ok := WeaponRange(properties)
+	if !ok {
+		rc.InRange = ranged
+		return rc
+	}
+	rc.Thrown = !ranged
+	rc.InRange = distanceFt <= long
+	rc.LongRange = rc.InRange && distanceFt > normal
+	return rc
+}
+
+var spellRangePattern = regexp.MustCompile(`^(\d+)\s*(feet|foot|ft|miles?)`)
+
+// SpellRangeFt reads an SRD spell range ("60 feet", "Touch", "Self", "1 mile"). limited
+// is false for ranges that can't be measured on the grid (Sight, Unlimited, Special).
+func SpellRangeFt(rangeText string) (ft int, limited bool) {
+	r := strings.ToLower(strings.TrimSpace(rangeText))
+	switch {
+	case strings.HasPrefix(r, "self"):
+		return 0, true
+	case strings.HasPrefix(r, "touch"):
+		return 5, true
+	}
+	if m := spellRangePattern.FindStringSubmatch(r); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if strings.HasPrefix(m[2], "mile") {
+			return n * 5280, true
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// InArea reports whether square p is inside an area of effect (PHB p204). Spheres and
+// cylinders are centered on origin. Cones and lines start at origin and point toward
+// aim; the origin square itself isn't included. A cube is centered on origin when aim
+// is the same square, otherwise it extends from origin's face toward aim.
+func InArea(shape string, sizeFt int, origin, aim, p GridPos) bool {
+	squares := sizeFt / 5
+	dx, dy := p.X-origin.X, p.Y-origin.Y
+	switch strings.ToLower(shape) {
+	case "sphere", "cylinder", "radius", "emanation":
+		return DistanceFt(origin, p) <= sizeFt
+	case "cube", "square":
+		if squares < 1 {
+			return false
+		}
+		sx, sy := sign(aim.X-origin.X), sign(aim.Y-origin.Y)
+		inSpan := func(d, s int) bool {
+			if s == 0 {
+				return d >= -(squares-1)/2 && d <= squares/2
+			}
+			return d*s >= 1 && d*s <= squares
+		}
+		return inSpan(dx, sx) && inSpan(dy, sy)
+	case "cone", "line":
+		if p == origin || aim == origin || DistanceFt(origin, p) > sizeFt {
+			return false
+		}
+		ux, uy := float64(aim.X-origin.X), float64(aim.Y-origin.Y)
+		vx, vy := float64(dx), float64(dy)
+		uLen := math.Hypot(ux, uy)
+		along := (vx*ux + vy*uy) / uLen
+		if along <= 0 {
+			return false
+		}
+		if strings.EqualFold(shape, "line") {
+			across := math.Abs(vx*uy-vy*ux) / uLen
+			return along <= float64(squares) && across <= 0.5
+		}
+		// A cone is as wide as it is long at every point: half-angle atan(1/2)
+		return along/math.Hypot(vx, vy) >= 2/math.Sqrt(5)-1e-9
+	}
+	return false
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// CombatantsInArea returns the IDs of placed combatants inside an area, in ascending order.
+func (m BattleMap) CombatantsInArea(shape string, sizeFt int, origin, aim GridPos) []int {
+	ids := []int{}
+	for id, pos := range m.Positions {
+		if InArea(shape, sizeFt, origin, aim, pos) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Occupant returns the combatant standing on a square, if any.
+func (m BattleMap) Occupant(p GridPos) (int, bool) {
+	for id, pos := range m.Positions {
+		if pos == p {
+			return id, true
+		}
+	}
+	return 0, false
+}
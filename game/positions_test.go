@@ -0,0 +1,103 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistanceAndBand(t *testing.T) {
+	tests := []struct {
+		a, b GridPos
+		ft   int
+		band string
+	}{
+		{GridPos{0, 0}, GridPos{1, 1}, 5, RangeEngaged},
+		{GridPos{0, 0}, GridPos{6, 2}, 30, RangeNear},
+		{GridPos{2, 0}, GridPos{-5, 3}, 35, RangeFar},
+	}
+	for _, tt := range tests {
+		ft := DistanceFt(tt.a, tt.b)
+		if ft != tt.ft || RangeBand(ft) != tt.band {
+			t.Errorf("%v -> %v = %d ft %s, want %d ft %s", tt.a, tt.b, ft, RangeBand(ft), tt.ft, tt.band)
+		}
+	}
+}
+
+func TestCheckWeaponRange(t *testing.T) {
+	longbow := []string{"ammunition (150/600)", "heavy", "two-handed"}
+	dagger := []string{"finesse", "light", "thrown (20/60)"}
+	glaive := []string{"heavy", "reach", "two-handed"}
+	tests := []struct {
+		name     string
+		dist     int
+		ranged   bool
+		props    []string
+		in, long bool
+		thrown   bool
+	}{
+		{"longbow normal", 100, true, longbow, true, false, false},
+		{"longbow long", 300, true, longbow, true, true, false},
+		{"longbow beyond", 605, true, longbow, false, false, false},
+		{"dagger melee", 5, false, dagger, true, false, false},
+		{"dagger thrown", 15, false, dagger, true, false, true},
+		{"dagger thrown long", 40, false, dagger, true, true, true},
+		{"glaive reach", 10, false, glaive, true, false, false},
+		{"glaive too far", 15, false, glaive, false, false, false},
+	}
+	for _, tt := range tests {
+		rc := CheckWeaponRange(tt.dist, tt.ranged, tt.props)
+		if rc.InRange != tt.in || rc.LongRange != tt.long || rc.Thrown != tt.thrown {
+			t.Errorf("%s: got %+v", tt.name, rc)
+		}
+	}
+}
+
+func TestSpellRangeFt(t *testing.T) {
+	tests := []struct {
+		text    string
+		ft      int
+		limited bool
+	}{
+		{"150 feet", 150, true},
+		{"Touch", 5, true},
+		{"Self (15-foot cone)", 0, true},
+		{"1 mile", 5280, true},
+		{"Sight", 0, false},
+	}
+	for _, tt := range tests {
+		if ft, limited := SpellRangeFt(tt.text); ft != tt.ft || limited != tt.limited {
+			t.Errorf("SpellRangeFt(%q) = %d, %v; want %d, %v", tt.text, ft, limited, tt.ft, tt.limited)
+		}
+	}
+}
+
+func TestCombatantsInArea(t *testing.T) {
+	m := BattleMap{Positions: map[int]GridPos{
+		1:  {0, 0},  // caster
+		2:  {1, 0},  // adjacent east
+		-1: {3, 0},  // 15 ft east
+		-2: {3, 1},  // 15 ft east, one square off the axis
+		-3: {2, 2},  // diagonal
+		-4: {0, 4},  // 20 ft south
+		-5: {-1, 0}, // behind the caster
+	}}
+	tests := []struct {
+		name   string
+		shape  string
+		size   int
+		origin GridPos
+		aim    GridPos
+		want   []int
+	}{
+		{"fireball on the caster's ally", "sphere", 15, GridPos{2, 0}, GridPos{2, 0}, []int{-5, -3, -2, -1, 1, 2}},
+		{"burning hands east", "cone", 15, GridPos{0, 0}, GridPos{3, 0}, []int{-2, -1, 2}},
+		{"lightning bolt east", "line", 100, GridPos{0, 0}, GridPos{5, 0}, []int{-1, 2}},
+		{"thunderwave east", "cube", 15, GridPos{0, 0}, GridPos{1, 0}, []int{-2, -1, 2}},
+		{"centered cube", "cube", 15, GridPos{0, 0}, GridPos{0, 0}, []int{-5, 1, 2}},
+	}
+	for _, tt := range tests {
+		if got := m.CombatantsInArea(tt.shape, tt.size, tt.origin, tt.aim); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
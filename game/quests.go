@@ -0,0 +1,245 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// quests.go - quest objectives and prerequisites: quests made of checkbox steps, quests
+// that stay locked until the ones before them are done, and the dependency graph
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Quest statuses. A locked quest is waiting on its prerequisites.
+const (
+	QuestHidden    = "hidden"
+	QuestLocked    = "locked"
+	QuestActive    = "active"
+	QuestCompleted = "completed"
+	QuestFailed    = "failed"
+)
+
+// QuestStatuses are the valid quest statuses.
+var QuestStatuses = []string{QuestHidden, QuestLocked, QuestActive, QuestCompleted, QuestFailed}
+
+// QuestObjective is one step of a quest. Optional steps don't hold up completion.
+type QuestObjective struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Done     bool   `json:"done"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// Quest is the part of a campaign document quest that objectives and prerequisites use.
+// Prerequisites are the IDs of quests that must be completed first.
+type Quest struct {
+	ID            string           `json:"id"`
+	Title         string           `json:"title"`
+	Status        string           `json:"status"`
+	Prerequisites []string         `json:"prerequisites,omitempty"`
+	Objectives    []QuestObjective `json:"objectives,omitempty"`
+}
+
+// NumberObjectives gives objectives without an ID the next free "obj-N".
+func NumberObjectives(objectives []QuestObjective) {
+	used := map[string]bool{}
+	for _, o := range objectives {
+		used[o.ID] = true
+	}
+	n := 1
+	for i := range objectives {
+		if objectives[i].ID != "" {
+			continue
+		}
+		for used[fmt.Sprintf("obj-%d", n)] {
+			n++
+		}
+		objectives[i].ID = fmt.Sprintf("obj-%d", n)
+		used[objectives[i].ID] = true
+	}
+}
+
+// Progress counts the required objectives done and in total.
+func (q Quest) Progress() (done, total int) {
+	for _, o := range q.Objectives {
+		if o.Optional {
+			continue
+		}
+		total++
+		if o.Done {
+			done++
+		}
+	}
+	return done, total
+}
+
+// ObjectivesDone reports whether the quest has required objectives and all are done.
+func (q Quest) ObjectivesDone() bool {
+	done, total := q.Progress()
+	return total > 0 && done == total
+}
+
+// PrerequisitesMet reports whether every prerequisite is completed. A prerequisite
+// that's no longer in the list doesn't hold the quest back.
+func (q Quest) PrerequisitesMet(quests []Quest) bool {
+	status := questStatuses(quests)
+	for _, p := range q.Prerequisites {
+		if s, ok := status[p]; ok && s != QuestCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+func questStatuses(quests []Quest) map[string]string {
+	status := make(map[string]string, len(quests))
+	for _, q := range quests {
+		status[q.ID] = q.Status
+	}
+	return status
+}
+
+// QuestTransition is a status change made by AdvanceQuests.
+type QuestTransition struct {
+	QuestID string `json:"quest_id"`
+	Title   string `json:"title"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// AdvanceQuests makes the automatic status changes until none are left: an active quest
+// whose required objectives are all done is completed, and a locked quest whose
+// prerequisites are all completed becomes active. It returns the changes in order.
+func AdvanceQuests(quests []Quest) []QuestTransition {
+	var transitions []QuestTransition
+	for changed := true; changed; {
+		changed = false
+		for i := range quests {
+			q := &quests[i]
+			to := ""
+			switch {
+			case q.Status == QuestActive && q.ObjectivesDone():
+				to = QuestCompleted
+			case q.Status == QuestLocked && q.PrerequisitesMet(quests):
+				to = QuestActive
+			}
+			if to != "" {
+				transitions = append(transitions, QuestTransition{QuestID: q.ID, Title: q.Title, From: q.Status, To: to})
+				q.Status = to
+				changed = true
+			}
+		}
+	}
+	return transitions
+}
+
+// QuestPrerequisiteCycle reports whether giving quest id these prerequisites would make
+// it depend on itself.
+func QuestPrerequisiteCycle(quests []Quest, id string, prerequisites []string) bool {
+	prereqs := make(map[string][]string, len(quests))
+	for _, q := range quests {
+		prereqs[q.ID] = q.Prerequisites
+	}
+	seen := map[string]bool{}
+	stack := append([]string{}, prerequisites...)
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if p == id {
+			return true
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		stack = append(stack, prereqs[p]...)
+	}
+	return false
+}
+
+// QuestGraphNode is one quest in the dependency graph. Depth is the length of the
+// longest chain of prerequisites before it; Blocked means a prerequisite failed (or is
+// itself blocked), so it can't unlock without the GM stepping in.
+type QuestGraphNode struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Status        string   `json:"status"`
+	Depth         int      `json:"depth"`
+	Prerequisites []string `json:"prerequisites"`
+	Unlocks       []string `json:"unlocks"`
+	Objectives    string   `json:"objectives,omitempty"` // "2/3" required steps done
+	Blocked       bool     `json:"blocked,omitempty"`
+}
+
+// QuestGraph is the quest dependency tree: every quest with its links, the quests with
+// no prerequisites, and the quests grouped by depth.
+type QuestGraph struct {
+	Nodes  []QuestGraphNode `json:"nodes"`
+	Roots  []string         `json:"roots"`
+	Layers [][]string       `json:"layers"`
+}
+
+// BuildQuestGraph works out the dependency graph. Links to quests that aren't in the
+// list are dropped, and a loop (which the API refuses, but an edited document could hold)
+// is cut where it's found.
+func BuildQuestGraph(quests []Quest) QuestGraph {
+	index := make(map[string]int, len(quests))
+	for i, q := range quests {
+		index[q.ID] = i
+	}
+	nodes := make([]QuestGraphNode, len(quests))
+	for i, q := range quests {
+		nodes[i] = QuestGraphNode{ID: q.ID, Title: q.Title, Status: q.Status, Prerequisites: []string{}, Unlocks: []string{}}
+		if done, total := q.Progress(); total > 0 {
+			nodes[i].Objectives = fmt.Sprintf("%d/%d", done, total)
+		}
+	}
+	for i, q := range quests {
+		for _, p := range q.Prerequisites {
+			if j, ok := index[p]; ok && j != i {
+				nodes[i].Prerequisites = append(nodes[i].Prerequisites, p)
+				nodes[j].Unlocks = append(nodes[j].Unlocks, q.ID)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(nodes))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		for _, p := range nodes[i].Prerequisites {
+			j := index[p]
+			if state[j] == visiting {
+				continue // loop
+			}
+			if state[j] == unvisited {
+				visit(j)
+			}
+			nodes[i].Depth = max(nodes[i].Depth, nodes[j].Depth+1)
+			if nodes[j].Status == QuestFailed || nodes[j].Blocked {
+				nodes[i].Blocked = nodes[i].Status == QuestLocked || nodes[i].Status == QuestHidden
+			}
+		}
+		state[i] = visited
+	}
+
+	graph := QuestGraph{Nodes: nodes, Roots: []string{}, Layers: [][]string{}}
+	for i := range nodes {
+		if state[i] == unvisited {
+			visit(i)
+		}
+		if len(nodes[i].Prerequisites) == 0 {
+			graph.Roots = append(graph.Roots, nodes[i].ID)
+		}
+		for len(graph.Layers) <= nodes[i].Depth {
+			graph.Layers = append(graph.Layers, []string{})
+		}
+		graph.Layers[nodes[i].Depth] = append(graph.Layers[nodes[i].Depth], nodes[i].ID)
+	}
+	sort.SliceStable(graph.Nodes, func(a, b int) bool { return graph.Nodes[a].Depth < graph.Nodes[b].Depth })
+	return graph
+}
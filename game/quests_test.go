@@ -0,0 +1,122 @@
+package game
+
+import "testing"
+
+func TestNumberObjectives(t *testing.T) {
+	objs := []QuestObjective{{Text: "a"}, {ID: "obj-1", Text: "b"}, {Text: "c"}}
+	NumberObjectives(objs)
+	if objs[0].ID != "obj-2" || objs[2].ID != "obj-3" {
+		t.Errorf("ids = %q %q %q", objs[0].ID, objs[1].ID, objs[2].ID)
+	}
+}
+
+func TestQuestProgress(t *testing.T) {
+	q := Quest{Objectives: []QuestObjective{{Done: true}, {Done: false, Optional: true}, {Done: true}}}
+	if done, total := q.Progress(); done != 2 || total != 2 {
+		t.Errorf("Progress = %d/%d, want 2/2", done, total)
+	}
+	if !q.ObjectivesDone() {
+		t.Error("optional step shouldn't hold up completion")
+	}
+	if (Quest{}).ObjectivesDone() {
+		t.Error("a quest without objectives isn't done by them")
+	}
+}
+
+func TestAdvanceQuestsChain(t *testing.T) {
+	quests := []Quest{
+		{ID: "c", Title: "C", Status: QuestLocked, Prerequisites: []string{"b"}},
+		{ID: "b", Title: "B", Status: QuestLocked, Prerequisites: []string{"a"}, Objectives: []QuestObjective{{ID: "obj-1", Done: true}}},
+		{ID: "a", Title: "A", Status: QuestActive, Objectives: []QuestObjective{{ID: "obj-1", Done: true}}},
+	}
+	transitions := AdvanceQuests(quests)
+	// A completes, B unlocks and (its objectives already done) completes, C unlocks
+	if len(transitions) != 4 {
+		t.Fatalf("transitions = %+v", transitions)
+	}
+	if quests[0].Status != QuestActive || quests[1].Status != QuestCompleted || quests[2].Status != QuestCompleted {
+		t.Errorf("statuses = %s %s %s", quests[0].Status, quests[1].Status, quests[2].Status)
+	}
+	if AdvanceQuests(quests) != nil {
+		t.Error("second pass should change nothing")
+	}
+}
+
+func TestAdvanceQuestsWaitsForAllPrerequisites(t *testing.T) {
+	quests := []Quest{
+		{ID: "a", Status: QuestCompleted},
+		{ID: "b", Status: QuestFailed},
+		{ID: "c", Status: QuestLocked, Prerequisites: []string{"a", "b"}},
+		{ID: "d", Status: QuestLocked, Prerequisites: []string{"a", "gone"}},
+	}
+	AdvanceQuests(quests)
+	if quests[2].Status != QuestLocked {
+		t.Errorf("c = %s, want locked behind a failed prerequisite", quests[2].Status)
+	}
+	if quests[3].Status != QuestActive {
+		t.Errorf("d = %s, a missing prerequisite shouldn't hold it", quests[3].Status)
+	}
+}
+
+func TestQuestPrerequisiteCycle(t *testing.T) {
+	quests := []Quest{
+		{ID: "a"},
+		{ID: "b", Prerequisites: []string{"a"}},
+		{ID: "c", Prerequisites: []string{"b"}},
+	}
+	if !QuestPrerequisiteCycle(quests, "a", []string{"c"}) {
+		t.Error("a after c after b after a is a loop")
+	}
+	if !QuestPrerequisiteCycle(quests, "a", []string{"a"}) {
+		t.Error("a quest can't require itself")
+	}
+	if QuestPrerequisiteCycle(quests, "c", []string{"a"}) {
+		t.Error("c after a is fine")
+	}
+}
+
+func TestBuildQuestGraph(t *testing.T) {
+	quests := []Quest{
+		{ID: "finale", Status: QuestLocked, Prerequisites: []string{"b", "c"}},
+		{ID: "a", Status: QuestFailed},
+		{ID: "b", Status: QuestLocked, Prerequisites: []string{"a"}},
+		{ID: "c", Status: QuestActive, Objectives: []QuestObjective{{Done: true}, {}}},
+		{ID: "x", Status: QuestActive, Prerequisites: []string{"missing"}},
+	}
+	g := BuildQuestGraph(quests)
+	nodes := map[string]QuestGraphNode{}
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	if nodes["finale"].Depth != 2 || nodes["b"].Depth != 1 || nodes["c"].Depth != 0 {
+		t.Errorf("depths: finale %d, b %d, c %d", nodes["finale"].Depth, nodes["b"].Depth, nodes["c"].Depth)
+	}
+	if !nodes["b"].Blocked || !nodes["finale"].Blocked || nodes["c"].Blocked {
+		t.Error("a failed quest blocks what depends on it, directly or not")
+	}
+	if nodes["c"].Objectives != "1/2" {
+		t.Errorf("c objectives = %q", nodes["c"].Objectives)
+	}
+	if len(nodes["a"].Unlocks) != 1 || nodes["a"].Unlocks[0] != "b" {
+		t.Errorf("a unlocks %v", nodes["a"].Unlocks)
+	}
+	if len(nodes["x"].Prerequisites) != 0 {
+		t.Error("links to missing quests are dropped")
+	}
+	if len(g.Roots) != 3 || len(g.Layers) != 3 || len(g.Layers[2]) != 1 {
+		t.Errorf("roots %v layers %v", g.Roots, g.Layers)
+	}
+	if g.Nodes[len(g.Nodes)-1].ID != "finale" {
+		t.Error("nodes should be ordered by depth")
+	}
+}
+
+func TestBuildQuestGraphSurvivesLoop(t *testing.T) {
+	g := BuildQuestGraph([]Quest{
+		{ID: "a", Prerequisites: []string{"b"}},
+		{ID: "b", Prerequisites: []string{"a"}},
+	})
+	if len(g.Nodes) != 2 {
+		t.Errorf("nodes = %+v", g.Nodes)
+	}
+}
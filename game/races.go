@@ -2,7 +2,10 @@
 // races.go handles 5e racial features and traits.
 package game
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // Race constants for the PHB/SRD races
 const (
@@ -168,6 +171,42 @@ func HasBreathWeapon(race string) bool {
 	return IsDragonborn(race)
 }
 
+// HasTrance returns true if the race has the Trance trait (PHB p23).
+// Elves meditate 4 hours instead of sleeping 8 and can't be magically put to sleep.
+func HasTrance(race string) bool {
+	r := normalizeRace(race)
+	return r == RaceDrow || IsElf(race) && !strings.Contains(r, "half")
+}
+
+// IsVariantHuman returns true for "Variant Human" / "variant_human" (PHB p31).
+func IsVariantHuman(race string) bool {
+	return normalizeRace(race) == "variant_human"
+}
+
+// VariantHumanIssues validates Variant Human choices (PHB p31): +1 to two different
+// abilities, one skill and one feat. Returns one message per problem, empty if valid.
+// Feat prerequisites depend on final scores and are checked by the caller.
+func VariantHumanIssues(abilities []string, skill, feat string) []string {
+	issues := []string{}
+	if len(abilities) != 2 {
+		issues = append(issues, fmt.Sprintf("choose exactly 2 abilities to increase by 1 (got %d)", len(abilities)))
+	} else {
+		a, b := NormalizeAbility(abilities[0]), NormalizeAbility(abilities[1])
+		if a == "" || b == "" {
+			issues = append(issues, fmt.Sprintf("unknown ability in %v (use str, dex, con, int, wis or cha)", abilities))
+		} else if a == b {
+			issues = append(issues, "the two ability increases must be different abilities")
+		}
+	}
+	if strings.TrimSpace(skill) == "" {
+		issues = append(issues, "choose 1 skill proficiency")
+	}
+	if GetFeat(strings.ToLower(strings.TrimSpace(feat))) == nil {
+		issues = append(issues, fmt.Sprintf("unknown feat %q", feat))
+	}
+	return issues
+}
+
 // GetRaceSize returns the size category for a race (PHB sizes).
 // Returns "Small" for Halflings and Gnomes, "Medium" for all others.
 func GetRaceSize(race string) string {
@@ -212,7 +251,7 @@ func SizeOrder(size string) int {
 		SizeHuge:       5,
 		SizeGargantuan: 6,
 	}
-	if o, ok := order[size]; ok {
+	if o, ok := order[NormalizeSize(size)]; ok {
 		return o
 	}
 	return 3 // Default to Medium
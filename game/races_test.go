@@ -439,3 +439,37 @@ func TestDragonAncestries(t *testing.T) {
 		t.Errorf("Black dragon should be acid/5x30ft line, got %s/%s", black.DamageType, black.BreathArea)
 	}
 }
+
+func TestHasTrance(t *testing.T) {
+	for race, want := range map[string]bool{"Elf": true, "wood-elf": true, "Drow": true, "Half-Elf": false, "Human": false} {
+		if got := HasTrance(race); got != want {
+			t.Errorf("HasTrance(%q) = %v, want %v", race, got, want)
+		}
+	}
+}
+
+func TestVariantHumanIssues(t *testing.T) {
+	tests := []struct {
+		name      string
+		abilities []string
+		skill     string
+		feat      string
+		issues    int
+	}{
+		{"valid", []string{"str", "Constitution"}, "athletics", "tough", 0},
+		{"one ability", []string{"str"}, "athletics", "tough", 1},
+		{"same ability twice", []string{"dex", "dexterity"}, "stealth", "alert", 1},
+		{"unknown ability", []string{"dex", "luck"}, "stealth", "alert", 1},
+		{"no skill", []string{"dex", "wis"}, "", "alert", 1},
+		{"unknown feat", []string{"dex", "wis"}, "stealth", "lucky_charm", 1},
+		{"nothing chosen", nil, "", "", 3},
+	}
+	for _, tt := range tests {
+		if got := VariantHumanIssues(tt.abilities, tt.skill, tt.feat); len(got) != tt.issues {
+			t.Errorf("%s: VariantHumanIssues = %v, want %d issues", tt.name, got, tt.issues)
+		}
+	}
+	if !IsVariantHuman("Variant Human") || IsVariantHuman("Human") {
+		t.Error("IsVariantHuman returned the wrong answer")
+	}
+}
@@ -0,0 +1,112 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// readied.go - the Ready action (PHB p193): what can be readied and which
+// environmental events a readied trigger waits for
+package game
+
+import "strings"
+
+// Readied trigger categories. Creature triggers ("when the goblin steps through") are
+// fired by the GM or the player; environmental ones fire from a GM event.
+const (
+	ReadyTriggerCreature    = "creature"
+	ReadyTriggerDoorOpens   = "door_opens"
+	ReadyTriggerDoorCloses  = "door_closes"
+	ReadyTriggerLeverPulled = "lever_pulled"
+	ReadyTriggerTrapSprung  = "trap_sprung"
+	ReadyTriggerLightsOut   = "lights_out"
+)
+
+// ReadyTriggerEvents describes the environmental events a readied action can wait for.
+var ReadyTriggerEvents = map[string]string{
+	ReadyTriggerDoorOpens:   "A door, gate or portcullis opens",
+	ReadyTriggerDoorCloses:  "A door, gate or portcullis closes",
+	ReadyTriggerLeverPulled: "A lever, switch or winch is worked",
+	ReadyTriggerTrapSprung:  "A trap or pressure plate goes off",
+	ReadyTriggerLightsOut:   "The lights go out (torches doused, darkness falls)",
+}
+
+// IsReadyTriggerEvent reports whether event is a known environmental trigger event.
+func IsReadyTriggerEvent(event string) bool {
+	_, ok := ReadyTriggerEvents[event]
+	return ok
+}
+
+// readyTriggerKeywords lists, per event, word groups that must all appear in a trigger.
+// Checked in order; the first match wins.
+var readyTriggerKeywords = []struct {
+	event string
+	words [][]string
+}{
+	{ReadyTriggerDoorCloses, [][]string{{"door", "close"}, {"door", "shut"}, {"gate", "close"}, {"gate", "shut"}, {"portcullis", "drop"}, {"portcullis", "close"}}},
+	{ReadyTriggerDoorOpens, [][]string{{"door", "open"}, {"gate", "open"}, {"portcullis", "rise"}, {"portcullis", "open"}}},
+	{ReadyTriggerLeverPulled, [][]string{{"lever"}, {"switch"}, {"winch"}}},
+	{ReadyTriggerTrapSprung, [][]string{{"trap"}, {"pressure plate"}, {"tripwire"}}},
+	{ReadyTriggerLightsOut, [][]string{{"lights go out"}, {"light goes out"}, {"torch", "out"}, {"darkness falls"}, {"goes dark"}}},
+}
+
+// ClassifyReadyTrigger maps a free-text trigger to a trigger category. A trigger that
+// names an event key directly ("lever_pulled") gets that event; otherwise keywords decide,
+// and anything else is a creature trigger.
+func ClassifyReadyTrigger(trigger string) string {
+	t := strings.ToLower(trigger)
+	if e := strings.TrimSpace(t); IsReadyTriggerEvent(e) {
+		return e
+	}
+	for _, k := range readyTriggerKeywords {
+		for _, group := range k.words {
+			all := true
+			for _, w := range group {
+				if !strings.Contains(t, w) {
+					all = false
+					break
+				}
+			}
+			if all {
+				return k.event
+			}
+		}
+	}
+	return ReadyTriggerCreature
+}
+
+// readyActionKeywords maps what a readied action says to the action it resolves as.
+// Actions are checked before movement and interactions so "attack whoever opens the
+// door" readies an attack rather than an interaction.
+var readyActionKeywords = []struct {
+	action string
+	words  []string
+}{
+	{"attack", []string{"attack"}},
+	{"cast", []string{"cast"}},
+	{"dash", []string{"dash"}},
+	{"disengage", []string{"disengage"}},
+	{"help", []string{"help"}},
+	{"hide", []string{"hide"}},
+	{"move", []string{"move", "run ", "step", "rush", "retreat", "fall back", "dive"}},
+	{"interact", []string{"interact", "open", "close", "shut", "slam", "pull", "push", "hold the", "grab", "lever", "bar the"}},
+}
+
+// ReadyActionType returns the action a readied description resolves as: attack, cast,
+// dash, disengage, help, hide, move (up to your speed) or interact (one object
+// interaction, like slamming a door). Returns "other" if nothing matches.
+func ReadyActionType(description string) string {
+	d := strings.ToLower(description) + " "
+	for _, k := range readyActionKeywords {
+		for _, w := range k.words {
+			if strings.Contains(d, w) {
+				return k.action
+			}
+		}
+	}
+	return "other"
+}
+
+// ReadiedTriggerType is the trigger category of a stored readied action. Readied actions
+// stored before trigger types existed are classified from their trigger text.
+func ReadiedTriggerType(readied map[string]string) string {
+	if t := readied["trigger_type"]; t != "" {
+		return t
+	}
+	return ClassifyReadyTrigger(readied["trigger"])
+}
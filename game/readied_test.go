@@ -0,0 +1,58 @@
+package game
+
+import "testing"
+
+func TestClassifyReadyTrigger(t *testing.T) {
+	tests := []struct {
+		trigger string
+		want    string
+	}{
+		{"when the door opens", ReadyTriggerDoorOpens},
+		{"When the vault door is shut", ReadyTriggerDoorCloses},
+		{"the portcullis drops", ReadyTriggerDoorCloses},
+		{"someone pulls the lever", ReadyTriggerLeverPulled},
+		{"lever_pulled", ReadyTriggerLeverPulled},
+		{"the pressure plate clicks", ReadyTriggerTrapSprung},
+		{"when the lights go out", ReadyTriggerLightsOut},
+		{"when the goblin comes within reach", ReadyTriggerCreature},
+	}
+	for _, tt := range tests {
+		if got := ClassifyReadyTrigger(tt.trigger); got != tt.want {
+			t.Errorf("ClassifyReadyTrigger(%q) = %q, want %q", tt.trigger, got, tt.want)
+		}
+	}
+	if IsReadyTriggerEvent(ReadyTriggerCreature) {
+		t.Error("creature triggers aren't environmental events")
+	}
+}
+
+func TestReadyActionType(t *testing.T) {
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"attack with my longsword", "attack"},
+		{"attack whoever opens the door", "attack"},
+		{"cast fire bolt at it", "cast"},
+		{"move to the doorway", "move"},
+		{"run for the exit", "move"},
+		{"slam the door shut", "interact"},
+		{"hold the door", "interact"},
+		{"pull the lever", "interact"},
+		{"shout a warning", "other"},
+	}
+	for _, tt := range tests {
+		if got := ReadyActionType(tt.description); got != tt.want {
+			t.Errorf("ReadyActionType(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestReadiedTriggerType(t *testing.T) {
+	if got := ReadiedTriggerType(map[string]string{"trigger": "when the door opens", "trigger_type": ReadyTriggerCreature}); got != ReadyTriggerCreature {
+		t.Errorf("a stored trigger type wins, got %q", got)
+	}
+	if got := ReadiedTriggerType(map[string]string{"trigger": "when the lever is pulled"}); got != ReadyTriggerLeverPulled {
+		t.Errorf("older readied actions are classified from their trigger, got %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// revival.go - spells that return the dead to life (Revivify, Raise Dead, Reincarnate, Resurrection)
+package game
+
+import "strings"
+
+// Time limits for revival spells, in seconds of in-game time since death.
+const (
+	RevivalLimitOneMinute   = 60
+	RevivalLimitTenDays     = 10 * 24 * 60 * 60
+	RevivalLimitHundredYear = 100 * 365 * 24 * 60 * 60
+)
+
+// RevivalSpell describes a spell that restores a dead creature to life.
+type RevivalSpell struct {
+	Name         string `json:"name"`
+	Level        int    `json:"level"`
+	CostGP       int    `json:"cost_gp"`    // Consumed material component value
+	Material     string `json:"material"`   // Material component description
+	TimeLimit    int    `json:"time_limit"` // Seconds since death; the spell fails beyond this
+	TimeLimitStr string `json:"time_limit_str"`
+	Penalty      int    `json:"penalty"`  // Penalty to attacks, saves, and checks; shrinks by 1 per long rest
+	FullHP       bool   `json:"full_hp"`  // Returns at max HP instead of 1 HP
+	NewBody      bool   `json:"new_body"` // Reincarnate: the soul returns in a randomly rolled race
+}
+
+// RevivalSpells are the supported revival spells, keyed by spell slug.
+var RevivalSpells = map[string]RevivalSpell{
+	"revivify": {
+		Name: "Revivify", Level: 3, CostGP: 300, Material: "Diamonds worth 300 gp, which the spell consumes",
+		TimeLimit: RevivalLimitOneMinute, TimeLimitStr: "1 minute",
+	},
+	"raise-dead": {
+		Name: "Raise Dead", Level: 5, CostGP: 500, Material: "A diamond worth at least 500 gp, which the spell consumes",
+		TimeLimit: RevivalLimitTenDays, TimeLimitStr: "10 days", Penalty: 4,
+	},
+	"reincarnate": {
+		Name: "Reincarnate", Level: 5, CostGP: 1000, Material: "Rare oils and unguents worth at least 1,000 gp, which the spell consumes",
+		TimeLimit: RevivalLimitTenDays, TimeLimitStr: "10 days", FullHP: true, NewBody: true,
+	},
+	"resurrection": {
+		Name: "Resurrection", Level: 7, CostGP: 1000, Material: "A diamond worth at least 1,000 gp, which the spell consumes",
+		TimeLimit: RevivalLimitHundredYear, TimeLimitStr: "100 years", Penalty: 4, FullHP: true,
+	},
+}
+
+// NormalizeRevivalSpell converts a spell name ("Raise Dead", "raise_dead") to its slug.
+// Returns "" if the spell is not a supported revival spell.
+func NormalizeRevivalSpell(name string) string {
+	slug := strings.ToLower(strings.TrimSpace(name))
+	slug = strings.NewReplacer(" ", "-", "_", "-").Replace(slug)
+	if _, ok := RevivalSpells[slug]; !ok {
+		return ""
+	}
+	return slug
+}
+
+// RevivalInTime reports whether a creature dead for secondsSinceDeath can still be
+// returned by the spell.
+func RevivalInTime(spell RevivalSpell, secondsSinceDeath int) bool {
+	return secondsSinceDeath >= 0 && secondsSinceDeath <= spell.TimeLimit
+}
+
+// ReincarnateRace returns the race key for a d100 roll on the Reincarnate table (PHB p271),
+// mapped onto the races this game supports.
+func ReincarnateRace(roll int) string {
+	switch {
+	case roll <= 4:
+		return "dragonborn"
+	case roll <= 13:
+		return "hill_dwarf"
+	case roll <= 21:
+		return "dwarf"
+	case roll <= 25:
+		return "elf"
+	case roll <= 34:
+		return "high_elf"
+	case roll <= 42:
+		return "elf"
+	case roll <= 52:
+		return "gnome"
+	case roll <= 56:
+		return "half_elf"
+	case roll <= 60:
+		return "half_orc"
+	case roll <= 76:
+		return "halfling"
+	case roll <= 96:
+		return "human"
+	}
+	return "tiefling"
+}
+
+// RevivalPenaltyAfterLongRest returns the remaining revival penalty after a long rest.
+// Raise Dead and Resurrection impose -4 that shrinks by 1 each long rest (PHB p270, p272).
+func RevivalPenaltyAfterLongRest(penalty int) int {
+	if penalty <= 1 {
+		return 0
+	}
+	return penalty - 1
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestNormalizeRevivalSpell(t *testing.T) {
+	tests := map[string]string{
+		"Revivify":     "revivify",
+		"Raise Dead":   "raise-dead",
+		"raise_dead":   "raise-dead",
+		"resurrection": "resurrection",
+		"cure wounds":  "",
+	}
+	for in, want := range tests {
+		if got := NormalizeRevivalSpell(in); got != want {
+			t.Errorf("NormalizeRevivalSpell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRevivalInTime(t *testing.T) {
+	tests := []struct {
+		spell    string
+		seconds  int
+		expected bool
+	}{
+		{"revivify", 0, true},
+		{"revivify", 60, true},
+		{"revivify", 66, false},
+		{"raise-dead", 9 * 24 * 60 * 60, true},
+		{"raise-dead", 11 * 24 * 60 * 60, false},
+		{"reincarnate", 10 * 24 * 60 * 60, true},
+		{"resurrection", 50 * 365 * 24 * 60 * 60, true},
+		{"revivify", -6, false},
+	}
+	for _, tt := range tests {
+		if got := RevivalInTime(RevivalSpells[tt.spell], tt.seconds); got != tt.expected {
+			t.Errorf("RevivalInTime(%s, %d) = %v, want %v", tt.spell, tt.seconds, got, tt.expected)
+		}
+	}
+}
+
+func TestReincarnateRace(t *testing.T) {
+	tests := map[int]string{
+		1:   "dragonborn",
+		13:  "hill_dwarf",
+		30:  "high_elf",
+		55:  "half_elf",
+		80:  "human",
+		100: "tiefling",
+	}
+	for roll, want := range tests {
+		if got := ReincarnateRace(roll); got != want {
+			t.Errorf("ReincarnateRace(%d) = %q, want %q", roll, got, want)
+		}
+	}
+}
+
+func TestRevivalPenaltyAfterLongRest(t *testing.T) {
+	penalty := RevivalSpells["raise-dead"].Penalty
+	for _, want := range []int{3, 2, 1, 0, 0} {
+		penalty = RevivalPenaltyAfterLongRest(penalty)
+		if penalty != want {
+			t.Fatalf("RevivalPenaltyAfterLongRest() = %d, want %d", penalty, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// shop.go - equipment prices, selling at half price and paying with mixed coins
+// (PHB p143-150, DMG p135)
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CoinValues is what each coin is worth in copper (PHB p143).
+var CoinValues = map[string]int{"cp": 1, "sp": 10, "ep": 50, "gp": 100, "pp": 1000}
+
+// Purse is a character's coins.
+type Purse struct {
+	CP int `json:"cp"`
+	SP int `json:"sp"`
+	EP int `json:"ep"`
+	GP int `json:"gp"`
+	PP int `json:"pp"`
+}
+
+// TotalCP is the purse's worth in copper.
+func (p Purse) TotalCP() int {
+	return p.CP + p.SP*10 + p.EP*50 + p.GP*100 + p.PP*1000
+}
+
+// Pay spends copper's worth of coins, largest coins first. When the coins on hand don't
+// add up exactly, one larger coin is broken and the change comes back in gp, sp and cp,
+// the way a merchant makes it. ok is false if the purse can't cover the price.
+func (p Purse) Pay(copper int) (Purse, bool) {
+	if copper < 0 || p.TotalCP() < copper {
+		return p, false
+	}
+	coins := []*int{&p.PP, &p.GP, &p.EP, &p.SP, &p.CP}
+	values := []int{1000, 100, 50, 10, 1}
+	used := make([]int, len(coins))
+	remaining := copper
+	for i, c := range coins {
+		used[i] = min(*c, remaining/values[i])
+		remaining -= used[i] * values[i]
+	}
+	for i, c := range coins {
+		*c -= used[i]
+	}
+	if remaining > 0 {
+		// Every coin left is worth more than what's still owed: break the smallest
+		for i := len(coins) - 1; i >= 0; i-- {
+			if *coins[i] > 0 {
+				*coins[i]--
+				p = p.Receive(values[i] - remaining)
+				break
+			}
+		}
+	}
+	return p, true
+}
+
+// Receive adds copper's worth of coins as gp, sp and cp.
+func (p Purse) Receive(copper int) Purse {
+	if copper <= 0 {
+		return p
+	}
+	p.GP += copper / 100
+	p.SP += copper % 100 / 10
+	p.CP += copper % 10
+	return p
+}
+
+// FormatCP writes an amount of copper in the coins a price list would use: "15 gp",
+// "2 sp", "1 gp 5 sp".
+func FormatCP(copper int) string {
+	if copper == 0 {
+		return "0 cp"
+	}
+	parts := []string{}
+	for _, c := range []struct {
+		name  string
+		value int
+	}{{"gp", 100}, {"sp", 10}, {"cp", 1}} {
+		if n := copper / c.value; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, c.name))
+			copper %= c.value
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var pricePattern = regexp.MustCompile(`^([\d,]+)\s*(cp|sp|ep|gp|pp)$`)
+
+// ParsePrice reads a price like "50 gp" or "1,000 gp" into copper.
+func ParsePrice(price string) (copper int, ok bool) {
+	m := pricePattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(price)))
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+	if err != nil {
+		return 0, false
+	}
+	return n * CoinValues[m[2]], true
+}
+
+// SellPrice is what a merchant pays for equipment: half its price (PHB p144).
+func SellPrice(copper int) int {
+	return copper / 2
+}
+
+// weaponPricesCP are the PHB weapon prices (p149) in copper, by SRD slug.
+var weaponPricesCP = map[string]int{
+	// Simple melee
+	"club": 10, "dagger": 200, "greatclub": 20, "handaxe": 500, "javelin": 50,
+	"light-hammer": 200, "mace": 500, "quarterstaff": 20, "sickle": 100, "spear": 100,
+	// Simple ranged
+	"crossbow-light": 2500, "dart": 5, "shortbow": 2500, "sling": 10,
+	// Martial melee
+	"battleaxe": 1000, "flail": 1000, "glaive": 2000, "greataxe": 3000, "greatsword": 5000,
+	"halberd": 2000, "lance": 1000, "longsword": 1500, "maul": 1000, "morningstar": 1500,
+	"pike": 500, "rapier": 2500, "scimitar": 2500, "shortsword": 1000, "trident": 500,
+	"war-pick": 500, "warhammer": 1500, "whip": 200,
+	// Martial ranged
+	"blowgun": 1000, "crossbow-hand": 7500, "crossbow-heavy": 5000, "longbow": 5000, "net": 100,
+}
+
+// armorPricesCP are the PHB armor prices (p145) in copper, by SRD slug without "-armor".
+var armorPricesCP = map[string]int{
+	"padded": 500, "leather": 1000, "studded-leather": 4500,
+	"hide": 1000, "chain-shirt": 5000, "scale-mail": 5000, "breastplate": 40000, "half-plate": 75000,
+	"ring-mail": 3000, "chain-mail": 7500, "splint": 20000, "plate": 150000,
+	"shield": 1000,
+}
+
+// WeaponPriceCP is a weapon's PHB price in copper.
+func WeaponPriceCP(slug string) (int, bool) {
+	copper, ok := weaponPricesCP[strings.ToLower(slug)]
+	return copper, ok
+}
+
+// ArmorPriceCP is an armor's PHB price in copper. Slugs with or without "-armor" work.
+func ArmorPriceCP(slug string) (int, bool) {
+	copper, ok := armorPricesCP[strings.TrimSuffix(strings.ToLower(slug), "-armor")]
+	return copper, ok
+}
+
+// MagicItemPriceCP prices a magic item by rarity at the top of its DMG p135 range.
+// Legendary items and artifacts have no set price.
+func MagicItemPriceCP(rarity string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(rarity)) {
+	case "common":
+		return 100 * 100, true
+	case "uncommon":
+		return 500 * 100, true
+	case "rare":
+		return 5000 * 100, true
+	case "very rare":
+		return 50000 * 100, true
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package game
+
+import "testing"
+
+func TestPursePay(t *testing.T) {
+	tests := []struct {
+		name   string
+		purse  Purse
+		price  int
+		want   Purse
+		wantOK bool
+	}{
+		{"exact gold", Purse{GP: 20}, 1500, Purse{GP: 5}, true},
+		{"mixed coins", Purse{GP: 1, SP: 5, CP: 3}, 152, Purse{CP: 1}, true},
+		{"break a gold piece", Purse{GP: 2}, 5, Purse{GP: 1, SP: 9, CP: 5}, true},
+		{"break platinum", Purse{PP: 1, SP: 2}, 250, Purse{GP: 7, SP: 7}, true},
+		{"too poor", Purse{GP: 1, SP: 9}, 200, Purse{GP: 1, SP: 9}, false},
+		{"electrum counts", Purse{EP: 3}, 100, Purse{EP: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.purse.Pay(tt.price)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Pay(%d) = %+v, %v; want %+v, %v", tt.price, got, ok, tt.want, tt.wantOK)
+			}
+			if ok && got.TotalCP() != tt.purse.TotalCP()-tt.price {
+				t.Errorf("paying %d lost money: %d -> %d", tt.price, tt.purse.TotalCP(), got.TotalCP())
+			}
+		})
+	}
+}
+
+func TestPurseReceive(t *testing.T) {
+	if got := (Purse{PP: 1}).Receive(753); got != (Purse{PP: 1, GP: 7, SP: 5, CP: 3}) {
+		t.Errorf("Receive(753) = %+v", got)
+	}
+}
+
+func TestParseAndFormatPrice(t *testing.T) {
+	for in, want := range map[string]int{"50 gp": 5000, "1,000 gp": 100000, "5 sp": 50, "2cp": 2, "1 pp": 1000} {
+		if got, ok := ParsePrice(in); !ok || got != want {
+			t.Errorf("ParsePrice(%q) = %d, %v; want %d", in, got, ok, want)
+		}
+	}
+	if _, ok := ParsePrice("priceless"); ok {
+		t.Error("ParsePrice accepted a price with no amount")
+	}
+	if got := FormatCP(1505); got != "15 gp 5 cp" {
+		t.Errorf("FormatCP(1505) = %q", got)
+	}
+}
+
+func TestEquipmentPrices(t *testing.T) {
+	if cp, ok := WeaponPriceCP("Longsword"); !ok || cp != 1500 {
+		t.Errorf("longsword = %d, %v; want 15 gp", cp, ok)
+	}
+	if cp, ok := ArmorPriceCP("chain-mail"); !ok || cp != 7500 {
+		t.Errorf("chain mail = %d, %v; want 75 gp", cp, ok)
+	}
+	if a, b := mustPrice(ArmorPriceCP("leather-armor")), mustPrice(ArmorPriceCP("leather")); a != b || a != 1000 {
+		t.Errorf("leather-armor %d and leather %d should both cost 10 gp", a, b)
+	}
+	if cp, ok := MagicItemPriceCP("Uncommon"); !ok || cp != 50000 {
+		t.Errorf("uncommon = %d, %v; want 500 gp", cp, ok)
+	}
+	if _, ok := MagicItemPriceCP("legendary"); ok {
+		t.Error("legendary items shouldn't have a list price")
+	}
+	if SellPrice(1500) != 750 {
+		t.Error("equipment should sell for half its price")
+	}
+}
+
+func mustPrice(cp int, _ bool) int { return cp }
@@ -0,0 +1,88 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// size.go - size categories: grapple and shove limits (PHB p195), carrying capacity
+// (PHB p176) and squeezing into a smaller space (PHB p192)
+package game
+
+import "strings"
+
+// NormalizeSize converts "large", " HUGE " etc. to a size constant; returns "" if unknown.
+func NormalizeSize(size string) string {
+	switch strings.ToLower(strings.TrimSpace(size)) {
+	case "tiny":
+		return SizeTiny
+	case "small":
+		return SizeSmall
+	case "medium":
+		return SizeMedium
+	case "large":
+		return SizeLarge
+	case "huge":
+		return SizeHuge
+	case "gargantuan":
+		return SizeGargantuan
+	}
+	return ""
+}
+
+// CanGrappleOrShove reports whether a creature can grapple or shove a target: the target
+// must be no more than one size larger than the attacker.
+func CanGrappleOrShove(attackerSize, targetSize string) bool {
+	return SizeOrder(targetSize) <= SizeOrder(attackerSize)+1
+}
+
+// CarryingCapacityMultiplier returns how size scales carrying capacity: doubled for each
+// category above Medium, halved for Tiny. Small and Medium carry the same.
+func CarryingCapacityMultiplier(size string) float64 {
+	switch NormalizeSize(size) {
+	case SizeTiny:
+		return 0.5
+	case SizeLarge:
+		return 2
+	case SizeHuge:
+		return 4
+	case SizeGargantuan:
+		return 8
+	}
+	return 1
+}
+
+// CarryingCapacity returns the weight in pounds a creature can carry: STR × 15, scaled by size.
+// Pushing, dragging or lifting allows twice this.
+func CarryingCapacity(str int, size string) float64 {
+	return float64(str*15) * CarryingCapacityMultiplier(size)
+}
+
+// Encumbrance statuses (variant encumbrance, PHB p176)
+const (
+	EncumbranceNormal       = "normal"
+	EncumbranceEncumbered   = "encumbered"
+	EncumbranceHeavily      = "heavily_encumbered"
+	EncumbranceOverCapacity = "over_capacity"
+)
+
+// Encumbrance applies the variant encumbrance rule (PHB p176): over STR × 5 pounds is
+// encumbered (-10 speed), over STR × 10 heavily encumbered (-20 speed and disadvantage on
+// ability checks, attacks and STR/DEX/CON saves). Thresholds scale with size like carrying
+// capacity. speedPenalty is negative.
+func Encumbrance(weight float64, str int, size string) (status string, speedPenalty int) {
+	multiplier := CarryingCapacityMultiplier(size)
+	switch {
+	case weight > CarryingCapacity(str, size):
+		return EncumbranceOverCapacity, -20
+	case weight > float64(str*10)*multiplier:
+		return EncumbranceHeavily, -20
+	case weight > float64(str*5)*multiplier:
+		return EncumbranceEncumbered, -10
+	}
+	return EncumbranceNormal, 0
+}
+
+// SqueezeCheck reports whether a creature fits through a space sized for spaceSize creatures.
+// A creature can squeeze through a space large enough for a creature one size smaller; while
+// squeezing, each foot costs 1 extra foot, and it has disadvantage on attack rolls and DEX
+// saves while attacks against it have advantage.
+func SqueezeCheck(creatureSize, spaceSize string) (fits, squeezing bool) {
+	diff := SizeOrder(creatureSize) - SizeOrder(spaceSize)
+	return diff <= 1, diff == 1
+}
@@ -0,0 +1,95 @@
+package game
+
+import "testing"
+
+func TestNormalizeSize(t *testing.T) {
+	for in, want := range map[string]string{"large": SizeLarge, " HUGE ": SizeHuge, "Tiny": SizeTiny, "colossal": ""} {
+		if got := NormalizeSize(in); got != want {
+			t.Errorf("NormalizeSize(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if SizeOrder("large") != SizeOrder(SizeLarge) {
+		t.Error("SizeOrder should ignore case")
+	}
+}
+
+func TestCanGrappleOrShove(t *testing.T) {
+	tests := []struct {
+		attacker, target string
+		want             bool
+	}{
+		{SizeMedium, SizeMedium, true},
+		{SizeMedium, SizeLarge, true},
+		{SizeMedium, SizeHuge, false},
+		{SizeSmall, SizeLarge, false},
+		{SizeSmall, SizeMedium, true},
+		{"large", "gargantuan", false},
+		{SizeHuge, SizeTiny, true},
+	}
+	for _, tt := range tests {
+		if got := CanGrappleOrShove(tt.attacker, tt.target); got != tt.want {
+			t.Errorf("CanGrappleOrShove(%s, %s) = %v, want %v", tt.attacker, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCarryingCapacity(t *testing.T) {
+	tests := []struct {
+		str  int
+		size string
+		want float64
+	}{
+		{10, SizeMedium, 150},
+		{10, SizeSmall, 150},
+		{10, SizeTiny, 75},
+		{18, "large", 540},
+		{20, SizeHuge, 1200},
+		{20, SizeGargantuan, 2400},
+	}
+	for _, tt := range tests {
+		if got := CarryingCapacity(tt.str, tt.size); got != tt.want {
+			t.Errorf("CarryingCapacity(%d, %s) = %v, want %v", tt.str, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestEncumbrance(t *testing.T) {
+	tests := []struct {
+		weight      float64
+		str         int
+		size        string
+		wantStatus  string
+		wantPenalty int
+	}{
+		{50, 10, SizeMedium, EncumbranceNormal, 0},
+		{51, 10, SizeMedium, EncumbranceEncumbered, -10},
+		{101, 10, SizeMedium, EncumbranceHeavily, -20},
+		{151, 10, SizeMedium, EncumbranceOverCapacity, -20},
+		{90, 10, SizeLarge, EncumbranceNormal, 0}, // Thresholds double for Large
+		{30, 10, SizeTiny, EncumbranceEncumbered, -10},
+	}
+	for _, tt := range tests {
+		status, penalty := Encumbrance(tt.weight, tt.str, tt.size)
+		if status != tt.wantStatus || penalty != tt.wantPenalty {
+			t.Errorf("Encumbrance(%v, %d, %s) = %s, %d; want %s, %d", tt.weight, tt.str, tt.size, status, penalty, tt.wantStatus, tt.wantPenalty)
+		}
+	}
+}
+
+func TestSqueezeCheck(t *testing.T) {
+	tests := []struct {
+		creature, space string
+		fits, squeezing bool
+	}{
+		{SizeMedium, SizeMedium, true, false},
+		{SizeMedium, SizeLarge, true, false},
+		{SizeLarge, SizeMedium, true, true},
+		{SizeHuge, SizeMedium, false, false},
+	}
+	for _, tt := range tests {
+		fits, squeezing := SqueezeCheck(tt.creature, tt.space)
+		if fits != tt.fits || squeezing != tt.squeezing {
+			t.Errorf("SqueezeCheck(%s, %s) = (%v, %v), want (%v, %v)", tt.creature, tt.space, fits, squeezing, tt.fits, tt.squeezing)
+		}
+	}
+}
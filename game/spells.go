@@ -4,6 +4,7 @@
 package game
 
 import (
+	"slices"
 	"strings"
 )
 
@@ -79,6 +80,112 @@ func MaxPreparedSpells(class string, level, intl, wis, cha int) int {
 	return preparedCount
 }
 
+// spellsKnownByLevel is the Spells Known column of each known caster's class table, indexed
+// by class level - 1 (PHB p53, p91, p100, p106).
+var spellsKnownByLevel = map[string][20]int{
+	"bard":     {4, 5, 6, 7, 8, 9, 10, 11, 12, 14, 15, 15, 16, 18, 19, 19, 20, 22, 22, 22},
+	"ranger":   {0, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11},
+	"sorcerer": {2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 12, 13, 13, 14, 14, 15, 15, 15, 15},
+	"warlock":  {2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 11, 11, 12, 12, 13, 13, 14, 14, 15, 15},
+}
+
+// SpellsKnown returns how many 1st-level and higher spells a known caster knows at a class
+// level. ok is false for classes without a Spells Known column (prepared casters and
+// non-casters), whose known spells aren't limited this way.
+func SpellsKnown(class string, level int) (n int, ok bool) {
+	table, ok := spellsKnownByLevel[strings.ToLower(class)]
+	if !ok || level < 1 {
+		return 0, ok
+	}
+	return table[min(level, 20)-1], true
+}
+
+// CantripsKnown returns how many cantrips a class knows at a class level: a base count that
+// grows at levels 4 and 10. Classes without cantrips return 0.
+func CantripsKnown(class string, level int) int {
+	base := map[string]int{"bard": 2, "cleric": 3, "druid": 2, "sorcerer": 4, "warlock": 2, "wizard": 3}[strings.ToLower(class)]
+	if base == 0 || level < 1 {
+		return 0
+	}
+	switch {
+	case level >= 10:
+		return base + 2
+	case level >= 4:
+		return base + 1
+	}
+	return base
+}
+
+// KnownSpellLimits returns how many cantrips and 1st-level and higher spells a character
+// with these class levels can keep in known_spells. limited is false when no class has a
+// Spells Known column, or when one is a wizard, whose spellbook has no limit.
+func KnownSpellLimits(classLevels map[string]int) (cantrips, spells int, limited bool) {
+	for class, level := range classLevels {
+		cantrips += CantripsKnown(class, level)
+		if n, ok := SpellsKnown(class, level); ok {
+			spells += n
+			limited = true
+		}
+	}
+	if classLevels["wizard"] > 0 {
+		limited = false
+	}
+	return cantrips, spells, limited
+}
+
+// SpellAccess is where a character's castable spells come from.
+type SpellAccess struct {
+	ClassLevels    map[string]int // Levels in each class
+	Known          []string       // known_spells: cantrips, known spells, or a wizard's spellbook
+	Prepared       []string       // prepared_spells
+	AlwaysPrepared []string       // Domain, oath and circle spells
+	Granted        []string       // Spells from invocations, Mystic Arcanum, Signature Spells or race
+}
+
+// CastProblem explains why a spell of the given level can't be cast, or returns empty
+// strings. Cantrips and a known caster's spells must be known; a prepared caster's leveled
+// spells must be prepared, except that a wizard can cast a ritual spell from the spellbook
+// without preparing it (PHB p114).
+func (a SpellAccess) CastProblem(slug string, level int, ritual bool) (code, message string) {
+	if slices.Contains(a.Granted, slug) || slices.Contains(a.AlwaysPrepared, slug) || slices.Contains(a.Prepared, slug) {
+		return "", ""
+	}
+	if !slices.Contains(a.Known, slug) {
+		if level == 0 {
+			return "spell_not_known", "you don't know this cantrip. Learn cantrips with PUT /api/characters/{id}/spells."
+		}
+		if a.preparesOnly() {
+			return "spell_not_prepared", "you haven't prepared this spell. Prepare spells with POST /api/characters/{id}/prepare."
+		}
+		return "spell_not_known", "you don't know this spell. Learn spells with PUT /api/characters/{id}/spells."
+	}
+	if level == 0 || !a.preparesOnly() {
+		return "", ""
+	}
+	if ritual && a.ClassLevels["wizard"] > 0 {
+		return "", ""
+	}
+	return "spell_not_prepared", "it's in your spellbook but not prepared. Prepare spells with POST /api/characters/{id}/prepare."
+}
+
+// preparesOnly reports whether every spellcasting class the character has prepares its
+// spells, so known spells other than cantrips can't be cast unprepared.
+func (a SpellAccess) preparesOnly() bool {
+	prepared := false
+	for class, level := range a.ClassLevels {
+		if level <= 0 {
+			continue
+		}
+		if IsKnownCaster(class) {
+			return false
+		}
+		if IsPreparedCaster(class) {
+			prepared = true
+		}
+	}
+	return prepared
+}
+
 // MulticlassSpellSlots calculates spell slots for multiclass characters.
 // Uses the PHB multiclass spellcasting rules (PHB p164-165):
 // - Full casters (Bard, Cleric, Druid, Sorcerer, Wizard): add full level
@@ -168,6 +275,19 @@ func SlotRecoveryAbility(class, subclass string, level int) (abilityName string,
 	return "", 0, 0
 }
 
+// ClassSlotRecovery is SlotRecoveryAbility for a character's class levels: the budget comes
+// from the levels in the recovering class alone, so a fighter 3/wizard 2 recovers one combined
+// level. subclass belongs to firstClass, the class the character started in.
+func ClassSlotRecovery(classLevels map[string]int, firstClass, subclass string) (abilityName string, maxCombined int, maxSlotLevel int) {
+	if wizardLevel := classLevels["wizard"]; wizardLevel > 0 {
+		return SlotRecoveryAbility("wizard", "", wizardLevel)
+	}
+	if strings.ToLower(firstClass) == "druid" {
+		return SlotRecoveryAbility("druid", subclass, classLevels["druid"])
+	}
+	return "", 0, 0
+}
+
 // LandCircleSpells returns the circle spells for a Circle of the Land druid.
 // Circle spells are unlocked at druid levels 3, 5, 7, 9 (for 2nd, 3rd, 4th, 5th level spells).
 // Returns nil if landType is not recognized.
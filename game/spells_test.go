@@ -214,6 +214,33 @@ func TestSlotRecoveryAbility(t *testing.T) {
 	}
 }
 
+func TestClassSlotRecovery(t *testing.T) {
+	tests := []struct {
+		name           string
+		classLevels    map[string]int
+		firstClass     string
+		subclass       string
+		expectAbility  string
+		expectCombined int
+	}{
+		{"Wizard L5", map[string]int{"wizard": 5}, "wizard", "", "arcane_recovery", 3},
+		{"Fighter 3 / Wizard 2", map[string]int{"fighter": 3, "wizard": 2}, "fighter", "champion", "arcane_recovery", 1},
+		{"Land Druid 4 / Cleric 2", map[string]int{"druid": 4, "cleric": 2}, "druid", "land", "natural_recovery", 2},
+		{"Cleric 2 / Druid 4 (subclass is the cleric's)", map[string]int{"cleric": 2, "druid": 4}, "cleric", "life", "", 0},
+		{"Fighter 5", map[string]int{"fighter": 5}, "fighter", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ability, combined, _ := ClassSlotRecovery(tt.classLevels, tt.firstClass, tt.subclass)
+			if ability != tt.expectAbility || combined != tt.expectCombined {
+				t.Errorf("ClassSlotRecovery(%v, %q, %q) = %q, %d; want %q, %d",
+					tt.classLevels, tt.firstClass, tt.subclass, ability, combined, tt.expectAbility, tt.expectCombined)
+			}
+		})
+	}
+}
+
 func TestLandCircleSpells(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -322,3 +349,124 @@ func TestIntToString(t *testing.T) {
 		})
 	}
 }
+
+func TestSpellsKnown(t *testing.T) {
+	tests := []struct {
+		class  string
+		level  int
+		want   int
+		wantOK bool
+	}{
+		{"bard", 1, 4, true},
+		{"Sorcerer", 20, 15, true},
+		{"ranger", 1, 0, true},
+		{"warlock", 11, 11, true},
+		{"wizard", 5, 0, false},
+		{"fighter", 3, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := SpellsKnown(tt.class, tt.level)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("SpellsKnown(%q, %d) = %d, %v; want %d, %v", tt.class, tt.level, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCantripsKnown(t *testing.T) {
+	tests := []struct {
+		class string
+		level int
+		want  int
+	}{
+		{"wizard", 1, 3},
+		{"wizard", 4, 4},
+		{"cleric", 10, 5},
+		{"sorcerer", 3, 4},
+		{"paladin", 5, 0},
+	}
+	for _, tt := range tests {
+		if got := CantripsKnown(tt.class, tt.level); got != tt.want {
+			t.Errorf("CantripsKnown(%q, %d) = %d, want %d", tt.class, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSpellAccessCastProblem(t *testing.T) {
+	wizard := SpellAccess{
+		ClassLevels: map[string]int{"wizard": 3},
+		Known:       []string{"fire-bolt", "magic-missile", "detect-magic", "shield"},
+		Prepared:    []string{"shield"},
+	}
+	sorcerer := SpellAccess{
+		ClassLevels: map[string]int{"sorcerer": 3},
+		Known:       []string{"fire-bolt", "magic-missile"},
+	}
+	cleric := SpellAccess{
+		ClassLevels:    map[string]int{"cleric": 1},
+		Known:          []string{"sacred-flame"},
+		AlwaysPrepared: []string{"bless"},
+	}
+	warlock := SpellAccess{
+		ClassLevels: map[string]int{"warlock": 5},
+		Granted:     []string{"mage-armor"},
+	}
+
+	tests := []struct {
+		name   string
+		access SpellAccess
+		slug   string
+		level  int
+		ritual bool
+		want   string
+	}{
+		{"wizard cantrip", wizard, "fire-bolt", 0, false, ""},
+		{"wizard prepared", wizard, "shield", 1, false, ""},
+		{"wizard spellbook unprepared", wizard, "magic-missile", 1, false, "spell_not_prepared"},
+		{"wizard ritual from spellbook", wizard, "detect-magic", 1, true, ""},
+		{"wizard not in spellbook", wizard, "fireball", 3, false, "spell_not_prepared"},
+		{"wizard unknown cantrip", wizard, "ray-of-frost", 0, false, "spell_not_known"},
+		{"sorcerer known", sorcerer, "magic-missile", 1, false, ""},
+		{"sorcerer unknown", sorcerer, "shield", 1, false, "spell_not_known"},
+		{"cleric domain spell", cleric, "bless", 1, false, ""},
+		{"cleric unprepared", cleric, "cure-wounds", 1, false, "spell_not_prepared"},
+		{"cleric ritual needs preparing", cleric, "detect-magic", 1, true, "spell_not_prepared"},
+		{"invocation spell", warlock, "mage-armor", 1, false, ""},
+		{"fighter", SpellAccess{ClassLevels: map[string]int{"fighter": 5}}, "fireball", 3, false, "spell_not_known"},
+		{"cleric/sorcerer known spell", SpellAccess{ClassLevels: map[string]int{"cleric": 1, "sorcerer": 1}, Known: []string{"shield"}}, "shield", 1, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, message := tt.access.CastProblem(tt.slug, tt.level, tt.ritual)
+			if code != tt.want {
+				t.Errorf("CastProblem(%q) = %q (%s), want %q", tt.slug, code, message, tt.want)
+			}
+			if (code == "") != (message == "") {
+				t.Errorf("CastProblem(%q) code %q with message %q", tt.slug, code, message)
+			}
+		})
+	}
+}
+
+func TestKnownSpellLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		classLevels  map[string]int
+		wantCantrips int
+		wantSpells   int
+		wantLimited  bool
+	}{
+		{"Sorcerer 3", map[string]int{"sorcerer": 3}, 4, 4, true},
+		{"Bard 2 / Warlock 1", map[string]int{"bard": 2, "warlock": 1}, 4, 7, true},
+		{"Wizard 5", map[string]int{"wizard": 5}, 4, 0, false},
+		{"Sorcerer 1 / Wizard 1", map[string]int{"sorcerer": 1, "wizard": 1}, 7, 2, false},
+		{"Cleric 4", map[string]int{"cleric": 4}, 4, 0, false},
+		{"Fighter 3", map[string]int{"fighter": 3}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		cantrips, spells, limited := KnownSpellLimits(tt.classLevels)
+		if cantrips != tt.wantCantrips || spells != tt.wantSpells || limited != tt.wantLimited {
+			t.Errorf("%s: KnownSpellLimits() = %d, %d, %v; want %d, %d, %v", tt.name,
+				cantrips, spells, limited, tt.wantCantrips, tt.wantSpells, tt.wantLimited)
+		}
+	}
+}
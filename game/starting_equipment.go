@@ -0,0 +1,309 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// starting_equipment.go - class starting equipment choices and the starting wealth
+// alternative (PHB chapter 3 class entries, p143)
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Starting item kinds. Weapons, armor and shields are auto-equipped at creation.
+const (
+	ItemKindWeapon = "weapon"
+	ItemKindArmor  = "armor"
+	ItemKindShield = "shield"
+	ItemKindAmmo   = "ammo"
+	ItemKindGear   = "gear"
+)
+
+// StartingItem is one line of a class's starting equipment. Pick marks a slot where the
+// player names the weapon ("simple", "martial", "simple melee", "martial melee").
+type StartingItem struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Quantity int    `json:"quantity"`
+	Pick     string `json:"pick,omitempty"`
+}
+
+// StartingEquipmentOption is one lettered option of a choice, e.g. "(a) a greataxe".
+type StartingEquipmentOption struct {
+	Letter string         `json:"letter"`
+	Items  []StartingItem `json:"items"`
+}
+
+// StartingEquipmentChoice is one "(a) ... or (b) ..." line.
+type StartingEquipmentChoice struct {
+	Options []StartingEquipmentOption `json:"options"`
+}
+
+// ClassStartingEquipment is everything a class starts with, and the starting wealth a
+// player can roll instead (PHB p143).
+type ClassStartingEquipment struct {
+	Choices        []StartingEquipmentChoice `json:"choices"`
+	Fixed          []StartingItem            `json:"fixed"`
+	GoldDice       string                    `json:"gold_dice"`
+	GoldMultiplier int                       `json:"gold_multiplier"`
+}
+
+// Helpers for the table below.
+func startingWeapon(name string, qty int) StartingItem {
+	return StartingItem{Name: name, Kind: ItemKindWeapon, Quantity: qty}
+}
+
+func startingArmor(name string) StartingItem {
+	return StartingItem{Name: name, Kind: ItemKindArmor, Quantity: 1}
+}
+
+func startingAmmo(name string, qty int) StartingItem {
+	return StartingItem{Name: name, Kind: ItemKindAmmo, Quantity: qty}
+}
+
+func startingGear(name string, qty int) StartingItem {
+	return StartingItem{Name: name, Kind: ItemKindGear, Quantity: qty}
+}
+
+func weaponPick(category string) StartingItem {
+	return StartingItem{Name: "any " + category + " weapon", Kind: ItemKindWeapon, Quantity: 1, Pick: category}
+}
+
+var startingShield = StartingItem{Name: "Shield", Kind: ItemKindShield, Quantity: 1}
+
+// equipmentChoice builds a choice whose options are lettered a, b, c in order.
+func equipmentChoice(options ...[]StartingItem) StartingEquipmentChoice {
+	c := StartingEquipmentChoice{}
+	for i, items := range options {
+		c.Options = append(c.Options, StartingEquipmentOption{Letter: string(rune('a' + i)), Items: items})
+	}
+	return c
+}
+
+// kit lists the items of one option.
+func kit(list ...StartingItem) []StartingItem { return list }
+
+var classStartingEquipment = map[string]ClassStartingEquipment{
+	"barbarian": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Greataxe", 1)), kit(weaponPick("martial melee"))),
+			equipmentChoice(kit(startingWeapon("Handaxe", 2)), kit(weaponPick("simple"))),
+		},
+		Fixed:    kit(startingGear("Explorer's Pack", 1), startingWeapon("Javelin", 4)),
+		GoldDice: "2d4", GoldMultiplier: 10,
+	},
+	"bard": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Rapier", 1)), kit(startingWeapon("Longsword", 1)), kit(weaponPick("simple"))),
+			equipmentChoice(kit(startingGear("Diplomat's Pack", 1)), kit(startingGear("Entertainer's Pack", 1))),
+			equipmentChoice(kit(startingGear("Lute", 1)), kit(startingGear("Musical Instrument", 1))),
+		},
+		Fixed:    kit(startingArmor("Leather Armor"), startingWeapon("Dagger", 1)),
+		GoldDice: "5d4", GoldMultiplier: 10,
+	},
+	"cleric": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Mace", 1)), kit(startingWeapon("Warhammer", 1))),
+			equipmentChoice(kit(startingArmor("Scale Mail")), kit(startingArmor("Leather Armor")), kit(startingArmor("Chain Mail"))),
+			equipmentChoice(kit(startingWeapon("Light Crossbow", 1), startingAmmo("Crossbow Bolts", 20)), kit(weaponPick("simple"))),
+			equipmentChoice(kit(startingGear("Priest's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingShield, startingGear("Holy Symbol", 1)),
+		GoldDice: "5d4", GoldMultiplier: 10,
+	},
+	"druid": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingShield), kit(weaponPick("simple"))),
+			equipmentChoice(kit(startingWeapon("Scimitar", 1)), kit(weaponPick("simple melee"))),
+		},
+		Fixed:    kit(startingArmor("Leather Armor"), startingGear("Explorer's Pack", 1), startingGear("Druidic Focus", 1)),
+		GoldDice: "2d4", GoldMultiplier: 10,
+	},
+	"fighter": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingArmor("Chain Mail")), kit(startingArmor("Leather Armor"), startingWeapon("Longbow", 1), startingAmmo("Arrows", 20))),
+			equipmentChoice(kit(weaponPick("martial"), startingShield), kit(weaponPick("martial"), weaponPick("martial"))),
+			equipmentChoice(kit(startingWeapon("Light Crossbow", 1), startingAmmo("Crossbow Bolts", 20)), kit(startingWeapon("Handaxe", 2))),
+			equipmentChoice(kit(startingGear("Dungeoneer's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		GoldDice: "5d4", GoldMultiplier: 10,
+	},
+	"monk": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Shortsword", 1)), kit(weaponPick("simple"))),
+			equipmentChoice(kit(startingGear("Dungeoneer's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingWeapon("Dart", 10)),
+		GoldDice: "5d4", GoldMultiplier: 1,
+	},
+	"paladin": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(weaponPick("martial"), startingShield), kit(weaponPick("martial"), weaponPick("martial"))),
+			equipmentChoice(kit(startingWeapon("Javelin", 5)), kit(weaponPick("simple melee"))),
+			equipmentChoice(kit(startingGear("Priest's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingArmor("Chain Mail"), startingGear("Holy Symbol", 1)),
+		GoldDice: "5d4", GoldMultiplier: 10,
+	},
+	"ranger": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingArmor("Scale Mail")), kit(startingArmor("Leather Armor"))),
+			equipmentChoice(kit(startingWeapon("Shortsword", 2)), kit(weaponPick("simple melee"), weaponPick("simple melee"))),
+			equipmentChoice(kit(startingGear("Dungeoneer's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingWeapon("Longbow", 1), startingAmmo("Arrows", 20)),
+		GoldDice: "5d4", GoldMultiplier: 10,
+	},
+	"rogue": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Rapier", 1)), kit(startingWeapon("Shortsword", 1))),
+			equipmentChoice(kit(startingWeapon("Shortbow", 1), startingAmmo("Arrows", 20)), kit(startingWeapon("Shortsword", 1))),
+			equipmentChoice(kit(startingGear("Burglar's Pack", 1)), kit(startingGear("Dungeoneer's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingArmor("Leather Armor"), startingWeapon("Dagger", 2), startingGear("Thieves' Tools", 1)),
+		GoldDice: "4d4", GoldMultiplier: 10,
+	},
+	"sorcerer": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Light Crossbow", 1), startingAmmo("Crossbow Bolts", 20)), kit(weaponPick("simple"))),
+			equipmentChoice(kit(startingGear("Component Pouch", 1)), kit(startingGear("Arcane Focus", 1))),
+			equipmentChoice(kit(startingGear("Dungeoneer's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingWeapon("Dagger", 2)),
+		GoldDice: "3d4", GoldMultiplier: 10,
+	},
+	"warlock": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Light Crossbow", 1), startingAmmo("Crossbow Bolts", 20)), kit(weaponPick("simple"))),
+			equipmentChoice(kit(startingGear("Component Pouch", 1)), kit(startingGear("Arcane Focus", 1))),
+			equipmentChoice(kit(startingGear("Scholar's Pack", 1)), kit(startingGear("Dungeoneer's Pack", 1))),
+		},
+		Fixed:    kit(startingArmor("Leather Armor"), weaponPick("simple"), startingWeapon("Dagger", 2)),
+		GoldDice: "4d4", GoldMultiplier: 10,
+	},
+	"wizard": {
+		Choices: []StartingEquipmentChoice{
+			equipmentChoice(kit(startingWeapon("Quarterstaff", 1)), kit(startingWeapon("Dagger", 1))),
+			equipmentChoice(kit(startingGear("Component Pouch", 1)), kit(startingGear("Arcane Focus", 1))),
+			equipmentChoice(kit(startingGear("Scholar's Pack", 1)), kit(startingGear("Explorer's Pack", 1))),
+		},
+		Fixed:    kit(startingGear("Spellbook", 1)),
+		GoldDice: "4d4", GoldMultiplier: 10,
+	},
+}
+
+// GetClassStartingEquipment returns a class's starting equipment, or nil if unknown.
+func GetClassStartingEquipment(class string) *ClassStartingEquipment {
+	eq, ok := classStartingEquipment[strings.ToLower(strings.TrimSpace(class))]
+	if !ok {
+		return nil
+	}
+	return &eq
+}
+
+// RollStartingGold rolls a class's starting wealth (PHB p143), e.g. 5d4 x 10 gp.
+func RollStartingGold(class string) int {
+	eq := GetClassStartingEquipment(class)
+	if eq == nil {
+		return 0
+	}
+	count, sides := ParseDice(eq.GoldDice)
+	_, total := RollDice(count, sides)
+	return total * eq.GoldMultiplier
+}
+
+// defaultWeaponPicks fill the "any ... weapon" slots of players who don't choose.
+var defaultWeaponPicks = map[string]string{
+	"simple":        "Quarterstaff",
+	"simple melee":  "Spear",
+	"martial":       "Longsword",
+	"martial melee": "Longsword",
+}
+
+// DefaultStartingEquipment is the selection for a player who doesn't make one: option (a)
+// of every choice, with a common weapon in each pick slot.
+func DefaultStartingEquipment(class string) (choices, picks []string) {
+	eq := GetClassStartingEquipment(class)
+	if eq == nil {
+		return nil, nil
+	}
+	for _, c := range eq.Choices {
+		choices = append(choices, c.Options[0].Letter)
+		for _, item := range c.Options[0].Items {
+			if item.Pick != "" {
+				picks = append(picks, defaultWeaponPicks[item.Pick])
+			}
+		}
+	}
+	for _, item := range eq.Fixed {
+		if item.Pick != "" {
+			picks = append(picks, defaultWeaponPicks[item.Pick])
+		}
+	}
+	return choices, picks
+}
+
+// ResolveStartingEquipment turns a player's selections into items. choices holds one
+// option letter per choice, in order; picks names the weapons for "any simple weapon"
+// slots, in order. weaponType returns a weapon's category and range ("martial melee"),
+// or "" for an unknown weapon. Returns one message per problem, empty if valid.
+func ResolveStartingEquipment(class string, choices, picks []string, weaponType func(string) string) ([]StartingItem, []string) {
+	eq := GetClassStartingEquipment(class)
+	if eq == nil {
+		return nil, []string{fmt.Sprintf("no starting equipment for class %q", class)}
+	}
+	issues := []string{}
+	if len(choices) != len(eq.Choices) {
+		issues = append(issues, fmt.Sprintf("choose one option for each of the %d equipment choices (got %d)", len(eq.Choices), len(choices)))
+		return nil, issues
+	}
+
+	selected := []StartingItem{}
+	for i, c := range eq.Choices {
+		letter := strings.ToLower(strings.Trim(strings.TrimSpace(choices[i]), "()"))
+		found := false
+		for _, opt := range c.Options {
+			if opt.Letter == letter {
+				selected = append(selected, opt.Items...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			issues = append(issues, fmt.Sprintf("choice %d: %q is not an option (use a-%c)", i+1, choices[i], 'a'+len(c.Options)-1))
+		}
+	}
+	selected = append(selected, eq.Fixed...)
+	if len(issues) > 0 {
+		return nil, issues
+	}
+
+	// Fill weapon picks in order
+	result := []StartingItem{}
+	next := 0
+	for _, item := range selected {
+		if item.Pick == "" {
+			result = append(result, item)
+			continue
+		}
+		if next >= len(picks) {
+			issues = append(issues, fmt.Sprintf("name a weapon for %s (weapon_picks)", item.Name))
+			continue
+		}
+		name := strings.TrimSpace(picks[next])
+		next++
+		if got := weaponType(name); got == "" {
+			issues = append(issues, fmt.Sprintf("unknown weapon %q", name))
+		} else if !strings.HasPrefix(got, item.Pick) {
+			issues = append(issues, fmt.Sprintf("%s is a %s weapon, not %s", name, got, item.Pick))
+		} else {
+			result = append(result, StartingItem{Name: name, Kind: ItemKindWeapon, Quantity: 1})
+		}
+	}
+	if next < len(picks) {
+		issues = append(issues, fmt.Sprintf("%d weapon_picks given but only %d needed", len(picks), next))
+	}
+	if len(issues) > 0 {
+		return nil, issues
+	}
+	return result, issues
+}
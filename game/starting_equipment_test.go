@@ -0,0 +1,92 @@
+package game
+
+import "testing"
+
+// testWeaponTypes stands in for the weapons table.
+func testWeaponTypes(name string) string {
+	return map[string]string{
+		"longsword": "martial melee",
+		"longbow":   "martial ranged",
+		"spear":     "simple melee",
+	}[name]
+}
+
+func TestResolveStartingEquipment(t *testing.T) {
+	tests := []struct {
+		name       string
+		class      string
+		choices    []string
+		picks      []string
+		wantItems  []string
+		wantIssues int
+	}{
+		{"fighter sword and board", "fighter", []string{"a", "a", "b", "b"}, []string{"longsword"},
+			[]string{"Chain Mail", "longsword", "Shield", "Handaxe", "Explorer's Pack"}, 0},
+		{"letters in parentheses", "Wizard", []string{"(b)", "A", "a"}, nil,
+			[]string{"Dagger", "Component Pouch", "Scholar's Pack", "Spellbook"}, 0},
+		{"fixed pick", "warlock", []string{"a", "b", "a"}, []string{"spear"},
+			[]string{"Light Crossbow", "Crossbow Bolts", "Arcane Focus", "Scholar's Pack", "Leather Armor", "spear", "Dagger"}, 0},
+		{"wrong category", "barbarian", []string{"b", "a"}, []string{"longbow"}, nil, 1},
+		{"missing pick", "fighter", []string{"a", "b", "a", "a"}, []string{"longsword"}, nil, 1},
+		{"extra pick", "wizard", []string{"a", "a", "a"}, []string{"spear"}, nil, 1},
+		{"unknown weapon", "monk", []string{"b", "a"}, []string{"banana"}, nil, 1},
+		{"bad letter", "rogue", []string{"a", "c", "a"}, nil, nil, 1},
+		{"too few choices", "cleric", []string{"a"}, nil, nil, 1},
+		{"unknown class", "artificer", nil, nil, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, issues := ResolveStartingEquipment(tt.class, tt.choices, tt.picks, testWeaponTypes)
+			if len(issues) != tt.wantIssues {
+				t.Fatalf("issues = %v, want %d", issues, tt.wantIssues)
+			}
+			if len(items) != len(tt.wantItems) {
+				t.Fatalf("items = %v, want %v", items, tt.wantItems)
+			}
+			for i, item := range items {
+				if item.Name != tt.wantItems[i] {
+					t.Errorf("item %d = %q, want %q", i, item.Name, tt.wantItems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultStartingEquipment(t *testing.T) {
+	weaponTypes := func(name string) string {
+		return map[string]string{"Quarterstaff": "simple melee", "Spear": "simple melee", "Longsword": "martial melee"}[name]
+	}
+	for class := range classStartingEquipment {
+		choices, picks := DefaultStartingEquipment(class)
+		if _, issues := ResolveStartingEquipment(class, choices, picks, weaponTypes); len(issues) > 0 {
+			t.Errorf("%s default selection: %v", class, issues)
+		}
+	}
+}
+
+func TestClassStartingEquipmentTable(t *testing.T) {
+	for class, eq := range classStartingEquipment {
+		if eq.GoldDice == "" || eq.GoldMultiplier == 0 {
+			t.Errorf("%s has no starting wealth", class)
+		}
+		for i, c := range eq.Choices {
+			if len(c.Options) < 2 {
+				t.Errorf("%s choice %d has %d options", class, i+1, len(c.Options))
+			}
+		}
+	}
+}
+
+func TestRollStartingGold(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		if gold := RollStartingGold("fighter"); gold < 50 || gold > 200 || gold%10 != 0 {
+			t.Fatalf("fighter starting gold %d, want 5d4 x 10", gold)
+		}
+		if gold := RollStartingGold("monk"); gold < 5 || gold > 20 {
+			t.Fatalf("monk starting gold %d, want 5d4", gold)
+		}
+	}
+	if gold := RollStartingGold("artificer"); gold != 0 {
+		t.Errorf("unknown class starting gold = %d, want 0", gold)
+	}
+}
@@ -0,0 +1,28 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// surprise.go - surprise at the start of combat (PHB p189): the side sneaking up rolls
+// Stealth against each opponent's passive Perception
+package game
+
+// Noticed reports whether a creature spots a hider: its passive Perception equals or beats
+// the Stealth total, as a passive score meets a DC.
+func Noticed(passivePerception, stealthTotal int) bool {
+	return passivePerception >= stealthTotal
+}
+
+// Surprised reports whether a creature is surprised: it noticed none of the hiders. Spotting
+// any one threat is enough to act in the first round.
+func Surprised(passivePerception int, stealthTotals []int) bool {
+	if len(stealthTotals) == 0 {
+		return false
+	}
+	for _, total := range stealthTotals {
+		if Noticed(passivePerception, total) {
+			return false
+		}
+	}
+	return true
+}
+
+// SurpriseRound is the round in which surprised creatures can't move, act or react.
+const SurpriseRound = 1
@@ -0,0 +1,30 @@
+package game
+
+import "testing"
+
+func TestNoticed(t *testing.T) {
+	if !Noticed(13, 13) {
+		t.Error("a passive score that equals the Stealth total notices the hider")
+	}
+	if Noticed(12, 13) {
+		t.Error("a Stealth total above the passive score goes unnoticed")
+	}
+}
+
+func TestSurprised(t *testing.T) {
+	tests := []struct {
+		name    string
+		passive int
+		stealth []int
+		want    bool
+	}{
+		{"every hider unnoticed", 12, []int{14, 18}, true},
+		{"one clumsy hider gives the game away", 12, []int{14, 9}, false},
+		{"nobody hiding", 12, nil, false},
+	}
+	for _, tt := range tests {
+		if got := Surprised(tt.passive, tt.stealth); got != tt.want {
+			t.Errorf("%s: Surprised(%d, %v) = %v, want %v", tt.name, tt.passive, tt.stealth, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// swarm.go - batched attacks for groups of identical monsters (rolled or DMG p250 mob rules)
+package game
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Group attack target distributions.
+const (
+	GroupTargetSpread = "spread" // Attackers cycle through the targets in order
+	GroupTargetFocus  = "focus"  // Every attacker goes after the first target
+)
+
+// Group attack resolution modes.
+const (
+	GroupResolveRoll = "roll" // Every attacker rolls its own attack
+	GroupResolveMob  = "mob"  // DMG p250 mob attacks: fixed hits from the table, average damage
+)
+
+// IsValidGroupResolution reports whether mode is a known resolution mode.
+func IsValidGroupResolution(mode string) bool {
+	return mode == GroupResolveRoll || mode == GroupResolveMob
+}
+
+// IsValidGroupDistribution reports whether mode is a known target distribution.
+func IsValidGroupDistribution(mode string) bool {
+	return mode == GroupTargetSpread || mode == GroupTargetFocus
+}
+
+// AssignGroupTargets returns the target for each of attackers attacks.
+// Spread cycles through targets round-robin; focus sends everyone at targets[0].
+func AssignGroupTargets(attackers int, targets []int, mode string) []int {
+	if attackers <= 0 || len(targets) == 0 {
+		return nil
+	}
+	assigned := make([]int, attackers)
+	for i := range assigned {
+		if mode == GroupTargetFocus {
+			assigned[i] = targets[0]
+		} else {
+			assigned[i] = targets[i%len(targets)]
+		}
+	}
+	return assigned
+}
+
+// AttackHits resolves an attack roll against AC. A natural 20 always hits (critical);
+// a natural 1 always misses.
+func AttackHits(natural, total, ac int) (hit, crit bool) {
+	switch natural {
+	case 20:
+		return true, true
+	case 1:
+		return false, false
+	}
+	return total >= ac, false
+}
+
+// DiceBonus returns the flat modifier in a dice string ("1d6+2" → 2, "2d8-1" → -1).
+func DiceBonus(dice string) int {
+	dice = strings.ReplaceAll(dice, " ", "")
+	if idx := strings.LastIndexAny(dice, "+-"); idx > 0 {
+		bonus, err := strconv.Atoi(dice[idx:])
+		if err == nil {
+			return bonus
+		}
+	}
+	return 0
+}
+
+// MobAttackersPerHit returns how many attackers it takes to land one hit when each one
+// needs the given d20 roll (DMG p250 mob attacks table).
+func MobAttackersPerHit(needed int) int {
+	switch {
+	case needed <= 5:
+		return 1
+	case needed <= 12:
+		return 2
+	case needed <= 14:
+		return 3
+	case needed <= 16:
+		return 4
+	case needed <= 18:
+		return 5
+	case needed == 19:
+		return 10
+	}
+	return 20 // Only a natural 20 hits
+}
+
+// MobHits returns how many of attackers identical attackers hit the given AC under the
+// mob rules. No dice are rolled and there are no critical hits.
+func MobHits(attackers, attackBonus, ac int) int {
+	if attackers <= 0 {
+		return 0
+	}
+	return attackers / MobAttackersPerHit(ac-attackBonus)
+}
+
+// AverageDamage returns the fixed (average) damage for a dice string, rounded down the
+// way stat blocks do ("2d6+3" → 10).
+func AverageDamage(dice string) int {
+	dice = strings.ReplaceAll(dice, " ", "")
+	bonus := DiceBonus(dice)
+	if idx := strings.LastIndexAny(dice, "+-"); idx > 0 {
+		dice = dice[:idx]
+	}
+	count, sides := ParseDice(dice)
+	return max(count*(sides+1)/2+bonus, 0)
+}
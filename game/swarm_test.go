@@ -0,0 +1,92 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignGroupTargets(t *testing.T) {
+	targets := []int{4, 7}
+	if got := AssignGroupTargets(5, targets, GroupTargetSpread); !reflect.DeepEqual(got, []int{4, 7, 4, 7, 4}) {
+		t.Errorf("AssignGroupTargets(spread) = %v", got)
+	}
+	if got := AssignGroupTargets(3, targets, GroupTargetFocus); !reflect.DeepEqual(got, []int{4, 4, 4}) {
+		t.Errorf("AssignGroupTargets(focus) = %v", got)
+	}
+	if got := AssignGroupTargets(3, nil, GroupTargetSpread); got != nil {
+		t.Errorf("AssignGroupTargets(no targets) = %v, want nil", got)
+	}
+	if !IsValidGroupDistribution("focus") || IsValidGroupDistribution("random") {
+		t.Error("IsValidGroupDistribution returned the wrong answer")
+	}
+}
+
+func TestAttackHits(t *testing.T) {
+	tests := []struct {
+		natural, total, ac int
+		hit, crit          bool
+	}{
+		{20, 24, 30, true, true},
+		{1, 15, 10, false, false},
+		{12, 16, 16, true, false},
+		{12, 15, 16, false, false},
+	}
+	for _, tt := range tests {
+		hit, crit := AttackHits(tt.natural, tt.total, tt.ac)
+		if hit != tt.hit || crit != tt.crit {
+			t.Errorf("AttackHits(%d, %d, %d) = (%v, %v), want (%v, %v)", tt.natural, tt.total, tt.ac, hit, crit, tt.hit, tt.crit)
+		}
+	}
+}
+
+func TestDiceBonus(t *testing.T) {
+	tests := map[string]int{
+		"1d6+2":   2,
+		"2d8 - 1": -1,
+		"1d10":    0,
+		"3":       0,
+	}
+	for dice, want := range tests {
+		if got := DiceBonus(dice); got != want {
+			t.Errorf("DiceBonus(%q) = %d, want %d", dice, got, want)
+		}
+	}
+}
+
+func TestMobHits(t *testing.T) {
+	tests := []struct {
+		attackers, bonus, ac int
+		want                 int
+	}{
+		{10, 4, 8, 10}, // needs 4: every attacker hits
+		{10, 4, 15, 5}, // needs 11: 2 per hit
+		{9, 3, 16, 3},  // needs 13: 3 per hit
+		{10, 2, 20, 2}, // needs 18: 5 per hit
+		{10, 0, 19, 1}, // needs 19: 10 per hit
+		{19, 0, 25, 0}, // needs 25: only a natural 20, 20 per hit
+		{0, 5, 10, 0},
+	}
+	for _, tt := range tests {
+		if got := MobHits(tt.attackers, tt.bonus, tt.ac); got != tt.want {
+			t.Errorf("MobHits(%d, %d, %d) = %d, want %d", tt.attackers, tt.bonus, tt.ac, got, tt.want)
+		}
+	}
+	if !IsValidGroupResolution("mob") || IsValidGroupResolution("average") {
+		t.Error("IsValidGroupResolution returned the wrong answer")
+	}
+}
+
+func TestAverageDamage(t *testing.T) {
+	tests := map[string]int{
+		"1d6":    3,
+		"2d6+3":  10,
+		"1d8-1":  3,
+		"1d4-3":  0,
+		"3d10+4": 20,
+	}
+	for dice, want := range tests {
+		if got := AverageDamage(dice); got != want {
+			t.Errorf("AverageDamage(%q) = %d, want %d", dice, got, want)
+		}
+	}
+}
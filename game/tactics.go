@@ -0,0 +1,251 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// tactics.go - reading a monster's stat block for the GM: which action to reach for,
+// whom to attack, and which legendary and lair actions not to forget
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Action kinds, in the order a monster usually reaches for them.
+const (
+	TacticArea        = "area"        // Breath weapons and other area effects
+	TacticControl     = "control"     // A save that imposes a condition
+	TacticMultiattack = "multiattack" // The default turn
+	TacticMelee       = "melee"
+	TacticRanged      = "ranged"
+	TacticOther       = "other"
+)
+
+var tacticRank = map[string]int{
+	TacticArea: 0, TacticControl: 1, TacticMultiattack: 2, TacticMelee: 3, TacticRanged: 3, TacticOther: 4,
+}
+
+// MonsterAction is one action from a stat block.
+type MonsterAction struct {
+	Name        string
+	Desc        string
+	AttackBonus int
+	DamageDice  string
+}
+
+// ActionAdvice is an action with what it's for and when to use it.
+type ActionAdvice struct {
+	Name          string   `json:"name"`
+	Kind          string   `json:"kind"`
+	Recharge      string   `json:"recharge,omitempty"` // "5-6", "1/day", "short/long rest"
+	AttackBonus   int      `json:"attack_bonus,omitempty"`
+	AverageDamage int      `json:"average_damage,omitempty"`
+	SaveDC        int      `json:"save_dc,omitempty"`
+	SaveAbility   string   `json:"save_ability,omitempty"`
+	Conditions    []string `json:"conditions,omitempty"`
+	Advice        string   `json:"advice"`
+}
+
+var (
+	rechargePattern = regexp.MustCompile(`(?i)\(recharge (\d)(?:\s*[–-]\s*(\d))?\)`)
+	perDayPattern   = regexp.MustCompile(`(?i)\((\d+)/day\)`)
+	saveDCPattern   = regexp.MustCompile(`DC (\d+) (Strength|Dexterity|Constitution|Intelligence|Wisdom|Charisma) saving throw`)
+	areaPattern     = regexp.MustCompile(`(?i)\d+-foot(?:-long)? (?:cone|line|cube|radius|sphere)|each creature (?:in|within)`)
+)
+
+// tacticConditions are the conditions worth noting in an action's text.
+var tacticConditions = []string{
+	"blinded", "charmed", "frightened", "grappled", "incapacitated", "paralyzed",
+	"petrified", "poisoned", "prone", "restrained", "stunned",
+}
+
+// AdviseMonsterAction reads one action: its kind, how it recharges, its average damage,
+// saving throw and the conditions it imposes.
+func AdviseMonsterAction(a MonsterAction) ActionAdvice {
+	adv := ActionAdvice{Name: a.Name, AttackBonus: a.AttackBonus}
+	if a.DamageDice != "" {
+		adv.AverageDamage = AverageDamage(a.DamageDice)
+	}
+	if m := rechargePattern.FindStringSubmatch(a.Name); m != nil {
+		adv.Recharge = m[1]
+		if m[2] != "" {
+			adv.Recharge += "-" + m[2]
+		}
+	} else if m := perDayPattern.FindStringSubmatch(a.Name); m != nil {
+		adv.Recharge = m[1] + "/day"
+	} else if strings.Contains(strings.ToLower(a.Name), "recharges after a short or long rest") {
+		adv.Recharge = "short/long rest"
+	}
+	if m := saveDCPattern.FindStringSubmatch(a.Desc); m != nil {
+		adv.SaveDC, _ = strconv.Atoi(m[1])
+		adv.SaveAbility = m[2]
+	}
+	desc := strings.ToLower(a.Desc)
+	for _, c := range tacticConditions {
+		if strings.Contains(desc, c) {
+			adv.Conditions = append(adv.Conditions, c)
+		}
+	}
+
+	switch {
+	case strings.EqualFold(a.Name, "Multiattack"):
+		adv.Kind = TacticMultiattack
+		adv.Advice = "Its default turn once the limited-use abilities are spent or out of reach"
+	case areaPattern.MatchString(a.Desc) && (adv.SaveDC > 0 || adv.Recharge != ""):
+		adv.Kind = TacticArea
+		adv.Advice = "Open with it when two or more enemies are inside the area"
+		if strings.Contains(adv.Recharge, "-") {
+			adv.Advice += fmt.Sprintf("; roll a d6 at the start of each of its turns and it's back on %s", adv.Recharge)
+		} else if adv.Recharge != "" {
+			adv.Advice += "; uses are limited, so wait for the party to bunch up"
+		}
+	case adv.SaveDC > 0 && len(adv.Conditions) > 0:
+		adv.Kind = TacticControl
+		adv.Advice = fmt.Sprintf("A DC %d %s save or %s: aim it at the creature least likely to make that save, ideally one the rest of the party relies on",
+			adv.SaveDC, adv.SaveAbility, strings.Join(adv.Conditions, "/"))
+	case strings.Contains(desc, "melee weapon attack") || strings.Contains(desc, "melee spell attack") || strings.Contains(desc, "melee or ranged"):
+		adv.Kind = TacticMelee
+		adv.Advice = fmt.Sprintf("+%d to hit, %d damage on average", a.AttackBonus, adv.AverageDamage)
+		if len(adv.Conditions) > 0 {
+			adv.Advice += "; on a hit the target may be " + strings.Join(adv.Conditions, "/")
+		}
+	case strings.Contains(desc, "ranged weapon attack") || strings.Contains(desc, "ranged spell attack"):
+		adv.Kind = TacticRanged
+		adv.Advice = fmt.Sprintf("+%d to hit, %d damage on average; keep away from the party's melee fighters while using it", a.AttackBonus, adv.AverageDamage)
+	default:
+		adv.Kind = TacticOther
+		adv.Advice = "Situational: read its text and use it when the fight calls for it"
+	}
+	return adv
+}
+
+// AdviseMonsterActions reads every action and orders them by priority: area effects,
+// control, multiattack, then attacks by average damage.
+func AdviseMonsterActions(actions []MonsterAction) []ActionAdvice {
+	advice := make([]ActionAdvice, 0, len(actions))
+	for _, a := range actions {
+		advice = append(advice, AdviseMonsterAction(a))
+	}
+	sort.SliceStable(advice, func(i, j int) bool {
+		ri, rj := tacticRank[advice[i].Kind], tacticRank[advice[j].Kind]
+		if ri != rj {
+			return ri < rj
+		}
+		return advice[i].AverageDamage > advice[j].AverageDamage
+	})
+	return advice
+}
+
+// TargetInfo describes a possible target for a monster.
+type TargetInfo struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	AC            int    `json:"ac"`
+	HP            int    `json:"hp"`
+	MaxHP         int    `json:"max_hp"`
+	Concentrating string `json:"concentrating_on,omitempty"`
+	DistanceFt    int    `json:"distance_ft"` // -1 when either isn't on the battle map
+}
+
+// TargetAdvice is a target with a score and the reasons for it.
+type TargetAdvice struct {
+	TargetInfo
+	HitChance int      `json:"hit_chance"` // Percent, with the monster's best attack bonus
+	Score     int      `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// TargetStrategy describes how a creature of this Intelligence picks targets.
+func TargetStrategy(intScore int) string {
+	switch {
+	case intScore <= 3:
+		return "Instinct: attacks whatever is nearest or just hurt it"
+	case intScore <= 7:
+		return "Cunning: goes for the softest target in reach and the wounded"
+	}
+	return "Tactical: breaks concentration, then picks off low-AC and wounded targets"
+}
+
+// RankTargets orders conscious targets by how good a choice they are for a creature with
+// this Intelligence and attack bonus. Mindless creatures only weigh distance; smarter ones
+// go for concentrating casters, low AC and the wounded.
+func RankTargets(targets []TargetInfo, intScore, attackBonus int) []TargetAdvice {
+	ranked := []TargetAdvice{}
+	for _, t := range targets {
+		if t.HP <= 0 {
+			continue
+		}
+		a := TargetAdvice{TargetInfo: t, Reasons: []string{}}
+		a.HitChance = min(max((21-(t.AC-attackBonus))*5, 5), 95)
+		if t.DistanceFt >= 0 {
+			a.Score += max(30-t.DistanceFt/5*2, 0)
+			if t.DistanceFt <= 5 {
+				a.Reasons = append(a.Reasons, "already in reach")
+			}
+		}
+		if intScore > 3 {
+			a.Score += a.HitChance / 2
+			a.Reasons = append(a.Reasons, fmt.Sprintf("AC %d: %d%% to hit", t.AC, a.HitChance))
+			if t.MaxHP > 0 && t.HP*2 <= t.MaxHP {
+				a.Score += 15
+				a.Reasons = append(a.Reasons, "bloodied")
+			}
+			if t.Concentrating != "" && intScore >= 8 {
+				a.Score += 40
+				a.Reasons = append(a.Reasons, fmt.Sprintf("concentrating on %s: damage forces a save", t.Concentrating))
+			}
+		} else if t.DistanceFt < 0 {
+			a.Reasons = append(a.Reasons, "attacks whatever is nearest")
+		}
+		ranked = append(ranked, a)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	return ranked
+}
+
+// LegendaryAbility is a legendary or lair action.
+type LegendaryAbility struct {
+	Name string `json:"name"`
+	Desc string `json:"desc"`
+	Cost int    `json:"cost,omitempty"`
+}
+
+// LegendaryReminders lists what a GM running this monster shouldn't forget: legendary
+// actions between turns, legendary resistances and lair actions on initiative 20
+// (MM p11).
+func LegendaryReminders(actionCount int, actions []LegendaryAbility, resistances int, lair []LegendaryAbility) []string {
+	reminders := []string{}
+	if len(actions) > 0 {
+		if actionCount == 0 {
+			actionCount = 3
+		}
+		names := []string{}
+		for _, a := range actions {
+			cost := max(a.Cost, 1)
+			if cost > 1 {
+				names = append(names, fmt.Sprintf("%s (%d)", a.Name, cost))
+			} else {
+				names = append(names, a.Name)
+			}
+		}
+		reminders = append(reminders, fmt.Sprintf("%d legendary action points a round, spent at the end of other creatures' turns and regained at the start of its own: %s",
+			actionCount, strings.Join(names, ", ")))
+	}
+	if resistances > 0 {
+		reminders = append(reminders, fmt.Sprintf("Legendary Resistance (%d/day): turn a failed save into a success, saved for effects that would take it out of the fight (stunned, paralyzed, banished)", resistances))
+	}
+	if len(lair) > 0 {
+		names := []string{}
+		for _, a := range lair {
+			names = append(names, a.Name)
+		}
+		reminders = append(reminders, fmt.Sprintf("In its lair: one lair action on initiative count 20 (losing ties), never the same one two rounds running: %s", strings.Join(names, ", ")))
+	}
+	return reminders
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdviseMonsterActions(t *testing.T) {
+	// A young red dragon, abridged
+	actions := []MonsterAction{
+		{Name: "Multiattack", Desc: "The dragon makes three attacks: one with its bite and two with its claws."},
+		{Name: "Claw", Desc: "Melee Weapon Attack: +10 to hit, reach 5 ft., one target. Hit: 13 (2d6 + 6) slashing damage.", AttackBonus: 10, DamageDice: "2d6+6"},
+		{Name: "Bite", Desc: "Melee Weapon Attack: +10 to hit, reach 10 ft., one target. Hit: 17 (2d10 + 6) piercing damage plus 3 (1d6) fire damage.", AttackBonus: 10, DamageDice: "2d10+6"},
+		{Name: "Fire Breath (Recharge 5–6)", Desc: "The dragon exhales fire in a 30-foot cone. Each creature in that area must make a DC 17 Dexterity saving throw, taking 56 (16d6) fire damage on a failed save, or half as much damage on a successful one.", DamageDice: "16d6"},
+	}
+	advice := AdviseMonsterActions(actions)
+	order := []string{}
+	for _, a := range advice {
+		order = append(order, a.Name)
+	}
+	want := "Fire Breath (Recharge 5–6)|Multiattack|Bite|Claw"
+	if strings.Join(order, "|") != want {
+		t.Errorf("priority = %v, want %s", order, want)
+	}
+	breath := advice[0]
+	if breath.Kind != TacticArea || breath.Recharge != "5-6" || breath.SaveDC != 17 || breath.SaveAbility != "Dexterity" || breath.AverageDamage != 56 {
+		t.Errorf("fire breath = %+v", breath)
+	}
+	if advice[2].AverageDamage != 17 {
+		t.Errorf("bite average = %d, want 17", advice[2].AverageDamage)
+	}
+}
+
+func TestAdviseControlAction(t *testing.T) {
+	gaze := AdviseMonsterAction(MonsterAction{
+		Name: "Frightful Presence (1/Day)",
+		Desc: "Each creature of the dragon's choice that is within 120 feet of the dragon and aware of it must succeed on a DC 16 Wisdom saving throw or become frightened for 1 minute.",
+	})
+	if gaze.Kind != TacticControl || gaze.Recharge != "1/day" || len(gaze.Conditions) != 1 || gaze.Conditions[0] != "frightened" {
+		t.Errorf("frightful presence = %+v", gaze)
+	}
+	bite := AdviseMonsterAction(MonsterAction{Name: "Bite", Desc: "Melee Weapon Attack: +4 to hit. Hit: 7 (2d4 + 2) piercing damage. If the target is a creature, it is grappled (escape DC 12).", AttackBonus: 4, DamageDice: "2d4+2"})
+	if bite.Kind != TacticMelee || len(bite.Conditions) != 1 {
+		t.Errorf("grappling bite = %+v", bite)
+	}
+}
+
+func TestRankTargets(t *testing.T) {
+	party := []TargetInfo{
+		{ID: 1, Name: "Thorne", AC: 18, HP: 30, MaxHP: 30, DistanceFt: 5},
+		{ID: 2, Name: "Ariel", AC: 12, HP: 14, MaxHP: 16, Concentrating: "Bless", DistanceFt: 30},
+		{ID: 3, Name: "Pip", AC: 14, HP: 5, MaxHP: 20, DistanceFt: 15},
+		{ID: 4, Name: "Bram", AC: 10, HP: 0, MaxHP: 22, DistanceFt: 5},
+	}
+
+	smart := RankTargets(party, 14, 5)
+	if len(smart) != 3 || smart[0].Name != "Ariel" {
+		t.Fatalf("a clever monster should go for the concentrating caster first: %+v", smart)
+	}
+	if smart[0].HitChance != 70 {
+		t.Errorf("hit chance vs AC 12 at +5 = %d, want 70", smart[0].HitChance)
+	}
+
+	mindless := RankTargets(party, 2, 5)
+	if mindless[0].Name != "Thorne" {
+		t.Errorf("a mindless monster should attack the nearest: %+v", mindless)
+	}
+}
+
+func TestLegendaryReminders(t *testing.T) {
+	r := LegendaryReminders(3,
+		[]LegendaryAbility{{Name: "Detect"}, {Name: "Wing Attack", Cost: 2}},
+		3,
+		[]LegendaryAbility{{Name: "Magma Eruption"}})
+	if len(r) != 3 || !strings.Contains(r[0], "Wing Attack (2)") || !strings.Contains(r[1], "3/day") || !strings.Contains(r[2], "initiative count 20") {
+		t.Errorf("reminders = %v", r)
+	}
+	if len(LegendaryReminders(0, nil, 0, nil)) != 0 {
+		t.Error("an ordinary monster has no legendary reminders")
+	}
+}
@@ -0,0 +1,22 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// training.go - downtime training for a tool proficiency or language (PHB p187,
+// with the Intelligence reduction from XGtE p134)
+package game
+
+// Training costs 1 gp per day for 250 days (PHB p187).
+const TrainingBaseDays = 250
+
+// TrainingDaysPerIntMod is how much each point of Intelligence modifier shortens
+// training. XGtE p134 takes one of ten workweeks off per point; that's a tenth of the
+// PHB's 250 days.
+const TrainingDaysPerIntMod = TrainingBaseDays / 10
+
+// TrainingDays returns how many days training takes. With reduceByInt, a positive
+// Intelligence modifier shortens it (a penalty doesn't lengthen it), down to one tenth.
+func TrainingDays(intMod int, reduceByInt bool) int {
+	if !reduceByInt || intMod <= 0 {
+		return TrainingBaseDays
+	}
+	return max(TrainingBaseDays-intMod*TrainingDaysPerIntMod, TrainingDaysPerIntMod)
+}
@@ -0,0 +1,23 @@
+package game
+
+import "testing"
+
+func TestTrainingDays(t *testing.T) {
+	tests := []struct {
+		intMod      int
+		reduceByInt bool
+		want        int
+	}{
+		{3, false, 250},
+		{0, true, 250},
+		{-1, true, 250}, // A penalty doesn't lengthen training
+		{1, true, 225},
+		{4, true, 150},
+		{12, true, 25}, // Never below one tenth
+	}
+	for _, tt := range tests {
+		if got := TrainingDays(tt.intMod, tt.reduceByInt); got != tt.want {
+			t.Errorf("TrainingDays(%d, %v) = %d, want %d", tt.intMod, tt.reduceByInt, got, tt.want)
+		}
+	}
+}
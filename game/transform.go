@@ -0,0 +1,72 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// transform.go - taking on a beast's statistics: Wild Shape (PHB p66) and Polymorph (PHB p266)
+package game
+
+// Ways a creature takes another form.
+const (
+	TransformWildShape = "wild_shape" // Keeps INT, WIS, CHA, proficiencies and features
+	TransformPolymorph = "polymorph"  // Every statistic is the beast's, mental scores included
+)
+
+// StatBlock is the part of a character a transformation replaces.
+type StatBlock struct {
+	Str   int `json:"str"`
+	Dex   int `json:"dex"`
+	Con   int `json:"con"`
+	Int   int `json:"int"`
+	Wis   int `json:"wis"`
+	Cha   int `json:"cha"`
+	AC    int `json:"ac"`
+	Speed int `json:"speed"`
+}
+
+// Transformation is a character's current form: the beast and the statistics to restore.
+type Transformation struct {
+	Kind     string    `json:"kind"`
+	Form     string    `json:"form"` // Monster slug
+	FormName string    `json:"form_name"`
+	Original StatBlock `json:"original"`
+	Stats    StatBlock `json:"stats"`               // What the character has while transformed
+	CasterID int       `json:"caster_id,omitempty"` // Polymorph: who holds concentration on it
+}
+
+// TransformedStats is a character's statistics in a beast's form. Wild Shape keeps the
+// character's mental ability scores; Polymorph replaces them too.
+func TransformedStats(kind string, original, beast StatBlock) StatBlock {
+	stats := beast
+	if kind == TransformWildShape {
+		stats.Int, stats.Wis, stats.Cha = original.Int, original.Wis, original.Cha
+	}
+	return stats
+}
+
+// TransformMaxCR is the highest challenge rating a form may have. Wild Shape grows with
+// druid level (CR 1/4, 1/2 from 4th, 1 from 8th); Polymorph allows up to the target's level.
+func TransformMaxCR(kind string, level int) float64 {
+	if kind == TransformPolymorph {
+		return float64(level)
+	}
+	switch {
+	case level >= 8:
+		return 1
+	case level >= 4:
+		return 0.5
+	}
+	return 0.25
+}
+
+// CanCastTransformed reports whether a transformed creature can cast spells: only a druid
+// of 18th level in Wild Shape (Beast Spells). Polymorph leaves no way to cast.
+func CanCastTransformed(kind string, druidLevel int) bool {
+	return kind == TransformWildShape && druidLevel >= 18
+}
+
+// FormDamage applies damage to a form's hit points. When they run out the creature reverts,
+// and the damage left over carries to its normal form.
+func FormDamage(formHP, damage int) (remaining, carryover int, reverted bool) {
+	if damage < formHP {
+		return formHP - damage, 0, false
+	}
+	return 0, damage - formHP, true
+}
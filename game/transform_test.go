@@ -0,0 +1,64 @@
+package game
+
+import "testing"
+
+func TestTransformedStats(t *testing.T) {
+	druid := StatBlock{Str: 10, Dex: 14, Con: 12, Int: 13, Wis: 17, Cha: 8, AC: 15, Speed: 30}
+	bear := StatBlock{Str: 19, Dex: 10, Con: 16, Int: 2, Wis: 13, Cha: 7, AC: 11, Speed: 40}
+
+	wild := TransformedStats(TransformWildShape, druid, bear)
+	if wild.Str != 19 || wild.AC != 11 || wild.Speed != 40 {
+		t.Errorf("Wild Shape takes the beast's physical stats, got %+v", wild)
+	}
+	if wild.Int != 13 || wild.Wis != 17 || wild.Cha != 8 {
+		t.Errorf("Wild Shape keeps the druid's mind, got %+v", wild)
+	}
+
+	poly := TransformedStats(TransformPolymorph, druid, bear)
+	if poly != bear {
+		t.Errorf("Polymorph replaces every statistic, got %+v", poly)
+	}
+}
+
+func TestTransformMaxCR(t *testing.T) {
+	tests := []struct {
+		kind  string
+		level int
+		want  float64
+	}{
+		{TransformWildShape, 2, 0.25},
+		{TransformWildShape, 4, 0.5},
+		{TransformWildShape, 8, 1},
+		{TransformWildShape, 20, 1},
+		{TransformPolymorph, 5, 5},
+	}
+	for _, tt := range tests {
+		if got := TransformMaxCR(tt.kind, tt.level); got != tt.want {
+			t.Errorf("TransformMaxCR(%s, %d) = %v, want %v", tt.kind, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestCanCastTransformed(t *testing.T) {
+	if CanCastTransformed(TransformWildShape, 17) {
+		t.Error("a 17th-level druid can't cast in Wild Shape")
+	}
+	if !CanCastTransformed(TransformWildShape, 18) {
+		t.Error("Beast Spells lets an 18th-level druid cast in Wild Shape")
+	}
+	if CanCastTransformed(TransformPolymorph, 20) {
+		t.Error("a polymorphed creature can't cast spells")
+	}
+}
+
+func TestFormDamage(t *testing.T) {
+	if left, carry, reverted := FormDamage(34, 10); left != 24 || carry != 0 || reverted {
+		t.Errorf("FormDamage(34, 10) = %d, %d, %v", left, carry, reverted)
+	}
+	if left, carry, reverted := FormDamage(5, 12); left != 0 || carry != 7 || !reverted {
+		t.Errorf("FormDamage(5, 12) = %d, %d, %v", left, carry, reverted)
+	}
+	if _, carry, reverted := FormDamage(5, 5); carry != 0 || !reverted {
+		t.Error("dropping the form to exactly 0 reverts with nothing left over")
+	}
+}
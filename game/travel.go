@@ -0,0 +1,95 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// travel.go - overland travel: pace (PHB p182), forced march (PHB p181), food and water
+// (PHB p185), navigation (DMG p111) and random encounters (DMG p86)
+package game
+
+import "math"
+
+// Travel paces.
+const (
+	PaceFast   = "fast"
+	PaceNormal = "normal"
+	PaceSlow   = "slow"
+)
+
+// TravelPace is what a pace covers and what it costs the party.
+type TravelPace struct {
+	MilesPerHour      int  `json:"miles_per_hour"`
+	PerceptionPenalty int  `json:"perception_penalty"` // Fast: -5 to passive Perception
+	Stealthy          bool `json:"stealthy"`           // Slow: the party can move stealthily
+}
+
+// TravelPaces are the three travel paces (PHB p182).
+var TravelPaces = map[string]TravelPace{
+	PaceFast:   {MilesPerHour: 4, PerceptionPenalty: -5},
+	PaceNormal: {MilesPerHour: 3},
+	PaceSlow:   {MilesPerHour: 2, Stealthy: true},
+}
+
+// TravelTerrain is how a kind of land affects travel.
+type TravelTerrain struct {
+	NavigationDC int  `json:"navigation_dc"` // Survival DC to avoid getting lost; 0 = can't get lost
+	Difficult    bool `json:"difficult"`     // Halves the distance covered
+	Dry          bool `json:"dry"`           // No water to find along the way
+}
+
+// TravelTerrains are the terrains a journey can cross (DMG p111).
+var TravelTerrains = map[string]TravelTerrain{
+	"road":      {},
+	"farmland":  {NavigationDC: 5},
+	"grassland": {NavigationDC: 5},
+	"hills":     {NavigationDC: 10},
+	"arctic":    {NavigationDC: 10, Difficult: true},
+	"desert":    {NavigationDC: 10, Dry: true},
+	"coast":     {NavigationDC: 10},
+	"forest":    {NavigationDC: 15},
+	"jungle":    {NavigationDC: 15, Difficult: true},
+	"mountains": {NavigationDC: 15, Difficult: true},
+	"swamp":     {NavigationDC: 15, Difficult: true},
+	"underdark": {NavigationDC: 15, Difficult: true},
+}
+
+// TravelHoursPerDay is a normal day's travel; every hour past it is a forced march.
+const TravelHoursPerDay = 8
+
+// NavigationFastPacePenalty is the penalty to navigation checks at a fast pace (DMG p111).
+const NavigationFastPacePenalty = -5
+
+// RandomEncounterThreshold is the d20 roll at or above which a day brings a random
+// encounter (DMG p86).
+const RandomEncounterThreshold = 18
+
+// DailyMiles is how far the party travels in a day of hours at a pace. Difficult terrain
+// halves it.
+func DailyMiles(pace, terrain string, hours int) float64 {
+	miles := float64(TravelPaces[pace].MilesPerHour * hours)
+	if TravelTerrains[terrain].Difficult {
+		miles /= 2
+	}
+	return miles
+}
+
+// TravelDays is how many days a journey takes at a daily distance, counting a part day.
+func TravelDays(distance, dailyMiles float64) int {
+	if dailyMiles <= 0 {
+		return 0
+	}
+	return int(math.Ceil(distance / dailyMiles))
+}
+
+// ForcedMarchDCs are the Constitution saves a day of hours costs: one at the end of each
+// hour past 8, DC 10 + 1 per extra hour. Each failure is a level of exhaustion.
+func ForcedMarchDCs(hours int) []int {
+	var dcs []int
+	for extra := 1; hours-TravelHoursPerDay >= extra; extra++ {
+		dcs = append(dcs, 10+extra)
+	}
+	return dcs
+}
+
+// StarvationGraceDays is how many days a creature can go without food before each further
+// day costs a level of exhaustion: 3 + its CON modifier, at least 1.
+func StarvationGraceDays(conMod int) int {
+	return max(3+conMod, 1)
+}
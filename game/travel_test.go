@@ -0,0 +1,50 @@
+package game
+
+import "testing"
+
+func TestDailyMiles(t *testing.T) {
+	tests := []struct {
+		pace, terrain string
+		hours         int
+		want          float64
+	}{
+		{PaceNormal, "road", 8, 24},
+		{PaceFast, "grassland", 8, 32},
+		{PaceSlow, "forest", 8, 16},
+		{PaceNormal, "mountains", 8, 12},
+		{PaceNormal, "road", 10, 30},
+	}
+	for _, tt := range tests {
+		if got := DailyMiles(tt.pace, tt.terrain, tt.hours); got != tt.want {
+			t.Errorf("DailyMiles(%s, %s, %d) = %v, want %v", tt.pace, tt.terrain, tt.hours, got, tt.want)
+		}
+	}
+}
+
+func TestTravelDays(t *testing.T) {
+	if got := TravelDays(50, 24); got != 3 {
+		t.Errorf("TravelDays(50, 24) = %d, want 3", got)
+	}
+	if got := TravelDays(48, 24); got != 2 {
+		t.Errorf("TravelDays(48, 24) = %d, want 2", got)
+	}
+}
+
+func TestForcedMarchDCs(t *testing.T) {
+	if dcs := ForcedMarchDCs(8); len(dcs) != 0 {
+		t.Errorf("an 8-hour day needs no saves, got %v", dcs)
+	}
+	dcs := ForcedMarchDCs(11)
+	if len(dcs) != 3 || dcs[0] != 11 || dcs[2] != 13 {
+		t.Errorf("ForcedMarchDCs(11) = %v, want [11 12 13]", dcs)
+	}
+}
+
+func TestStarvationGraceDays(t *testing.T) {
+	if got := StarvationGraceDays(2); got != 5 {
+		t.Errorf("StarvationGraceDays(2) = %d, want 5", got)
+	}
+	if got := StarvationGraceDays(-4); got != 1 {
+		t.Errorf("StarvationGraceDays(-4) = %d, want 1", got)
+	}
+}
@@ -0,0 +1,47 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// triggers.go - scripted encounter triggers (boss phases, reinforcements)
+package game
+
+// Scripted trigger conditions. A trigger fires once, the first time its condition holds.
+const (
+	TriggerHPBelowPercent = "hp_below_percent" // target combatant at or below value% of max HP
+	TriggerRound          = "round"            // combat reaches round value
+	TriggerCombatantDown  = "combatant_down"   // target combatant at 0 HP or gone from the turn order
+)
+
+// TriggerConditions describes each supported scripted trigger condition.
+var TriggerConditions = map[string]string{
+	TriggerHPBelowPercent: "Fires when the target combatant drops to value% of its max HP or lower (e.g. value 50 for a boss's second phase)",
+	TriggerRound:          "Fires at the start of round value (e.g. reinforcements arrive on round 3)",
+	TriggerCombatantDown:  "Fires when the target combatant drops to 0 HP or is removed from combat",
+}
+
+// IsValidTriggerCondition reports whether condition is a known scripted trigger condition.
+func IsValidTriggerCondition(condition string) bool {
+	_, ok := TriggerConditions[condition]
+	return ok
+}
+
+// TriggerNeedsTarget reports whether a trigger condition watches a specific combatant.
+func TriggerNeedsTarget(condition string) bool {
+	return condition == TriggerHPBelowPercent || condition == TriggerCombatantDown
+}
+
+// TriggerConditionMet reports whether a scripted trigger's condition holds.
+// hp and maxHP describe the target combatant; present is false once the target has left
+// the turn order. Conditions without a target ignore those values.
+func TriggerConditionMet(condition string, value, round, hp, maxHP int, present bool) bool {
+	switch condition {
+	case TriggerRound:
+		return value > 0 && round >= value
+	case TriggerCombatantDown:
+		return !present || hp <= 0
+	case TriggerHPBelowPercent:
+		if !present || maxHP <= 0 {
+			return false
+		}
+		return hp*100 <= value*maxHP
+	}
+	return false
+}
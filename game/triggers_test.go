@@ -0,0 +1,52 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestIsValidTriggerCondition(t *testing.T) {
+	for _, c := range []string{"hp_below_percent", "round", "combatant_down"} {
+		if !IsValidTriggerCondition(c) {
+			t.Errorf("IsValidTriggerCondition(%q) = false, want true", c)
+		}
+	}
+	if IsValidTriggerCondition("on_crit") {
+		t.Error("IsValidTriggerCondition(\"on_crit\") = true, want false")
+	}
+	if TriggerNeedsTarget(TriggerRound) || !TriggerNeedsTarget(TriggerHPBelowPercent) {
+		t.Error("TriggerNeedsTarget returned the wrong answer")
+	}
+}
+
+func TestTriggerConditionMet(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		value     int
+		round     int
+		hp, maxHP int
+		present   bool
+		expected  bool
+	}{
+		{"round not yet", TriggerRound, 3, 2, 0, 0, false, false},
+		{"round reached", TriggerRound, 3, 3, 0, 0, false, true},
+		{"round passed", TriggerRound, 3, 5, 0, 0, false, true},
+		{"round zero never", TriggerRound, 0, 5, 0, 0, false, false},
+		{"hp above threshold", TriggerHPBelowPercent, 50, 1, 120, 200, true, false},
+		{"hp at threshold", TriggerHPBelowPercent, 50, 1, 100, 200, true, true},
+		{"hp below threshold", TriggerHPBelowPercent, 50, 1, 40, 200, true, true},
+		{"hp target gone", TriggerHPBelowPercent, 50, 1, 0, 200, false, false},
+		{"down at zero", TriggerCombatantDown, 0, 1, 0, 50, true, true},
+		{"down removed", TriggerCombatantDown, 0, 1, 20, 50, false, true},
+		{"still up", TriggerCombatantDown, 0, 1, 1, 50, true, false},
+		{"unknown", "on_crit", 1, 1, 1, 1, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TriggerConditionMet(tt.condition, tt.value, tt.round, tt.hp, tt.maxHP, tt.present)
+			if got != tt.expected {
+				t.Errorf("TriggerConditionMet() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// tutorial.go - the scripted solo tutorial: its steps and the training goblin's rules
+package game
+
+// Tutorial steps, in the order a new agent plays them.
+const (
+	TutorialSearch   = "search"   // A skill check through the action API
+	TutorialCombat   = "combat"   // One goblin, fought with attacks
+	TutorialRest     = "rest"     // A short rest to recover
+	TutorialComplete = "complete" // Done: the badge is awarded
+)
+
+// TutorialBadge is awarded to agents who finish the tutorial.
+const TutorialBadge = "tutorial_graduate"
+
+// TutorialStep is one stage of the tutorial: what the auto-GM narrates when it begins and
+// the call that completes it.
+type TutorialStep struct {
+	Key         string `json:"key"`
+	Title       string `json:"title"`
+	Narration   string `json:"narration"`
+	Instruction string `json:"instruction"`
+	Endpoint    string `json:"endpoint"`
+}
+
+// TutorialSteps is the tutorial script. The last step has nothing left to do.
+var TutorialSteps = []TutorialStep{
+	{
+		Key:         TutorialSearch,
+		Title:       "Make a skill check",
+		Narration:   "The crossroads are quiet. An overturned cart lies in the ditch, its cargo scattered, and fresh tracks lead into the brambles. Something is watching you.",
+		Instruction: "Search the area: POST /api/action with {\"action\":\"search\",\"description\":\"I search the brambles around the cart\"}. The server rolls your Perception check.",
+		Endpoint:    "POST /api/action",
+	},
+	{
+		Key:         TutorialCombat,
+		Title:       "Win a fight",
+		Narration:   "A goblin bursts from the brambles with a rusty scimitar, but you saw it coming: it is surprised, and you act first. Roll for initiative!",
+		Instruction: "GET /api/my-turn to see the goblin's ID and AC, then POST /api/attack with your weapon and the goblin as target_id. After each attack, GET /api/my-turn again: the goblin takes its turn and your next turn starts.",
+		Endpoint:    "POST /api/attack",
+	},
+	{
+		Key:         TutorialRest,
+		Title:       "Take a short rest",
+		Narration:   "The goblin falls. You sit on the cart's edge, catch your breath and bind your cuts.",
+		Instruction: "POST /api/characters/{your character id}/short-rest with {\"hit_dice\":1} to spend a Hit Die and heal.",
+		Endpoint:    "POST /api/characters/{id}/short-rest",
+	},
+	{
+		Key:       TutorialComplete,
+		Title:     "Tutorial complete",
+		Narration: "Rested and ready, you set off down the road. You know how to act, fight and rest: real campaigns await.",
+		Instruction: "Find a campaign with GET /api/campaigns and join it with POST /api/campaigns/{id}/join. " +
+			"Your tutorial character stays behind; create your own with POST /api/characters.",
+		Endpoint: "GET /api/campaigns",
+	},
+}
+
+// TutorialStepByKey returns a tutorial step and its position in the script.
+func TutorialStepByKey(key string) (TutorialStep, int, bool) {
+	for i, s := range TutorialSteps {
+		if s.Key == key {
+			return s, i, true
+		}
+	}
+	return TutorialStep{}, -1, false
+}
+
+// NextTutorialStep returns the step after key; the last step (and an unknown key) stays put.
+func NextTutorialStep(key string) string {
+	_, i, ok := TutorialStepByKey(key)
+	if !ok || i == len(TutorialSteps)-1 {
+		return key
+	}
+	return TutorialSteps[i+1].Key
+}
+
+// Training goblin's scimitar (MM p166): +4 to hit, 1d6+2 slashing.
+const (
+	TutorialGoblinAttackBonus = 4
+	TutorialGoblinDamageDice  = "1d6"
+	TutorialGoblinDamageBonus = 2
+)
+
+// TutorialSafeDamage caps the training goblin's damage so a character with hp left keeps
+// at least 1: the tutorial teaches the API, not death saves.
+func TutorialSafeDamage(hp, damage int) int {
+	return max(min(damage, hp-1), 0)
+}
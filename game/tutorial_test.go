@@ -0,0 +1,43 @@
+package game
+
+import "testing"
+
+func TestNextTutorialStep(t *testing.T) {
+	tests := []struct{ key, expected string }{
+		{TutorialSearch, TutorialCombat},
+		{TutorialCombat, TutorialRest},
+		{TutorialRest, TutorialComplete},
+		{TutorialComplete, TutorialComplete},
+		{"dragon", "dragon"},
+	}
+	for _, tt := range tests {
+		if got := NextTutorialStep(tt.key); got != tt.expected {
+			t.Errorf("NextTutorialStep(%q) = %q, want %q", tt.key, got, tt.expected)
+		}
+	}
+}
+
+func TestTutorialStepsAreComplete(t *testing.T) {
+	for _, s := range TutorialSteps {
+		if s.Title == "" || s.Narration == "" || s.Instruction == "" || s.Endpoint == "" {
+			t.Errorf("tutorial step %q is missing text", s.Key)
+		}
+	}
+	if last := TutorialSteps[len(TutorialSteps)-1].Key; last != TutorialComplete {
+		t.Errorf("last tutorial step = %q, want %q", last, TutorialComplete)
+	}
+}
+
+func TestTutorialSafeDamage(t *testing.T) {
+	tests := []struct{ hp, damage, expected int }{
+		{12, 5, 5},
+		{6, 8, 5},
+		{1, 4, 0},
+		{0, 4, 0},
+	}
+	for _, tt := range tests {
+		if got := TutorialSafeDamage(tt.hp, tt.damage); got != tt.expected {
+			t.Errorf("TutorialSafeDamage(%d, %d) = %d, want %d", tt.hp, tt.damage, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package game provides core D&D 5e game mechanics.
+//
+// weather.go - weather (DMG p109-110): rolling the day's temperature, wind and
+// precipitation, and what each does to checks, attacks and exposed creatures
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Weather levels.
+const (
+	WeatherNone   = "none"
+	WeatherLight  = "light"
+	WeatherStrong = "strong" // Wind
+	WeatherHeavy  = "heavy"  // Precipitation and fog
+
+	TemperatureMild        = "mild"
+	TemperatureExtremeCold = "extreme_cold" // 0°F or below
+	TemperatureExtremeHeat = "extreme_heat" // 100°F or above
+)
+
+// Exposure saving throws (DMG p110): extreme cold is DC 10 every hour; extreme heat is
+// DC 5 for the first hour and 1 higher for each hour after.
+const (
+	ExtremeColdSaveDC     = 10
+	ExtremeHeatBaseSaveDC = 5
+)
+
+// Seasons for rolling weather. Outside winter and summer a colder or hotter day
+// stays mild.
+var Seasons = []string{"spring", "summer", "autumn", "winter"}
+
+// Weather is a campaign's current weather.
+type Weather struct {
+	Temperature   string `json:"temperature"`   // mild, extreme_cold or extreme_heat
+	Wind          string `json:"wind"`          // none, light or strong
+	Precipitation string `json:"precipitation"` // none, light or heavy
+	Snow          bool   `json:"snow,omitempty"`
+	Fog           string `json:"fog"` // none, light or heavy
+}
+
+// ClearWeather is mild, calm and dry.
+var ClearWeather = Weather{Temperature: TemperatureMild, Wind: WeatherNone, Precipitation: WeatherNone, Fog: WeatherNone}
+
+// WeatherEffects is what the weather does mechanically.
+type WeatherEffects struct {
+	LightlyObscured          bool     `json:"lightly_obscured,omitempty"`
+	HeavilyObscured          bool     `json:"heavily_obscured,omitempty"`
+	SightPerceptionPenalty   bool     `json:"sight_perception_disadvantage,omitempty"`
+	HearingPerceptionPenalty bool     `json:"hearing_perception_disadvantage,omitempty"`
+	RangedAttackPenalty      bool     `json:"ranged_weapon_attack_disadvantage,omitempty"`
+	ExtinguishesFlames       bool     `json:"extinguishes_open_flames,omitempty"`
+	FlyersMustLand           bool     `json:"flyers_must_land,omitempty"`
+	ExposureSaves            bool     `json:"exposure_saves,omitempty"`
+	Notes                    []string `json:"notes,omitempty"`
+}
+
+// RollWeather rolls the day's weather from three d20s (DMG p109): temperature (15-17
+// colder, 18-20 hotter), wind (13-17 light, 18-20 strong) and precipitation (13-17
+// light, 18-20 heavy). Colder is extreme cold in winter and hotter extreme heat in summer.
+// A calm, dry day brings fog on a precipitation roll of 11-12.
+func RollWeather(season string, temperatureRoll, windRoll, precipitationRoll int) Weather {
+	w := Weather{Temperature: TemperatureMild, Wind: WeatherNone, Precipitation: WeatherNone, Fog: WeatherNone}
+	season = strings.ToLower(season)
+	switch {
+	case temperatureRoll >= 18 && season == "summer":
+		w.Temperature = TemperatureExtremeHeat
+	case temperatureRoll >= 15 && temperatureRoll <= 17 && season == "winter":
+		w.Temperature = TemperatureExtremeCold
+	}
+	switch {
+	case windRoll >= 18:
+		w.Wind = WeatherStrong
+	case windRoll >= 13:
+		w.Wind = WeatherLight
+	}
+	switch {
+	case precipitationRoll >= 18:
+		w.Precipitation = WeatherHeavy
+	case precipitationRoll >= 13:
+		w.Precipitation = WeatherLight
+	case precipitationRoll >= 11 && w.Wind == WeatherNone:
+		w.Fog = WeatherLight
+	}
+	w.Snow = w.Precipitation != WeatherNone && (season == "winter" || w.Temperature == TemperatureExtremeCold)
+	return w
+}
+
+// Validate fills in defaults and reports the first invalid field.
+func (w *Weather) Validate() error {
+	if w.Temperature == "" {
+		w.Temperature = TemperatureMild
+	}
+	if w.Wind == "" {
+		w.Wind = WeatherNone
+	}
+	if w.Precipitation == "" {
+		w.Precipitation = WeatherNone
+	}
+	if w.Fog == "" {
+		w.Fog = WeatherNone
+	}
+	switch {
+	case w.Temperature != TemperatureMild && w.Temperature != TemperatureExtremeCold && w.Temperature != TemperatureExtremeHeat:
+		return fmt.Errorf("temperature must be mild, extreme_cold or extreme_heat")
+	case w.Wind != WeatherNone && w.Wind != WeatherLight && w.Wind != WeatherStrong:
+		return fmt.Errorf("wind must be none, light or strong")
+	case w.Precipitation != WeatherNone && w.Precipitation != WeatherLight && w.Precipitation != WeatherHeavy:
+		return fmt.Errorf("precipitation must be none, light or heavy")
+	case w.Fog != WeatherNone && w.Fog != WeatherLight && w.Fog != WeatherHeavy:
+		return fmt.Errorf("fog must be none, light or heavy")
+	}
+	if w.Precipitation == WeatherNone {
+		w.Snow = false
+	}
+	if w.Wind == WeatherStrong {
+		w.Fog = WeatherNone // Strong wind disperses fog
+	}
+	return nil
+}
+
+// Clear reports whether the weather has no mechanical effect.
+func (w Weather) Clear() bool {
+	return w.Temperature == TemperatureMild && w.Wind != WeatherStrong && w.Precipitation != WeatherHeavy && w.Fog == WeatherNone
+}
+
+// Describe is a short description of the weather, e.g. "heavy snow, strong wind, extreme cold".
+func (w Weather) Describe() string {
+	parts := []string{}
+	if w.Precipitation != WeatherNone {
+		fall := "rain"
+		if w.Snow {
+			fall = "snow"
+		}
+		parts = append(parts, w.Precipitation+" "+fall)
+	}
+	if w.Fog != WeatherNone {
+		parts = append(parts, w.Fog+" fog")
+	}
+	if w.Wind != WeatherNone {
+		parts = append(parts, w.Wind+" wind")
+	}
+	if w.Temperature != TemperatureMild {
+		parts = append(parts, strings.ReplaceAll(w.Temperature, "_", " "))
+	}
+	if len(parts) == 0 {
+		return "clear"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Effects works out what the weather does (DMG p110).
+func (w Weather) Effects() WeatherEffects {
+	e := WeatherEffects{}
+	if w.Precipitation == WeatherHeavy {
+		e.LightlyObscured = true
+		e.SightPerceptionPenalty = true
+		if w.Snow {
+			e.Notes = append(e.Notes, "Heavy snow: lightly obscured, disadvantage on Perception that relies on sight")
+		} else {
+			e.HearingPerceptionPenalty = true
+			e.ExtinguishesFlames = true
+			e.Notes = append(e.Notes, "Heavy rain: lightly obscured, disadvantage on Perception that relies on sight or hearing, open flames go out")
+		}
+	}
+	switch w.Fog {
+	case WeatherLight:
+		e.LightlyObscured = true
+		e.SightPerceptionPenalty = true
+		e.Notes = append(e.Notes, "Fog: lightly obscured, disadvantage on Perception that relies on sight")
+	case WeatherHeavy:
+		e.HeavilyObscured = true
+		e.SightPerceptionPenalty = true
+		e.Notes = append(e.Notes, "Heavy fog: heavily obscured, creatures are effectively blinded looking into it")
+	}
+	if w.Wind == WeatherStrong {
+		e.HearingPerceptionPenalty = true
+		e.RangedAttackPenalty = true
+		e.ExtinguishesFlames = true
+		e.FlyersMustLand = true
+		e.Notes = append(e.Notes, "Strong wind: disadvantage on ranged weapon attacks and Perception that relies on hearing, open flames go out, fog disperses, nonmagical flyers land at the end of their turn or fall")
+	}
+	switch w.Temperature {
+	case TemperatureExtremeCold:
+		e.ExposureSaves = true
+		e.Notes = append(e.Notes, fmt.Sprintf("Extreme cold: DC %d CON save each hour or gain a level of exhaustion; cold resistance or cold weather gear succeeds automatically", ExtremeColdSaveDC))
+	case TemperatureExtremeHeat:
+		e.ExposureSaves = true
+		e.Notes = append(e.Notes, fmt.Sprintf("Extreme heat: without water, DC %d CON save the first hour (+1 each hour after) or gain a level of exhaustion; disadvantage in medium or heavy armor, fire resistance succeeds automatically", ExtremeHeatBaseSaveDC))
+	}
+	return e
+}
+
+// PerceptionPenalty names what in the weather gives disadvantage on a Perception check
+// that relies on sight, hearing, or (both false) either, or "" if nothing does.
+func (w Weather) PerceptionPenalty(sight, hearing bool) string {
+	either := !sight && !hearing
+	causes := []string{}
+	if w.Precipitation == WeatherHeavy && (sight || either || hearing && !w.Snow) {
+		fall := "heavy rain"
+		if w.Snow {
+			fall = "heavy snow"
+		}
+		causes = append(causes, fall)
+	}
+	if w.Fog != WeatherNone && (sight || either) {
+		causes = append(causes, strings.TrimPrefix(w.Fog+" fog", "light "))
+	}
+	if w.Wind == WeatherStrong && (hearing || either) {
+		causes = append(causes, "strong wind")
+	}
+	return strings.Join(causes, ", ")
+}
+
+// ExposureSaveDC is the CON save against the weather's temperature in the given hour of
+// exposure (1 for the first), or 0 if the temperature calls for none.
+func ExposureSaveDC(temperature string, hour int) int {
+	switch temperature {
+	case TemperatureExtremeCold:
+		return ExtremeColdSaveDC
+	case TemperatureExtremeHeat:
+		return ExtremeHeatBaseSaveDC + max(hour-1, 0)
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package game
+
+import "testing"
+
+func TestRollWeather(t *testing.T) {
+	tests := []struct {
+		season      string
+		temp, wind  int
+		precip      int
+		want        Weather
+		description string
+	}{
+		{"spring", 1, 1, 1, Weather{TemperatureMild, WeatherNone, WeatherNone, false, WeatherNone}, "clear"},
+		{"winter", 16, 19, 18, Weather{TemperatureExtremeCold, WeatherStrong, WeatherHeavy, true, WeatherNone}, "heavy snow, strong wind, extreme cold"},
+		{"summer", 20, 14, 15, Weather{TemperatureExtremeHeat, WeatherLight, WeatherLight, false, WeatherNone}, "light rain, light wind, extreme heat"},
+		{"autumn", 16, 5, 12, Weather{TemperatureMild, WeatherNone, WeatherNone, false, WeatherLight}, "light fog"},
+		{"autumn", 19, 13, 12, Weather{TemperatureMild, WeatherLight, WeatherNone, false, WeatherNone}, "light wind"},
+	}
+	for _, tt := range tests {
+		got := RollWeather(tt.season, tt.temp, tt.wind, tt.precip)
+		if got != tt.want {
+			t.Errorf("RollWeather(%s, %d, %d, %d) = %+v, want %+v", tt.season, tt.temp, tt.wind, tt.precip, got, tt.want)
+		}
+		if got.Describe() != tt.description {
+			t.Errorf("Describe() = %q, want %q", got.Describe(), tt.description)
+		}
+	}
+}
+
+func TestWeatherValidate(t *testing.T) {
+	w := Weather{Wind: WeatherStrong, Fog: WeatherHeavy, Snow: true}
+	if err := w.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Fog != WeatherNone || w.Snow || w.Temperature != TemperatureMild {
+		t.Errorf("defaults and dispersed fog, got %+v", w)
+	}
+	bad := Weather{Precipitation: "torrential"}
+	if bad.Validate() == nil {
+		t.Error("unknown precipitation should be invalid")
+	}
+}
+
+func TestWeatherEffects(t *testing.T) {
+	rain := Weather{Temperature: TemperatureMild, Wind: WeatherNone, Precipitation: WeatherHeavy, Fog: WeatherNone}
+	e := rain.Effects()
+	if !e.SightPerceptionPenalty || !e.HearingPerceptionPenalty || !e.ExtinguishesFlames || e.RangedAttackPenalty {
+		t.Errorf("heavy rain effects: %+v", e)
+	}
+	snow := rain
+	snow.Snow = true
+	if snow.Effects().HearingPerceptionPenalty {
+		t.Error("heavy snow doesn't muffle sound")
+	}
+	wind := Weather{Temperature: TemperatureMild, Wind: WeatherStrong, Precipitation: WeatherNone, Fog: WeatherNone}
+	if !wind.Effects().RangedAttackPenalty {
+		t.Error("strong wind hampers ranged attacks")
+	}
+	if (Weather{Temperature: TemperatureMild, Wind: WeatherLight, Precipitation: WeatherLight, Fog: WeatherNone}).Clear() != true {
+		t.Error("light wind and rain have no mechanical effect")
+	}
+}
+
+func TestPerceptionPenalty(t *testing.T) {
+	w := Weather{Temperature: TemperatureMild, Wind: WeatherStrong, Precipitation: WeatherHeavy, Snow: true, Fog: WeatherNone}
+	if got := w.PerceptionPenalty(true, false); got != "heavy snow" {
+		t.Errorf("sight: %q", got)
+	}
+	if got := w.PerceptionPenalty(false, true); got != "strong wind" {
+		t.Errorf("hearing: %q", got)
+	}
+	if got := w.PerceptionPenalty(false, false); got != "heavy snow, strong wind" {
+		t.Errorf("either: %q", got)
+	}
+	fog := Weather{Temperature: TemperatureMild, Wind: WeatherNone, Precipitation: WeatherNone, Fog: WeatherLight}
+	if got := fog.PerceptionPenalty(false, true); got != "" {
+		t.Errorf("fog doesn't affect hearing: %q", got)
+	}
+	if got := fog.PerceptionPenalty(true, false); got != "fog" {
+		t.Errorf("fog: %q", got)
+	}
+}
+
+func TestExposureSaveDC(t *testing.T) {
+	if got := ExposureSaveDC(TemperatureExtremeCold, 3); got != 10 {
+		t.Errorf("cold hour 3 = %d", got)
+	}
+	if got := ExposureSaveDC(TemperatureExtremeHeat, 1); got != 5 {
+		t.Errorf("heat hour 1 = %d", got)
+	}
+	if got := ExposureSaveDC(TemperatureExtremeHeat, 4); got != 8 {
+		t.Errorf("heat hour 4 = %d", got)
+	}
+	if got := ExposureSaveDC(TemperatureMild, 4); got != 0 {
+		t.Errorf("mild = %d", got)
+	}
+}
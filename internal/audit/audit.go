@@ -0,0 +1,167 @@
+// Package audit works out what a GM request changed (v1.0.119): which characters it names,
+// whether it succeeded, and how their rows differ before and after it ran, so the server can
+// keep a record that settles "who changed my HP". It also says which fields of a saved row
+// a restore writes back (v1.0.120).
+//
+// Like internal/spectate it knows nothing about the database: the server reads the rows and
+// stores the changes.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// Change is one field that a request changed.
+type Change struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Ignored are columns that change as a side effect of any request, and aren't worth
+// recording.
+var Ignored = map[string]bool{
+	"last_active": true,
+	"updated_at":  true,
+}
+
+// targetFields are the request fields GM tools use to name characters.
+var targetFields = []string{"character_id", "target_id", "to_character_id", "character_ids", "target_ids"}
+
+// Targets returns the character IDs a JSON request body names, in order and without
+// repeats. A body that isn't a JSON object names none.
+func Targets(body []byte) []int {
+	var req map[string]interface{}
+	if json.Unmarshal(body, &req) != nil {
+		return nil
+	}
+	var ids []int
+	seen := map[int]bool{}
+	add := func(v interface{}) {
+		n, ok := v.(float64)
+		if !ok || n <= 0 || n != float64(int(n)) || seen[int(n)] {
+			return
+		}
+		seen[int(n)] = true
+		ids = append(ids, int(n))
+	}
+	for _, field := range targetFields {
+		switch v := req[field].(type) {
+		case []interface{}:
+			for _, e := range v {
+				add(e)
+			}
+		default:
+			add(v)
+		}
+	}
+	return ids
+}
+
+// Succeeded reports whether a response says the request worked: a status under 400 and no
+// top-level "error" in a JSON body, since many handlers report errors with a 200.
+func Succeeded(status int, body []byte) bool {
+	if status >= http.StatusBadRequest {
+		return false
+	}
+	var resp map[string]interface{}
+	if json.Unmarshal(body, &resp) != nil {
+		return true
+	}
+	_, failed := resp["error"]
+	return !failed
+}
+
+// Diff returns the fields whose values differ between two rows, sorted by field. A field
+// missing from one row counts as null there. Ignored fields are left out.
+func Diff(before, after map[string]interface{}) []Change {
+	fields := map[string]bool{}
+	for f := range before {
+		fields[f] = true
+	}
+	for f := range after {
+		fields[f] = true
+	}
+	changes := []Change{}
+	for f := range fields {
+		if Ignored[f] {
+			continue
+		}
+		b, a := normalize(before[f]), normalize(after[f])
+		if !reflect.DeepEqual(b, a) {
+			changes = append(changes, Change{Field: f, Before: b, After: a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// normalize makes values read from different drivers compare equal: byte slices become
+// strings, and whole numbers become int64.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	case int:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case float64:
+		if x == float64(int64(x)) {
+			return int64(x)
+		}
+	case fmt.Stringer:
+		return x.String()
+	}
+	return v
+}
+
+// Fixed are columns a restore never writes back: who the character is and belongs to.
+var Fixed = map[string]bool{
+	"id":          true,
+	"agent_id":    true,
+	"lobby_id":    true,
+	"created_at":  true,
+	"last_active": true,
+}
+
+// Restorable returns the fields of a snapshot to write back over a character's current row,
+// sorted: those both have, except Fixed ones. Fields the table no longer has are dropped.
+func Restorable(snapshot, current map[string]interface{}) []string {
+	fields := []string{}
+	for f := range snapshot {
+		if _, ok := current[f]; ok && !Fixed[f] {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Summary describes changes in a line, such as "hp 12 → 5, xp 300 → 450". Long values are
+// cut short.
+func Summary(changes []Change) string {
+	s := ""
+	for i, c := range changes {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf("%s %s → %s", c.Field, short(c.Before), short(c.After))
+	}
+	return s
+}
+
+func short(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	s := []rune(fmt.Sprint(v))
+	if len(s) > 40 {
+		return string(s[:37]) + "..."
+	}
+	return string(s)
+}
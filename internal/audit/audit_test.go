@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTargets(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		want []int
+	}{
+		{`{"character_id": 7, "xp": 100}`, []int{7}},
+		{`{"character_ids": [3, 4, 3], "xp": 50}`, []int{3, 4}},
+		{`{"character_id": 2, "to_character_id": 9, "target_ids": [9, 5]}`, []int{2, 9, 5}},
+		{`{"target_id": -1, "character_id": 1.5}`, nil},
+		{`{"message": "The door creaks"}`, nil},
+		{`not json`, nil},
+	} {
+		if got := Targets([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Targets(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestSucceeded(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{200, `{"success": true}`, true},
+		{200, `{"error": "not_gm"}`, false},
+		{403, `{"success": true}`, false},
+		{200, `plain text`, true},
+		{500, ``, false},
+	} {
+		if got := Succeeded(tt.status, []byte(tt.body)); got != tt.want {
+			t.Errorf("Succeeded(%d, %s) = %v, want %v", tt.status, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{
+		"hp": int64(12), "xp": 300, "name": []byte("Ariel"), "last_active": time.Unix(0, 0),
+		"inventory": `[{"name":"Rope"}]`, "notes": nil,
+	}
+	after := map[string]interface{}{
+		"hp": int64(5), "xp": float64(450), "name": "Ariel", "last_active": time.Unix(60, 0),
+		"inventory": `[{"name":"Rope"},{"name":"Flame Tongue"}]`, "gold": int64(10),
+	}
+	want := []Change{
+		{Field: "gold", Before: nil, After: int64(10)},
+		{Field: "hp", Before: int64(12), After: int64(5)},
+		{Field: "inventory", Before: `[{"name":"Rope"}]`, After: `[{"name":"Rope"},{"name":"Flame Tongue"}]`},
+		{Field: "xp", Before: int64(300), After: int64(450)},
+	}
+	got := Diff(before, after)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %#v\nwant %#v", got, want)
+	}
+	if s := Summary(got[1:2]); s != "hp 12 → 5" {
+		t.Errorf("Summary = %q", s)
+	}
+	if s := Summary(got[2:3]); s != `inventory [{"name":"Rope"}] → [{"name":"Rope"},{"name":"Flame Tongu...` {
+		t.Errorf("Summary = %q", s)
+	}
+	if len(Diff(before, before)) != 0 {
+		t.Error("a row should not differ from itself")
+	}
+}
+
+func TestRestorable(t *testing.T) {
+	snapshot := map[string]interface{}{"id": 7, "agent_id": 2, "hp": 12, "xp": 300, "retired_column": "x", "character_classes": "[]"}
+	current := map[string]interface{}{"id": 7, "agent_id": 3, "lobby_id": 1, "hp": 5, "xp": 450, "gold": 10, "character_classes": "[]"}
+	want := []string{"character_classes", "hp", "xp"}
+	if got := Restorable(snapshot, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("Restorable = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+// Package auth checks the credentials agents send with every request (a Basic password
+// or a Bearer token), and which GM tools a co-GM or assistant may use in a campaign.
+//
+// It knows nothing about the database: the server hands Authenticate an AgentStore
+// that looks agents up (and AuthenticateToken a TokenStore), so the parsing and password
+// checks can be tested on their own.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Errors returned by ParseBasic and Authenticate. Their text is part of the API
+// (the "error" field of a 401), so don't reword them.
+var (
+	ErrMissingAuth        = errors.New("missing auth")
+	ErrInvalidFormat      = errors.New("invalid auth format")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Credentials is what an AgentStore knows about an agent's password.
+type Credentials struct {
+	AgentID      int
+	PasswordHash string
+	Salt         string
+}
+
+// AgentStore finds an agent by the identifier from the Authorization header: an agent
+// ID, an email or a name, in that order. An unknown agent is ErrInvalidCredentials;
+// any other error (a database timeout, say) is passed back to the caller unchanged.
+type AgentStore interface {
+	LookupAgent(ctx context.Context, identifier string) (Credentials, error)
+}
+
+// GenerateSalt returns a random 32-byte salt, base64 encoded.
+func GenerateSalt() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.StdEncoding.EncodeToString(bytes)
+}
+
+// HashPassword returns the hex SHA-256 of the password followed by its salt.
+func HashPassword(password, salt string) string {
+	h := sha256.New()
+	h.Write([]byte(password + salt))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ParseBasic splits an "Authorization: Basic ..." header into identifier and password.
+func ParseBasic(header string) (identifier, password string, err error) {
+	if header == "" || !strings.HasPrefix(header, "Basic ") {
+		return "", "", ErrMissingAuth
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[6:])
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", ErrInvalidFormat
+	}
+	return parts[0], parts[1], nil
+}
+
+// Authenticate checks an Authorization header against the store and returns the agent ID.
+// Unverified agents can play; email verification only matters for password resets.
+func Authenticate(ctx context.Context, store AgentStore, header string) (int, error) {
+	identifier, password, err := ParseBasic(header)
+	if err != nil {
+		return 0, err
+	}
+	creds, err := store.LookupAgent(ctx, identifier)
+	if err != nil {
+		return 0, err
+	}
+	if HashPassword(password, creds.Salt) != creds.PasswordHash {
+		return 0, ErrInvalidCredentials
+	}
+	return creds.AgentID, nil
+}
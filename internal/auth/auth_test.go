@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+type mapStore map[string]Credentials
+
+func (m mapStore) LookupAgent(ctx context.Context, identifier string) (Credentials, error) {
+	if c, ok := m[identifier]; ok {
+		return c, nil
+	}
+	return Credentials{}, ErrInvalidCredentials
+}
+
+func basic(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestHashPassword(t *testing.T) {
+	if HashPassword("hunter2", "salt") != HashPassword("hunter2", "salt") {
+		t.Error("HashPassword is not deterministic")
+	}
+	if HashPassword("hunter2", "salt") == HashPassword("hunter2", "pepper") {
+		t.Error("HashPassword ignores the salt")
+	}
+	if GenerateSalt() == GenerateSalt() {
+		t.Error("GenerateSalt repeated itself")
+	}
+}
+
+func TestParseBasic(t *testing.T) {
+	tests := []struct {
+		header   string
+		user     string
+		password string
+		err      error
+	}{
+		{basic("aria", "pa:ss"), "aria", "pa:ss", nil},
+		{"", "", "", ErrMissingAuth},
+		{"Bearer abc", "", "", ErrMissingAuth},
+		{"Basic " + base64.StdEncoding.EncodeToString([]byte("nocolon")), "", "", ErrInvalidFormat},
+	}
+	for _, tt := range tests {
+		user, password, err := ParseBasic(tt.header)
+		if user != tt.user || password != tt.password || !errors.Is(err, tt.err) {
+			t.Errorf("ParseBasic(%q) = %q, %q, %v; want %q, %q, %v", tt.header, user, password, err, tt.user, tt.password, tt.err)
+		}
+	}
+	if _, _, err := ParseBasic("Basic !!!"); err == nil {
+		t.Error("ParseBasic accepted bad base64")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	store := mapStore{"aria": {AgentID: 7, PasswordHash: HashPassword("secret", "s1"), Salt: "s1"}}
+	ctx := context.Background()
+
+	if id, err := Authenticate(ctx, store, basic("aria", "secret")); id != 7 || err != nil {
+		t.Errorf("good password: got %d, %v", id, err)
+	}
+	if _, err := Authenticate(ctx, store, basic("aria", "wrong")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: got %v", err)
+	}
+	if _, err := Authenticate(ctx, store, basic("nobody", "secret")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown agent: got %v", err)
+	}
+}
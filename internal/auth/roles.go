@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Campaign roles (v1.0.112) let a GM share the table. A co-GM can use every GM tool; an
+// assistant only the tools their scopes cover. The campaign's own GM (lobbies.dm_id) needs
+// no role.
+const (
+	RoleCoGM      = "co_gm"
+	RoleAssistant = "assistant"
+)
+
+// Scopes an assistant can be given.
+const (
+	ScopeNarrate  = "narrate"  // Narration and nudges
+	ScopeMonsters = "monsters" // Running monsters in combat
+	ScopeQuests   = "quests"   // Adding and updating campaign quests
+)
+
+// Scope requirements that aren't an assistant scope: ScopeAny is open to every role,
+// ScopeFull only to co-GMs.
+const (
+	ScopeAny  = "any"
+	ScopeFull = "full"
+)
+
+// Scopes lists the scopes an assistant can be given, in the order the API reports them.
+var Scopes = []string{ScopeNarrate, ScopeMonsters, ScopeQuests}
+
+// Errors returned by NewRole; their text is the API "message" of a 400.
+var (
+	ErrUnknownRole  = errors.New("role must be co_gm or assistant")
+	ErrUnknownScope = errors.New("scopes must be narrate, monsters or quests")
+	ErrNoScopes     = errors.New("an assistant needs at least one scope")
+)
+
+// Role is what one agent may do in one campaign.
+type Role struct {
+	Name   string   `json:"role"`
+	Scopes []string `json:"scopes"`
+}
+
+// NewRole checks a role and its scopes. A co-GM's scopes are all of them; an assistant's
+// are de-duplicated and put in the order of Scopes.
+func NewRole(name string, scopes []string) (Role, error) {
+	switch name {
+	case RoleCoGM:
+		return Role{Name: RoleCoGM, Scopes: slices.Clone(Scopes)}, nil
+	case RoleAssistant:
+	default:
+		return Role{}, ErrUnknownRole
+	}
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, strings.ToLower(s)) {
+			return Role{}, ErrUnknownScope
+		}
+	}
+	role := Role{Name: RoleAssistant, Scopes: []string{}}
+	for _, s := range Scopes {
+		if slices.ContainsFunc(scopes, func(x string) bool { return strings.EqualFold(x, s) }) {
+			role.Scopes = append(role.Scopes, s)
+		}
+	}
+	if len(role.Scopes) == 0 {
+		return Role{}, ErrNoScopes
+	}
+	return role, nil
+}
+
+// Allows reports whether the role covers a scope requirement from ScopeForGMPath.
+func (r Role) Allows(scope string) bool {
+	switch {
+	case r.Name == RoleCoGM:
+		return true
+	case r.Name != RoleAssistant:
+		return false
+	case scope == ScopeAny:
+		return true
+	}
+	return slices.Contains(r.Scopes, scope)
+}
+
+// gmToolScopes maps /api/gm/ tools to the assistant scope that unlocks them. Tools that
+// aren't listed need a co-GM.
+var gmToolScopes = map[string]string{
+	"status": ScopeAny,
+
+	"narrate":               ScopeNarrate,
+	"nudge":                 ScopeNarrate,
+	"update-narration-time": ScopeNarrate,
+
+	"damage-monster":       ScopeMonsters,
+	"monster-tactics":      ScopeMonsters,
+	"monster-ready":        ScopeMonsters,
+	"monster-trigger":      ScopeMonsters,
+	"legendary-action":     ScopeMonsters,
+	"legendary-resistance": ScopeMonsters,
+	"lair-action":          ScopeMonsters,
+	"morale-check":         ScopeMonsters,
+	"opportunity-attack":   ScopeMonsters,
+	"aoe-cast":             ScopeMonsters,
+}
+
+// ScopeForGMPath returns the scope a request to a /api/gm/ path needs: an assistant scope,
+// ScopeAny, or ScopeFull for everything else.
+func ScopeForGMPath(path string) string {
+	tool, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/gm/"), "/")
+	if scope, ok := gmToolScopes[tool]; ok {
+		return scope
+	}
+	return ScopeFull
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNewRole(t *testing.T) {
+	role, err := NewRole(RoleAssistant, []string{"quests", "Narrate", "quests"})
+	if err != nil || !slices.Equal(role.Scopes, []string{ScopeNarrate, ScopeQuests}) {
+		t.Errorf("assistant = %+v, %v", role, err)
+	}
+	if role, _ := NewRole(RoleCoGM, nil); !slices.Equal(role.Scopes, Scopes) {
+		t.Errorf("co-GM scopes = %v", role.Scopes)
+	}
+	for _, tt := range []struct {
+		name   string
+		scopes []string
+		err    error
+	}{
+		{"owner", nil, ErrUnknownRole},
+		{RoleAssistant, []string{"combat"}, ErrUnknownScope},
+		{RoleAssistant, nil, ErrNoScopes},
+	} {
+		if _, err := NewRole(tt.name, tt.scopes); !errors.Is(err, tt.err) {
+			t.Errorf("NewRole(%q, %v) = %v, want %v", tt.name, tt.scopes, err, tt.err)
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	narrator, _ := NewRole(RoleAssistant, []string{ScopeNarrate})
+	coGM, _ := NewRole(RoleCoGM, nil)
+	tests := []struct {
+		path     string
+		narrator bool
+	}{
+		{"/api/gm/narrate", true},
+		{"/api/gm/status", true},
+		{"/api/gm/damage-monster", false},
+		{"/api/gm/monster-tactics/goblin", false},
+		{"/api/gm/award-xp", false},
+		{"/api/gm/deadline/4", false},
+	}
+	for _, tt := range tests {
+		scope := ScopeForGMPath(tt.path)
+		if narrator.Allows(scope) != tt.narrator {
+			t.Errorf("narrator on %s (%s) = %v", tt.path, scope, !tt.narrator)
+		}
+		if !coGM.Allows(scope) {
+			t.Errorf("co-GM refused %s", tt.path)
+		}
+	}
+	if ScopeForGMPath("/api/gm/monster-tactics/goblin") != ScopeMonsters {
+		t.Error("sub-paths should take their tool's scope")
+	}
+	if (Role{Name: "player"}).Allows(ScopeAny) {
+		t.Error("an unknown role allowed something")
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Personal access tokens (v1.0.114) let bots authenticate with "Authorization: Bearer ..."
+// instead of sending their password every time. Only a token's SHA-256 is stored.
+
+// Token scopes. A read token can only look (and revoke or rotate itself); play covers a character's own play (see
+// playPaths); gm covers everything.
+const (
+	TokenRead = "read"
+	TokenPlay = "play"
+	TokenGM   = "gm"
+)
+
+// TokenScopes lists the token scopes in the order the API reports them.
+var TokenScopes = []string{TokenRead, TokenPlay, TokenGM}
+
+// TokenPrefix starts every token, so leaked ones are easy to search for.
+const TokenPrefix = "arpg_"
+
+// Errors returned for Bearer tokens. Like the Basic auth errors, their text is the API
+// "error" field, so don't reword them.
+var (
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrTokenExpired      = errors.New("token expired")
+	ErrTokenScope        = errors.New("token scope does not allow this request")
+	ErrUnknownTokenScope = errors.New("scopes must be read, play or gm")
+)
+
+// Token is what a TokenStore knows about a token.
+type Token struct {
+	ID        int
+	AgentID   int
+	Scopes    []string
+	ExpiresAt time.Time // Zero for tokens that don't expire
+	Revoked   bool
+}
+
+// TokenStore finds a token by its hash. An unknown hash is ErrInvalidToken; any other
+// error is passed back unchanged.
+type TokenStore interface {
+	LookupToken(ctx context.Context, hash string) (Token, error)
+}
+
+// GenerateToken returns a new random token and the hash to store for it.
+func GenerateToken() (token, hash string) {
+	b := make([]byte, 20)
+	rand.Read(b)
+	token = TokenPrefix + hex.EncodeToString(b)
+	return token, HashToken(token)
+}
+
+// HashToken returns the hex SHA-256 of a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseBearer returns the token from an "Authorization: Bearer ..." header.
+func ParseBearer(header string) (string, error) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", ErrMissingAuth
+	}
+	token := strings.TrimSpace(header[7:])
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return "", ErrInvalidToken
+	}
+	return token, nil
+}
+
+// CheckTokenScopes validates scopes for a new token and returns them de-duplicated in the
+// order of TokenScopes.
+func CheckTokenScopes(scopes []string) ([]string, error) {
+	out := []string{}
+	for _, s := range scopes {
+		if !slices.Contains(TokenScopes, s) {
+			return nil, ErrUnknownTokenScope
+		}
+	}
+	for _, s := range TokenScopes {
+		if slices.Contains(scopes, s) {
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrUnknownTokenScope
+	}
+	return out, nil
+}
+
+// playPaths are the endpoints, and everything under them, a play token can use: a
+// character's own turns, actions and gear, and the rulebook. GM tools, moderation, admin,
+// creating or listing tokens and webhooks aren't listed, so they need a gm token.
+var playPaths = []string{
+	"/api/my-turn", "/api/action", "/api/actions", "/api/attack", "/api/roll", "/api/context",
+	"/api/characters", "/api/combat", "/api/respond-check", "/api/trigger-readied",
+	"/api/inspiration", "/api/shop", "/api/tutorial", "/api/heartbeat", "/api/events/stream",
+	"/api/observe", "/api/availability", "/api/nudge-settings", "/api/feature-requests",
+	"/api/campaigns/messages", "/api/universe", "/api/campaign-templates", "/api/capabilities",
+	"/api/version", "/api/conditions",
+}
+
+// playCampaignWrites are the /api/campaigns/{id}/ resources a play token can change. It can
+// read the others, except the GM's roles and audit log.
+var playCampaignWrites = []string{"join", "observe", "votes", "sessions", "loot/claim", "combat/pass"}
+
+// under reports whether path is prefix or below it.
+func under(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// playAllows reports whether a play token covers a request.
+func playAllows(method, path string) bool {
+	for _, p := range playPaths {
+		if under(path, p) {
+			return true
+		}
+	}
+	rest, ok := strings.CutPrefix(path, "/api/campaigns/")
+	if !ok {
+		return path == "/api/campaigns" && readOnly(method)
+	}
+	_, sub, _ := strings.Cut(rest, "/")
+	if under(sub, "roles") || under(sub, "audit") {
+		return false
+	}
+	if readOnly(method) {
+		return true
+	}
+	for _, p := range playCampaignWrites {
+		if under(sub, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// managesToken reports whether a request revokes or rotates one token. Every scope may try:
+// the handler only lets a token manage tokens it covers (see Covers), itself included.
+func managesToken(method, path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/tokens/")
+	if !ok {
+		return false
+	}
+	id, sub, _ := strings.Cut(rest, "/")
+	if id == "" || strings.Trim(id, "0123456789") != "" {
+		return false
+	}
+	return (method == "DELETE" && rest == id) || (method == "POST" && sub == "rotate")
+}
+
+func readOnly(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+// Allows reports whether the token's scopes cover a request.
+func (t Token) Allows(method, path string) bool {
+	if slices.Contains(t.Scopes, TokenGM) || managesToken(method, path) {
+		return true
+	}
+	if slices.Contains(t.Scopes, TokenPlay) && playAllows(method, path) {
+		return true
+	}
+	return slices.Contains(t.Scopes, TokenRead) && readOnly(method)
+}
+
+// Covers reports whether the token's scopes include everything the given scopes allow, so
+// that it can revoke or rotate a token with them without gaining anything.
+func (t Token) Covers(scopes []string) bool {
+	if slices.Contains(t.Scopes, TokenGM) {
+		return true
+	}
+	for _, s := range scopes {
+		if !slices.Contains(t.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthenticateToken checks a Bearer token against the store and that it covers the
+// request, and returns the token.
+func AuthenticateToken(ctx context.Context, store TokenStore, header, method, path string, now time.Time) (Token, error) {
+	raw, err := ParseBearer(header)
+	if err != nil {
+		return Token{}, err
+	}
+	token, err := store.LookupToken(ctx, HashToken(raw))
+	if err != nil {
+		return Token{}, err
+	}
+	if token.Revoked {
+		return Token{}, ErrInvalidToken
+	}
+	if !token.ExpiresAt.IsZero() && now.After(token.ExpiresAt) {
+		return Token{}, ErrTokenExpired
+	}
+	if !token.Allows(method, path) {
+		return Token{}, ErrTokenScope
+	}
+	return token, nil
+}
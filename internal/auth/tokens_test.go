@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tokenMap map[string]Token
+
+func (m tokenMap) LookupToken(ctx context.Context, hash string) (Token, error) {
+	if t, ok := m[hash]; ok {
+		return t, nil
+	}
+	return Token{}, ErrInvalidToken
+}
+
+func TestGenerateToken(t *testing.T) {
+	token, hash := GenerateToken()
+	if !strings.HasPrefix(token, TokenPrefix) || len(token) != len(TokenPrefix)+40 {
+		t.Errorf("token = %q", token)
+	}
+	if hash != HashToken(token) || hash == token {
+		t.Error("hash should be the token's SHA-256")
+	}
+	if other, _ := GenerateToken(); other == token {
+		t.Error("GenerateToken repeated itself")
+	}
+}
+
+func TestCheckTokenScopes(t *testing.T) {
+	if scopes, err := CheckTokenScopes([]string{"gm", "read", "gm"}); err != nil || !slices.Equal(scopes, []string{TokenRead, TokenGM}) {
+		t.Errorf("scopes = %v, %v", scopes, err)
+	}
+	for _, bad := range [][]string{nil, {"admin"}, {"play", "write"}} {
+		if _, err := CheckTokenScopes(bad); !errors.Is(err, ErrUnknownTokenScope) {
+			t.Errorf("CheckTokenScopes(%v) = %v", bad, err)
+		}
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	read := Token{Scopes: []string{TokenRead}}
+	play := Token{Scopes: []string{TokenPlay}}
+	gm := Token{Scopes: []string{TokenGM}}
+	tests := []struct {
+		method, path     string
+		read, play, gmOK bool
+	}{
+		{"GET", "/api/my-turn", true, true, true},
+		{"POST", "/api/action", false, true, true},
+		{"GET", "/api/gm/status", true, false, true},
+		{"POST", "/api/gm/narrate", false, false, true},
+		{"POST", "/api/actions", false, true, true},
+		{"POST", "/api/characters/5/short-rest", false, true, true},
+		{"POST", "/api/campaigns/3/join", false, true, true},
+		{"POST", "/api/campaigns/3/loot/claim", false, true, true},
+		{"POST", "/api/campaigns/3/combat/pass", false, true, true},
+		{"GET", "/api/campaigns/3/feed", true, true, true},
+		{"POST", "/api/campaigns", false, false, true},
+		{"POST", "/api/campaigns/3/combat/next", false, false, true},
+		{"POST", "/api/campaigns/3/roles", false, false, true},
+		{"GET", "/api/campaigns/3/audit", true, false, true},
+		{"POST", "/api/mod/reset-password", false, false, true},
+		{"GET", "/api/admin/users", true, false, true},
+		{"POST", "/api/admin/seed", false, false, true},
+		{"POST", "/api/tokens", false, false, true},
+		{"GET", "/api/tokens", true, false, true},
+		{"POST", "/api/tokens/4/rotate", true, true, true}, // Covers decides which tokens
+		{"DELETE", "/api/tokens/4", true, true, true},
+		{"DELETE", "/api/tokens/4/rotate", false, false, true},
+		{"POST", "/api/tokens/all/rotate", false, false, true},
+		{"POST", "/api/webhooks", false, false, true},
+		{"POST", "/api/my-turnover", false, false, true},
+	}
+	for _, tt := range tests {
+		if read.Allows(tt.method, tt.path) != tt.read || play.Allows(tt.method, tt.path) != tt.play || gm.Allows(tt.method, tt.path) != tt.gmOK {
+			t.Errorf("%s %s: read %v play %v gm %v", tt.method, tt.path,
+				read.Allows(tt.method, tt.path), play.Allows(tt.method, tt.path), gm.Allows(tt.method, tt.path))
+		}
+	}
+}
+
+func TestTokenCovers(t *testing.T) {
+	read := Token{Scopes: []string{TokenRead}}
+	play := Token{Scopes: []string{TokenPlay}}
+	readPlay := Token{Scopes: []string{TokenRead, TokenPlay}}
+	gm := Token{Scopes: []string{TokenGM}}
+	for _, tt := range []struct {
+		caller Token
+		target []string
+		want   bool
+	}{
+		{play, []string{TokenGM}, false},
+		{play, []string{TokenRead}, false},
+		{play, []string{TokenPlay}, true},
+		{read, []string{TokenRead}, true},
+		{read, []string{TokenRead, TokenPlay}, false},
+		{readPlay, []string{TokenRead, TokenPlay}, true},
+		{readPlay, []string{TokenRead, TokenGM}, false},
+		{gm, []string{TokenRead, TokenPlay, TokenGM}, true},
+	} {
+		if got := tt.caller.Covers(tt.target); got != tt.want {
+			t.Errorf("%v covers %v = %v, want %v", tt.caller.Scopes, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	good, _ := GenerateToken()
+	old, _ := GenerateToken()
+	revoked, _ := GenerateToken()
+	store := tokenMap{
+		HashToken(good):    {ID: 1, AgentID: 7, Scopes: []string{TokenPlay}},
+		HashToken(old):     {ID: 2, AgentID: 7, Scopes: []string{TokenPlay}, ExpiresAt: now.Add(-time.Hour)},
+		HashToken(revoked): {ID: 3, AgentID: 7, Scopes: []string{TokenPlay}, Revoked: true},
+	}
+	ctx := context.Background()
+	if tok, err := AuthenticateToken(ctx, store, "Bearer "+good, "POST", "/api/action", now); err != nil || tok.AgentID != 7 {
+		t.Errorf("good token: %+v, %v", tok, err)
+	}
+	for _, tt := range []struct {
+		header, path string
+		err          error
+	}{
+		{"Bearer " + good, "/api/gm/narrate", ErrTokenScope},
+		{"Bearer " + old, "/api/action", ErrTokenExpired},
+		{"Bearer " + revoked, "/api/action", ErrInvalidToken},
+		{"Bearer arpg_unknown", "/api/action", ErrInvalidToken},
+		{"Bearer not-ours", "/api/action", ErrInvalidToken},
+		{basic("aria", "secret"), "/api/action", ErrMissingAuth},
+	} {
+		if _, err := AuthenticateToken(ctx, store, tt.header, "POST", tt.path, now); !errors.Is(err, tt.err) {
+			t.Errorf("%q on %s: %v, want %v", tt.header, tt.path, err, tt.err)
+		}
+	}
+}
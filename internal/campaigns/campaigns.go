@@ -0,0 +1,84 @@
+// Package campaigns holds the lobby rules: who may join a campaign at what level, when a
+// recruiting campaign fills up and goes live, and what a joining player is told.
+//
+// It knows nothing about the database: a campaign's roster is read and its status written
+// through a Store, so the rules can be tested on their own.
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Campaign statuses the lobby rules move between.
+const (
+	StatusRecruiting = "recruiting"
+	StatusActive     = "active"
+)
+
+// ErrNotFound is returned by a Store for a campaign that doesn't exist.
+var ErrNotFound = errors.New("campaign not found")
+
+// Roster is what the lobby rules need to know about a campaign.
+type Roster struct {
+	Status     string
+	MaxPlayers int // 0 means no limit
+	Players    int // Characters in the campaign
+}
+
+// Store reads a campaign's roster and sets its status.
+type Store interface {
+	Roster(ctx context.Context, campaignID int) (Roster, error)
+	SetStatus(ctx context.Context, campaignID int, status string) error
+}
+
+// Full reports whether a recruiting campaign has all the players it asked for, and so
+// should go live.
+func (r Roster) Full() bool {
+	return r.Status == StatusRecruiting && r.MaxPlayers > 0 && r.Players >= r.MaxPlayers
+}
+
+// Reconcile starts a recruiting campaign that has filled up and returns its status. If
+// starting it fails the campaign keeps, and Reconcile returns, its old status.
+func Reconcile(ctx context.Context, s Store, campaignID int) (string, error) {
+	r, err := s.Roster(ctx, campaignID)
+	if err != nil {
+		return "", err
+	}
+	if r.Full() {
+		if err := s.SetStatus(ctx, campaignID, StatusActive); err == nil {
+			return StatusActive, nil
+		}
+	}
+	return r.Status, nil
+}
+
+// LevelAllowed reports whether a character of level may join a campaign for minLevel to
+// maxLevel.
+func LevelAllowed(level, minLevel, maxLevel int) bool {
+	return level >= minLevel && level <= maxLevel
+}
+
+// LevelRequirement describes a campaign's level range for players: "Level 3 only" or
+// "Levels 1-4".
+func LevelRequirement(minLevel, maxLevel int) string {
+	if minLevel == maxLevel {
+		return fmt.Sprintf("Level %d only", minLevel)
+	}
+	return fmt.Sprintf("Levels %d-%d", minLevel, maxLevel)
+}
+
+// JoinMessage is what a player is told after joining a campaign now in status.
+func JoinMessage(alreadyInCampaign bool, status string) string {
+	if alreadyInCampaign {
+		if status == StatusActive {
+			return "You're already in this campaign. It's live now—check your turn and jump in."
+		}
+		return "You're already in this campaign. Check messages and get ready for it to start."
+	}
+	if status == StatusActive {
+		return "You've joined the campaign! The party is full and the campaign is now live."
+	}
+	return "You've joined the campaign!"
+}
@@ -0,0 +1,97 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// memStore keeps rosters in a map; setErr makes SetStatus fail.
+type memStore struct {
+	rosters map[int]Roster
+	setErr  error
+}
+
+func (m *memStore) Roster(ctx context.Context, campaignID int) (Roster, error) {
+	r, ok := m.rosters[campaignID]
+	if !ok {
+		return Roster{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (m *memStore) SetStatus(ctx context.Context, campaignID int, status string) error {
+	if m.setErr != nil {
+		return m.setErr
+	}
+	r := m.rosters[campaignID]
+	r.Status = status
+	m.rosters[campaignID] = r
+	return nil
+}
+
+func TestRosterFull(t *testing.T) {
+	tests := []struct {
+		roster Roster
+		want   bool
+	}{
+		{Roster{StatusRecruiting, 4, 3}, false},
+		{Roster{StatusRecruiting, 4, 4}, true},
+		{Roster{StatusRecruiting, 4, 5}, true},
+		{Roster{StatusRecruiting, 0, 9}, false},
+		{Roster{StatusActive, 4, 4}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.roster.Full(); got != tt.want {
+			t.Errorf("%+v.Full() = %v, want %v", tt.roster, got, tt.want)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	s := &memStore{rosters: map[int]Roster{
+		1: {StatusRecruiting, 3, 3},
+		2: {StatusRecruiting, 3, 1},
+	}}
+
+	if status, err := Reconcile(ctx, s, 1); status != StatusActive || err != nil {
+		t.Errorf("full campaign: Reconcile = %q, %v; want active", status, err)
+	}
+	if s.rosters[1].Status != StatusActive {
+		t.Error("full campaign wasn't started")
+	}
+	if status, _ := Reconcile(ctx, s, 2); status != StatusRecruiting {
+		t.Errorf("campaign still recruiting: Reconcile = %q", status)
+	}
+	if _, err := Reconcile(ctx, s, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown campaign: err = %v, want ErrNotFound", err)
+	}
+
+	s.rosters[4] = Roster{StatusRecruiting, 2, 2}
+	s.setErr = errors.New("database is down")
+	if status, err := Reconcile(ctx, s, 4); status != StatusRecruiting || err != nil {
+		t.Errorf("failed start: Reconcile = %q, %v; want the old status", status, err)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	if !LevelAllowed(3, 1, 4) || LevelAllowed(5, 1, 4) || LevelAllowed(1, 2, 2) {
+		t.Error("LevelAllowed got a range wrong")
+	}
+	if got := LevelRequirement(3, 3); got != "Level 3 only" {
+		t.Errorf("LevelRequirement(3, 3) = %q", got)
+	}
+	if got := LevelRequirement(1, 4); got != "Levels 1-4" {
+		t.Errorf("LevelRequirement(1, 4) = %q", got)
+	}
+}
+
+func TestJoinMessage(t *testing.T) {
+	if got := JoinMessage(false, StatusActive); got != "You've joined the campaign! The party is full and the campaign is now live." {
+		t.Errorf("JoinMessage(new, active) = %q", got)
+	}
+	if got := JoinMessage(true, StatusRecruiting); got != "You're already in this campaign. Check messages and get ready for it to start." {
+		t.Errorf("JoinMessage(already, recruiting) = %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+// Package characters holds the character rules every other system leans on: which
+// conditions a character has, whether they can act, and how damage eats through
+// temporary hit points.
+//
+// It knows nothing about the database: conditions are read and written through a Store,
+// and the hit point helpers work on plain numbers, so they can be tested on their own.
+package characters
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrNotFound is returned by a Store for a character that doesn't exist.
+var ErrNotFound = errors.New("character not found")
+
+// Store reads and replaces a character's conditions, as stored: "prone", "poisoned",
+// or a tagged form such as "invisible:one_with_shadows".
+type Store interface {
+	Conditions(ctx context.Context, charID int) ([]string, error)
+	SetConditions(ctx context.Context, charID int, conditions []string) error
+}
+
+// incapacitating are the conditions that stop a character taking actions or reactions.
+var incapacitating = []string{"incapacitated", "paralyzed", "stunned", "unconscious", "petrified"}
+
+// Has reports whether conditions includes condition, ignoring case.
+func Has(conditions []string, condition string) bool {
+	for _, c := range conditions {
+		if strings.EqualFold(c, condition) {
+			return true
+		}
+	}
+	return false
+}
+
+// Without returns conditions with every copy of condition (ignoring case) taken out,
+// and whether there was one to take. It never returns nil.
+func Without(conditions []string, condition string) ([]string, bool) {
+	kept := []string{}
+	removed := false
+	for _, c := range conditions {
+		if strings.EqualFold(c, condition) {
+			removed = true
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	return kept, removed
+}
+
+// Incapacitated reports whether conditions stop a character taking actions or reactions
+// (PHB p290): incapacitated, or paralyzed, stunned, unconscious or petrified, which all
+// include it.
+func Incapacitated(conditions []string) bool {
+	for _, c := range incapacitating {
+		if Has(conditions, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCondition reports whether the character has condition.
+func HasCondition(ctx context.Context, s Store, charID int, condition string) (bool, error) {
+	conditions, err := s.Conditions(ctx, charID)
+	if err != nil {
+		return false, err
+	}
+	return Has(conditions, condition), nil
+}
+
+// RemoveCondition takes condition off the character, writing only when it was there.
+// It reports whether it was.
+func RemoveCondition(ctx context.Context, s Store, charID int, condition string) (bool, error) {
+	conditions, err := s.Conditions(ctx, charID)
+	if err != nil {
+		return false, err
+	}
+	kept, removed := Without(conditions, condition)
+	if !removed {
+		return false, nil
+	}
+	if err := s.SetConditions(ctx, charID, kept); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AbsorbTempHP spends temporary hit points on damage first (PHB p198) and returns what's
+// left of each.
+func AbsorbTempHP(tempHP, damage int) (tempLeft, damageLeft int) {
+	if tempHP <= 0 {
+		return tempHP, damage
+	}
+	if damage <= tempHP {
+		return tempHP - damage, 0
+	}
+	return 0, damage - tempHP
+}
+
+// MassiveDamage reports whether a character left at hp kills outright: the damage past
+// 0 HP is at least their hit point maximum (PHB p197).
+func MassiveDamage(hp, maxHP int) bool {
+	return hp <= -maxHP
+}
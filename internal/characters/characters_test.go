@@ -0,0 +1,117 @@
+package characters
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// memStore keeps conditions in a map and counts writes.
+type memStore struct {
+	conditions map[int][]string
+	writes     int
+}
+
+func (m *memStore) Conditions(ctx context.Context, charID int) ([]string, error) {
+	c, ok := m.conditions[charID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+func (m *memStore) SetConditions(ctx context.Context, charID int, conditions []string) error {
+	m.writes++
+	m.conditions[charID] = conditions
+	return nil
+}
+
+func TestHasAndWithout(t *testing.T) {
+	conditions := []string{"Prone", "poisoned", "invisible:one_with_shadows"}
+	if !Has(conditions, "prone") || !Has(conditions, "POISONED") {
+		t.Error("Has should ignore case")
+	}
+	if Has(conditions, "invisible") {
+		t.Error("Has matched a tagged condition by its prefix")
+	}
+
+	kept, removed := Without(conditions, "PRONE")
+	if !removed || !slices.Equal(kept, []string{"poisoned", "invisible:one_with_shadows"}) {
+		t.Errorf("Without(prone) = %v, %v", kept, removed)
+	}
+	if kept, removed := Without(nil, "prone"); removed || kept == nil {
+		t.Errorf("Without(nil) = %#v, %v; want an empty, non-nil slice", kept, removed)
+	}
+}
+
+func TestIncapacitated(t *testing.T) {
+	tests := []struct {
+		conditions []string
+		want       bool
+	}{
+		{nil, false},
+		{[]string{"prone", "poisoned", "frightened"}, false},
+		{[]string{"Stunned"}, true},
+		{[]string{"prone", "unconscious"}, true},
+		{[]string{"petrified"}, true},
+		{[]string{"incapacitated"}, true},
+	}
+	for _, tt := range tests {
+		if got := Incapacitated(tt.conditions); got != tt.want {
+			t.Errorf("Incapacitated(%v) = %v, want %v", tt.conditions, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveCondition(t *testing.T) {
+	ctx := context.Background()
+	s := &memStore{conditions: map[int][]string{1: {"prone", "grappled"}}}
+
+	if ok, err := HasCondition(ctx, s, 1, "Grappled"); !ok || err != nil {
+		t.Fatalf("HasCondition(grappled) = %v, %v", ok, err)
+	}
+	if removed, err := RemoveCondition(ctx, s, 1, "prone"); !removed || err != nil {
+		t.Fatalf("RemoveCondition(prone) = %v, %v", removed, err)
+	}
+	if !slices.Equal(s.conditions[1], []string{"grappled"}) {
+		t.Errorf("conditions = %v, want [grappled]", s.conditions[1])
+	}
+	if removed, err := RemoveCondition(ctx, s, 1, "prone"); removed || err != nil {
+		t.Errorf("second RemoveCondition(prone) = %v, %v", removed, err)
+	}
+	if s.writes != 1 {
+		t.Errorf("writes = %d, want 1: nothing to remove shouldn't write", s.writes)
+	}
+	if _, err := RemoveCondition(ctx, s, 2, "prone"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown character: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAbsorbTempHP(t *testing.T) {
+	tests := []struct {
+		tempHP, damage int
+		wantTemp       int
+		wantDamage     int
+	}{
+		{0, 7, 0, 7},
+		{5, 3, 2, 0},
+		{5, 5, 0, 0},
+		{5, 8, 0, 3},
+	}
+	for _, tt := range tests {
+		temp, damage := AbsorbTempHP(tt.tempHP, tt.damage)
+		if temp != tt.wantTemp || damage != tt.wantDamage {
+			t.Errorf("AbsorbTempHP(%d, %d) = %d, %d; want %d, %d", tt.tempHP, tt.damage, temp, damage, tt.wantTemp, tt.wantDamage)
+		}
+	}
+}
+
+func TestMassiveDamage(t *testing.T) {
+	if MassiveDamage(-11, 12) {
+		t.Error("11 past 0 with a 12 HP maximum isn't massive damage")
+	}
+	if !MassiveDamage(-12, 12) {
+		t.Error("12 past 0 with a 12 HP maximum is massive damage")
+	}
+}
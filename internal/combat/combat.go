@@ -0,0 +1,142 @@
+// Package combat works on a campaign's turn order: finding a combatant, marking one with a
+// condition, slotting in a newcomer and moving the turn on past reinforcements that haven't
+// arrived yet. The initiative maths itself lives in game; this package applies it to the
+// turn order entries the server stores.
+//
+// It knows nothing about the database: the turn order is read and written through a Store,
+// so the rules can be tested on their own.
+package combat
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/game"
+)
+
+// ErrNoCombat is returned by a Store for a campaign with no combat state.
+var ErrNoCombat = errors.New("no combat")
+
+// TurnOrder is a campaign's combatants in initiative order. Entries are the stored JSON
+// objects, decoded generically ("id", "name", "initiative", "hp", "conditions", ...), so
+// fields this package doesn't know about survive a round trip.
+type TurnOrder struct {
+	Entries []map[string]interface{}
+	Active  bool // Combat is running, not just set up or finished
+}
+
+// Store reads and replaces a campaign's turn order. SetTurnOrder leaves Active alone.
+type Store interface {
+	TurnOrder(ctx context.Context, campaignID int) (TurnOrder, error)
+	SetTurnOrder(ctx context.Context, campaignID int, entries []map[string]interface{}) error
+}
+
+// Int reads a numeric field from an entry: a float64 once it has been through JSON, an
+// int if it was added in this request.
+func Int(entry map[string]interface{}, key string) int {
+	switch v := entry[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// Find returns the entry with id.
+func Find(entries []map[string]interface{}, id int) (map[string]interface{}, bool) {
+	for _, e := range entries {
+		if Int(e, "id") == id {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Mark adds condition to an entry's comma-separated "conditions" (the format Intimidating
+// Presence uses), reporting false if it was already there.
+func Mark(entry map[string]interface{}, condition string) bool {
+	existing, _ := entry["conditions"].(string)
+	for _, c := range strings.Split(existing, ",") {
+		if c == condition {
+			return false
+		}
+	}
+	if existing != "" {
+		existing += ","
+	}
+	entry["conditions"] = existing + condition
+	return true
+}
+
+// Insert slots a combatant joining mid-combat into entries by initiative (ties by DEX
+// score) without reordering anyone else. It returns the new order, the newcomer's position
+// and turnIndex shifted so the same combatant keeps the turn.
+func Insert(entries []map[string]interface{}, entry map[string]interface{}, turnIndex int) ([]map[string]interface{}, int, int) {
+	inits := make([]int, len(entries))
+	dexes := make([]int, len(entries))
+	for i, e := range entries {
+		inits[i], dexes[i] = Int(e, "initiative"), Int(e, "dex_score")
+	}
+	pos := game.InitiativeInsertIndex(inits, dexes, Int(entry, "initiative"), Int(entry, "dex_score"))
+	entries = append(entries[:pos], append([]map[string]interface{}{entry}, entries[pos:]...)...)
+	return entries, pos, game.ShiftTurnIndex(turnIndex, pos)
+}
+
+// NextTurn is game.NextTurn that also passes over reinforcements waiting for a later round
+// (an entry's "joins_round"). round is the round before advancing.
+func NextTurn(entries []map[string]interface{}, mode string, ids []int, current int, acted []int, chosenID, round int) (int, bool, []int, bool) {
+	next, newRound, acted, ok := game.NextTurn(mode, ids, current, acted, chosenID)
+	if !ok {
+		return next, newRound, acted, ok
+	}
+	joins := map[int]int{}
+	for _, e := range entries {
+		if r := Int(e, "joins_round"); r > 0 {
+			joins[Int(e, "id")] = r
+		}
+	}
+	if len(joins) == 0 {
+		return next, newRound, acted, ok
+	}
+	if newRound {
+		round++
+	}
+	for guard := 0; guard < len(ids) && next >= 0 && next < len(ids) && joins[ids[next]] > round; guard++ {
+		n, wrapped, a, _ := game.NextTurn(mode, ids, next, acted, 0)
+		next, acted = n, a
+		if wrapped {
+			newRound = true
+			round++
+		}
+	}
+	return next, newRound, acted, ok
+}
+
+// ActiveEntry returns a combatant's entry while the campaign's combat is running.
+func ActiveEntry(ctx context.Context, s Store, campaignID, id int) (map[string]interface{}, bool, error) {
+	order, err := s.TurnOrder(ctx, campaignID)
+	if err != nil || !order.Active {
+		return nil, false, err
+	}
+	e, ok := Find(order.Entries, id)
+	return e, ok, nil
+}
+
+// AddCondition marks a combatant in the stored turn order with condition, writing only
+// when it changed. It reports false if there's no such combatant or it already had it.
+func AddCondition(ctx context.Context, s Store, campaignID, id int, condition string) (bool, error) {
+	order, err := s.TurnOrder(ctx, campaignID)
+	if err != nil {
+		return false, err
+	}
+	e, ok := Find(order.Entries, id)
+	if !ok || !Mark(e, condition) {
+		return false, nil
+	}
+	if err := s.SetTurnOrder(ctx, campaignID, order.Entries); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,119 @@
+package combat
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// memStore keeps turn orders in a map and counts writes.
+type memStore struct {
+	orders map[int]TurnOrder
+	writes int
+}
+
+func (m *memStore) TurnOrder(ctx context.Context, campaignID int) (TurnOrder, error) {
+	o, ok := m.orders[campaignID]
+	if !ok {
+		return TurnOrder{}, ErrNoCombat
+	}
+	return o, nil
+}
+
+func (m *memStore) SetTurnOrder(ctx context.Context, campaignID int, entries []map[string]interface{}) error {
+	m.writes++
+	o := m.orders[campaignID]
+	o.Entries = entries
+	m.orders[campaignID] = o
+	return nil
+}
+
+func TestInt(t *testing.T) {
+	e := map[string]interface{}{"id": float64(-3), "initiative": 17, "name": "Goblin"}
+	if Int(e, "id") != -3 || Int(e, "initiative") != 17 || Int(e, "name") != 0 || Int(e, "hp") != 0 {
+		t.Errorf("Int read %d, %d, %d, %d", Int(e, "id"), Int(e, "initiative"), Int(e, "name"), Int(e, "hp"))
+	}
+}
+
+func TestMark(t *testing.T) {
+	e := map[string]interface{}{"id": 1}
+	if !Mark(e, "frightened") || !Mark(e, "prone") {
+		t.Fatal("Mark refused a new condition")
+	}
+	if Mark(e, "frightened") {
+		t.Error("Mark added a condition twice")
+	}
+	if e["conditions"] != "frightened,prone" {
+		t.Errorf("conditions = %q, want frightened,prone", e["conditions"])
+	}
+}
+
+func TestInsert(t *testing.T) {
+	entries := []map[string]interface{}{
+		{"id": 1, "initiative": 18, "dex_score": 14},
+		{"id": -1, "initiative": 12, "dex_score": 12},
+		{"id": 2, "initiative": 8, "dex_score": 10},
+	}
+	// Ties with the goblin on initiative but has the higher DEX, so goes before it. The
+	// turn is with the last combatant, who should keep it.
+	entries, pos, turn := Insert(entries, map[string]interface{}{"id": -2, "initiative": 12, "dex_score": 16}, 2)
+	if pos != 1 || turn != 3 || len(entries) != 4 || Int(entries[1], "id") != -2 || Int(entries[3], "id") != 2 {
+		t.Errorf("Insert = pos %d, turn %d, order %v", pos, turn, entries)
+	}
+}
+
+func TestNextTurnSkipsReinforcements(t *testing.T) {
+	entries := []map[string]interface{}{
+		{"id": 1},
+		{"id": -1, "joins_round": float64(3)},
+		{"id": 2},
+	}
+	ids := []int{1, -1, 2}
+
+	// Round 1: the reinforcement's slot is passed over
+	next, newRound, _, ok := NextTurn(entries, "standard", ids, 0, nil, 0, 1)
+	if !ok || next != 2 || newRound {
+		t.Errorf("round 1 from 0 = %d, %v, %v; want 2, false, true", next, newRound, ok)
+	}
+	// Round 2 wrapping into round 3: it arrives and takes its slot
+	next, newRound, _, _ = NextTurn(entries, "standard", ids, 2, nil, 0, 2)
+	if next != 0 || !newRound {
+		t.Errorf("end of round 2 = %d, %v; want 0, true", next, newRound)
+	}
+	next, _, _, _ = NextTurn(entries, "standard", ids, 0, nil, 0, 3)
+	if next != 1 {
+		t.Errorf("round 3 from 0 = %d, want the reinforcement at 1", next)
+	}
+}
+
+func TestAddConditionAndActiveEntry(t *testing.T) {
+	ctx := context.Background()
+	s := &memStore{orders: map[int]TurnOrder{
+		7: {Active: true, Entries: []map[string]interface{}{{"id": float64(-1), "name": "Orc"}}},
+		8: {Active: false, Entries: []map[string]interface{}{{"id": float64(-1), "name": "Orc"}}},
+	}}
+
+	if ok, err := AddCondition(ctx, s, 7, -1, "frightened"); !ok || err != nil {
+		t.Fatalf("AddCondition = %v, %v", ok, err)
+	}
+	if ok, _ := AddCondition(ctx, s, 7, -1, "frightened"); ok {
+		t.Error("AddCondition added the same condition twice")
+	}
+	if ok, _ := AddCondition(ctx, s, 7, -9, "frightened"); ok {
+		t.Error("AddCondition marked a combatant that isn't in the order")
+	}
+	if s.writes != 1 {
+		t.Errorf("writes = %d, want 1", s.writes)
+	}
+
+	e, ok, err := ActiveEntry(ctx, s, 7, -1)
+	if !ok || err != nil || e["conditions"] != "frightened" {
+		t.Errorf("ActiveEntry = %v, %v, %v", e, ok, err)
+	}
+	if _, ok, _ := ActiveEntry(ctx, s, 8, -1); ok {
+		t.Error("ActiveEntry found a combatant in a combat that isn't running")
+	}
+	if _, _, err := ActiveEntry(ctx, s, 9, -1); !errors.Is(err, ErrNoCombat) {
+		t.Errorf("no combat: err = %v, want ErrNoCombat", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Package combatlock decides which requests change a campaign's combat and checks the
+// combat version clients send with them (v1.0.115). The server serializes those requests
+// per campaign, and combat_state.version goes up with every write, so a client that read
+// version 7 can say "only if it's still 7" and get a 409 instead of clobbering a change it
+// never saw.
+package combatlock
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Route says how to find the campaign whose combat a request may change.
+type Route int
+
+const (
+	None     Route = iota // Can't change combat
+	Campaign              // /api/campaigns/{id}/combat/...: the ID is in the path
+	GM                    // /api/gm/...: the GM's campaign
+	Player                // Any other write: a player action that can change combat
+)
+
+// nonCombatPaths are the endpoints, and everything under them, whose writes never touch
+// combat: accounts, tokens, settings and the rulebook. Every other write outside /api/gm/
+// and /api/campaigns/ counts as a player change that can, so a new endpoint is serialized
+// unless it's listed here.
+var nonCombatPaths = []string{
+	"/api/register", "/api/login", "/api/verify", "/api/password-reset", "/api/tokens",
+	"/api/webhooks", "/api/nudge-settings", "/api/availability", "/api/feature-requests",
+	"/api/heartbeat", "/api/events", "/api/universe", "/api/campaign-templates",
+	"/api/admin", "/api/mod",
+}
+
+// ErrBadVersion is returned for an If-Match value that isn't a combat version.
+var ErrBadVersion = errors.New("If-Match must be a combat version like \"7\"")
+
+// Classify returns the route of a request and, for Campaign, the campaign ID. Reads never
+// change combat.
+func Classify(method, path string) (Route, int) {
+	if method == "GET" || method == "HEAD" || method == "OPTIONS" {
+		return None, 0
+	}
+	if rest, ok := strings.CutPrefix(path, "/api/campaigns/"); ok {
+		parts := strings.Split(rest, "/")
+		id, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) < 2 || parts[1] != "combat" {
+			return None, 0
+		}
+		return Campaign, id
+	}
+	if strings.HasPrefix(path, "/api/gm/") {
+		return GM, 0
+	}
+	if path == "/api/campaigns" || !strings.HasPrefix(path, "/api/") {
+		return None, 0
+	}
+	for _, p := range nonCombatPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return None, 0
+		}
+	}
+	return Player, 0
+}
+
+// ETag is the entity tag for a combat version.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Matches reports whether an If-Match header allows a write to combat at version. An empty
+// header or "*" allows any version; otherwise one of the listed tags must match. Weak tags
+// (W/"7") count.
+func Matches(header string, version int) (bool, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return true, nil
+	}
+	match := false
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		n, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err != nil || n < 0 {
+			return false, ErrBadVersion
+		}
+		match = match || n == version
+	}
+	return match, nil
+}
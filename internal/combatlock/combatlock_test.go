@@ -0,0 +1,65 @@
+package combatlock
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		method, path string
+		route        Route
+		id           int
+	}{
+		{"POST", "/api/campaigns/12/combat/next", Campaign, 12},
+		{"POST", "/api/campaigns/12/combat", Campaign, 12},
+		{"GET", "/api/campaigns/12/combat", None, 0},
+		{"POST", "/api/campaigns/12/messages", None, 0},
+		{"POST", "/api/campaigns/abc/combat/next", None, 0},
+		{"POST", "/api/gm/narrate", GM, 0},
+		{"DELETE", "/api/gm/combat-hazards", GM, 0},
+		{"GET", "/api/gm/status", None, 0},
+		{"POST", "/api/action", Player, 0},
+		{"POST", "/api/combat/delay", Player, 0},
+		{"POST", "/api/characters/breath-weapon", Player, 0},
+		{"POST", "/api/attack", Player, 0},
+		{"POST", "/api/trigger-readied", Player, 0},
+		{"POST", "/api/respond-check", Player, 0},
+		{"POST", "/api/characters/mount", Player, 0},
+		{"POST", "/api/characters/transform", Player, 0},
+		{"POST", "/api/some-future-endpoint", Player, 0},
+		{"POST", "/api/campaigns", None, 0},
+		{"POST", "/api/tokens/3/rotate", None, 0},
+		{"POST", "/api/webhooks", None, 0},
+		{"POST", "/api/register", None, 0},
+		{"POST", "/api/password-reset/confirm", None, 0},
+		{"GET", "/api/attack", None, 0},
+	}
+	for _, tt := range tests {
+		if route, id := Classify(tt.method, tt.path); route != tt.route || id != tt.id {
+			t.Errorf("Classify(%s %s) = %v, %d; want %v, %d", tt.method, tt.path, route, id, tt.route, tt.id)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	if ETag(7) != `"7"` {
+		t.Errorf("ETag(7) = %s", ETag(7))
+	}
+	for _, tt := range []struct {
+		header string
+		want   bool
+	}{
+		{"", true}, {"*", true}, {`"7"`, true}, {"7", true}, {`W/"7"`, true},
+		{`"6"`, false}, {`"5", "7"`, true}, {`"5", "6"`, false},
+	} {
+		if got, err := Matches(tt.header, 7); got != tt.want || err != nil {
+			t.Errorf("Matches(%q, 7) = %v, %v", tt.header, got, err)
+		}
+	}
+	for _, bad := range []string{`"seven"`, `"-1"`, `"7", x`} {
+		if _, err := Matches(bad, 7); !errors.Is(err, ErrBadVersion) {
+			t.Errorf("Matches(%q) = %v, want ErrBadVersion", bad, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package gm decides who may run a campaign's GM tools. The campaign's own GM always may;
+// a co-GM or assistant may use the tools their role's scopes cover (see auth.Role), but only
+// in the campaign the role is for, even when the GM runs several.
+//
+// It knows nothing about the database: roles are read through a Store, so which campaign
+// a request acts in, and whether a role reaches a tool, can be tested on their own.
+package gm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/agentrpg/agentrpg/internal/auth"
+)
+
+// ErrCampaignRequired is returned by Pick when an agent helps run several campaigns, has
+// none of their own, and didn't say which one a request is for.
+var ErrCampaignRequired = errors.New("campaign required")
+
+// ErrNoRole is returned by a Store for an agent with no role in a campaign.
+var ErrNoRole = errors.New("no role")
+
+// Role is an agent's role in one campaign, with the campaign's GM.
+type Role struct {
+	CampaignID int
+	GMID       int
+	auth.Role
+}
+
+// Store reads campaign roles. Roles lists an agent's roles in active campaigns, in
+// campaign order; OwnCampaign is the active campaign the agent runs as GM, or 0.
+type Store interface {
+	Roles(ctx context.Context, agentID int) ([]Role, error)
+	Role(ctx context.Context, campaignID, agentID int) (auth.Role, error)
+	OwnCampaign(ctx context.Context, agentID int) (int, error)
+}
+
+// Pick returns the role a GM request runs under, given the agent's roles, the campaign the
+// request names (0 for none) and the agent's own active campaign (0 for none). It returns
+// nil when the request is for the agent's own campaign, or one they have no part in.
+// Without a named campaign, an agent with no campaign of their own uses their only role;
+// with several roles they must name one (ErrCampaignRequired).
+func Pick(roles []Role, requested, own int) (*Role, error) {
+	if requested != 0 {
+		for i := range roles {
+			if roles[i].CampaignID == requested {
+				return &roles[i], nil
+			}
+		}
+		return nil, nil
+	}
+	switch {
+	case own != 0 || len(roles) == 0:
+		return nil, nil
+	case len(roles) == 1:
+		return &roles[0], nil
+	}
+	return nil, ErrCampaignRequired
+}
+
+// CampaignIDs lists the campaigns roles are for, to tell an agent what they can name.
+func CampaignIDs(roles []Role) []int {
+	ids := []int{}
+	for _, r := range roles {
+		ids = append(ids, r.CampaignID)
+	}
+	return ids
+}
+
+// Allows reports whether role reaches the /api/gm/ tool at path, and the scope it needs.
+func Allows(role Role, path string) (scope string, ok bool) {
+	scope = auth.ScopeForGMPath(path)
+	return scope, role.Allows(scope)
+}
+
+// HasScope reports whether agentID is the campaign's GM (gmID) or holds a role in it that
+// covers scope. For GM tools outside /api/gm/, like campaign quests.
+func HasScope(ctx context.Context, s Store, campaignID, gmID, agentID int, scope string) bool {
+	if agentID == gmID {
+		return true
+	}
+	role, err := s.Role(ctx, campaignID, agentID)
+	if err != nil {
+		return false
+	}
+	return role.Allows(scope)
+}
@@ -0,0 +1,105 @@
+package gm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/agentrpg/agentrpg/internal/auth"
+)
+
+// memStore keeps roles by campaign, then agent.
+type memStore map[int]map[int]auth.Role
+
+func (m memStore) Roles(ctx context.Context, agentID int) ([]Role, error) {
+	var roles []Role
+	for campaignID, agents := range m {
+		if r, ok := agents[agentID]; ok {
+			roles = append(roles, Role{CampaignID: campaignID, Role: r})
+		}
+	}
+	return roles, nil
+}
+
+func (m memStore) Role(ctx context.Context, campaignID, agentID int) (auth.Role, error) {
+	if r, ok := m[campaignID][agentID]; ok {
+		return r, nil
+	}
+	return auth.Role{}, ErrNoRole
+}
+
+func (m memStore) OwnCampaign(ctx context.Context, agentID int) (int, error) {
+	return 0, nil
+}
+
+var (
+	coGM     = auth.Role{Name: auth.RoleCoGM, Scopes: auth.Scopes}
+	narrator = auth.Role{Name: auth.RoleAssistant, Scopes: []string{auth.ScopeNarrate}}
+)
+
+func TestPick(t *testing.T) {
+	one := []Role{{CampaignID: 5, GMID: 1, Role: narrator}}
+	two := []Role{{CampaignID: 5, GMID: 1, Role: narrator}, {CampaignID: 9, GMID: 2, Role: coGM}}
+
+	tests := []struct {
+		name      string
+		roles     []Role
+		requested int
+		own       int
+		want      int // Campaign of the picked role, 0 for none
+		err       error
+	}{
+		{"no roles", nil, 0, 0, 0, nil},
+		{"only role", one, 0, 0, 5, nil},
+		{"own campaign first", one, 0, 3, 0, nil},
+		{"named role", two, 9, 3, 9, nil},
+		{"named campaign without a role", two, 7, 0, 0, nil},
+		{"several roles, none named", two, 0, 0, 0, ErrCampaignRequired},
+	}
+	for _, tt := range tests {
+		role, err := Pick(tt.roles, tt.requested, tt.own)
+		got := 0
+		if role != nil {
+			got = role.CampaignID
+		}
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%s: Pick = campaign %d, %v; want %d, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+
+	if ids := CampaignIDs(two); !slices.Equal(ids, []int{5, 9}) {
+		t.Errorf("CampaignIDs = %v, want [5 9]", ids)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	r := Role{CampaignID: 5, Role: narrator}
+	if scope, ok := Allows(r, "/api/gm/narrate"); !ok || scope != auth.ScopeNarrate {
+		t.Errorf("narrator on narrate = %q, %v", scope, ok)
+	}
+	if scope, ok := Allows(r, "/api/gm/damage-monster"); ok || scope != auth.ScopeMonsters {
+		t.Errorf("narrator on damage-monster = %q, %v", scope, ok)
+	}
+	if _, ok := Allows(Role{Role: coGM}, "/api/gm/award-xp"); !ok {
+		t.Error("a co-GM should reach every tool")
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	ctx := context.Background()
+	s := memStore{5: {20: narrator, 21: coGM}}
+
+	if !HasScope(ctx, s, 5, 10, 10, auth.ScopeQuests) {
+		t.Error("the GM should have every scope")
+	}
+	if HasScope(ctx, s, 5, 10, 20, auth.ScopeQuests) {
+		t.Error("a narrator shouldn't reach quests")
+	}
+	if !HasScope(ctx, s, 5, 10, 21, auth.ScopeQuests) {
+		t.Error("a co-GM should reach quests")
+	}
+	if HasScope(ctx, s, 6, 10, 21, auth.ScopeQuests) {
+		t.Error("a role reached a campaign it isn't for")
+	}
+}
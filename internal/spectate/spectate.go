@@ -0,0 +1,170 @@
+// Package spectate prepares a campaign's feed for an audience: it strips what only the GM
+// should see and pages the feed with cursors, so dashboards can follow a game without
+// joining it.
+//
+// Like internal/transcript it knows nothing about the database: the server reads actions
+// and messages after a cursor, and this package redacts, merges and pages them.
+package spectate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Kinds of Event.
+const (
+	KindAction  = "action"
+	KindMessage = "message"
+)
+
+// Event is one item of the public feed: an action or a chat message.
+type Event struct {
+	Kind        string    `json:"kind"`
+	ID          int       `json:"id"`
+	Type        string    `json:"type"` // The action type, or "message"
+	Actor       string    `json:"actor,omitempty"`
+	Description string    `json:"description"`
+	Result      string    `json:"result,omitempty"`
+	Time        time.Time `json:"created_at"`
+}
+
+// Cursor marks a place in the feed: the last action and message already seen. Actions and
+// messages are numbered separately, so one number can't mark both.
+type Cursor struct {
+	ActionID  int
+	MessageID int
+}
+
+// String writes a cursor as "a<action ID>m<message ID>", the form ParseCursor reads.
+func (c Cursor) String() string {
+	return fmt.Sprintf("a%dm%d", c.ActionID, c.MessageID)
+}
+
+var cursorPattern = regexp.MustCompile(`^a(\d+)m(\d+)$`)
+
+// ParseCursor reads a cursor written by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	m := cursorPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Cursor{}, fmt.Errorf("cursor %q should look like a120m45", s)
+	}
+	actionID, _ := strconv.Atoi(m[1])
+	messageID, _ := strconv.Atoi(m[2])
+	return Cursor{ActionID: actionID, MessageID: messageID}, nil
+}
+
+// Advance moves the cursor past an event.
+func (c Cursor) Advance(e Event) Cursor {
+	if e.Kind == KindMessage && e.ID > c.MessageID {
+		c.MessageID = e.ID
+	} else if e.Kind == KindAction && e.ID > c.ActionID {
+		c.ActionID = e.ID
+	}
+	return c
+}
+
+// gmOnlyTypes are actions the audience doesn't see at all: the GM's reminders to players,
+// difficulty tuning and table disputes.
+var gmOnlyTypes = map[string]bool{
+	"gm_nudge":          true,
+	"difficulty_assist": true,
+	"dispute":           true,
+	"dispute_resolved":  true,
+}
+
+// planTypes keep their description but not their result, which gives away what a monster
+// is waiting to do.
+var planTypes = map[string]bool{
+	"monster_ready": true,
+}
+
+// exactHP matches the "(12/30 HP)" the GM tools write after monster damage.
+var exactHP = regexp.MustCompile(`\((\d+)/(\d+) HP\)`)
+
+// Redact returns the event as the audience sees it, or false if they don't see it at all.
+// Monsters' exact hit points become how hurt they look.
+func Redact(e Event) (Event, bool) {
+	if e.Kind == KindAction {
+		if gmOnlyTypes[e.Type] {
+			return Event{}, false
+		}
+		if planTypes[e.Type] {
+			e.Result = ""
+		}
+	}
+	hide := func(s string) string {
+		return exactHP.ReplaceAllStringFunc(s, func(m string) string {
+			parts := exactHP.FindStringSubmatch(m)
+			hp, _ := strconv.Atoi(parts[1])
+			maxHP, _ := strconv.Atoi(parts[2])
+			return "(" + HealthStatus(hp, maxHP) + ")"
+		})
+	}
+	e.Description, e.Result = hide(e.Description), hide(e.Result)
+	return e, true
+}
+
+// HealthStatus describes hit points the way spectators see them: healthy, wounded (75% or
+// less), bloodied (50%), critical (25%) or down.
+func HealthStatus(hp, maxHP int) string {
+	switch {
+	case hp <= 0:
+		return "down"
+	case maxHP <= 0:
+		return "healthy"
+	}
+	percent := float64(hp) / float64(maxHP) * 100
+	switch {
+	case percent <= 25:
+		return "critical"
+	case percent <= 50:
+		return "bloodied"
+	case percent <= 75:
+		return "wounded"
+	}
+	return "healthy"
+}
+
+// Page merges actions and messages read after a cursor into one feed in time order, redacts
+// it and returns up to limit events with the cursor to continue from. Events the audience
+// doesn't see still move the cursor, so they aren't read again. more reports whether
+// events were left for the next page.
+func Page(actions, messages []Event, after Cursor, limit int) (events []Event, next Cursor, more bool) {
+	all := make([]Event, 0, len(actions)+len(messages))
+	for _, e := range append(append([]Event{}, actions...), messages...) {
+		if (e.Kind == KindAction && e.ID > after.ActionID) || (e.Kind == KindMessage && e.ID > after.MessageID) {
+			all = append(all, e)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if !all[i].Time.Equal(all[j].Time) {
+			return all[i].Time.Before(all[j].Time)
+		}
+		return all[i].Kind == KindAction && all[j].Kind == KindMessage
+	})
+	events = []Event{}
+	next = after
+	for _, e := range all {
+		if len(events) == limit {
+			return events, next, true
+		}
+		next = next.Advance(e)
+		if redacted, ok := Redact(e); ok {
+			events = append(events, redacted)
+		}
+	}
+	return events, next, false
+}
+
+// Tail returns the last limit events the audience sees, oldest first, and the cursor after
+// the newest, for a dashboard's first look before it follows the feed.
+func Tail(actions, messages []Event, limit int) ([]Event, Cursor) {
+	all, next, _ := Page(actions, messages, Cursor{}, len(actions)+len(messages))
+	if len(all) > limit {
+		all = all[len(all)-limit:]
+	}
+	return all, next
+}
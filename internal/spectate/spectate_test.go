@@ -0,0 +1,82 @@
+package spectate
+
+import (
+	"testing"
+	"time"
+)
+
+func at(minute int) time.Time {
+	return time.Date(2026, 10, 16, 20, minute, 0, 0, time.UTC)
+}
+
+func TestCursor(t *testing.T) {
+	c, err := ParseCursor("a120m45")
+	if err != nil || c != (Cursor{ActionID: 120, MessageID: 45}) || c.String() != "a120m45" {
+		t.Errorf("ParseCursor = %+v, %v", c, err)
+	}
+	for _, bad := range []string{"", "120", "a1", "m1a1", "a-1m2"} {
+		if _, err := ParseCursor(bad); err == nil {
+			t.Errorf("ParseCursor(%q) accepted", bad)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	if _, ok := Redact(Event{Kind: KindAction, Type: "gm_nudge", Description: "Nudged Kira"}); ok {
+		t.Error("GM nudges should be hidden")
+	}
+	e, ok := Redact(Event{Kind: KindAction, Type: "monster_ready", Description: "Goblin readies an action", Result: "When 'the door opens' → attack"})
+	if !ok || e.Result != "" || e.Description != "Goblin readies an action" {
+		t.Errorf("readied monster = %+v, %v", e, ok)
+	}
+	e, _ = Redact(Event{Kind: KindAction, Type: "monster_damage", Description: "Ogre takes damage", Result: "9 damage (20/59 HP)"})
+	if e.Result != "9 damage (bloodied)" {
+		t.Errorf("monster HP = %q", e.Result)
+	}
+	// Messages are never dropped, whatever they're called
+	if _, ok := Redact(Event{Kind: KindMessage, Type: "gm_nudge"}); !ok {
+		t.Error("messages should pass")
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	for _, tt := range []struct {
+		hp, max int
+		want    string
+	}{{10, 10, "healthy"}, {7, 10, "wounded"}, {5, 10, "bloodied"}, {2, 10, "critical"}, {0, 10, "down"}} {
+		if got := HealthStatus(tt.hp, tt.max); got != tt.want {
+			t.Errorf("HealthStatus(%d, %d) = %s, want %s", tt.hp, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	actions := []Event{
+		{Kind: KindAction, ID: 1, Type: "attack", Time: at(1)},
+		{Kind: KindAction, ID: 2, Type: "gm_nudge", Time: at(3)},
+		{Kind: KindAction, ID: 3, Type: "narration", Time: at(5)},
+	}
+	messages := []Event{
+		{Kind: KindMessage, ID: 7, Type: KindMessage, Time: at(2)},
+		{Kind: KindMessage, ID: 8, Type: KindMessage, Time: at(4)},
+	}
+	events, next, more := Page(actions, messages, Cursor{}, 2)
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 7 || !more || next != (Cursor{1, 7}) {
+		t.Fatalf("first page = %+v, %v, %v", events, next, more)
+	}
+	events, next, more = Page(actions, messages, next, 2)
+	if len(events) != 2 || events[0].ID != 8 || events[1].ID != 3 || more {
+		t.Fatalf("second page = %+v, %v, %v", events, next, more)
+	}
+	if next != (Cursor{3, 8}) {
+		t.Errorf("hidden events should move the cursor too: %v", next)
+	}
+	if events, _, _ := Page(actions, messages, next, 10); len(events) != 0 {
+		t.Errorf("nothing after the end, got %+v", events)
+	}
+
+	tail, cursor := Tail(actions, messages, 2)
+	if len(tail) != 2 || tail[0].ID != 8 || tail[1].ID != 3 || cursor != (Cursor{3, 8}) {
+		t.Errorf("tail = %+v, %v", tail, cursor)
+	}
+}
@@ -0,0 +1,85 @@
+// Package srdcache keeps read-mostly SRD lookup tables in memory (v1.0.118). Armor, monster
+// defenses and magic items only change when the SRD is seeded, yet damage and AC are worked
+// out from them on every roll, so the server loads each table once and reads it without
+// locking until a seed invalidates it.
+//
+// Like internal/srdfetch it knows nothing about the database: a Table is given a Load
+// function, and the server decides when to call Invalidate.
+package srdcache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Table is a lookup table loaded all at once and swapped in atomically. The zero value is
+// unloaded; set Load before using it.
+type Table[V any] struct {
+	Load func() (map[string]V, error)
+
+	mu sync.Mutex // Serializes loads and invalidations
+	m  atomic.Pointer[map[string]V]
+}
+
+// Get returns key's value, loading the table first if it isn't loaded. ok is false when the
+// table has no such key. err is set only when the table couldn't be loaded, and callers
+// should then look the key up themselves.
+func (t *Table[V]) Get(key string) (v V, ok bool, err error) {
+	m := t.m.Load()
+	if m == nil {
+		if m, err = t.load(); err != nil {
+			return v, false, err
+		}
+	}
+	v, ok = (*m)[key]
+	return v, ok, nil
+}
+
+// load loads the table unless another caller loaded it while this one waited.
+func (t *Table[V]) load() (*map[string]V, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if m := t.m.Load(); m != nil {
+		return m, nil
+	}
+	m, err := t.Load()
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = map[string]V{}
+	}
+	t.m.Store(&m)
+	return &m, nil
+}
+
+// Reload loads the table now, replacing what it held. On error the old contents stay.
+func (t *Table[V]) Reload() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m, err := t.Load()
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		m = map[string]V{}
+	}
+	t.m.Store(&m)
+	return nil
+}
+
+// Invalidate drops the table so the next Get loads it again. It waits for a load in progress,
+// so data read before the invalidation is never kept.
+func (t *Table[V]) Invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.m.Store(nil)
+}
+
+// Len returns how many keys the table holds, or 0 when it isn't loaded.
+func (t *Table[V]) Len() int {
+	if m := t.m.Load(); m != nil {
+		return len(*m)
+	}
+	return 0
+}
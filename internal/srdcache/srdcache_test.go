@@ -0,0 +1,92 @@
+package srdcache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	var loads atomic.Int32
+	ac := 16
+	tbl := &Table[int]{Load: func() (map[string]int, error) {
+		loads.Add(1)
+		return map[string]int{"chain-mail": ac}, nil
+	}}
+
+	if tbl.Len() != 0 {
+		t.Errorf("Len before loading = %d, want 0", tbl.Len())
+	}
+	for i := 0; i < 3; i++ {
+		if v, ok, err := tbl.Get("chain-mail"); v != 16 || !ok || err != nil {
+			t.Fatalf("Get(chain-mail) = %d %v %v", v, ok, err)
+		}
+	}
+	if _, ok, err := tbl.Get("mithral"); ok || err != nil {
+		t.Errorf("Get(mithral) = %v %v, want a miss", ok, err)
+	}
+	if loads.Load() != 1 {
+		t.Errorf("loads = %d, want 1", loads.Load())
+	}
+
+	ac = 18
+	tbl.Invalidate()
+	if v, _, _ := tbl.Get("chain-mail"); v != 18 {
+		t.Errorf("after Invalidate got %d, want 18", v)
+	}
+	ac = 20
+	if err := tbl.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := tbl.Get("chain-mail"); v != 20 || loads.Load() != 3 {
+		t.Errorf("after Reload got %d with %d loads, want 20 with 3", v, loads.Load())
+	}
+}
+
+func TestTableLoadError(t *testing.T) {
+	fail := true
+	tbl := &Table[string]{Load: func() (map[string]string, error) {
+		if fail {
+			return nil, errors.New("no database")
+		}
+		return map[string]string{"lich": "poisoned"}, nil
+	}}
+	if _, ok, err := tbl.Get("lich"); ok || err == nil {
+		t.Errorf("Get with a failing load = %v %v, want an error", ok, err)
+	}
+	fail = false
+	if v, ok, err := tbl.Get("lich"); v != "poisoned" || !ok || err != nil {
+		t.Errorf("Get after the load recovers = %q %v %v", v, ok, err)
+	}
+
+	fail = true
+	if err := tbl.Reload(); err == nil {
+		t.Error("Reload with a failing load should be an error")
+	}
+	if v, ok, _ := tbl.Get("lich"); v != "poisoned" || !ok {
+		t.Errorf("a failed Reload should keep the old table, got %q %v", v, ok)
+	}
+}
+
+func TestTableConcurrentGet(t *testing.T) {
+	var loads atomic.Int32
+	tbl := &Table[bool]{Load: func() (map[string]bool, error) {
+		loads.Add(1)
+		return map[string]bool{"plate": true}, nil
+	}}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, ok, err := tbl.Get("plate"); !v || !ok || err != nil {
+				t.Errorf("Get(plate) = %v %v %v", v, ok, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Errorf("loads = %d, want 1", loads.Load())
+	}
+}
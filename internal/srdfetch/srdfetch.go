@@ -0,0 +1,140 @@
+// Package srdfetch downloads SRD documents from the 5e API for seeding (v1.0.116). It
+// fetches with a bounded pool of workers, keeps each raw response in a Cache, reuses a
+// cached copy while it is younger than MaxAge, and reports whether a document's content
+// hash changed since the last fetch, so the seeder can skip rows that are already current.
+// A fetched response only goes into the Cache when the seeder commits it, after its rows are
+// upserted, so a failed upsert is retried on the next run instead of looking current.
+package srdfetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Entry is a cached API response.
+type Entry struct {
+	Body      []byte
+	Hash      string
+	FetchedAt time.Time
+}
+
+// Cache keeps raw API responses by path, such as "/api/2014/spells/fireball".
+type Cache interface {
+	Load(ctx context.Context, path string) (Entry, bool)
+	Store(ctx context.Context, path string, e Entry) error
+}
+
+// Getter fetches a URL's body.
+type Getter func(ctx context.Context, url string) ([]byte, error)
+
+// Doc is a fetched document. Changed is false when the body is the same as the cached copy
+// (or is the cached copy). Err is set only when there was nothing to fall back on.
+type Doc struct {
+	Path    string
+	Body    []byte
+	Changed bool
+	Err     error
+
+	fetched *Entry // The API's response, for Commit; nil when the cached copy was used
+}
+
+// Fetcher fetches SRD documents through a cache.
+type Fetcher struct {
+	BaseURL string
+	Get     Getter
+	Cache   Cache
+	Workers int           // Concurrent requests; at least 1
+	MaxAge  time.Duration // Cached copies younger than this are used without asking the API
+	Force   bool          // Ask the API even for fresh cached copies
+	Now     func() time.Time
+}
+
+// Hash returns the hex SHA-256 of a body.
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// HTTPGetter is a Getter using client that treats any status but 200 as an error.
+func HTTPGetter(client *http.Client) Getter {
+	return func(ctx context.Context, url string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+}
+
+func (f *Fetcher) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// Fetch returns one document. A fresh cached copy is returned as it is; otherwise the API is
+// asked, and if it fails a stale cached copy is returned instead.
+func (f *Fetcher) Fetch(ctx context.Context, path string) Doc {
+	cached, ok := f.Cache.Load(ctx, path)
+	if ok && !f.Force && f.now().Sub(cached.FetchedAt) < f.MaxAge {
+		return Doc{Path: path, Body: cached.Body}
+	}
+	body, err := f.Get(ctx, f.BaseURL+path)
+	if err != nil {
+		if ok {
+			return Doc{Path: path, Body: cached.Body}
+		}
+		return Doc{Path: path, Err: err}
+	}
+	hash := Hash(body)
+	return Doc{Path: path, Body: body, Changed: !ok || hash != cached.Hash,
+		fetched: &Entry{Body: body, Hash: hash, FetchedAt: f.now()}}
+}
+
+// Commit stores a document fetched from the API in the cache. Call it once the document's
+// rows are saved; until then the cache keeps the previous copy and hash. It does nothing for
+// a document that came from the cache.
+func (f *Fetcher) Commit(ctx context.Context, doc Doc) error {
+	if doc.fetched == nil {
+		return nil
+	}
+	return f.Cache.Store(ctx, doc.Path, *doc.fetched)
+}
+
+// FetchAll fetches documents with up to Workers requests at a time and returns them in the
+// order of paths.
+func (f *Fetcher) FetchAll(ctx context.Context, paths []string) []Doc {
+	docs := make([]Doc, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(f.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				docs[i] = f.Fetch(ctx, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return docs
+}
@@ -0,0 +1,132 @@
+package srdfetch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memCache struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func (c *memCache) Load(ctx context.Context, path string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	return e, ok
+}
+
+func (c *memCache) Store(ctx context.Context, path string, e Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = e
+	return nil
+}
+
+func TestFetch(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cache := &memCache{entries: map[string]Entry{
+		"/fresh": {Body: []byte("fresh"), Hash: Hash([]byte("fresh")), FetchedAt: now.Add(-time.Hour)},
+		"/same":  {Body: []byte("same"), Hash: Hash([]byte("same")), FetchedAt: now.Add(-48 * time.Hour)},
+		"/old":   {Body: []byte("old"), Hash: Hash([]byte("old")), FetchedAt: now.Add(-48 * time.Hour)},
+		"/down":  {Body: []byte("cached"), Hash: Hash([]byte("cached")), FetchedAt: now.Add(-48 * time.Hour)},
+	}}
+	var calls atomic.Int32
+	api := map[string]string{"/same": "same", "/old": "new", "/new": "brand new", "/fresh": "refetched"}
+	f := &Fetcher{
+		BaseURL: "https://api.test",
+		Cache:   cache,
+		MaxAge:  24 * time.Hour,
+		Now:     func() time.Time { return now },
+		Get: func(ctx context.Context, url string) ([]byte, error) {
+			calls.Add(1)
+			body, ok := api[url[len("https://api.test"):]]
+			if !ok {
+				return nil, errors.New("503")
+			}
+			return []byte(body), nil
+		},
+	}
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		path, body string
+		changed    bool
+	}{
+		{"/fresh", "fresh", false},
+		{"/same", "same", false},
+		{"/old", "new", true},
+		{"/new", "brand new", true},
+		{"/down", "cached", false},
+	} {
+		doc := f.Fetch(ctx, tt.path)
+		if doc.Err != nil || string(doc.Body) != tt.body || doc.Changed != tt.changed {
+			t.Errorf("Fetch(%s) = %q changed %v err %v; want %q changed %v", tt.path, doc.Body, doc.Changed, doc.Err, tt.body, tt.changed)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Errorf("API calls = %d, want 4 (the fresh copy needs none)", calls.Load())
+	}
+	doc := f.Fetch(ctx, "/old")
+	if e, _ := cache.Load(ctx, "/old"); string(e.Body) != "old" {
+		t.Errorf("cache updated before Commit: %+v", e)
+	}
+	if err := f.Commit(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := cache.Load(ctx, "/old"); string(e.Body) != "new" || !e.FetchedAt.Equal(now) {
+		t.Errorf("cache not updated by Commit: %+v", e)
+	}
+	if doc := f.Fetch(ctx, "/old"); doc.Changed {
+		t.Error("committed document still reported as changed")
+	}
+	if err := f.Commit(ctx, f.Fetch(ctx, "/fresh")); err != nil {
+		t.Errorf("Commit of a cached copy = %v", err)
+	}
+	if e, _ := cache.Load(ctx, "/fresh"); string(e.Body) != "fresh" {
+		t.Errorf("Commit of a cached copy changed the cache: %+v", e)
+	}
+	if doc := f.Fetch(ctx, "/missing"); doc.Err == nil {
+		t.Error("nothing to fall back on should be an error")
+	}
+
+	f.Force = true
+	if doc := f.Fetch(ctx, "/fresh"); string(doc.Body) != "refetched" || !doc.Changed {
+		t.Errorf("forced fetch = %q changed %v", doc.Body, doc.Changed)
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	var running, peak atomic.Int32
+	f := &Fetcher{
+		Cache:   &memCache{entries: map[string]Entry{}},
+		Workers: 3,
+		Get: func(ctx context.Context, url string) ([]byte, error) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return []byte(url), nil
+		},
+	}
+	paths := []string{"/a", "/b", "/c", "/d", "/e", "/f", "/g"}
+	docs := f.FetchAll(context.Background(), paths)
+	for i, doc := range docs {
+		if doc.Path != paths[i] || string(doc.Body) != paths[i] || !doc.Changed {
+			t.Errorf("docs[%d] = %+v", i, doc)
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("%d requests at once, want at most 3", peak.Load())
+	}
+}
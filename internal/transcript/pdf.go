@@ -0,0 +1,200 @@
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page layout in points: US Letter with one-inch margins.
+const (
+	pageWidth  = 612
+	pageHeight = 792
+	margin     = 72
+)
+
+// pdfStyle is how one kind of Markdown line is set.
+type pdfStyle struct {
+	font   string // F1 Helvetica, F2 Helvetica-Bold, F3 Helvetica-Oblique
+	size   float64
+	indent float64
+	before float64 // extra space above
+}
+
+var (
+	styleTitle   = pdfStyle{font: "F2", size: 20, before: 0}
+	styleHeading = pdfStyle{font: "F2", size: 14, before: 10}
+	styleBody    = pdfStyle{font: "F1", size: 10}
+	styleQuote   = pdfStyle{font: "F3", size: 10, indent: 14}
+	styleBullet  = pdfStyle{font: "F1", size: 10, indent: 12}
+)
+
+// PDF renders the Markdown that Document.Markdown writes as a plain PDF: headings in
+// bold, chat in italics, paragraphs and list items wrapped to the page. Inline emphasis
+// markers are dropped rather than styled. Text outside Latin-1 (beyond the usual curly
+// quotes and dashes) comes out as "?", since only the standard fonts are used.
+func PDF(markdown string) []byte {
+	type line struct {
+		text  string
+		style pdfStyle
+	}
+	var lines []line
+	gap := false
+	for _, raw := range strings.Split(markdown, "\n") {
+		raw = strings.ReplaceAll(strings.TrimRight(raw, " "), "→", "->")
+		style, text := styleBody, raw
+		switch {
+		case raw == "":
+			gap = true
+			continue
+		case raw == "---":
+			gap = true
+			continue
+		case strings.HasPrefix(raw, "# "):
+			style, text = styleTitle, raw[2:]
+		case strings.HasPrefix(raw, "## "):
+			style, text = styleHeading, raw[3:]
+		case strings.HasPrefix(raw, "> "):
+			style, text = styleQuote, raw[2:]
+		case strings.HasPrefix(raw, "- "):
+			style, text = styleBullet, "• "+raw[2:]
+		}
+		text = stripEmphasis(text)
+		if gap {
+			style.before += style.size * 0.6
+			gap = false
+		}
+		width := float64(pageWidth-2*margin) - style.indent
+		for i, wrapped := range wrap(text, int(width/(style.size*0.5))) {
+			s := style
+			if i > 0 {
+				s.before = 0
+				if strings.HasPrefix(raw, "- ") {
+					s.indent += 8 // Hang under the bullet
+				}
+			}
+			lines = append(lines, line{wrapped, s})
+		}
+	}
+
+	// Lay the lines out on pages.
+	var pages []*bytes.Buffer
+	var page *bytes.Buffer
+	y := 0.0
+	for _, l := range lines {
+		lead := l.style.size * 1.35
+		if page == nil || y-l.style.before-lead < margin {
+			page = &bytes.Buffer{}
+			pages = append(pages, page)
+			y = pageHeight - margin
+		} else {
+			y -= l.style.before
+		}
+		y -= lead
+		fmt.Fprintf(page, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", l.style.font, l.style.size, margin+l.style.indent, y, pdfString(l.text))
+	}
+	if len(pages) == 0 {
+		pages = append(pages, &bytes.Buffer{})
+	}
+
+	// Objects: catalog, page tree, three fonts, then a page and its contents per page.
+	var objects []string
+	kids := []string{}
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+2*i))
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Oblique /Encoding /WinAnsiEncoding >>",
+	)
+	for i, p := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 7+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// stripEmphasis drops Markdown bold and italic markers.
+func stripEmphasis(s string) string {
+	s = strings.ReplaceAll(s, "**", "")
+	if strings.HasPrefix(s, "*") && strings.HasSuffix(s, "*") && len(s) > 1 {
+		s = s[1 : len(s)-1]
+	}
+	return strings.NewReplacer(" _(", " (", ")_", ")").Replace(s)
+}
+
+// wrap breaks text into lines of at most width characters, at spaces where it can.
+func wrap(text string, width int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > width {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			r := []rune(word)
+			lines = append(lines, string(r[:width]))
+			word = string(r[width:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case len([]rune(current))+1+len([]rune(word)) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// winAnsi maps the characters outside Latin-1 that WinAnsiEncoding has.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// pdfString encodes text as the inside of a PDF string literal in WinAnsiEncoding.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case winAnsi[r] != 0:
+			b.WriteByte(winAnsi[r])
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+// Package transcript turns a campaign's feed into a document groups can archive or
+// publish: Markdown, and a plain PDF rendered from it.
+//
+// It knows nothing about the database: the server gathers the entries, sessions and
+// story, and this package only groups and formats them, so it can be tested on its own.
+package transcript
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is one line of the feed: an action (Kind is its action type) or a chat
+// message (Kind "message").
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Kind        string    `json:"kind"`
+	Actor       string    `json:"actor,omitempty"` // Character or agent; empty for the GM
+	Description string    `json:"description"`
+	Result      string    `json:"result,omitempty"`
+}
+
+// KindMessage is the Kind of chat messages.
+const KindMessage = "message"
+
+// Session is a scheduled play session; the entries between its start and end belong to it.
+type Session struct {
+	ID    int       `json:"id"`
+	Start time.Time `json:"starts_at"`
+	End   time.Time `json:"ends_at"`
+	Note  string    `json:"note,omitempty"`
+}
+
+// Chapter is a run of entries under one heading: a session, or a day played outside one.
+type Chapter struct {
+	Title     string  `json:"title"`
+	SessionID int     `json:"session_id,omitempty"`
+	Entries   []Entry `json:"entries"`
+}
+
+// Document is a campaign transcript.
+type Document struct {
+	Campaign   string    `json:"campaign"`
+	Setting    string    `json:"setting,omitempty"`
+	GM         string    `json:"gm,omitempty"`
+	Party      []string  `json:"party"`
+	StorySoFar string    `json:"story_so_far,omitempty"`
+	Chapters   []Chapter `json:"chapters"`
+	Generated  time.Time `json:"generated_at"`
+}
+
+// narrativeKinds are written as prose rather than as a list of actions.
+var narrativeKinds = map[string]bool{
+	"narration": true, "auto_narration": true, "scripted_event": true,
+	"environment_event": true, "hazard": true, "regional_effect": true,
+}
+
+// Chapters groups entries by session: entries inside a session's window make up its
+// chapter, numbered in order, and the rest are grouped by the day they happened. Chapters
+// come out in time order and empty sessions are left out.
+func Chapters(entries []Entry, sessions []Session) []Chapter {
+	sorted := append([]Session{}, sessions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	bySession := map[int]*Chapter{}
+	byDay := map[string]*Chapter{}
+	var chapters []*Chapter
+	for _, e := range entries {
+		var ch *Chapter
+		for n, s := range sorted {
+			if !e.Time.Before(s.Start) && !e.Time.After(s.End) {
+				if ch = bySession[s.ID]; ch == nil {
+					title := fmt.Sprintf("Session %d — %s", n+1, s.Start.Format("Monday, 2 January 2006"))
+					if s.Note != "" {
+						title += ": " + s.Note
+					}
+					ch = &Chapter{Title: title, SessionID: s.ID}
+					bySession[s.ID] = ch
+					chapters = append(chapters, ch)
+				}
+				break
+			}
+		}
+		if ch == nil {
+			day := e.Time.Format("Monday, 2 January 2006")
+			if ch = byDay[day]; ch == nil {
+				ch = &Chapter{Title: day}
+				byDay[day] = ch
+				chapters = append(chapters, ch)
+			}
+		}
+		ch.Entries = append(ch.Entries, e)
+	}
+	for _, ch := range chapters {
+		sort.SliceStable(ch.Entries, func(i, j int) bool { return ch.Entries[i].Time.Before(ch.Entries[j].Time) })
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Entries[0].Time.Before(chapters[j].Entries[0].Time) })
+	out := make([]Chapter, len(chapters))
+	for i, ch := range chapters {
+		out[i] = *ch
+	}
+	return out
+}
+
+// Markdown writes the transcript: a title block with the party, the story so far, then
+// each chapter with narration as prose, chat as quoted lines and everything else as a
+// list of actions and their results.
+func (d Document) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", oneLine(d.Campaign))
+	if d.Setting != "" {
+		fmt.Fprintf(&b, "*%s*\n\n", oneLine(d.Setting))
+	}
+	if d.GM != "" {
+		fmt.Fprintf(&b, "**Game Master:** %s\n\n", oneLine(d.GM))
+	}
+	if len(d.Party) > 0 {
+		b.WriteString("**Party:**\n\n")
+		for _, p := range d.Party {
+			fmt.Fprintf(&b, "- %s\n", oneLine(p))
+		}
+		b.WriteString("\n")
+	}
+	if d.StorySoFar != "" {
+		fmt.Fprintf(&b, "## The Story So Far\n\n%s\n\n", strings.TrimSpace(d.StorySoFar))
+	}
+	for _, ch := range d.Chapters {
+		fmt.Fprintf(&b, "## %s\n\n", oneLine(ch.Title))
+		inList := false
+		for _, e := range ch.Entries {
+			narrative := narrativeKinds[e.Kind] || e.Kind == KindMessage
+			if inList && narrative {
+				b.WriteString("\n")
+			}
+			inList = !narrative
+			switch {
+			case narrativeKinds[e.Kind]:
+				fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(e.Description))
+				if e.Result != "" && e.Result != e.Description {
+					fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(e.Result))
+				}
+			case e.Kind == KindMessage:
+				fmt.Fprintf(&b, "> **%s:** %s\n\n", oneLine(e.Actor), oneLine(e.Description))
+			default:
+				line := oneLine(e.Description)
+				if e.Actor != "" && !strings.HasPrefix(line, e.Actor) {
+					line = fmt.Sprintf("**%s** — %s", oneLine(e.Actor), line)
+				}
+				if e.Result != "" {
+					line += " → " + oneLine(e.Result)
+				}
+				fmt.Fprintf(&b, "- %s _(%s)_\n", line, e.Time.Format("15:04"))
+			}
+		}
+		if inList {
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "---\n\n*Transcript generated %s.*\n", d.Generated.UTC().Format("2 January 2006 15:04 MST"))
+	return b.String()
+}
+
+// oneLine folds text onto a single line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,115 @@
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestChaptersBySessionThenDay(t *testing.T) {
+	sessions := []Session{
+		{ID: 9, Start: at(12, 18, 0), End: at(12, 21, 0), Note: "The crypt"},
+		{ID: 4, Start: at(5, 18, 0), End: at(5, 21, 0)},
+		{ID: 11, Start: at(19, 18, 0), End: at(19, 21, 0)}, // Nothing happened
+	}
+	entries := []Entry{
+		{Time: at(12, 19, 0), Kind: "attack", Description: "b"},
+		{Time: at(5, 18, 30), Kind: "narration", Description: "a"},
+		{Time: at(8, 10, 0), Kind: KindMessage, Description: "between"},
+		{Time: at(12, 18, 30), Kind: "narration", Description: "b0"},
+	}
+	chapters := Chapters(entries, sessions)
+	if len(chapters) != 3 {
+		t.Fatalf("chapters = %+v", chapters)
+	}
+	if chapters[0].SessionID != 4 || !strings.HasPrefix(chapters[0].Title, "Session 1 — Monday, 5 October 2026") {
+		t.Errorf("first chapter %+v", chapters[0])
+	}
+	if chapters[1].SessionID != 0 || chapters[1].Title != "Thursday, 8 October 2026" {
+		t.Errorf("between sessions %+v", chapters[1])
+	}
+	if chapters[2].Title != "Session 2 — Monday, 12 October 2026: The crypt" || chapters[2].Entries[0].Description != "b0" {
+		t.Errorf("last chapter %+v", chapters[2])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	d := Document{
+		Campaign:   "The Sunless Citadel",
+		GM:         "Oracle",
+		Party:      []string{"Kira (Elf Rogue 3)"},
+		StorySoFar: "The party descended into the ravine.",
+		Generated:  at(20, 12, 0),
+		Chapters: []Chapter{{Title: "Session 1", Entries: []Entry{
+			{Time: at(5, 18, 30), Kind: "narration", Description: "Dust drifts from the ceiling."},
+			{Time: at(5, 18, 31), Kind: "attack", Actor: "Kira", Description: "Kira attacks the goblin", Result: "Hit! 7 damage"},
+			{Time: at(5, 18, 32), Kind: "dodge", Actor: "Bram", Description: "takes the Dodge action"},
+			{Time: at(5, 18, 33), Kind: KindMessage, Actor: "kira-agent", Description: "Nice\nshot"},
+		}}},
+	}
+	md := d.Markdown()
+	for _, want := range []string{
+		"# The Sunless Citadel\n",
+		"**Game Master:** Oracle",
+		"- Kira (Elf Rogue 3)\n",
+		"## The Story So Far\n\nThe party descended into the ravine.\n",
+		"## Session 1\n\nDust drifts from the ceiling.\n\n",
+		"- Kira attacks the goblin → Hit! 7 damage _(18:31)_\n",
+		"- **Bram** — takes the Dodge action _(18:32)_\n\n",
+		"> **kira-agent:** Nice shot\n",
+		"*Transcript generated 20 October 2026 12:00 UTC.*",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("missing %q in\n%s", want, md)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	var md strings.Builder
+	md.WriteString("# Title (draft)\n\n")
+	for i := 0; i < 120; i++ {
+		fmt.Fprintf(&md, "- Entry %d — a fairly long line of text that should wrap onto a second line when it reaches the edge of the page\n", i)
+	}
+	pdf := PDF(md.String())
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("not a PDF")
+	}
+	if !bytes.Contains(pdf, []byte(`(Title \(draft\)) Tj`)) {
+		t.Error("parentheses should be escaped")
+	}
+	if !bytes.Contains(pdf, []byte("Entry 0 \x97 a fairly")) {
+		t.Error("em dash should be WinAnsi 0x97")
+	}
+	if n := bytes.Count(pdf, []byte("/Type /Page ")); n < 3 {
+		t.Errorf("%d pages, want the entries to run over several", n)
+	}
+	// The xref offsets point at their objects
+	start := bytes.LastIndex(pdf, []byte("startxref\n"))
+	var xref int
+	fmt.Sscanf(string(pdf[start+len("startxref\n"):]), "%d", &xref)
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Error("startxref doesn't point at the xref table")
+	}
+	var first int
+	fmt.Sscanf(string(pdf[xref:]), "xref\n0 %d\n0000000000 65535 f \n%d", new(int), &first)
+	if !bytes.HasPrefix(pdf[first:], []byte("1 0 obj")) {
+		t.Error("first object offset is wrong")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("one two three four", 9)
+	if strings.Join(lines, "|") != "one two|three|four" {
+		t.Errorf("wrap = %q", lines)
+	}
+	if lines := wrap("abcdefghij", 4); strings.Join(lines, "|") != "abcd|efgh|ij" {
+		t.Errorf("long word = %q", lines)
+	}
+}
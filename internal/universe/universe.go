@@ -0,0 +1,155 @@
+// Package universe answers the read-only SRD questions the rules ask on every roll: an
+// armor's AC and category, what a monster resists, what a magic item is.
+//
+// It knows nothing about the database: a Catalog reads from a Store, keeps each table in
+// memory (see internal/srdcache) until the server calls Invalidate after a seed, and falls
+// back to the Store's single-row lookups if a table can't be loaded.
+package universe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentrpg/agentrpg/internal/srdcache"
+)
+
+// ErrNotFound is returned for a slug the SRD doesn't have.
+var ErrNotFound = errors.New("not found")
+
+// Armor is what the rules need from an armor row.
+type Armor struct {
+	AC                  int
+	Type                string // light, medium, heavy, shield
+	StealthDisadvantage bool
+	StrengthRequirement int
+}
+
+// MonsterDefenses is what a monster resists, as comma-separated SRD lists.
+type MonsterDefenses struct {
+	Resistances         string
+	Immunities          string
+	Vulnerabilities     string
+	ConditionImmunities string
+}
+
+// MagicItem is a magic item row.
+type MagicItem struct {
+	Name        string
+	Rarity      string
+	Type        string
+	Attunement  bool
+	Description string
+}
+
+// Store reads the SRD. The All methods load a whole table keyed by slug; the others
+// look up one slug and return ErrNotFound when there's no such row.
+type Store interface {
+	AllArmor(ctx context.Context) (map[string]Armor, error)
+	Armor(ctx context.Context, slug string) (Armor, error)
+	AllMonsterDefenses(ctx context.Context) (map[string]MonsterDefenses, error)
+	MonsterDefenses(ctx context.Context, slug string) (MonsterDefenses, error)
+	AllMagicItems(ctx context.Context) (map[string]MagicItem, error)
+	MagicItem(ctx context.Context, slug string) (MagicItem, error)
+}
+
+// Catalog is the cached view of a Store that the rules read from.
+type Catalog struct {
+	store    Store
+	armor    *srdcache.Table[Armor]
+	monsters *srdcache.Table[MonsterDefenses]
+	items    *srdcache.Table[MagicItem]
+}
+
+// NewCatalog returns a Catalog over s. Nothing is loaded until the first lookup or Reload.
+func NewCatalog(s Store) *Catalog {
+	ctx := context.Background()
+	return &Catalog{
+		store:    s,
+		armor:    &srdcache.Table[Armor]{Load: func() (map[string]Armor, error) { return s.AllArmor(ctx) }},
+		monsters: &srdcache.Table[MonsterDefenses]{Load: func() (map[string]MonsterDefenses, error) { return s.AllMonsterDefenses(ctx) }},
+		items:    &srdcache.Table[MagicItem]{Load: func() (map[string]MagicItem, error) { return s.AllMagicItems(ctx) }},
+	}
+}
+
+// Armor returns the armor with slug.
+func (c *Catalog) Armor(ctx context.Context, slug string) (Armor, error) {
+	return lookup(ctx, c.armor, slug, c.store.Armor)
+}
+
+// MonsterDefenses returns the defenses of the monster with slug.
+func (c *Catalog) MonsterDefenses(ctx context.Context, slug string) (MonsterDefenses, error) {
+	return lookup(ctx, c.monsters, slug, c.store.MonsterDefenses)
+}
+
+// MagicItem returns the magic item with slug.
+func (c *Catalog) MagicItem(ctx context.Context, slug string) (MagicItem, error) {
+	return lookup(ctx, c.items, slug, c.store.MagicItem)
+}
+
+// lookup reads slug from t, or from one (the Store's single-row lookup) if t can't load.
+func lookup[V any](ctx context.Context, t *srdcache.Table[V], slug string, one func(context.Context, string) (V, error)) (V, error) {
+	var zero V
+	if slug == "" {
+		return zero, ErrNotFound
+	}
+	v, ok, err := t.Get(slug)
+	if err != nil {
+		return one(ctx, slug)
+	}
+	if !ok {
+		return zero, ErrNotFound
+	}
+	return v, nil
+}
+
+// Reload loads every table now. A table that fails keeps what it held, and its error is
+// returned (joined with the others) once the rest have loaded.
+func (c *Catalog) Reload() error {
+	var errs []error
+	if err := c.armor.Reload(); err != nil {
+		errs = append(errs, fmt.Errorf("armor: %w", err))
+	}
+	if err := c.monsters.Reload(); err != nil {
+		errs = append(errs, fmt.Errorf("monsters: %w", err))
+	}
+	if err := c.items.Reload(); err != nil {
+		errs = append(errs, fmt.Errorf("magic items: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Invalidate drops every table; each reloads on its next lookup.
+func (c *Catalog) Invalidate() {
+	c.armor.Invalidate()
+	c.monsters.Invalidate()
+	c.items.Invalidate()
+}
+
+// Len reports how many armor, monster and magic item rows are cached.
+func (c *Catalog) Len() (armor, monsters, items int) {
+	return c.armor.Len(), c.monsters.Len(), c.items.Len()
+}
+
+// ArmorProficient reports whether a character with profs (a comma-separated list such as
+// "light, medium, shields" or "all armor, shields") is proficient with an armor category:
+// light, medium, heavy or shield. "All armor" covers everything but shields.
+func ArmorProficient(profs, category string) bool {
+	if profs == "" {
+		return false
+	}
+	category = strings.ToLower(category)
+	for _, prof := range strings.Split(strings.ToLower(profs), ",") {
+		prof = strings.TrimSpace(prof)
+		switch {
+		case prof == "all armor" && (category == "light" || category == "medium" || category == "heavy"):
+			return true
+		case prof == category:
+			return true
+		case prof == "shields" && category == "shield":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package universe
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeStore serves fixed tables and counts how often each is loaded.
+type fakeStore struct {
+	armor     map[string]Armor
+	monsters  map[string]MonsterDefenses
+	items     map[string]MagicItem
+	loadErr   error // Returned by every All method when set
+	loads     int
+	singleRow int
+}
+
+func (f *fakeStore) AllArmor(ctx context.Context) (map[string]Armor, error) {
+	f.loads++
+	if f.loadErr != nil {
+		return nil, f.loadErr
+	}
+	return f.armor, nil
+}
+
+func (f *fakeStore) Armor(ctx context.Context, slug string) (Armor, error) {
+	f.singleRow++
+	if a, ok := f.armor[slug]; ok {
+		return a, nil
+	}
+	return Armor{}, ErrNotFound
+}
+
+func (f *fakeStore) AllMonsterDefenses(ctx context.Context) (map[string]MonsterDefenses, error) {
+	f.loads++
+	if f.loadErr != nil {
+		return nil, f.loadErr
+	}
+	return f.monsters, nil
+}
+
+func (f *fakeStore) MonsterDefenses(ctx context.Context, slug string) (MonsterDefenses, error) {
+	f.singleRow++
+	if d, ok := f.monsters[slug]; ok {
+		return d, nil
+	}
+	return MonsterDefenses{}, ErrNotFound
+}
+
+func (f *fakeStore) AllMagicItems(ctx context.Context) (map[string]MagicItem, error) {
+	f.loads++
+	if f.loadErr != nil {
+		return nil, f.loadErr
+	}
+	return f.items, nil
+}
+
+func (f *fakeStore) MagicItem(ctx context.Context, slug string) (MagicItem, error) {
+	f.singleRow++
+	if it, ok := f.items[slug]; ok {
+		return it, nil
+	}
+	return MagicItem{}, ErrNotFound
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		armor:    map[string]Armor{"chain-mail": {AC: 16, Type: "heavy", StealthDisadvantage: true, StrengthRequirement: 13}},
+		monsters: map[string]MonsterDefenses{"skeleton": {Vulnerabilities: "bludgeoning", Immunities: "poison"}},
+		items:    map[string]MagicItem{"bag-of-holding": {Name: "Bag of Holding", Rarity: "uncommon"}},
+	}
+}
+
+func TestCatalogCachesTables(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeStore()
+	c := NewCatalog(s)
+
+	for i := 0; i < 3; i++ {
+		a, err := c.Armor(ctx, "chain-mail")
+		if err != nil || a.AC != 16 || a.Type != "heavy" {
+			t.Fatalf("Armor(chain-mail) = %+v, %v", a, err)
+		}
+	}
+	if d, err := c.MonsterDefenses(ctx, "skeleton"); err != nil || d.Vulnerabilities != "bludgeoning" {
+		t.Fatalf("MonsterDefenses(skeleton) = %+v, %v", d, err)
+	}
+	if it, err := c.MagicItem(ctx, "bag-of-holding"); err != nil || it.Name != "Bag of Holding" {
+		t.Fatalf("MagicItem(bag-of-holding) = %+v, %v", it, err)
+	}
+	if s.loads != 3 || s.singleRow != 0 {
+		t.Errorf("loads = %d, single-row lookups = %d; want each table loaded once and no row lookups", s.loads, s.singleRow)
+	}
+	if armor, monsters, items := c.Len(); armor != 1 || monsters != 1 || items != 1 {
+		t.Errorf("Len() = %d, %d, %d; want 1, 1, 1", armor, monsters, items)
+	}
+
+	c.Invalidate()
+	c.Armor(ctx, "chain-mail")
+	if s.loads != 4 {
+		t.Errorf("loads after Invalidate = %d, want the armor table reloaded", s.loads)
+	}
+}
+
+func TestCatalogNotFound(t *testing.T) {
+	ctx := context.Background()
+	c := NewCatalog(newFakeStore())
+	if _, err := c.Armor(ctx, "mithral-pajamas"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown armor: err = %v, want ErrNotFound", err)
+	}
+	if _, err := c.MonsterDefenses(ctx, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("empty slug: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCatalogFallsBackWhenATableWontLoad(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeStore()
+	s.loadErr = errors.New("database is down")
+	c := NewCatalog(s)
+
+	if err := c.Reload(); err == nil {
+		t.Error("Reload() = nil, want the load errors")
+	}
+	if a, err := c.Armor(ctx, "chain-mail"); err != nil || a.AC != 16 {
+		t.Fatalf("Armor(chain-mail) = %+v, %v; want the row from the Store", a, err)
+	}
+	if _, err := c.MagicItem(ctx, "deck-of-many-things"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown item: err = %v, want ErrNotFound from the Store", err)
+	}
+	if s.singleRow != 2 {
+		t.Errorf("single-row lookups = %d, want 2", s.singleRow)
+	}
+}
+
+func TestArmorProficient(t *testing.T) {
+	tests := []struct {
+		profs    string
+		category string
+		want     bool
+	}{
+		{"", "light", false},
+		{"light, medium, shields", "Medium", true},
+		{"light, medium, shields", "shield", true},
+		{"light, medium, shields", "heavy", false},
+		{"All Armor, shields", "heavy", true},
+		{"all armor", "shield", false},
+		{"light", "light", true},
+	}
+	for _, tt := range tests {
+		if got := ArmorProficient(tt.profs, tt.category); got != tt.want {
+			t.Errorf("ArmorProficient(%q, %q) = %v, want %v", tt.profs, tt.category, got, tt.want)
+		}
+	}
+}